@@ -11,7 +11,9 @@ import (
 	vminsertcommon "github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
 	vminsertrelabel "github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/continuousquery"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/queryrules"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envflag"
@@ -33,7 +35,7 @@ var (
 	minScrapeInterval = flag.Duration("dedup.minScrapeInterval", 0, "Leave only the last sample in every time series per each discrete interval "+
 		"equal to -dedup.minScrapeInterval > 0. See also -streamAggr.dedupInterval and https://docs.victoriametrics.com/#deduplication")
 	dryRun = flag.Bool("dryRun", false, "Whether to check config files without running VictoriaMetrics. The following config files are checked: "+
-		"-promscrape.config, -relabelConfig and -streamAggr.config. Unknown config entries aren't allowed in -promscrape.config by default. "+
+		"-promscrape.config, -relabelConfig, -streamAggr.config, -search.queryRules and -continuousQueries.config. Unknown config entries aren't allowed in -promscrape.config by default. "+
 		"This can be changed with -promscrape.config.strictParse=false command-line flag")
 	inmemoryDataFlushInterval = flag.Duration("inmemoryDataFlushInterval", 5*time.Second, "The interval for guaranteed saving of in-memory data to disk. "+
 		"The saved data survives unclean shutdowns such as OOM crash, hardware reset, SIGKILL, etc. "+
@@ -62,6 +64,12 @@ func main() {
 		if err := vminsertcommon.CheckStreamAggrConfig(); err != nil {
 			logger.Fatalf("error when checking -streamAggr.config: %s", err)
 		}
+		if err := queryrules.CheckConfig(); err != nil {
+			logger.Fatalf("error when checking -search.queryRules: %s", err)
+		}
+		if err := continuousquery.CheckConfig(); err != nil {
+			logger.Fatalf("error when checking -continuousQueries.config: %s", err)
+		}
 		logger.Infof("-promscrape.config is ok; exiting with 0 status code")
 		return
 	}
@@ -77,6 +85,7 @@ func main() {
 	vmstorage.Init(promql.ResetRollupResultCacheIfNeeded)
 	vmselect.Init()
 	vminsert.Init()
+	continuousquery.Init()
 
 	startSelfScraper()
 
@@ -97,6 +106,7 @@ func main() {
 	}
 	logger.Infof("successfully shut down the webservice in %.3f seconds", time.Since(startTime).Seconds())
 	vminsert.Stop()
+	continuousquery.Stop()
 
 	vmstorage.Stop()
 	vmselect.Stop()
@@ -192,6 +192,11 @@ func (lmp *logMessageProcessor) AddRow(timestamp int64, fields []logstorage.Fiel
 		return
 	}
 
+	if !lmp.cp.Debug && pushToStreamAggr(lmp.cp.TenantID, fields) {
+		rowsDroppedTotalStreamAggr.Inc()
+		return
+	}
+
 	lmp.lr.MustAdd(lmp.cp.TenantID, timestamp, fields)
 	if lmp.cp.Debug {
 		s := lmp.lr.GetRowString(0)
@@ -242,4 +247,5 @@ var (
 	rowsDroppedTotalDebug         = metrics.NewCounter(`vl_rows_dropped_total{reason="debug"}`)
 	rowsDroppedTotalTooManyFields = metrics.NewCounter(`vl_rows_dropped_total{reason="too_many_fields"}`)
 	rowsDroppedTotalMsgNotValid   = metrics.NewCounter(`vl_rows_dropped_total{reason="msg_not_exist"}`)
+	rowsDroppedTotalStreamAggr    = metrics.NewCounter(`vl_rows_dropped_total{reason="stream_aggr"}`)
 )
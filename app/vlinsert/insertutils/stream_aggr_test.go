@@ -0,0 +1,209 @@
+package insertutils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestParseStreamAggrConfigFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		_, err := parseStreamAggrConfig([]byte(data))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// invalid yaml
+	f(`foobar`)
+
+	// unknown option
+	f(`
+- interval: 1m
+  stats: count()
+  foo: bar
+`)
+
+	// missing interval
+	f(`
+- stats: count()
+`)
+
+	// invalid interval
+	f(`
+- interval: foo
+  stats: count()
+`)
+	f(`
+- interval: 100ms
+  stats: count()
+`)
+
+	// missing stats
+	f(`
+- interval: 1m
+`)
+
+	// invalid stats
+	f(`
+- interval: 1m
+  stats: foo
+`)
+	f(`
+- interval: 1m
+  stats: count() | limit 10
+`)
+
+	// result names, which cannot be used as metric names
+	f(`
+- interval: 1m
+  stats: count()
+`)
+	f(`
+- interval: 1m
+  stats: count() as "requests.total"
+`)
+	f(`
+- interval: 1m
+  stats: count() as "5xx"
+`)
+}
+
+func TestStreamAggregatorFlush(t *testing.T) {
+	f := func(config string, rows [][]logstorage.Field, resultExpected string) {
+		t.Helper()
+
+		as, err := parseStreamAggrConfig([]byte(config))
+		if err != nil {
+			t.Fatalf("cannot parse config: %s", err)
+		}
+
+		var lines []string
+		pushFunc := func(ts prompbmarshal.TimeSeries) error {
+			lines = append(lines, timeSeriesToString(ts))
+			return nil
+		}
+		for _, a := range as {
+			for _, fields := range rows {
+				a.add(logstorage.TenantID{}, fields)
+			}
+			a.flush(pushFunc, 1234)
+		}
+
+		result := strings.Join(lines, "\n")
+		if result != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	rows := [][]logstorage.Field{
+		{
+			{Name: "_msg", Value: "GET /foo"},
+			{Name: "status", Value: "200"},
+			{Name: "bytes", Value: "10"},
+		},
+		{
+			{Name: "_msg", Value: "GET /foo"},
+			{Name: "status", Value: "200"},
+			{Name: "bytes", Value: "20"},
+		},
+	}
+
+	// stats without by(...)
+	f(`
+- interval: 1m
+  stats: count() requests, sum(bytes) bytes_total, values(status) statuses
+`, rows, `requests{vm_account_id="0",vm_project_id="0"} 2 1234
+bytes_total{vm_account_id="0",vm_project_id="0"} 30 1234`)
+
+	// stats with by(...)
+	f(`
+- name: access_logs
+  interval: 1m
+  stats: stats by (status, path) count() requests
+`, rows, `requests{status="200",vm_account_id="0",vm_project_id="0"} 2 1234`)
+
+	// by fields with unsupported chars in label names
+	f(`
+- interval: 1m
+  stats: by (status, "http.method") count() requests
+`, [][]logstorage.Field{
+		{
+			{Name: "status", Value: "200"},
+			{Name: "http.method", Value: "GET"},
+		},
+	}, `requests{status="200",http_method="GET",vm_account_id="0",vm_project_id="0"} 1 1234`)
+
+	// multiple configs
+	f(`
+- interval: 1m
+  stats: count() requests
+- interval: 5m
+  stats: max(bytes) max_bytes
+`, rows, `requests{vm_account_id="0",vm_project_id="0"} 2 1234
+max_bytes{vm_account_id="0",vm_project_id="0"} 20 1234`)
+}
+
+func TestStreamAggregatorFlushMultiTenant(t *testing.T) {
+	as, err := parseStreamAggrConfig([]byte(`
+- interval: 1m
+  stats: by (status) count() requests
+`))
+	if err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+	a := as[0]
+
+	var lines []string
+	pushFunc := func(ts prompbmarshal.TimeSeries) error {
+		lines = append(lines, timeSeriesToString(ts))
+		return nil
+	}
+	fields := []logstorage.Field{
+		{Name: "status", Value: "200"},
+	}
+	a.add(logstorage.TenantID{AccountID: 42, ProjectID: 7}, fields)
+	a.add(logstorage.TenantID{}, fields)
+	a.add(logstorage.TenantID{AccountID: 42, ProjectID: 7}, fields)
+	a.flush(pushFunc, 1234)
+
+	result := strings.Join(lines, "\n")
+	resultExpected := `requests{status="200",vm_account_id="0",vm_project_id="0"} 1 1234
+requests{status="200",vm_account_id="42",vm_project_id="7"} 2 1234`
+	if result != resultExpected {
+		t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+
+	// The tenants without new rows must be forgotten after the flush.
+	lines = nil
+	a.flush(pushFunc, 1234)
+	if len(lines) > 0 {
+		t.Fatalf("unexpected series after the second flush: %q", lines)
+	}
+	if len(a.sas) > 0 {
+		t.Fatalf("unexpected tenants after the second flush: %d", len(a.sas))
+	}
+}
+
+func timeSeriesToString(ts prompbmarshal.TimeSeries) string {
+	var labels []string
+	metricName := ""
+	for _, label := range ts.Labels {
+		if label.Name == "__name__" {
+			metricName = label.Value
+			continue
+		}
+		labels = append(labels, fmt.Sprintf("%s=%q", label.Name, label.Value))
+	}
+	s := metricName
+	if len(labels) > 0 {
+		s += "{" + strings.Join(labels, ",") + "}"
+	}
+	sample := ts.Samples[0]
+	return fmt.Sprintf("%s %v %d", s, sample.Value, sample.Timestamp)
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/elasticsearch"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/insertutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/jsonline"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/loki"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vlinsert/opentelemetry"
@@ -13,12 +14,14 @@ import (
 
 // Init initializes vlinsert
 func Init() {
+	insertutils.MustInitStreamAggr()
 	syslog.MustInit()
 }
 
 // Stop stops vlinsert
 func Stop() {
 	syslog.MustStop()
+	insertutils.MustStopStreamAggr()
 }
 
 // RequestHandler handles insert requests for VictoriaLogs
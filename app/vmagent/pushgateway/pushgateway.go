@@ -0,0 +1,225 @@
+package pushgateway
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+)
+
+var (
+	pushInterval = flag.Duration("pushgateway.pushInterval", 15*time.Second, "The interval for sending the latest values for metric groups "+
+		"pushed via Pushgateway-compatible API at /metrics/job/... to remote storage. See https://docs.victoriametrics.com/vmagent/#pushgateway-compatible-api")
+	persistPath = flag.String("pushgateway.persistPath", "", "Optional path to file for persisting metric groups pushed via Pushgateway-compatible API. "+
+		"The groups are restored from this file on vmagent restart. The groups are kept in memory only if the path isn't set. "+
+		"See https://docs.victoriametrics.com/vmagent/#pushgateway-compatible-api")
+	maxRequestSize = flagutil.NewBytes("pushgateway.maxRequestSize", 32*1024*1024, "The maximum size in bytes of a single request "+
+		"to Pushgateway-compatible API at /metrics/job/...")
+)
+
+// PushFunc is called with the latest values for metric groups every -pushgateway.pushInterval.
+type PushFunc func(at *auth.Token, wr *prompbmarshal.WriteRequest)
+
+var (
+	gs     *groupsStorage
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+)
+
+// Init initializes Pushgateway-compatible API.
+//
+// The latest values for the pushed metric groups are sent to pushFunc every -pushgateway.pushInterval.
+//
+// MustStop must be called when Pushgateway-compatible API is no longer needed.
+func Init(pushFunc PushFunc) {
+	if *pushInterval <= 0 {
+		logger.Fatalf("-pushgateway.pushInterval must be positive; got %s", *pushInterval)
+	}
+	gs = newGroupsStorage()
+	if *persistPath != "" && fs.IsPathExist(*persistPath) {
+		data, err := os.ReadFile(*persistPath)
+		if err != nil {
+			logger.Fatalf("cannot read -pushgateway.persistPath=%q: %s", *persistPath, err)
+		}
+		if err := gs.unmarshal(data); err != nil {
+			logger.Errorf("cannot restore Pushgateway groups from -pushgateway.persistPath=%q; starting with empty groups: %s", *persistPath, err)
+		}
+	}
+	_ = metrics.NewGauge(`vmagent_pushgateway_groups`, func() float64 {
+		groups, _ := gs.groupsCount()
+		return float64(groups)
+	})
+	_ = metrics.NewGauge(`vmagent_pushgateway_series`, func() float64 {
+		_, series := gs.groupsCount()
+		return float64(series)
+	})
+
+	stopCh = make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runPusher(pushFunc)
+	}()
+}
+
+// MustStop stops Pushgateway-compatible API and persists metric groups to -pushgateway.persistPath.
+func MustStop() {
+	close(stopCh)
+	wg.Wait()
+	mustPersist()
+}
+
+func runPusher(pushFunc PushFunc) {
+	t := time.NewTicker(*pushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+			gs.flush(pushFunc, time.Now().UnixMilli())
+			mustPersist()
+		}
+	}
+}
+
+func mustPersist() {
+	if *persistPath == "" {
+		return
+	}
+	data, ok := gs.marshal()
+	if !ok {
+		return
+	}
+	fs.MustWriteAtomic(*persistPath, data, true)
+}
+
+var (
+	pushedRows  = metrics.NewCounter(`vmagent_rows_inserted_total{type="pushgateway"}`)
+	deletes     = metrics.NewCounter(`vmagent_pushgateway_group_deletes_total`)
+	parseErrors = metrics.NewCounter(`vm_protoparser_unmarshal_errors_total{type="pushgateway"}`)
+)
+
+// InsertHandler processes Pushgateway-compatible request to /metrics/job/<job>{/<label>/<value>}.
+//
+// PUT replaces all the metrics in the group, POST replaces metrics with the same names in the group, while DELETE deletes the group.
+//
+// See https://github.com/prometheus/pushgateway#api
+func InsertHandler(at *auth.Token, w http.ResponseWriter, r *http.Request) error {
+	groupLabels, err := common.GetPushgatewayLabels(r.URL.Path)
+	if err != nil {
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("cannot parse grouping labels from %q: %w", r.URL.Path, err),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	if len(groupLabels) == 0 || groupLabels[0].Name != "job" {
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("missing non-empty job name in %q", r.URL.Path),
+			StatusCode: http.StatusBadRequest,
+		}
+	}
+	switch r.Method {
+	case http.MethodPut, http.MethodPost:
+		bb := bodyBufferPool.Get()
+		defer bodyBufferPool.Put(bb)
+		rows, err := readRows(bb, r)
+		if err != nil {
+			parseErrors.Inc()
+			return &httpserver.ErrorWithStatusCode{
+				Err:        err,
+				StatusCode: http.StatusBadRequest,
+			}
+		}
+		gs.push(at, groupLabels, rows.Rows, r.Method == http.MethodPut, time.Now().UnixMilli())
+		pushedRows.Add(len(rows.Rows))
+		putRows(rows)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	case http.MethodDelete:
+		if gs.delete(at, groupLabels) {
+			deletes.Inc()
+		}
+		w.WriteHeader(http.StatusAccepted)
+		return nil
+	default:
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("unsupported method %q; supported methods: PUT, POST, DELETE", r.Method),
+			StatusCode: http.StatusMethodNotAllowed,
+		}
+	}
+}
+
+// readRows reads Prometheus text exposition format rows from r body into bb.
+//
+// The returned rows refer to bb, so they must be released via putRows before bb is re-used.
+func readRows(bb *bytesutil.ByteBuffer, r *http.Request) (*prometheus.Rows, error) {
+	var body io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := common.GetGzipReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read gzipped request body: %w", err)
+		}
+		defer common.PutGzipReader(zr)
+		body = zr
+	}
+	lr := io.LimitReader(body, maxRequestSize.N+1)
+	if _, err := bb.ReadFrom(lr); err != nil {
+		return nil, fmt.Errorf("cannot read request body: %w", err)
+	}
+	if int64(len(bb.B)) > maxRequestSize.N {
+		return nil, fmt.Errorf("too big request; mustn't exceed -pushgateway.maxRequestSize=%d bytes", maxRequestSize.N)
+	}
+
+	rows := getRows()
+	var parseErr error
+	rows.UnmarshalWithErrLogger(bytesutil.ToUnsafeString(bb.B), func(s string) {
+		if parseErr == nil {
+			parseErr = fmt.Errorf("cannot parse pushed metrics: %s", s)
+		}
+	})
+	if parseErr != nil {
+		putRows(rows)
+		return nil, parseErr
+	}
+	for i := range rows.Rows {
+		if rows.Rows[i].Timestamp != 0 {
+			err := fmt.Errorf("pushed metrics mustn't contain timestamps; got timestamp for %q", rows.Rows[i].Metric)
+			putRows(rows)
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+var bodyBufferPool bytesutil.ByteBufferPool
+
+func getRows() *prometheus.Rows {
+	v := rowsPool.Get()
+	if v == nil {
+		return &prometheus.Rows{}
+	}
+	return v.(*prometheus.Rows)
+}
+
+func putRows(rows *prometheus.Rows) {
+	rows.Reset()
+	rowsPool.Put(rows)
+}
+
+var rowsPool sync.Pool
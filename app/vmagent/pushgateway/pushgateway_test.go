@@ -0,0 +1,64 @@
+package pushgateway
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+)
+
+func TestInsertHandler(t *testing.T) {
+	gs = newGroupsStorage()
+
+	f := func(method, path, body string, statusCodeExpected int, groupsExpected, seriesExpected int) {
+		t.Helper()
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		statusCode := http.StatusOK
+		if err := InsertHandler(nil, w, r); err != nil {
+			var esc *httpserver.ErrorWithStatusCode
+			if !errors.As(err, &esc) {
+				t.Fatalf("unexpected error type %T: %s", err, err)
+			}
+			statusCode = esc.StatusCode
+		} else {
+			statusCode = w.Code
+		}
+		if statusCode != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d", statusCode, statusCodeExpected)
+		}
+		groups, series := gs.groupsCount()
+		if groups != groupsExpected || series != seriesExpected {
+			t.Fatalf("unexpected number of groups and series; got %d and %d; want %d and %d", groups, series, groupsExpected, seriesExpected)
+		}
+	}
+
+	// missing job
+	f(http.MethodPut, "/metrics/instance/foo", "foo 1", http.StatusBadRequest, 0, 0)
+	f(http.MethodPut, "/metrics/job/", "foo 1", http.StatusBadRequest, 0, 0)
+
+	// invalid grouping labels
+	f(http.MethodPut, "/metrics/job/foo/instance", "foo 1", http.StatusBadRequest, 0, 0)
+
+	// unsupported method
+	f(http.MethodGet, "/metrics/job/foo", "", http.StatusMethodNotAllowed, 0, 0)
+
+	// invalid body
+	f(http.MethodPut, "/metrics/job/foo", "foo{", http.StatusBadRequest, 0, 0)
+
+	// timestamps aren't allowed
+	f(http.MethodPut, "/metrics/job/foo", "foo 1 123", http.StatusBadRequest, 0, 0)
+
+	// successful pushes
+	f(http.MethodPut, "/metrics/job/foo", "foo 1\nbar 2", http.StatusOK, 1, 2)
+	f(http.MethodPost, "/metrics/job/foo", "foo 3\nbaz 4", http.StatusOK, 1, 3)
+	f(http.MethodPut, "/metrics/job@base64/Zm9v/instance/bar", "foo 1", http.StatusOK, 2, 4)
+
+	// delete
+	f(http.MethodDelete, "/metrics/job/foo", "", http.StatusAccepted, 1, 1)
+	f(http.MethodDelete, "/metrics/job/foo", "", http.StatusAccepted, 1, 1)
+	f(http.MethodDelete, "/metrics/job/foo/instance/bar", "", http.StatusAccepted, 0, 0)
+}
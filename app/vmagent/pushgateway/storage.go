@@ -0,0 +1,399 @@
+package pushgateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+)
+
+// groupsStorage holds the latest values for metric groups pushed via Pushgateway-compatible API.
+type groupsStorage struct {
+	mu sync.Mutex
+
+	// groups contains metric groups keyed by tenant and grouping labels.
+	groups map[string]*group
+
+	// staleSeries contains series, which must be sent with staleness markers on the next flush.
+	staleSeries []*series
+
+	// isDirty is set to true when groups are changed after the last call to marshal().
+	isDirty bool
+}
+
+// group is a metric group identified by grouping labels.
+//
+// See https://github.com/prometheus/pushgateway#about-metric-inconsistencies
+type group struct {
+	at     *auth.Token
+	labels []prompbmarshal.Label
+
+	// pushTime is the time in milliseconds of the last successful push to the group.
+	pushTime int64
+
+	// series contains the latest values for series in the group keyed by series labels.
+	series map[string]*series
+}
+
+type series struct {
+	at     *auth.Token
+	labels []prompbmarshal.Label
+	value  float64
+}
+
+func newGroupsStorage() *groupsStorage {
+	return &groupsStorage{
+		groups: make(map[string]*group),
+	}
+}
+
+// push stores rows in the group with the given groupLabels.
+//
+// If replaceAll is set, then all the previously pushed series in the group are replaced with rows (PUT semantics).
+// Otherwise only series with metric names from rows are replaced (POST semantics).
+//
+// Replaced series, which are missing in rows, are sent with staleness markers on the next flush.
+func (gs *groupsStorage) push(at *auth.Token, groupLabels []prompbmarshal.Label, rows []prometheus.Row, replaceAll bool, pushTime int64) {
+	groupLabels = sortLabels(groupLabels)
+	groupKey := marshalGroupKey(at, groupLabels)
+
+	newSeries := make(map[string]*series, len(rows))
+	metricNames := make(map[string]struct{})
+	for i := range rows {
+		r := &rows[i]
+		labels := newSeriesLabels(r, groupLabels)
+		newSeries[marshalLabels(labels)] = &series{
+			at:     at,
+			labels: labels,
+			value:  r.Value,
+		}
+		metricNames[r.Metric] = struct{}{}
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	g := gs.groups[groupKey]
+	if g == nil {
+		g = &group{
+			at:     at,
+			labels: cloneLabels(groupLabels),
+		}
+		gs.groups[groupKey] = g
+	}
+	for k, s := range g.series {
+		if _, ok := newSeries[k]; ok {
+			continue
+		}
+		if !replaceAll {
+			if _, ok := metricNames[getMetricName(s.labels)]; !ok {
+				// Keep series with metric names missing in rows for POST requests.
+				newSeries[k] = s
+				continue
+			}
+		}
+		gs.staleSeries = append(gs.staleSeries, s)
+	}
+	g.series = newSeries
+	g.pushTime = pushTime
+	gs.isDirty = true
+}
+
+// delete deletes the group with the given groupLabels.
+//
+// All the series in the deleted group are sent with staleness markers on the next flush.
+// It returns false if the group doesn't exist.
+func (gs *groupsStorage) delete(at *auth.Token, groupLabels []prompbmarshal.Label) bool {
+	groupLabels = sortLabels(groupLabels)
+	groupKey := marshalGroupKey(at, groupLabels)
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	g := gs.groups[groupKey]
+	if g == nil {
+		return false
+	}
+	delete(gs.groups, groupKey)
+	for _, s := range g.series {
+		gs.staleSeries = append(gs.staleSeries, s)
+	}
+	gs.staleSeries = append(gs.staleSeries, g.newPushTimeSeries())
+	gs.isDirty = true
+	return true
+}
+
+// flush calls pushFunc for every tenant with the latest values for all the stored series plus push_time_seconds series per each group.
+//
+// Series from deleted groups and replaced series are sent with staleness markers.
+func (gs *groupsStorage) flush(pushFunc PushFunc, timestamp int64) {
+	m := make(map[string]*tenantSeries)
+	appendSeries := func(s *series, value float64) {
+		k := s.at.String()
+		ts := m[k]
+		if ts == nil {
+			ts = &tenantSeries{
+				at: s.at,
+			}
+			m[k] = ts
+		}
+		ts.samples = append(ts.samples, prompbmarshal.Sample{
+			Value:     value,
+			Timestamp: timestamp,
+		})
+		ts.tss = append(ts.tss, prompbmarshal.TimeSeries{
+			Labels:  s.labels,
+			Samples: ts.samples[len(ts.samples)-1:],
+		})
+	}
+
+	gs.mu.Lock()
+	for _, g := range gs.groups {
+		for _, s := range g.series {
+			appendSeries(s, s.value)
+		}
+		s := g.newPushTimeSeries()
+		appendSeries(s, s.value)
+	}
+	for _, s := range gs.staleSeries {
+		appendSeries(s, decimal.StaleNaN)
+	}
+	gs.staleSeries = nil
+	gs.mu.Unlock()
+
+	for _, ts := range m {
+		pushFunc(ts.at, &prompbmarshal.WriteRequest{
+			Timeseries: ts.tss,
+		})
+	}
+}
+
+type tenantSeries struct {
+	at      *auth.Token
+	tss     []prompbmarshal.TimeSeries
+	samples []prompbmarshal.Sample
+}
+
+func (g *group) newPushTimeSeries() *series {
+	labels := make([]prompbmarshal.Label, 0, len(g.labels)+1)
+	labels = append(labels, prompbmarshal.Label{
+		Name:  "__name__",
+		Value: "push_time_seconds",
+	})
+	labels = append(labels, g.labels...)
+	return &series{
+		at:     g.at,
+		labels: sortLabels(labels),
+		value:  float64(g.pushTime) / 1e3,
+	}
+}
+
+// groupsCount returns the number of groups and the number of series in them.
+func (gs *groupsStorage) groupsCount() (int, int) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	seriesCount := 0
+	for _, g := range gs.groups {
+		seriesCount += len(g.series)
+	}
+	return len(gs.groups), seriesCount
+}
+
+type persistedGroup struct {
+	Tenant   string            `json:"tenant,omitempty"`
+	Labels   map[string]string `json:"labels"`
+	PushTime int64             `json:"pushTime"`
+	Series   []persistedSeries `json:"series"`
+}
+
+type persistedSeries struct {
+	Labels map[string]string `json:"labels"`
+
+	// Value is stored as a string, since JSON doesn't support NaN and Inf values.
+	Value string `json:"value"`
+}
+
+// marshal returns JSON representation of gs.
+//
+// It returns false if gs isn't changed since the previous call to marshal.
+func (gs *groupsStorage) marshal() ([]byte, bool) {
+	gs.mu.Lock()
+	if !gs.isDirty {
+		gs.mu.Unlock()
+		return nil, false
+	}
+	pgs := make([]persistedGroup, 0, len(gs.groups))
+	for _, g := range gs.groups {
+		pg := persistedGroup{
+			Labels:   labelsToMap(g.labels),
+			PushTime: g.pushTime,
+			Series:   make([]persistedSeries, 0, len(g.series)),
+		}
+		if g.at != nil {
+			pg.Tenant = g.at.String()
+		}
+		for _, s := range g.series {
+			pg.Series = append(pg.Series, persistedSeries{
+				Labels: labelsToMap(s.labels),
+				Value:  strconv.FormatFloat(s.value, 'g', -1, 64),
+			})
+		}
+		pgs = append(pgs, pg)
+	}
+	gs.isDirty = false
+	gs.mu.Unlock()
+
+	sort.Slice(pgs, func(i, j int) bool {
+		return pgs[i].PushTime < pgs[j].PushTime
+	})
+	data, err := json.Marshal(pgs)
+	if err != nil {
+		// This shouldn't happen, since persistedGroup contains only JSON-serializable fields.
+		logger.Panicf("BUG: cannot marshal pushgateway groups to JSON: %s", err)
+	}
+	return data, true
+}
+
+// unmarshal restores gs from data obtained via marshal.
+func (gs *groupsStorage) unmarshal(data []byte) error {
+	var pgs []persistedGroup
+	if err := json.Unmarshal(data, &pgs); err != nil {
+		return fmt.Errorf("cannot parse JSON: %w", err)
+	}
+	groups := make(map[string]*group, len(pgs))
+	for _, pg := range pgs {
+		var at *auth.Token
+		if pg.Tenant != "" {
+			t, err := auth.NewToken(pg.Tenant)
+			if err != nil {
+				return fmt.Errorf("cannot parse tenant for group %v: %w", pg.Labels, err)
+			}
+			at = t
+		}
+		g := &group{
+			at:       at,
+			labels:   labelsFromMap(pg.Labels),
+			pushTime: pg.PushTime,
+			series:   make(map[string]*series, len(pg.Series)),
+		}
+		for _, ps := range pg.Series {
+			v, err := strconv.ParseFloat(ps.Value, 64)
+			if err != nil {
+				return fmt.Errorf("cannot parse value for series %v: %w", ps.Labels, err)
+			}
+			labels := labelsFromMap(ps.Labels)
+			g.series[marshalLabels(labels)] = &series{
+				at:     at,
+				labels: labels,
+				value:  v,
+			}
+		}
+		groups[marshalGroupKey(at, g.labels)] = g
+	}
+
+	gs.mu.Lock()
+	gs.groups = groups
+	gs.mu.Unlock()
+	return nil
+}
+
+// newSeriesLabels returns sorted labels for r with the given groupLabels.
+//
+// Grouping labels override labels with the same names in r.
+func newSeriesLabels(r *prometheus.Row, groupLabels []prompbmarshal.Label) []prompbmarshal.Label {
+	labels := make([]prompbmarshal.Label, 0, len(r.Tags)+len(groupLabels)+1)
+	labels = append(labels, prompbmarshal.Label{
+		Name:  "__name__",
+		Value: strings.Clone(r.Metric),
+	})
+	for _, tag := range r.Tags {
+		if hasLabel(groupLabels, tag.Key) {
+			continue
+		}
+		labels = append(labels, prompbmarshal.Label{
+			Name:  strings.Clone(tag.Key),
+			Value: strings.Clone(tag.Value),
+		})
+	}
+	labels = append(labels, groupLabels...)
+	return sortLabels(labels)
+}
+
+func hasLabel(labels []prompbmarshal.Label, name string) bool {
+	for _, label := range labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func getMetricName(labels []prompbmarshal.Label) string {
+	for _, label := range labels {
+		if label.Name == "__name__" {
+			return label.Value
+		}
+	}
+	return ""
+}
+
+func sortLabels(labels []prompbmarshal.Label) []prompbmarshal.Label {
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	return labels
+}
+
+func cloneLabels(labels []prompbmarshal.Label) []prompbmarshal.Label {
+	dst := make([]prompbmarshal.Label, len(labels))
+	for i, label := range labels {
+		dst[i] = prompbmarshal.Label{
+			Name:  strings.Clone(label.Name),
+			Value: strings.Clone(label.Value),
+		}
+	}
+	return dst
+}
+
+func marshalGroupKey(at *auth.Token, labels []prompbmarshal.Label) string {
+	return at.String() + "\xff" + marshalLabels(labels)
+}
+
+func marshalLabels(labels []prompbmarshal.Label) string {
+	var b []byte
+	for _, label := range labels {
+		b = strconv.AppendQuote(b, label.Name)
+		b = append(b, '=')
+		b = strconv.AppendQuote(b, label.Value)
+		b = append(b, ',')
+	}
+	return string(b)
+}
+
+func labelsToMap(labels []prompbmarshal.Label) map[string]string {
+	m := make(map[string]string, len(labels))
+	for _, label := range labels {
+		m[label.Name] = label.Value
+	}
+	return m
+}
+
+func labelsFromMap(m map[string]string) []prompbmarshal.Label {
+	labels := make([]prompbmarshal.Label, 0, len(m))
+	for name, value := range m {
+		labels = append(labels, prompbmarshal.Label{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return sortLabels(labels)
+}
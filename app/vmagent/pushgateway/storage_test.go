@@ -0,0 +1,195 @@
+package pushgateway
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+)
+
+func TestGroupsStorage(t *testing.T) {
+	gs := newGroupsStorage()
+
+	push := func(groupLabels []prompbmarshal.Label, data string, replaceAll bool, pushTime int64) {
+		t.Helper()
+		var rows prometheus.Rows
+		rows.Unmarshal(data)
+		gs.push(nil, groupLabels, rows.Rows, replaceAll, pushTime)
+	}
+	f := func(resultExpected string) {
+		t.Helper()
+		result := flushToString(gs, 123000)
+		if result != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	jobLabels := []prompbmarshal.Label{{
+		Name:  "job",
+		Value: "backup",
+	}}
+
+	// empty storage
+	f(``)
+
+	// PUT
+	push(jobLabels, "foo 1\nbar{job=\"x\",a=\"b\"} 2", true, 1000)
+	f(`bar{a="b",job="backup"} 2
+foo{job="backup"} 1
+push_time_seconds{job="backup"} 1`)
+
+	// Re-sending the latest values on every flush
+	f(`bar{a="b",job="backup"} 2
+foo{job="backup"} 1
+push_time_seconds{job="backup"} 1`)
+
+	// POST replaces only metrics with the same names
+	push(jobLabels, "foo{c=\"d\"} 3", false, 2000)
+	f(`bar{a="b",job="backup"} 2
+foo{c="d",job="backup"} 3
+foo{job="backup"} NaN
+push_time_seconds{job="backup"} 2`)
+
+	// PUT replaces all the metrics
+	push(jobLabels, "baz 4", true, 3000)
+	f(`bar{a="b",job="backup"} NaN
+baz{job="backup"} 4
+foo{c="d",job="backup"} NaN
+push_time_seconds{job="backup"} 3`)
+
+	// Another group
+	instanceLabels := []prompbmarshal.Label{
+		{
+			Name:  "job",
+			Value: "backup",
+		},
+		{
+			Name:  "instance",
+			Value: "host1",
+		},
+	}
+	push(instanceLabels, "baz 5", true, 4000)
+	f(`baz{instance="host1",job="backup"} 5
+baz{job="backup"} 4
+push_time_seconds{instance="host1",job="backup"} 4
+push_time_seconds{job="backup"} 3`)
+
+	groups, series := gs.groupsCount()
+	if groups != 2 || series != 2 {
+		t.Fatalf("unexpected number of groups and series; got %d and %d; want 2 and 2", groups, series)
+	}
+
+	// Persist and restore groups
+	data, ok := gs.marshal()
+	if !ok {
+		t.Fatalf("expecting changed groups")
+	}
+	if _, ok := gs.marshal(); ok {
+		t.Fatalf("expecting unchanged groups after marshal")
+	}
+	gsRestored := newGroupsStorage()
+	if err := gsRestored.unmarshal(data); err != nil {
+		t.Fatalf("cannot unmarshal groups: %s", err)
+	}
+	if result, resultExpected := flushToString(gsRestored, 123000), flushToString(gs, 123000); result != resultExpected {
+		t.Fatalf("unexpected result after restore;\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+
+	// DELETE
+	if !gs.delete(nil, jobLabels) {
+		t.Fatalf("expecting the group to be deleted")
+	}
+	if gs.delete(nil, jobLabels) {
+		t.Fatalf("unexpected deletion of already deleted group")
+	}
+	f(`baz{instance="host1",job="backup"} 5
+baz{job="backup"} NaN
+push_time_seconds{instance="host1",job="backup"} 4
+push_time_seconds{job="backup"} NaN`)
+	f(`baz{instance="host1",job="backup"} 5
+push_time_seconds{instance="host1",job="backup"} 4`)
+}
+
+func TestGroupsStorageMultitenant(t *testing.T) {
+	gs := newGroupsStorage()
+	jobLabels := []prompbmarshal.Label{{
+		Name:  "job",
+		Value: "backup",
+	}}
+	var rows prometheus.Rows
+	rows.Unmarshal("foo 1")
+	at := &auth.Token{
+		AccountID: 1,
+		ProjectID: 2,
+	}
+	gs.push(nil, jobLabels, rows.Rows, true, 1000)
+	gs.push(at, jobLabels, rows.Rows, true, 2000)
+
+	data, _ := gs.marshal()
+	gsRestored := newGroupsStorage()
+	if err := gsRestored.unmarshal(data); err != nil {
+		t.Fatalf("cannot unmarshal groups: %s", err)
+	}
+	for _, gs := range []*groupsStorage{gs, gsRestored} {
+		tenants := make(map[string]int)
+		gs.flush(func(at *auth.Token, wr *prompbmarshal.WriteRequest) {
+			tenants[at.String()] = len(wr.Timeseries)
+		}, 123000)
+		if len(tenants) != 2 || tenants["multitenant"] != 2 || tenants["1:2"] != 2 {
+			t.Fatalf("unexpected series per tenant: %v", tenants)
+		}
+	}
+}
+
+func TestGroupsStorageUnmarshalFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		gs := newGroupsStorage()
+		if err := gs.unmarshal([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	f("foo")
+	f(`[{"tenant":"bar","labels":{"job":"x"}}]`)
+	f(`[{"labels":{"job":"x"},"series":[{"labels":{"__name__":"foo"},"value":"bar"}]}]`)
+}
+
+func flushToString(gs *groupsStorage, timestamp int64) string {
+	var lines []string
+	gs.flush(func(_ *auth.Token, wr *prompbmarshal.WriteRequest) {
+		for _, ts := range wr.Timeseries {
+			lines = append(lines, timeSeriesToString(ts, timestamp))
+		}
+	}, timestamp)
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
+
+func timeSeriesToString(ts prompbmarshal.TimeSeries, timestamp int64) string {
+	var name string
+	var labels []string
+	for _, label := range ts.Labels {
+		if label.Name == "__name__" {
+			name = label.Value
+			continue
+		}
+		labels = append(labels, label.Name+`="`+label.Value+`"`)
+	}
+	if len(ts.Samples) != 1 || ts.Samples[0].Timestamp != timestamp {
+		panic("BUG: unexpected samples")
+	}
+	v := ts.Samples[0].Value
+	valueStr := "NaN"
+	if !math.IsNaN(v) {
+		valueStr = strconv.FormatFloat(v, 'g', -1, 64)
+	} else if !decimal.IsStaleNaN(v) {
+		panic("BUG: unexpected non-stale NaN")
+	}
+	return name + "{" + strings.Join(labels, ",") + "} " + valueStr
+}
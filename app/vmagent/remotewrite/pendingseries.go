@@ -109,9 +109,10 @@ type writeRequest struct {
 
 	wr prompbmarshal.WriteRequest
 
-	tss     []prompbmarshal.TimeSeries
-	labels  []prompbmarshal.Label
-	samples []prompbmarshal.Sample
+	tss       []prompbmarshal.TimeSeries
+	labels    []prompbmarshal.Label
+	samples   []prompbmarshal.Sample
+	exemplars []prompbmarshal.Exemplar
 
 	// buf holds labels data
 	buf []byte
@@ -129,6 +130,10 @@ func (wr *writeRequest) reset() {
 	wr.labels = wr.labels[:0]
 
 	wr.samples = wr.samples[:0]
+
+	clear(wr.exemplars)
+	wr.exemplars = wr.exemplars[:0]
+
 	wr.buf = wr.buf[:0]
 }
 
@@ -201,26 +206,48 @@ func (wr *writeRequest) copyTimeSeries(dst, src *prompbmarshal.TimeSeries) {
 	labelsLen := len(wr.labels)
 	samplesDst := wr.samples
 	buf := wr.buf
-	for i := range src.Labels {
-		labelsDst = append(labelsDst, prompbmarshal.Label{})
-		dstLabel := &labelsDst[len(labelsDst)-1]
-		srcLabel := &src.Labels[i]
-
-		buf = append(buf, srcLabel.Name...)
-		dstLabel.Name = bytesutil.ToUnsafeString(buf[len(buf)-len(srcLabel.Name):])
-		buf = append(buf, srcLabel.Value...)
-		dstLabel.Value = bytesutil.ToUnsafeString(buf[len(buf)-len(srcLabel.Value):])
-	}
+	labelsDst, buf = copyLabels(labelsDst, buf, src.Labels)
 	dst.Labels = labelsDst[labelsLen:]
 
 	samplesDst = append(samplesDst, src.Samples...)
 	dst.Samples = samplesDst[len(samplesDst)-len(src.Samples):]
 
+	if len(src.Exemplars) > 0 {
+		exemplarsDst := wr.exemplars
+		exemplarsLen := len(exemplarsDst)
+		for i := range src.Exemplars {
+			srcExemplar := &src.Exemplars[i]
+			labelsLen := len(labelsDst)
+			labelsDst, buf = copyLabels(labelsDst, buf, srcExemplar.Labels)
+			exemplarsDst = append(exemplarsDst, prompbmarshal.Exemplar{
+				Labels:    labelsDst[labelsLen:],
+				Value:     srcExemplar.Value,
+				Timestamp: srcExemplar.Timestamp,
+			})
+		}
+		dst.Exemplars = exemplarsDst[exemplarsLen:]
+		wr.exemplars = exemplarsDst
+	}
+
 	wr.samples = samplesDst
 	wr.labels = labelsDst
 	wr.buf = buf
 }
 
+func copyLabels(labelsDst []prompbmarshal.Label, buf []byte, src []prompbmarshal.Label) ([]prompbmarshal.Label, []byte) {
+	for i := range src {
+		labelsDst = append(labelsDst, prompbmarshal.Label{})
+		dstLabel := &labelsDst[len(labelsDst)-1]
+		srcLabel := &src[i]
+
+		buf = append(buf, srcLabel.Name...)
+		dstLabel.Name = bytesutil.ToUnsafeString(buf[len(buf)-len(srcLabel.Name):])
+		buf = append(buf, srcLabel.Value...)
+		dstLabel.Value = bytesutil.ToUnsafeString(buf[len(buf)-len(srcLabel.Value):])
+	}
+	return labelsDst, buf
+}
+
 // marshalConcurrency limits the maximum number of concurrent workers, which marshal and compress WriteRequest.
 var marshalConcurrencyCh = make(chan struct{}, cgroup.AvailableCPUs())
 
@@ -270,6 +297,8 @@ func tryPushWriteRequest(wr *prompbmarshal.WriteRequest, tryPushBlock func(block
 			logger.Warnf("dropping a sample for metric with too long labels exceeding -remoteWrite.maxBlockSize=%d bytes", maxUnpackedBlockSize.N)
 			return true
 		}
+		// Exemplars are sent only with the first part of samples in order to avoid their duplication.
+		exemplars := wr.Timeseries[0].Exemplars
 		n := len(samples) / 2
 		wr.Timeseries[0].Samples = samples[:n]
 		if !tryPushWriteRequest(wr, tryPushBlock, isVMRemoteWrite) {
@@ -277,11 +306,14 @@ func tryPushWriteRequest(wr *prompbmarshal.WriteRequest, tryPushBlock func(block
 			return false
 		}
 		wr.Timeseries[0].Samples = samples[n:]
+		wr.Timeseries[0].Exemplars = nil
 		if !tryPushWriteRequest(wr, tryPushBlock, isVMRemoteWrite) {
 			wr.Timeseries[0].Samples = samples
+			wr.Timeseries[0].Exemplars = exemplars
 			return false
 		}
 		wr.Timeseries[0].Samples = samples
+		wr.Timeseries[0].Exemplars = exemplars
 		return true
 	}
 	timeseries := wr.Timeseries
@@ -3,6 +3,7 @@ package remotewrite
 import (
 	"fmt"
 	"math"
+	"reflect"
 	"testing"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
@@ -21,6 +22,53 @@ func TestPushWriteRequest(t *testing.T) {
 	}
 }
 
+func TestWriteRequestCopyTimeSeriesWithExemplars(t *testing.T) {
+	src := prompbmarshal.TimeSeries{
+		Labels: []prompbmarshal.Label{
+			{
+				Name:  "__name__",
+				Value: "foo_total",
+			},
+		},
+		Samples: []prompbmarshal.Sample{
+			{
+				Value:     10,
+				Timestamp: 1000,
+			},
+		},
+		Exemplars: []prompbmarshal.Exemplar{
+			{
+				Labels: []prompbmarshal.Label{
+					{
+						Name:  "trace_id",
+						Value: "abc",
+					},
+				},
+				Value:     1.5,
+				Timestamp: 900,
+			},
+		},
+	}
+
+	var wr writeRequest
+	var dst prompbmarshal.TimeSeries
+	wr.copyTimeSeries(&dst, &src)
+	if !reflect.DeepEqual(&dst, &src) {
+		t.Fatalf("unexpected time series copy;\ngot\n%+v\nwant\n%+v", &dst, &src)
+	}
+
+	// The copy mustn't refer the source labels
+	src.Exemplars[0].Labels[0].Value = "def"
+	if dst.Exemplars[0].Labels[0].Value != "abc" {
+		t.Fatalf("unexpected exemplar label value after modifying the source; got %q; want %q", dst.Exemplars[0].Labels[0].Value, "abc")
+	}
+
+	wr.reset()
+	if len(wr.exemplars) != 0 {
+		t.Fatalf("unexpected exemplars after reset: %d", len(wr.exemplars))
+	}
+}
+
 func testPushWriteRequest(t *testing.T, rowsCount, expectedBlockLenProm, expectedBlockLenVM int) {
 	f := func(isVMRemoteWrite bool, expectedBlockLen int, tolerancePrc float64) {
 		t.Helper()
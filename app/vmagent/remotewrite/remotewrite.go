@@ -370,7 +370,7 @@ func Stop() {
 	close(configReloaderStopCh)
 	configReloaderWG.Wait()
 
-	sasGlobal.Load().MustStop()
+	sasGlobal.Load().MustStopAndSaveState()
 	if deduplicatorGlobal != nil {
 		deduplicatorGlobal.MustStop()
 		deduplicatorGlobal = nil
@@ -851,7 +851,7 @@ func (rwctx *remoteWriteCtx) MustStop() {
 	// sas and deduplicator must be stopped before rwctx is closed
 	// because they can write pending series to rwctx.pss if there are any
 	sas := rwctx.sas.Swap(nil)
-	sas.MustStop()
+	sas.MustStopAndSaveState()
 
 	if rwctx.deduplicator != nil {
 		rwctx.deduplicator.MustStop()
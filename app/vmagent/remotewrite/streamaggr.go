@@ -3,6 +3,7 @@ package remotewrite
 import (
 	"flag"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
@@ -35,6 +36,9 @@ var (
 		"clients pushing data into the vmagent. See https://docs.victoriametrics.com/stream-aggregation/#ignore-aggregation-intervals-on-start")
 	streamAggrGlobalDropInputLabels = flagutil.NewArrayString("streamAggr.dropInputLabels", "An optional list of labels to drop from samples for aggregator "+
 		"before stream de-duplication and aggregation . See https://docs.victoriametrics.com/stream-aggregation/#dropping-unneeded-labels")
+	streamAggrGlobalPersistState = flag.Bool("streamAggr.persistState", false, "Whether to persist the state of aggregator with -streamAggr.config "+
+		"at -remoteWrite.tmpDataPath on graceful shutdown and restore it on the next start if the config wasn't changed. "+
+		"See https://docs.victoriametrics.com/stream-aggregation/#state-persistence")
 
 	// Per URL config
 	streamAggrConfig = flagutil.NewArrayString("remoteWrite.streamAggr.config", "Optional path to file with stream aggregation config for the corresponding -remoteWrite.url. "+
@@ -59,12 +63,18 @@ var (
 		"before stream de-duplication and aggregation with -remoteWrite.streamAggr.config and -remoteWrite.streamAggr.dedupInterval at the corresponding -remoteWrite.url. "+
 		"Multiple labels per remoteWrite.url must be delimited by '^^': -remoteWrite.streamAggr.dropInputLabels='replica^^az,replica'. "+
 		"See https://docs.victoriametrics.com/stream-aggregation/#dropping-unneeded-labels")
+	streamAggrPersistState = flagutil.NewArrayBool("remoteWrite.streamAggr.persistState", "Whether to persist the state of aggregator with -remoteWrite.streamAggr.config "+
+		"for the corresponding -remoteWrite.url at -remoteWrite.tmpDataPath on graceful shutdown and restore it on the next start if the config wasn't changed. "+
+		"See https://docs.victoriametrics.com/stream-aggregation/#state-persistence")
 )
 
+const streamAggrStateDirname = "streamaggr-state"
+
 // CheckStreamAggrConfigs checks -remoteWrite.streamAggr.config and -streamAggr.config.
 func CheckStreamAggrConfigs() error {
 	// Check global config
-	sas, err := newStreamAggrConfigGlobal()
+	// The state mustn't be restored when checking the config, since this results in the removal of the persisted state.
+	sas, err := newStreamAggrConfigGlobal(false)
 	if err != nil {
 		return err
 	}
@@ -76,7 +86,7 @@ func CheckStreamAggrConfigs() error {
 
 	pushNoop := func(_ []prompbmarshal.TimeSeries) {}
 	for idx := range *streamAggrConfig {
-		sas, err := newStreamAggrConfigPerURL(idx, pushNoop)
+		sas, err := newStreamAggrConfigPerURL(idx, pushNoop, false)
 		if err != nil {
 			return err
 		}
@@ -101,7 +111,7 @@ func reloadStreamAggrConfigGlobal() {
 	logger.Infof("reloading stream aggregation configs pointed by -streamAggr.config=%q", path)
 	metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_streamaggr_config_reloads_total{path=%q}`, path)).Inc()
 
-	sasNew, err := newStreamAggrConfigGlobal()
+	sasNew, err := newStreamAggrConfigGlobal(*streamAggrGlobalPersistState)
 	if err != nil {
 		metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_streamaggr_config_reloads_errors_total{path=%q}`, path)).Inc()
 		metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_streamaggr_config_reload_successful{path=%q}`, path)).Set(0)
@@ -123,7 +133,7 @@ func reloadStreamAggrConfigGlobal() {
 }
 
 func initStreamAggrConfigGlobal() {
-	sas, err := newStreamAggrConfigGlobal()
+	sas, err := newStreamAggrConfigGlobal(*streamAggrGlobalPersistState)
 	if err != nil {
 		logger.Fatalf("cannot initialize gloabl stream aggregators: %s", err)
 	}
@@ -195,7 +205,7 @@ func (rwctx *remoteWriteCtx) reloadStreamAggrConfig() {
 	metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_streamaggr_config_reload_success_timestamp_seconds{path=%q}`, path)).Set(fasttime.UnixTimestamp())
 }
 
-func newStreamAggrConfigGlobal() (*streamaggr.Aggregators, error) {
+func newStreamAggrConfigGlobal(persistState bool) (*streamaggr.Aggregators, error) {
 	path := *streamAggrGlobalConfig
 	if path == "" {
 		return nil, nil
@@ -208,6 +218,9 @@ func newStreamAggrConfigGlobal() (*streamaggr.Aggregators, error) {
 		IgnoreFirstIntervals: *streamAggrGlobalIgnoreFirstIntervals,
 		KeepInput:            *streamAggrGlobalKeepInput,
 	}
+	if persistState {
+		opts.StateFilePath = filepath.Join(*tmpDataPath, streamAggrStateDirname, "global.bin")
+	}
 
 	sas, err := streamaggr.LoadFromFile(path, pushToRemoteStoragesTrackDropped, opts, "global")
 	if err != nil {
@@ -217,10 +230,11 @@ func newStreamAggrConfigGlobal() (*streamaggr.Aggregators, error) {
 }
 
 func (rwctx *remoteWriteCtx) newStreamAggrConfig() (*streamaggr.Aggregators, error) {
-	return newStreamAggrConfigPerURL(rwctx.idx, rwctx.pushInternalTrackDropped)
+	persistState := streamAggrPersistState.GetOptionalArg(rwctx.idx)
+	return newStreamAggrConfigPerURL(rwctx.idx, rwctx.pushInternalTrackDropped, persistState)
 }
 
-func newStreamAggrConfigPerURL(idx int, pushFunc streamaggr.PushFunc) (*streamaggr.Aggregators, error) {
+func newStreamAggrConfigPerURL(idx int, pushFunc streamaggr.PushFunc, persistState bool) (*streamaggr.Aggregators, error) {
 	path := streamAggrConfig.GetOptionalArg(idx)
 	if path == "" {
 		return nil, nil
@@ -241,6 +255,9 @@ func newStreamAggrConfigPerURL(idx int, pushFunc streamaggr.PushFunc) (*streamag
 		IgnoreFirstIntervals: streamAggrIgnoreFirstIntervals.GetOptionalArg(idx),
 		KeepInput:            streamAggrKeepInput.GetOptionalArg(idx),
 	}
+	if persistState {
+		opts.StateFilePath = filepath.Join(*tmpDataPath, streamAggrStateDirname, fmt.Sprintf("%d.bin", idx+1))
+	}
 
 	sas, err := streamaggr.LoadFromFile(path, pushFunc, opts, alias)
 	if err != nil {
@@ -0,0 +1,46 @@
+package statsd
+
+import (
+	"io"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd/stream"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	rowsInserted  = metrics.NewCounter(`vmagent_rows_inserted_total{type="statsd"}`)
+	rowsPerInsert = metrics.NewHistogram(`vmagent_rows_per_insert{type="statsd"}`)
+)
+
+// Init starts aggregation for StatsD metrics.
+//
+// MustStop must be called when StatsD metrics are no longer accepted.
+func Init() {
+	stream.Init(pushAggregatedSeries)
+}
+
+// MustStop stops aggregation for StatsD metrics and pushes the remaining aggregated series.
+func MustStop() {
+	stream.MustStop()
+}
+
+// InsertHandler processes StatsD and DogStatsD lines.
+//
+// The received metrics are aggregated and pushed to remote storage every -statsd.flushInterval.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+// and https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/
+func InsertHandler(r io.Reader) error {
+	return stream.Parse(r)
+}
+
+func pushAggregatedSeries(tss []prompbmarshal.TimeSeries) {
+	wr := prompbmarshal.WriteRequest{
+		Timeseries: tss,
+	}
+	remotewrite.PushDropSamplesOnFailure(nil, &wr)
+	rowsInserted.Add(len(tss))
+	rowsPerInsert.Update(float64(len(tss)))
+}
@@ -259,6 +259,9 @@ func (s *VMStorage) newRequest(ctx context.Context) (*http.Request, error) {
 		logger.Panicf("BUG: unexpected error from http.NewRequest(%q): %s", s.datasourceURL, err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	// User-Agent allows prioritizing vmalert requests in vmselect.
+	// See https://docs.victoriametrics.com/#fair-query-scheduling
+	req.Header.Set("User-Agent", "vmalert")
 	if s.authCfg != nil {
 		err = s.authCfg.SetHeaders(req, true)
 		if err != nil {
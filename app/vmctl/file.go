@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/arrow"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/parquet"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+const (
+	fileFormatAuto    = "auto"
+	fileFormatParquet = "parquet"
+	fileFormatArrow   = "arrow"
+)
+
+type fileProcessor struct {
+	// files contains paths to files to import
+	files []string
+	// format is the format of files.
+	// It is detected by file extension if set to fileFormatAuto
+	format string
+	// im performs import requests
+	// for timeseries data read from files
+	im *vm.Importer
+	// cc stands for concurrency
+	// and defines number of concurrently
+	// running file readers
+	cc int
+
+	// isVerbose enables verbose output
+	isVerbose bool
+}
+
+func (fp *fileProcessor) run() error {
+	if len(fp.files) < 1 {
+		return fmt.Errorf("found no files to import")
+	}
+	formats := make([]string, len(fp.files))
+	for i, path := range fp.files {
+		format, err := getFileFormat(path, fp.format)
+		if err != nil {
+			return err
+		}
+		formats[i] = format
+	}
+	question := fmt.Sprintf("Found %d files to import. Continue?", len(fp.files))
+	if !prompt(question) {
+		return nil
+	}
+
+	bar := barpool.AddWithTemplate(fmt.Sprintf(barTpl, "Processing files"), len(fp.files))
+	if err := barpool.Start(); err != nil {
+		return err
+	}
+	defer barpool.Stop()
+
+	type fileWork struct {
+		path   string
+		format string
+	}
+	fileCh := make(chan fileWork)
+	errCh := make(chan error, fp.cc)
+	fp.im.ResetStats()
+
+	var wg sync.WaitGroup
+	wg.Add(fp.cc)
+	for i := 0; i < fp.cc; i++ {
+		go func() {
+			defer wg.Done()
+			for fw := range fileCh {
+				if err := fp.do(fw.path, fw.format); err != nil {
+					errCh <- fmt.Errorf("read failed for file %q: %s", fw.path, err)
+					return
+				}
+				bar.Increment()
+			}
+		}()
+	}
+	// any error breaks the import
+	for i, path := range fp.files {
+		select {
+		case fileErr := <-errCh:
+			close(fileCh)
+			return fmt.Errorf("file error: %s", fileErr)
+		case vmErr := <-fp.im.Errors():
+			close(fileCh)
+			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, fp.isVerbose))
+		case fileCh <- fileWork{path: path, format: formats[i]}:
+		}
+	}
+
+	close(fileCh)
+	wg.Wait()
+	// wait for all buffers to flush
+	fp.im.Close()
+	close(errCh)
+	// drain import errors channel
+	for vmErr := range fp.im.Errors() {
+		if vmErr.Err != nil {
+			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, fp.isVerbose))
+		}
+	}
+	for err := range errCh {
+		return fmt.Errorf("import process failed: %s", err)
+	}
+
+	log.Println("Import finished!")
+	log.Print(fp.im.Stats())
+	return nil
+}
+
+func (fp *fileProcessor) do(path, format string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("cannot open file: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	switch format {
+	case fileFormatParquet:
+		fi, err := f.Stat()
+		if err != nil {
+			return fmt.Errorf("cannot stat file: %w", err)
+		}
+		r, err := parquet.NewReader(f, fi.Size())
+		if err != nil {
+			return err
+		}
+		for i := 0; i < r.RowGroupsCount(); i++ {
+			err := r.ReadRowGroup(i, func(s *parquet.Series) error {
+				return fp.importSeries(s.Labels, s.Timestamps, s.Values)
+			})
+			if err != nil {
+				return fmt.Errorf("cannot read row group #%d: %w", i, err)
+			}
+		}
+		return nil
+	case fileFormatArrow:
+		r := arrow.NewReader(bufio.NewReaderSize(f, 1024*1024))
+		for {
+			ok, err := r.ReadRecordBatch(func(s *arrow.Series) error {
+				return fp.importSeries(s.Labels, s.Timestamps, s.Values)
+			})
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported file format %q", format)
+	}
+}
+
+// importSeries sends the given series to importer.
+//
+// It copies the given data, since readers re-use it for the next series.
+func (fp *fileProcessor) importSeries(labels []prompbmarshal.Label, timestamps []int64, values []float64) error {
+	var name string
+	var labelPairs []vm.LabelPair
+	for _, label := range labels {
+		if label.Name == "__name__" {
+			name = strings.Clone(label.Value)
+			continue
+		}
+		labelPairs = append(labelPairs, vm.LabelPair{
+			Name:  strings.Clone(label.Name),
+			Value: strings.Clone(label.Value),
+		})
+	}
+	if name == "" {
+		return fmt.Errorf("failed to find `__name__` label in labelset %v", labels)
+	}
+	ts := vm.TimeSeries{
+		Name:       name,
+		LabelPairs: labelPairs,
+		Timestamps: append([]int64{}, timestamps...),
+		Values:     append([]float64{}, values...),
+	}
+	return fp.im.Input(&ts)
+}
+
+// getFileFormat returns format for the file at the given path.
+//
+// The format is detected by file extension if format is set to fileFormatAuto.
+func getFileFormat(path, format string) (string, error) {
+	switch format {
+	case fileFormatParquet, fileFormatArrow:
+		return format, nil
+	case fileFormatAuto, "":
+	default:
+		return "", fmt.Errorf("unsupported --%s=%q; supported values: %s, %s, %s", fileFormat, format, fileFormatAuto, fileFormatParquet, fileFormatArrow)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet":
+		return fileFormatParquet, nil
+	case ".arrow", ".arrows":
+		return fileFormatArrow, nil
+	default:
+		return "", fmt.Errorf("cannot detect format for file %q by its extension; set it explicitly via --%s", path, fileFormat)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGetFileFormat(t *testing.T) {
+	f := func(path, format, resultExpected string) {
+		t.Helper()
+		result, err := getFileFormat(path, format)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result != resultExpected {
+			t.Fatalf("unexpected format; got %q; want %q", result, resultExpected)
+		}
+	}
+
+	f("data.parquet", fileFormatAuto, fileFormatParquet)
+	f("/tmp/DATA.PARQUET", "", fileFormatParquet)
+	f("data.arrow", fileFormatAuto, fileFormatArrow)
+	f("data.arrows", fileFormatAuto, fileFormatArrow)
+	f("data.bin", fileFormatParquet, fileFormatParquet)
+	f("data.parquet", fileFormatArrow, fileFormatArrow)
+}
+
+func TestGetFileFormatFailure(t *testing.T) {
+	f := func(path, format string) {
+		t.Helper()
+		if _, err := getFileFormat(path, format); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f("data.bin", fileFormatAuto)
+	f("data", "")
+	f("data.parquet", "csv")
+}
@@ -376,6 +376,32 @@ var (
 	}
 )
 
+const (
+	filePath        = "file-path"
+	fileFormat      = "file-format"
+	fileConcurrency = "file-concurrency"
+)
+
+var (
+	fileFlags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:     filePath,
+			Usage:    "Path to Parquet or Arrow IPC stream file exported from VictoriaMetrics via /api/v1/export/parquet or /api/v1/export/arrow. Multiple files can be set via multiple flags",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  fileFormat,
+			Usage: "Format of files set via --file-path. Supported values: auto, parquet, arrow. The format is detected by file extension (.parquet, .arrow or .arrows) when set to auto",
+			Value: fileFormatAuto,
+		},
+		&cli.IntFlag{
+			Name:  fileConcurrency,
+			Usage: "Number of concurrently running file readers",
+			Value: 1,
+		},
+	}
+)
+
 const (
 	promSnapshot         = "prom-snapshot"
 	promConcurrency      = "prom-concurrency"
@@ -264,6 +264,34 @@ func main() {
 					return pp.run()
 				},
 			},
+			{
+				Name:   "file",
+				Usage:  "Import time series from Parquet or Arrow IPC stream files exported from VictoriaMetrics",
+				Flags:  mergeFlags(globalFlags, fileFlags, vmFlags),
+				Before: beforeFn,
+				Action: func(c *cli.Context) error {
+					fmt.Println("File import mode")
+
+					vmCfg, err := initConfigVM(c)
+					if err != nil {
+						return fmt.Errorf("failed to init VM configuration: %s", err)
+					}
+
+					importer, err = vm.NewImporter(ctx, vmCfg)
+					if err != nil {
+						return fmt.Errorf("failed to create VM importer: %s", err)
+					}
+
+					fp := fileProcessor{
+						files:     c.StringSlice(filePath),
+						format:    c.String(fileFormat),
+						im:        importer,
+						cc:        c.Int(fileConcurrency),
+						isVerbose: c.Bool(globalVerbose),
+					}
+					return fp.run()
+				},
+			},
 			{
 				Name:   "vm-native",
 				Usage:  "Migrate time series between VictoriaMetrics installations via native binary format",
@@ -151,7 +151,7 @@ func MustStopStreamAggr() {
 	saCfgReloaderWG.Wait()
 
 	sas := sasGlobal.Swap(nil)
-	sas.MustStop()
+	sas.MustStopAndSaveState()
 
 	if deduplicator != nil {
 		deduplicator.MustStop()
@@ -0,0 +1,79 @@
+package statsd
+
+import (
+	"io"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd/stream"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="statsd"}`)
+	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="statsd"}`)
+)
+
+// Init starts aggregation for StatsD metrics.
+//
+// MustStop must be called when StatsD metrics are no longer accepted.
+func Init() {
+	stream.Init(insertAggregatedSeries)
+}
+
+// MustStop stops aggregation for StatsD metrics and stores the remaining aggregated series.
+func MustStop() {
+	stream.MustStop()
+}
+
+// InsertHandler processes StatsD and DogStatsD lines.
+//
+// The received metrics are aggregated and stored every -statsd.flushInterval.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+// and https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/
+func InsertHandler(r io.Reader) error {
+	return stream.Parse(r)
+}
+
+func insertAggregatedSeries(tss []prompbmarshal.TimeSeries) {
+	if err := insertRows(tss); err != nil {
+		logger.Errorf("cannot store aggregated StatsD metrics: %s", err)
+	}
+}
+
+func insertRows(tss []prompbmarshal.TimeSeries) error {
+	ctx := common.GetInsertCtx()
+	defer common.PutInsertCtx(ctx)
+
+	ctx.Reset(len(tss))
+	hasRelabeling := relabel.HasRelabeling()
+	for i := range tss {
+		ts := &tss[i]
+		ctx.Labels = ctx.Labels[:0]
+		for _, label := range ts.Labels {
+			name := label.Name
+			if name == "__name__" {
+				name = ""
+			}
+			ctx.AddLabel(name, label.Value)
+		}
+		if hasRelabeling {
+			ctx.ApplyRelabeling()
+		}
+		if len(ctx.Labels) == 0 {
+			// Skip metric without labels.
+			continue
+		}
+		ctx.SortLabelsIfNeeded()
+		sample := &ts.Samples[0]
+		if err := ctx.WriteDataPoint(nil, ctx.Labels, sample.Timestamp, sample.Value); err != nil {
+			return err
+		}
+	}
+	rowsInserted.Add(len(tss))
+	rowsPerInsert.Update(float64(len(tss)))
+	return ctx.FlushBufs()
+}
@@ -0,0 +1,77 @@
+package zabbix
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	parserCommon "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/zabbix"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/zabbix/stream"
+)
+
+var (
+	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="zabbix"}`)
+	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="zabbix"}`)
+)
+
+// InsertHandlerForHTTP processes remote write for Zabbix sender POST /zabbix/api/v1/sender request.
+//
+// It writes Zabbix sender response to w on success.
+func InsertHandlerForHTTP(w http.ResponseWriter, req *http.Request) error {
+	extraLabels, err := parserCommon.GetExtraLabels(req)
+	if err != nil {
+		return err
+	}
+	startTime := time.Now()
+	processed := 0
+	isGzipped := req.Header.Get("Content-Encoding") == "gzip"
+	failed, err := stream.Parse(req.Body, isGzipped, func(rows []zabbix.Row) error {
+		processed = len(rows)
+		return insertRows(rows, extraLabels)
+	})
+	if err != nil {
+		return err
+	}
+	stream.WriteSuccessResponse(w, processed, failed, time.Since(startTime))
+	return nil
+}
+
+func insertRows(rows []zabbix.Row, extraLabels []prompbmarshal.Label) error {
+	ctx := common.GetInsertCtx()
+	defer common.PutInsertCtx(ctx)
+
+	ctx.Reset(len(rows))
+	hasRelabeling := relabel.HasRelabeling()
+	for i := range rows {
+		r := &rows[i]
+		ctx.Labels = ctx.Labels[:0]
+		ctx.AddLabel("", r.Metric)
+		for j := range r.Tags {
+			tag := &r.Tags[j]
+			ctx.AddLabel(tag.Key, tag.Value)
+		}
+		for j := range extraLabels {
+			label := &extraLabels[j]
+			ctx.AddLabel(label.Name, label.Value)
+		}
+		if hasRelabeling {
+			ctx.ApplyRelabeling()
+		}
+		if len(ctx.Labels) == 0 {
+			// Skip metric without labels.
+			continue
+		}
+		ctx.SortLabelsIfNeeded()
+		if err := ctx.WriteDataPoint(nil, ctx.Labels, r.Timestamp, r.Value); err != nil {
+			return err
+		}
+	}
+	rowsInserted.Add(len(rows))
+	rowsPerInsert.Update(float64(len(rows)))
+	return ctx.FlushBufs()
+}
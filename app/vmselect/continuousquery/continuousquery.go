@@ -0,0 +1,458 @@
+package continuousquery
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/prometheus"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+var (
+	configPath = flag.String("continuousQueries.config", "", "Optional path to a file with continuous queries, which are evaluated inside VictoriaMetrics "+
+		"as new data arrives. Results are written directly to the storage. The path can point either to local file or to http url. "+
+		"See https://docs.victoriametrics.com/#continuous-queries")
+	defaultInterval = flag.Duration("continuousQueries.interval", time.Minute, "The default evaluation interval for continuous queries "+
+		"from -continuousQueries.config. It can be overridden with `interval` option per each query")
+	evalDelay = flag.Duration("continuousQueries.evalDelay", 30*time.Second, "The delay for evaluating continuous queries from -continuousQueries.config. "+
+		"It must cover the delay for ingesting the data into VictoriaMetrics, so the results are calculated over the complete data")
+	defaultBackfill = flag.Duration("continuousQueries.backfill", 24*time.Hour, "The default duration for calculating results for continuous queries "+
+		"on historical data on the first start. It can be overridden with `backfill` option per each query")
+	evalTimeout = flag.Duration("continuousQueries.evalTimeout", 30*time.Second, "The timeout for a single evaluation of continuous query "+
+		"from -continuousQueries.config")
+)
+
+// maxPointsPerEval is the maximum number of points per series, which can be calculated by a single evaluation of a continuous query.
+//
+// Bigger time ranges, e.g. during backfilling, are split into multiple evaluations.
+const maxPointsPerEval = 1000
+
+// Config represents -continuousQueries.config file.
+type Config struct {
+	Queries []Query `yaml:"queries"`
+}
+
+// Query is a continuous query, which is evaluated every Interval and whose results are written to the storage under the Record name.
+type Query struct {
+	// Record is the metric name for the query results.
+	Record string `yaml:"record"`
+
+	// Expr is the query to evaluate.
+	//
+	// It must be an aggregate function over rollup function over series selector such as `sum(rate(foo[5m])) by (job)`.
+	Expr string `yaml:"expr"`
+
+	// Interval is the interval between points of the query results.
+	Interval *promutils.Duration `yaml:"interval,omitempty"`
+
+	// Backfill is the duration for calculating the results on historical data when the query is evaluated for the first time.
+	Backfill *promutils.Duration `yaml:"backfill,omitempty"`
+
+	// Labels are additional labels to add to the query results.
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+type parsedQuery struct {
+	record   string
+	expr     string
+	interval int64
+	backfill int64
+	labels   []prompb.Label
+
+	// key uniquely identifies the query in the state file.
+	key string
+}
+
+var (
+	evaluations      = metrics.NewCounter(`vm_continuous_queries_evaluations_total`)
+	evaluationErrors = metrics.NewCounter(`vm_continuous_queries_evaluation_errors_total`)
+	samplesWritten   = metrics.NewCounter(`vm_continuous_queries_samples_written_total`)
+)
+
+var (
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	states *stateStorage
+)
+
+// Init starts evaluating continuous queries from -continuousQueries.config.
+//
+// It must be called after vmstorage and vmselect initialization.
+func Init() {
+	if len(*configPath) == 0 {
+		return
+	}
+	pqs, err := loadConfig()
+	if err != nil {
+		logger.Fatalf("cannot load continuous queries: %s", err)
+	}
+	states = mustOpenStateStorage(filepath.Join(*vmstorage.DataPath, "continuous_queries"))
+	states.removeUnknownKeys(pqs)
+	stopCh = make(chan struct{})
+	for _, pq := range pqs {
+		wg.Add(1)
+		go func(pq *parsedQuery) {
+			defer wg.Done()
+			pq.run()
+		}(pq)
+	}
+	logger.Infof("started evaluating %d continuous queries from -continuousQueries.config=%q", len(pqs), *configPath)
+}
+
+// Stop stops evaluating continuous queries.
+//
+// It must be called before stopping vmstorage.
+func Stop() {
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	wg.Wait()
+	stopCh = nil
+}
+
+// CheckConfig checks -continuousQueries.config.
+func CheckConfig() error {
+	if len(*configPath) == 0 {
+		return nil
+	}
+	_, err := loadConfig()
+	return err
+}
+
+func loadConfig() ([]*parsedQuery, error) {
+	data, err := fscore.ReadFileOrHTTP(*configPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read -continuousQueries.config=%q: %w", *configPath, err)
+	}
+	pqs, err := parseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse -continuousQueries.config=%q: %w", *configPath, err)
+	}
+	return pqs, nil
+}
+
+func parseConfig(data []byte) ([]*parsedQuery, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	var pqs []*parsedQuery
+	keys := make(map[string]bool, len(cfg.Queries))
+	for i := range cfg.Queries {
+		q := &cfg.Queries[i]
+		pq, err := q.parse()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse query #%d with record=%q: %w", i+1, q.Record, err)
+		}
+		if keys[pq.key] {
+			return nil, fmt.Errorf("duplicate query with record=%q and expr=%q", q.Record, q.Expr)
+		}
+		keys[pq.key] = true
+		pqs = append(pqs, pq)
+	}
+	return pqs, nil
+}
+
+func (q *Query) parse() (*parsedQuery, error) {
+	if q.Record == "" {
+		return nil, fmt.Errorf("missing `record`")
+	}
+	if q.Expr == "" {
+		return nil, fmt.Errorf("missing `expr`")
+	}
+	e, err := metricsql.Parse(q.Expr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `expr`: %w", err)
+	}
+	if !promql.IsIncrementalAggrExpr(e) {
+		return nil, fmt.Errorf("unsupported `expr`: %q; it must be an aggregate function over rollup function over series selector such as `sum(rate(foo[5m])) by (job)`; "+
+			"supported aggregate functions: any, avg, count, geomean, group, max, min, sum, sum2", q.Expr)
+	}
+	interval := q.Interval.Duration()
+	if interval <= 0 {
+		interval = *defaultInterval
+	}
+	if interval < time.Second {
+		return nil, fmt.Errorf("too small `interval`: %s; it must be at least 1s", interval)
+	}
+	backfill := *defaultBackfill
+	if q.Backfill != nil {
+		backfill = q.Backfill.Duration()
+	}
+	if backfill < 0 {
+		return nil, fmt.Errorf("`backfill` cannot be negative; got %s", backfill)
+	}
+	pq := &parsedQuery{
+		record:   q.Record,
+		expr:     string(e.AppendString(nil)),
+		interval: interval.Milliseconds(),
+		backfill: backfill.Milliseconds(),
+	}
+	for name, value := range q.Labels {
+		if name == "__name__" {
+			return nil, fmt.Errorf("`labels` cannot contain __name__; use `record` instead")
+		}
+		pq.labels = append(pq.labels, prompb.Label{
+			Name:  name,
+			Value: value,
+		})
+	}
+	sort.Slice(pq.labels, func(i, j int) bool {
+		return pq.labels[i].Name < pq.labels[j].Name
+	})
+	pq.key = pq.getKey()
+	return pq, nil
+}
+
+func (pq *parsedQuery) getKey() string {
+	var b []byte
+	b = append(b, pq.record...)
+	b = append(b, '{')
+	for i, label := range pq.labels {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		b = append(b, label.Name...)
+		b = append(b, '=')
+		b = append(b, label.Value...)
+	}
+	b = append(b, "} "...)
+	b = append(b, pq.expr...)
+	return fmt.Sprintf("%s interval=%dms", b, pq.interval)
+}
+
+func (pq *parsedQuery) run() {
+	// Evaluate the query immediately after the start in order to backfill the missing results.
+	pq.evalPending()
+
+	ticker := time.NewTicker(time.Duration(pq.interval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			pq.evalPending()
+		}
+	}
+}
+
+// evalPending calculates query results, which weren't calculated yet.
+func (pq *parsedQuery) evalPending() {
+	lastTimestamp, ok := states.get(pq.key)
+	maxTimestamp := time.Now().Add(-*evalDelay).UnixMilli()
+	for _, tr := range getEvalTimeRanges(lastTimestamp, ok, maxTimestamp, pq.interval, pq.backfill) {
+		select {
+		case <-stopCh:
+			return
+		default:
+		}
+		evaluations.Inc()
+		if err := pq.eval(tr.start, tr.end); err != nil {
+			evaluationErrors.Inc()
+			logger.Errorf("cannot evaluate continuous query with record=%q on the time range [%d..%d]: %s; the evaluation will be retried in %dms",
+				pq.record, tr.start, tr.end, err, pq.interval)
+			return
+		}
+		states.set(pq.key, tr.end)
+	}
+}
+
+type evalTimeRange struct {
+	start int64
+	end   int64
+}
+
+// getEvalTimeRanges returns time ranges for calculating missing query results up to maxTimestamp.
+//
+// lastTimestamp is the timestamp for the last calculated result if hasLastTimestamp is set.
+// Otherwise the results are calculated for the last backfill duration.
+// Every returned time range contains up to maxPointsPerEval points aligned to interval.
+func getEvalTimeRanges(lastTimestamp int64, hasLastTimestamp bool, maxTimestamp, interval, backfill int64) []evalTimeRange {
+	end := maxTimestamp - maxTimestamp%interval
+	var start int64
+	if hasLastTimestamp {
+		start = lastTimestamp + interval
+	} else {
+		start = end - backfill
+		start -= start % interval
+	}
+	var trs []evalTimeRange
+	for start <= end {
+		trEnd := start + (maxPointsPerEval-1)*interval
+		if trEnd > end {
+			trEnd = end
+		}
+		trs = append(trs, evalTimeRange{
+			start: start,
+			end:   trEnd,
+		})
+		start = trEnd + interval
+	}
+	return trs
+}
+
+// eval calculates query results on the [start..end] time range and writes them to the storage.
+func (pq *parsedQuery) eval(start, end int64) error {
+	ec := &promql.EvalConfig{
+		Start:              start,
+		End:                end,
+		Step:               pq.interval,
+		MaxPointsPerSeries: maxPointsPerEval,
+		MaxSeries:          prometheus.GetMaxUniqueTimeSeries(),
+		Deadline:           searchutils.NewDeadline(time.Now(), *evalTimeout, "-continuousQueries.evalTimeout"),
+		RoundDigits:        100,
+		GetRequestURI: func() string {
+			return fmt.Sprintf("continuous query with record=%q", pq.record)
+		},
+	}
+	result, err := promql.Exec(nil, ec, pq.expr, false)
+	if err != nil {
+		return err
+	}
+	mrs := pq.getMetricRows(result)
+	if len(mrs) == 0 {
+		return nil
+	}
+	if err := vmstorage.AddRows(mrs); err != nil {
+		return fmt.Errorf("cannot write results: %w", err)
+	}
+	samplesWritten.Add(len(mrs))
+	return nil
+}
+
+// getMetricRows converts query results to rows for writing to the storage.
+func (pq *parsedQuery) getMetricRows(result []netstorage.Result) []storage.MetricRow {
+	var mrs []storage.MetricRow
+	var labels []prompb.Label
+	for i := range result {
+		r := &result[i]
+		labels = labels[:0]
+		labels = append(labels, prompb.Label{
+			Name:  "__name__",
+			Value: pq.record,
+		})
+		for _, tag := range r.MetricName.Tags {
+			if hasLabel(pq.labels, string(tag.Key)) {
+				// Labels from the config override labels from the query results.
+				continue
+			}
+			labels = append(labels, prompb.Label{
+				Name:  string(tag.Key),
+				Value: string(tag.Value),
+			})
+		}
+		labels = append(labels, pq.labels...)
+		metricNameRaw := storage.MarshalMetricNameRaw(nil, labels)
+		for j, v := range r.Values {
+			if math.IsNaN(v) {
+				continue
+			}
+			mrs = append(mrs, storage.MetricRow{
+				MetricNameRaw: metricNameRaw,
+				Timestamp:     r.Timestamps[j],
+				Value:         v,
+			})
+		}
+	}
+	return mrs
+}
+
+func hasLabel(labels []prompb.Label, name string) bool {
+	for _, label := range labels {
+		if label.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stateStorage holds timestamps for the last calculated results per each continuous query.
+//
+// The state is persisted to disk, so the evaluation continues from the last calculated result after restart.
+type stateStorage struct {
+	path string
+
+	mu sync.Mutex
+	m  map[string]int64
+}
+
+func mustOpenStateStorage(dir string) *stateStorage {
+	fs.MustMkdirIfNotExist(dir)
+	ss := &stateStorage{
+		path: filepath.Join(dir, "state.json"),
+		m:    make(map[string]int64),
+	}
+	if !fs.IsPathExist(ss.path) {
+		return ss
+	}
+	data, err := os.ReadFile(ss.path)
+	if err != nil {
+		logger.Panicf("FATAL: cannot read continuous queries state: %s", err)
+	}
+	if err := json.Unmarshal(data, &ss.m); err != nil {
+		logger.Errorf("cannot parse continuous queries state from %q: %s; the state will be reset", ss.path, err)
+		ss.m = make(map[string]int64)
+	}
+	return ss
+}
+
+// removeUnknownKeys removes state for queries missing in pqs.
+//
+// This guarantees that the results are backfilled if the removed query is added again to -continuousQueries.config.
+func (ss *stateStorage) removeUnknownKeys(pqs []*parsedQuery) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	keys := make(map[string]bool, len(pqs))
+	for _, pq := range pqs {
+		keys[pq.key] = true
+	}
+	for key := range ss.m {
+		if !keys[key] {
+			delete(ss.m, key)
+		}
+	}
+}
+
+func (ss *stateStorage) get(key string) (int64, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ts, ok := ss.m[key]
+	return ts, ok
+}
+
+func (ss *stateStorage) set(key string, ts int64) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	ss.m[key] = ts
+	data, err := json.Marshal(ss.m)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal continuous queries state: %s", err)
+	}
+	fs.MustWriteAtomic(ss.path, data, true)
+}
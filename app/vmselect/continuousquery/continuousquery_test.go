@@ -0,0 +1,199 @@
+package continuousquery
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestParseConfigFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseConfig([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error for config:\n%s", data)
+		}
+	}
+
+	// invalid yaml
+	f(`foobar`)
+
+	// unknown field
+	f(`
+queries:
+- record: foo
+  expr: sum(rate(bar[5m]))
+  foo: bar
+`)
+
+	// missing record
+	f(`
+queries:
+- expr: sum(rate(bar[5m]))
+`)
+
+	// missing expr
+	f(`
+queries:
+- record: foo
+`)
+
+	// invalid expr
+	f(`
+queries:
+- record: foo
+  expr: sum(
+`)
+
+	// expr without incremental aggregation
+	f(`
+queries:
+- record: foo
+  expr: rate(bar[5m])
+`)
+	f(`
+queries:
+- record: foo
+  expr: sum(rate(bar[5m])) * 2
+`)
+	f(`
+queries:
+- record: foo
+  expr: quantile(0.5, rate(bar[5m]))
+`)
+
+	// too small interval
+	f(`
+queries:
+- record: foo
+  expr: sum(rate(bar[5m]))
+  interval: 100ms
+`)
+
+	// __name__ in labels
+	f(`
+queries:
+- record: foo
+  expr: sum(rate(bar[5m]))
+  labels:
+    __name__: baz
+`)
+
+	// duplicate queries
+	f(`
+queries:
+- record: foo
+  expr: sum(rate(bar[5m]))
+- record: foo
+  expr: sum(rate(bar[5m]))
+`)
+}
+
+func TestParseConfigSuccess(t *testing.T) {
+	data := `
+queries:
+- record: job:http_requests:rate5m
+  expr: sum(rate(http_requests_total[5m])) by (job)
+  interval: 30s
+  backfill: 7d
+  labels:
+    source: cq
+    env: prod
+- record: instance:cpu:max
+  expr: max by (instance) (rate(node_cpu_seconds_total[1m]))
+`
+	pqs, err := parseConfig([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(pqs) != 2 {
+		t.Fatalf("unexpected number of queries; got %d; want 2", len(pqs))
+	}
+	pq := pqs[0]
+	if pq.expr != `sum(rate(http_requests_total[5m])) by(job)` {
+		t.Fatalf("unexpected expr: %q", pq.expr)
+	}
+	if pq.interval != 30e3 || pq.backfill != 7*24*3600e3 {
+		t.Fatalf("unexpected interval=%d or backfill=%d", pq.interval, pq.backfill)
+	}
+	keyExpected := `job:http_requests:rate5m{env=prod,source=cq} sum(rate(http_requests_total[5m])) by(job) interval=30000ms`
+	if pq.key != keyExpected {
+		t.Fatalf("unexpected key;\ngot\n%s\nwant\n%s", pq.key, keyExpected)
+	}
+	pq = pqs[1]
+	if pq.interval != defaultInterval.Milliseconds() || pq.backfill != defaultBackfill.Milliseconds() {
+		t.Fatalf("unexpected default interval=%d or backfill=%d", pq.interval, pq.backfill)
+	}
+}
+
+func TestGetEvalTimeRanges(t *testing.T) {
+	f := func(lastTimestamp int64, hasLastTimestamp bool, maxTimestamp, interval, backfill int64, trsExpected []evalTimeRange) {
+		t.Helper()
+		trs := getEvalTimeRanges(lastTimestamp, hasLastTimestamp, maxTimestamp, interval, backfill)
+		if !reflect.DeepEqual(trs, trsExpected) {
+			t.Fatalf("unexpected time ranges;\ngot\n%v\nwant\n%v", trs, trsExpected)
+		}
+	}
+
+	// the first evaluation without backfill
+	f(0, false, 12345e3, 60e3, 0, []evalTimeRange{
+		{start: 12300e3, end: 12300e3},
+	})
+
+	// the first evaluation with backfill
+	f(0, false, 12345e3, 60e3, 600e3, []evalTimeRange{
+		{start: 11700e3, end: 12300e3},
+	})
+
+	// backfill exceeding maxPointsPerEval
+	f(0, false, 3000*60e3+5, 60e3, 1500*60e3, []evalTimeRange{
+		{start: 1500 * 60e3, end: 2499 * 60e3},
+		{start: 2500 * 60e3, end: 3000 * 60e3},
+	})
+
+	// the next evaluation
+	f(12300e3, true, 12425e3, 60e3, 600e3, []evalTimeRange{
+		{start: 12360e3, end: 12420e3},
+	})
+
+	// nothing to evaluate
+	f(12300e3, true, 12345e3, 60e3, 600e3, nil)
+}
+
+func TestGetMetricRows(t *testing.T) {
+	pq := &parsedQuery{
+		record: "job:foo:sum",
+		labels: []prompb.Label{
+			{
+				Name:  "env",
+				Value: "prod",
+			},
+		},
+	}
+
+	var r netstorage.Result
+	r.MetricName.AddTag("env", "dev")
+	r.MetricName.AddTag("job", "x")
+	r.Timestamps = []int64{1000, 2000, 3000}
+	r.Values = []float64{1, math.NaN(), 3}
+
+	mrs := pq.getMetricRows([]netstorage.Result{r})
+	if len(mrs) != 2 {
+		t.Fatalf("unexpected number of rows; got %d; want 2", len(mrs))
+	}
+	for i, mr := range mrs {
+		var mn storage.MetricName
+		if err := mn.UnmarshalRaw(mr.MetricNameRaw); err != nil {
+			t.Fatalf("cannot unmarshal metric name: %s", err)
+		}
+		if s := mn.String(); s != `job:foo:sum{env="prod",job="x"}` {
+			t.Fatalf("unexpected metric name for row #%d: %s", i, s)
+		}
+	}
+	if mrs[0].Timestamp != 1000 || mrs[0].Value != 1 || mrs[1].Timestamp != 3000 || mrs[1].Value != 3 {
+		t.Fatalf("unexpected rows: %v", mrs)
+	}
+}
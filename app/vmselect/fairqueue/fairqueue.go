@@ -0,0 +1,502 @@
+package fairqueue
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	keySource = flag.String("search.fairQueue.key", "", "Optional source of the key for fair scheduling of search requests when -search.maxConcurrentRequests limit is reached. "+
+		"Supported values: user - basic auth username; remoteAddr - client address; header:<name> - the value of the given request header, "+
+		"for example, header:X-Scope-OrgID for per-tenant scheduling. By default requests are executed in FIFO order. "+
+		"See https://docs.victoriametrics.com/#fair-query-scheduling")
+	keyShares = flagutil.NewArrayString("search.fairQueue.shares", "Optional concurrency shares for fair query scheduling in the form key:share. "+
+		"For example, -search.fairQueue.shares=team-a:3 allows requests with team-a key to get 3x bigger share of -search.maxConcurrentRequests "+
+		"than requests with keys without explicitly set share. The default share is 1. See also -search.fairQueue.key")
+	highPriorityUserAgents = flag.String("search.fairQueue.highPriorityUserAgents", "", "Optional comma-separated list of User-Agent prefixes for requests, "+
+		"which must be executed before the remaining queued requests when fair query scheduling is enabled via -search.fairQueue.key. "+
+		"For example, -search.fairQueue.highPriorityUserAgents=vmalert prioritizes requests from vmalert. "+
+		"Note that User-Agent header is set by clients, so enable this only if untrusted clients cannot reach VictoriaMetrics directly")
+	allowPriorityHeader = flag.Bool("search.fairQueue.allowPriorityHeader", false, "Whether to allow setting the priority class for requests via X-Query-Priority header "+
+		"when fair query scheduling is enabled via -search.fairQueue.key. Note that the header is set by clients, "+
+		"so enable this only if untrusted clients cannot reach VictoriaMetrics directly")
+	maxMetricKeys = flag.Int("search.fairQueue.maxMetricKeys", 100, "The maximum number of fair queue keys with individual metrics at /metrics page. "+
+		"Metrics for the remaining keys are exposed with key=\"__other__\" label. Keys with explicitly set -search.fairQueue.shares always have individual metrics")
+)
+
+// PriorityHeader is the name of request header, which can be used for setting the priority class for the request
+// if -search.fairQueue.allowPriorityHeader is set.
+//
+// Supported values are high, normal and low.
+const PriorityHeader = "X-Query-Priority"
+
+// Priority is the priority class for the request.
+type Priority int
+
+const (
+	// PriorityHigh is the priority class for requests, which must be executed before the remaining requests.
+	PriorityHigh Priority = iota
+
+	// PriorityNormal is the default priority class.
+	PriorityNormal
+
+	// PriorityLow is the priority class for requests, which are executed only if there are no other queued requests.
+	PriorityLow
+
+	prioritiesCount
+)
+
+// String returns string representation of p.
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityNormal:
+		return "normal"
+	case PriorityLow:
+		return "low"
+	default:
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+}
+
+// ErrTimeout is returned from Acquire if the request couldn't be scheduled for execution during the given timeout.
+var ErrTimeout = errors.New("timeout exceeded while waiting in the fair queue")
+
+var (
+	defaultScheduler *Scheduler
+	initOnce         sync.Once
+)
+
+// Enabled returns true if fair query scheduling is enabled via -search.fairQueue.key.
+func Enabled() bool {
+	return *keySource != ""
+}
+
+// Init initializes fair query scheduling for the given maxConcurrentRequests.
+//
+// Init must be called only if Enabled returns true.
+func Init(maxConcurrentRequests int) {
+	initOnce.Do(func() {
+		if err := validateKeySource(*keySource); err != nil {
+			logger.Fatalf("invalid -search.fairQueue.key: %s", err)
+		}
+		shares, err := parseShares(*keyShares)
+		if err != nil {
+			logger.Fatalf("invalid -search.fairQueue.shares: %s", err)
+		}
+		defaultScheduler = NewScheduler(maxConcurrentRequests, shares, *maxMetricKeys)
+		metrics.RegisterMetricsWriter(defaultScheduler.writeMetrics)
+		logger.Infof("enabled fair query scheduling with -search.fairQueue.key=%q", *keySource)
+	})
+}
+
+// GetRequestKey returns the fair queue key and the priority class for r.
+func GetRequestKey(r *http.Request) (string, Priority) {
+	return getKey(r, *keySource), getPriority(r, *allowPriorityHeader, *highPriorityUserAgents)
+}
+
+// Acquire waits until the request with the given key and priority can be executed.
+//
+// It returns ErrTimeout if the request couldn't be scheduled during the given timeout.
+// It returns ctx.Err() if ctx is canceled while waiting. Release must be called after the request execution
+// if Acquire returns nil error.
+func Acquire(ctx context.Context, key string, p Priority, timeout time.Duration) (bool, error) {
+	return defaultScheduler.Acquire(ctx, key, p, timeout)
+}
+
+// Release must be called when the request scheduled via Acquire is finished.
+func Release(key string) {
+	defaultScheduler.Release(key)
+}
+
+// Capacity returns the maximum number of concurrently executed requests.
+func Capacity() int {
+	return defaultScheduler.maxConcurrency
+}
+
+// Concurrency returns the number of currently executed requests.
+func Concurrency() int {
+	return defaultScheduler.Concurrency()
+}
+
+func validateKeySource(s string) error {
+	switch {
+	case s == "user", s == "remoteAddr":
+		return nil
+	case strings.HasPrefix(s, "header:"):
+		if len(s) == len("header:") {
+			return fmt.Errorf("missing header name in %q", s)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported value %q; supported values: user, remoteAddr, header:<name>", s)
+	}
+}
+
+func getKey(r *http.Request, keySource string) string {
+	switch {
+	case keySource == "user":
+		username, _, _ := r.BasicAuth()
+		return username
+	case keySource == "remoteAddr":
+		// Use only the client host, since the port is distinct per each connection.
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	case strings.HasPrefix(keySource, "header:"):
+		return r.Header.Get(keySource[len("header:"):])
+	default:
+		return ""
+	}
+}
+
+func getPriority(r *http.Request, allowPriorityHeader bool, highPriorityUserAgents string) Priority {
+	if allowPriorityHeader {
+		switch strings.ToLower(r.Header.Get(PriorityHeader)) {
+		case "high":
+			return PriorityHigh
+		case "normal":
+			return PriorityNormal
+		case "low":
+			return PriorityLow
+		}
+	}
+	userAgent := r.Header.Get("User-Agent")
+	if userAgent == "" {
+		return PriorityNormal
+	}
+	for _, prefix := range strings.Split(highPriorityUserAgents, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix != "" && strings.HasPrefix(userAgent, prefix) {
+			return PriorityHigh
+		}
+	}
+	return PriorityNormal
+}
+
+func parseShares(a []string) (map[string]int, error) {
+	shares := make(map[string]int, len(a))
+	for _, s := range a {
+		n := strings.LastIndexByte(s, ':')
+		if n < 0 {
+			return nil, fmt.Errorf("missing ':' in %q; expecting key:share", s)
+		}
+		key := s[:n]
+		share, err := strconv.Atoi(s[n+1:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse share in %q: %w", s, err)
+		}
+		if share <= 0 {
+			return nil, fmt.Errorf("share must be positive in %q", s)
+		}
+		shares[key] = share
+	}
+	return shares, nil
+}
+
+// Scheduler limits the number of concurrently executed requests and schedules queued requests
+// in a weighted fair manner across keys.
+//
+// Queued requests with higher priority class are always scheduled before requests with lower priority class.
+// Queued requests with the same priority class are scheduled for the key with the smallest number of currently executed requests
+// relative to the key share.
+type Scheduler struct {
+	maxConcurrency int
+	shares         map[string]int
+	maxMetricKeys  int
+
+	mu          sync.Mutex
+	concurrency int
+	keys        map[string]*keyState
+	nextSeq     uint64
+
+	// queued is the total number of queued requests across all the keys.
+	queued int
+
+	// metricKeys contains keys with individual metrics. The number of such keys is limited by maxMetricKeys
+	// in order to avoid unbounded metrics cardinality, since keys are obtained from client requests.
+	metricKeys map[string]struct{}
+}
+
+type keyState struct {
+	key         string
+	metricKey   string
+	share       int
+	concurrency int
+	queues      [prioritiesCount][]*waiter
+}
+
+type waiter struct {
+	ch      chan struct{}
+	seq     uint64
+	granted bool
+}
+
+// otherMetricKey is the key label value for metrics of keys exceeding maxMetricKeys.
+const otherMetricKey = "__other__"
+
+// NewScheduler returns new Scheduler, which limits the number of concurrently executed requests to maxConcurrency.
+//
+// shares contains per-key concurrency shares. Keys without shares get the share 1.
+// maxMetricKeys limits the number of keys without shares, which get individual metrics.
+func NewScheduler(maxConcurrency int, shares map[string]int, maxMetricKeys int) *Scheduler {
+	s := &Scheduler{
+		maxConcurrency: maxConcurrency,
+		shares:         shares,
+		maxMetricKeys:  maxMetricKeys,
+		keys:           make(map[string]*keyState),
+		metricKeys:     make(map[string]struct{}),
+	}
+	return s
+}
+
+// Acquire waits until the request with the given key and priority can be executed.
+//
+// It returns true if the request has been queued before the execution.
+// It returns ErrTimeout if the request couldn't be scheduled during the given timeout.
+// It returns ctx.Err() if ctx is canceled while waiting. Release must be called after the request execution
+// if Acquire returns nil error.
+func (s *Scheduler) Acquire(ctx context.Context, key string, p Priority, timeout time.Duration) (bool, error) {
+	if p < 0 || p >= prioritiesCount {
+		p = PriorityNormal
+	}
+	s.mu.Lock()
+	ks := s.getKeyStateLocked(key)
+	metricKey := ks.metricKey
+	getRequestsCounter(metricKey, p).Inc()
+	if s.concurrency < s.maxConcurrency && s.queued == 0 {
+		s.concurrency++
+		ks.concurrency++
+		s.mu.Unlock()
+		return false, nil
+	}
+	w := &waiter{
+		ch:  make(chan struct{}),
+		seq: s.nextSeq,
+	}
+	s.nextSeq++
+	ks.queues[p] = append(ks.queues[p], w)
+	s.queued++
+	s.mu.Unlock()
+
+	startTime := time.Now()
+	defer func() {
+		getWaitDurationCounter(metricKey).Add(time.Since(startTime).Seconds())
+	}()
+
+	t := timerpool.Get(timeout)
+	defer timerpool.Put(t)
+	var err error
+	select {
+	case <-w.ch:
+		return true, nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-t.C:
+		err = ErrTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w.granted {
+		// The request has been scheduled concurrently with the timeout or the cancelation.
+		return true, nil
+	}
+	if ks.removeWaiter(p, w) {
+		s.queued--
+	}
+	s.deleteKeyStateIfIdleLocked(ks)
+	if errors.Is(err, ErrTimeout) {
+		getTimeoutsCounter(metricKey).Inc()
+	}
+	return true, err
+}
+
+// Release must be called when the request scheduled via Acquire is finished.
+func (s *Scheduler) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ks := s.keys[key]
+	if ks == nil || ks.concurrency <= 0 {
+		logger.Panicf("BUG: Release is called for the key %q without Acquire", key)
+	}
+	ks.concurrency--
+	s.concurrency--
+	s.deleteKeyStateIfIdleLocked(ks)
+
+	for s.concurrency < s.maxConcurrency {
+		if !s.scheduleNextLocked() {
+			return
+		}
+	}
+}
+
+// Concurrency returns the number of currently executed requests.
+func (s *Scheduler) Concurrency() int {
+	s.mu.Lock()
+	n := s.concurrency
+	s.mu.Unlock()
+	return n
+}
+
+func (s *Scheduler) scheduleNextLocked() bool {
+	for p := Priority(0); p < prioritiesCount; p++ {
+		var best *keyState
+		for _, ks := range s.keys {
+			if len(ks.queues[p]) == 0 {
+				continue
+			}
+			if best == nil || ks.isBetterThan(best, p) {
+				best = ks
+			}
+		}
+		if best == nil {
+			continue
+		}
+		w := best.queues[p][0]
+		best.queues[p][0] = nil
+		best.queues[p] = best.queues[p][1:]
+		s.queued--
+		w.granted = true
+		best.concurrency++
+		s.concurrency++
+		close(w.ch)
+		return true
+	}
+	return false
+}
+
+// isBetterThan returns true if the next queued request with the priority p for ks must be scheduled before the request for other.
+func (ks *keyState) isBetterThan(other *keyState, p Priority) bool {
+	// Compare ks.concurrency/ks.share with other.concurrency/other.share without divisions.
+	a := ks.concurrency * other.share
+	b := other.concurrency * ks.share
+	if a != b {
+		return a < b
+	}
+	return ks.queues[p][0].seq < other.queues[p][0].seq
+}
+
+// removeWaiter removes w from the queue with the priority p and returns true if w has been found there.
+func (ks *keyState) removeWaiter(p Priority, w *waiter) bool {
+	q := ks.queues[p]
+	for i := range q {
+		if q[i] == w {
+			ks.queues[p] = append(q[:i], q[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (ks *keyState) queuedRequests() int {
+	n := 0
+	for _, q := range ks.queues {
+		n += len(q)
+	}
+	return n
+}
+
+func (s *Scheduler) getKeyStateLocked(key string) *keyState {
+	ks := s.keys[key]
+	if ks != nil {
+		return ks
+	}
+	share := s.shares[key]
+	if share <= 0 {
+		share = 1
+	}
+	ks = &keyState{
+		key:       key,
+		metricKey: s.getMetricKeyLocked(key),
+		share:     share,
+	}
+	s.keys[key] = ks
+	return ks
+}
+
+// getMetricKeyLocked returns the key label value for metrics of the given key.
+func (s *Scheduler) getMetricKeyLocked(key string) string {
+	if _, ok := s.shares[key]; ok {
+		return key
+	}
+	if _, ok := s.metricKeys[key]; ok {
+		return key
+	}
+	if key == otherMetricKey || len(s.metricKeys) >= s.maxMetricKeys {
+		return otherMetricKey
+	}
+	s.metricKeys[key] = struct{}{}
+	return key
+}
+
+func (s *Scheduler) deleteKeyStateIfIdleLocked(ks *keyState) {
+	if ks.concurrency == 0 && ks.queuedRequests() == 0 {
+		delete(s.keys, ks.key)
+	}
+}
+
+func (s *Scheduler) writeMetrics(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type keyStats struct {
+		concurrency int
+		queued      [prioritiesCount]int
+	}
+	m := make(map[string]*keyStats)
+	for _, ks := range s.keys {
+		st := m[ks.metricKey]
+		if st == nil {
+			st = &keyStats{}
+			m[ks.metricKey] = st
+		}
+		st.concurrency += ks.concurrency
+		for p, q := range ks.queues {
+			st.queued[p] += len(q)
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		st := m[key]
+		metrics.WriteGaugeUint64(w, fmt.Sprintf(`vm_search_fair_queue_concurrent_requests{key=%q}`, key), uint64(st.concurrency))
+		for p, n := range st.queued {
+			metrics.WriteGaugeUint64(w, fmt.Sprintf(`vm_search_fair_queue_queued_requests{key=%q,priority=%q}`, key, Priority(p)), uint64(n))
+		}
+	}
+}
+
+func getRequestsCounter(key string, p Priority) *metrics.Counter {
+	return metrics.GetOrCreateCounter(fmt.Sprintf(`vm_search_fair_queue_requests_total{key=%q,priority=%q}`, key, p))
+}
+
+func getTimeoutsCounter(key string) *metrics.Counter {
+	return metrics.GetOrCreateCounter(fmt.Sprintf(`vm_search_fair_queue_timeouts_total{key=%q}`, key))
+}
+
+func getWaitDurationCounter(key string) *metrics.FloatCounter {
+	return metrics.GetOrCreateFloatCounter(fmt.Sprintf(`vm_search_fair_queue_wait_duration_seconds_total{key=%q}`, key))
+}
@@ -0,0 +1,339 @@
+package fairqueue
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSharesSuccess(t *testing.T) {
+	f := func(a []string, sharesExpected map[string]int) {
+		t.Helper()
+		shares, err := parseShares(a)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(shares, sharesExpected) {
+			t.Fatalf("unexpected shares; got %v; want %v", shares, sharesExpected)
+		}
+	}
+
+	f(nil, map[string]int{})
+	f([]string{"foo:1", "bar:10"}, map[string]int{
+		"foo": 1,
+		"bar": 10,
+	})
+
+	// key with colon
+	f([]string{"foo:bar:3"}, map[string]int{
+		"foo:bar": 3,
+	})
+
+	// empty key
+	f([]string{":2"}, map[string]int{
+		"": 2,
+	})
+}
+
+func TestParseSharesFailure(t *testing.T) {
+	f := func(a []string) {
+		t.Helper()
+		_, err := parseShares(a)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f([]string{"foo"})
+	f([]string{"foo:"})
+	f([]string{"foo:bar"})
+	f([]string{"foo:0"})
+	f([]string{"foo:-1"})
+}
+
+func TestValidateKeySource(t *testing.T) {
+	f := func(s string, resultExpected bool) {
+		t.Helper()
+		err := validateKeySource(s)
+		if result := err == nil; result != resultExpected {
+			t.Fatalf("unexpected result for validateKeySource(%q); got %v; want %v", s, result, resultExpected)
+		}
+	}
+
+	f("user", true)
+	f("remoteAddr", true)
+	f("header:X-Scope-OrgID", true)
+
+	f("", false)
+	f("header:", false)
+	f("foo", false)
+}
+
+func TestGetRequestKey(t *testing.T) {
+	f := func(keySource string, header http.Header, keyExpected string, priorityExpected Priority) {
+		t.Helper()
+		r := &http.Request{
+			Header:     header,
+			RemoteAddr: "1.2.3.4:56789",
+		}
+		key := getKey(r, keySource)
+		if key != keyExpected {
+			t.Fatalf("unexpected key; got %q; want %q", key, keyExpected)
+		}
+		priority := getPriority(r, true, "vmalert, grafana-alerts")
+		if priority != priorityExpected {
+			t.Fatalf("unexpected priority; got %s; want %s", priority, priorityExpected)
+		}
+	}
+
+	// client address without port
+	f("remoteAddr", http.Header{
+		"X-Forwarded-For": {"5.6.7.8"},
+	}, "1.2.3.4", PriorityNormal)
+
+	f("header:X-Scope-OrgID", http.Header{}, "", PriorityNormal)
+	f("header:X-Scope-OrgID", http.Header{
+		"X-Scope-Orgid": {"team-a"},
+	}, "team-a", PriorityNormal)
+	f("user", http.Header{
+		"Authorization": {"Basic Zm9vOmJhcg=="},
+	}, "foo", PriorityNormal)
+
+	// priority by User-Agent
+	f("user", http.Header{
+		"User-Agent": {"vmalert"},
+	}, "", PriorityHigh)
+	f("user", http.Header{
+		"User-Agent": {"grafana-alerts/1.0"},
+	}, "", PriorityHigh)
+	f("user", http.Header{
+		"User-Agent": {"Grafana/11.0"},
+	}, "", PriorityNormal)
+
+	// priority by header
+	f("user", http.Header{
+		"X-Query-Priority": {"LOW"},
+		"User-Agent":       {"vmalert"},
+	}, "", PriorityLow)
+	f("user", http.Header{
+		"X-Query-Priority": {"high"},
+	}, "", PriorityHigh)
+	f("user", http.Header{
+		"X-Query-Priority": {"foobar"},
+	}, "", PriorityNormal)
+}
+
+func TestGetPriorityDefaults(t *testing.T) {
+	f := func(header http.Header) {
+		t.Helper()
+		r := &http.Request{
+			Header: header,
+		}
+		// Neither the priority header nor User-Agent must affect the priority by default.
+		if priority := getPriority(r, false, ""); priority != PriorityNormal {
+			t.Fatalf("unexpected priority; got %s; want %s", priority, PriorityNormal)
+		}
+	}
+
+	f(http.Header{
+		"X-Query-Priority": {"high"},
+	})
+	f(http.Header{
+		"User-Agent": {"vmalert"},
+	})
+}
+
+func TestSchedulerAcquireRelease(t *testing.T) {
+	s := NewScheduler(2, nil, 100)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		waited, err := s.Acquire(ctx, "foo", PriorityNormal, time.Second)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if waited {
+			t.Fatalf("unexpected wait for the request #%d", i)
+		}
+	}
+	if n := s.Concurrency(); n != 2 {
+		t.Fatalf("unexpected concurrency; got %d; want 2", n)
+	}
+
+	// The limit is reached
+	_, err := s.Acquire(ctx, "bar", PriorityHigh, 10*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("unexpected error; got %v; want %v", err, ErrTimeout)
+	}
+
+	// The canceled request
+	ctxCanceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = s.Acquire(ctxCanceled, "bar", PriorityHigh, time.Second)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error; got %v; want %v", err, context.Canceled)
+	}
+	if s.queued != 0 {
+		t.Fatalf("unexpected number of queued requests after timeout and cancelation; got %d; want 0", s.queued)
+	}
+
+	s.Release("foo")
+	s.Release("foo")
+	if n := s.Concurrency(); n != 0 {
+		t.Fatalf("unexpected concurrency; got %d; want 0", n)
+	}
+	if n := len(s.keys); n != 0 {
+		t.Fatalf("unexpected number of tracked keys; got %d; want 0", n)
+	}
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	f := func(shares map[string]int, running []string, queued []queuedRequest, orderExpected []string) {
+		t.Helper()
+
+		s := NewScheduler(len(running), shares, 100)
+		ctx := context.Background()
+		for _, key := range running {
+			if _, err := s.Acquire(ctx, key, PriorityNormal, time.Second); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+
+		scheduledCh := make(chan string, len(queued))
+		errCh := make(chan error, len(queued))
+		for i, qr := range queued {
+			go func(qr queuedRequest) {
+				if _, err := s.Acquire(ctx, qr.key, qr.priority, 10*time.Second); err != nil {
+					errCh <- err
+					return
+				}
+				scheduledCh <- qr.key
+			}(qr)
+			// Wait until the request is queued in order to guarantee the queue order.
+			s.waitForQueuedRequests(i + 1)
+		}
+
+		nextScheduled := func() string {
+			t.Helper()
+			select {
+			case key := <-scheduledCh:
+				return key
+			case err := <-errCh:
+				t.Fatalf("unexpected error: %s", err)
+			}
+			return ""
+		}
+
+		// Release running requests one by one and verify the order of scheduled requests.
+		var order []string
+		for _, key := range running {
+			if len(order) == len(queued) {
+				break
+			}
+			s.Release(key)
+			order = append(order, nextScheduled())
+		}
+		for len(order) < len(queued) {
+			s.Release(order[len(order)-len(running)])
+			order = append(order, nextScheduled())
+		}
+		if !reflect.DeepEqual(order, orderExpected) {
+			t.Fatalf("unexpected order of scheduled requests; got %q; want %q", order, orderExpected)
+		}
+	}
+
+	// FIFO order for the same key and priority
+	f(nil, []string{"a"}, []queuedRequest{
+		{"a", PriorityNormal},
+		{"a", PriorityNormal},
+	}, []string{"a", "a"})
+
+	// Higher priority is scheduled first
+	f(nil, []string{"a"}, []queuedRequest{
+		{"low", PriorityLow},
+		{"normal", PriorityNormal},
+		{"high", PriorityHigh},
+	}, []string{"high", "normal", "low"})
+
+	// The key with the lower number of executed requests is scheduled first
+	f(nil, []string{"a", "b"}, []queuedRequest{
+		{"a", PriorityNormal},
+		{"a", PriorityNormal},
+		{"b", PriorityNormal},
+	}, []string{"a", "b", "a"})
+	f(nil, []string{"b", "a", "a"}, []queuedRequest{
+		{"a", PriorityNormal},
+		{"b", PriorityNormal},
+	}, []string{"b", "a"})
+
+	// The request queued first is scheduled first for keys with equal number of executed requests per share
+	f(nil, []string{"a", "a", "b"}, []queuedRequest{
+		{"b", PriorityNormal},
+		{"a", PriorityNormal},
+	}, []string{"b", "a"})
+
+	// The key with bigger share gets more concurrency
+	f(map[string]int{
+		"a": 3,
+	}, []string{"a", "a", "b"}, []queuedRequest{
+		{"b", PriorityNormal},
+		{"a", PriorityNormal},
+	}, []string{"a", "b"})
+}
+
+type queuedRequest struct {
+	key      string
+	priority Priority
+}
+
+func TestSchedulerMetricKeys(t *testing.T) {
+	s := NewScheduler(10, map[string]int{
+		"with-share": 2,
+	}, 2)
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c", otherMetricKey, "with-share"} {
+		if _, err := s.Acquire(ctx, key, PriorityNormal, time.Second); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	var bb bytes.Buffer
+	s.writeMetrics(&bb)
+	resultExpected := `vm_search_fair_queue_concurrent_requests{key="__other__"} 2
+vm_search_fair_queue_queued_requests{key="__other__",priority="high"} 0
+vm_search_fair_queue_queued_requests{key="__other__",priority="normal"} 0
+vm_search_fair_queue_queued_requests{key="__other__",priority="low"} 0
+vm_search_fair_queue_concurrent_requests{key="a"} 1
+vm_search_fair_queue_queued_requests{key="a",priority="high"} 0
+vm_search_fair_queue_queued_requests{key="a",priority="normal"} 0
+vm_search_fair_queue_queued_requests{key="a",priority="low"} 0
+vm_search_fair_queue_concurrent_requests{key="b"} 1
+vm_search_fair_queue_queued_requests{key="b",priority="high"} 0
+vm_search_fair_queue_queued_requests{key="b",priority="normal"} 0
+vm_search_fair_queue_queued_requests{key="b",priority="low"} 0
+vm_search_fair_queue_concurrent_requests{key="with-share"} 1
+vm_search_fair_queue_queued_requests{key="with-share",priority="high"} 0
+vm_search_fair_queue_queued_requests{key="with-share",priority="normal"} 0
+vm_search_fair_queue_queued_requests{key="with-share",priority="low"} 0
+`
+	if result := bb.String(); result != resultExpected {
+		t.Fatalf("unexpected metrics;\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+}
+
+// waitForQueuedRequests waits until s has at least n queued requests.
+func (s *Scheduler) waitForQueuedRequests(n int) {
+	for {
+		s.mu.Lock()
+		queued := s.queued
+		s.mu.Unlock()
+		if queued >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -2,6 +2,7 @@ package vmselect
 
 import (
 	"embed"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
@@ -10,10 +11,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/fairqueue"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/graphite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/prometheus"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/queryrules"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
@@ -29,8 +32,9 @@ import (
 )
 
 var (
-	deleteAuthKey         = flagutil.NewPassword("deleteAuthKey", "authKey for metrics' deletion via /api/v1/admin/tsdb/delete_series and /tags/delSeries. It overrides -httpAuth.*")
-	maxConcurrentRequests = flag.Int("search.maxConcurrentRequests", getDefaultMaxConcurrentRequests(), "The maximum number of concurrent search requests. "+
+	deleteAuthKey                = flagutil.NewPassword("deleteAuthKey", "authKey for metrics' deletion via /api/v1/admin/tsdb/delete_series and /tags/delSeries. It overrides -httpAuth.*")
+	metricNamesStatsResetAuthKey = flagutil.NewPassword("metricNamesStatsResetAuthKey", "authKey for resetting metric names usage stats via /api/v1/admin/status/metric_names_stats/reset. It overrides -httpAuth.*")
+	maxConcurrentRequests        = flag.Int("search.maxConcurrentRequests", getDefaultMaxConcurrentRequests(), "The maximum number of concurrent search requests. "+
 		"It shouldn't be high, since a single request can saturate all the CPU cores, while many concurrently executed requests may require high amounts of memory. "+
 		"See also -search.maxQueueDuration and -search.maxMemoryPerQuery")
 	maxQueueDuration = flag.Duration("search.maxQueueDuration", 10*time.Second, "The maximum time the request waits for execution when -search.maxConcurrentRequests "+
@@ -63,11 +67,16 @@ func Init() {
 	prometheus.InitMaxUniqueTimeseries(*maxConcurrentRequests)
 
 	concurrencyLimitCh = make(chan struct{}, *maxConcurrentRequests)
+	if fairqueue.Enabled() {
+		fairqueue.Init(*maxConcurrentRequests)
+	}
 	initVMAlertProxy()
+	queryrules.Init()
 }
 
 // Stop stops vmselect
 func Stop() {
+	queryrules.Stop()
 	promql.StopRollupResultCache()
 }
 
@@ -81,6 +90,9 @@ var (
 		return float64(cap(concurrencyLimitCh))
 	})
 	_ = metrics.NewGauge(`vm_concurrent_select_current`, func() float64 {
+		if fairqueue.Enabled() {
+			return float64(fairqueue.Concurrency())
+		}
 		return float64(len(concurrencyLimitCh))
 	})
 	_ = metrics.NewGauge(`vm_search_max_unique_timeseries`, func() float64 {
@@ -118,42 +130,50 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	qt := querytracer.New(tracerEnabled, "%s", r.URL.Path)
 
 	// Limit the number of concurrent queries.
-	select {
-	case concurrencyLimitCh <- struct{}{}:
-		defer func() { <-concurrencyLimitCh }()
-	default:
-		// Sleep for a while until giving up. This should resolve short bursts in requests.
-		concurrencyLimitReached.Inc()
-		d := searchutils.GetMaxQueryDuration(r)
-		if d > *maxQueueDuration {
-			d = *maxQueueDuration
+	if fairqueue.Enabled() {
+		key, ok := acquireFairQueue(qt, w, r, startTime)
+		if !ok {
+			return true
 		}
-		t := timerpool.Get(d)
+		defer fairqueue.Release(key)
+	} else {
 		select {
 		case concurrencyLimitCh <- struct{}{}:
-			timerpool.Put(t)
-			qt.Printf("wait in queue because -search.maxConcurrentRequests=%d concurrent requests are executed", *maxConcurrentRequests)
 			defer func() { <-concurrencyLimitCh }()
-		case <-r.Context().Done():
-			timerpool.Put(t)
-			remoteAddr := httpserver.GetQuotedRemoteAddr(r)
-			requestURI := httpserver.GetRequestURI(r)
-			logger.Infof("client has canceled the request after %.3f seconds: remoteAddr=%s, requestURI: %q",
-				time.Since(startTime).Seconds(), remoteAddr, requestURI)
-			return true
-		case <-t.C:
-			timerpool.Put(t)
-			concurrencyLimitTimeout.Inc()
-			err := &httpserver.ErrorWithStatusCode{
-				Err: fmt.Errorf("couldn't start executing the request in %.3f seconds, since -search.maxConcurrentRequests=%d concurrent requests "+
-					"are executed. Possible solutions: to reduce query load; to add more compute resources to the server; "+
-					"to increase -search.maxQueueDuration=%s; to increase -search.maxQueryDuration; to increase -search.maxConcurrentRequests",
-					d.Seconds(), *maxConcurrentRequests, maxQueueDuration),
-				StatusCode: http.StatusTooManyRequests,
+		default:
+			// Sleep for a while until giving up. This should resolve short bursts in requests.
+			concurrencyLimitReached.Inc()
+			d := searchutils.GetMaxQueryDuration(r)
+			if d > *maxQueueDuration {
+				d = *maxQueueDuration
+			}
+			t := timerpool.Get(d)
+			select {
+			case concurrencyLimitCh <- struct{}{}:
+				timerpool.Put(t)
+				qt.Printf("wait in queue because -search.maxConcurrentRequests=%d concurrent requests are executed", *maxConcurrentRequests)
+				defer func() { <-concurrencyLimitCh }()
+			case <-r.Context().Done():
+				timerpool.Put(t)
+				remoteAddr := httpserver.GetQuotedRemoteAddr(r)
+				requestURI := httpserver.GetRequestURI(r)
+				logger.Infof("client has canceled the request after %.3f seconds: remoteAddr=%s, requestURI: %q",
+					time.Since(startTime).Seconds(), remoteAddr, requestURI)
+				return true
+			case <-t.C:
+				timerpool.Put(t)
+				concurrencyLimitTimeout.Inc()
+				err := &httpserver.ErrorWithStatusCode{
+					Err: fmt.Errorf("couldn't start executing the request in %.3f seconds, since -search.maxConcurrentRequests=%d concurrent requests "+
+						"are executed. Possible solutions: to reduce query load; to add more compute resources to the server; "+
+						"to increase -search.maxQueueDuration=%s; to increase -search.maxQueryDuration; to increase -search.maxConcurrentRequests",
+						d.Seconds(), *maxConcurrentRequests, maxQueueDuration),
+					StatusCode: http.StatusTooManyRequests,
+				}
+				w.Header().Add("Retry-After", "10")
+				httpserver.Errorf(w, r, "%s", err)
+				return true
 			}
-			w.Header().Add("Retry-After", "10")
-			httpserver.Errorf(w, r, "%s", err)
-			return true
 		}
 	}
 
@@ -223,6 +243,15 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/query/explain":
+		queryExplainRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.QueryExplainHandler(qt, startTime, w, r); err != nil {
+			queryExplainErrors.Inc()
+			httpserver.SendPrometheusError(w, r, err)
+			return true
+		}
+		return true
 	case "/api/v1/series":
 		seriesRequests.Inc()
 		httpserver.EnableCORS(w, r)
@@ -259,6 +288,15 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/status/metric_names_stats":
+		metricNamesStatsRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.MetricNamesStatsHandler(qt, startTime, w, r); err != nil {
+			metricNamesStatsErrors.Inc()
+			httpserver.SendPrometheusError(w, r, err)
+			return true
+		}
+		return true
 	case "/api/v1/export":
 		exportRequests.Inc()
 		if err := prometheus.ExportHandler(startTime, w, r); err != nil {
@@ -283,6 +321,22 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/export/parquet":
+		exportParquetRequests.Inc()
+		if err := prometheus.ExportParquetHandler(startTime, w, r); err != nil {
+			exportParquetErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		return true
+	case "/api/v1/export/arrow":
+		exportArrowRequests.Inc()
+		if err := prometheus.ExportArrowHandler(startTime, w, r); err != nil {
+			exportArrowErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		return true
 	case "/federate":
 		federateRequests.Inc()
 		if err := prometheus.FederateHandler(startTime, w, r); err != nil {
@@ -399,11 +453,61 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		}
 		w.WriteHeader(http.StatusNoContent)
 		return true
+	case "/api/v1/admin/status/metric_names_stats/reset":
+		if !httpserver.CheckAuthFlag(w, r, metricNamesStatsResetAuthKey) {
+			return true
+		}
+		metricNamesStatsResetRequests.Inc()
+		netstorage.ResetMetricNamesStats(qt)
+		w.WriteHeader(http.StatusNoContent)
+		return true
 	default:
 		return false
 	}
 }
 
+// acquireFairQueue waits until r can be executed according to fair query scheduling.
+//
+// It returns false if r couldn't be scheduled for execution. In this case the error is already sent to the client.
+// Otherwise fairqueue.Release must be called with the returned key after r is processed.
+func acquireFairQueue(qt *querytracer.Tracer, w http.ResponseWriter, r *http.Request, startTime time.Time) (string, bool) {
+	key, priority := fairqueue.GetRequestKey(r)
+	d := searchutils.GetMaxQueryDuration(r)
+	if d > *maxQueueDuration {
+		d = *maxQueueDuration
+	}
+	waited, err := fairqueue.Acquire(r.Context(), key, priority, d)
+	if waited {
+		concurrencyLimitReached.Inc()
+	}
+	if err == nil {
+		if waited {
+			qt.Printf("wait in fair queue for key=%q, priority=%s because -search.maxConcurrentRequests=%d concurrent requests are executed",
+				key, priority, *maxConcurrentRequests)
+		}
+		return key, true
+	}
+	if errors.Is(err, fairqueue.ErrTimeout) {
+		concurrencyLimitTimeout.Inc()
+		err := &httpserver.ErrorWithStatusCode{
+			Err: fmt.Errorf("couldn't start executing the request for key=%q in %.3f seconds, since -search.maxConcurrentRequests=%d concurrent requests "+
+				"are executed. Possible solutions: to reduce query load; to add more compute resources to the server; "+
+				"to increase -search.maxQueueDuration=%s; to increase -search.maxQueryDuration; to increase -search.maxConcurrentRequests; "+
+				"to increase the share for the key via -search.fairQueue.shares",
+				key, d.Seconds(), *maxConcurrentRequests, maxQueueDuration),
+			StatusCode: http.StatusTooManyRequests,
+		}
+		w.Header().Add("Retry-After", "10")
+		httpserver.Errorf(w, r, "%s", err)
+		return "", false
+	}
+	remoteAddr := httpserver.GetQuotedRemoteAddr(r)
+	requestURI := httpserver.GetRequestURI(r)
+	logger.Infof("client has canceled the request after %.3f seconds: remoteAddr=%s, requestURI: %q",
+		time.Since(startTime).Seconds(), remoteAddr, requestURI)
+	return "", false
+}
+
 func handleStaticAndSimpleRequests(w http.ResponseWriter, r *http.Request, path string) bool {
 	// vmui access.
 	if path == "/vmui" || path == "/graph" {
@@ -587,6 +691,9 @@ var (
 	queryRangeRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/query_range"}`)
 	queryRangeErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/query_range"}`)
 
+	queryExplainRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/query/explain"}`)
+	queryExplainErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/query/explain"}`)
+
 	seriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/series"}`)
 	seriesErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/series"}`)
 
@@ -599,6 +706,11 @@ var (
 	statusTSDBRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/tsdb"}`)
 	statusTSDBErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/tsdb"}`)
 
+	metricNamesStatsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/metric_names_stats"}`)
+	metricNamesStatsErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/metric_names_stats"}`)
+
+	metricNamesStatsResetRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/admin/status/metric_names_stats/reset"}`)
+
 	statusActiveQueriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/active_queries"}`)
 
 	topQueriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/top_queries"}`)
@@ -616,6 +728,12 @@ var (
 	exportNativeRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/export/native"}`)
 	exportNativeErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/export/native"}`)
 
+	exportParquetRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/export/parquet"}`)
+	exportParquetErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/export/parquet"}`)
+
+	exportArrowRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/export/arrow"}`)
+	exportArrowErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/export/arrow"}`)
+
 	federateRequests = metrics.NewCounter(`vm_http_requests_total{path="/federate"}`)
 	federateErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/federate"}`)
 
@@ -443,6 +443,14 @@ func (pts *packedTimeseries) Unpack(dst *Result, tbf *tmpBlocksFile, tr storage.
 	dedupInterval := storage.GetDedupInterval()
 	mergeSortBlocks(dst, sbh, dedupInterval)
 	putSortBlocksHeap(sbh)
+	if storage.IsDownsamplingEnabled() {
+		// Apply downsampling and retention filters to samples, which weren't processed by background merges yet.
+		// This guarantees consistent query results for time ranges with mixed resolutions.
+		// See https://docs.victoriametrics.com/#downsampling
+		n := len(dst.Timestamps)
+		dst.Timestamps, dst.Values = storage.DownsampleSamples(&dst.MetricName, dst.Timestamps, dst.Values, int64(fasttime.UnixTimestamp()*1000))
+		downsampledSamplesDuringSelect.Add(n - len(dst.Timestamps))
+	}
 	return nil
 }
 
@@ -628,6 +636,8 @@ func mergeSortBlocks(dst *Result, sbh *sortBlocksHeap, dedupInterval int64) {
 
 var dedupsDuringSelect = metrics.NewCounter(`vm_deduplicated_samples_total{type="select"}`)
 
+var downsampledSamplesDuringSelect = metrics.NewCounter(`vm_downsampled_samples_total{type="select"}`)
+
 func equalSamplesPrefix(a, b *sortBlock) int {
 	n := equalTimestampsPrefix(a.Timestamps[a.NextIdx:], b.Timestamps[b.NextIdx:])
 	if n == 0 {
@@ -946,6 +956,49 @@ func TSDBStatus(qt *querytracer.Tracer, sq *storage.SearchQuery, focusLabel stri
 	return status, nil
 }
 
+// TSDBStatusDiff returns the difference between tsdb stats at the date from sq and at the compareDate.
+func TSDBStatusDiff(qt *querytracer.Tracer, sq *storage.SearchQuery, compareDate uint64, focusLabel string, topN int, deadline searchutils.Deadline) (*storage.TSDBStatusDiff, error) {
+	qt = qt.NewChild("get tsdb stats diff: %s, compareDate=%d, focusLabel=%q, topN=%d", sq, compareDate, focusLabel, topN)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return nil, fmt.Errorf("timeout exceeded before starting the query processing: %s", deadline.String())
+	}
+	tr := sq.GetTimeRange()
+	tfss, err := setupTfss(qt, tr, sq.TagFilterss, sq.MaxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+	date := uint64(tr.MinTimestamp) / (3600 * 24 * 1000)
+	diff, err := vmstorage.GetTSDBStatusDiff(qt, tfss, date, compareDate, focusLabel, topN, sq.MaxMetrics, deadline.Deadline())
+	if err != nil {
+		return nil, fmt.Errorf("error during tsdb status diff request: %w", err)
+	}
+	return diff, nil
+}
+
+// MetricNamesStats returns usage stats for metric names.
+//
+// See storage.Storage.GetMetricNamesStats for details on limit, le and matchPattern args.
+func MetricNamesStats(qt *querytracer.Tracer, limit int, le uint64, matchPattern string, deadline searchutils.Deadline) (storage.MetricNamesStatsResponse, error) {
+	qt = qt.NewChild("get metric names stats: limit=%d, le=%d, matchPattern=%q", limit, le, matchPattern)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return storage.MetricNamesStatsResponse{}, fmt.Errorf("timeout exceeded before starting the query processing: %s", deadline.String())
+	}
+	resp, err := vmstorage.GetMetricNamesStats(qt, limit, le, matchPattern, deadline.Deadline())
+	if err != nil {
+		return resp, fmt.Errorf("error during metric names stats request: %w", err)
+	}
+	return resp, nil
+}
+
+// ResetMetricNamesStats resets usage stats for metric names.
+func ResetMetricNamesStats(qt *querytracer.Tracer) {
+	qt = qt.NewChild("reset metric names stats")
+	defer qt.Done()
+	vmstorage.ResetMetricNamesStats()
+}
+
 // SeriesCount returns the number of unique series.
 func SeriesCount(qt *querytracer.Tracer, deadline searchutils.Deadline) (uint64, error) {
 	qt = qt.NewChild("get series count")
@@ -1089,6 +1142,36 @@ var exportWorkPool = &sync.Pool{
 	},
 }
 
+// MatchingSeriesCount returns the number of series matching sq until the given deadline.
+//
+// The number is obtained from the index without reading samples.
+func MatchingSeriesCount(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline searchutils.Deadline) (int, error) {
+	qt = qt.NewChild("count matching series: %s", sq)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return 0, fmt.Errorf("timeout exceeded before starting to count matching series: %s", deadline.String())
+	}
+
+	tr := sq.GetTimeRange()
+	if err := vmstorage.CheckTimeRange(tr); err != nil {
+		return 0, err
+	}
+	tfss, err := setupTfss(qt, tr, sq.TagFilterss, sq.MaxMetrics, deadline)
+	if err != nil {
+		return 0, err
+	}
+	n, err := vmstorage.GetMatchingSeriesCount(qt, tfss, tr, sq.MaxMetrics, deadline.Deadline())
+	if err != nil {
+		return 0, fmt.Errorf("cannot count matching series: %w", err)
+	}
+	return n, nil
+}
+
+// GetMaxSamplesPerQuery returns the limit on the number of raw samples a single query can process.
+func GetMaxSamplesPerQuery() int {
+	return *maxSamplesPerQuery
+}
+
 // SearchMetricNames returns all the metric names matching sq until the given deadline.
 //
 // The returned metric names must be unmarshaled via storage.MetricName.UnmarshalString().
@@ -1279,6 +1362,7 @@ func ProcessSearchQuery(qt *querytracer.Tracer, sq *storage.SearchQuery, deadlin
 		return nil, fmt.Errorf("cannot finalize temporary file: %w", err)
 	}
 	qt.Printf("fetch unique series=%d, blocks=%d, samples=%d, bytes=%d", len(m), blocksRead, samples, tbf.Len())
+	vmstorage.Storage.RegisterMetricNamesQuery(orderedMetricNames)
 
 	var rss Results
 	rss.tr = tr
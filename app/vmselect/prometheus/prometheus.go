@@ -18,8 +18,10 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/queryrules"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/querystats"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/arrow"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bufferedwriter"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
@@ -29,6 +31,8 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/parquet"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 )
@@ -289,6 +293,152 @@ func ExportNativeHandler(startTime time.Time, w http.ResponseWriter, r *http.Req
 
 var exportNativeDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/export/native"}`)
 
+// ExportParquetHandler exports data in Parquet format from /api/v1/export/parquet.
+//
+// Every sample is exported as a row with labels, timestamp and value columns.
+func ExportParquetHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer exportParquetDuration.UpdateDuration(startTime)
+
+	cp, err := getExportParams(r, startTime)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apache.parquet")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	pw := parquet.NewWriter(bw)
+	newBatch := func() columnarBatch {
+		return &parquet.RowGroup{}
+	}
+	writeBatch := func(cb columnarBatch) error {
+		return pw.WriteRowGroup(cb.(*parquet.RowGroup))
+	}
+	if err := exportColumnar(cp, bw, newBatch, writeBatch); err != nil {
+		return fmt.Errorf("error during sending parquet data to remote client: %w", err)
+	}
+	if err := pw.Close(); err != nil {
+		return fmt.Errorf("error during sending parquet data to remote client: %w", err)
+	}
+	return bw.Flush()
+}
+
+var exportParquetDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/export/parquet"}`)
+
+// ExportArrowHandler exports data in Arrow IPC stream format from /api/v1/export/arrow.
+//
+// Every sample is exported as a row with labels, timestamp and value columns.
+func ExportArrowHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer exportArrowDuration.UpdateDuration(startTime)
+
+	cp, err := getExportParams(r, startTime)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apache.arrow.stream")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	aw := arrow.NewWriter(bw)
+	newBatch := func() columnarBatch {
+		return &arrow.RecordBatch{}
+	}
+	writeBatch := func(cb columnarBatch) error {
+		return aw.WriteRecordBatch(cb.(*arrow.RecordBatch))
+	}
+	if err := exportColumnar(cp, bw, newBatch, writeBatch); err != nil {
+		return fmt.Errorf("error during sending arrow data to remote client: %w", err)
+	}
+	if err := aw.Close(); err != nil {
+		return fmt.Errorf("error during sending arrow data to remote client: %w", err)
+	}
+	return bw.Flush()
+}
+
+var exportArrowDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/export/arrow"}`)
+
+// columnarBatch is a batch of rows for columnar export formats such as Parquet and Arrow.
+type columnarBatch interface {
+	AddSeries(labels []prompbmarshal.Label, timestamps []int64, values []float64)
+	SizeBytes() int
+	Reset()
+}
+
+// maxColumnarBatchSize is the maximum size of columnarBatch, which is buffered per each worker before sending it to the client.
+const maxColumnarBatchSize = 8 * 1024 * 1024
+
+type columnarExportShard struct {
+	cb         columnarBatch
+	labels     []prompbmarshal.Label
+	timestamps []int64
+	values     []float64
+}
+
+// exportColumnar streams blocks matching cp to columnar batches created via newBatch.
+//
+// Every worker fills its own batch and passes it to writeBatch when it becomes big enough,
+// so the whole response isn't buffered in memory.
+func exportColumnar(cp *commonParams, bw *bufferedwriter.Writer, newBatch func() columnarBatch, writeBatch func(cb columnarBatch) error) error {
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxExportSeries)
+	var shards sync.Map
+	err := netstorage.ExportBlocks(nil, sq, cp.deadline, func(mn *storage.MetricName, b *storage.Block, tr storage.TimeRange, workerID uint) error {
+		if err := bw.Error(); err != nil {
+			return err
+		}
+		if err := b.UnmarshalData(); err != nil {
+			return fmt.Errorf("cannot unmarshal block during export: %w", err)
+		}
+		v, ok := shards.Load(workerID)
+		if !ok {
+			v = &columnarExportShard{
+				cb: newBatch(),
+			}
+			shards.Store(workerID, v)
+		}
+		shard := v.(*columnarExportShard)
+		shard.timestamps, shard.values = b.AppendRowsWithTimeRangeFilter(shard.timestamps[:0], shard.values[:0], tr)
+		if len(shard.timestamps) == 0 {
+			return nil
+		}
+		shard.labels = appendLabelsFromMetricName(shard.labels[:0], mn)
+		shard.cb.AddSeries(shard.labels, shard.timestamps, shard.values)
+		if shard.cb.SizeBytes() < maxColumnarBatchSize {
+			return nil
+		}
+		err := writeBatch(shard.cb)
+		shard.cb.Reset()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	shards.Range(func(_, v any) bool {
+		shard := v.(*columnarExportShard)
+		err = writeBatch(shard.cb)
+		return err == nil
+	})
+	return err
+}
+
+// appendLabelsFromMetricName appends labels from mn to dst.
+//
+// The appended labels refer to mn contents, so they become invalid after mn modification.
+func appendLabelsFromMetricName(dst []prompbmarshal.Label, mn *storage.MetricName) []prompbmarshal.Label {
+	if len(mn.MetricGroup) > 0 {
+		dst = append(dst, prompbmarshal.Label{
+			Name:  "__name__",
+			Value: bytesutil.ToUnsafeString(mn.MetricGroup),
+		})
+	}
+	for _, tag := range mn.Tags {
+		dst = append(dst, prompbmarshal.Label{
+			Name:  bytesutil.ToUnsafeString(tag.Key),
+			Value: bytesutil.ToUnsafeString(tag.Value),
+		})
+	}
+	return dst
+}
+
 var bbPool bytesutil.ByteBufferPool
 
 // ExportHandler exports data in raw format from /api/v1/export.
@@ -545,18 +695,13 @@ func TSDBStatusHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 	}
 	cp.deadline = searchutils.GetDeadlineForStatusRequest(r, startTime)
 
-	date := fasttime.UnixDate()
-	dateStr := r.FormValue("date")
-	if len(dateStr) > 0 {
-		if dateStr == "0" {
-			date = 0
-		} else {
-			t, err := time.Parse("2006-01-02", dateStr)
-			if err != nil {
-				return fmt.Errorf("cannot parse `date` arg %q: %w", dateStr, err)
-			}
-			date = uint64(t.Unix()) / secsPerDay
-		}
+	date, err := getDateArg(r, "date", fasttime.UnixDate())
+	if err != nil {
+		return err
+	}
+	compareDate, err := getDateArg(r, "compareDate", 0)
+	if err != nil {
+		return err
 	}
 	focusLabel := r.FormValue("focusLabel")
 	topN := 10
@@ -581,11 +726,18 @@ func TSDBStatusHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 	if err != nil {
 		return fmt.Errorf("cannot obtain tsdb stats: %w", err)
 	}
+	var diff *storage.TSDBStatusDiff
+	if len(r.FormValue("compareDate")) > 0 {
+		diff, err = netstorage.TSDBStatusDiff(qt, sq, compareDate, focusLabel, topN, cp.deadline)
+		if err != nil {
+			return fmt.Errorf("cannot obtain tsdb stats diff: %w", err)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
-	WriteTSDBStatusResponse(bw, status, qt)
+	WriteTSDBStatusResponse(bw, status, diff, qt)
 	if err := bw.Flush(); err != nil {
 		return fmt.Errorf("cannot send tsdb status response to remote client: %w", err)
 	}
@@ -594,6 +746,72 @@ func TSDBStatusHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 
 var tsdbStatusDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/status/tsdb"}`)
 
+// getDateArg returns the date in days since unix epoch from the argName query arg in YYYY-MM-DD format.
+//
+// defaultDate is returned if the arg is missing. Zero is returned for `0` value, which means the whole time range.
+func getDateArg(r *http.Request, argName string, defaultDate uint64) (uint64, error) {
+	dateStr := r.FormValue(argName)
+	if len(dateStr) == 0 {
+		return defaultDate, nil
+	}
+	if dateStr == "0" {
+		return 0, nil
+	}
+	t, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse `%s` arg %q: %w", argName, dateStr, err)
+	}
+	return uint64(t.Unix()) / secsPerDay, nil
+}
+
+// MetricNamesStatsHandler processes /api/v1/status/metric_names_stats request.
+//
+// It returns the number of queries and the last query time per each metric name.
+// The following optional query args are supported:
+//
+// - limit - the maximum number of returned records. Records with the smallest number of queries are returned first.
+// - le - return only metric names with the number of queries smaller or equal to the given value.
+// - match_pattern - return only metric names containing the given substring.
+func MetricNamesStatsHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer metricNamesStatsDuration.UpdateDuration(startTime)
+
+	deadline := searchutils.GetDeadlineForStatusRequest(r, startTime)
+	limit := 1000
+	limitStr := r.FormValue("limit")
+	if len(limitStr) > 0 {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return fmt.Errorf("cannot parse `limit` arg %q: %w", limitStr, err)
+		}
+		limit = n
+	}
+	le := uint64(math.MaxUint64)
+	leStr := r.FormValue("le")
+	if len(leStr) > 0 {
+		n, err := strconv.ParseUint(leStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("cannot parse `le` arg %q: %w", leStr, err)
+		}
+		le = n
+	}
+	matchPattern := r.FormValue("match_pattern")
+	resp, err := netstorage.MetricNamesStats(qt, limit, le, matchPattern, deadline)
+	if err != nil {
+		return fmt.Errorf("cannot obtain metric names stats: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	WriteMetricNamesStatsResponse(bw, &resp, qt)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send metric names stats response to remote client: %w", err)
+	}
+	return nil
+}
+
+var metricNamesStatsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/status/metric_names_stats"}`)
+
 // LabelsHandler processes /api/v1/labels request.
 //
 // See https://prometheus.io/docs/prometheus/latest/querying/api/#getting-label-names
@@ -719,6 +937,13 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 	if len(query) > maxQueryLen.IntN() {
 		return fmt.Errorf("too long query; got %d bytes; mustn't exceed `-search.maxQueryLen=%d` bytes", len(query), maxQueryLen.N)
 	}
+	qrr, step, err := applyQueryRules(qt, r, query, step)
+	if err != nil {
+		return err
+	}
+	if qrr != nil && qrr.NoCache {
+		mayCache = false
+	}
 	etfs, err := searchutils.GetExtraTagFilters(r)
 	if err != nil {
 		return err
@@ -771,7 +996,7 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 		start -= offset
 		end := start
 		start = end - window
-		if err := queryRangeHandler(qt, startTime, w, childQuery, start, end, step, r, ct, etfs); err != nil {
+		if err := queryRangeHandler(qt, startTime, w, childQuery, start, end, step, r, ct, etfs, qrr); err != nil {
 			return fmt.Errorf("error when executing query=%q on the time range (start=%d, end=%d, step=%d): %w", childQuery, start, end, step, err)
 		}
 		return nil
@@ -781,7 +1006,7 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 	if err != nil {
 		return err
 	}
-	if !httputils.GetBool(r, "nocache") && ct-start < queryOffset && start-ct < queryOffset {
+	if mayCache && ct-start < queryOffset && start-ct < queryOffset {
 		// Adjust start time only if `nocache` arg isn't set.
 		// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/241
 		startPrev := start
@@ -865,20 +1090,30 @@ func QueryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 	if err != nil {
 		return err
 	}
+	qrr, step, err := applyQueryRules(qt, r, query, step)
+	if err != nil {
+		return err
+	}
 	etfs, err := searchutils.GetExtraTagFilters(r)
 	if err != nil {
 		return err
 	}
-	if err := queryRangeHandler(qt, startTime, w, query, start, end, step, r, ct, etfs); err != nil {
+	if err := queryRangeHandler(qt, startTime, w, query, start, end, step, r, ct, etfs, qrr); err != nil {
 		return fmt.Errorf("error when executing query=%q on the time range (start=%d, end=%d, step=%d): %w", query, start, end, step, err)
 	}
 	return nil
 }
 
 func queryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, query string,
-	start, end, step int64, r *http.Request, ct int64, etfs [][]storage.TagFilter) error {
+	start, end, step int64, r *http.Request, ct int64, etfs [][]storage.TagFilter, qrr *queryrules.Result) error {
 	deadline := searchutils.GetDeadlineForQuery(r, startTime)
 	mayCache := !httputils.GetBool(r, "nocache")
+	if qrr != nil {
+		if qrr.NoCache {
+			mayCache = false
+		}
+		start = qrr.AdjustStart(start, end)
+	}
 	lookbackDelta, err := getMaxLookback(r)
 	if err != nil {
 		return err
@@ -989,8 +1224,135 @@ func removeEmptyValuesAndTimeseries(tss []netstorage.Result) []netstorage.Result
 	return dst
 }
 
+// applyQueryRules applies -search.queryRules to the given query from r and returns the step adjusted according to the matching rules.
+//
+// It returns nil result if the query doesn't match any rule.
+// It returns an error if the query must be rejected.
+func applyQueryRules(qt *querytracer.Tracer, r *http.Request, query string, step int64) (*queryrules.Result, int64, error) {
+	qrr := queryrules.Match(r, query)
+	if qrr == nil {
+		return nil, step, nil
+	}
+	qt.Printf("the query matches -search.queryRules: %s", qrr)
+	if qrr.Deny {
+		return nil, step, &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("the query is rejected by -search.queryRules rules %q: %s", qrr.Rules, qrr.Message),
+			StatusCode: http.StatusForbidden,
+		}
+	}
+	step = qrr.AdjustStep(step)
+	if err := qrr.CheckRollupWindows(query, step); err != nil {
+		return nil, step, &httpserver.ErrorWithStatusCode{
+			Err:        err,
+			StatusCode: http.StatusUnprocessableEntity,
+		}
+	}
+	return qrr, step, nil
+}
+
 var queryRangeDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/query_range"}`)
 
+// QueryExplainHandler processes /api/v1/query/explain request.
+//
+// It returns the optimized query together with the estimated number of series and samples the query selects,
+// without executing the query. Range query is explained if `start` query arg is set. Otherwise instant query is explained.
+func QueryExplainHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer queryExplainDuration.UpdateDuration(startTime)
+
+	ct := startTime.UnixNano() / 1e6
+	deadline := searchutils.GetDeadlineForQuery(r, startTime)
+	mayCache := !httputils.GetBool(r, "nocache")
+	query := r.FormValue("query")
+	if len(query) == 0 {
+		return fmt.Errorf("missing `query` arg")
+	}
+	if len(query) > maxQueryLen.IntN() {
+		return fmt.Errorf("too long query; got %d bytes; mustn't exceed `-search.maxQueryLen=%d` bytes", len(query), maxQueryLen.N)
+	}
+	lookbackDelta, err := getMaxLookback(r)
+	if err != nil {
+		return err
+	}
+	var start, end, step int64
+	if len(r.FormValue("start")) > 0 {
+		start, err = httputils.GetTime(r, "start", ct-defaultStep)
+		if err != nil {
+			return err
+		}
+		end, err = httputils.GetTime(r, "end", ct)
+		if err != nil {
+			return err
+		}
+		step, err = httputils.GetDuration(r, "step", defaultStep)
+		if err != nil {
+			return err
+		}
+		if start > end {
+			end = start + defaultStep
+		}
+		if err := promql.ValidateMaxPointsPerSeries(start, end, step, *maxPointsPerTimeseries); err != nil {
+			return fmt.Errorf("%w; (see -search.maxPointsPerTimeseries command-line flag)", err)
+		}
+		if mayCache {
+			start, end = promql.AdjustStartEnd(start, end, step)
+		}
+	} else {
+		start, err = httputils.GetTime(r, "time", ct)
+		if err != nil {
+			return err
+		}
+		end = start
+		step, err = httputils.GetDuration(r, "step", lookbackDelta)
+		if err != nil {
+			return err
+		}
+	}
+	if step <= 0 {
+		step = defaultStep
+	}
+	scrapeInterval, err := httputils.GetDuration(r, "scrape_interval", defaultScrapeIntervalForExplain)
+	if err != nil {
+		return err
+	}
+	etfs, err := searchutils.GetExtraTagFilters(r)
+	if err != nil {
+		return err
+	}
+	ec := &promql.EvalConfig{
+		Start:               start,
+		End:                 end,
+		Step:                step,
+		MaxPointsPerSeries:  *maxPointsPerTimeseries,
+		MaxSeries:           GetMaxUniqueTimeSeries(),
+		QuotedRemoteAddr:    httpserver.GetQuotedRemoteAddr(r),
+		Deadline:            deadline,
+		MayCache:            mayCache,
+		LookbackDelta:       lookbackDelta,
+		EnforcedTagFilterss: etfs,
+		GetRequestURI: func() string {
+			return httpserver.GetRequestURI(r)
+		},
+	}
+	qe, err := promql.Explain(qt, ec, query, scrapeInterval)
+	if err != nil {
+		return fmt.Errorf("cannot explain query=%q: %w", query, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	WriteQueryExplainResponse(bw, qe, netstorage.GetMaxSamplesPerQuery(), qt)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send query explain response to remote client: %w", err)
+	}
+	return nil
+}
+
+// defaultScrapeIntervalForExplain is the default scrape interval in milliseconds used for estimating the number of samples at /api/v1/query/explain.
+const defaultScrapeIntervalForExplain = 30 * 1000
+
+var queryExplainDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/query/explain"}`)
+
 var nan = math.NaN()
 
 // adjustLastPoints substitutes the last point values on the time range (start..end]
@@ -26,124 +26,197 @@ var (
 )
 
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:8
-func StreamTSDBStatusResponse(qw422016 *qt422016.Writer, status *storage.TSDBStatus, qt *querytracer.Tracer) {
+func StreamTSDBStatusResponse(qw422016 *qt422016.Writer, status *storage.TSDBStatus, diff *storage.TSDBStatusDiff, qt *querytracer.Tracer) {
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:8
-	qw422016.N().S(`{"status":"success","data":{"totalSeries":`)
+	qw422016.N().S(`{"status":"success","data":{`)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:12
-	qw422016.N().DUL(status.TotalSeries)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:12
-	qw422016.N().S(`,"totalLabelValuePairs":`)
+	streamtsdbStatusData(qw422016, status)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:13
-	qw422016.N().DUL(status.TotalLabelValuePairs)
+	if diff != nil {
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:13
-	qw422016.N().S(`,"seriesCountByMetricName":`)
+		qw422016.N().S(`,"newSeries":{`)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:14
-	streamtsdbStatusEntries(qw422016, status.SeriesCountByMetricName)
+		streamtsdbStatusData(qw422016, diff.NewSeries)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:14
-	qw422016.N().S(`,"seriesCountByLabelName":`)
+		qw422016.N().S(`},"disappearedSeries":{`)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:15
-	streamtsdbStatusEntries(qw422016, status.SeriesCountByLabelName)
+		streamtsdbStatusData(qw422016, diff.DisappearedSeries)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:15
-	qw422016.N().S(`,"seriesCountByFocusLabelValue":`)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:16
-	streamtsdbStatusEntries(qw422016, status.SeriesCountByFocusLabelValue)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:16
-	qw422016.N().S(`,"seriesCountByLabelValuePair":`)
+		qw422016.N().S(`},"seriesChurn":{"netCreatedSeriesPerHour":`)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:17
-	streamtsdbStatusEntries(qw422016, status.SeriesCountByLabelValuePair)
+		qw422016.N().F(diff.Churn.NetCreatedSeriesPerHour)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:17
-	qw422016.N().S(`,"labelValueCountByLabelName":`)
+		qw422016.N().S(`,"netDisappearedSeriesPerHour":`)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:18
-	streamtsdbStatusEntries(qw422016, status.LabelValueCountByLabelName)
+		qw422016.N().F(diff.Churn.NetDisappearedSeriesPerHour)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:18
-	qw422016.N().S(`}`)
+		qw422016.N().S(`,"currentHourSeries":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:19
+		qw422016.N().DUL(diff.Churn.CurrentHourSeries)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:19
+		qw422016.N().S(`,"prevHourSeries":`)
 //line app/vmselect/prometheus/tsdb_status_response.qtpl:20
+		qw422016.N().DUL(diff.Churn.PrevHourSeries)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:20
+		qw422016.N().S(`,"currentHourCreatedSeries":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:21
+		qw422016.N().DUL(diff.Churn.CurrentHourCreatedSeries)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:21
+		qw422016.N().S(`,"currentHourDisappearedSeries":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:22
+		qw422016.N().DUL(diff.Churn.CurrentHourDisappearedSeries)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:22
+		qw422016.N().S(`}`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:24
+	}
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:24
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:26
 	qt.Done()
 
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:21
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:27
 	streamdumpQueryTrace(qw422016, qt)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:21
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:27
 	qw422016.N().S(`}`)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 }
 
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
-func WriteTSDBStatusResponse(qq422016 qtio422016.Writer, status *storage.TSDBStatus, qt *querytracer.Tracer) {
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
+func WriteTSDBStatusResponse(qq422016 qtio422016.Writer, status *storage.TSDBStatus, diff *storage.TSDBStatusDiff, qt *querytracer.Tracer) {
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
-	StreamTSDBStatusResponse(qw422016, status, qt)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
+	StreamTSDBStatusResponse(qw422016, status, diff, qt)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 }
 
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
-func TSDBStatusResponse(status *storage.TSDBStatus, qt *querytracer.Tracer) string {
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
+func TSDBStatusResponse(status *storage.TSDBStatus, diff *storage.TSDBStatusDiff, qt *querytracer.Tracer) string {
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
-	WriteTSDBStatusResponse(qb422016, status, qt)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
+	WriteTSDBStatusResponse(qb422016, status, diff, qt)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 	qs422016 := string(qb422016.B)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 	return qs422016
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:23
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
 }
 
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:25
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:31
+func streamtsdbStatusData(qw422016 *qt422016.Writer, status *storage.TSDBStatus) {
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:31
+	qw422016.N().S(`"totalSeries":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:32
+	qw422016.N().DUL(status.TotalSeries)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:32
+	qw422016.N().S(`,"totalLabelValuePairs":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:33
+	qw422016.N().DUL(status.TotalLabelValuePairs)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:33
+	qw422016.N().S(`,"seriesCountByMetricName":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:34
+	streamtsdbStatusEntries(qw422016, status.SeriesCountByMetricName)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:34
+	qw422016.N().S(`,"seriesCountByLabelName":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+	streamtsdbStatusEntries(qw422016, status.SeriesCountByLabelName)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+	qw422016.N().S(`,"seriesCountByFocusLabelValue":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:36
+	streamtsdbStatusEntries(qw422016, status.SeriesCountByFocusLabelValue)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:36
+	qw422016.N().S(`,"seriesCountByLabelValuePair":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:37
+	streamtsdbStatusEntries(qw422016, status.SeriesCountByLabelValuePair)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:37
+	qw422016.N().S(`,"labelValueCountByLabelName":`)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:38
+	streamtsdbStatusEntries(qw422016, status.LabelValueCountByLabelName)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+}
+
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+func writetsdbStatusData(qq422016 qtio422016.Writer, status *storage.TSDBStatus) {
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+	streamtsdbStatusData(qw422016, status)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+}
+
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+func tsdbStatusData(status *storage.TSDBStatus) string {
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+	writetsdbStatusData(qb422016, status)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+	return qs422016
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:39
+}
+
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:41
 func streamtsdbStatusEntries(qw422016 *qt422016.Writer, a []storage.TopHeapEntry) {
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:25
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:41
 	qw422016.N().S(`[`)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:27
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:43
 	for i, e := range a {
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:27
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:43
 		qw422016.N().S(`{"name":`)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:45
 		qw422016.N().Q(e.Name)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:29
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:45
 		qw422016.N().S(`,"value":`)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:30
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:46
 		qw422016.N().D(int(e.Count))
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:30
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:46
 		qw422016.N().S(`}`)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:32
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:48
 		if i+1 < len(a) {
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:32
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:48
 			qw422016.N().S(`,`)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:32
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:48
 		}
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:33
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:49
 	}
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:33
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:49
 	qw422016.N().S(`]`)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 }
 
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 func writetsdbStatusEntries(qq422016 qtio422016.Writer, a []storage.TopHeapEntry) {
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 	streamtsdbStatusEntries(qw422016, a)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 }
 
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 func tsdbStatusEntries(a []storage.TopHeapEntry) string {
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 	writetsdbStatusEntries(qb422016, a)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 	qs422016 := string(qb422016.B)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 	return qs422016
-//line app/vmselect/prometheus/tsdb_status_response.qtpl:35
+//line app/vmselect/prometheus/tsdb_status_response.qtpl:51
 }
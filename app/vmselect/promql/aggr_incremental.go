@@ -175,6 +175,19 @@ type incrementalAggrFuncCallbacks struct {
 	keepOriginal bool
 }
 
+// IsIncrementalAggrExpr returns true if e is calculated via optimized incremental aggregation.
+//
+// Such expressions are aggregate functions over rollup functions over series selectors, e.g. `sum(rate(foo[5m])) by (job)`.
+// They do not require loading all the matching series into memory.
+func IsIncrementalAggrExpr(e metricsql.Expr) bool {
+	ae, ok := e.(*metricsql.AggrFuncExpr)
+	if !ok || getIncrementalAggrFuncCallbacks(ae.Name) == nil {
+		return false
+	}
+	fe, _ := tryGetArgRollupFuncWithMetricExpr(ae)
+	return fe != nil
+}
+
 func getIncrementalAggrFuncCallbacks(name string) *incrementalAggrFuncCallbacks {
 	name = strings.ToLower(name)
 	return incrementalAggrFuncCallbacksMap[name]
@@ -189,3 +189,30 @@ func compareValues(vs1, vs2 []float64) error {
 	}
 	return nil
 }
+
+func TestIsIncrementalAggrExpr(t *testing.T) {
+	f := func(q string, resultExpected bool) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("unexpected error in metricsql.Parse(%q): %s", q, err)
+		}
+		result := IsIncrementalAggrExpr(e)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for IsIncrementalAggrExpr(%q); got %v; want %v", q, result, resultExpected)
+		}
+	}
+
+	f(`sum(foo)`, true)
+	f(`sum(rate(foo[5m])) by (job)`, true)
+	f(`max without (instance) (max_over_time(foo{bar="baz"}[1h]))`, true)
+	f(`count(foo[5m])`, true)
+
+	f(`foo`, false)
+	f(`rate(foo[5m])`, false)
+	f(`sum(rate(foo[5m])) * 2`, false)
+	f(`sum(rate(foo[5m]) * 2)`, false)
+	f(`sum(max_over_time(rate(foo[5m])[1h:1m]))`, false)
+	f(`quantile(0.5, rate(foo[5m]))`, false)
+	f(`topk(3, rate(foo[5m]))`, false)
+}
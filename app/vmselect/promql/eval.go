@@ -145,6 +145,11 @@ type EvalConfig struct {
 	// The caller must initialize QueryStats, otherwise it isn't collected.
 	QueryStats *QueryStats
 
+	// splitTimeRangeID is the id of the sub-range if the query is split into sub-ranges according to -search.splitQueryInterval.
+	//
+	// It is used for caching results for every sub-range independently in the rollup result cache.
+	splitTimeRangeID int64
+
 	timestamps     []int64
 	timestampsOnce sync.Once
 }
@@ -164,6 +169,7 @@ func copyEvalConfig(src *EvalConfig) *EvalConfig {
 	ec.EnforcedTagFilterss = src.EnforcedTagFilterss
 	ec.GetRequestURI = src.GetRequestURI
 	ec.QueryStats = src.QueryStats
+	ec.splitTimeRangeID = src.splitTimeRangeID
 
 	// do not copy src.timestamps - they must be generated again.
 	return &ec
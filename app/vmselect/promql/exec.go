@@ -65,7 +65,12 @@ func Exec(qt *querytracer.Tracer, ec *EvalConfig, q string, isFirstPointOnly boo
 	}
 
 	qid := activeQueriesV.Add(ec, q)
-	rv, err := evalExpr(qt, ec, e)
+	var rv []*timeseries
+	if isFirstPointOnly {
+		rv, err = evalExpr(qt, ec, e)
+	} else {
+		rv, err = evalExprMaybeSplit(qt, ec, e)
+	}
 	activeQueriesV.Remove(qid)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,271 @@
+package promql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// QueryExplanation contains the execution plan and the estimated cost for MetricsQL query.
+type QueryExplanation struct {
+	// Query is the original query.
+	Query string
+
+	// OptimizedQuery is the query after the optimizations, which are applied before the query execution.
+	OptimizedQuery string
+
+	// Selectors contains the explanation for every series selector in the OptimizedQuery.
+	Selectors []SelectorExplanation
+
+	// SeriesCount is the estimated number of series the query selects.
+	SeriesCount int
+
+	// SamplesCount is the estimated number of raw samples the query reads.
+	SamplesCount int64
+}
+
+// SelectorExplanation contains the execution plan and the estimated cost for a single series selector.
+type SelectorExplanation struct {
+	// Selector is the series selector.
+	Selector string
+
+	// Expr is the rollup expression, which contains the Selector.
+	Expr string
+
+	// Window is the lookbehind window in milliseconds for the rollup function.
+	Window int64
+
+	// Start and End is the time range in milliseconds for the raw samples, which must be read for the Selector.
+	Start int64
+	End   int64
+
+	// SeriesCount is the number of series matching the Selector on the [Start...End] time range.
+	SeriesCount int
+
+	// SamplesCount is the estimated number of raw samples, which must be read for the Selector.
+	SamplesCount int64
+
+	// RollupCache contains the state of rollup result cache for Expr.
+	//
+	// It may contain the following values:
+	//
+	//   - disabled - the cache isn't used for Expr. For example, if nocache=1 query arg is passed or if start and end aren't aligned to step.
+	//   - instant - the cache isn't used for instant queries except of a few optimized cases.
+	//   - miss - the cache doesn't contain results for Expr.
+	//   - partial - the cache contains results for Expr on the beginning of the time range, so only the remaining part must be calculated.
+	//   - full - the cache contains results for Expr on the whole time range, so no raw samples must be read.
+	RollupCache string
+
+	// Error contains an error, which occurred during the estimation.
+	//
+	// For example, the number of matching series may exceed -search.maxUniqueTimeseries.
+	Error string
+}
+
+// Explain returns the execution plan and the estimated cost for the query q evaluated with ec.
+//
+// The number of matching series is obtained from the index without reading raw samples.
+// The number of raw samples is estimated from the number of matching series, the time range and the scrapeInterval in milliseconds.
+func Explain(qt *querytracer.Tracer, ec *EvalConfig, q string, scrapeInterval int64) (*QueryExplanation, error) {
+	if scrapeInterval <= 0 {
+		return nil, fmt.Errorf("scrapeInterval must be positive; got %d", scrapeInterval)
+	}
+	e, err := parsePromQLWithCache(q)
+	if err != nil {
+		return nil, err
+	}
+	ec.validate()
+
+	ex := &explainer{
+		qt:             qt,
+		scrapeInterval: scrapeInterval,
+	}
+	if err := ex.explainExpr(ec, e); err != nil {
+		return nil, err
+	}
+	qe := &QueryExplanation{
+		Query:          q,
+		OptimizedQuery: string(e.AppendString(nil)),
+		Selectors:      ex.selectors,
+	}
+	for _, se := range ex.selectors {
+		qe.SeriesCount += se.SeriesCount
+		qe.SamplesCount += se.SamplesCount
+	}
+	return qe, nil
+}
+
+type explainer struct {
+	qt             *querytracer.Tracer
+	scrapeInterval int64
+	selectors      []SelectorExplanation
+}
+
+// explainExpr walks e in the same way as evalExprInternal does.
+func (ex *explainer) explainExpr(ec *EvalConfig, e metricsql.Expr) error {
+	switch t := e.(type) {
+	case *metricsql.MetricExpr:
+		re := &metricsql.RollupExpr{
+			Expr: t,
+		}
+		return ex.explainRollup(ec, "default_rollup", e, re)
+	case *metricsql.RollupExpr:
+		return ex.explainRollup(ec, "default_rollup", e, t)
+	case *metricsql.FuncExpr:
+		if getRollupFunc(t.Name) == nil {
+			return ex.explainExprs(ec, t.Args)
+		}
+		rollupArgIdx := metricsql.GetRollupArgIdx(t)
+		if rollupArgIdx >= len(t.Args) {
+			return fmt.Errorf("expecting at least %d args to %q; got %d args; expr: %q", rollupArgIdx+1, t.Name, len(t.Args), t.AppendString(nil))
+		}
+		for i, arg := range t.Args {
+			if i == rollupArgIdx {
+				continue
+			}
+			if err := ex.explainExpr(ec, arg); err != nil {
+				return err
+			}
+		}
+		re := getRollupExprArg(t.Args[rollupArgIdx])
+		return ex.explainRollup(ec, t.Name, e, re)
+	case *metricsql.AggrFuncExpr:
+		if callbacks := getIncrementalAggrFuncCallbacks(t.Name); callbacks != nil {
+			fe, _ := tryGetArgRollupFuncWithMetricExpr(t)
+			if fe != nil {
+				re := getRollupExprArg(fe.Args[metricsql.GetRollupArgIdx(fe)])
+				return ex.explainRollup(ec, fe.Name, e, re)
+			}
+		}
+		return ex.explainExprs(ec, t.Args)
+	case *metricsql.BinaryOpExpr:
+		return ex.explainExprs(ec, []metricsql.Expr{t.Left, t.Right})
+	default:
+		return nil
+	}
+}
+
+func (ex *explainer) explainExprs(ec *EvalConfig, es []metricsql.Expr) error {
+	for _, e := range es {
+		if err := ex.explainExpr(ec, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// explainRollup explains funcName over re, which is evaluated as a part of expr.
+func (ex *explainer) explainRollup(ec *EvalConfig, funcName string, expr metricsql.Expr, re *metricsql.RollupExpr) error {
+	funcName = strings.ToLower(funcName)
+	ecNew := ec
+	if re.At != nil {
+		// Only numeric `@` modifiers can be evaluated without the query execution.
+		// Other `@` modifiers are explained separately, while the rollup is explained on the whole time range.
+		if ne, ok := re.At.(*metricsql.NumberExpr); ok {
+			atTimestamp := int64(ne.N * 1000)
+			ecNew = copyEvalConfig(ecNew)
+			ecNew.Start = atTimestamp
+			ecNew.End = atTimestamp
+		} else if err := ex.explainExpr(ec, re.At); err != nil {
+			return err
+		}
+	}
+	if re.Offset != nil {
+		offset := re.Offset.Duration(ec.Step)
+		ecNew = copyEvalConfig(ecNew)
+		ecNew.Start -= offset
+		ecNew.End -= offset
+	}
+	if funcName == "rollup_candlestick" {
+		step := ecNew.Step
+		ecNew = copyEvalConfig(ecNew)
+		ecNew.Start += step
+		ecNew.End += step
+	}
+	window, err := re.Window.NonNegativeDuration(ec.Step)
+	if err != nil {
+		return fmt.Errorf("cannot parse lookbehind window in square brackets at %s: %w", expr.AppendString(nil), err)
+	}
+
+	me, ok := re.Expr.(*metricsql.MetricExpr)
+	if !ok {
+		// Subquery
+		step, err := re.Step.NonNegativeDuration(ec.Step)
+		if err != nil {
+			return fmt.Errorf("cannot parse step in square brackets at %s: %w", expr.AppendString(nil), err)
+		}
+		if step == 0 {
+			step = ec.Step
+		}
+		ecSQ := copyEvalConfig(ecNew)
+		ecSQ.Start -= window + step + maxSilenceInterval()
+		ecSQ.End += step
+		ecSQ.Step = step
+		ecSQ.MaxPointsPerSeries = *maxPointsSubqueryPerTimeseries
+		if err := ValidateMaxPointsPerSeries(ecSQ.Start, ecSQ.End, ecSQ.Step, ecSQ.MaxPointsPerSeries); err != nil {
+			return fmt.Errorf("%w; (see -search.maxPointsSubqueryPerTimeseries command-line flag)", err)
+		}
+		ecSQ.Start, ecSQ.End = alignStartEnd(ecSQ.Start, ecSQ.End, ecSQ.Step)
+		return ex.explainExpr(ecSQ, re.Expr)
+	}
+	if me.IsEmpty() {
+		return nil
+	}
+
+	se := SelectorExplanation{
+		Selector: string(me.AppendString(nil)),
+		Expr:     string(expr.AppendString(nil)),
+		Window:   window,
+	}
+	var start int64
+	se.RollupCache, start = ex.getRollupCacheState(ecNew, expr, window)
+	if se.RollupCache == "full" {
+		ex.selectors = append(ex.selectors, se)
+		return nil
+	}
+	if needSilenceIntervalForRollupFunc[funcName] {
+		start -= maxSilenceInterval()
+	}
+	if window > ecNew.Step {
+		start -= window
+	} else {
+		start -= ecNew.Step
+	}
+	se.Start = start
+	se.End = ecNew.End
+
+	tfss := searchutils.ToTagFilterss(me.LabelFilterss)
+	tfss = searchutils.JoinTagFilterss(tfss, ecNew.EnforcedTagFilterss)
+	sq := storage.NewSearchQuery(se.Start, se.End, tfss, ecNew.MaxSeries)
+	n, err := netstorage.MatchingSeriesCount(ex.qt, sq, ecNew.Deadline)
+	if err != nil {
+		se.Error = err.Error()
+	}
+	se.SeriesCount = n
+	se.SamplesCount = int64(n) * (1 + (se.End-se.Start)/ex.scrapeInterval)
+	ex.selectors = append(ex.selectors, se)
+	return nil
+}
+
+// getRollupCacheState returns rollup result cache state for expr and the start timestamp for the remaining time range, which isn't cached.
+func (ex *explainer) getRollupCacheState(ec *EvalConfig, expr metricsql.Expr, window int64) (string, int64) {
+	if !ec.mayCache() {
+		return "disabled", ec.Start
+	}
+	if ec.Start == ec.End {
+		return "instant", ec.Start
+	}
+	_, start := rollupResultCacheV.GetSeries(ex.qt, ec, expr, window)
+	if start > ec.End {
+		return "full", start
+	}
+	if start > ec.Start {
+		return "partial", start
+	}
+	return "miss", start
+}
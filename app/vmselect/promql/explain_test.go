@@ -0,0 +1,116 @@
+package promql
+
+import (
+	"testing"
+)
+
+func TestExplainFailure(t *testing.T) {
+	f := func(q string, scrapeInterval int64) {
+		t.Helper()
+		ec := &EvalConfig{
+			Start: 1000,
+			End:   2000,
+			Step:  200,
+		}
+		qe, err := Explain(nil, ec, q, scrapeInterval)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for query=%q", q)
+		}
+		if qe != nil {
+			t.Fatalf("expecting nil explanation for query=%q", q)
+		}
+	}
+
+	// invalid query
+	f("foo(", 1000)
+	f("", 1000)
+
+	// invalid scrape interval
+	f("foo", 0)
+	f("foo", -1000)
+}
+
+func TestExplainWithoutSelectors(t *testing.T) {
+	f := func(q, optimizedQueryExpected string) {
+		t.Helper()
+		ec := &EvalConfig{
+			Start: 1000,
+			End:   2000,
+			Step:  200,
+		}
+		qe, err := Explain(nil, ec, q, 1000)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if qe.OptimizedQuery != optimizedQueryExpected {
+			t.Fatalf("unexpected optimized query; got %q; want %q", qe.OptimizedQuery, optimizedQueryExpected)
+		}
+		if len(qe.Selectors) != 0 {
+			t.Fatalf("unexpected selectors: %v", qe.Selectors)
+		}
+		if qe.SeriesCount != 0 || qe.SamplesCount != 0 {
+			t.Fatalf("unexpected non-zero series=%d and samples=%d", qe.SeriesCount, qe.SamplesCount)
+		}
+	}
+
+	f("1 + 2", "3")
+	f("time() > 10", "time() > 10")
+	f(`label_set(time(), "foo", "bar")`, `label_set(time(), "foo", "bar")`)
+}
+
+func TestExplainFullRollupCacheHit(t *testing.T) {
+	InitRollupResultCache("")
+	defer StopRollupResultCache()
+
+	f := func(q, cacheKeyExpr string, window int64) {
+		t.Helper()
+		ResetRollupResultCache()
+		ec := &EvalConfig{
+			Start:              1000,
+			End:                2000,
+			Step:               200,
+			MaxPointsPerSeries: 1e4,
+
+			MayCache: true,
+		}
+		expr, err := parsePromQLWithCache(cacheKeyExpr)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", cacheKeyExpr, err)
+		}
+		tss := []*timeseries{
+			{
+				Timestamps: []int64{1000, 1200, 1400, 1600, 1800, 2000},
+				Values:     []float64{0, 1, 2, 3, 4, 5},
+			},
+		}
+		rollupResultCacheV.PutSeries(nil, ec, expr, window, tss)
+
+		qe, err := Explain(nil, ec, q, 1000)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(qe.Selectors) != 1 {
+			t.Fatalf("unexpected number of selectors; got %d; want 1", len(qe.Selectors))
+		}
+		se := qe.Selectors[0]
+		if se.Expr != cacheKeyExpr {
+			t.Fatalf("unexpected expr; got %q; want %q", se.Expr, cacheKeyExpr)
+		}
+		if se.Window != window {
+			t.Fatalf("unexpected window; got %d; want %d", se.Window, window)
+		}
+		if se.RollupCache != "full" {
+			t.Fatalf("unexpected rollup cache state; got %q; want %q", se.RollupCache, "full")
+		}
+		if qe.SeriesCount != 0 || qe.SamplesCount != 0 {
+			t.Fatalf("unexpected non-zero series=%d and samples=%d for fully cached query", qe.SeriesCount, qe.SamplesCount)
+		}
+	}
+
+	f(`foo{bar="baz"}`, `foo{bar="baz"}`, 0)
+	f(`rate(foo[5m])`, `rate(foo[5m])`, 300e3)
+	f(`abs(rate(foo[5m]))`, `rate(foo[5m])`, 300e3)
+
+	// incremental aggregate function over rollup function is cached as a whole
+	f(`sum(rate(foo[5m])) by (job)`, `sum(rate(foo[5m])) by(job)`, 300e3)
+}
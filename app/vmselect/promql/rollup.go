@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -700,35 +701,14 @@ func (rc *rollupConfig) doInternal(dstValues []float64, tsm *timeseriesMap, valu
 	dstValues = decimal.ExtendFloat64sCapacity(dstValues, len(rc.Timestamps))
 
 	scrapeInterval := getScrapeInterval(timestamps, rc.Step)
-	maxPrevInterval := getMaxPrevInterval(scrapeInterval)
-	if rc.LookbackDelta > 0 && maxPrevInterval > rc.LookbackDelta {
-		maxPrevInterval = rc.LookbackDelta
-	}
-	if *minStalenessInterval > 0 {
-		if msi := minStalenessInterval.Milliseconds(); msi > 0 && maxPrevInterval < msi {
-			maxPrevInterval = msi
-		}
-	}
-	window := rc.Window
-	if window <= 0 {
-		window = rc.Step
-		if rc.MayAdjustWindow && window < maxPrevInterval {
-			// Adjust lookbehind window only if it isn't set explicitly, e.g. rate(foo).
-			// In the case of missing lookbehind window it should be adjusted in order to return non-empty graph
-			// when the window doesn't cover at least two raw samples (this is what most users expect).
-			//
-			// If the user explicitly sets the lookbehind window to some fixed value, e.g. rate(foo[1s]),
-			// then it is expected he knows what he is doing. Do not adjust the lookbehind window then.
-			//
-			// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/3483
-			window = maxPrevInterval
-		}
-		if rc.isDefaultRollup && rc.LookbackDelta > 0 && window > rc.LookbackDelta {
-			// Implicit window exceeds -search.maxStalenessInterval, so limit it to -search.maxStalenessInterval
-			// according to https://github.com/VictoriaMetrics/VictoriaMetrics/issues/784
-			window = rc.LookbackDelta
-		}
-	}
+	window, maxPrevInterval := rc.getWindowAndMaxPrevInterval(scrapeInterval)
+
+	// Time series may have distinct resolutions on the selected time range if downsampling is enabled.
+	// Estimate the scrape interval individually per each point in this case, so implicit lookbehind window
+	// and staleness detection are properly adjusted to the resolution of the data.
+	// See https://docs.victoriametrics.com/#downsampling
+	hasMixedResolution := storage.IsDownsamplingEnabled() && isMixedResolution(timestamps, scrapeInterval, rc.Step)
+
 	rfa := getRollupFuncArg()
 	rfa.idx = 0
 	rfa.window = window
@@ -742,6 +722,17 @@ func (rc *rollupConfig) doInternal(dstValues []float64, tsm *timeseriesMap, valu
 	samplesScanned := uint64(len(values))
 	samplesScannedPerCall := uint64(rc.samplesScannedPerCall)
 	for _, tEnd := range rc.Timestamps {
+		if hasMixedResolution {
+			window, maxPrevInterval = rc.getWindowAndMaxPrevInterval(getLocalScrapeInterval(timestamps, tEnd, rc.Step))
+			rfa.window = window
+			if i > 0 && timestamps[i-1] > tEnd-window {
+				// The window has been increased, so the start index must be moved back.
+				i = sort.Search(i, func(n int) bool {
+					return timestamps[n] > tEnd-window
+				})
+				ni = 0
+			}
+		}
 		tStart := tEnd - window
 		ni = seekFirstTimestampIdxAfter(timestamps[i:], tStart, ni)
 		i += ni
@@ -784,6 +775,68 @@ func (rc *rollupConfig) doInternal(dstValues []float64, tsm *timeseriesMap, valu
 	return dstValues, samplesScanned
 }
 
+func (rc *rollupConfig) getWindowAndMaxPrevInterval(scrapeInterval int64) (int64, int64) {
+	maxPrevInterval := getMaxPrevInterval(scrapeInterval)
+	if rc.LookbackDelta > 0 && maxPrevInterval > rc.LookbackDelta {
+		maxPrevInterval = rc.LookbackDelta
+	}
+	if *minStalenessInterval > 0 {
+		if msi := minStalenessInterval.Milliseconds(); msi > 0 && maxPrevInterval < msi {
+			maxPrevInterval = msi
+		}
+	}
+	window := rc.Window
+	if window <= 0 {
+		window = rc.Step
+		if rc.MayAdjustWindow && window < maxPrevInterval {
+			// Adjust lookbehind window only if it isn't set explicitly, e.g. rate(foo).
+			// In the case of missing lookbehind window it should be adjusted in order to return non-empty graph
+			// when the window doesn't cover at least two raw samples (this is what most users expect).
+			//
+			// If the user explicitly sets the lookbehind window to some fixed value, e.g. rate(foo[1s]),
+			// then it is expected he knows what he is doing. Do not adjust the lookbehind window then.
+			//
+			// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/3483
+			window = maxPrevInterval
+		}
+		if rc.isDefaultRollup && rc.LookbackDelta > 0 && window > rc.LookbackDelta {
+			// Implicit window exceeds -search.maxStalenessInterval, so limit it to -search.maxStalenessInterval
+			// according to https://github.com/VictoriaMetrics/VictoriaMetrics/issues/784
+			window = rc.LookbackDelta
+		}
+	}
+	return window, maxPrevInterval
+}
+
+// isMixedResolution returns true if the interval between the last timestamps differs from scrapeInterval
+// estimated for the first timestamps by more than two times.
+func isMixedResolution(timestamps []int64, scrapeInterval, defaultInterval int64) bool {
+	if len(timestamps) <= scrapeIntervalSamples+1 {
+		return false
+	}
+	lastScrapeInterval := getScrapeInterval(timestamps[len(timestamps)-scrapeIntervalSamples-1:], defaultInterval)
+	return lastScrapeInterval > 2*scrapeInterval || scrapeInterval > 2*lastScrapeInterval
+}
+
+// getLocalScrapeInterval estimates the scrape interval for samples preceding tEnd.
+func getLocalScrapeInterval(timestamps []int64, tEnd, defaultInterval int64) int64 {
+	n := sort.Search(len(timestamps), func(i int) bool {
+		return timestamps[i] > tEnd
+	})
+	start := n - scrapeIntervalSamples - 1
+	if start < 0 {
+		start = 0
+	}
+	if n-start < 2 {
+		// There are no enough samples before tEnd. Use the samples after tEnd.
+		n = start + scrapeIntervalSamples + 1
+		if n > len(timestamps) {
+			n = len(timestamps)
+		}
+	}
+	return getScrapeInterval(timestamps[start:n], defaultInterval)
+}
+
 func seekFirstTimestampIdxAfter(timestamps []int64, seekTimestamp int64, nHint int) int {
 	if len(timestamps) == 0 || timestamps[0] > seekTimestamp {
 		return 0
@@ -836,6 +889,9 @@ func binarySearchInt64(a []int64, v int64) uint {
 	return i
 }
 
+// scrapeIntervalSamples is the number of intervals between samples used for scrape interval estimation.
+const scrapeIntervalSamples = 20
+
 func getScrapeInterval(timestamps []int64, defaultInterval int64) int64 {
 	if len(timestamps) < 2 {
 		// can't calculate scrape interval with less than 2 timestamps
@@ -843,11 +899,11 @@ func getScrapeInterval(timestamps []int64, defaultInterval int64) int64 {
 		return defaultInterval
 	}
 
-	// Estimate scrape interval as 0.6 quantile for the first 20 intervals.
+	// Estimate scrape interval as 0.6 quantile for the first scrapeIntervalSamples intervals.
 	tsPrev := timestamps[0]
 	timestamps = timestamps[1:]
-	if len(timestamps) > 20 {
-		timestamps = timestamps[:20]
+	if len(timestamps) > scrapeIntervalSamples {
+		timestamps = timestamps[:scrapeIntervalSamples]
 	}
 	a := getFloat64s()
 	intervals := a.A[:0]
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/VictoriaMetrics/metricsql"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 )
 
 var (
@@ -1587,3 +1589,79 @@ func TestRollupDelta(t *testing.T) {
 	f(1, nan, nan, nil, 0)
 	f(100, nan, nan, nil, 0)
 }
+
+func TestRollupMixedResolution(t *testing.T) {
+	if err := storage.SetDownsamplingPeriods([]string{"1h:1m"}); err != nil {
+		t.Fatalf("cannot set downsampling periods: %s", err)
+	}
+	defer func() {
+		if err := storage.SetDownsamplingPeriods(nil); err != nil {
+			t.Fatalf("cannot reset downsampling periods: %s", err)
+		}
+	}()
+
+	// Downsampled samples with 1m interval are followed by raw samples with 10s interval.
+	var timestamps []int64
+	for ts := int64(0); ts < 1800e3; ts += 60e3 {
+		timestamps = append(timestamps, ts)
+	}
+	for ts := int64(1800e3); ts <= 2400e3; ts += 10e3 {
+		timestamps = append(timestamps, ts)
+	}
+	values := make([]float64, len(timestamps))
+	for i := range values {
+		values[i] = float64(i)
+	}
+
+	f := func(tEnd int64, valueExpected float64) {
+		t.Helper()
+		rc := rollupConfig{
+			Func:               rollupCount,
+			Start:              tEnd,
+			End:                tEnd,
+			Step:               5e3,
+			Window:             0,
+			MaxPointsPerSeries: 1e4,
+			MayAdjustWindow:    true,
+		}
+		rc.Timestamps = rc.getTimestamps()
+		gotValues, _ := rc.Do(nil, values, timestamps)
+		testRowsEqual(t, gotValues, rc.Timestamps, []float64{valueExpected}, []int64{tEnd})
+	}
+
+	// The implicit window must be adjusted to the resolution of samples around the given point.
+	f(1500e3, 2)
+	f(2400e3, 2)
+}
+
+func TestGetLocalScrapeInterval(t *testing.T) {
+	f := func(timestamps []int64, tEnd, resultExpected int64) {
+		t.Helper()
+		result := getLocalScrapeInterval(timestamps, tEnd, 123)
+		if result != resultExpected {
+			t.Fatalf("unexpected scrape interval at %d; got %d; want %d", tEnd, result, resultExpected)
+		}
+	}
+
+	var timestamps []int64
+	for ts := int64(0); ts < 3000; ts += 100 {
+		timestamps = append(timestamps, ts)
+	}
+	for ts := int64(3000); ts < 4000; ts += 10 {
+		timestamps = append(timestamps, ts)
+	}
+
+	f(nil, 100, 123)
+	f(timestamps, -100, 100)
+	f(timestamps, 0, 100)
+	f(timestamps, 2500, 100)
+	f(timestamps, 3500, 10)
+	f(timestamps, 5000, 10)
+
+	if isMixedResolution(timestamps[:30], 100, 123) {
+		t.Fatalf("unexpected mixed resolution for samples with equal intervals")
+	}
+	if !isMixedResolution(timestamps, 100, 123) {
+		t.Fatalf("expecting mixed resolution")
+	}
+}
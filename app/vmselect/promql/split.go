@@ -0,0 +1,232 @@
+package promql
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/VictoriaMetrics/metricsql"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+var (
+	splitQueryInterval = flag.Duration("search.splitQueryInterval", 0, "The interval for splitting long-range queries at /api/v1/query_range into sub-ranges, "+
+		"which are evaluated in parallel and are cached independently in the rollup result cache. Sub-ranges are aligned to multiples of the interval in UTC, "+
+		"so re-querying a dashboard with a shifted time window re-uses cached results for the majority of sub-ranges. "+
+		"For example, -search.splitQueryInterval=1d splits a query over 90 days into 90 daily sub-ranges. Splitting is disabled if the interval equals to 0. "+
+		"See also -search.splitQueryConcurrency")
+	splitQueryConcurrency = flag.Int("search.splitQueryConcurrency", 4, "The maximum number of concurrently evaluated sub-ranges per query "+
+		"when the query is split according to -search.splitQueryInterval")
+)
+
+// maxSplitTimeRanges is the maximum number of sub-ranges a single query can be split into.
+//
+// Queries, which must be split into bigger number of sub-ranges, are evaluated without splitting,
+// since the overhead on evaluating small sub-ranges may exceed the benefits.
+const maxSplitTimeRanges = 1000
+
+var splitQueries = metrics.NewCounter(`vm_split_queries_total`)
+
+// evalExprMaybeSplit evaluates e on the time range from ec.
+//
+// The time range is split into sub-ranges according to -search.splitQueryInterval if this is allowed for e.
+func evalExprMaybeSplit(qt *querytracer.Tracer, ec *EvalConfig, e metricsql.Expr) ([]*timeseries, error) {
+	trs := getSplitTimeRanges(ec.Start, ec.End, ec.Step, splitQueryInterval.Milliseconds())
+	if len(trs) <= 1 || !canSplitExpr(e) {
+		return evalExpr(qt, ec, e)
+	}
+	splitQueries.Inc()
+	qtChild := qt.NewChild("split the query on timeRange=%s into %d sub-ranges", ec.timeRangeString(), len(trs))
+	tss, ok, err := evalSplitTimeRanges(qtChild, ec, e, trs)
+	qtChild.Done()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		qt.Printf("cannot stitch results for sub-ranges because of duplicate series; evaluate the query without splitting")
+		return evalExpr(qt, ec, e)
+	}
+	return tss, nil
+}
+
+type splitTimeRange struct {
+	// id is the 1-based index of the interval the sub-range belongs to.
+	//
+	// Zero id is reserved for queries without splitting.
+	id int64
+
+	start int64
+	end   int64
+}
+
+// getSplitTimeRanges splits [start..end] time range with the given step into sub-ranges aligned to the given interval.
+//
+// Every returned sub-range starts at start+N*step, so the points for sub-ranges are aligned with the points for the original time range.
+// nil is returned if the time range cannot be split.
+func getSplitTimeRanges(start, end, step, interval int64) []splitTimeRange {
+	if interval <= 0 || step <= 0 || step >= interval || start <= 0 || end-start < interval {
+		return nil
+	}
+	var trs []splitTimeRange
+	for start <= end {
+		if len(trs) >= maxSplitTimeRanges {
+			return nil
+		}
+		intervalEnd := (start/interval + 1) * interval
+		subEnd := start + ((intervalEnd-1-start)/step)*step
+		if subEnd > end {
+			subEnd = end
+		}
+		trs = append(trs, splitTimeRange{
+			id:    start/interval + 1,
+			start: start,
+			end:   subEnd,
+		})
+		start = subEnd + step
+	}
+	return trs
+}
+
+// canSplitExpr returns true if e can be evaluated independently on sub-ranges of the original time range.
+//
+// Functions, which calculate results over all the points on the selected time range, cannot be split.
+func canSplitExpr(e metricsql.Expr) bool {
+	ok := true
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		switch t := expr.(type) {
+		case *metricsql.RollupExpr:
+			if t.At != nil {
+				// The @ modifier may refer to start() and end() of the original time range.
+				ok = false
+			}
+		case *metricsql.FuncExpr:
+			name := strings.ToLower(t.Name)
+			if strings.HasPrefix(name, "range_") || strings.HasPrefix(name, "running_") || strings.HasPrefix(name, "sort") || nonSplittableFuncs[name] {
+				ok = false
+			}
+		case *metricsql.AggrFuncExpr:
+			name := strings.ToLower(t.Name)
+			if strings.HasPrefix(name, "topk") || strings.HasPrefix(name, "bottomk") || nonSplittableFuncs[name] {
+				ok = false
+			}
+			if t.Limit > 0 {
+				// The limit modifier selects the first N groups on the whole time range.
+				ok = false
+			}
+		}
+	})
+	return ok
+}
+
+// nonSplittableFuncs contains functions, which depend on all the points on the selected time range,
+// in addition to range_*, running_*, sort*, topk* and bottomk* functions.
+//
+// For example, any() and outliers_*() select series depending on the points on the whole time range,
+// so every sub-range could select distinct series.
+var nonSplittableFuncs = map[string]bool{
+	"any":                true,
+	"end":                true,
+	"interpolate":        true,
+	"keep_last_value":    true,
+	"keep_next_value":    true,
+	"limit_offset":       true,
+	"limitk":             true,
+	"outliers_iqr":       true,
+	"outliers_mad":       true,
+	"outliersk":          true,
+	"remove_resets":      true,
+	"smooth_exponential": true,
+	"start":              true,
+}
+
+// evalSplitTimeRanges evaluates e on the given sub-ranges of the ec time range and stitches the results.
+//
+// It returns false if the results cannot be stitched because of duplicate series.
+func evalSplitTimeRanges(qt *querytracer.Tracer, ec *EvalConfig, e metricsql.Expr, trs []splitTimeRange) ([]*timeseries, bool, error) {
+	tsss := make([][]*timeseries, len(trs))
+	errs := make([]error, len(trs))
+	concurrency := *splitQueryConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	concurrencyCh := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range trs {
+		tr := trs[i]
+		qtChild := qt.NewChild("eval sub-range #%d", i)
+		wg.Add(1)
+		concurrencyCh <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-concurrencyCh
+				wg.Done()
+			}()
+			ecSub := copyEvalConfig(ec)
+			ecSub.Start = tr.start
+			ecSub.End = tr.end
+			ecSub.splitTimeRangeID = tr.id
+			startTime := time.Now()
+			tsss[i], errs[i] = evalExpr(qtChild, ecSub, e)
+			qtChild.Donef("timeRange=%s, series=%d, duration=%s", ecSub.timeRangeString(), len(tsss[i]), time.Since(startTime))
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	return stitchSplitResults(ec, trs, tsss)
+}
+
+// stitchSplitResults merges series with identical names from tsss, which contain results for the corresponding sub-ranges trs of ec time range.
+//
+// It returns false if tsss contain duplicate series for the same sub-range.
+func stitchSplitResults(ec *EvalConfig, trs []splitTimeRange, tsss [][]*timeseries) ([]*timeseries, bool, error) {
+	type stitchedSeries struct {
+		ts           *timeseries
+		lastRangeIdx int
+	}
+	sharedTimestamps := ec.getSharedTimestamps()
+	var rvs []*timeseries
+	m := make(map[string]*stitchedSeries)
+	bb := bbPool.Get()
+	defer bbPool.Put(bb)
+	for i, tss := range tsss {
+		offset := int((trs[i].start - ec.Start) / ec.Step)
+		for _, ts := range tss {
+			if offset+len(ts.Values) > len(sharedTimestamps) || len(ts.Timestamps) > 0 && ts.Timestamps[0] != sharedTimestamps[offset] {
+				return nil, false, fmt.Errorf("BUG: points for the sub-range %d..%d don't match points for the original time range %s", trs[i].start, trs[i].end, ec.timeRangeString())
+			}
+			bb.B = marshalMetricNameSorted(bb.B[:0], &ts.MetricName)
+			ss := m[string(bb.B)]
+			if ss == nil {
+				dst := &timeseries{}
+				dst.MetricName.CopyFrom(&ts.MetricName)
+				dst.Values = make([]float64, len(sharedTimestamps))
+				for j := range dst.Values {
+					dst.Values[j] = nan
+				}
+				dst.Timestamps = sharedTimestamps
+				dst.denyReuse = true
+				ss = &stitchedSeries{
+					ts:           dst,
+					lastRangeIdx: -1,
+				}
+				m[string(bb.B)] = ss
+				rvs = append(rvs, dst)
+			}
+			if ss.lastRangeIdx == i {
+				// Duplicate series on the same sub-range
+				return nil, false, nil
+			}
+			ss.lastRangeIdx = i
+			copy(ss.ts.Values[offset:], ts.Values)
+		}
+	}
+	return rvs, true, nil
+}
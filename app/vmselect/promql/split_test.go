@@ -0,0 +1,290 @@
+package promql
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metricsql"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+)
+
+func TestGetSplitTimeRanges(t *testing.T) {
+	f := func(start, end, step, interval int64, trsExpected []splitTimeRange) {
+		t.Helper()
+		trs := getSplitTimeRanges(start, end, step, interval)
+		if !reflect.DeepEqual(trs, trsExpected) {
+			t.Fatalf("unexpected sub-ranges for getSplitTimeRanges(%d, %d, %d, %d);\ngot\n%v\nwant\n%v", start, end, step, interval, trs, trsExpected)
+		}
+	}
+
+	// Splitting is disabled
+	f(1000, 5000, 100, 0, nil)
+
+	// Too small time range
+	f(1000, 1500, 100, 1000, nil)
+
+	// Too big step
+	f(1000, 5000, 1000, 1000, nil)
+
+	// Aligned start
+	f(1000, 3500, 500, 1000, []splitTimeRange{
+		{id: 2, start: 1000, end: 1500},
+		{id: 3, start: 2000, end: 2500},
+		{id: 4, start: 3000, end: 3500},
+	})
+
+	// Unaligned start
+	f(1300, 3600, 500, 1000, []splitTimeRange{
+		{id: 2, start: 1300, end: 1800},
+		{id: 3, start: 2300, end: 2800},
+		{id: 4, start: 3300, end: 3600},
+	})
+
+	// Sub-range with a single point
+	f(1900, 3000, 300, 1000, []splitTimeRange{
+		{id: 2, start: 1900, end: 1900},
+		{id: 3, start: 2200, end: 2800},
+	})
+
+	// Too many sub-ranges
+	f(1000, 1000+2*maxSplitTimeRanges*1000, 100, 1000, nil)
+}
+
+func TestCanSplitExpr(t *testing.T) {
+	f := func(q string, resultExpected bool) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("unexpected error in metricsql.Parse(%q): %s", q, err)
+		}
+		result := canSplitExpr(e)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for canSplitExpr(%q); got %v; want %v", q, result, resultExpected)
+		}
+	}
+
+	f(`foo`, true)
+	f(`rate(foo[5m])`, true)
+	f(`sum(rate(foo[5m])) by (job) / on(job) group_left() bar`, true)
+	f(`histogram_quantile(0.9, sum(rate(foo_bucket[5m])) by (le))`, true)
+	f(`max_over_time(foo[1h:1m])`, true)
+	f(`quantile(0.5, foo)`, true)
+
+	f(`foo @ end()`, false)
+	f(`rate(foo[5m] @ 123)`, false)
+	f(`range_avg(foo)`, false)
+	f(`running_sum(foo)`, false)
+	f(`sort_desc(foo)`, false)
+	f(`topk_max(3, foo)`, false)
+	f(`bottomk(3, foo)`, false)
+	f(`limitk(3, foo)`, false)
+	f(`sum(keep_last_value(foo))`, false)
+	f(`foo - remove_resets(bar)`, false)
+	f(`time() - start()`, false)
+	f(`any(foo) by (job)`, false)
+	f(`outliers_iqr(foo)`, false)
+	f(`outliers_mad(0.5, foo)`, false)
+	f(`sum(foo) by (job) limit 3`, false)
+	f(`rate(foo[5m]) / on(job) group_left() count(bar) by (job) limit 1`, false)
+}
+
+func TestEvalSplitTimeRanges(t *testing.T) {
+	f := func(q string, start, end, step, interval int64) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("unexpected error in metricsql.Parse(%q): %s", q, err)
+		}
+		if !canSplitExpr(e) {
+			t.Fatalf("expecting %q to be splittable", q)
+		}
+		trs := getSplitTimeRanges(start, end, step, interval)
+		if len(trs) <= 1 {
+			t.Fatalf("expecting more than one sub-range for start=%d, end=%d, step=%d, interval=%d; got %d", start, end, step, interval, len(trs))
+		}
+		newEvalConfig := func() *EvalConfig {
+			return &EvalConfig{
+				Start:              start,
+				End:                end,
+				Step:               step,
+				MaxPointsPerSeries: 1e4,
+				MaxSeries:          1000,
+				Deadline:           searchutils.NewDeadline(time.Now(), time.Minute, ""),
+				RoundDigits:        100,
+			}
+		}
+		tssExpected, err := evalExpr(nil, newEvalConfig(), e)
+		if err != nil {
+			t.Fatalf("unexpected error when evaluating %q without splitting: %s", q, err)
+		}
+		tss, ok, err := evalSplitTimeRanges(nil, newEvalConfig(), e, trs)
+		if err != nil {
+			t.Fatalf("unexpected error when evaluating %q with splitting: %s", q, err)
+		}
+		if !ok {
+			t.Fatalf("cannot stitch results for %q", q)
+		}
+		if len(tss) != len(tssExpected) {
+			t.Fatalf("unexpected number of series for %q; got %d; want %d", q, len(tss), len(tssExpected))
+		}
+		for i := range tss {
+			ts := tss[i]
+			tsExpected := tssExpected[i]
+			testMetricNamesEqual(t, &ts.MetricName, &tsExpected.MetricName, i)
+			testRowsEqual(t, ts.Values, ts.Timestamps, tsExpected.Values, tsExpected.Timestamps)
+		}
+	}
+
+	f(`time()`, 1000e3, 2000e3, 100e3, 300e3)
+	f(`label_set(time(), "foo", "bar") or label_set(-time(), "foo", "baz")`, 1000e3, 2000e3, 100e3, 300e3)
+	f(`rate(label_set(time(), "foo", "bar")[5m:1m])`, 1050e3, 3050e3, 100e3, 600e3)
+	f(`sum_over_time(time()[300s])`, 1000e3, 2000e3, 60e3, 300e3)
+	f(`time() > 1500`, 1000e3, 2000e3, 100e3, 300e3)
+}
+
+func TestEvalExprMaybeSplitNonSplittable(t *testing.T) {
+	intervalOrig := *splitQueryInterval
+	*splitQueryInterval = 300 * time.Second
+	defer func() {
+		*splitQueryInterval = intervalOrig
+	}()
+
+	const start, end, step = 1000e3, 2000e3, 100e3
+	newEvalConfig := func() *EvalConfig {
+		return &EvalConfig{
+			Start:              start,
+			End:                end,
+			Step:               step,
+			MaxPointsPerSeries: 1e4,
+			MaxSeries:          1000,
+			Deadline:           searchutils.NewDeadline(time.Now(), time.Minute, ""),
+			RoundDigits:        100,
+		}
+	}
+	f := func(q string) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("unexpected error in metricsql.Parse(%q): %s", q, err)
+		}
+		tssExpected, err := evalExpr(nil, newEvalConfig(), e)
+		if err != nil {
+			t.Fatalf("unexpected error when evaluating %q without splitting: %s", q, err)
+		}
+
+		// Make sure the query returns different results if it is forcibly split into sub-ranges,
+		// so the test below verifies that the query isn't split.
+		trs := getSplitTimeRanges(start, end, step, splitQueryInterval.Milliseconds())
+		tssSplit, ok, err := evalSplitTimeRanges(nil, newEvalConfig(), e, trs)
+		if err != nil {
+			t.Fatalf("unexpected error when evaluating %q with splitting: %s", q, err)
+		}
+		if ok && timeseriesEqual(tssSplit, tssExpected) {
+			t.Fatalf("expecting different results for %q with splitting", q)
+		}
+
+		tss, err := evalExprMaybeSplit(nil, newEvalConfig(), e)
+		if err != nil {
+			t.Fatalf("unexpected error when evaluating %q: %s", q, err)
+		}
+		if len(tss) != len(tssExpected) {
+			t.Fatalf("unexpected number of series for %q; got %d; want %d", q, len(tss), len(tssExpected))
+		}
+		for i := range tss {
+			ts := tss[i]
+			tsExpected := tssExpected[i]
+			testMetricNamesEqual(t, &ts.MetricName, &tsExpected.MetricName, i)
+			testRowsEqual(t, ts.Values, ts.Timestamps, tsExpected.Values, tsExpected.Timestamps)
+		}
+	}
+
+	// any() picks the first non-empty series per group
+	f(`any(label_set(time() < 1500, "x", "a") or label_set(time() >= 1500, "x", "b"))`)
+
+	// limit picks the first non-empty groups
+	f(`sum(label_set(time() < 1500, "x", "a") or label_set(time() >= 1500, "x", "b")) by (x) limit 1`)
+
+	// outliers_* select series with outliers at any point on the time range
+	series := `(
+		label_set(time(), "x", "a")
+		or label_set(time() + 1, "x", "b")
+		or label_set(time() + 2, "x", "c")
+		or label_set(time() + 3, "x", "d")
+		or label_set(time() + 1e6*(time() >bool 1500), "x", "e")
+	)`
+	f(`outliers_iqr(` + series + `)`)
+	f(`outliers_mad(1, ` + series + `)`)
+}
+
+func timeseriesEqual(tss, tssExpected []*timeseries) bool {
+	if len(tss) != len(tssExpected) {
+		return false
+	}
+	for i, ts := range tss {
+		tsExpected := tssExpected[i]
+		if ts.MetricName.String() != tsExpected.MetricName.String() || !reflect.DeepEqual(ts.Timestamps, tsExpected.Timestamps) {
+			return false
+		}
+		for j, v := range ts.Values {
+			vExpected := tsExpected.Values[j]
+			if v != vExpected && !(math.IsNaN(v) && math.IsNaN(vExpected)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestStitchSplitResultsDuplicateSeries(t *testing.T) {
+	ec := &EvalConfig{
+		Start:              1000,
+		End:                2000,
+		Step:               500,
+		MaxPointsPerSeries: 1e4,
+	}
+	trs := []splitTimeRange{
+		{id: 1, start: 1000, end: 1000},
+		{id: 2, start: 1500, end: 2000},
+	}
+	newTimeseries := func(timestamps []int64) *timeseries {
+		ts := &timeseries{
+			Values:     make([]float64, len(timestamps)),
+			Timestamps: timestamps,
+		}
+		ts.MetricName.MetricGroup = []byte("foo")
+		return ts
+	}
+
+	// The same series on different sub-ranges must be stitched
+	tsss := [][]*timeseries{
+		{newTimeseries([]int64{1000})},
+		{newTimeseries([]int64{1500, 2000})},
+	}
+	tss, ok, err := stitchSplitResults(ec, trs, tsss)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !ok {
+		t.Fatalf("expecting successful stitching")
+	}
+	if len(tss) != 1 {
+		t.Fatalf("unexpected number of series; got %d; want 1", len(tss))
+	}
+	testRowsEqual(t, tss[0].Values, tss[0].Timestamps, []float64{0, 0, 0}, []int64{1000, 1500, 2000})
+
+	// Duplicate series on the same sub-range must prevent stitching
+	tsss = [][]*timeseries{
+		{newTimeseries([]int64{1000})},
+		{newTimeseries([]int64{1500, 2000}), newTimeseries([]int64{1500, 2000})},
+	}
+	_, ok, err = stitchSplitResults(ec, trs, tsss)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ok {
+		t.Fatalf("expecting unsuccessful stitching for duplicate series")
+	}
+}
@@ -0,0 +1,533 @@
+package queryrules
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/querystats"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/regexutil"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+var (
+	rulesPath = flag.String("search.queryRules", "", "Optional path to a file with rules for blocking and rewriting queries at /api/v1/query and /api/v1/query_range. "+
+		"The path can point either to local file or to http url. The file is re-read on SIGHUP signal and every -search.queryRules.checkInterval. "+
+		"See https://docs.victoriametrics.com/#query-rules")
+	rulesCheckInterval = flag.Duration("search.queryRules.checkInterval", 0, "Interval for checking for changes in -search.queryRules file. "+
+		"By default the checking is disabled. Send SIGHUP signal in order to force re-reading of -search.queryRules file")
+)
+
+// Action is the action of the query rule.
+type Action string
+
+const (
+	// ActionDeny rejects the matching query with the message from the rule.
+	ActionDeny Action = "deny"
+
+	// ActionCap limits the step and the time range for the matching query.
+	ActionCap Action = "cap"
+
+	// ActionNoCache disables the rollup result cache for the matching query.
+	ActionNoCache Action = "nocache"
+)
+
+// Config represents the -search.queryRules file.
+type Config struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule is a single rule for blocking or rewriting queries.
+//
+// All the non-empty matchers must match the query in order to apply the rule action.
+type Rule struct {
+	// Name is the name of the rule, which is used in logs, query stats and metrics.
+	Name string `yaml:"name,omitempty"`
+
+	// Query is an optional regexp, which must match any part of the query.
+	Query string `yaml:"query,omitempty"`
+
+	// MetricName is an optional regexp, which must match the whole name of any metric referred by the query.
+	//
+	// Queries with series selectors, which may select arbitrary metric names such as `{job="foo"}`, match any MetricName.
+	MetricName string `yaml:"metric_name,omitempty"`
+
+	// Headers contains optional regexps, which must match the whole value of the corresponding request headers.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Action is the action to apply to the matching query.
+	Action Action `yaml:"action"`
+
+	// Message is the message returned to the client for the deny action.
+	Message string `yaml:"message,omitempty"`
+
+	// MinStep is the minimum step for the cap action.
+	MinStep *promutils.Duration `yaml:"min_step,omitempty"`
+
+	// MaxRange is the maximum time range for the cap action.
+	MaxRange *promutils.Duration `yaml:"max_range,omitempty"`
+}
+
+type parsedRule struct {
+	name       string
+	query      *regexp.Regexp
+	metricName *regexutil.PromRegex
+	headers    []headerMatcher
+	action     Action
+	message    string
+	minStep    int64
+	maxRange   int64
+
+	hits *metrics.Counter
+}
+
+type headerMatcher struct {
+	name  string
+	value *regexutil.PromRegex
+}
+
+type parsedRules struct {
+	rules []*parsedRule
+
+	// needMetricNames is set to true if at least a single rule has metric_name matcher.
+	needMetricNames bool
+}
+
+// Result is the result of applying query rules to a query.
+type Result struct {
+	// Rules contains names of the matching rules.
+	Rules []string
+
+	// Deny is set to true if the query must be rejected.
+	Deny bool
+
+	// Message is the message for the rejected query.
+	Message string
+
+	// MinStep is the minimum step in milliseconds for the query. Zero means no limit.
+	MinStep int64
+
+	// MaxRange is the maximum time range in milliseconds for the query. Zero means no limit.
+	MaxRange int64
+
+	// NoCache is set to true if the rollup result cache mustn't be used for the query.
+	NoCache bool
+}
+
+// String returns human-readable representation of qrr.
+func (qrr *Result) String() string {
+	return fmt.Sprintf("rules=%q, deny=%v, minStep=%dms, maxRange=%dms, nocache=%v", qrr.Rules, qrr.Deny, qrr.MinStep, qrr.MaxRange, qrr.NoCache)
+}
+
+// AdjustStep returns step adjusted to the MinStep.
+func (qrr *Result) AdjustStep(step int64) int64 {
+	if qrr.MinStep > step {
+		return qrr.MinStep
+	}
+	return step
+}
+
+// AdjustStart returns start adjusted to the MaxRange for the time range ending at end.
+func (qrr *Result) AdjustStart(start, end int64) int64 {
+	if qrr.MaxRange > 0 && end-start > qrr.MaxRange {
+		return end - qrr.MaxRange
+	}
+	return start
+}
+
+// CheckRollupWindows returns an error if the given query contains lookbehind windows in square brackets exceeding the MaxRange.
+//
+// step is used for calculating step-based windows such as `[10i]` and implicit windows for rollup functions such as `rate(foo)`.
+func (qrr *Result) CheckRollupWindows(query string, step int64) error {
+	if qrr.MaxRange <= 0 {
+		return nil
+	}
+	e, err := metricsql.Parse(query)
+	if err != nil {
+		// The query will be rejected later with the proper error message.
+		return nil
+	}
+	var window int64
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		d := int64(0)
+		switch t := expr.(type) {
+		case *metricsql.RollupExpr:
+			if t.Window != nil {
+				d = t.Window.Duration(step)
+			}
+		case *metricsql.FuncExpr:
+			if !metricsql.IsRollupFunc(t.Name) {
+				return
+			}
+			argIdx := metricsql.GetRollupArgIdx(t)
+			if argIdx < 0 || argIdx >= len(t.Args) {
+				return
+			}
+			if re, ok := t.Args[argIdx].(*metricsql.RollupExpr); ok && re.Window != nil {
+				return
+			}
+			// The rollup function without explicitly set window uses the step as the window.
+			d = step
+		}
+		if d > window {
+			window = d
+		}
+	})
+	if window > qrr.MaxRange {
+		return fmt.Errorf("the lookbehind window %dms for rollup functions exceeds max_range=%dms set by -search.queryRules rules %q", window, qrr.MaxRange, qrr.Rules)
+	}
+	return nil
+}
+
+var (
+	rulesGlobal atomic.Pointer[parsedRules]
+	rulesData   atomic.Pointer[[]byte]
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+)
+
+// Init must be called after flag.Parse and before using the queryrules package.
+func Init() {
+	if len(*rulesPath) == 0 {
+		return
+	}
+
+	// Register SIGHUP handler for config re-read just before reloadRules call.
+	// This guarantees that the config will be re-read if the signal arrives during reloadRules call.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1240
+	sighupCh := procutil.NewSighupChan()
+
+	if _, err := reloadRules(); err != nil {
+		logger.Fatalf("cannot load -search.queryRules: %s", err)
+	}
+	configSuccess.Set(1)
+	configTimestamp.Set(fasttime.UnixTimestamp())
+
+	stopCh = make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rulesReloader(sighupCh)
+	}()
+}
+
+// Stop stops the queryrules package.
+func Stop() {
+	if stopCh == nil {
+		return
+	}
+	close(stopCh)
+	wg.Wait()
+	stopCh = nil
+}
+
+// CheckConfig checks the config pointed by -search.queryRules.
+func CheckConfig() error {
+	if len(*rulesPath) == 0 {
+		return nil
+	}
+	data, err := fscore.ReadFileOrHTTP(*rulesPath)
+	if err != nil {
+		return fmt.Errorf("cannot read -search.queryRules=%q: %w", *rulesPath, err)
+	}
+	if _, err := parseRules(data); err != nil {
+		return fmt.Errorf("cannot parse -search.queryRules=%q: %w", *rulesPath, err)
+	}
+	return nil
+}
+
+func rulesReloader(sighupCh <-chan os.Signal) {
+	var refreshCh <-chan time.Time
+	if *rulesCheckInterval > 0 {
+		ticker := time.NewTicker(*rulesCheckInterval)
+		defer ticker.Stop()
+		refreshCh = ticker.C
+	}
+
+	updateFn := func() {
+		configReloads.Inc()
+		updated, err := reloadRules()
+		if err != nil {
+			configReloadErrors.Inc()
+			configSuccess.Set(0)
+			logger.Errorf("cannot load the updated -search.queryRules: %s; preserving the previous rules", err)
+			return
+		}
+		configSuccess.Set(1)
+		if updated {
+			configTimestamp.Set(fasttime.UnixTimestamp())
+		}
+	}
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-refreshCh:
+			updateFn()
+		case <-sighupCh:
+			logger.Infof("received SIGHUP; reloading -search.queryRules=%q...", *rulesPath)
+			updateFn()
+		}
+	}
+}
+
+var (
+	configReloads      = metrics.NewCounter(`vm_query_rules_config_reloads_total`)
+	configReloadErrors = metrics.NewCounter(`vm_query_rules_config_reloads_errors_total`)
+	configSuccess      = metrics.NewGauge(`vm_query_rules_config_last_reload_successful`, nil)
+	configTimestamp    = metrics.NewCounter(`vm_query_rules_config_last_reload_success_timestamp_seconds`)
+)
+
+// reloadRules reads rules from -search.queryRules and applies them.
+//
+// It returns false if the rules didn't change since the previous call.
+func reloadRules() (bool, error) {
+	data, err := fscore.ReadFileOrHTTP(*rulesPath)
+	if err != nil {
+		return false, fmt.Errorf("cannot read -search.queryRules=%q: %w", *rulesPath, err)
+	}
+	oldData := rulesData.Load()
+	if oldData != nil && bytes.Equal(data, *oldData) {
+		// Nothing changed since the previous load.
+		return false, nil
+	}
+	prs, err := parseRules(data)
+	if err != nil {
+		return false, fmt.Errorf("cannot parse -search.queryRules=%q: %w", *rulesPath, err)
+	}
+	rulesGlobal.Store(prs)
+	rulesData.Store(&data)
+	logger.Infof("loaded %d rules from -search.queryRules=%q", len(prs.rules), *rulesPath)
+	return true, nil
+}
+
+func parseRules(data []byte) (*parsedRules, error) {
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	prs := &parsedRules{}
+	names := make(map[string]bool, len(cfg.Rules))
+	for i := range cfg.Rules {
+		r := &cfg.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule#%d", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate rule name %q", r.Name)
+		}
+		names[r.Name] = true
+		pr, err := r.parse()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse rule %q: %w", r.Name, err)
+		}
+		if pr.metricName != nil {
+			prs.needMetricNames = true
+		}
+		prs.rules = append(prs.rules, pr)
+	}
+	return prs, nil
+}
+
+func (r *Rule) parse() (*parsedRule, error) {
+	pr := &parsedRule{
+		name:   r.Name,
+		action: r.Action,
+		hits:   metrics.GetOrCreateCounter(fmt.Sprintf(`vm_query_rules_hits_total{rule=%q,action=%q}`, r.Name, r.Action)),
+	}
+	if r.Query != "" {
+		re, err := regexp.Compile(r.Query)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `query` regexp: %w", err)
+		}
+		pr.query = re
+	}
+	if r.MetricName != "" {
+		re, err := regexutil.NewPromRegex(r.MetricName)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `metric_name` regexp: %w", err)
+		}
+		pr.metricName = re
+	}
+	for name, value := range r.Headers {
+		re, err := regexutil.NewPromRegex(value)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse regexp for header %q: %w", name, err)
+		}
+		pr.headers = append(pr.headers, headerMatcher{
+			name:  name,
+			value: re,
+		})
+	}
+	switch r.Action {
+	case ActionDeny:
+		pr.message = r.Message
+		if pr.message == "" {
+			pr.message = "the query is blocked by administrator"
+		}
+	case ActionCap:
+		pr.minStep = r.MinStep.Duration().Milliseconds()
+		pr.maxRange = r.MaxRange.Duration().Milliseconds()
+		if pr.minStep <= 0 && pr.maxRange <= 0 {
+			return nil, fmt.Errorf("`cap` action requires non-zero `min_step` or `max_range`")
+		}
+	case ActionNoCache:
+	case "":
+		return nil, fmt.Errorf("missing `action`; supported values: %s, %s, %s", ActionDeny, ActionCap, ActionNoCache)
+	default:
+		return nil, fmt.Errorf("unsupported `action`: %q; supported values: %s, %s, %s", r.Action, ActionDeny, ActionCap, ActionNoCache)
+	}
+	return pr, nil
+}
+
+// Match applies -search.queryRules to the given query from r.
+//
+// It returns nil if the query doesn't match any rule.
+func Match(r *http.Request, query string) *Result {
+	prs := rulesGlobal.Load()
+	if prs == nil || len(prs.rules) == 0 {
+		return nil
+	}
+	return prs.match(r, query)
+}
+
+func (prs *parsedRules) match(r *http.Request, query string) *Result {
+	var mns *metricNames
+	if prs.needMetricNames {
+		mns = getMetricNames(query)
+	}
+	var qrr *Result
+	for _, pr := range prs.rules {
+		if !pr.matches(r, query, mns) {
+			continue
+		}
+		if qrr == nil {
+			qrr = &Result{}
+		}
+		qrr.Rules = append(qrr.Rules, pr.name)
+		switch pr.action {
+		case ActionDeny:
+			qrr.Deny = true
+			qrr.Message = pr.message
+		case ActionCap:
+			if pr.minStep > qrr.MinStep {
+				qrr.MinStep = pr.minStep
+			}
+			if pr.maxRange > 0 && (qrr.MaxRange == 0 || pr.maxRange < qrr.MaxRange) {
+				qrr.MaxRange = pr.maxRange
+			}
+		case ActionNoCache:
+			qrr.NoCache = true
+		}
+		pr.hits.Inc()
+		querystats.RegisterRuleHit(pr.name, string(pr.action), query)
+		ruleHitsLogger.Warnf("query matches -search.queryRules rule %q with action %q; query=%q", pr.name, pr.action, query)
+		if qrr.Deny {
+			// There is no need in applying the remaining rules to the denied query.
+			break
+		}
+	}
+	return qrr
+}
+
+var ruleHitsLogger = logger.WithThrottler("queryRulesHits", 5*time.Second)
+
+func (pr *parsedRule) matches(r *http.Request, query string, mns *metricNames) bool {
+	if pr.query != nil && !pr.query.MatchString(query) {
+		return false
+	}
+	for _, hm := range pr.headers {
+		if !hm.value.MatchString(r.Header.Get(hm.name)) {
+			return false
+		}
+	}
+	if pr.metricName != nil && !mns.matches(pr.metricName) {
+		return false
+	}
+	return true
+}
+
+// metricNames contains metric names, which can be selected by the query.
+type metricNames struct {
+	// names contains metric names from `foo`, `{__name__="foo"}` and `{__name__=~"foo|bar"}` filters.
+	names []string
+
+	// hasArbitraryNames is set to true if the query contains series selectors, which may select series with arbitrary names.
+	// For example, `{__name__=~"foo.+"}`, `{__name__!="foo"}` or `{job="bar"}`.
+	hasArbitraryNames bool
+}
+
+// matches returns true if re matches at least a single metric name, which can be selected by the query.
+//
+// It returns true for queries with series selectors, which may select arbitrary metric names,
+// since it is impossible to determine whether such selectors select metric names matching re.
+func (mns *metricNames) matches(re *regexutil.PromRegex) bool {
+	if mns.hasArbitraryNames {
+		return true
+	}
+	for _, name := range mns.names {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// getMetricNames returns metric names, which can be selected by the given query.
+func getMetricNames(query string) *metricNames {
+	var mns metricNames
+	e, err := metricsql.Parse(query)
+	if err != nil {
+		// The query will be rejected later with the proper error message.
+		return &mns
+	}
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		me, ok := expr.(*metricsql.MetricExpr)
+		if !ok {
+			return
+		}
+		for _, lfs := range me.LabelFilterss {
+			names := getMetricNamesFromLabelFilters(lfs)
+			if names == nil {
+				mns.hasArbitraryNames = true
+				continue
+			}
+			mns.names = append(mns.names, names...)
+		}
+	})
+	return &mns
+}
+
+// getMetricNamesFromLabelFilters returns metric names, which can be selected by lfs.
+//
+// It returns nil if lfs may select arbitrary metric names.
+func getMetricNamesFromLabelFilters(lfs []metricsql.LabelFilter) []string {
+	for _, lf := range lfs {
+		if lf.Label != "__name__" || lf.IsNegative {
+			continue
+		}
+		if !lf.IsRegexp {
+			return []string{lf.Value}
+		}
+		if names := regexutil.GetOrValuesPromRegex(lf.Value); len(names) > 0 {
+			return names
+		}
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package queryrules
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseRulesFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseRules([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error for rules:\n%s", data)
+		}
+	}
+
+	// invalid yaml
+	f(`foobar`)
+
+	// unknown field
+	f(`
+rules:
+- action: deny
+  foo: bar
+`)
+
+	// missing action
+	f(`
+rules:
+- query: foo
+`)
+
+	// unsupported action
+	f(`
+rules:
+- action: drop
+`)
+
+	// cap without limits
+	f(`
+rules:
+- action: cap
+`)
+
+	// invalid duration
+	f(`
+rules:
+- action: cap
+  min_step: foo
+`)
+
+	// invalid regexps
+	f(`
+rules:
+- action: deny
+  query: "foo("
+`)
+	f(`
+rules:
+- action: deny
+  metric_name: "foo("
+`)
+	f(`
+rules:
+- action: deny
+  headers:
+    X-Grafana-User: "foo("
+`)
+
+	// duplicate names
+	f(`
+rules:
+- name: foo
+  action: deny
+- name: foo
+  action: nocache
+`)
+}
+
+func TestParseRulesSuccess(t *testing.T) {
+	data := `
+rules:
+- name: deny-broken-dashboard
+  query: 'rate\(http_requests_total\[1s\]\)'
+  headers:
+    X-Grafana-User: bob|alice
+  action: deny
+  message: fix your dashboard
+- metric_name: 'node_.+'
+  action: cap
+  min_step: 1m
+  max_range: 7d
+- action: nocache
+`
+	prs, err := parseRules([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(prs.rules) != 3 {
+		t.Fatalf("unexpected number of rules; got %d; want 3", len(prs.rules))
+	}
+	if !prs.needMetricNames {
+		t.Fatalf("expecting needMetricNames=true")
+	}
+	if prs.rules[1].name != "rule#2" {
+		t.Fatalf("unexpected name for the rule without explicitly set name; got %q; want %q", prs.rules[1].name, "rule#2")
+	}
+	if prs.rules[1].minStep != 60e3 || prs.rules[1].maxRange != 7*24*3600e3 {
+		t.Fatalf("unexpected limits; got minStep=%d, maxRange=%d", prs.rules[1].minStep, prs.rules[1].maxRange)
+	}
+}
+
+func TestParsedRulesMatch(t *testing.T) {
+	data := `
+rules:
+- name: deny-bob
+  query: 'rate\(http_requests_total\[1s\]\)'
+  headers:
+    X-Grafana-User: bob
+  action: deny
+  message: fix your dashboard
+- name: cap-node
+  metric_name: 'node_.+'
+  action: cap
+  min_step: 1m
+  max_range: 7d
+- name: cap-node-cpu
+  metric_name: node_cpu_seconds_total
+  action: cap
+  min_step: 5m
+  max_range: 30d
+- name: nocache-foo
+  query: foo
+  action: nocache
+`
+	prs, err := parseRules([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f := func(query, user string, resultExpected *Result) {
+		t.Helper()
+		r, err := http.NewRequest(http.MethodGet, "http://localhost/api/v1/query", nil)
+		if err != nil {
+			t.Fatalf("cannot create request: %s", err)
+		}
+		if user != "" {
+			r.Header.Set("X-Grafana-User", user)
+		}
+		result := prs.match(r, query)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result for query=%q, user=%q;\ngot\n%v\nwant\n%v", query, user, result, resultExpected)
+		}
+	}
+
+	// no matches
+	f(`up`, "", nil)
+	f(`sum(rate(http_requests_total[1s]))`, "alice", nil)
+	f(`{__name__=~"up|process_cpu_seconds_total"}`, "", nil)
+
+	// deny
+	f(`sum(rate(http_requests_total[1s]))`, "bob", &Result{
+		Rules:   []string{"deny-bob"},
+		Deny:    true,
+		Message: "fix your dashboard",
+	})
+
+	// cap
+	f(`rate(node_network_receive_bytes_total[5m])`, "", &Result{
+		Rules:    []string{"cap-node"},
+		MinStep:  60e3,
+		MaxRange: 7 * 24 * 3600e3,
+	})
+
+	// multiple cap rules - the strictest limits win
+	f(`sum(rate(node_cpu_seconds_total[5m])) / up`, "", &Result{
+		Rules:    []string{"cap-node", "cap-node-cpu"},
+		MinStep:  5 * 60e3,
+		MaxRange: 7 * 24 * 3600e3,
+	})
+
+	// regexp filters on metric names
+	f(`{__name__=~"node_cpu_seconds_total|up"}`, "", &Result{
+		Rules:    []string{"cap-node", "cap-node-cpu"},
+		MinStep:  5 * 60e3,
+		MaxRange: 7 * 24 * 3600e3,
+	})
+	f(`{__name__=~"node_.+"}`, "", &Result{
+		Rules:    []string{"cap-node", "cap-node-cpu"},
+		MinStep:  5 * 60e3,
+		MaxRange: 7 * 24 * 3600e3,
+	})
+
+	// selectors, which may select arbitrary metric names
+	f(`count({job="node"})`, "", &Result{
+		Rules:    []string{"cap-node", "cap-node-cpu"},
+		MinStep:  5 * 60e3,
+		MaxRange: 7 * 24 * 3600e3,
+	})
+	f(`{__name__!="up"}`, "", &Result{
+		Rules:    []string{"cap-node", "cap-node-cpu"},
+		MinStep:  5 * 60e3,
+		MaxRange: 7 * 24 * 3600e3,
+	})
+
+	// cap and nocache
+	f(`node_load1 + foo`, "", &Result{
+		Rules:    []string{"cap-node", "nocache-foo"},
+		MinStep:  60e3,
+		MaxRange: 7 * 24 * 3600e3,
+		NoCache:  true,
+	})
+}
+
+func TestResultAdjust(t *testing.T) {
+	qrr := &Result{
+		MinStep:  60e3,
+		MaxRange: 3600e3,
+	}
+	if step := qrr.AdjustStep(15e3); step != 60e3 {
+		t.Fatalf("unexpected step; got %d; want %d", step, int64(60e3))
+	}
+	if step := qrr.AdjustStep(300e3); step != 300e3 {
+		t.Fatalf("unexpected step; got %d; want %d", step, int64(300e3))
+	}
+	if start := qrr.AdjustStart(0, 7200e3); start != 3600e3 {
+		t.Fatalf("unexpected start; got %d; want %d", start, int64(3600e3))
+	}
+	if start := qrr.AdjustStart(5000e3, 7200e3); start != 5000e3 {
+		t.Fatalf("unexpected start; got %d; want %d", start, int64(5000e3))
+	}
+}
+
+func TestCheckRollupWindows(t *testing.T) {
+	f := func(query string, step int64, resultExpected bool) {
+		t.Helper()
+		qrr := &Result{
+			Rules:    []string{"cap"},
+			MaxRange: 3600e3,
+		}
+		err := qrr.CheckRollupWindows(query, step)
+		if result := err == nil; result != resultExpected {
+			t.Fatalf("unexpected result for CheckRollupWindows(%q, %d); got %v; want %v; err: %v", query, step, result, resultExpected, err)
+		}
+	}
+
+	f(`foo`, 60e3, true)
+	f(`rate(foo[1h])`, 60e3, true)
+	f(`rate(foo)`, 60e3, true)
+	f(`max_over_time(rate(foo[5m])[1h:1m])`, 60e3, true)
+	f(`rate(foo[10i])`, 60e3, true)
+
+	f(`rate(foo[365d])`, 60e3, false)
+	f(`sum(increase(foo[2h])) by (job)`, 60e3, false)
+	f(`max_over_time(rate(foo[5m])[1d:1m])`, 60e3, false)
+	f(`rate(foo[100i])`, 60e3, false)
+	f(`rate(foo)`, 2*3600e3, false)
+}
+
+func TestGetMetricNames(t *testing.T) {
+	f := func(query string, namesExpected []string, hasArbitraryNamesExpected bool) {
+		t.Helper()
+		mns := getMetricNames(query)
+		if !reflect.DeepEqual(mns.names, namesExpected) {
+			t.Fatalf("unexpected metric names for %q; got %q; want %q", query, mns.names, namesExpected)
+		}
+		if mns.hasArbitraryNames != hasArbitraryNamesExpected {
+			t.Fatalf("unexpected hasArbitraryNames for %q; got %v; want %v", query, mns.hasArbitraryNames, hasArbitraryNamesExpected)
+		}
+	}
+
+	f(`123`, nil, false)
+	f(`foo(`, nil, false)
+	f(`foo`, []string{"foo"}, false)
+	f(`sum(rate(foo{job="x"}[5m])) / bar`, []string{"foo", "bar"}, false)
+	f(`{__name__="foo"} + {__name__=~"bar|baz"}`, []string{"foo", "bar", "baz"}, false)
+	f(`foo or {__name__="bar" or __name__="baz"}`, []string{"foo", "bar", "baz"}, false)
+
+	f(`{__name__="foo"} + {__name__=~"bar.*"}`, []string{"foo"}, true)
+	f(`{__name__=~"(?i)foo"}`, nil, true)
+	f(`{__name__!="foo"}`, nil, true)
+	f(`{__name__!~"foo"}`, nil, true)
+	f(`{job="foo"}`, nil, true)
+}
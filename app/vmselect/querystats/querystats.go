@@ -36,6 +36,12 @@ func RegisterQuery(query string, timeRangeMsecs int64, startTime time.Time) {
 	qsTracker.registerQuery(query, timeRangeMsecs, startTime)
 }
 
+// RegisterRuleHit registers the hit of -search.queryRules rule with the given name and action for the given query.
+func RegisterRuleHit(rule, action, query string) {
+	initOnce.Do(initQueryStats)
+	qsTracker.registerRuleHit(rule, action, query)
+}
+
 // WriteJSONQueryStats writes query stats to given writer in json format.
 func WriteJSONQueryStats(w io.Writer, topN int, maxLifetime time.Duration) {
 	initOnce.Do(initQueryStats)
@@ -47,6 +53,22 @@ type queryStatsTracker struct {
 	mu      sync.Mutex
 	a       []queryStatRecord
 	nextIdx uint
+
+	ruleHits        []ruleHitRecord
+	nextRuleHitsIdx uint
+}
+
+type ruleHitRecord struct {
+	rule         string
+	action       string
+	query        string
+	registerTime time.Time
+}
+
+type ruleHitKey struct {
+	rule   string
+	action string
+	query  string
 }
 
 type queryStatRecord struct {
@@ -70,7 +92,8 @@ func initQueryStats() {
 			*lastQueriesCount, *minQueryDuration)
 	}
 	qsTracker = &queryStatsTracker{
-		a: make([]queryStatRecord, recordsCount),
+		a:        make([]queryStatRecord, recordsCount),
+		ruleHits: make([]ruleHitRecord, recordsCount),
 	}
 }
 
@@ -102,9 +125,77 @@ func (qst *queryStatsTracker) writeJSONQueryStats(w io.Writer, topN int, maxLife
 			fmt.Fprintf(w, `,`)
 		}
 	}
+	fmt.Fprintf(w, `],"topByRuleHits":[`)
+	topByRuleHits := qst.getTopByRuleHits(topN, maxLifetime)
+	for i, r := range topByRuleHits {
+		fmt.Fprintf(w, `{"rule":%s,"action":%s,"query":%s,"count":%d}`, stringsutil.JSONString(r.rule), stringsutil.JSONString(r.action), stringsutil.JSONString(r.query), r.count)
+		if i+1 < len(topByRuleHits) {
+			fmt.Fprintf(w, `,`)
+		}
+	}
 	fmt.Fprintf(w, `]}`)
 }
 
+func (qst *queryStatsTracker) registerRuleHit(rule, action, query string) {
+	registerTime := time.Now()
+
+	qst.mu.Lock()
+	defer qst.mu.Unlock()
+
+	a := qst.ruleHits
+	idx := qst.nextRuleHitsIdx
+	if idx >= uint(len(a)) {
+		idx = 0
+	}
+	qst.nextRuleHitsIdx = idx + 1
+	r := &a[idx]
+	r.rule = rule
+	r.action = action
+	r.query = query
+	r.registerTime = registerTime
+}
+
+func (qst *queryStatsTracker) getTopByRuleHits(topN int, maxLifetime time.Duration) []ruleHitsByCount {
+	currentTime := time.Now()
+	qst.mu.Lock()
+	m := make(map[ruleHitKey]int)
+	for _, r := range qst.ruleHits {
+		if r.rule != "" && currentTime.Sub(r.registerTime) <= maxLifetime {
+			k := ruleHitKey{
+				rule:   r.rule,
+				action: r.action,
+				query:  r.query,
+			}
+			m[k] = m[k] + 1
+		}
+	}
+	qst.mu.Unlock()
+
+	var a []ruleHitsByCount
+	for k, count := range m {
+		a = append(a, ruleHitsByCount{
+			rule:   k.rule,
+			action: k.action,
+			query:  k.query,
+			count:  count,
+		})
+	}
+	sort.Slice(a, func(i, j int) bool {
+		return a[i].count > a[j].count
+	})
+	if len(a) > topN {
+		a = a[:topN]
+	}
+	return a
+}
+
+type ruleHitsByCount struct {
+	rule   string
+	action string
+	query  string
+	count  int
+}
+
 func (qst *queryStatsTracker) registerQuery(query string, timeRangeMsecs int64, startTime time.Time) {
 	registerTime := time.Now()
 	duration := registerTime.Sub(startTime)
@@ -27,7 +27,12 @@ import (
 )
 
 var (
-	retentionPeriod   = flagutil.NewRetentionDuration("retentionPeriod", "1", "Data with timestamps outside the retentionPeriod is automatically deleted. The minimum retentionPeriod is 24h or 1d. See also -retentionFilter")
+	retentionPeriod  = flagutil.NewRetentionDuration("retentionPeriod", "1", "Data with timestamps outside the retentionPeriod is automatically deleted. The minimum retentionPeriod is 24h or 1d. See also -retentionFilter")
+	retentionFilters = flagutil.NewArrayString("retentionFilter", "Retention filter in the format 'filter:retention'. For example, '{env=\"dev\"}:3d' configures the retention "+
+		"for time series with env=\"dev\" label to 3 days. See https://docs.victoriametrics.com/#retention-filters for details")
+	downsamplingPeriods = flagutil.NewArrayString("downsampling.period", "Comma-separated downsampling periods in the format 'offset:period'. For example, '30d:10m' instructs "+
+		"to leave a single sample per 10 minutes for samples older than 30 days. When setting multiple downsampling periods, it is necessary for the periods to be multiples of each other. "+
+		"See https://docs.victoriametrics.com/#downsampling for details")
 	snapshotAuthKey   = flagutil.NewPassword("snapshotAuthKey", "authKey, which must be passed in query string to /snapshot* pages. It overrides -httpAuth.*")
 	forceMergeAuthKey = flagutil.NewPassword("forceMergeAuthKey", "authKey, which must be passed in query string to /internal/force_merge pages. It overrides -httpAuth.*")
 	forceFlushAuthKey = flagutil.NewPassword("forceFlushAuthKey", "authKey, which must be passed in query string to /internal/force_flush pages. It overrides -httpAuth.*")
@@ -49,6 +54,9 @@ var (
 
 	logNewSeries = flag.Bool("logNewSeries", false, "Whether to log new series. This option is for debug purposes only. It can lead to performance issues "+
 		"when big number of new series are ingested into VictoriaMetrics")
+	trackMetricNamesStats = flag.Bool("storage.trackMetricNamesStats", false, "Whether to track the number of queries and the last query time per each metric name. "+
+		"The collected stats can be obtained via /api/v1/status/metric_names_stats . "+
+		"See https://docs.victoriametrics.com/#track-ingested-metrics-usage")
 	denyQueriesOutsideRetention = flag.Bool("denyQueriesOutsideRetention", false, "Whether to deny queries outside the configured -retentionPeriod. "+
 		"When set, then /api/v1/query_range would return '503 Service Unavailable' error for queries with 'from' value outside -retentionPeriod. "+
 		"This may be useful when multiple data sources with distinct retentions are hidden behind query-tee")
@@ -96,6 +104,7 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 
 	resetResponseCacheIfNeeded = resetCacheIfNeeded
 	storage.SetLogNewSeries(*logNewSeries)
+	storage.SetTrackMetricNamesStats(*trackMetricNamesStats)
 	storage.SetRetentionTimezoneOffset(*retentionTimezoneOffset)
 	storage.SetFreeDiskSpaceLimit(minFreeDiskSpaceBytes.N)
 	storage.SetTSIDCacheSize(cacheSizeStorageTSID.IntN())
@@ -107,6 +116,12 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 	if retentionPeriod.Duration() < 24*time.Hour {
 		logger.Fatalf("-retentionPeriod cannot be smaller than a day; got %s", retentionPeriod)
 	}
+	if err := storage.SetRetentionFilters(*retentionFilters, retentionPeriod.Duration()); err != nil {
+		logger.Fatalf("invalid -retentionFilter: %s", err)
+	}
+	if err := storage.SetDownsamplingPeriods(*downsamplingPeriods); err != nil {
+		logger.Fatalf("invalid -downsampling.period: %s", err)
+	}
 	logger.Infof("opening storage at %q with -retentionPeriod=%s", *DataPath, retentionPeriod)
 	startTime := time.Now()
 	WG = syncwg.WaitGroup{}
@@ -233,6 +248,37 @@ func GetTSDBStatus(qt *querytracer.Tracer, tfss []*storage.TagFilters, date uint
 	return status, err
 }
 
+// GetMatchingSeriesCount returns the number of series matching the given tfss on the given tr.
+func GetMatchingSeriesCount(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr storage.TimeRange, maxMetrics int, deadline uint64) (int, error) {
+	WG.Add(1)
+	n, err := Storage.GetMatchingSeriesCount(qt, tfss, tr, maxMetrics, deadline)
+	WG.Done()
+	return n, err
+}
+
+// GetTSDBStatusDiff returns the difference between TSDB status data at the date and at the compareDate.
+func GetTSDBStatusDiff(qt *querytracer.Tracer, tfss []*storage.TagFilters, date, compareDate uint64, focusLabel string, topN, maxMetrics int, deadline uint64) (*storage.TSDBStatusDiff, error) {
+	WG.Add(1)
+	diff, err := Storage.GetTSDBStatusDiff(qt, tfss, date, compareDate, focusLabel, topN, maxMetrics, deadline)
+	WG.Done()
+	return diff, err
+}
+
+// GetMetricNamesStats returns metric names usage stats.
+func GetMetricNamesStats(qt *querytracer.Tracer, limit int, le uint64, matchPattern string, deadline uint64) (storage.MetricNamesStatsResponse, error) {
+	WG.Add(1)
+	resp, err := Storage.GetMetricNamesStats(qt, limit, le, matchPattern, deadline)
+	WG.Done()
+	return resp, err
+}
+
+// ResetMetricNamesStats resets metric names usage stats.
+func ResetMetricNamesStats() {
+	WG.Add(1)
+	Storage.ResetMetricNamesStats()
+	WG.Done()
+}
+
 // GetSeriesCount returns the number of time series in the storage.
 func GetSeriesCount(deadline uint64) (uint64, error) {
 	WG.Add(1)
@@ -0,0 +1,395 @@
+package arrow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestFlatbuffersMarshalUnmarshal(t *testing.T) {
+	child := &fbTable{}
+	child.addInt32(0, -42)
+	child.addObject(1, fbString("child"))
+
+	root := &fbTable{}
+	root.addUint8(0, 7)
+	root.addBool(1, true)
+	root.addInt16(2, -3)
+	root.addInt64(3, 1<<50)
+	root.addObject(4, child)
+	root.addObject(5, fbString("foobar"))
+	root.addObject(6, fbTables{child, &fbTable{}})
+	root.addObject(7, &fbStructs{
+		count: 2,
+		data:  []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+	})
+	var fb fbBuilder
+	data := fb.finish(root)
+
+	tr, err := getRootTable(data)
+	if err != nil {
+		t.Fatalf("cannot read root table: %s", err)
+	}
+	if v := tr.getUint8(0, 0); v != 7 {
+		t.Fatalf("unexpected field #0; got %d; want 7", v)
+	}
+	if !tr.getBool(1) {
+		t.Fatalf("unexpected field #1; got false; want true")
+	}
+	if v := tr.getInt16(2, 0); v != -3 {
+		t.Fatalf("unexpected field #2; got %d; want -3", v)
+	}
+	if v := tr.getInt64(3, 0); v != 1<<50 {
+		t.Fatalf("unexpected field #3; got %d; want %d", v, int64(1<<50))
+	}
+	ct, ok, err := tr.getTable(4)
+	if err != nil || !ok {
+		t.Fatalf("cannot read field #4: ok=%v, err=%v", ok, err)
+	}
+	if v := ct.getInt32(0, 0); v != -42 {
+		t.Fatalf("unexpected field #4.0; got %d; want -42", v)
+	}
+	if s, _ := ct.getString(1); s != "child" {
+		t.Fatalf("unexpected field #4.1; got %q; want %q", s, "child")
+	}
+	if s, _ := tr.getString(5); s != "foobar" {
+		t.Fatalf("unexpected field #5; got %q; want %q", s, "foobar")
+	}
+	tables, err := tr.getTables(6)
+	if err != nil || len(tables) != 2 {
+		t.Fatalf("cannot read field #6: tables=%d, err=%v", len(tables), err)
+	}
+	if v := tables[0].getInt32(0, 0); v != -42 {
+		t.Fatalf("unexpected field #6[0].0; got %d; want -42", v)
+	}
+	if v := tables[1].getInt32(0, 123); v != 123 {
+		t.Fatalf("unexpected default value for missing field #6[1].0; got %d; want 123", v)
+	}
+	structs, n, err := tr.getStructs(7, 8)
+	if err != nil || n != 2 || structs[15] != 16 {
+		t.Fatalf("unexpected field #7: %v, n=%d, err=%v", structs, n, err)
+	}
+	if pos := tr.fieldPos(100, 1); pos != 0 {
+		t.Fatalf("expecting zero position for missing field; got %d", pos)
+	}
+}
+
+func TestWriterReader(t *testing.T) {
+	f := func(batches [][]Series) {
+		t.Helper()
+
+		var bb bytes.Buffer
+		w := NewWriter(&bb)
+		var rb RecordBatch
+		for _, ss := range batches {
+			rb.Reset()
+			for _, s := range ss {
+				rb.AddSeries(s.Labels, s.Timestamps, s.Values)
+			}
+			if err := w.WriteRecordBatch(&rb); err != nil {
+				t.Fatalf("cannot write record batch: %s", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("cannot close writer: %s", err)
+		}
+
+		var ssExpected []Series
+		for _, ss := range batches {
+			ssExpected = append(ssExpected, ss...)
+		}
+		ssResult := readAllSeries(t, bb.Bytes())
+		if !reflect.DeepEqual(ssResult, ssExpected) {
+			t.Fatalf("unexpected series read\ngot\n%v\nwant\n%v", ssResult, ssExpected)
+		}
+	}
+
+	// empty stream
+	f(nil)
+
+	// single series
+	f([][]Series{
+		{
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "foo"},
+					{Name: "job", Value: "bar"},
+				},
+				Timestamps: []int64{1, 2, 3},
+				Values:     []float64{1.5, math.Inf(1), -3},
+			},
+		},
+	})
+
+	// multiple series and record batches
+	f([][]Series{
+		{
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "foo"},
+				},
+				Timestamps: []int64{1700000000000},
+				Values:     []float64{1},
+			},
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "bar"},
+					{Name: "empty", Value: ""},
+				},
+				Timestamps: []int64{-5, 10},
+				Values:     []float64{2, 3},
+			},
+		},
+		{
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "baz"},
+					{Name: "a", Value: "b"},
+					{Name: "c", Value: "d"},
+				},
+				Timestamps: []int64{7, 8, 9, 10, 11, 12, 13, 14, 15},
+				Values:     []float64{7, 8, 9, 10, 11, 12, 13, 14, 15},
+			},
+		},
+	})
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	var bb bytes.Buffer
+	w := NewWriter(&bb)
+	const workers = 4
+	const seriesPerWorker = 100
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			var rb RecordBatch
+			for j := 0; j < seriesPerWorker; j++ {
+				labels := []prompbmarshal.Label{
+					{Name: "__name__", Value: fmt.Sprintf("metric_%d_%d", workerID, j)},
+				}
+				rb.AddSeries(labels, []int64{1, 2}, []float64{float64(workerID), float64(j)})
+				if rb.RowsCount() >= 50 {
+					if err := w.WriteRecordBatch(&rb); err != nil {
+						panic(fmt.Errorf("cannot write record batch: %w", err))
+					}
+					rb.Reset()
+				}
+			}
+			if err := w.WriteRecordBatch(&rb); err != nil {
+				panic(fmt.Errorf("cannot write record batch: %w", err))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatalf("cannot close writer: %s", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range readAllSeries(t, bb.Bytes()) {
+		name := s.Labels[0].Value
+		if seen[name] {
+			t.Fatalf("duplicate series %q", name)
+		}
+		seen[name] = true
+		if len(s.Timestamps) != 2 {
+			t.Fatalf("unexpected number of samples for %q; got %d; want 2", name, len(s.Timestamps))
+		}
+	}
+	if len(seen) != workers*seriesPerWorker {
+		t.Fatalf("unexpected number of series; got %d; want %d", len(seen), workers*seriesPerWorker)
+	}
+}
+
+func TestReaderLabelColumns(t *testing.T) {
+	// Build a stream with labels stored in distinct Utf8 columns and timestamps in seconds.
+	utf8Field := func(name string) *fbTable {
+		t := &fbTable{}
+		t.addObject(0, fbString(name))
+		t.addBool(1, true)
+		t.addUint8(2, typeUtf8)
+		t.addObject(3, &fbTable{})
+		t.addObject(5, fbTables{})
+		return t
+	}
+	tsType := &fbTable{}
+	tsType.addInt16(0, timeUnitSecond)
+	timestamp := &fbTable{}
+	timestamp.addObject(0, fbString(TimestampColumn))
+	timestamp.addUint8(2, typeTimestamp)
+	timestamp.addObject(3, tsType)
+	valueType := &fbTable{}
+	valueType.addInt16(0, precisionDouble)
+	value := &fbTable{}
+	value.addObject(0, fbString(ValueColumn))
+	value.addUint8(2, typeFloatingPoint)
+	value.addObject(3, valueType)
+	schema := &fbTable{}
+	schema.addObject(1, fbTables{utf8Field("__name__"), utf8Field("job"), timestamp, value})
+	data := appendMessage(nil, messageHeaderSchema, schema, nil)
+
+	var nodes, buffers, body []byte
+	addNode := func(length, nullCount int) {
+		nodes = binary.LittleEndian.AppendUint64(nodes, uint64(length))
+		nodes = binary.LittleEndian.AppendUint64(nodes, uint64(nullCount))
+	}
+	addBuffer := func(b []byte) {
+		buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(body)))
+		buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(b)))
+		body = append(body, b...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+	int32s := func(a ...int32) []byte {
+		var b []byte
+		for _, v := range a {
+			b = binary.LittleEndian.AppendUint32(b, uint32(v))
+		}
+		return b
+	}
+	uint64s := func(a ...uint64) []byte {
+		var b []byte
+		for _, v := range a {
+			b = binary.LittleEndian.AppendUint64(b, v)
+		}
+		return b
+	}
+
+	// __name__ column: "foo", "foo", "bar"
+	addNode(3, 0)
+	addBuffer(nil)
+	addBuffer(int32s(0, 3, 6, 9))
+	addBuffer([]byte("foofoobar"))
+	// job column: "x", null, null
+	addNode(3, 2)
+	addBuffer([]byte{0b001})
+	addBuffer(int32s(0, 1, 1, 1))
+	addBuffer([]byte("x"))
+	// timestamp column
+	addNode(3, 0)
+	addBuffer(nil)
+	addBuffer(uint64s(1, 2, 3))
+	// value column
+	addNode(3, 0)
+	addBuffer(nil)
+	addBuffer(uint64s(math.Float64bits(1), math.Float64bits(2), math.Float64bits(3)))
+
+	header := &fbTable{}
+	header.addInt64(0, 3)
+	header.addObject(1, &fbStructs{count: len(nodes) / 16, data: nodes})
+	header.addObject(2, &fbStructs{count: len(buffers) / 16, data: buffers})
+	data = appendMessage(data, messageHeaderRecordBatch, header, body)
+
+	// Verify that the missing end of stream marker is handled properly.
+	ssResult := readAllSeries(t, data)
+	ssExpected := []Series{
+		{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: "foo"},
+				{Name: "job", Value: "x"},
+			},
+			Timestamps: []int64{1000},
+			Values:     []float64{1},
+		},
+		{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: "foo"},
+			},
+			Timestamps: []int64{2000},
+			Values:     []float64{2},
+		},
+		{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: "bar"},
+			},
+			Timestamps: []int64{3000},
+			Values:     []float64{3},
+		},
+	}
+	if !reflect.DeepEqual(ssResult, ssExpected) {
+		t.Fatalf("unexpected series read\ngot\n%v\nwant\n%v", ssResult, ssExpected)
+	}
+}
+
+func TestReaderFailure(t *testing.T) {
+	f := func(data []byte) {
+		t.Helper()
+		r := NewReader(bytes.NewReader(data))
+		for {
+			ok, err := r.ReadRecordBatch(func(_ *Series) error { return nil })
+			if err != nil {
+				return
+			}
+			if !ok {
+				t.Fatalf("expecting non-nil error")
+			}
+		}
+	}
+
+	// missing schema
+	f(nil)
+	f([]byte("\xff\xff\xff\xff\x00\x00\x00\x00"))
+
+	// invalid message
+	f([]byte("\xff\xff\xff\xff\x08\x00\x00\x00foobarba"))
+
+	// truncated stream
+	var bb bytes.Buffer
+	w := NewWriter(&bb)
+	var rb RecordBatch
+	rb.AddSeries([]prompbmarshal.Label{{Name: "foo", Value: "bar"}}, []int64{1, 2}, []float64{3, 4})
+	if err := w.WriteRecordBatch(&rb); err != nil {
+		t.Fatalf("cannot write record batch: %s", err)
+	}
+	data := bb.Bytes()
+	schemaLen := len(marshalSchemaMessage(nil))
+	for i := 1; i < len(data); i++ {
+		if i == schemaLen {
+			// The stream without record batches and without the end of stream marker is valid.
+			continue
+		}
+		f(data[:i])
+	}
+}
+
+func readAllSeries(t *testing.T, data []byte) []Series {
+	t.Helper()
+	var ss []Series
+	r := NewReader(bytes.NewReader(data))
+	for {
+		ok, err := r.ReadRecordBatch(func(s *Series) error {
+			ss = append(ss, cloneSeries(s))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("cannot read record batch: %s", err)
+		}
+		if !ok {
+			return ss
+		}
+	}
+}
+
+func cloneSeries(s *Series) Series {
+	labels := make([]prompbmarshal.Label, len(s.Labels))
+	for i, label := range s.Labels {
+		labels[i] = prompbmarshal.Label{
+			Name:  string(append([]byte{}, label.Name...)),
+			Value: string(append([]byte{}, label.Value...)),
+		}
+	}
+	return Series{
+		Labels:     labels,
+		Timestamps: append([]int64{}, s.Timestamps...),
+		Values:     append([]float64{}, s.Values...),
+	}
+}
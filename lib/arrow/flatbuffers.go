@@ -0,0 +1,378 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// fbObject is an object, which can be written into flatbuffer.
+//
+// See https://flatbuffers.dev/md__internals.html for the flatbuffers format.
+type fbObject interface {
+	// writeTo writes the object to fb and returns its position in fb.
+	writeTo(fb *fbBuilder) int
+}
+
+// fbBuilder builds flatbuffers front to back.
+//
+// Child objects are always written after the parent object, so all the offsets to them are positive as required by flatbuffers format.
+type fbBuilder struct {
+	b []byte
+}
+
+// finish writes root into fb.b and returns the result.
+func (fb *fbBuilder) finish(root *fbTable) []byte {
+	fb.b = append(fb.b[:0], 0, 0, 0, 0)
+	pos := root.writeTo(fb)
+	binary.LittleEndian.PutUint32(fb.b, uint32(pos))
+	return fb.b
+}
+
+func (fb *fbBuilder) pad(alignment int) {
+	for len(fb.b)%alignment != 0 {
+		fb.b = append(fb.b, 0)
+	}
+}
+
+// fbTable is a flatbuffers table.
+type fbTable struct {
+	fields []fbField
+}
+
+type fbField struct {
+	id int
+
+	// size is the size of scalar field in bytes. It is set to 4 for offset fields.
+	size int
+
+	// scalar contains the value for scalar field.
+	scalar uint64
+
+	// child contains the object for offset field.
+	child fbObject
+}
+
+func (t *fbTable) addUint8(id int, v uint8) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, size: 1, scalar: uint64(v)})
+	return t
+}
+
+func (t *fbTable) addBool(id int, v bool) *fbTable {
+	if v {
+		return t.addUint8(id, 1)
+	}
+	return t.addUint8(id, 0)
+}
+
+func (t *fbTable) addInt16(id int, v int16) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, size: 2, scalar: uint64(uint16(v))})
+	return t
+}
+
+func (t *fbTable) addInt32(id int, v int32) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, size: 4, scalar: uint64(uint32(v))})
+	return t
+}
+
+func (t *fbTable) addInt64(id int, v int64) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, size: 8, scalar: uint64(v)})
+	return t
+}
+
+func (t *fbTable) addObject(id int, child fbObject) *fbTable {
+	t.fields = append(t.fields, fbField{id: id, size: 4, child: child})
+	return t
+}
+
+func (t *fbTable) writeTo(fb *fbBuilder) int {
+	// Lay out fields in the table starting from 8-byte aligned position,
+	// so the alignment of fields relative to the table start matches their absolute alignment.
+	maxID := -1
+	for _, f := range t.fields {
+		maxID = max(maxID, f.id)
+	}
+	fieldOffsets := make([]int, len(t.fields))
+	tableSize := 4 // soffset to vtable
+	for _, size := range []int{8, 4, 2, 1} {
+		for i, f := range t.fields {
+			if f.size != size {
+				continue
+			}
+			for tableSize%size != 0 {
+				tableSize++
+			}
+			fieldOffsets[i] = tableSize
+			tableSize += size
+		}
+	}
+
+	// Write vtable.
+	fb.pad(2)
+	vtablePos := len(fb.b)
+	vtableSize := 4 + 2*(maxID+1)
+	fb.b = binary.LittleEndian.AppendUint16(fb.b, uint16(vtableSize))
+	fb.b = binary.LittleEndian.AppendUint16(fb.b, uint16(tableSize))
+	vtableFieldsPos := len(fb.b)
+	fb.b = append(fb.b, make([]byte, 2*(maxID+1))...)
+	for i, f := range t.fields {
+		binary.LittleEndian.PutUint16(fb.b[vtableFieldsPos+2*f.id:], uint16(fieldOffsets[i]))
+	}
+
+	// Write table.
+	fb.pad(8)
+	tablePos := len(fb.b)
+	fb.b = append(fb.b, make([]byte, tableSize)...)
+	binary.LittleEndian.PutUint32(fb.b[tablePos:], uint32(int32(tablePos-vtablePos)))
+	for i, f := range t.fields {
+		if f.child != nil {
+			continue
+		}
+		dst := fb.b[tablePos+fieldOffsets[i]:]
+		switch f.size {
+		case 1:
+			dst[0] = byte(f.scalar)
+		case 2:
+			binary.LittleEndian.PutUint16(dst, uint16(f.scalar))
+		case 4:
+			binary.LittleEndian.PutUint32(dst, uint32(f.scalar))
+		case 8:
+			binary.LittleEndian.PutUint64(dst, f.scalar)
+		}
+	}
+
+	// Write children after the table and patch offsets to them.
+	for i, f := range t.fields {
+		if f.child == nil {
+			continue
+		}
+		slotPos := tablePos + fieldOffsets[i]
+		childPos := f.child.writeTo(fb)
+		binary.LittleEndian.PutUint32(fb.b[slotPos:], uint32(childPos-slotPos))
+	}
+	return tablePos
+}
+
+// fbString is a flatbuffers string.
+type fbString string
+
+func (s fbString) writeTo(fb *fbBuilder) int {
+	fb.pad(4)
+	pos := len(fb.b)
+	fb.b = binary.LittleEndian.AppendUint32(fb.b, uint32(len(s)))
+	fb.b = append(fb.b, s...)
+	fb.b = append(fb.b, 0)
+	return pos
+}
+
+// fbTables is a flatbuffers vector of tables.
+type fbTables []*fbTable
+
+func (ts fbTables) writeTo(fb *fbBuilder) int {
+	fb.pad(4)
+	pos := len(fb.b)
+	fb.b = binary.LittleEndian.AppendUint32(fb.b, uint32(len(ts)))
+	slotsPos := len(fb.b)
+	fb.b = append(fb.b, make([]byte, 4*len(ts))...)
+	for i, t := range ts {
+		slotPos := slotsPos + 4*i
+		tablePos := t.writeTo(fb)
+		binary.LittleEndian.PutUint32(fb.b[slotPos:], uint32(tablePos-slotPos))
+	}
+	return pos
+}
+
+// fbStructs is a flatbuffers vector of structs with 8-byte alignment.
+type fbStructs struct {
+	count int
+	data  []byte
+}
+
+func (ss *fbStructs) writeTo(fb *fbBuilder) int {
+	// The vector length must be located right before 8-byte aligned vector data.
+	for (len(fb.b)+4)%8 != 0 {
+		fb.b = append(fb.b, 0)
+	}
+	pos := len(fb.b)
+	fb.b = binary.LittleEndian.AppendUint32(fb.b, uint32(ss.count))
+	fb.b = append(fb.b, ss.data...)
+	return pos
+}
+
+// fbTableReader reads a flatbuffers table.
+type fbTableReader struct {
+	b   []byte
+	pos int
+
+	vtablePos  int
+	vtableSize int
+}
+
+// getRootTable returns the root table for flatbuffer b.
+func getRootTable(b []byte) (fbTableReader, error) {
+	if len(b) < 4 {
+		return fbTableReader{}, fmt.Errorf("too short flatbuffer; got %d bytes; want at least 4 bytes", len(b))
+	}
+	return newTableReader(b, int(binary.LittleEndian.Uint32(b)))
+}
+
+func newTableReader(b []byte, pos int) (fbTableReader, error) {
+	if pos < 0 || pos+4 > len(b) {
+		return fbTableReader{}, fmt.Errorf("table position=%d is out of flatbuffer bounds [0..%d)", pos, len(b))
+	}
+	vtablePos := pos - int(int32(binary.LittleEndian.Uint32(b[pos:])))
+	if vtablePos < 0 || vtablePos+4 > len(b) {
+		return fbTableReader{}, fmt.Errorf("vtable position=%d is out of flatbuffer bounds [0..%d)", vtablePos, len(b))
+	}
+	vtableSize := int(binary.LittleEndian.Uint16(b[vtablePos:]))
+	if vtableSize < 4 || vtablePos+vtableSize > len(b) {
+		return fbTableReader{}, fmt.Errorf("invalid vtable size=%d at position=%d", vtableSize, vtablePos)
+	}
+	tableSize := int(binary.LittleEndian.Uint16(b[vtablePos+2:]))
+	if pos+tableSize > len(b) {
+		return fbTableReader{}, fmt.Errorf("table size=%d at position=%d is out of flatbuffer bounds [0..%d)", tableSize, pos, len(b))
+	}
+	return fbTableReader{
+		b:          b,
+		pos:        pos,
+		vtablePos:  vtablePos,
+		vtableSize: vtableSize,
+	}, nil
+}
+
+// fieldPos returns the position of the field with the given id and the given size.
+//
+// It returns 0 if the field is missing.
+func (t *fbTableReader) fieldPos(id, size int) int {
+	n := 4 + 2*id
+	if n+2 > t.vtableSize {
+		return 0
+	}
+	offset := int(binary.LittleEndian.Uint16(t.b[t.vtablePos+n:]))
+	if offset == 0 {
+		return 0
+	}
+	pos := t.pos + offset
+	if pos+size > len(t.b) {
+		return 0
+	}
+	return pos
+}
+
+func (t *fbTableReader) getUint8(id int, defaultValue uint8) uint8 {
+	pos := t.fieldPos(id, 1)
+	if pos == 0 {
+		return defaultValue
+	}
+	return t.b[pos]
+}
+
+func (t *fbTableReader) getBool(id int) bool {
+	return t.getUint8(id, 0) != 0
+}
+
+func (t *fbTableReader) getInt16(id int, defaultValue int16) int16 {
+	pos := t.fieldPos(id, 2)
+	if pos == 0 {
+		return defaultValue
+	}
+	return int16(binary.LittleEndian.Uint16(t.b[pos:]))
+}
+
+func (t *fbTableReader) getInt32(id int, defaultValue int32) int32 {
+	pos := t.fieldPos(id, 4)
+	if pos == 0 {
+		return defaultValue
+	}
+	return int32(binary.LittleEndian.Uint32(t.b[pos:]))
+}
+
+func (t *fbTableReader) getInt64(id int, defaultValue int64) int64 {
+	pos := t.fieldPos(id, 8)
+	if pos == 0 {
+		return defaultValue
+	}
+	return int64(binary.LittleEndian.Uint64(t.b[pos:]))
+}
+
+// getOffset returns the position of the object referred by the offset field with the given id.
+//
+// It returns 0 if the field is missing.
+func (t *fbTableReader) getOffset(id int) (int, error) {
+	pos := t.fieldPos(id, 4)
+	if pos == 0 {
+		return 0, nil
+	}
+	target := pos + int(binary.LittleEndian.Uint32(t.b[pos:]))
+	if target >= len(t.b) {
+		return 0, fmt.Errorf("offset for field #%d points outside flatbuffer bounds", id)
+	}
+	return target, nil
+}
+
+// getTable returns the table for the field with the given id.
+func (t *fbTableReader) getTable(id int) (fbTableReader, bool, error) {
+	pos, err := t.getOffset(id)
+	if err != nil || pos == 0 {
+		return fbTableReader{}, false, err
+	}
+	tr, err := newTableReader(t.b, pos)
+	if err != nil {
+		return fbTableReader{}, false, err
+	}
+	return tr, true, nil
+}
+
+// getString returns the string for the field with the given id.
+func (t *fbTableReader) getString(id int) (string, error) {
+	pos, n, err := t.getVector(id, 1)
+	if err != nil || pos == 0 {
+		return "", err
+	}
+	return string(t.b[pos : pos+n]), nil
+}
+
+// getVector returns the data position and the number of items for the vector with the given id and the given itemSize.
+//
+// It returns zero position if the field is missing.
+func (t *fbTableReader) getVector(id, itemSize int) (int, int, error) {
+	pos, err := t.getOffset(id)
+	if err != nil || pos == 0 {
+		return 0, 0, err
+	}
+	if pos+4 > len(t.b) {
+		return 0, 0, fmt.Errorf("vector length for field #%d is out of flatbuffer bounds", id)
+	}
+	n := int(binary.LittleEndian.Uint32(t.b[pos:]))
+	pos += 4
+	if n < 0 || n > (len(t.b)-pos)/itemSize {
+		return 0, 0, fmt.Errorf("vector for field #%d with %d items is out of flatbuffer bounds", id, n)
+	}
+	return pos, n, nil
+}
+
+// getStructs returns data for the vector of structs with the given id and the given structSize.
+func (t *fbTableReader) getStructs(id, structSize int) ([]byte, int, error) {
+	pos, n, err := t.getVector(id, structSize)
+	if err != nil || pos == 0 {
+		return nil, 0, err
+	}
+	return t.b[pos : pos+n*structSize], n, nil
+}
+
+// getTables returns tables from the vector with the given id.
+func (t *fbTableReader) getTables(id int) ([]fbTableReader, error) {
+	pos, n, err := t.getVector(id, 4)
+	if err != nil || pos == 0 {
+		return nil, err
+	}
+	tables := make([]fbTableReader, n)
+	for i := 0; i < n; i++ {
+		slotPos := pos + 4*i
+		tr, err := newTableReader(t.b, slotPos+int(binary.LittleEndian.Uint32(t.b[slotPos:])))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read table #%d in vector for field #%d: %w", i, id, err)
+		}
+		tables[i] = tr
+	}
+	return tables, nil
+}
@@ -0,0 +1,90 @@
+package arrow
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// interopSeries contains the series stored in testdata/*.arrow* files.
+//
+// testdata/arrow-go*.arrow* files are generated by github.com/apache/arrow-go via `go run . gen ..`
+// from testdata/interop directory. See testdata/interop/main.go for details.
+var interopSeries = []Series{
+	{
+		Labels: []prompbmarshal.Label{
+			{Name: "__name__", Value: "foo"},
+			{Name: "job", Value: "x"},
+		},
+		Timestamps: []int64{1000, 2000},
+		Values:     []float64{1, 2.5},
+	},
+	{
+		Labels: []prompbmarshal.Label{
+			{Name: "__name__", Value: "bar"},
+		},
+		Timestamps: []int64{3000},
+		Values:     []float64{-3},
+	},
+}
+
+func TestReaderInterop(t *testing.T) {
+	f := func(path string) {
+		t.Helper()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("cannot read %q: %s", path, err)
+		}
+		ssResult := readAllSeries(t, data)
+		if !reflect.DeepEqual(ssResult, interopSeries) {
+			t.Fatalf("unexpected series read from %q\ngot\n%v\nwant\n%v", path, ssResult, interopSeries)
+		}
+	}
+
+	// streams and files written by github.com/apache/arrow-go
+	f("testdata/arrow-go.arrows")
+	f("testdata/arrow-go-zstd.arrows")
+	f("testdata/arrow-go-label-columns.arrows")
+	f("testdata/arrow-go.arrow")
+
+	// stream written by Writer and verified by github.com/apache/arrow-go
+	f("testdata/vm.arrows")
+}
+
+// TestWriterInterop verifies that Writer generates testdata/vm.arrows byte-by-byte.
+//
+// testdata/vm.arrows is verified by github.com/apache/arrow-go via `go run . verify ../vm.arrows`
+// from testdata/interop directory. If Writer output changes, then testdata/vm.arrows must be updated
+// via `UPDATE_TESTDATA=1 go test -run=TestWriterInterop` and verified again.
+func TestWriterInterop(t *testing.T) {
+	var bb bytes.Buffer
+	w := NewWriter(&bb)
+	var rb RecordBatch
+	for _, s := range interopSeries {
+		rb.Reset()
+		rb.AddSeries(s.Labels, s.Timestamps, s.Values)
+		if err := w.WriteRecordBatch(&rb); err != nil {
+			t.Fatalf("cannot write record batch: %s", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("cannot close writer: %s", err)
+	}
+
+	if os.Getenv("UPDATE_TESTDATA") == "1" {
+		if err := os.WriteFile("testdata/vm.arrows", bb.Bytes(), 0o644); err != nil {
+			t.Fatalf("cannot update testdata/vm.arrows: %s", err)
+		}
+	}
+	dataExpected, err := os.ReadFile("testdata/vm.arrows")
+	if err != nil {
+		t.Fatalf("cannot read testdata/vm.arrows: %s", err)
+	}
+	if !bytes.Equal(bb.Bytes(), dataExpected) {
+		t.Fatalf("Writer output doesn't match testdata/vm.arrows; update it via UPDATE_TESTDATA=1 and verify it with testdata/interop")
+	}
+}
@@ -0,0 +1,602 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// maxMetadataSize is the maximum size of message metadata, which can be read by Reader.
+const maxMetadataSize = 64 * 1024 * 1024
+
+// maxBodySize is the maximum size of message body, which can be read by Reader.
+const maxBodySize = 1024 * 1024 * 1024
+
+// fileMagic is the magic prefix for Arrow IPC files.
+const fileMagic = "ARROW1\x00\x00"
+
+// Body compression codecs.
+const (
+	compressionLZ4Frame = 0
+	compressionZSTD     = 1
+)
+
+// Series contains samples for a single series.
+type Series struct {
+	Labels     []prompbmarshal.Label
+	Timestamps []int64
+	Values     []float64
+}
+
+// Reader reads samples from Arrow IPC stream.
+//
+// It supports streams written by Writer and streams written by other tools with the following columns:
+//
+//   - labels - Map<Utf8, Utf8> column with series labels.
+//     Alternatively, series labels may be stored in distinct Utf8 columns named after label names.
+//   - timestamp - Timestamp or Int64 column with millisecond timestamps.
+//   - value - Double or Int64 column.
+//
+// Record batches may be compressed with ZSTD. Dictionary-encoded columns aren't supported.
+type Reader struct {
+	r io.Reader
+
+	columns []*column
+
+	labelsColumn    *column
+	labelColumns    []*column
+	timestampColumn *column
+	valueColumn     *column
+
+	// timestampDivisor and timestampMultiplier are used for converting timestamps to milliseconds.
+	timestampDivisor    int64
+	timestampMultiplier int64
+
+	headerBuf [8]byte
+	metadata  []byte
+	body      []byte
+
+	labels     []prompbmarshal.Label
+	prevLabels []prompbmarshal.Label
+	timestamps []int64
+	values     []float64
+
+	eos bool
+}
+
+type column struct {
+	name     string
+	typ      uint8
+	timeUnit int16
+	bitWidth int32
+
+	// precision is set for floating point columns.
+	precision int16
+
+	children []*column
+
+	// The following fields are filled for every record batch.
+	length   int
+	validity []byte
+	offsets  []byte
+	data     []byte
+}
+
+// NewReader returns new Reader for Arrow IPC stream read from r.
+//
+// Arrow IPC files are supported too, since they contain Arrow IPC stream after the file magic.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r: r,
+	}
+}
+
+// ReadRecordBatch reads the next record batch and calls f for each series in it.
+//
+// Consecutive rows with identical labels are passed to f as a single series.
+// f must not hold references to s and its contents after returning.
+//
+// It returns false when the end of stream is reached.
+func (ar *Reader) ReadRecordBatch(f func(s *Series) error) (bool, error) {
+	for {
+		if ar.eos {
+			return false, nil
+		}
+		headerType, header, err := ar.readMessage()
+		if err != nil {
+			return false, err
+		}
+		switch headerType {
+		case 0:
+			ar.eos = true
+			if ar.columns == nil {
+				return false, fmt.Errorf("missing schema message in arrow stream")
+			}
+			return false, nil
+		case messageHeaderSchema:
+			if ar.columns != nil {
+				return false, fmt.Errorf("unexpected second schema message in arrow stream")
+			}
+			if err := ar.initSchema(header); err != nil {
+				return false, fmt.Errorf("cannot parse arrow schema: %w", err)
+			}
+		case messageHeaderDictionaryBatch:
+			return false, fmt.Errorf("dictionary-encoded arrow columns aren't supported")
+		case messageHeaderRecordBatch:
+			if ar.columns == nil {
+				return false, fmt.Errorf("missing schema message before record batch in arrow stream")
+			}
+			if err := ar.readRecordBatch(header, f); err != nil {
+				return false, fmt.Errorf("cannot read arrow record batch: %w", err)
+			}
+			return true, nil
+		default:
+			// Skip unsupported messages such as Tensor and SparseTensor
+		}
+	}
+}
+
+// readMessage reads the next encapsulated message.
+//
+// It returns zero headerType at the end of stream.
+func (ar *Reader) readMessage() (uint8, fbTableReader, error) {
+	hb := ar.headerBuf[:4]
+	if _, err := io.ReadFull(ar.r, hb); err != nil {
+		if errors.Is(err, io.EOF) {
+			// The end of stream marker is optional.
+			return 0, fbTableReader{}, nil
+		}
+		return 0, fbTableReader{}, fmt.Errorf("cannot read arrow message length: %w", err)
+	}
+	if string(hb) == fileMagic[:4] {
+		// Skip the rest of Arrow file magic and read the message length.
+		if _, err := io.ReadFull(ar.r, ar.headerBuf[:]); err != nil {
+			return 0, fbTableReader{}, fmt.Errorf("cannot read arrow file magic: %w", err)
+		}
+		if string(ar.headerBuf[:4]) != fileMagic[4:] {
+			return 0, fbTableReader{}, fmt.Errorf("invalid arrow file magic")
+		}
+		copy(hb, ar.headerBuf[4:])
+	}
+	metadataLen := binary.LittleEndian.Uint32(hb)
+	if metadataLen == continuationMarker {
+		if _, err := io.ReadFull(ar.r, hb); err != nil {
+			return 0, fbTableReader{}, fmt.Errorf("cannot read arrow message length: %w", err)
+		}
+		metadataLen = binary.LittleEndian.Uint32(hb)
+	}
+	if metadataLen == 0 {
+		return 0, fbTableReader{}, nil
+	}
+	if metadataLen > maxMetadataSize {
+		return 0, fbTableReader{}, fmt.Errorf("too big arrow message metadata size=%d; mustn't exceed %d bytes", metadataLen, maxMetadataSize)
+	}
+	ar.metadata = bytesutil.ResizeNoCopyNoOverallocate(ar.metadata, int(metadataLen))
+	if _, err := io.ReadFull(ar.r, ar.metadata); err != nil {
+		return 0, fbTableReader{}, fmt.Errorf("cannot read arrow message metadata: %w", err)
+	}
+	msg, err := getRootTable(ar.metadata)
+	if err != nil {
+		return 0, fbTableReader{}, fmt.Errorf("cannot parse arrow message: %w", err)
+	}
+	bodyLen := msg.getInt64(3, 0)
+	if bodyLen < 0 || bodyLen > maxBodySize {
+		return 0, fbTableReader{}, fmt.Errorf("invalid arrow message body size=%d; it must be in the range [0..%d]", bodyLen, maxBodySize)
+	}
+	ar.body = bytesutil.ResizeNoCopyNoOverallocate(ar.body, int(bodyLen))
+	if _, err := io.ReadFull(ar.r, ar.body); err != nil {
+		return 0, fbTableReader{}, fmt.Errorf("cannot read arrow message body: %w", err)
+	}
+	headerType := msg.getUint8(1, 0)
+	if headerType == 0 {
+		return 0, fbTableReader{}, fmt.Errorf("missing arrow message header type")
+	}
+	header, ok, err := msg.getTable(2)
+	if err != nil {
+		return 0, fbTableReader{}, fmt.Errorf("cannot read arrow message header: %w", err)
+	}
+	if !ok {
+		return 0, fbTableReader{}, fmt.Errorf("missing arrow message header")
+	}
+	return headerType, header, nil
+}
+
+func (ar *Reader) initSchema(schema fbTableReader) error {
+	if schema.getInt16(0, 0) != 0 {
+		return fmt.Errorf("big-endian arrow streams aren't supported")
+	}
+	fields, err := schema.getTables(1)
+	if err != nil {
+		return fmt.Errorf("cannot read schema fields: %w", err)
+	}
+	columns := make([]*column, len(fields))
+	for i := range fields {
+		c, err := parseField(&fields[i], 0)
+		if err != nil {
+			return err
+		}
+		columns[i] = c
+	}
+	for _, c := range columns {
+		switch {
+		case c.name == TimestampColumn:
+			switch {
+			case c.typ == typeTimestamp:
+				switch c.timeUnit {
+				case timeUnitSecond:
+					ar.timestampMultiplier = 1000
+				case timeUnitMillisecond:
+				case timeUnitMicrosecond:
+					ar.timestampDivisor = 1000
+				case timeUnitNanosecond:
+					ar.timestampDivisor = 1000_000
+				default:
+					return fmt.Errorf("unsupported time unit=%d for %q column", c.timeUnit, c.name)
+				}
+			case c.typ == typeInt && c.bitWidth == 64:
+			default:
+				return fmt.Errorf("unsupported type for %q column; it must be Timestamp or Int64", c.name)
+			}
+			ar.timestampColumn = c
+		case c.name == ValueColumn:
+			if (c.typ != typeFloatingPoint || c.precision != precisionDouble) && (c.typ != typeInt || c.bitWidth != 64) {
+				return fmt.Errorf("unsupported type for %q column; it must be Double or Int64", c.name)
+			}
+			ar.valueColumn = c
+		case c.typ == typeMap:
+			if ar.labelsColumn != nil {
+				return fmt.Errorf("unexpected second map column %q; only a single map column with labels is supported", c.name)
+			}
+			if len(c.children) != 1 || len(c.children[0].children) != 2 || c.children[0].children[0].typ != typeUtf8 || c.children[0].children[1].typ != typeUtf8 {
+				return fmt.Errorf("unsupported type for %q column; it must be Map<Utf8, Utf8>", c.name)
+			}
+			ar.labelsColumn = c
+		case c.typ == typeUtf8:
+			ar.labelColumns = append(ar.labelColumns, c)
+		default:
+			return fmt.Errorf("unsupported type for %q column; label columns must have Utf8 type", c.name)
+		}
+	}
+	if ar.timestampColumn == nil {
+		return fmt.Errorf("missing %q column", TimestampColumn)
+	}
+	if ar.valueColumn == nil {
+		return fmt.Errorf("missing %q column", ValueColumn)
+	}
+	if ar.timestampDivisor == 0 {
+		ar.timestampDivisor = 1
+	}
+	if ar.timestampMultiplier == 0 {
+		ar.timestampMultiplier = 1
+	}
+	ar.columns = columns
+	return nil
+}
+
+func parseField(field *fbTableReader, depth int) (*column, error) {
+	if depth > 8 {
+		return nil, fmt.Errorf("too deep nesting for arrow field")
+	}
+	name, err := field.getString(0)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read field name: %w", err)
+	}
+	if _, ok, err := field.getTable(4); err != nil || ok {
+		return nil, fmt.Errorf("dictionary-encoded column %q isn't supported", name)
+	}
+	c := &column{
+		name: name,
+		typ:  field.getUint8(2, 0),
+	}
+	typ, _, err := field.getTable(3)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read type for column %q: %w", name, err)
+	}
+	switch c.typ {
+	case typeInt:
+		c.bitWidth = typ.getInt32(0, 0)
+	case typeFloatingPoint:
+		c.precision = typ.getInt16(0, 0)
+	case typeTimestamp:
+		c.timeUnit = typ.getInt16(0, 0)
+	case typeUtf8, typeStruct, typeMap:
+	default:
+		return nil, fmt.Errorf("unsupported type=%d for column %q", c.typ, name)
+	}
+	children, err := field.getTables(5)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read children for column %q: %w", name, err)
+	}
+	for i := range children {
+		child, err := parseField(&children[i], depth+1)
+		if err != nil {
+			return nil, err
+		}
+		c.children = append(c.children, child)
+	}
+	return c, nil
+}
+
+// recordBatchLoader loads record batch data into columns.
+type recordBatchLoader struct {
+	body        []byte
+	nodes       []byte
+	buffers     []byte
+	compression int
+}
+
+func (rbl *recordBatchLoader) nextNode() (int, error) {
+	if len(rbl.nodes) < 16 {
+		return 0, fmt.Errorf("too few field nodes in record batch")
+	}
+	length := int64(binary.LittleEndian.Uint64(rbl.nodes))
+	rbl.nodes = rbl.nodes[16:]
+	if length < 0 || length > math.MaxInt32 {
+		return 0, fmt.Errorf("invalid field node length=%d", length)
+	}
+	return int(length), nil
+}
+
+func (rbl *recordBatchLoader) nextBuffer() ([]byte, error) {
+	if len(rbl.buffers) < 16 {
+		return nil, fmt.Errorf("too few buffers in record batch")
+	}
+	offset := binary.LittleEndian.Uint64(rbl.buffers)
+	length := binary.LittleEndian.Uint64(rbl.buffers[8:])
+	rbl.buffers = rbl.buffers[16:]
+	if offset > uint64(len(rbl.body)) || length > uint64(len(rbl.body))-offset {
+		return nil, fmt.Errorf("buffer with offset=%d and length=%d is out of body bounds [0..%d)", offset, length, len(rbl.body))
+	}
+	data := rbl.body[offset : offset+length]
+	if rbl.compression < 0 || len(data) == 0 {
+		return data, nil
+	}
+	if len(data) < 8 {
+		return nil, fmt.Errorf("too short compressed buffer; got %d bytes; want at least 8 bytes", len(data))
+	}
+	uncompressedLen := int64(binary.LittleEndian.Uint64(data))
+	data = data[8:]
+	if uncompressedLen == -1 {
+		return data, nil
+	}
+	if uncompressedLen < 0 || uncompressedLen > maxBodySize {
+		return nil, fmt.Errorf("invalid uncompressed buffer length=%d", uncompressedLen)
+	}
+	// Allocate new buffer, since series labels may refer to it.
+	dst, err := zstd.Decompress(make([]byte, 0, uncompressedLen), data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress zstd buffer: %w", err)
+	}
+	if int64(len(dst)) != uncompressedLen {
+		return nil, fmt.Errorf("unexpected uncompressed buffer length; got %d; want %d", len(dst), uncompressedLen)
+	}
+	return dst, nil
+}
+
+func (rbl *recordBatchLoader) loadColumn(c *column) error {
+	length, err := rbl.nextNode()
+	if err != nil {
+		return err
+	}
+	c.length = length
+	if c.validity, err = rbl.nextBuffer(); err != nil {
+		return err
+	}
+	if len(c.validity) > 0 && len(c.validity)*8 < length {
+		return fmt.Errorf("too short validity buffer for column %q", c.name)
+	}
+	c.offsets = nil
+	c.data = nil
+	switch c.typ {
+	case typeUtf8:
+		if c.offsets, err = rbl.nextBuffer(); err != nil {
+			return err
+		}
+		if c.data, err = rbl.nextBuffer(); err != nil {
+			return err
+		}
+		if err := c.checkOffsets(len(c.data)); err != nil {
+			return err
+		}
+	case typeMap:
+		if c.offsets, err = rbl.nextBuffer(); err != nil {
+			return err
+		}
+	case typeInt, typeFloatingPoint, typeTimestamp:
+		if c.data, err = rbl.nextBuffer(); err != nil {
+			return err
+		}
+		if len(c.data) < 8*length {
+			return fmt.Errorf("too short data buffer for column %q; got %d bytes; want at least %d bytes", c.name, len(c.data), 8*length)
+		}
+	}
+	for _, child := range c.children {
+		if err := rbl.loadColumn(child); err != nil {
+			return err
+		}
+		if c.typ == typeStruct && child.length < length {
+			return fmt.Errorf("too few rows in column %q; got %d; want at least %d", child.name, child.length, length)
+		}
+	}
+	if c.typ == typeMap {
+		if err := c.checkOffsets(c.children[0].length); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *column) checkOffsets(maxOffset int) error {
+	if c.length == 0 {
+		return nil
+	}
+	if len(c.offsets) < 4*(c.length+1) {
+		return fmt.Errorf("too short offsets buffer for column %q; got %d bytes; want at least %d bytes", c.name, len(c.offsets), 4*(c.length+1))
+	}
+	prev := int32(0)
+	for i := 0; i <= c.length; i++ {
+		offset := int32(binary.LittleEndian.Uint32(c.offsets[4*i:]))
+		if offset < prev || int(offset) > maxOffset {
+			return fmt.Errorf("invalid offset=%d at position %d for column %q", offset, i, c.name)
+		}
+		prev = offset
+	}
+	return nil
+}
+
+func (c *column) isNull(i int) bool {
+	if len(c.validity) == 0 {
+		return false
+	}
+	return c.validity[i/8]&(1<<(i%8)) == 0
+}
+
+func (c *column) offsetsAt(i int) (int, int) {
+	start := binary.LittleEndian.Uint32(c.offsets[4*i:])
+	end := binary.LittleEndian.Uint32(c.offsets[4*(i+1):])
+	return int(start), int(end)
+}
+
+func (c *column) stringAt(i int) string {
+	if c.isNull(i) {
+		return ""
+	}
+	start, end := c.offsetsAt(i)
+	return bytesutil.ToUnsafeString(c.data[start:end])
+}
+
+func (c *column) uint64At(i int) uint64 {
+	return binary.LittleEndian.Uint64(c.data[8*i:])
+}
+
+func (ar *Reader) readRecordBatch(header fbTableReader, f func(s *Series) error) error {
+	rowsCount := header.getInt64(0, 0)
+	nodes, _, err := header.getStructs(1, 16)
+	if err != nil {
+		return fmt.Errorf("cannot read field nodes: %w", err)
+	}
+	buffers, _, err := header.getStructs(2, 16)
+	if err != nil {
+		return fmt.Errorf("cannot read buffers: %w", err)
+	}
+	rbl := &recordBatchLoader{
+		body:        ar.body,
+		nodes:       nodes,
+		buffers:     buffers,
+		compression: -1,
+	}
+	compression, ok, err := header.getTable(3)
+	if err != nil {
+		return fmt.Errorf("cannot read body compression: %w", err)
+	}
+	if ok {
+		codec := compression.getUint8(0, compressionLZ4Frame)
+		if codec != compressionZSTD {
+			return fmt.Errorf("unsupported body compression codec=%d; only ZSTD is supported", codec)
+		}
+		rbl.compression = compressionZSTD
+	}
+	for _, c := range ar.columns {
+		if err := rbl.loadColumn(c); err != nil {
+			return err
+		}
+		if int64(c.length) != rowsCount {
+			return fmt.Errorf("unexpected number of rows in column %q; got %d; want %d", c.name, c.length, rowsCount)
+		}
+	}
+	return ar.assembleSeries(int(rowsCount), f)
+}
+
+func (ar *Reader) assembleSeries(rowsCount int, f func(s *Series) error) error {
+	ar.prevLabels = ar.prevLabels[:0]
+	ar.timestamps = ar.timestamps[:0]
+	ar.values = ar.values[:0]
+	flush := func() error {
+		if len(ar.timestamps) == 0 {
+			return nil
+		}
+		s := &Series{
+			Labels:     ar.prevLabels,
+			Timestamps: ar.timestamps,
+			Values:     ar.values,
+		}
+		if err := f(s); err != nil {
+			return err
+		}
+		ar.timestamps = ar.timestamps[:0]
+		ar.values = ar.values[:0]
+		return nil
+	}
+
+	tc := ar.timestampColumn
+	vc := ar.valueColumn
+	for i := 0; i < rowsCount; i++ {
+		if tc.isNull(i) || vc.isNull(i) {
+			continue
+		}
+		labels := ar.getRowLabels(i)
+		if !labelsEqual(labels, ar.prevLabels) {
+			if err := flush(); err != nil {
+				return err
+			}
+			ar.prevLabels = append(ar.prevLabels[:0], labels...)
+		}
+
+		ts := int64(tc.uint64At(i))
+		ts = ts / ar.timestampDivisor * ar.timestampMultiplier
+		var v float64
+		if vc.typ == typeInt {
+			v = float64(int64(vc.uint64At(i)))
+		} else {
+			v = math.Float64frombits(vc.uint64At(i))
+		}
+		ar.timestamps = append(ar.timestamps, ts)
+		ar.values = append(ar.values, v)
+	}
+	return flush()
+}
+
+func (ar *Reader) getRowLabels(i int) []prompbmarshal.Label {
+	labels := ar.labels[:0]
+	if lc := ar.labelsColumn; lc != nil && !lc.isNull(i) {
+		keys := lc.children[0].children[0]
+		values := lc.children[0].children[1]
+		start, end := lc.offsetsAt(i)
+		for j := start; j < end; j++ {
+			labels = append(labels, prompbmarshal.Label{
+				Name:  keys.stringAt(j),
+				Value: values.stringAt(j),
+			})
+		}
+	}
+	for _, c := range ar.labelColumns {
+		value := c.stringAt(i)
+		if value == "" {
+			continue
+		}
+		labels = append(labels, prompbmarshal.Label{
+			Name:  c.name,
+			Value: value,
+		})
+	}
+	ar.labels = labels
+	return labels
+}
+
+func labelsEqual(a, b []prompbmarshal.Label) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
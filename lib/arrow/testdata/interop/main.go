@@ -0,0 +1,210 @@
+// The interop program writes Arrow IPC streams with the reference implementation from github.com/apache/arrow-go
+// and verifies that streams written by lib/arrow are readable by the reference implementation.
+//
+// Usage:
+//
+//	go run . gen /path/to/testdata
+//	go run . verify /path/to/file.arrows
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+type label struct {
+	name  string
+	value string
+}
+
+type row struct {
+	labels    []label
+	timestamp int64
+	value     float64
+}
+
+var rows = []row{
+	{labels: []label{{"__name__", "foo"}, {"job", "x"}}, timestamp: 1000, value: 1},
+	{labels: []label{{"__name__", "foo"}, {"job", "x"}}, timestamp: 2000, value: 2.5},
+	{labels: []label{{"__name__", "bar"}}, timestamp: 3000, value: -3},
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatalf("usage: %s (gen <dir>|verify <file>)", os.Args[0])
+	}
+	switch os.Args[1] {
+	case "gen":
+		gen(os.Args[2])
+	case "verify":
+		verify(os.Args[2])
+	default:
+		log.Fatalf("unknown command %q", os.Args[1])
+	}
+}
+
+var mapSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "labels", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.BinaryTypes.String)},
+	{Name: "timestamp", Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}},
+	{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+}, nil)
+
+var labelColumnsSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "__name__", Type: arrow.BinaryTypes.String},
+	{Name: "job", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "timestamp", Type: &arrow.TimestampType{Unit: arrow.Microsecond}},
+	{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+}, nil)
+
+func gen(dir string) {
+	writeStream(filepath.Join(dir, "arrow-go.arrows"), mapSchema, newMapRecord)
+	writeStream(filepath.Join(dir, "arrow-go-zstd.arrows"), mapSchema, newMapRecord, ipc.WithZstd())
+	writeStream(filepath.Join(dir, "arrow-go-label-columns.arrows"), labelColumnsSchema, newLabelColumnsRecord)
+	writeFile(filepath.Join(dir, "arrow-go.arrow"), mapSchema, newMapRecord)
+}
+
+// newMapRecord returns a record with rs stored in labels map column.
+func newMapRecord(rs []row) arrow.RecordBatch {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, mapSchema)
+	defer b.Release()
+	mb := b.Field(0).(*array.MapBuilder)
+	kb := mb.KeyBuilder().(*array.StringBuilder)
+	ib := mb.ItemBuilder().(*array.StringBuilder)
+	tb := b.Field(1).(*array.TimestampBuilder)
+	vb := b.Field(2).(*array.Float64Builder)
+	for _, r := range rs {
+		mb.Append(true)
+		for _, l := range r.labels {
+			kb.Append(l.name)
+			ib.Append(l.value)
+		}
+		tb.Append(arrow.Timestamp(r.timestamp))
+		vb.Append(r.value)
+	}
+	return b.NewRecordBatch()
+}
+
+// newLabelColumnsRecord returns a record with rs labels stored in distinct columns and timestamps in microseconds.
+func newLabelColumnsRecord(rs []row) arrow.RecordBatch {
+	b := array.NewRecordBuilder(memory.DefaultAllocator, labelColumnsSchema)
+	defer b.Release()
+	nb := b.Field(0).(*array.StringBuilder)
+	jb := b.Field(1).(*array.StringBuilder)
+	tb := b.Field(2).(*array.TimestampBuilder)
+	vb := b.Field(3).(*array.Float64Builder)
+	for _, r := range rs {
+		job := ""
+		for _, l := range r.labels {
+			switch l.name {
+			case "__name__":
+				nb.Append(l.value)
+			case "job":
+				job = l.value
+			}
+		}
+		if job == "" {
+			jb.AppendNull()
+		} else {
+			jb.Append(job)
+		}
+		tb.Append(arrow.Timestamp(r.timestamp * 1000))
+		vb.Append(r.value)
+	}
+	return b.NewRecordBatch()
+}
+
+// batches returns rows split into two record batches in order to verify reading multiple record batches.
+func batches() [][]row {
+	return [][]row{rows[:2], rows[2:]}
+}
+
+func writeStream(path string, schema *arrow.Schema, newRecord func(rs []row) arrow.RecordBatch, opts ...ipc.Option) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("cannot create %q: %s", path, err)
+	}
+	opts = append(opts, ipc.WithSchema(schema))
+	w := ipc.NewWriter(f, opts...)
+	for _, rs := range batches() {
+		rec := newRecord(rs)
+		if err := w.Write(rec); err != nil {
+			log.Fatalf("cannot write record to %q: %s", path, err)
+		}
+		rec.Release()
+	}
+	if err := w.Close(); err != nil {
+		log.Fatalf("cannot close writer for %q: %s", path, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("cannot close %q: %s", path, err)
+	}
+}
+
+func writeFile(path string, schema *arrow.Schema, newRecord func(rs []row) arrow.RecordBatch) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("cannot create %q: %s", path, err)
+	}
+	w, err := ipc.NewFileWriter(f, ipc.WithSchema(schema))
+	if err != nil {
+		log.Fatalf("cannot create writer for %q: %s", path, err)
+	}
+	for _, rs := range batches() {
+		rec := newRecord(rs)
+		if err := w.Write(rec); err != nil {
+			log.Fatalf("cannot write record to %q: %s", path, err)
+		}
+		rec.Release()
+	}
+	if err := w.Close(); err != nil {
+		log.Fatalf("cannot close writer for %q: %s", path, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("cannot close %q: %s", path, err)
+	}
+}
+
+func verify(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("cannot open %q: %s", path, err)
+	}
+	defer f.Close()
+	r, err := ipc.NewReader(f, ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		log.Fatalf("cannot create reader for %q: %s", path, err)
+	}
+	defer r.Release()
+	fmt.Println(r.Schema())
+	for r.Next() {
+		rec := r.RecordBatch()
+		sa, err := array.NewStructArray(rec.Columns(), fieldNames(rec.Schema()))
+		if err != nil {
+			log.Fatalf("invalid record in %q: %s", path, err)
+		}
+		if err := sa.ValidateFull(); err != nil {
+			log.Fatalf("invalid record data in %q: %s", path, err)
+		}
+		sa.Release()
+		fmt.Println(rec)
+	}
+	if err := r.Err(); err != nil && err != io.EOF {
+		log.Fatalf("cannot read %q: %s", path, err)
+	}
+}
+
+func fieldNames(schema *arrow.Schema) []string {
+	var names []string
+	for _, f := range schema.Fields() {
+		names = append(names, f.Name)
+	}
+	return names
+}
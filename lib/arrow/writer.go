@@ -0,0 +1,355 @@
+package arrow
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// Column names in Arrow streams.
+//
+// Every row contains a single sample. Series labels are stored in the map column.
+const (
+	LabelsColumn    = "labels"
+	TimestampColumn = "timestamp"
+	ValueColumn     = "value"
+)
+
+// Arrow metadata version V5.
+const metadataVersion = 4
+
+// Arrow message header types.
+const (
+	messageHeaderSchema          = 1
+	messageHeaderDictionaryBatch = 2
+	messageHeaderRecordBatch     = 3
+)
+
+// Arrow types.
+const (
+	typeInt           = 2
+	typeFloatingPoint = 3
+	typeUtf8          = 5
+	typeTimestamp     = 10
+	typeStruct        = 13
+	typeMap           = 17
+)
+
+// Arrow time units.
+const (
+	timeUnitSecond      = 0
+	timeUnitMillisecond = 1
+	timeUnitMicrosecond = 2
+	timeUnitNanosecond  = 3
+)
+
+// precisionDouble is Arrow floating point precision for float64.
+const precisionDouble = 2
+
+// continuationMarker precedes every message in Arrow IPC stream.
+const continuationMarker = 0xffffffff
+
+// Writer writes samples in Arrow IPC streaming format.
+//
+// Every sample is written as a row with labels, timestamp and value columns.
+// Rows are written in record batches via WriteRecordBatch. Close must be called after writing all the record batches.
+//
+// See https://arrow.apache.org/docs/format/Columnar.html#ipc-streaming-format
+type Writer struct {
+	mu sync.Mutex
+
+	w             io.Writer
+	schemaWritten bool
+	err           error
+}
+
+// NewWriter returns new Writer, which writes Arrow IPC stream to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: w,
+	}
+}
+
+// WriteRecordBatch writes rb to w.
+//
+// It is safe calling WriteRecordBatch from concurrently running goroutines.
+// rb may be re-used after WriteRecordBatch returns.
+func (w *Writer) WriteRecordBatch(rb *RecordBatch) error {
+	if rb.rowsCount == 0 {
+		return nil
+	}
+
+	// Marshal the record batch outside the lock, so multiple record batches can be marshaled in parallel.
+	rb.marshal()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeSchemaIfNeededLocked(); err != nil {
+		return err
+	}
+	return w.writeLocked(rb.buf)
+}
+
+// Close writes the end of stream marker to w.
+//
+// Close doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeSchemaIfNeededLocked(); err != nil {
+		return err
+	}
+	var b []byte
+	b = binary.LittleEndian.AppendUint32(b, continuationMarker)
+	b = binary.LittleEndian.AppendUint32(b, 0)
+	return w.writeLocked(b)
+}
+
+func (w *Writer) writeSchemaIfNeededLocked() error {
+	if w.schemaWritten {
+		return nil
+	}
+	w.schemaWritten = true
+	return w.writeLocked(marshalSchemaMessage(nil))
+}
+
+func (w *Writer) writeLocked(data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	if _, err := w.w.Write(data); err != nil {
+		w.err = fmt.Errorf("cannot write arrow data: %w", err)
+		return w.err
+	}
+	return nil
+}
+
+func marshalSchemaMessage(dst []byte) []byte {
+	utf8Field := func(name string, nullable bool) *fbTable {
+		t := &fbTable{}
+		t.addObject(0, fbString(name))
+		t.addBool(1, nullable)
+		t.addUint8(2, typeUtf8)
+		t.addObject(3, &fbTable{})
+		t.addObject(5, fbTables{})
+		return t
+	}
+
+	entries := &fbTable{}
+	entries.addObject(0, fbString("entries"))
+	entries.addBool(1, false)
+	entries.addUint8(2, typeStruct)
+	entries.addObject(3, &fbTable{})
+	entries.addObject(5, fbTables{utf8Field("key", false), utf8Field("value", true)})
+
+	mapType := &fbTable{}
+	mapType.addBool(0, false) // keysSorted
+	labels := &fbTable{}
+	labels.addObject(0, fbString(LabelsColumn))
+	labels.addBool(1, false)
+	labels.addUint8(2, typeMap)
+	labels.addObject(3, mapType)
+	labels.addObject(5, fbTables{entries})
+
+	tsType := &fbTable{}
+	tsType.addInt16(0, timeUnitMillisecond)
+	tsType.addObject(1, fbString("UTC"))
+	timestamp := &fbTable{}
+	timestamp.addObject(0, fbString(TimestampColumn))
+	timestamp.addBool(1, false)
+	timestamp.addUint8(2, typeTimestamp)
+	timestamp.addObject(3, tsType)
+	timestamp.addObject(5, fbTables{})
+
+	valueType := &fbTable{}
+	valueType.addInt16(0, precisionDouble)
+	value := &fbTable{}
+	value.addObject(0, fbString(ValueColumn))
+	value.addBool(1, false)
+	value.addUint8(2, typeFloatingPoint)
+	value.addObject(3, valueType)
+	value.addObject(5, fbTables{})
+
+	schema := &fbTable{}
+	schema.addInt16(0, 0) // little endian
+	schema.addObject(1, fbTables{labels, timestamp, value})
+
+	return appendMessage(dst, messageHeaderSchema, schema, nil)
+}
+
+// appendMessage appends encapsulated message with the given header and body to dst.
+//
+// See https://arrow.apache.org/docs/format/Columnar.html#encapsulated-message-format
+func appendMessage(dst []byte, headerType uint8, header *fbTable, body []byte) []byte {
+	msg := &fbTable{}
+	msg.addInt16(0, metadataVersion)
+	msg.addUint8(1, headerType)
+	msg.addObject(2, header)
+	msg.addInt64(3, int64(len(body)))
+	var fb fbBuilder
+	metadata := fb.finish(msg)
+
+	// The metadata must be padded to 8 bytes, so the body is properly aligned.
+	metadataLen := (len(metadata) + 7) &^ 7
+	dst = binary.LittleEndian.AppendUint32(dst, continuationMarker)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(metadataLen))
+	dst = append(dst, metadata...)
+	dst = append(dst, make([]byte, metadataLen-len(metadata))...)
+	return append(dst, body...)
+}
+
+// RecordBatch accumulates rows for writing them to Arrow IPC stream via Writer.WriteRecordBatch.
+type RecordBatch struct {
+	rowsCount int
+
+	// labelsOffsets contains offsets for labels map entries per each row.
+	labelsOffsets []int32
+
+	keysOffsets   []int32
+	keysData      []byte
+	valuesOffsets []int32
+	valuesData    []byte
+
+	timestamps []int64
+	values     []float64
+
+	buf  []byte
+	body []byte
+}
+
+// Reset resets rb, so it can be re-used.
+func (rb *RecordBatch) Reset() {
+	rb.rowsCount = 0
+	rb.labelsOffsets = rb.labelsOffsets[:0]
+	rb.keysOffsets = rb.keysOffsets[:0]
+	rb.keysData = rb.keysData[:0]
+	rb.valuesOffsets = rb.valuesOffsets[:0]
+	rb.valuesData = rb.valuesData[:0]
+	rb.timestamps = rb.timestamps[:0]
+	rb.values = rb.values[:0]
+	rb.buf = rb.buf[:0]
+}
+
+// RowsCount returns the number of rows in rb.
+func (rb *RecordBatch) RowsCount() int {
+	return rb.rowsCount
+}
+
+// SizeBytes returns the approximate size of rb in bytes.
+func (rb *RecordBatch) SizeBytes() int {
+	return len(rb.keysData) + len(rb.valuesData) + 4*(len(rb.labelsOffsets)+len(rb.keysOffsets)+len(rb.valuesOffsets)) + 8*len(rb.timestamps) + 8*len(rb.values)
+}
+
+// AddSeries adds rows for the given samples of the series with the given labels to rb.
+//
+// timestamps must contain millisecond timestamps. The length of timestamps and values must be equal.
+func (rb *RecordBatch) AddSeries(labels []prompbmarshal.Label, timestamps []int64, values []float64) {
+	if len(timestamps) != len(values) {
+		panic(fmt.Errorf("BUG: len(timestamps)=%d must match len(values)=%d", len(timestamps), len(values)))
+	}
+	if len(timestamps) == 0 {
+		return
+	}
+	if rb.rowsCount == 0 {
+		rb.labelsOffsets = append(rb.labelsOffsets[:0], 0)
+		rb.keysOffsets = append(rb.keysOffsets[:0], 0)
+		rb.valuesOffsets = append(rb.valuesOffsets[:0], 0)
+	}
+	for range timestamps {
+		for _, label := range labels {
+			rb.keysData = append(rb.keysData, label.Name...)
+			rb.keysOffsets = append(rb.keysOffsets, int32(len(rb.keysData)))
+			rb.valuesData = append(rb.valuesData, label.Value...)
+			rb.valuesOffsets = append(rb.valuesOffsets, int32(len(rb.valuesData)))
+		}
+		rb.labelsOffsets = append(rb.labelsOffsets, int32(len(rb.keysOffsets)-1))
+	}
+	rb.timestamps = append(rb.timestamps, timestamps...)
+	rb.values = append(rb.values, values...)
+	rb.rowsCount += len(timestamps)
+}
+
+// marshal marshals rb as encapsulated RecordBatch message to rb.buf.
+func (rb *RecordBatch) marshal() {
+	entriesCount := len(rb.keysOffsets) - 1
+	var nodes []byte
+	addNode := func(length int) {
+		nodes = binary.LittleEndian.AppendUint64(nodes, uint64(length))
+		nodes = binary.LittleEndian.AppendUint64(nodes, 0) // null_count
+	}
+	var buffers []byte
+	body := rb.body[:0]
+	addBuffer := func(data []byte) {
+		offset := len(body)
+		body = append(body, data...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+		buffers = binary.LittleEndian.AppendUint64(buffers, uint64(offset))
+		buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(data)))
+	}
+	var tmp []byte
+	appendInt32s := func(a []int32) []byte {
+		tmp = tmp[:0]
+		for _, v := range a {
+			tmp = binary.LittleEndian.AppendUint32(tmp, uint32(v))
+		}
+		return tmp
+	}
+
+	// labels map: validity and offsets
+	addNode(rb.rowsCount)
+	addBuffer(nil)
+	addBuffer(appendInt32s(rb.labelsOffsets))
+
+	// labels map entries struct: validity
+	addNode(entriesCount)
+	addBuffer(nil)
+
+	// labels keys and values: validity, offsets and data
+	addNode(entriesCount)
+	addBuffer(nil)
+	addBuffer(appendInt32s(rb.keysOffsets))
+	addBuffer(rb.keysData)
+	addNode(entriesCount)
+	addBuffer(nil)
+	addBuffer(appendInt32s(rb.valuesOffsets))
+	addBuffer(rb.valuesData)
+
+	// timestamps: validity and data
+	addNode(rb.rowsCount)
+	addBuffer(nil)
+	tmp = tmp[:0]
+	for _, ts := range rb.timestamps {
+		tmp = binary.LittleEndian.AppendUint64(tmp, uint64(ts))
+	}
+	addBuffer(tmp)
+
+	// values: validity and data
+	addNode(rb.rowsCount)
+	addBuffer(nil)
+	tmp = tmp[:0]
+	for _, v := range rb.values {
+		tmp = binary.LittleEndian.AppendUint64(tmp, math.Float64bits(v))
+	}
+	addBuffer(tmp)
+
+	header := &fbTable{}
+	header.addInt64(0, int64(rb.rowsCount))
+	header.addObject(1, &fbStructs{
+		count: len(nodes) / 16,
+		data:  nodes,
+	})
+	header.addObject(2, &fbStructs{
+		count: len(buffers) / 16,
+		data:  buffers,
+	})
+	rb.body = body
+	rb.buf = appendMessage(rb.buf[:0], messageHeaderRecordBatch, header, body)
+}
@@ -1,6 +1,7 @@
 package awsapi
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -129,6 +130,27 @@ func (cfg *Config) GetEC2APIResponse(action, filtersQueryString, nextPageToken s
 	return readResponseBody(resp, apiURL)
 }
 
+// GetJSONAPIResponse performs POST request with the given body to AWS JSON API at apiURL for the given target.
+//
+// target must contain the value for X-Amz-Target header, e.g. Lightsail_20161128.GetInstances.
+// See https://docs.aws.amazon.com/lightsail/2016-11-28/api-reference/CommonParameters.html
+func (cfg *Config) GetJSONAPIResponse(apiURL, target string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create http request for %q: %w", apiURL, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	if err := cfg.SignRequest(req, HashHex(body)); err != nil {
+		return nil, fmt.Errorf("cannot sign request: %w", err)
+	}
+	resp, err := cfg.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot perform http request to %q: %w", apiURL, err)
+	}
+	return readResponseBody(resp, apiURL)
+}
+
 // SignRequest signs request for service access and payloadHash.
 func (cfg *Config) SignRequest(req *http.Request, payloadHash string) error {
 	ac, err := cfg.getFreshAPICredentials()
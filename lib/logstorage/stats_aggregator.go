@@ -0,0 +1,235 @@
+package logstorage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+)
+
+// statsAggregatorMaxBlockRows is the maximum number of rows to buffer before passing them to the stats pipe processor.
+const statsAggregatorMaxBlockRows = 1024
+
+// StatsAggregator calculates `stats` pipe results over log rows added via Add().
+//
+// It is used for aggregating logs before storing them, so the calculated stats can be sent elsewhere as metrics.
+//
+// It is safe calling StatsAggregator methods from concurrent goroutines.
+type StatsAggregator struct {
+	ps *pipeStats
+
+	// shardIdx is used for selecting the shard for the next Add call.
+	shardIdx atomic.Uint32
+
+	// shards reduce lock contention when adding rows on multi-CPU systems.
+	//
+	// Every shard passes rows to pp with its own worker id, so shards don't block each other.
+	shards []statsAggregatorShard
+
+	// pp is the stats pipe processor for the current aggregation interval.
+	//
+	// It is updated on Flush() call while all the shards are locked.
+	pp pipeProcessor
+
+	// ppResult collects the results from pp on Flush() call.
+	ppResult *statsAggregatorResult
+}
+
+type statsAggregatorShard struct {
+	statsAggregatorShardNopad
+
+	// The padding prevents false sharing on widespread platforms with 128 mod (cache line size) = 0 .
+	_ [128 - unsafe.Sizeof(statsAggregatorShardNopad{})%128]byte
+}
+
+type statsAggregatorShardNopad struct {
+	mu sync.Mutex
+
+	// rcs contains buffered rows, which aren't passed to pp yet.
+	rcs       []resultColumn
+	rowsCount int
+	br        blockResult
+
+	// a holds values for rcs.
+	a arena
+}
+
+// ParseStatsAggregator parses `stats by (...) ...` pipe from s and returns StatsAggregator for it.
+//
+// The leading `stats` keyword may be omitted.
+func ParseStatsAggregator(s string) (*StatsAggregator, error) {
+	lex := newLexer(s)
+	needStatsKeyword := lex.isKeyword("stats")
+	ps, err := parsePipeStats(lex, needStatsKeyword)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse stats pipe [%s]: %w", s, err)
+	}
+	if !lex.isEnd() {
+		return nil, fmt.Errorf("unexpected unparsed tail after [%s]; tail: [%s]", ps, lex.s)
+	}
+
+	byFields := getStatsByFieldNames(ps)
+	for i := range ps.funcs {
+		f := &ps.funcs[i]
+		if slices.Contains(byFields, f.resultName) {
+			return nil, fmt.Errorf("the %q field cannot be overridden at [%s]", f.resultName, ps)
+		}
+	}
+
+	sa := &StatsAggregator{
+		ps:     ps,
+		shards: make([]statsAggregatorShard, cgroup.AvailableCPUs()),
+	}
+	sa.initPipeProcessorLocked()
+	return sa, nil
+}
+
+func (sa *StatsAggregator) initPipeProcessorLocked() {
+	stopCh := make(chan struct{})
+	cancel := func() {}
+	sa.ppResult = &statsAggregatorResult{}
+	sa.pp = sa.ps.newPipeProcessor(len(sa.shards), stopCh, cancel, sa.ppResult)
+}
+
+// String returns string representation for sa.
+func (sa *StatsAggregator) String() string {
+	return sa.ps.String()
+}
+
+// ByFields returns `by (...)` field names for sa.
+func (sa *StatsAggregator) ByFields() []string {
+	return getStatsByFieldNames(sa.ps)
+}
+
+func getStatsByFieldNames(ps *pipeStats) []string {
+	byFields := make([]string, len(ps.byFields))
+	for i, f := range ps.byFields {
+		byFields[i] = f.name
+	}
+	return byFields
+}
+
+// ResultFields returns the names of result fields calculated by stats functions at sa.
+func (sa *StatsAggregator) ResultFields() []string {
+	resultFields := make([]string, len(sa.ps.funcs))
+	for i := range sa.ps.funcs {
+		resultFields[i] = sa.ps.funcs[i].resultName
+	}
+	return resultFields
+}
+
+// Add adds a log row with the given fields to sa.
+//
+// sa doesn't hold references to fields after returning from Add, so the caller can re-use them.
+func (sa *StatsAggregator) Add(fields []Field) {
+	n := sa.shardIdx.Add(1)
+	workerID := uint(n % uint32(len(sa.shards)))
+	shard := &sa.shards[workerID]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if !shard.areSameFieldsLocked(fields) {
+		shard.flushBlockLocked(sa.pp, workerID)
+
+		shard.rcs = shard.rcs[:0]
+		for _, f := range fields {
+			shard.rcs = appendResultColumnWithName(shard.rcs, strings.Clone(f.Name))
+		}
+	}
+	for i, f := range fields {
+		shard.rcs[i].addValue(shard.a.copyString(f.Value))
+	}
+	shard.rowsCount++
+	if shard.rowsCount >= statsAggregatorMaxBlockRows {
+		shard.flushBlockLocked(sa.pp, workerID)
+	}
+}
+
+func (shard *statsAggregatorShard) areSameFieldsLocked(fields []Field) bool {
+	if len(shard.rcs) != len(fields) {
+		return false
+	}
+	for i := range shard.rcs {
+		if shard.rcs[i].name != fields[i].Name {
+			return false
+		}
+	}
+	return true
+}
+
+// flushBlockLocked passes the buffered rows to pp under the given workerID.
+func (shard *statsAggregatorShard) flushBlockLocked(pp pipeProcessor, workerID uint) {
+	if shard.rowsCount == 0 {
+		return
+	}
+	shard.br.setResultColumns(shard.rcs, shard.rowsCount)
+	pp.writeBlock(workerID, &shard.br)
+	shard.br.reset()
+	for i := range shard.rcs {
+		shard.rcs[i].resetValues()
+	}
+	shard.rowsCount = 0
+	shard.a.reset()
+}
+
+// Flush returns stats rows calculated over the added rows since the previous Flush call.
+//
+// The state of sa is reset after the call, so the next Flush returns stats for newly added rows.
+func (sa *StatsAggregator) Flush() ([][]Field, error) {
+	for i := range sa.shards {
+		sa.shards[i].mu.Lock()
+	}
+	for i := range sa.shards {
+		sa.shards[i].flushBlockLocked(sa.pp, uint(i))
+	}
+	pp := sa.pp
+	ppResult := sa.ppResult
+	sa.initPipeProcessorLocked()
+	for i := range sa.shards {
+		sa.shards[i].mu.Unlock()
+	}
+
+	if err := pp.flush(); err != nil {
+		return nil, err
+	}
+	return ppResult.rows, nil
+}
+
+// statsAggregatorResult collects the results of stats pipe processor.
+type statsAggregatorResult struct {
+	// mu protects rows, since the stats pipe processor writes results from concurrent workers.
+	mu   sync.Mutex
+	rows [][]Field
+}
+
+func (sar *statsAggregatorResult) writeBlock(_ uint, br *blockResult) {
+	cs := br.getColumns()
+	columnValues := make([][]string, len(cs))
+	for i, c := range cs {
+		columnValues[i] = c.getValues(br)
+	}
+	rows := make([][]Field, br.rowsLen)
+	for rowIdx := range rows {
+		row := make([]Field, len(cs))
+		for i, values := range columnValues {
+			row[i] = Field{
+				Name:  strings.Clone(cs[i].name),
+				Value: strings.Clone(values[rowIdx]),
+			}
+		}
+		rows[rowIdx] = row
+	}
+
+	sar.mu.Lock()
+	sar.rows = append(sar.rows, rows...)
+	sar.mu.Unlock()
+}
+
+func (sar *statsAggregatorResult) flush() error {
+	return nil
+}
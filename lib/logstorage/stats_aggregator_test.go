@@ -0,0 +1,159 @@
+package logstorage
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestParseStatsAggregatorFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		sa, err := ParseStatsAggregator(s)
+		if err == nil {
+			t.Fatalf("expecting non-nil error when parsing [%s]; got %s", s, sa)
+		}
+	}
+
+	f(``)
+	f(`foo`)
+	f(`stats`)
+	f(`stats count() | limit 10`)
+	f(`stats by (x) count() x`)
+	f(`by (x) count() x`)
+}
+
+func TestParseStatsAggregatorSuccess(t *testing.T) {
+	f := func(s, resultExpected string) {
+		t.Helper()
+
+		sa, err := ParseStatsAggregator(s)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing [%s]: %s", s, err)
+		}
+		result := sa.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result; got\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	f(`count()`, `stats count(*) as "count(*)"`)
+	f(`stats count() x`, `stats count(*) as x`)
+	f(`by (status) count() requests, sum(bytes) bytes`, `stats by (status) count(*) as requests, sum(bytes) as bytes`)
+	f(`stats by (status, path) count() if (error) errors`, `stats by (status, path) count(*) if (error) as errors`)
+}
+
+func TestStatsAggregatorFlush(t *testing.T) {
+	sa, err := ParseStatsAggregator(`stats by (status) count() requests, sum(bytes) bytes, count() if (error) errors`)
+	if err != nil {
+		t.Fatalf("cannot parse stats aggregator: %s", err)
+	}
+
+	byFieldsExpected := []string{"status"}
+	if byFields := sa.ByFields(); !equalStrings(byFields, byFieldsExpected) {
+		t.Fatalf("unexpected by fields; got %q; want %q", byFields, byFieldsExpected)
+	}
+	resultFieldsExpected := []string{"requests", "bytes", "errors"}
+	if resultFields := sa.ResultFields(); !equalStrings(resultFields, resultFieldsExpected) {
+		t.Fatalf("unexpected result fields; got %q; want %q", resultFields, resultFieldsExpected)
+	}
+
+	fields := []Field{
+		{Name: "_msg", Value: "GET /foo"},
+		{Name: "status", Value: "200"},
+		{Name: "bytes", Value: "10"},
+	}
+	sa.Add(fields)
+	// The caller may re-use fields after Add call.
+	fields[2].Value = "20"
+	sa.Add(fields)
+	sa.Add([]Field{
+		{Name: "_msg", Value: "internal error"},
+		{Name: "status", Value: "500"},
+	})
+
+	rows, err := sa.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertRowsEqual(t, rows, [][]Field{
+		{
+			{Name: "status", Value: "200"},
+			{Name: "requests", Value: "2"},
+			{Name: "bytes", Value: "30"},
+			{Name: "errors", Value: "0"},
+		},
+		{
+			{Name: "status", Value: "500"},
+			{Name: "requests", Value: "1"},
+			{Name: "bytes", Value: "NaN"},
+			{Name: "errors", Value: "1"},
+		},
+	})
+
+	// The state must be reset after Flush
+	rows, err = sa.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rows) != 0 {
+		t.Fatalf("unexpected rows after the second flush: %s", rowsToString(rows))
+	}
+}
+
+func TestStatsAggregatorConcurrentAdd(t *testing.T) {
+	sa, err := ParseStatsAggregator(`stats by (status) count() requests, sum(bytes) bytes`)
+	if err != nil {
+		t.Fatalf("cannot parse stats aggregator: %s", err)
+	}
+
+	const workers = 8
+	const rowsPerWorker = 3000
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rowsPerWorker; j++ {
+				status := "200"
+				if j%3 == 0 {
+					status = "500"
+				}
+				sa.Add([]Field{
+					{Name: "status", Value: status},
+					{Name: "bytes", Value: "2"},
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	rows, err := sa.Flush()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertRowsEqual(t, rows, [][]Field{
+		{
+			{Name: "status", Value: "200"},
+			{Name: "requests", Value: "16000"},
+			{Name: "bytes", Value: "32000"},
+		},
+		{
+			{Name: "status", Value: "500"},
+			{Name: "requests", Value: "8000"},
+			{Name: "bytes", Value: "16000"},
+		},
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
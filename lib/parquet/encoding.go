@@ -0,0 +1,324 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+)
+
+// appendHybridLevels appends levels encoded with RLE / bit-packing hybrid encoding with the given bitWidth to dst.
+//
+// See https://parquet.apache.org/docs/file-format/data-pages/encodings/#run-length-encoding--bit-packing-hybrid-rle--3
+func appendHybridLevels(dst, levels []byte, bitWidth int) []byte {
+	if len(levels) == 0 {
+		return dst
+	}
+	if isConstLevels(levels) {
+		// Encode all the levels with a single RLE run.
+		dst = encoding.MarshalVarUint64(dst, uint64(len(levels))<<1)
+		return appendRLEValue(dst, uint32(levels[0]), bitWidth)
+	}
+
+	// Encode all the levels with a single bit-packed run.
+	groups := (len(levels) + 7) / 8
+	dst = encoding.MarshalVarUint64(dst, uint64(groups)<<1|1)
+	var acc uint64
+	accBits := 0
+	for i := 0; i < groups*8; i++ {
+		var v byte
+		if i < len(levels) {
+			v = levels[i]
+		}
+		acc |= uint64(v) << accBits
+		accBits += bitWidth
+		for accBits >= 8 {
+			dst = append(dst, byte(acc))
+			acc >>= 8
+			accBits -= 8
+		}
+	}
+	return dst
+}
+
+func isConstLevels(levels []byte) bool {
+	for _, v := range levels[1:] {
+		if v != levels[0] {
+			return false
+		}
+	}
+	return true
+}
+
+func appendRLEValue(dst []byte, v uint32, bitWidth int) []byte {
+	for i := 0; i < (bitWidth+7)/8; i++ {
+		dst = append(dst, byte(v))
+		v >>= 8
+	}
+	return dst
+}
+
+// decodeHybrid decodes n values encoded with RLE / bit-packing hybrid encoding with the given bitWidth from src and appends them to dst.
+//
+// It returns the tail left after decoding.
+func decodeHybrid(dst []uint32, src []byte, bitWidth, n int) ([]uint32, []byte, error) {
+	if bitWidth < 0 || bitWidth > 32 {
+		return dst, src, fmt.Errorf("unsupported bit width=%d; it must be in the range [0..32]", bitWidth)
+	}
+	if bitWidth == 0 {
+		for i := 0; i < n; i++ {
+			dst = append(dst, 0)
+		}
+		return dst, src, nil
+	}
+	for n > 0 {
+		header, nSize := encoding.UnmarshalVarUint64(src)
+		if nSize <= 0 {
+			return dst, src, fmt.Errorf("cannot unmarshal run header")
+		}
+		src = src[nSize:]
+		if header&1 == 0 {
+			// RLE run
+			count := header >> 1
+			valueSize := (bitWidth + 7) / 8
+			if len(src) < valueSize {
+				return dst, src, fmt.Errorf("missing RLE value; got %d bytes; want %d bytes", len(src), valueSize)
+			}
+			var v uint32
+			for i := 0; i < valueSize; i++ {
+				v |= uint32(src[i]) << (8 * i)
+			}
+			src = src[valueSize:]
+			if count > uint64(n) {
+				count = uint64(n)
+			}
+			for i := uint64(0); i < count; i++ {
+				dst = append(dst, v)
+			}
+			n -= int(count)
+			continue
+		}
+
+		// Bit-packed run
+		groups := header >> 1
+		runBytes := groups * uint64(bitWidth)
+		if runBytes > uint64(len(src)) {
+			return dst, src, fmt.Errorf("too short bit-packed run; got %d bytes; want %d bytes", len(src), runBytes)
+		}
+		count := groups * 8
+		if count > uint64(n) {
+			count = uint64(n)
+		}
+		mask := uint64(1)<<bitWidth - 1
+		var acc uint64
+		accBits := 0
+		pos := 0
+		for i := uint64(0); i < count; i++ {
+			for accBits < bitWidth {
+				acc |= uint64(src[pos]) << accBits
+				pos++
+				accBits += 8
+			}
+			dst = append(dst, uint32(acc&mask))
+			acc >>= bitWidth
+			accBits -= bitWidth
+		}
+		src = src[runBytes:]
+		n -= int(count)
+	}
+	return dst, src, nil
+}
+
+func appendByteArray(dst []byte, s string) []byte {
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(len(s)))
+	return append(dst, s...)
+}
+
+// decodeByteArrays decodes n PLAIN-encoded byte arrays from src and appends them to dst.
+func decodeByteArrays(dst [][]byte, src []byte, n int) ([][]byte, []byte, error) {
+	for i := 0; i < n; i++ {
+		if len(src) < 4 {
+			return dst, src, fmt.Errorf("missing length for byte array #%d", i)
+		}
+		size := binary.LittleEndian.Uint32(src)
+		src = src[4:]
+		if uint64(size) > uint64(len(src)) {
+			return dst, src, fmt.Errorf("too big length for byte array #%d; got %d bytes; mustn't exceed %d bytes", i, size, len(src))
+		}
+		dst = append(dst, src[:size])
+		src = src[size:]
+	}
+	return dst, src, nil
+}
+
+// decodeInt64s decodes n PLAIN-encoded int64 values from src and appends them to dst.
+func decodeInt64s(dst []int64, src []byte, n int) ([]int64, []byte, error) {
+	if len(src) < 8*n {
+		return dst, src, fmt.Errorf("too short data for %d int64 values; got %d bytes; want %d bytes", n, len(src), 8*n)
+	}
+	for i := 0; i < n; i++ {
+		dst = append(dst, int64(binary.LittleEndian.Uint64(src[8*i:])))
+	}
+	return dst, src[8*n:], nil
+}
+
+// decodeFloat64s decodes n PLAIN-encoded double values from src and appends them to dst.
+func decodeFloat64s(dst []float64, src []byte, n int) ([]float64, []byte, error) {
+	if len(src) < 8*n {
+		return dst, src, fmt.Errorf("too short data for %d double values; got %d bytes; want %d bytes", n, len(src), 8*n)
+	}
+	for i := 0; i < n; i++ {
+		dst = append(dst, float64FromLE(src[8*i:]))
+	}
+	return dst, src[8*n:], nil
+}
+
+func float64FromLE(src []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(src))
+}
+
+// decodeDeltaBinaryPacked decodes n DELTA_BINARY_PACKED values from src and appends them to dst.
+//
+// It returns the tail left after decoding.
+//
+// See https://parquet.apache.org/docs/file-format/data-pages/encodings/#delta-encoding-delta_binary_packed--5
+func decodeDeltaBinaryPacked(dst []int64, src []byte, n int) ([]int64, []byte, error) {
+	blockSize, nSize := encoding.UnmarshalVarUint64(src)
+	if nSize <= 0 {
+		return dst, src, fmt.Errorf("cannot unmarshal block size")
+	}
+	src = src[nSize:]
+	miniblocksCount, nSize := encoding.UnmarshalVarUint64(src)
+	if nSize <= 0 {
+		return dst, src, fmt.Errorf("cannot unmarshal miniblocks count")
+	}
+	src = src[nSize:]
+	totalCount, nSize := encoding.UnmarshalVarUint64(src)
+	if nSize <= 0 {
+		return dst, src, fmt.Errorf("cannot unmarshal total values count")
+	}
+	src = src[nSize:]
+	firstValue, nSize := encoding.UnmarshalVarInt64(src)
+	if nSize <= 0 {
+		return dst, src, fmt.Errorf("cannot unmarshal first value")
+	}
+	src = src[nSize:]
+
+	if blockSize == 0 || blockSize%128 != 0 || blockSize > 1<<20 {
+		return dst, src, fmt.Errorf("invalid block size=%d; it must be a multiple of 128", blockSize)
+	}
+	if miniblocksCount == 0 || blockSize%miniblocksCount != 0 || (blockSize/miniblocksCount)%32 != 0 {
+		return dst, src, fmt.Errorf("invalid miniblocks count=%d for block size=%d", miniblocksCount, blockSize)
+	}
+	if totalCount != uint64(n) {
+		return dst, src, fmt.Errorf("unexpected values count=%d; want %d", totalCount, n)
+	}
+	if n == 0 {
+		return dst, src, nil
+	}
+	valuesPerMiniblock := blockSize / miniblocksCount
+
+	v := firstValue
+	dst = append(dst, v)
+	n--
+	for n > 0 {
+		minDelta, nSize := encoding.UnmarshalVarInt64(src)
+		if nSize <= 0 {
+			return dst, src, fmt.Errorf("cannot unmarshal min delta")
+		}
+		src = src[nSize:]
+		if uint64(len(src)) < miniblocksCount {
+			return dst, src, fmt.Errorf("missing bit widths for miniblocks")
+		}
+		bitWidths := src[:miniblocksCount]
+		src = src[miniblocksCount:]
+		for _, bitWidth := range bitWidths {
+			if n == 0 {
+				break
+			}
+			if bitWidth > 64 {
+				return dst, src, fmt.Errorf("invalid bit width=%d for miniblock; it mustn't exceed 64", bitWidth)
+			}
+			miniblockSize := valuesPerMiniblock * uint64(bitWidth) / 8
+			if miniblockSize > uint64(len(src)) {
+				return dst, src, fmt.Errorf("too short miniblock; got %d bytes; want %d bytes", len(src), miniblockSize)
+			}
+			count := valuesPerMiniblock
+			if count > uint64(n) {
+				count = uint64(n)
+			}
+			for i := uint64(0); i < count; i++ {
+				delta := unpackBits(src, i*uint64(bitWidth), int(bitWidth))
+				// Overflows are expected according to the spec.
+				v = int64(uint64(v) + uint64(minDelta) + delta)
+				dst = append(dst, v)
+			}
+			src = src[miniblockSize:]
+			n -= int(count)
+		}
+	}
+	return dst, src, nil
+}
+
+// unpackBits returns the value with the given bitWidth starting at the given bitPos in src, where bits are packed from LSB to MSB.
+func unpackBits(src []byte, bitPos uint64, bitWidth int) uint64 {
+	var v uint64
+	for i := 0; i < bitWidth; {
+		bitOffset := int(bitPos & 7)
+		bitsCount := min(8-bitOffset, bitWidth-i)
+		b := uint64(src[bitPos>>3]>>bitOffset) & (1<<bitsCount - 1)
+		v |= b << i
+		i += bitsCount
+		bitPos += uint64(bitsCount)
+	}
+	return v
+}
+
+// decodeDeltaLengthByteArrays decodes n DELTA_LENGTH_BYTE_ARRAY values from src and appends them to dst.
+//
+// It returns the tail left after decoding.
+//
+// See https://parquet.apache.org/docs/file-format/data-pages/encodings/#delta-length-byte-array-delta_length_byte_array--6
+func decodeDeltaLengthByteArrays(dst [][]byte, src []byte, n int) ([][]byte, []byte, error) {
+	lengths, src, err := decodeDeltaBinaryPacked(nil, src, n)
+	if err != nil {
+		return dst, src, fmt.Errorf("cannot decode lengths: %w", err)
+	}
+	for i, size := range lengths {
+		if size < 0 || uint64(size) > uint64(len(src)) {
+			return dst, src, fmt.Errorf("invalid length for byte array #%d; got %d bytes; mustn't exceed %d bytes", i, size, len(src))
+		}
+		dst = append(dst, src[:size])
+		src = src[size:]
+	}
+	return dst, src, nil
+}
+
+// decodeDeltaByteArrays decodes n DELTA_BYTE_ARRAY values from src and appends them to dst.
+//
+// It returns the tail left after decoding.
+//
+// See https://parquet.apache.org/docs/file-format/data-pages/encodings/#delta-strings-delta_byte_array--7
+func decodeDeltaByteArrays(dst [][]byte, src []byte, n int) ([][]byte, []byte, error) {
+	prefixLengths, src, err := decodeDeltaBinaryPacked(nil, src, n)
+	if err != nil {
+		return dst, src, fmt.Errorf("cannot decode prefix lengths: %w", err)
+	}
+	suffixes, src, err := decodeDeltaLengthByteArrays(nil, src, n)
+	if err != nil {
+		return dst, src, fmt.Errorf("cannot decode suffixes: %w", err)
+	}
+	var prev []byte
+	for i, prefixLen := range prefixLengths {
+		if prefixLen < 0 || prefixLen > int64(len(prev)) {
+			return dst, src, fmt.Errorf("invalid prefix length for byte array #%d; got %d bytes; mustn't exceed %d bytes", i, prefixLen, len(prev))
+		}
+		b := make([]byte, 0, int(prefixLen)+len(suffixes[i]))
+		b = append(b, prev[:prefixLen]...)
+		b = append(b, suffixes[i]...)
+		dst = append(dst, b)
+		prev = b
+	}
+	return dst, src, nil
+}
@@ -0,0 +1,133 @@
+package parquet
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// interopSeries contains the series stored in testdata/*.parquet files.
+//
+// testdata/parquet-go-*.parquet files are generated by github.com/parquet-go/parquet-go via `go run . gen ..`
+// from testdata/interop directory. See testdata/interop/main.go for details.
+var interopSeries = []Series{
+	{
+		Labels: []prompbmarshal.Label{
+			{Name: "__name__", Value: "foo"},
+			{Name: "job", Value: "x"},
+		},
+		Timestamps: []int64{1000, 2000},
+		Values:     []float64{1, 2.5},
+	},
+	{
+		Labels: []prompbmarshal.Label{
+			{Name: "__name__", Value: "bar"},
+		},
+		Timestamps: []int64{3000},
+		Values:     []float64{-3},
+	},
+}
+
+func TestReaderInterop(t *testing.T) {
+	f := func(path string, ssExpected []Series) {
+		t.Helper()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("cannot read %q: %s", path, err)
+		}
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("cannot open reader for %q: %s", path, err)
+		}
+		var ssResult []Series
+		for i := 0; i < r.RowGroupsCount(); i++ {
+			err := r.ReadRowGroup(i, func(s *Series) error {
+				// Merge samples for the series split among row groups.
+				if n := len(ssResult); n > 0 && labelsEqual(ssResult[n-1].Labels, s.Labels) {
+					ssResult[n-1].Timestamps = append(ssResult[n-1].Timestamps, s.Timestamps...)
+					ssResult[n-1].Values = append(ssResult[n-1].Values, s.Values...)
+					return nil
+				}
+				ssResult = append(ssResult, cloneSeries(s))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("cannot read row group #%d from %q: %s", i, path, err)
+			}
+		}
+		if !reflect.DeepEqual(ssResult, ssExpected) {
+			t.Fatalf("unexpected series read from %q\ngot\n%v\nwant\n%v", path, ssResult, ssExpected)
+		}
+	}
+
+	// files written by github.com/parquet-go/parquet-go
+	f("testdata/parquet-go-zstd-v2.parquet", interopSeries)
+	f("testdata/parquet-go-snappy-v1.parquet", interopSeries)
+	f("testdata/parquet-go-gzip-dict.parquet", interopSeries)
+	f("testdata/parquet-go-uncompressed.parquet", interopSeries)
+	f("testdata/parquet-go-micros.parquet", interopSeries)
+	f("testdata/parquet-go-delta.parquet", interopSeries)
+	f("testdata/parquet-go-delta-many.parquet", getInteropManySeries())
+
+	// file written by Writer and verified by github.com/parquet-go/parquet-go
+	f("testdata/vm.parquet", interopSeries)
+}
+
+// getInteropManySeries returns the series stored in testdata/parquet-go-delta-many.parquet.
+//
+// It must be in sync with manyRow at testdata/interop/main.go.
+func getInteropManySeries() []Series {
+	var ss []Series
+	for i := 0; i < 1000; i++ {
+		if i%10 == 0 {
+			ss = append(ss, Series{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "many"},
+					{Name: "idx", Value: fmt.Sprintf("%04d", i/10)},
+				},
+			})
+		}
+		s := &ss[len(ss)-1]
+		s.Timestamps = append(s.Timestamps, 1e12+int64(i)*15000-int64(i%7)*3)
+		s.Values = append(s.Values, float64(i)/4)
+	}
+	return ss
+}
+
+// TestWriterInterop verifies that Writer generates testdata/vm.parquet byte-by-byte.
+//
+// testdata/vm.parquet is verified by github.com/parquet-go/parquet-go via `go run . verify ../vm.parquet`
+// from testdata/interop directory. If Writer output changes, then testdata/vm.parquet must be updated
+// via `UPDATE_TESTDATA=1 go test -run=TestWriterInterop` and verified again.
+func TestWriterInterop(t *testing.T) {
+	var bb bytes.Buffer
+	w := NewWriter(&bb)
+	var rg RowGroup
+	for _, s := range interopSeries {
+		rg.AddSeries(s.Labels, s.Timestamps, s.Values)
+	}
+	if err := w.WriteRowGroup(&rg); err != nil {
+		t.Fatalf("cannot write row group: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("cannot close writer: %s", err)
+	}
+
+	if os.Getenv("UPDATE_TESTDATA") == "1" {
+		if err := os.WriteFile("testdata/vm.parquet", bb.Bytes(), 0o644); err != nil {
+			t.Fatalf("cannot update testdata/vm.parquet: %s", err)
+		}
+	}
+	dataExpected, err := os.ReadFile("testdata/vm.parquet")
+	if err != nil {
+		t.Fatalf("cannot read testdata/vm.parquet: %s", err)
+	}
+	if !bytes.Equal(bb.Bytes(), dataExpected) {
+		t.Fatalf("Writer output doesn't match testdata/vm.parquet; update it via UPDATE_TESTDATA=1 and verify it with testdata/interop")
+	}
+}
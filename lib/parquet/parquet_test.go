@@ -0,0 +1,320 @@
+package parquet
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestHybridLevelsMarshalUnmarshal(t *testing.T) {
+	f := func(levels []byte) {
+		t.Helper()
+		data := appendHybridLevels(nil, levels, 1)
+		result, tail, err := decodeHybrid(nil, data, 1, len(levels))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(tail) != 0 {
+			t.Fatalf("unexpected non-empty tail: %X", tail)
+		}
+		for i, v := range result {
+			if byte(v) != levels[i] {
+				t.Fatalf("unexpected level at position %d; got %d; want %d", i, v, levels[i])
+			}
+		}
+		if len(result) != len(levels) {
+			t.Fatalf("unexpected number of levels; got %d; want %d", len(result), len(levels))
+		}
+	}
+
+	f(nil)
+	f([]byte{0})
+	f([]byte{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1})
+	f([]byte{0, 1, 1, 0, 1, 1, 0, 1, 1})
+	f([]byte{0, 1, 1, 1, 1, 1, 1, 1, 0, 1, 1, 1, 1, 1, 1, 1, 0})
+}
+
+func TestDecodeHybrid(t *testing.T) {
+	f := func(data []byte, bitWidth, n int, resultExpected []uint32) {
+		t.Helper()
+		result, _, err := decodeHybrid(nil, data, bitWidth, n)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result; got %v; want %v", result, resultExpected)
+		}
+	}
+
+	// The example from https://parquet.apache.org/docs/file-format/data-pages/encodings/
+	f([]byte{0x03, 0x88, 0xc6, 0xfa}, 3, 8, []uint32{0, 1, 2, 3, 4, 5, 6, 7})
+
+	// RLE run followed by bit-packed run
+	f([]byte{0x06, 0x05, 0x03, 0x88, 0xc6, 0xfa}, 3, 11, []uint32{5, 5, 5, 0, 1, 2, 3, 4, 5, 6, 7})
+
+	// RLE run with multi-byte value
+	f([]byte{0x04, 0x34, 0x12}, 13, 2, []uint32{0x1234, 0x1234})
+
+	// zero bit width
+	f(nil, 0, 3, []uint32{0, 0, 0})
+}
+
+func TestThriftMarshalUnmarshal(t *testing.T) {
+	var tw thriftWriter
+	tw.structBegin()
+	tw.i32Field(1, -123)
+	tw.boolField(2, true)
+	tw.stringField(3, "foo")
+	tw.structFieldBegin(5)
+	tw.i64Field(1, 1<<40)
+	tw.boolField(20, false)
+	tw.structEnd()
+	tw.listFieldBegin(6, thriftI32, 20)
+	for i := 0; i < 20; i++ {
+		tw.i32(int32(i))
+	}
+	tw.listFieldBegin(100, thriftStruct, 1)
+	tw.structBegin()
+	tw.stringField(1, "bar")
+	tw.structEnd()
+	tw.structEnd()
+
+	tf, tail, err := unmarshalThriftStruct(tw.b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(tail) != 0 {
+		t.Fatalf("unexpected non-empty tail: %X", tail)
+	}
+	if n, _ := tf.getInt(1); n != -123 {
+		t.Fatalf("unexpected field #1; got %d; want -123", n)
+	}
+	if v, _ := tf.getBool(2); !v {
+		t.Fatalf("unexpected field #2; got false; want true")
+	}
+	if s := tf.getString(3); s != "foo" {
+		t.Fatalf("unexpected field #3; got %q; want %q", s, "foo")
+	}
+	st, ok := tf.getStruct(5)
+	if !ok {
+		t.Fatalf("missing field #5")
+	}
+	if n, _ := st.getInt(1); n != 1<<40 {
+		t.Fatalf("unexpected field #5.1; got %d; want %d", n, int64(1<<40))
+	}
+	if v, ok := st.getBool(20); !ok || v {
+		t.Fatalf("unexpected field #5.20; got %v; want false", v)
+	}
+	a, _ := tf.getList(6)
+	if len(a) != 20 || a[19].(int64) != 19 {
+		t.Fatalf("unexpected field #6: %v", a)
+	}
+	a, _ = tf.getList(100)
+	if len(a) != 1 || a[0].(thriftFields).getString(1) != "bar" {
+		t.Fatalf("unexpected field #100: %v", a)
+	}
+
+	// Truncated data
+	for i := 0; i < len(tw.b); i++ {
+		if _, _, err := unmarshalThriftStruct(tw.b[:i]); err == nil {
+			t.Fatalf("expecting non-nil error for truncated data of size %d", i)
+		}
+	}
+}
+
+func TestWriterReader(t *testing.T) {
+	f := func(rowGroups [][]Series) {
+		t.Helper()
+
+		var bb bytes.Buffer
+		w := NewWriter(&bb)
+		var rg RowGroup
+		for _, ss := range rowGroups {
+			rg.Reset()
+			for _, s := range ss {
+				rg.AddSeries(s.Labels, s.Timestamps, s.Values)
+			}
+			if err := w.WriteRowGroup(&rg); err != nil {
+				t.Fatalf("cannot write row group: %s", err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("cannot close writer: %s", err)
+		}
+
+		data := bb.Bytes()
+		r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("cannot open reader: %s", err)
+		}
+		var ssExpected []Series
+		for _, ss := range rowGroups {
+			if len(ss) > 0 {
+				ssExpected = append(ssExpected, ss...)
+			}
+		}
+		var ssResult []Series
+		for i := 0; i < r.RowGroupsCount(); i++ {
+			err := r.ReadRowGroup(i, func(s *Series) error {
+				ssResult = append(ssResult, cloneSeries(s))
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("cannot read row group #%d: %s", i, err)
+			}
+		}
+		if !reflect.DeepEqual(ssResult, ssExpected) {
+			t.Fatalf("unexpected series read\ngot\n%v\nwant\n%v", ssResult, ssExpected)
+		}
+	}
+
+	// empty file
+	f(nil)
+
+	// single series
+	f([][]Series{
+		{
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "foo"},
+					{Name: "job", Value: "bar"},
+				},
+				Timestamps: []int64{1, 2, 3},
+				Values:     []float64{1.5, math.Inf(1), -3},
+			},
+		},
+	})
+
+	// multiple series and row groups
+	f([][]Series{
+		{
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "foo"},
+				},
+				Timestamps: []int64{1700000000000},
+				Values:     []float64{1},
+			},
+			{
+				Labels:     []prompbmarshal.Label{},
+				Timestamps: []int64{-5, 10},
+				Values:     []float64{2, 3},
+			},
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "bar"},
+					{Name: "empty", Value: ""},
+				},
+				Timestamps: []int64{4},
+				Values:     []float64{4},
+			},
+		},
+		{
+			{
+				Labels: []prompbmarshal.Label{
+					{Name: "__name__", Value: "baz"},
+					{Name: "a", Value: "b"},
+					{Name: "c", Value: "d"},
+				},
+				Timestamps: []int64{7, 8, 9, 10, 11, 12, 13, 14, 15},
+				Values:     []float64{7, 8, 9, 10, 11, 12, 13, 14, 15},
+			},
+		},
+	})
+}
+
+func TestWriterConcurrent(t *testing.T) {
+	var bb bytes.Buffer
+	w := NewWriter(&bb)
+	const workers = 4
+	const seriesPerWorker = 100
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(workerID int) {
+			defer wg.Done()
+			var rg RowGroup
+			for j := 0; j < seriesPerWorker; j++ {
+				labels := []prompbmarshal.Label{
+					{Name: "__name__", Value: fmt.Sprintf("metric_%d_%d", workerID, j)},
+				}
+				rg.AddSeries(labels, []int64{1, 2}, []float64{float64(workerID), float64(j)})
+				if rg.RowsCount() >= 50 {
+					if err := w.WriteRowGroup(&rg); err != nil {
+						panic(fmt.Errorf("cannot write row group: %w", err))
+					}
+					rg.Reset()
+				}
+			}
+			if err := w.WriteRowGroup(&rg); err != nil {
+				panic(fmt.Errorf("cannot write row group: %w", err))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatalf("cannot close writer: %s", err)
+	}
+
+	data := bb.Bytes()
+	r, err := NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("cannot open reader: %s", err)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < r.RowGroupsCount(); i++ {
+		err := r.ReadRowGroup(i, func(s *Series) error {
+			name := s.Labels[0].Value
+			if seen[name] {
+				return fmt.Errorf("duplicate series %q", name)
+			}
+			seen[name] = true
+			if len(s.Timestamps) != 2 {
+				return fmt.Errorf("unexpected number of samples for %q; got %d; want 2", name, len(s.Timestamps))
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("cannot read row group #%d: %s", i, err)
+		}
+	}
+	if len(seen) != workers*seriesPerWorker {
+		t.Fatalf("unexpected number of series; got %d; want %d", len(seen), workers*seriesPerWorker)
+	}
+}
+
+func TestNewReaderFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := NewReader(bytes.NewReader([]byte(data)), int64(len(data))); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f("")
+	f("PAR1")
+	f("PAR1\x00\x00\x00\x00PAR1")
+	f("PAR1foobar\x06\x00\x00\x00PAR1")
+	f("PAR1foobar\xff\x00\x00\x00PAR1")
+	f("PAR1foobar\x06\x00\x00\x00PAR2")
+}
+
+func cloneSeries(s *Series) Series {
+	labels := make([]prompbmarshal.Label, len(s.Labels))
+	for i, label := range s.Labels {
+		labels[i] = prompbmarshal.Label{
+			Name:  string(append([]byte{}, label.Name...)),
+			Value: string(append([]byte{}, label.Value...)),
+		}
+	}
+	return Series{
+		Labels:     labels,
+		Timestamps: append([]int64{}, s.Timestamps...),
+		Values:     append([]float64{}, s.Values...),
+	}
+}
@@ -0,0 +1,666 @@
+package parquet
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"github.com/golang/snappy"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// maxFooterSize is the maximum size of Parquet footer, which can be read by Reader.
+const maxFooterSize = 256 * 1024 * 1024
+
+// Reader reads samples from Parquet files written by Writer.
+//
+// It also supports reading Parquet files with the same schema written by other tools
+// with PLAIN, dictionary and DELTA_* encodings, data pages v1 and v2, and SNAPPY, GZIP or ZSTD compression.
+type Reader struct {
+	r io.ReaderAt
+
+	rowGroups []thriftFields
+
+	// columns contains leaf columns for labels keys, labels values, timestamps and values.
+	columns [columnsCount]leafColumn
+
+	// timestampDivisor is used for converting timestamps to milliseconds.
+	timestampDivisor int64
+}
+
+type leafColumn struct {
+	idx         int
+	path        []string
+	typ         int64
+	maxRepLevel int
+	maxDefLevel int
+}
+
+// Series contains samples for a single series.
+type Series struct {
+	Labels     []prompbmarshal.Label
+	Timestamps []int64
+	Values     []float64
+}
+
+// NewReader returns new Reader for Parquet file with the given size, which is read from r.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	if size < int64(2*len(magic)+4) {
+		return nil, fmt.Errorf("too small file size=%d for parquet file", size)
+	}
+	tail := make([]byte, len(magic)+4)
+	if _, err := r.ReadAt(tail, size-int64(len(tail))); err != nil {
+		return nil, fmt.Errorf("cannot read parquet footer: %w", err)
+	}
+	if string(tail[4:]) != magic {
+		return nil, fmt.Errorf("missing %q magic at the end of parquet file", magic)
+	}
+	footerSize := int64(binary.LittleEndian.Uint32(tail))
+	if footerSize > maxFooterSize || footerSize > size-int64(2*len(magic)+4) {
+		return nil, fmt.Errorf("invalid parquet footer size=%d for file size=%d", footerSize, size)
+	}
+	footer := make([]byte, footerSize)
+	if _, err := r.ReadAt(footer, size-int64(len(tail))-footerSize); err != nil {
+		return nil, fmt.Errorf("cannot read parquet footer: %w", err)
+	}
+	fmd, _, err := unmarshalThriftStruct(footer)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal parquet file metadata: %w", err)
+	}
+
+	pr := &Reader{
+		r:                r,
+		timestampDivisor: 1,
+	}
+	if err := pr.initColumns(fmd); err != nil {
+		return nil, err
+	}
+	rowGroups, _ := fmd.getList(4)
+	for i, v := range rowGroups {
+		rg, ok := v.(thriftFields)
+		if !ok {
+			return nil, fmt.Errorf("unexpected type for row group #%d: %T", i, v)
+		}
+		pr.rowGroups = append(pr.rowGroups, rg)
+	}
+	return pr, nil
+}
+
+// RowGroupsCount returns the number of row groups in the file.
+func (pr *Reader) RowGroupsCount() int {
+	return len(pr.rowGroups)
+}
+
+func (pr *Reader) initColumns(fmd thriftFields) error {
+	schema, ok := fmd.getList(2)
+	if !ok || len(schema) == 0 {
+		return fmt.Errorf("missing parquet schema")
+	}
+	var leaves []leafColumn
+	var tsElement thriftFields
+	var walk func(path []string, repLevel, defLevel int) error
+	idx := 0
+	walk = func(path []string, repLevel, defLevel int) error {
+		if idx >= len(schema) {
+			return fmt.Errorf("unexpected end of parquet schema")
+		}
+		se, ok := schema[idx].(thriftFields)
+		if !ok {
+			return fmt.Errorf("unexpected type for schema element #%d: %T", idx, schema[idx])
+		}
+		idx++
+		if len(path) > 0 || idx > 1 {
+			path = append(path[:len(path):len(path)], se.getString(4))
+			switch repetition, _ := se.getInt(3); repetition {
+			case repetitionOptional:
+				defLevel++
+			case repetitionRepeated:
+				repLevel++
+				defLevel++
+			}
+		} else {
+			// Skip the root element
+			path = path[:0]
+		}
+		numChildren, _ := se.getInt(5)
+		if numChildren == 0 && len(path) > 0 {
+			typ, _ := se.getInt(1)
+			leaves = append(leaves, leafColumn{
+				idx:         len(leaves),
+				path:        path,
+				typ:         typ,
+				maxRepLevel: repLevel,
+				maxDefLevel: defLevel,
+			})
+			if len(path) == 1 && path[0] == TimestampColumn {
+				tsElement = se
+			}
+			return nil
+		}
+		for i := int64(0); i < numChildren; i++ {
+			if err := walk(path, repLevel, defLevel); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(nil, 0, 0); err != nil {
+		return err
+	}
+
+	found := [columnsCount]bool{}
+	for _, lc := range leaves {
+		i := -1
+		switch {
+		case len(lc.path) == 3 && lc.path[0] == LabelsColumn && lc.path[2] == "key":
+			i = 0
+		case len(lc.path) == 3 && lc.path[0] == LabelsColumn && lc.path[2] == "value":
+			i = 1
+		case len(lc.path) == 1 && lc.path[0] == TimestampColumn:
+			i = 2
+		case len(lc.path) == 1 && lc.path[0] == ValueColumn:
+			i = 3
+		}
+		if i < 0 {
+			continue
+		}
+		if lc.typ != int64(columnTypes[i]) {
+			return fmt.Errorf("unexpected physical type=%d for column %q; want %d", lc.typ, lc.path, columnTypes[i])
+		}
+		if i < 2 && lc.maxRepLevel != 1 {
+			return fmt.Errorf("column %q must be a map", lc.path)
+		}
+		if i >= 2 && lc.maxRepLevel != 0 {
+			return fmt.Errorf("column %q mustn't be repeated", lc.path)
+		}
+		pr.columns[i] = lc
+		found[i] = true
+	}
+	for i, ok := range found {
+		if !ok {
+			return fmt.Errorf("missing %q column in parquet schema", columnPaths[i])
+		}
+	}
+
+	unit := getTimestampUnit(tsElement)
+	switch unit {
+	case "MILLIS":
+		pr.timestampDivisor = 1
+	case "MICROS":
+		pr.timestampDivisor = 1e3
+	case "NANOS":
+		pr.timestampDivisor = 1e6
+	default:
+		return fmt.Errorf("unsupported unit %q for %q column; supported units: MILLIS, MICROS, NANOS", unit, TimestampColumn)
+	}
+	return nil
+}
+
+// getTimestampUnit returns the unit for timestamp column schema element se.
+//
+// Timestamps without explicitly set unit are treated as milliseconds.
+func getTimestampUnit(se thriftFields) string {
+	if logicalType, ok := se.getStruct(10); ok {
+		if ts, ok := logicalType.getStruct(8); ok {
+			if unit, ok := ts.getStruct(2); ok {
+				switch {
+				case unit.get(1) != nil:
+					return "MILLIS"
+				case unit.get(2) != nil:
+					return "MICROS"
+				case unit.get(3) != nil:
+					return "NANOS"
+				}
+			}
+			return ""
+		}
+	}
+	if convertedType, ok := se.getInt(6); ok && convertedType == convertedTypeTimestampMicros {
+		return "MICROS"
+	}
+	return "MILLIS"
+}
+
+// ReadRowGroup reads the row group with the given idx and calls f for every series in it.
+//
+// Consecutive rows with identical labels are passed to f as a single series.
+// f must not hold references to s after returning.
+func (pr *Reader) ReadRowGroup(idx int, f func(s *Series) error) error {
+	if idx < 0 || idx >= len(pr.rowGroups) {
+		return fmt.Errorf("row group index=%d is out of range [0..%d)", idx, len(pr.rowGroups))
+	}
+	rg := pr.rowGroups[idx]
+	rowsCount, _ := rg.getInt(3)
+	chunks, _ := rg.getList(1)
+
+	var cds [columnsCount]*columnData
+	for i := range pr.columns {
+		lc := &pr.columns[i]
+		if lc.idx >= len(chunks) {
+			return fmt.Errorf("missing column chunk for %q in row group #%d", lc.path, idx)
+		}
+		cc, ok := chunks[lc.idx].(thriftFields)
+		if !ok {
+			return fmt.Errorf("unexpected type for column chunk %q in row group #%d: %T", lc.path, idx, chunks[lc.idx])
+		}
+		cd, err := pr.readColumnChunk(lc, cc)
+		if err != nil {
+			return fmt.Errorf("cannot read column %q in row group #%d: %w", lc.path, idx, err)
+		}
+		cds[i] = cd
+	}
+	if err := assembleSeries(cds, pr.columns, rowsCount, pr.timestampDivisor, f); err != nil {
+		return fmt.Errorf("cannot read row group #%d: %w", idx, err)
+	}
+	return nil
+}
+
+// columnData contains decoded values for a column chunk.
+type columnData struct {
+	repLevels []uint32
+	defLevels []uint32
+
+	byteArrays [][]byte
+	int64s     []int64
+	float64s   []float64
+
+	// dict contains dictionary page values.
+	dict *columnData
+}
+
+func (cd *columnData) valuesCount() int {
+	return len(cd.byteArrays) + len(cd.int64s) + len(cd.float64s)
+}
+
+func (pr *Reader) readColumnChunk(lc *leafColumn, cc thriftFields) (*columnData, error) {
+	cmd, ok := cc.getStruct(3)
+	if !ok {
+		return nil, fmt.Errorf("missing column metadata")
+	}
+	codec, _ := cmd.getInt(4)
+	valuesCount, _ := cmd.getInt(5)
+	compressedSize, _ := cmd.getInt(7)
+	offset, _ := cmd.getInt(9)
+	if dictOffset, ok := cmd.getInt(11); ok && dictOffset > 0 && dictOffset < offset {
+		offset = dictOffset
+	}
+	if compressedSize < 0 || compressedSize > 1<<31 {
+		return nil, fmt.Errorf("invalid column chunk size=%d", compressedSize)
+	}
+	data := make([]byte, compressedSize)
+	if _, err := pr.r.ReadAt(data, offset); err != nil {
+		return nil, fmt.Errorf("cannot read column chunk at offset=%d: %w", offset, err)
+	}
+
+	cd := &columnData{}
+	repBitWidth := bits.Len(uint(lc.maxRepLevel))
+	defBitWidth := bits.Len(uint(lc.maxDefLevel))
+	valuesRead := int64(0)
+	for valuesRead < valuesCount {
+		if len(data) == 0 {
+			return nil, fmt.Errorf("unexpected end of column chunk after reading %d values out of %d values", valuesRead, valuesCount)
+		}
+		ph, tail, err := unmarshalThriftStruct(data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshal page header: %w", err)
+		}
+		pageType, _ := ph.getInt(1)
+		uncompressedSize, _ := ph.getInt(2)
+		pageSize, _ := ph.getInt(3)
+		if pageSize < 0 || pageSize > int64(len(tail)) {
+			return nil, fmt.Errorf("invalid page size=%d; it mustn't exceed %d bytes", pageSize, len(tail))
+		}
+		page := tail[:pageSize]
+		data = tail[pageSize:]
+
+		switch pageType {
+		case pageTypeDictionary:
+			dph, ok := ph.getStruct(7)
+			if !ok {
+				return nil, fmt.Errorf("missing dictionary page header")
+			}
+			n, _ := dph.getInt(1)
+			body, err := decompress(page, codec, uncompressedSize)
+			if err != nil {
+				return nil, err
+			}
+			dict := &columnData{}
+			if _, err := dict.decodePlainValues(body, lc.typ, int(n)); err != nil {
+				return nil, fmt.Errorf("cannot decode dictionary page: %w", err)
+			}
+			cd.dict = dict
+		case pageTypeData:
+			dph, ok := ph.getStruct(5)
+			if !ok {
+				return nil, fmt.Errorf("missing data page header")
+			}
+			n, _ := dph.getInt(1)
+			valuesEncoding, _ := dph.getInt(2)
+			body, err := decompress(page, codec, uncompressedSize)
+			if err != nil {
+				return nil, err
+			}
+			if lc.maxRepLevel > 0 {
+				cd.repLevels, body, err = decodeLevelsV1(cd.repLevels, body, repBitWidth, int(n))
+				if err != nil {
+					return nil, fmt.Errorf("cannot decode repetition levels: %w", err)
+				}
+			}
+			nonNulls := int(n)
+			if lc.maxDefLevel > 0 {
+				defLevelsLen := len(cd.defLevels)
+				cd.defLevels, body, err = decodeLevelsV1(cd.defLevels, body, defBitWidth, int(n))
+				if err != nil {
+					return nil, fmt.Errorf("cannot decode definition levels: %w", err)
+				}
+				nonNulls = countNonNulls(cd.defLevels[defLevelsLen:], lc.maxDefLevel)
+			}
+			if err := cd.decodeValues(body, lc.typ, valuesEncoding, nonNulls); err != nil {
+				return nil, err
+			}
+			valuesRead += n
+		case pageTypeDataV2:
+			dph, ok := ph.getStruct(8)
+			if !ok {
+				return nil, fmt.Errorf("missing data page v2 header")
+			}
+			n, _ := dph.getInt(1)
+			numNulls, _ := dph.getInt(2)
+			valuesEncoding, _ := dph.getInt(4)
+			defLevelsLen, _ := dph.getInt(5)
+			repLevelsLen, _ := dph.getInt(6)
+			isCompressed, ok := dph.getBool(7)
+			if !ok {
+				isCompressed = true
+			}
+			if repLevelsLen < 0 || defLevelsLen < 0 || repLevelsLen+defLevelsLen > int64(len(page)) {
+				return nil, fmt.Errorf("invalid levels size in data page v2")
+			}
+			if lc.maxRepLevel > 0 {
+				cd.repLevels, _, err = decodeHybrid(cd.repLevels, page[:repLevelsLen], repBitWidth, int(n))
+				if err != nil {
+					return nil, fmt.Errorf("cannot decode repetition levels: %w", err)
+				}
+			}
+			if lc.maxDefLevel > 0 {
+				cd.defLevels, _, err = decodeHybrid(cd.defLevels, page[repLevelsLen:repLevelsLen+defLevelsLen], defBitWidth, int(n))
+				if err != nil {
+					return nil, fmt.Errorf("cannot decode definition levels: %w", err)
+				}
+			}
+			body := page[repLevelsLen+defLevelsLen:]
+			if isCompressed {
+				body, err = decompress(body, codec, uncompressedSize-repLevelsLen-defLevelsLen)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if err := cd.decodeValues(body, lc.typ, valuesEncoding, int(n-numNulls)); err != nil {
+				return nil, err
+			}
+			valuesRead += n
+		case pageTypeIndex:
+			// Skip index pages
+		default:
+			return nil, fmt.Errorf("unsupported page type=%d", pageType)
+		}
+	}
+	return cd, nil
+}
+
+func decodeLevelsV1(dst []uint32, src []byte, bitWidth, n int) ([]uint32, []byte, error) {
+	if len(src) < 4 {
+		return dst, src, fmt.Errorf("missing levels length")
+	}
+	size := binary.LittleEndian.Uint32(src)
+	src = src[4:]
+	if uint64(size) > uint64(len(src)) {
+		return dst, src, fmt.Errorf("too big levels length=%d; it mustn't exceed %d bytes", size, len(src))
+	}
+	dst, _, err := decodeHybrid(dst, src[:size], bitWidth, n)
+	return dst, src[size:], err
+}
+
+func countNonNulls(defLevels []uint32, maxDefLevel int) int {
+	n := 0
+	for _, level := range defLevels {
+		if int(level) == maxDefLevel {
+			n++
+		}
+	}
+	return n
+}
+
+func (cd *columnData) decodeValues(src []byte, typ, valuesEncoding int64, n int) error {
+	switch valuesEncoding {
+	case encodingPlain:
+		if _, err := cd.decodePlainValues(src, typ, n); err != nil {
+			return fmt.Errorf("cannot decode PLAIN values: %w", err)
+		}
+		return nil
+	case encodingPlainDictionary, encodingRLEDictionary:
+		if cd.dict == nil {
+			return fmt.Errorf("missing dictionary page for dictionary-encoded values")
+		}
+		if n == 0 {
+			return nil
+		}
+		if len(src) < 1 {
+			return fmt.Errorf("missing bit width for dictionary indexes")
+		}
+		indexes, _, err := decodeHybrid(nil, src[1:], int(src[0]), n)
+		if err != nil {
+			return fmt.Errorf("cannot decode dictionary indexes: %w", err)
+		}
+		dictLen := uint32(cd.dict.valuesCount())
+		for _, idx := range indexes {
+			if idx >= dictLen {
+				return fmt.Errorf("dictionary index=%d is out of range [0..%d)", idx, dictLen)
+			}
+		}
+		switch typ {
+		case typeByteArray:
+			for _, idx := range indexes {
+				cd.byteArrays = append(cd.byteArrays, cd.dict.byteArrays[idx])
+			}
+		case typeInt64:
+			for _, idx := range indexes {
+				cd.int64s = append(cd.int64s, cd.dict.int64s[idx])
+			}
+		case typeDouble:
+			for _, idx := range indexes {
+				cd.float64s = append(cd.float64s, cd.dict.float64s[idx])
+			}
+		}
+		return nil
+	case encodingDeltaBinaryPacked:
+		if typ != typeInt64 {
+			return fmt.Errorf("unexpected DELTA_BINARY_PACKED encoding for physical type=%d", typ)
+		}
+		var err error
+		if cd.int64s, _, err = decodeDeltaBinaryPacked(cd.int64s, src, n); err != nil {
+			return fmt.Errorf("cannot decode DELTA_BINARY_PACKED values: %w", err)
+		}
+		return nil
+	case encodingDeltaLengthByteArray:
+		if typ != typeByteArray {
+			return fmt.Errorf("unexpected DELTA_LENGTH_BYTE_ARRAY encoding for physical type=%d", typ)
+		}
+		var err error
+		if cd.byteArrays, _, err = decodeDeltaLengthByteArrays(cd.byteArrays, src, n); err != nil {
+			return fmt.Errorf("cannot decode DELTA_LENGTH_BYTE_ARRAY values: %w", err)
+		}
+		return nil
+	case encodingDeltaByteArray:
+		if typ != typeByteArray {
+			return fmt.Errorf("unexpected DELTA_BYTE_ARRAY encoding for physical type=%d", typ)
+		}
+		var err error
+		if cd.byteArrays, _, err = decodeDeltaByteArrays(cd.byteArrays, src, n); err != nil {
+			return fmt.Errorf("cannot decode DELTA_BYTE_ARRAY values: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported values encoding=%d; supported encodings: PLAIN, PLAIN_DICTIONARY, RLE_DICTIONARY, "+
+			"DELTA_BINARY_PACKED, DELTA_LENGTH_BYTE_ARRAY, DELTA_BYTE_ARRAY", valuesEncoding)
+	}
+}
+
+func (cd *columnData) decodePlainValues(src []byte, typ int64, n int) ([]byte, error) {
+	var err error
+	switch typ {
+	case typeByteArray:
+		cd.byteArrays, src, err = decodeByteArrays(cd.byteArrays, src, n)
+	case typeInt64:
+		cd.int64s, src, err = decodeInt64s(cd.int64s, src, n)
+	case typeDouble:
+		cd.float64s, src, err = decodeFloat64s(cd.float64s, src, n)
+	default:
+		err = fmt.Errorf("unsupported physical type=%d", typ)
+	}
+	return src, err
+}
+
+func decompress(src []byte, codec, uncompressedSize int64) ([]byte, error) {
+	if uncompressedSize < 0 || uncompressedSize > 1<<31 {
+		return nil, fmt.Errorf("invalid uncompressed page size=%d", uncompressedSize)
+	}
+	switch codec {
+	case codecUncompressed:
+		return src, nil
+	case codecSnappy:
+		dst, err := snappy.Decode(make([]byte, 0, uncompressedSize), src)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress snappy page: %w", err)
+		}
+		return dst, nil
+	case codecGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(src))
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress gzip page: %w", err)
+		}
+		dst := bytes.NewBuffer(make([]byte, 0, uncompressedSize))
+		if _, err := io.Copy(dst, zr); err != nil {
+			return nil, fmt.Errorf("cannot decompress gzip page: %w", err)
+		}
+		return dst.Bytes(), nil
+	case codecZstd:
+		dst, err := zstd.Decompress(make([]byte, 0, uncompressedSize), src)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decompress zstd page: %w", err)
+		}
+		return dst, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression codec=%d; supported codecs: UNCOMPRESSED, SNAPPY, GZIP, ZSTD", codec)
+	}
+}
+
+// assembleSeries assembles rows from cds and passes consecutive rows with identical labels to f as a single series.
+func assembleSeries(cds [columnsCount]*columnData, columns [columnsCount]leafColumn, rowsCount, timestampDivisor int64, f func(s *Series) error) error {
+	keys, values, timestamps, vs := cds[0], cds[1], cds[2], cds[3]
+	if len(keys.repLevels) != len(values.repLevels) {
+		return fmt.Errorf("the number of labels keys=%d must match the number of labels values=%d", len(keys.repLevels), len(values.repLevels))
+	}
+
+	var s Series
+	var rowLabels []prompbmarshal.Label
+	entryIdx, keyIdx, valueIdx := 0, 0, 0
+	tsIdx, vIdx := 0, 0
+	for row := int64(0); row < rowsCount; row++ {
+		// Read labels for the row.
+		rowLabels = rowLabels[:0]
+		for entryIdx < len(keys.repLevels) {
+			if keys.repLevels[entryIdx] == 0 && len(rowLabels) > 0 {
+				// The next row starts
+				break
+			}
+			if int(keys.defLevels[entryIdx]) == columns[0].maxDefLevel {
+				if keyIdx >= len(keys.byteArrays) {
+					return fmt.Errorf("missing label key for row #%d", row)
+				}
+				label := prompbmarshal.Label{
+					Name: bytesutil.ToUnsafeString(keys.byteArrays[keyIdx]),
+				}
+				keyIdx++
+				if int(values.defLevels[entryIdx]) == columns[1].maxDefLevel {
+					if valueIdx >= len(values.byteArrays) {
+						return fmt.Errorf("missing label value for row #%d", row)
+					}
+					label.Value = bytesutil.ToUnsafeString(values.byteArrays[valueIdx])
+					valueIdx++
+				}
+				rowLabels = append(rowLabels, label)
+				entryIdx++
+				continue
+			}
+			// Empty or null map
+			entryIdx++
+			break
+		}
+
+		// Read timestamp and value for the row.
+		tsOK := isNotNull(timestamps, columns[2], int(row))
+		vOK := isNotNull(vs, columns[3], int(row))
+		var ts int64
+		var v float64
+		if tsOK {
+			if tsIdx >= len(timestamps.int64s) {
+				return fmt.Errorf("missing timestamp for row #%d", row)
+			}
+			ts = timestamps.int64s[tsIdx] / timestampDivisor
+			tsIdx++
+		}
+		if vOK {
+			if vIdx >= len(vs.float64s) {
+				return fmt.Errorf("missing value for row #%d", row)
+			}
+			v = vs.float64s[vIdx]
+			vIdx++
+		}
+		if !tsOK || !vOK {
+			continue
+		}
+
+		if !labelsEqual(s.Labels, rowLabels) {
+			if len(s.Timestamps) > 0 {
+				if err := f(&s); err != nil {
+					return err
+				}
+			}
+			s.Labels = append(s.Labels[:0], rowLabels...)
+			s.Timestamps = s.Timestamps[:0]
+			s.Values = s.Values[:0]
+		}
+		s.Timestamps = append(s.Timestamps, ts)
+		s.Values = append(s.Values, v)
+	}
+	if len(s.Timestamps) > 0 {
+		return f(&s)
+	}
+	return nil
+}
+
+func isNotNull(cd *columnData, lc leafColumn, row int) bool {
+	if lc.maxDefLevel == 0 {
+		return true
+	}
+	return row < len(cd.defLevels) && int(cd.defLevels[row]) == lc.maxDefLevel
+}
+
+func labelsEqual(a, b []prompbmarshal.Label) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
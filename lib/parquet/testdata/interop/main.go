@@ -0,0 +1,177 @@
+// The interop program writes Parquet files with the reference implementation from github.com/parquet-go/parquet-go
+// and verifies that files written by lib/parquet are readable by the reference implementation.
+//
+// Usage:
+//
+//	go run . gen /path/to/testdata
+//	go run . verify /path/to/file.parquet
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+type row struct {
+	Labels    map[string]string `parquet:"labels"`
+	Timestamp int64             `parquet:"timestamp,timestamp(millisecond)"`
+	Value     float64           `parquet:"value"`
+}
+
+type rowMicros struct {
+	Labels    map[string]string `parquet:"labels"`
+	Timestamp int64             `parquet:"timestamp,timestamp(microsecond)"`
+	Value     float64           `parquet:"value"`
+}
+
+var rows = []row{
+	{Labels: map[string]string{"__name__": "foo", "job": "x"}, Timestamp: 1000, Value: 1},
+	{Labels: map[string]string{"__name__": "foo", "job": "x"}, Timestamp: 2000, Value: 2.5},
+	{Labels: map[string]string{"__name__": "bar"}, Timestamp: 3000, Value: -3},
+}
+
+func main() {
+	if len(os.Args) != 3 {
+		log.Fatalf("usage: %s (gen <dir>|verify <file>)", os.Args[0])
+	}
+	switch os.Args[1] {
+	case "gen":
+		gen(os.Args[2])
+	case "verify":
+		verify(os.Args[2])
+	default:
+		log.Fatalf("unknown command %q", os.Args[1])
+	}
+}
+
+func gen(dir string) {
+	write(filepath.Join(dir, "parquet-go-zstd-v2.parquet"), rows, parquet.Compression(&parquet.Zstd), parquet.DataPageVersion(2))
+	write(filepath.Join(dir, "parquet-go-snappy-v1.parquet"), rows, parquet.Compression(&parquet.Snappy), parquet.DataPageVersion(1))
+	write(filepath.Join(dir, "parquet-go-gzip-dict.parquet"), rows, parquet.Compression(&parquet.Gzip), parquet.DataPageVersion(1),
+		parquet.DefaultEncoding(&parquet.RLEDictionary))
+	write(filepath.Join(dir, "parquet-go-uncompressed.parquet"), rows, parquet.Compression(&parquet.Uncompressed))
+
+	rs := make([]rowMicros, len(rows))
+	for i, r := range rows {
+		rs[i] = rowMicros{Labels: r.Labels, Timestamp: r.Timestamp * 1000, Value: r.Value}
+	}
+	write(filepath.Join(dir, "parquet-go-micros.parquet"), rs, parquet.Compression(&parquet.Snappy))
+
+	deltaOpts := []parquet.WriterOption{
+		parquet.Compression(&parquet.Zstd),
+		parquet.DefaultEncodingFor(parquet.ByteArray, &parquet.DeltaByteArray),
+		parquet.DefaultEncodingFor(parquet.Int64, &parquet.DeltaBinaryPacked),
+	}
+	write(filepath.Join(dir, "parquet-go-delta.parquet"), rows, deltaOpts...)
+
+	// Write many rows into a single row group in order to verify decoding of multiple DELTA_BINARY_PACKED blocks and miniblocks.
+	many := make([]row, manyRowsCount)
+	for i := range many {
+		many[i] = manyRow(i)
+	}
+	f, err := os.Create(filepath.Join(dir, "parquet-go-delta-many.parquet"))
+	if err != nil {
+		log.Fatalf("cannot create file: %s", err)
+	}
+	w := parquet.NewGenericWriter[row](f, deltaOpts...)
+	if _, err := w.Write(many); err != nil {
+		log.Fatalf("cannot write rows: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		log.Fatalf("cannot close writer: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("cannot close file: %s", err)
+	}
+}
+
+// manyRowsCount and manyRow must be in sync with the corresponding code at lib/parquet/interop_test.go
+const manyRowsCount = 1000
+
+func manyRow(i int) row {
+	return row{
+		Labels: map[string]string{
+			"__name__": "many",
+			"idx":      fmt.Sprintf("%04d", i/10),
+		},
+		Timestamp: 1e12 + int64(i)*15000 - int64(i%7)*3,
+		Value:     float64(i) / 4,
+	}
+}
+
+func write[T any](path string, rs []T, opts ...parquet.WriterOption) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatalf("cannot create %q: %s", path, err)
+	}
+	w := parquet.NewGenericWriter[T](f, opts...)
+	// Put every row into a distinct row group in order to verify reading multiple row groups.
+	for i := range rs {
+		if _, err := w.Write(rs[i : i+1]); err != nil {
+			log.Fatalf("cannot write row to %q: %s", path, err)
+		}
+		if err := w.Flush(); err != nil {
+			log.Fatalf("cannot flush row group to %q: %s", path, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		log.Fatalf("cannot close %q: %s", path, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("cannot close %q: %s", path, err)
+	}
+}
+
+func verify(path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("cannot open %q: %s", path, err)
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		log.Fatalf("cannot stat %q: %s", path, err)
+	}
+	pf, err := parquet.OpenFile(f, st.Size())
+	if err != nil {
+		log.Fatalf("cannot open %q: %s", path, err)
+	}
+	fmt.Println(pf.Schema())
+	for i, rg := range pf.Metadata().RowGroups {
+		for _, cc := range rg.Columns {
+			md := cc.MetaData
+			fmt.Printf("row group #%d: column %v: encodings=%v, codec=%v, values=%d, min=%x, max=%x\n",
+				i, md.PathInSchema, md.Encoding, md.Codec, md.NumValues, md.Statistics.MinValue, md.Statistics.MaxValue)
+		}
+	}
+	r := parquet.NewGenericReader[row](f)
+	buf := make([]row, 16)
+	for {
+		n, err := r.Read(buf)
+		for _, rr := range buf[:n] {
+			fmt.Printf("%s %d %v\n", labelsString(rr.Labels), rr.Timestamp, rr.Value)
+		}
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			log.Fatalf("cannot read %q: %s", path, err)
+		}
+	}
+}
+
+func labelsString(m map[string]string) string {
+	var a []string
+	for k, v := range m {
+		a = append(a, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(a)
+	return "{" + strings.Join(a, ",") + "}"
+}
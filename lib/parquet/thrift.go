@@ -0,0 +1,317 @@
+package parquet
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+)
+
+// Thrift compact protocol types.
+//
+// See https://github.com/apache/thrift/blob/master/doc/specs/thrift-compact-protocol.md
+const (
+	thriftStop      = 0
+	thriftTrue      = 1
+	thriftFalse     = 2
+	thriftByte      = 3
+	thriftI16       = 4
+	thriftI32       = 5
+	thriftI64       = 6
+	thriftDouble    = 7
+	thriftBinary    = 8
+	thriftList      = 9
+	thriftSet       = 10
+	thriftMap       = 11
+	thriftStruct    = 12
+	thriftMaxDepth  = 64
+	thriftMaxLength = 1 << 28
+)
+
+// thriftWriter marshals Thrift structs in compact protocol.
+type thriftWriter struct {
+	b []byte
+
+	lastFieldID  int16
+	fieldIDStack []int16
+}
+
+func (tw *thriftWriter) structBegin() {
+	tw.fieldIDStack = append(tw.fieldIDStack, tw.lastFieldID)
+	tw.lastFieldID = 0
+}
+
+func (tw *thriftWriter) structEnd() {
+	tw.b = append(tw.b, thriftStop)
+	n := len(tw.fieldIDStack) - 1
+	tw.lastFieldID = tw.fieldIDStack[n]
+	tw.fieldIDStack = tw.fieldIDStack[:n]
+}
+
+func (tw *thriftWriter) fieldHeader(id int16, typ byte) {
+	delta := id - tw.lastFieldID
+	if delta > 0 && delta <= 15 {
+		tw.b = append(tw.b, byte(delta<<4)|typ)
+	} else {
+		tw.b = append(tw.b, typ)
+		tw.b = encoding.MarshalVarInt64(tw.b, int64(id))
+	}
+	tw.lastFieldID = id
+}
+
+func (tw *thriftWriter) boolField(id int16, v bool) {
+	if v {
+		tw.fieldHeader(id, thriftTrue)
+	} else {
+		tw.fieldHeader(id, thriftFalse)
+	}
+}
+
+func (tw *thriftWriter) i32Field(id int16, v int32) {
+	tw.fieldHeader(id, thriftI32)
+	tw.b = encoding.MarshalVarInt64(tw.b, int64(v))
+}
+
+func (tw *thriftWriter) i64Field(id int16, v int64) {
+	tw.fieldHeader(id, thriftI64)
+	tw.b = encoding.MarshalVarInt64(tw.b, v)
+}
+
+func (tw *thriftWriter) binaryField(id int16, v []byte) {
+	tw.fieldHeader(id, thriftBinary)
+	tw.binary(v)
+}
+
+func (tw *thriftWriter) stringField(id int16, s string) {
+	tw.fieldHeader(id, thriftBinary)
+	tw.string(s)
+}
+
+func (tw *thriftWriter) structFieldBegin(id int16) {
+	tw.fieldHeader(id, thriftStruct)
+	tw.structBegin()
+}
+
+func (tw *thriftWriter) listFieldBegin(id int16, elemType byte, n int) {
+	tw.fieldHeader(id, thriftList)
+	if n < 15 {
+		tw.b = append(tw.b, byte(n<<4)|elemType)
+	} else {
+		tw.b = append(tw.b, 0xf0|elemType)
+		tw.b = encoding.MarshalVarUint64(tw.b, uint64(n))
+	}
+}
+
+func (tw *thriftWriter) i32(v int32) {
+	tw.b = encoding.MarshalVarInt64(tw.b, int64(v))
+}
+
+func (tw *thriftWriter) binary(v []byte) {
+	tw.b = encoding.MarshalVarUint64(tw.b, uint64(len(v)))
+	tw.b = append(tw.b, v...)
+}
+
+func (tw *thriftWriter) string(s string) {
+	tw.b = encoding.MarshalVarUint64(tw.b, uint64(len(s)))
+	tw.b = append(tw.b, s...)
+}
+
+// thriftFields is an unmarshaled Thrift struct.
+//
+// Field values have the following types: int64 for integer types, bool, float64, []byte, []any for lists and sets,
+// [][2]any for maps and thriftFields for structs.
+type thriftFields []thriftField
+
+type thriftField struct {
+	id    int16
+	value any
+}
+
+func (tf thriftFields) get(id int16) any {
+	for i := range tf {
+		if tf[i].id == id {
+			return tf[i].value
+		}
+	}
+	return nil
+}
+
+func (tf thriftFields) getInt(id int16) (int64, bool) {
+	n, ok := tf.get(id).(int64)
+	return n, ok
+}
+
+func (tf thriftFields) getBool(id int16) (bool, bool) {
+	v, ok := tf.get(id).(bool)
+	return v, ok
+}
+
+func (tf thriftFields) getBinary(id int16) ([]byte, bool) {
+	b, ok := tf.get(id).([]byte)
+	return b, ok
+}
+
+func (tf thriftFields) getString(id int16) string {
+	b, _ := tf.getBinary(id)
+	return string(b)
+}
+
+func (tf thriftFields) getStruct(id int16) (thriftFields, bool) {
+	s, ok := tf.get(id).(thriftFields)
+	return s, ok
+}
+
+func (tf thriftFields) getList(id int16) ([]any, bool) {
+	a, ok := tf.get(id).([]any)
+	return a, ok
+}
+
+// unmarshalThriftStruct unmarshals Thrift struct in compact protocol from src.
+//
+// It returns the unmarshaled struct and the tail left after unmarshaling.
+func unmarshalThriftStruct(src []byte) (thriftFields, []byte, error) {
+	return unmarshalThriftStructDepth(src, 0)
+}
+
+func unmarshalThriftStructDepth(src []byte, depth int) (thriftFields, []byte, error) {
+	if depth > thriftMaxDepth {
+		return nil, src, fmt.Errorf("too deep nesting of thrift structs; mustn't exceed %d", thriftMaxDepth)
+	}
+	var fields thriftFields
+	var lastFieldID int16
+	for {
+		if len(src) == 0 {
+			return nil, src, fmt.Errorf("missing thrift struct end")
+		}
+		b := src[0]
+		src = src[1:]
+		if b == thriftStop {
+			return fields, src, nil
+		}
+		typ := b & 0x0f
+		id := lastFieldID + int16(b>>4)
+		if b>>4 == 0 {
+			n, nSize := encoding.UnmarshalVarInt64(src)
+			if nSize <= 0 {
+				return nil, src, fmt.Errorf("cannot unmarshal thrift field id")
+			}
+			src = src[nSize:]
+			id = int16(n)
+		}
+		lastFieldID = id
+		var v any
+		var err error
+		switch typ {
+		case thriftTrue:
+			v = true
+		case thriftFalse:
+			v = false
+		default:
+			v, src, err = unmarshalThriftValue(src, typ, depth)
+			if err != nil {
+				return nil, src, fmt.Errorf("cannot unmarshal thrift field #%d: %w", id, err)
+			}
+		}
+		fields = append(fields, thriftField{
+			id:    id,
+			value: v,
+		})
+	}
+}
+
+func unmarshalThriftValue(src []byte, typ byte, depth int) (any, []byte, error) {
+	switch typ {
+	case thriftTrue, thriftFalse:
+		// bool values in collections are encoded as a single byte
+		if len(src) < 1 {
+			return nil, src, fmt.Errorf("missing bool value")
+		}
+		return src[0] == thriftTrue, src[1:], nil
+	case thriftByte:
+		if len(src) < 1 {
+			return nil, src, fmt.Errorf("missing byte value")
+		}
+		return int64(int8(src[0])), src[1:], nil
+	case thriftI16, thriftI32, thriftI64:
+		n, nSize := encoding.UnmarshalVarInt64(src)
+		if nSize <= 0 {
+			return nil, src, fmt.Errorf("cannot unmarshal varint")
+		}
+		return n, src[nSize:], nil
+	case thriftDouble:
+		if len(src) < 8 {
+			return nil, src, fmt.Errorf("missing double value")
+		}
+		return float64FromLE(src), src[8:], nil
+	case thriftBinary:
+		n, nSize := encoding.UnmarshalVarUint64(src)
+		if nSize <= 0 {
+			return nil, src, fmt.Errorf("cannot unmarshal binary length")
+		}
+		src = src[nSize:]
+		if n > uint64(len(src)) {
+			return nil, src, fmt.Errorf("too big binary length=%d; it mustn't exceed %d bytes", n, len(src))
+		}
+		return src[:n], src[n:], nil
+	case thriftList, thriftSet:
+		if len(src) < 1 {
+			return nil, src, fmt.Errorf("missing list header")
+		}
+		elemType := src[0] & 0x0f
+		n := uint64(src[0] >> 4)
+		src = src[1:]
+		if n == 15 {
+			var nSize int
+			n, nSize = encoding.UnmarshalVarUint64(src)
+			if nSize <= 0 {
+				return nil, src, fmt.Errorf("cannot unmarshal list size")
+			}
+			src = src[nSize:]
+		}
+		if n > thriftMaxLength || n > uint64(len(src)) {
+			return nil, src, fmt.Errorf("too big list size=%d", n)
+		}
+		a := make([]any, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, tail, err := unmarshalThriftValue(src, elemType, depth+1)
+			if err != nil {
+				return nil, src, fmt.Errorf("cannot unmarshal list item #%d: %w", i, err)
+			}
+			src = tail
+			a = append(a, v)
+		}
+		return a, src, nil
+	case thriftMap:
+		n, nSize := encoding.UnmarshalVarUint64(src)
+		if nSize <= 0 {
+			return nil, src, fmt.Errorf("cannot unmarshal map size")
+		}
+		src = src[nSize:]
+		if n == 0 {
+			return [][2]any(nil), src, nil
+		}
+		if n > thriftMaxLength || n > uint64(len(src)) || len(src) < 1 {
+			return nil, src, fmt.Errorf("too big map size=%d", n)
+		}
+		keyType := src[0] >> 4
+		valueType := src[0] & 0x0f
+		src = src[1:]
+		m := make([][2]any, 0, n)
+		for i := uint64(0); i < n; i++ {
+			k, tail, err := unmarshalThriftValue(src, keyType, depth+1)
+			if err != nil {
+				return nil, src, fmt.Errorf("cannot unmarshal map key #%d: %w", i, err)
+			}
+			v, tail, err := unmarshalThriftValue(tail, valueType, depth+1)
+			if err != nil {
+				return nil, src, fmt.Errorf("cannot unmarshal map value #%d: %w", i, err)
+			}
+			src = tail
+			m = append(m, [2]any{k, v})
+		}
+		return m, src, nil
+	case thriftStruct:
+		return unmarshalThriftStructDepth(src, depth+1)
+	default:
+		return nil, src, fmt.Errorf("unsupported thrift type %d", typ)
+	}
+}
@@ -0,0 +1,515 @@
+package parquet
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// magic is the magic string at the start and at the end of Parquet file.
+const magic = "PAR1"
+
+// Parquet physical types.
+const (
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+)
+
+// Parquet field repetition types.
+const (
+	repetitionRequired = 0
+	repetitionOptional = 1
+	repetitionRepeated = 2
+)
+
+// Parquet converted types.
+const (
+	convertedTypeUTF8            = 0
+	convertedTypeMap             = 1
+	convertedTypeTimestampMillis = 9
+	convertedTypeTimestampMicros = 10
+)
+
+// Parquet encodings.
+const (
+	encodingPlain                = 0
+	encodingPlainDictionary      = 2
+	encodingRLE                  = 3
+	encodingDeltaBinaryPacked    = 5
+	encodingDeltaLengthByteArray = 6
+	encodingDeltaByteArray       = 7
+	encodingRLEDictionary        = 8
+)
+
+// Parquet compression codecs.
+const (
+	codecUncompressed = 0
+	codecSnappy       = 1
+	codecGzip         = 2
+	codecZstd         = 6
+)
+
+// Parquet page types.
+const (
+	pageTypeData       = 0
+	pageTypeIndex      = 1
+	pageTypeDictionary = 2
+	pageTypeDataV2     = 3
+)
+
+// zstdCompressionLevel is the compression level for data pages.
+const zstdCompressionLevel = 1
+
+// Column names in Parquet files.
+//
+// Every row contains a single sample. Series labels are stored in the map column.
+const (
+	LabelsColumn    = "labels"
+	TimestampColumn = "timestamp"
+	ValueColumn     = "value"
+)
+
+// columnsCount is the number of leaf columns in the written files: labels keys, labels values, timestamps and values.
+const columnsCount = 4
+
+var columnPaths = [columnsCount][]string{
+	{LabelsColumn, "key_value", "key"},
+	{LabelsColumn, "key_value", "value"},
+	{TimestampColumn},
+	{ValueColumn},
+}
+
+var columnTypes = [columnsCount]int32{typeByteArray, typeByteArray, typeInt64, typeDouble}
+
+// Writer writes samples in Parquet format.
+//
+// Every sample is written as a row with labels, timestamp and value columns.
+// Rows are written in row groups via WriteRowGroup. Close must be called after writing all the row groups.
+//
+// See https://parquet.apache.org/docs/file-format/
+type Writer struct {
+	mu sync.Mutex
+
+	w         io.Writer
+	offset    int64
+	rowGroups []rowGroupMeta
+	err       error
+}
+
+// NewWriter returns new Writer, which writes Parquet data to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{
+		w: w,
+	}
+}
+
+type rowGroupMeta struct {
+	rowsCount int64
+	columns   [columnsCount]columnChunkMeta
+}
+
+type columnChunkMeta struct {
+	offset           int64
+	valuesCount      int64
+	uncompressedSize int64
+	compressedSize   int64
+
+	// minValue and maxValue contain PLAIN-encoded statistics. They are set only for timestamp column.
+	minValue []byte
+	maxValue []byte
+}
+
+// WriteRowGroup writes rg to w.
+//
+// It is safe calling WriteRowGroup from concurrently running goroutines.
+// rg may be re-used after WriteRowGroup returns.
+func (w *Writer) WriteRowGroup(rg *RowGroup) error {
+	if rg.rowsCount == 0 {
+		return nil
+	}
+
+	// Marshal the row group outside the lock, so multiple row groups can be marshaled in parallel.
+	rgm := rg.marshal()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeMagicIfNeededLocked(); err != nil {
+		return err
+	}
+	for i := range rgm.columns {
+		rgm.columns[i].offset += w.offset
+	}
+	if err := w.writeLocked(rg.buf); err != nil {
+		return err
+	}
+	w.rowGroups = append(w.rowGroups, rgm)
+	return nil
+}
+
+// Close writes Parquet footer to w.
+//
+// Close doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.writeMagicIfNeededLocked(); err != nil {
+		return err
+	}
+	var tw thriftWriter
+	w.marshalFileMetadataLocked(&tw)
+	tw.b = binary.LittleEndian.AppendUint32(tw.b, uint32(len(tw.b)))
+	tw.b = append(tw.b, magic...)
+	return w.writeLocked(tw.b)
+}
+
+func (w *Writer) writeMagicIfNeededLocked() error {
+	if w.offset > 0 {
+		return nil
+	}
+	return w.writeLocked([]byte(magic))
+}
+
+func (w *Writer) writeLocked(data []byte) error {
+	if w.err != nil {
+		return w.err
+	}
+	n, err := w.w.Write(data)
+	w.offset += int64(n)
+	if err != nil {
+		w.err = fmt.Errorf("cannot write parquet data: %w", err)
+		return w.err
+	}
+	return nil
+}
+
+func (w *Writer) marshalFileMetadataLocked(tw *thriftWriter) {
+	rowsCount := int64(0)
+	for i := range w.rowGroups {
+		rowsCount += w.rowGroups[i].rowsCount
+	}
+
+	tw.structBegin()
+	tw.i32Field(1, 1) // version
+	marshalSchema(tw)
+	tw.i64Field(3, rowsCount)
+	tw.listFieldBegin(4, thriftStruct, len(w.rowGroups))
+	for i := range w.rowGroups {
+		w.rowGroups[i].marshal(tw)
+	}
+	tw.stringField(6, "VictoriaMetrics")
+	tw.listFieldBegin(7, thriftStruct, columnsCount) // column_orders
+	for i := 0; i < columnsCount; i++ {
+		tw.structBegin()
+		tw.structFieldBegin(1) // TYPE_ORDER
+		tw.structEnd()
+		tw.structEnd()
+	}
+	tw.structEnd()
+}
+
+func marshalSchema(tw *thriftWriter) {
+	tw.listFieldBegin(2, thriftStruct, 7)
+
+	// root
+	tw.structBegin()
+	tw.stringField(4, "schema")
+	tw.i32Field(5, 3)
+	tw.structEnd()
+
+	// labels map
+	tw.structBegin()
+	tw.i32Field(3, repetitionRequired)
+	tw.stringField(4, LabelsColumn)
+	tw.i32Field(5, 1)
+	tw.i32Field(6, convertedTypeMap)
+	tw.structFieldBegin(10) // logicalType
+	tw.structFieldBegin(2)  // MAP
+	tw.structEnd()
+	tw.structEnd()
+	tw.structEnd()
+
+	// labels map key_value
+	tw.structBegin()
+	tw.i32Field(3, repetitionRepeated)
+	tw.stringField(4, "key_value")
+	tw.i32Field(5, 2)
+	tw.structEnd()
+
+	// labels map key and value
+	for _, name := range []string{"key", "value"} {
+		tw.structBegin()
+		tw.i32Field(1, typeByteArray)
+		tw.i32Field(3, repetitionRequired)
+		tw.stringField(4, name)
+		tw.i32Field(6, convertedTypeUTF8)
+		tw.structFieldBegin(10) // logicalType
+		tw.structFieldBegin(1)  // STRING
+		tw.structEnd()
+		tw.structEnd()
+		tw.structEnd()
+	}
+
+	// timestamp
+	tw.structBegin()
+	tw.i32Field(1, typeInt64)
+	tw.i32Field(3, repetitionRequired)
+	tw.stringField(4, TimestampColumn)
+	tw.i32Field(6, convertedTypeTimestampMillis)
+	tw.structFieldBegin(10) // logicalType
+	tw.structFieldBegin(8)  // TIMESTAMP
+	tw.boolField(1, true)   // isAdjustedToUTC
+	tw.structFieldBegin(2)  // unit
+	tw.structFieldBegin(1)  // MILLIS
+	tw.structEnd()
+	tw.structEnd()
+	tw.structEnd()
+	tw.structEnd()
+	tw.structEnd()
+
+	// value
+	tw.structBegin()
+	tw.i32Field(1, typeDouble)
+	tw.i32Field(3, repetitionRequired)
+	tw.stringField(4, ValueColumn)
+	tw.structEnd()
+}
+
+func (rgm *rowGroupMeta) marshal(tw *thriftWriter) {
+	totalSize := int64(0)
+	totalCompressedSize := int64(0)
+	for i := range rgm.columns {
+		totalSize += rgm.columns[i].uncompressedSize
+		totalCompressedSize += rgm.columns[i].compressedSize
+	}
+
+	tw.structBegin()
+	tw.listFieldBegin(1, thriftStruct, len(rgm.columns))
+	for i := range rgm.columns {
+		cm := &rgm.columns[i]
+
+		tw.structBegin()
+		tw.i64Field(2, cm.offset) // file_offset
+		tw.structFieldBegin(3)    // meta_data
+		tw.i32Field(1, columnTypes[i])
+		tw.listFieldBegin(2, thriftI32, 2)
+		tw.i32(encodingPlain)
+		tw.i32(encodingRLE)
+		path := columnPaths[i]
+		tw.listFieldBegin(3, thriftBinary, len(path))
+		for _, s := range path {
+			tw.string(s)
+		}
+		tw.i32Field(4, codecZstd)
+		tw.i64Field(5, cm.valuesCount)
+		tw.i64Field(6, cm.uncompressedSize)
+		tw.i64Field(7, cm.compressedSize)
+		tw.i64Field(9, cm.offset) // data_page_offset
+		if cm.minValue != nil {
+			tw.structFieldBegin(12) // statistics
+			tw.i64Field(3, 0)       // null_count
+			tw.binaryField(5, cm.maxValue)
+			tw.binaryField(6, cm.minValue)
+			tw.structEnd()
+		}
+		tw.structEnd()
+		tw.structEnd()
+	}
+	tw.i64Field(2, totalSize)
+	tw.i64Field(3, rgm.rowsCount)
+	if len(rgm.columns) > 0 {
+		tw.i64Field(5, rgm.columns[0].offset)
+	}
+	tw.i64Field(6, totalCompressedSize)
+	tw.structEnd()
+}
+
+// RowGroup accumulates rows for writing them to Parquet file via Writer.WriteRowGroup.
+type RowGroup struct {
+	rowsCount int
+
+	// repLevels and defLevels contain repetition and definition levels for labels key and value columns.
+	repLevels []byte
+	defLevels []byte
+
+	// labelKeys and labelValues contain PLAIN-encoded labels.
+	labelKeys   []byte
+	labelValues []byte
+
+	timestamps []int64
+	values     []float64
+
+	minTimestamp int64
+	maxTimestamp int64
+
+	buf  []byte
+	page []byte
+	tmp  []byte
+}
+
+// Reset resets rg, so it can be re-used.
+func (rg *RowGroup) Reset() {
+	rg.rowsCount = 0
+	rg.repLevels = rg.repLevels[:0]
+	rg.defLevels = rg.defLevels[:0]
+	rg.labelKeys = rg.labelKeys[:0]
+	rg.labelValues = rg.labelValues[:0]
+	rg.timestamps = rg.timestamps[:0]
+	rg.values = rg.values[:0]
+	rg.minTimestamp = 0
+	rg.maxTimestamp = 0
+	rg.buf = rg.buf[:0]
+}
+
+// RowsCount returns the number of rows in rg.
+func (rg *RowGroup) RowsCount() int {
+	return rg.rowsCount
+}
+
+// SizeBytes returns the approximate size of rg in bytes before compression.
+func (rg *RowGroup) SizeBytes() int {
+	return len(rg.labelKeys) + len(rg.labelValues) + 2*len(rg.repLevels) + 8*len(rg.timestamps) + 8*len(rg.values)
+}
+
+// AddSeries adds rows for the given samples of the series with the given labels to rg.
+//
+// timestamps must contain millisecond timestamps. The length of timestamps and values must be equal.
+func (rg *RowGroup) AddSeries(labels []prompbmarshal.Label, timestamps []int64, values []float64) {
+	if len(timestamps) != len(values) {
+		panic(fmt.Errorf("BUG: len(timestamps)=%d must match len(values)=%d", len(timestamps), len(values)))
+	}
+	if len(timestamps) == 0 {
+		return
+	}
+
+	// Marshal labels once and then copy them to every row.
+	tmp := rg.tmp[:0]
+	for _, label := range labels {
+		tmp = appendByteArray(tmp, label.Name)
+	}
+	keysLen := len(tmp)
+	for _, label := range labels {
+		tmp = appendByteArray(tmp, label.Value)
+	}
+	rg.tmp = tmp
+	labelKeys := tmp[:keysLen]
+	labelValues := tmp[keysLen:]
+
+	for range timestamps {
+		if len(labels) == 0 {
+			// Empty map
+			rg.repLevels = append(rg.repLevels, 0)
+			rg.defLevels = append(rg.defLevels, 0)
+			continue
+		}
+		rg.repLevels = append(rg.repLevels, 0)
+		for i := 1; i < len(labels); i++ {
+			rg.repLevels = append(rg.repLevels, 1)
+		}
+		for range labels {
+			rg.defLevels = append(rg.defLevels, 1)
+		}
+		rg.labelKeys = append(rg.labelKeys, labelKeys...)
+		rg.labelValues = append(rg.labelValues, labelValues...)
+	}
+
+	if rg.rowsCount == 0 {
+		rg.minTimestamp = math.MaxInt64
+		rg.maxTimestamp = math.MinInt64
+	}
+	for _, ts := range timestamps {
+		rg.minTimestamp = min(rg.minTimestamp, ts)
+		rg.maxTimestamp = max(rg.maxTimestamp, ts)
+	}
+	rg.timestamps = append(rg.timestamps, timestamps...)
+	rg.values = append(rg.values, values...)
+	rg.rowsCount += len(timestamps)
+}
+
+// marshal marshals rg column chunks to rg.buf and returns metadata for the marshaled row group.
+//
+// Offsets in the returned metadata are relative to the start of rg.buf.
+func (rg *RowGroup) marshal() rowGroupMeta {
+	rgm := rowGroupMeta{
+		rowsCount: int64(rg.rowsCount),
+	}
+	rg.buf = rg.buf[:0]
+
+	// labels keys and values
+	for i, data := range [][]byte{rg.labelKeys, rg.labelValues} {
+		page := rg.page[:0]
+		page = appendLevels(page, rg.repLevels)
+		page = appendLevels(page, rg.defLevels)
+		page = append(page, data...)
+		rg.page = page
+		rgm.columns[i] = rg.appendPage(len(rg.repLevels))
+	}
+
+	// timestamps
+	page := rg.page[:0]
+	for _, ts := range rg.timestamps {
+		page = binary.LittleEndian.AppendUint64(page, uint64(ts))
+	}
+	rg.page = page
+	cm := rg.appendPage(len(rg.timestamps))
+	cm.minValue = binary.LittleEndian.AppendUint64(nil, uint64(rg.minTimestamp))
+	cm.maxValue = binary.LittleEndian.AppendUint64(nil, uint64(rg.maxTimestamp))
+	rgm.columns[2] = cm
+
+	// values
+	page = rg.page[:0]
+	for _, v := range rg.values {
+		page = binary.LittleEndian.AppendUint64(page, math.Float64bits(v))
+	}
+	rg.page = page
+	rgm.columns[3] = rg.appendPage(len(rg.values))
+
+	return rgm
+}
+
+// appendLevels appends levels with the maximum level 1 to dst in the format suitable for data page v1.
+func appendLevels(dst, levels []byte) []byte {
+	n := len(dst)
+	dst = append(dst, 0, 0, 0, 0)
+	dst = appendHybridLevels(dst, levels, 1)
+	binary.LittleEndian.PutUint32(dst[n:], uint32(len(dst)-n-4))
+	return dst
+}
+
+// appendPage appends rg.page as a compressed data page to rg.buf.
+func (rg *RowGroup) appendPage(valuesCount int) columnChunkMeta {
+	offset := len(rg.buf)
+
+	compressed := zstd.CompressLevel(rg.tmp[:0], rg.page, zstdCompressionLevel)
+	rg.tmp = compressed
+
+	var tw thriftWriter
+	tw.b = rg.buf
+	tw.structBegin()
+	tw.i32Field(1, pageTypeData)
+	tw.i32Field(2, int32(len(rg.page)))
+	tw.i32Field(3, int32(len(compressed)))
+	tw.structFieldBegin(5) // data_page_header
+	tw.i32Field(1, int32(valuesCount))
+	tw.i32Field(2, encodingPlain)
+	tw.i32Field(3, encodingRLE)
+	tw.i32Field(4, encodingRLE)
+	tw.structEnd()
+	tw.structEnd()
+	headerSize := len(tw.b) - offset
+
+	rg.buf = append(tw.b, compressed...)
+	return columnChunkMeta{
+		offset:           int64(offset),
+		valuesCount:      int64(valuesCount),
+		uncompressedSize: int64(headerSize + len(rg.page)),
+		compressedSize:   int64(headerSize + len(compressed)),
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"testing"
 
+	"github.com/VictoriaMetrics/easyproto"
+
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 )
@@ -75,3 +77,96 @@ func TestWriteRequestMarshalProtobuf(t *testing.T) {
 		t.Fatalf("unexpected data obtained after marshaling\ngot\n%X\nwant\n%X", dataResult, data)
 	}
 }
+
+func TestWriteRequestMarshalProtobufWithExemplars(t *testing.T) {
+	wrm := &prompbmarshal.WriteRequest{
+		Timeseries: []prompbmarshal.TimeSeries{
+			{
+				Labels: []prompbmarshal.Label{
+					{
+						Name:  "__name__",
+						Value: "http_requests_total",
+					},
+				},
+				Samples: []prompbmarshal.Sample{
+					{
+						Value:     10,
+						Timestamp: 1700000000000,
+					},
+				},
+				Exemplars: []prompbmarshal.Exemplar{
+					{
+						Labels: []prompbmarshal.Label{
+							{
+								Name:  "trace_id",
+								Value: "abc",
+							},
+						},
+						Value:     0.5,
+						Timestamp: 1699999999000,
+					},
+				},
+			},
+		},
+	}
+	data := wrm.MarshalProtobuf(nil)
+
+	// Verify that the marshaled exemplar can be unmarshaled
+	var fc easyproto.FieldContext
+	var exemplar prompbmarshal.Exemplar
+	tsData := mustGetMessageData(t, data, 1)
+	exemplarData := mustGetMessageData(t, tsData, 3)
+	for len(exemplarData) > 0 {
+		var err error
+		exemplarData, err = fc.NextField(exemplarData)
+		if err != nil {
+			t.Fatalf("cannot read exemplar field: %s", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			labelData, _ := fc.MessageData()
+			if string(labelData) != "\n\x08trace_id\x12\x03abc" {
+				t.Fatalf("unexpected exemplar label data: %q", labelData)
+			}
+			exemplar.Labels = append(exemplar.Labels, prompbmarshal.Label{})
+		case 2:
+			exemplar.Value, _ = fc.Double()
+		case 3:
+			exemplar.Timestamp, _ = fc.Int64()
+		}
+	}
+	if len(exemplar.Labels) != 1 || exemplar.Value != 0.5 || exemplar.Timestamp != 1699999999000 {
+		t.Fatalf("unexpected exemplar: %+v", exemplar)
+	}
+
+	// Verify that exemplars are skipped by the WriteRequest parser
+	var wr prompb.WriteRequest
+	if err := wr.UnmarshalProtobuf(data); err != nil {
+		t.Fatalf("cannot unmarshal protobuf: %s", err)
+	}
+	if len(wr.Timeseries) != 1 || len(wr.Timeseries[0].Samples) != 1 {
+		t.Fatalf("unexpected time series: %+v", wr.Timeseries)
+	}
+}
+
+func mustGetMessageData(t *testing.T, src []byte, fieldNum uint32) []byte {
+	t.Helper()
+
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		var err error
+		src, err = fc.NextField(src)
+		if err != nil {
+			t.Fatalf("cannot read field: %s", err)
+		}
+		if fc.FieldNum == fieldNum {
+			data, ok := fc.MessageData()
+			if !ok {
+				t.Fatalf("cannot read message data for field #%d", fieldNum)
+			}
+			return data
+		}
+	}
+	t.Fatalf("missing field #%d", fieldNum)
+	return nil
+}
@@ -15,8 +15,21 @@ type Sample struct {
 
 // TimeSeries represents samples and labels for a single time series.
 type TimeSeries struct {
-	Labels  []Label
-	Samples []Sample
+	Labels    []Label
+	Samples   []Sample
+	Exemplars []Exemplar
+}
+
+// Exemplar is an optional exemplar attached to TimeSeries.
+type Exemplar struct {
+	// Labels contains optional labels for the exemplar such as trace_id.
+	Labels []Label
+
+	// Value is the exemplar value.
+	Value float64
+
+	// Timestamp is an optional timestamp in milliseconds for the exemplar.
+	Timestamp int64
 }
 
 type Label struct {
@@ -42,6 +55,16 @@ func (m *Sample) MarshalToSizedBuffer(dst []byte) (int, error) {
 
 func (m *TimeSeries) MarshalToSizedBuffer(dst []byte) (int, error) {
 	i := len(dst)
+	for j := len(m.Exemplars) - 1; j >= 0; j-- {
+		size, err := m.Exemplars[j].MarshalToSizedBuffer(dst[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarint(dst, i, uint64(size))
+		i--
+		dst[i] = 0x1a
+	}
 	for j := len(m.Samples) - 1; j >= 0; j-- {
 		size, err := m.Samples[j].MarshalToSizedBuffer(dst[:i])
 		if err != nil {
@@ -65,6 +88,32 @@ func (m *TimeSeries) MarshalToSizedBuffer(dst []byte) (int, error) {
 	return len(dst) - i, nil
 }
 
+func (m *Exemplar) MarshalToSizedBuffer(dst []byte) (int, error) {
+	i := len(dst)
+	if m.Timestamp != 0 {
+		i = encodeVarint(dst, i, uint64(m.Timestamp))
+		i--
+		dst[i] = 0x18
+	}
+	if m.Value != 0 {
+		i -= 8
+		binary.LittleEndian.PutUint64(dst[i:], uint64(math.Float64bits(float64(m.Value))))
+		i--
+		dst[i] = 0x11
+	}
+	for j := len(m.Labels) - 1; j >= 0; j-- {
+		size, err := m.Labels[j].MarshalToSizedBuffer(dst[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarint(dst, i, uint64(size))
+		i--
+		dst[i] = 0xa
+	}
+	return len(dst) - i, nil
+}
+
 func (m *Label) MarshalToSizedBuffer(dst []byte) (int, error) {
 	i := len(dst)
 	if len(m.Value) > 0 {
@@ -109,6 +158,27 @@ func (m *TimeSeries) Size() (n int) {
 		l := e.Size()
 		n += 1 + l + sov(uint64(l))
 	}
+	for _, e := range m.Exemplars {
+		l := e.Size()
+		n += 1 + l + sov(uint64(l))
+	}
+	return n
+}
+
+func (m *Exemplar) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	for _, e := range m.Labels {
+		l := e.Size()
+		n += 1 + l + sov(uint64(l))
+	}
+	if m.Value != 0 {
+		n += 9
+	}
+	if m.Timestamp != 0 {
+		n += 1 + sov(uint64(m.Timestamp))
+	}
 	return n
 }
 
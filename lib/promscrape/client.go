@@ -2,6 +2,7 @@ package promscrape
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -15,6 +16,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/netutil"
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
 )
 
 var (
@@ -36,6 +38,7 @@ type client struct {
 	ctx                     context.Context
 	scrapeURL               string
 	scrapeTimeoutSecondsStr string
+	acceptHeader            string
 	setHeaders              func(req *http.Request) error
 	setProxyHeaders         func(req *http.Request) error
 	maxScrapeSize           int64
@@ -101,6 +104,7 @@ func newClient(ctx context.Context, sw *ScrapeWork) (*client, error) {
 		ctx:                     ctx,
 		scrapeURL:               sw.ScrapeURL,
 		scrapeTimeoutSecondsStr: fmt.Sprintf("%.3f", sw.ScrapeTimeout.Seconds()),
+		acceptHeader:            getAcceptHeader(sw.ScrapeProtocols),
 		setHeaders:              setHeaders,
 		setProxyHeaders:         setProxyHeaders,
 		maxScrapeSize:           sw.MaxScrapeSize,
@@ -108,31 +112,27 @@ func newClient(ctx context.Context, sw *ScrapeWork) (*client, error) {
 	return c, nil
 }
 
-func (c *client) ReadData(dst *bytesutil.ByteBuffer) error {
+// ReadData reads the response from the scrape target into dst and returns the Content-Type of the response.
+func (c *client) ReadData(dst *bytesutil.ByteBuffer) (string, error) {
 	deadline := time.Now().Add(c.c.Timeout)
 	ctx, cancel := context.WithDeadline(c.ctx, deadline)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.scrapeURL, nil)
 	if err != nil {
 		cancel()
-		return fmt.Errorf("cannot create request for %q: %w", c.scrapeURL, err)
-	}
-	// The following `Accept` header has been copied from Prometheus sources.
-	// See https://github.com/prometheus/prometheus/blob/f9d21f10ecd2a343a381044f131ea4e46381ce09/scrape/scrape.go#L532 .
-	// This is needed as a workaround for scraping stupid Java-based servers such as Spring Boot.
-	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/608 for details.
-	// Do not bloat the `Accept` header with OpenMetrics shit, since it looks like dead standard now.
-	req.Header.Set("Accept", "text/plain;version=0.0.4;q=1,*/*;q=0.1")
+		return "", fmt.Errorf("cannot create request for %q: %w", c.scrapeURL, err)
+	}
+	req.Header.Set("Accept", c.acceptHeader)
 	// Set X-Prometheus-Scrape-Timeout-Seconds like Prometheus does, since it is used by some exporters such as PushProx.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1179#issuecomment-813117162
 	req.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", c.scrapeTimeoutSecondsStr)
 	req.Header.Set("User-Agent", "vm_promscrape")
 	if err := c.setHeaders(req); err != nil {
 		cancel()
-		return fmt.Errorf("failed to set request headers for %q: %w", c.scrapeURL, err)
+		return "", fmt.Errorf("failed to set request headers for %q: %w", c.scrapeURL, err)
 	}
 	if err := c.setProxyHeaders(req); err != nil {
 		cancel()
-		return fmt.Errorf("failed to set proxy request headers for %q: %w", c.scrapeURL, err)
+		return "", fmt.Errorf("failed to set proxy request headers for %q: %w", c.scrapeURL, err)
 	}
 	scrapeRequests.Inc()
 	resp, err := c.c.Do(req)
@@ -141,15 +141,18 @@ func (c *client) ReadData(dst *bytesutil.ByteBuffer) error {
 		if ue, ok := err.(*url.Error); ok && ue.Timeout() {
 			scrapesTimedout.Inc()
 		}
-		return fmt.Errorf("cannot perform request to %q: %w", c.scrapeURL, err)
+		return "", fmt.Errorf("cannot perform request to %q: %w", c.scrapeURL, err)
 	}
 	if resp.StatusCode != http.StatusOK {
 		metrics.GetOrCreateCounter(fmt.Sprintf(`vm_promscrape_scrapes_total{status_code="%d"}`, resp.StatusCode)).Inc()
 		respBody, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
 		cancel()
-		return fmt.Errorf("unexpected status code returned when scraping %q: %d; expecting %d; response body: %q",
-			c.scrapeURL, resp.StatusCode, http.StatusOK, respBody)
+		return "", &statusCodeError{
+			scrapeURL:  c.scrapeURL,
+			statusCode: resp.StatusCode,
+			body:       respBody,
+		}
 	}
 	scrapesOK.Inc()
 
@@ -161,21 +164,95 @@ func (c *client) ReadData(dst *bytesutil.ByteBuffer) error {
 	_, err = dst.ReadFrom(r)
 	_ = resp.Body.Close()
 	cancel()
+	contentType := resp.Header.Get("Content-Type")
 	if err != nil {
 		if ue, ok := err.(*url.Error); ok && ue.Timeout() {
 			scrapesTimedout.Inc()
 		}
-		return fmt.Errorf("cannot read data from %s: %w", c.scrapeURL, err)
+		return "", fmt.Errorf("cannot read data from %s: %w", c.scrapeURL, err)
 	}
 	if int64(len(dst.B)) >= c.maxScrapeSize {
 		maxScrapeSizeExceeded.Inc()
-		return fmt.Errorf("the response from %q exceeds -promscrape.maxScrapeSize or max_scrape_size in the scrape config (%d bytes). "+
+		return "", fmt.Errorf("the response from %q exceeds -promscrape.maxScrapeSize or max_scrape_size in the scrape config (%d bytes). "+
 			"Possible solutions are: reduce the response size for the target, increase -promscrape.maxScrapeSize command-line flag, "+
 			"increase max_scrape_size value in scrape config for the given target", c.scrapeURL, c.maxScrapeSize)
 	}
+	return contentType, nil
+}
+
+// statusCodeError is returned from client.ReadData when the scrape target returns unexpected http status code.
+type statusCodeError struct {
+	scrapeURL  string
+	statusCode int
+	body       []byte
+}
+
+func (e *statusCodeError) Error() string {
+	return fmt.Sprintf("unexpected status code returned when scraping %q: %d; expecting %d; response body: %q",
+		e.scrapeURL, e.statusCode, http.StatusOK, e.body)
+}
+
+// getStatusCode returns http status code for the scrape response, which has been read with the given err.
+//
+// 0 is returned if the response hasn't been received.
+func getStatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var se *statusCodeError
+	if errors.As(err, &se) {
+		return se.statusCode
+	}
+	return 0
+}
+
+// scrapeProtocolHeaders contains `Accept` header values for the supported `scrape_protocols`.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#scrape_config
+var scrapeProtocolHeaders = map[string]string{
+	"PrometheusProto":      parser.ProtobufContentType,
+	"PrometheusText0.0.4":  "text/plain;version=0.0.4",
+	"OpenMetricsText0.0.1": "application/openmetrics-text;version=0.0.1",
+	"OpenMetricsText1.0.0": "application/openmetrics-text;version=1.0.0",
+}
+
+func checkScrapeProtocols(scrapeProtocols []string) error {
+	seen := make(map[string]struct{}, len(scrapeProtocols))
+	for _, protocol := range scrapeProtocols {
+		if _, ok := scrapeProtocolHeaders[protocol]; !ok {
+			return fmt.Errorf("unsupported scrape protocol %q; supported values: PrometheusProto, PrometheusText0.0.4, OpenMetricsText0.0.1, OpenMetricsText1.0.0", protocol)
+		}
+		if _, ok := seen[protocol]; ok {
+			return fmt.Errorf("duplicate scrape protocol %q", protocol)
+		}
+		seen[protocol] = struct{}{}
+	}
 	return nil
 }
 
+// getAcceptHeader returns `Accept` header value for the given scrapeProtocols in the order of preference.
+func getAcceptHeader(scrapeProtocols []string) string {
+	if len(scrapeProtocols) == 0 {
+		// The following `Accept` header has been copied from Prometheus sources.
+		// See https://github.com/prometheus/prometheus/blob/f9d21f10ecd2a343a381044f131ea4e46381ce09/scrape/scrape.go#L532 .
+		// This is needed as a workaround for scraping stupid Java-based servers such as Spring Boot.
+		// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/608 for details.
+		// Do not bloat the `Accept` header with OpenMetrics shit, since it looks like dead standard now.
+		return "text/plain;version=0.0.4;q=1,*/*;q=0.1"
+	}
+
+	// Build the header in the same way as Prometheus does, so the most preferred protocol gets the highest weight.
+	// See https://github.com/prometheus/prometheus/blob/d3fc9b4f4d1d0a5d0bb1c4a4b4b1e2fa5a4bd20c/scrape/scrape.go#L672
+	a := make([]string, 0, len(scrapeProtocols)+1)
+	weight := len(scrapeProtocols) + 1
+	for _, protocol := range scrapeProtocols {
+		a = append(a, fmt.Sprintf("%s;q=0.%d", scrapeProtocolHeaders[protocol], weight))
+		weight--
+	}
+	a = append(a, fmt.Sprintf("*/*;q=0.%d", weight))
+	return strings.Join(a, ",")
+}
+
 var (
 	maxScrapeSizeExceeded = metrics.NewCounter(`vm_promscrape_max_scrape_size_exceeded_errors_total`)
 	scrapesTimedout       = metrics.NewCounter(`vm_promscrape_scrapes_timed_out_total`)
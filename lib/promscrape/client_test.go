@@ -11,8 +11,11 @@ import (
 	"testing"
 	"time"
 
+	"github.com/VictoriaMetrics/easyproto"
+
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
 )
 
@@ -157,7 +160,7 @@ func TestClientProxyReadOk(t *testing.T) {
 		}
 
 		var bb bytesutil.ByteBuffer
-		if err = c.ReadData(&bb); err != nil {
+		if _, err = c.ReadData(&bb); err != nil {
 			t.Fatalf("unexpected error at ReadData: %s", err)
 		}
 		got, err := io.ReadAll(bb.NewReader())
@@ -200,3 +203,69 @@ func TestClientProxyReadOk(t *testing.T) {
 	// backend tls and proxy auth
 	f(true, false, nil, &promauth.BasicAuthConfig{Username: "proxy-test", Password: promauth.NewSecret("1234")})
 }
+
+func TestClientReadDataProtobuf(t *testing.T) {
+	var mp easyproto.MarshalerPool
+	m := mp.Get()
+	mm := m.MessageMarshaler()
+	mm.AppendString(1, "foo_total")
+	mm.AppendInt32(3, 0)
+	metric := mm.AppendMessage(4)
+	label := metric.AppendMessage(1)
+	label.AppendString(1, "job")
+	label.AppendString(2, "bar")
+	metric.AppendMessage(3).AppendDouble(1, 42)
+	data := m.MarshalWithLen(nil)
+	mp.Put(m)
+
+	var acceptHeader string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptHeader = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", parser.ProtobufContentType)
+		w.Write(data)
+	}))
+	defer backend.Close()
+
+	c, err := newClient(context.Background(), &ScrapeWork{
+		ScrapeURL:       backend.URL,
+		ScrapeTimeout:   5 * time.Second,
+		AuthConfig:      newTestAuthConfig(t, false, nil),
+		ProxyAuthConfig: newTestAuthConfig(t, false, nil),
+		MaxScrapeSize:   16000,
+		ScrapeProtocols: []string{"PrometheusProto", "PrometheusText0.0.4"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+
+	var bb bytesutil.ByteBuffer
+	contentType, err := c.ReadData(&bb)
+	if err != nil {
+		t.Fatalf("unexpected error at ReadData: %s", err)
+	}
+	acceptHeaderExpected := parser.ProtobufContentType + ";q=0.3,text/plain;version=0.0.4;q=0.2,*/*;q=0.1"
+	if acceptHeader != acceptHeaderExpected {
+		t.Fatalf("unexpected Accept header;\ngot\n%s\nwant\n%s", acceptHeader, acceptHeaderExpected)
+	}
+	if !parser.IsProtobufContentType(contentType) {
+		t.Fatalf("unexpected Content-Type: %q", contentType)
+	}
+	if string(bb.B) != string(data) {
+		t.Fatalf("unexpected response;\ngot\n%X\nwant\n%X", bb.B, data)
+	}
+}
+
+func TestGetAcceptHeader(t *testing.T) {
+	f := func(scrapeProtocols []string, resultExpected string) {
+		t.Helper()
+
+		result := getAcceptHeader(scrapeProtocols)
+		if result != resultExpected {
+			t.Fatalf("unexpected Accept header;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	f(nil, "text/plain;version=0.0.4;q=1,*/*;q=0.1")
+	f([]string{"OpenMetricsText1.0.0"}, "application/openmetrics-text;version=1.0.0;q=0.2,*/*;q=0.1")
+	f([]string{"PrometheusText0.0.4", "OpenMetricsText0.0.1"}, "text/plain;version=0.0.4;q=0.3,application/openmetrics-text;version=0.0.1;q=0.2,*/*;q=0.1")
+}
@@ -33,13 +33,19 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/gce"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/hetzner"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/http"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/ionos"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/kubernetes"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/kuma"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/lightsail"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/linode"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/nomad"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/openstack"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/ovhcloud"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/puppetdb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/scaleway"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/vultr"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/yandexcloud"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/prober"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
 	"github.com/VictoriaMetrics/metrics"
@@ -112,6 +118,11 @@ type Config struct {
 	ScrapeConfigs     []*ScrapeConfig `yaml:"scrape_configs,omitempty"`
 	ScrapeConfigFiles []string        `yaml:"scrape_config_files,omitempty"`
 
+	// ProbeConfigs contains scrape configs, which probe the discovered targets instead of scraping them.
+	// They are merged into ScrapeConfigs during config parsing.
+	// See https://docs.victoriametrics.com/vmagent/#probing-targets
+	ProbeConfigs []*ScrapeConfig `yaml:"probe_configs,omitempty"`
+
 	// This is set to the directory from where the config has been loaded.
 	baseDir string
 }
@@ -133,7 +144,20 @@ func (cfg *Config) unmarshal(data []byte, isStrict bool) error {
 }
 
 func (cfg *Config) marshal() []byte {
-	data, err := yaml.Marshal(cfg)
+	// Marshal scrape configs with probes back under `probe_configs`, since the `probe` section isn't allowed in `scrape_configs`.
+	// This allows loading the marshaled config again.
+	cfgCopy := *cfg
+	cfgCopy.ScrapeConfigs = nil
+	cfgCopy.ProbeConfigs = nil
+	for _, sc := range cfg.ScrapeConfigs {
+		if sc.Probe != nil {
+			cfgCopy.ProbeConfigs = append(cfgCopy.ProbeConfigs, sc)
+		} else {
+			cfgCopy.ScrapeConfigs = append(cfgCopy.ScrapeConfigs, sc)
+		}
+	}
+	cfgCopy.ProbeConfigs = append(cfgCopy.ProbeConfigs, cfg.ProbeConfigs...)
+	data, err := yaml.Marshal(&cfgCopy)
 	if err != nil {
 		logger.Panicf("BUG: cannot marshal Config: %s", err)
 	}
@@ -282,13 +306,17 @@ type ScrapeConfig struct {
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/4697#issuecomment-1654614799 for details.
 	HonorTimestamps bool `yaml:"honor_timestamps,omitempty"`
 
-	Scheme               string                      `yaml:"scheme,omitempty"`
-	Params               map[string][]string         `yaml:"params,omitempty"`
-	HTTPClientConfig     promauth.HTTPClientConfig   `yaml:",inline"`
-	ProxyURL             *proxy.URL                  `yaml:"proxy_url,omitempty"`
-	RelabelConfigs       []promrelabel.RelabelConfig `yaml:"relabel_configs,omitempty"`
-	MetricRelabelConfigs []promrelabel.RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
-	SampleLimit          int                         `yaml:"sample_limit,omitempty"`
+	Scheme                string                      `yaml:"scheme,omitempty"`
+	Params                map[string][]string         `yaml:"params,omitempty"`
+	HTTPClientConfig      promauth.HTTPClientConfig   `yaml:",inline"`
+	ProxyURL              *proxy.URL                  `yaml:"proxy_url,omitempty"`
+	RelabelConfigs        []promrelabel.RelabelConfig `yaml:"relabel_configs,omitempty"`
+	MetricRelabelConfigs  []promrelabel.RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
+	SampleLimit           int                         `yaml:"sample_limit,omitempty"`
+	LabelLimit            int                         `yaml:"label_limit,omitempty"`
+	LabelNameLengthLimit  int                         `yaml:"label_name_length_limit,omitempty"`
+	LabelValueLengthLimit int                         `yaml:"label_value_length_limit,omitempty"`
+	ScrapeProtocols       []string                    `yaml:"scrape_protocols,omitempty"`
 
 	// This silly option is needed for compatibility with Prometheus.
 	// vmagent was supporting disable_compression option since the beginning, while Prometheus developers
@@ -309,11 +337,16 @@ type ScrapeConfig struct {
 	GCESDConfigs          []gce.SDConfig          `yaml:"gce_sd_configs,omitempty"`
 	HetznerSDConfigs      []hetzner.SDConfig      `yaml:"hetzner_sd_configs,omitempty"`
 	HTTPSDConfigs         []http.SDConfig         `yaml:"http_sd_configs,omitempty"`
+	IONOSSDConfigs        []ionos.SDConfig        `yaml:"ionos_sd_configs,omitempty"`
 	KubernetesSDConfigs   []kubernetes.SDConfig   `yaml:"kubernetes_sd_configs,omitempty"`
 	KumaSDConfigs         []kuma.SDConfig         `yaml:"kuma_sd_configs,omitempty"`
+	LightsailSDConfigs    []lightsail.SDConfig    `yaml:"lightsail_sd_configs,omitempty"`
+	LinodeSDConfigs       []linode.SDConfig       `yaml:"linode_sd_configs,omitempty"`
 	NomadSDConfigs        []nomad.SDConfig        `yaml:"nomad_sd_configs,omitempty"`
 	OpenStackSDConfigs    []openstack.SDConfig    `yaml:"openstack_sd_configs,omitempty"`
 	OVHCloudSDConfigs     []ovhcloud.SDConfig     `yaml:"ovhcloud_sd_configs,omitempty"`
+	PuppetDBSDConfigs     []puppetdb.SDConfig     `yaml:"puppetdb_sd_configs,omitempty"`
+	ScalewaySDConfigs     []scaleway.SDConfig     `yaml:"scaleway_sd_configs,omitempty"`
 	StaticConfigs         []StaticConfig          `yaml:"static_configs,omitempty"`
 	VultrSDConfigs        []vultr.SDConfig        `yaml:"vultr_configs,omitempty"`
 	YandexCloudSDConfigs  []yandexcloud.SDConfig  `yaml:"yandexcloud_sd_configs,omitempty"`
@@ -328,6 +361,9 @@ type ScrapeConfig struct {
 	NoStaleMarkers      *bool                      `yaml:"no_stale_markers,omitempty"`
 	ProxyClientConfig   promauth.ProxyClientConfig `yaml:",inline"`
 
+	// Probe is set only for `probe_configs` entries.
+	Probe *prober.Config `yaml:"probe,omitempty"`
+
 	// This is set in loadConfig
 	swc *scrapeWorkConfig
 }
@@ -384,12 +420,21 @@ func (sc *ScrapeConfig) mustStop() {
 	for i := range sc.HTTPSDConfigs {
 		sc.HTTPSDConfigs[i].MustStop()
 	}
+	for i := range sc.IONOSSDConfigs {
+		sc.IONOSSDConfigs[i].MustStop()
+	}
 	for i := range sc.KubernetesSDConfigs {
 		sc.KubernetesSDConfigs[i].MustStop()
 	}
 	for i := range sc.KumaSDConfigs {
 		sc.KumaSDConfigs[i].MustStop()
 	}
+	for i := range sc.LightsailSDConfigs {
+		sc.LightsailSDConfigs[i].MustStop()
+	}
+	for i := range sc.LinodeSDConfigs {
+		sc.LinodeSDConfigs[i].MustStop()
+	}
 	for i := range sc.NomadSDConfigs {
 		sc.NomadSDConfigs[i].MustStop()
 	}
@@ -399,6 +444,12 @@ func (sc *ScrapeConfig) mustStop() {
 	for i := range sc.OVHCloudSDConfigs {
 		sc.OVHCloudSDConfigs[i].MustStop()
 	}
+	for i := range sc.PuppetDBSDConfigs {
+		sc.PuppetDBSDConfigs[i].MustStop()
+	}
+	for i := range sc.ScalewaySDConfigs {
+		sc.ScalewaySDConfigs[i].MustStop()
+	}
 	for i := range sc.VultrSDConfigs {
 		sc.VultrSDConfigs[i].MustStop()
 	}
@@ -520,13 +571,28 @@ func (cfg *Config) parseData(data []byte, path string) error {
 	cfg.ScrapeConfigFiles = nil
 	cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, scs...)
 
+	// Merge cfg.ProbeConfigs into cfg.ScrapeConfigs, so they share discovery, relabeling and scheduling with scrape configs.
+	for _, sc := range cfg.ScrapeConfigs {
+		if sc.Probe != nil {
+			cfg.ScrapeConfigs = nil
+			return fmt.Errorf("`probe` section is allowed only in `probe_configs`; found it in `scrape_configs` for job_name=%q loaded from %q", sc.JobName, path)
+		}
+	}
+	for _, sc := range cfg.ProbeConfigs {
+		if sc.Probe == nil {
+			sc.Probe = &prober.Config{}
+		}
+	}
+	cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, cfg.ProbeConfigs...)
+	cfg.ProbeConfigs = nil
+
 	// Check that all the scrape configs have unique JobName
 	m := make(map[string]struct{}, len(cfg.ScrapeConfigs))
 	for _, sc := range cfg.ScrapeConfigs {
 		jobName := sc.JobName
 		if _, ok := m[jobName]; ok {
 			cfg.ScrapeConfigs = nil
-			return fmt.Errorf("duplicate `job_name` in `scrape_configs` loaded from %q: %q", path, jobName)
+			return fmt.Errorf("duplicate `job_name` in `scrape_configs` and `probe_configs` loaded from %q: %q", path, jobName)
 		}
 		m[jobName] = struct{}{}
 	}
@@ -704,6 +770,16 @@ func (cfg *Config) getHTTPDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	return cfg.getScrapeWorkGeneric(visitConfigs, "http_sd_config", prev)
 }
 
+// getIONOSSDScrapeWork returns `ionos_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getIONOSSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	visitConfigs := func(sc *ScrapeConfig, visitor func(sdc targetLabelsGetter)) {
+		for i := range sc.IONOSSDConfigs {
+			visitor(&sc.IONOSSDConfigs[i])
+		}
+	}
+	return cfg.getScrapeWorkGeneric(visitConfigs, "ionos_sd_config", prev)
+}
+
 // getKubernetesSDScrapeWork returns `kubernetes_sd_configs` ScrapeWork from cfg.
 func (cfg *Config) getKubernetesSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	const discoveryType = "kubernetes_sd_config"
@@ -742,6 +818,26 @@ func (cfg *Config) getKumaSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	return cfg.getScrapeWorkGeneric(visitConfigs, "kuma_sd_config", prev)
 }
 
+// getLightsailSDScrapeWork returns `lightsail_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getLightsailSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	visitConfigs := func(sc *ScrapeConfig, visitor func(sdc targetLabelsGetter)) {
+		for i := range sc.LightsailSDConfigs {
+			visitor(&sc.LightsailSDConfigs[i])
+		}
+	}
+	return cfg.getScrapeWorkGeneric(visitConfigs, "lightsail_sd_config", prev)
+}
+
+// getLinodeSDScrapeWork returns `linode_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getLinodeSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	visitConfigs := func(sc *ScrapeConfig, visitor func(sdc targetLabelsGetter)) {
+		for i := range sc.LinodeSDConfigs {
+			visitor(&sc.LinodeSDConfigs[i])
+		}
+	}
+	return cfg.getScrapeWorkGeneric(visitConfigs, "linode_sd_config", prev)
+}
+
 // getNomadSDScrapeWork returns `nomad_sd_configs` ScrapeWork from cfg.
 func (cfg *Config) getNomadSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	visitConfigs := func(sc *ScrapeConfig, visitor func(sdc targetLabelsGetter)) {
@@ -772,6 +868,26 @@ func (cfg *Config) getOVHCloudSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	return cfg.getScrapeWorkGeneric(visitConfigs, "ovhcloud_sd_config", prev)
 }
 
+// getPuppetDBSDScrapeWork returns `puppetdb_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getPuppetDBSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	visitConfigs := func(sc *ScrapeConfig, visitor func(sdc targetLabelsGetter)) {
+		for i := range sc.PuppetDBSDConfigs {
+			visitor(&sc.PuppetDBSDConfigs[i])
+		}
+	}
+	return cfg.getScrapeWorkGeneric(visitConfigs, "puppetdb_sd_config", prev)
+}
+
+// getScalewaySDScrapeWork returns `scaleway_sd_configs` ScrapeWork from cfg.
+func (cfg *Config) getScalewaySDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
+	visitConfigs := func(sc *ScrapeConfig, visitor func(sdc targetLabelsGetter)) {
+		for i := range sc.ScalewaySDConfigs {
+			visitor(&sc.ScalewaySDConfigs[i])
+		}
+	}
+	return cfg.getScrapeWorkGeneric(visitConfigs, "scaleway_sd_config", prev)
+}
+
 // getVultrSDScrapeWork returns `vultr_sd_configs` ScrapeWork from cfg.
 func (cfg *Config) getVultrSDScrapeWork(prev []*ScrapeWork) []*ScrapeWork {
 	visitConfigs := func(sc *ScrapeConfig, visitor func(sdc targetLabelsGetter)) {
@@ -883,9 +999,21 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 	if sc.HTTPClientConfig.FollowRedirects != nil {
 		denyRedirects = !*sc.HTTPClientConfig.FollowRedirects
 	}
+	var pr *prober.Prober
+	if sc.Probe != nil {
+		p, err := prober.NewProber(sc.Probe)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `probe` section for `job_name` %q: %w", jobName, err)
+		}
+		pr = p
+	}
 	metricsPath := sc.MetricsPath
 	if metricsPath == "" {
 		metricsPath = "/metrics"
+		if pr != nil {
+			// Probes are performed against the root path by default.
+			metricsPath = "/"
+		}
 	}
 	scheme := strings.ToLower(sc.Scheme)
 	if scheme == "" {
@@ -920,68 +1048,81 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 	if sc.SeriesLimit != nil {
 		seriesLimit = *sc.SeriesLimit
 	}
+	if err := checkScrapeProtocols(sc.ScrapeProtocols); err != nil {
+		return nil, fmt.Errorf("invalid `scrape_protocols` for `job_name` %q: %w", jobName, err)
+	}
 	disableCompression := sc.DisableCompression
 	if sc.EnableCompression != nil {
 		disableCompression = !*sc.EnableCompression
 	}
 	swc := &scrapeWorkConfig{
-		scrapeInterval:       scrapeInterval,
-		scrapeIntervalString: scrapeInterval.String(),
-		scrapeTimeout:        scrapeTimeout,
-		scrapeTimeoutString:  scrapeTimeout.String(),
-		maxScrapeSize:        mss,
-		jobName:              jobName,
-		metricsPath:          metricsPath,
-		scheme:               scheme,
-		params:               params,
-		proxyURL:             sc.ProxyURL,
-		proxyAuthConfig:      proxyAC,
-		authConfig:           ac,
-		honorLabels:          honorLabels,
-		honorTimestamps:      honorTimestamps,
-		denyRedirects:        denyRedirects,
-		externalLabels:       externalLabels,
-		relabelConfigs:       relabelConfigs,
-		metricRelabelConfigs: metricRelabelConfigs,
-		sampleLimit:          sc.SampleLimit,
-		disableCompression:   disableCompression,
-		disableKeepAlive:     sc.DisableKeepAlive,
-		streamParse:          sc.StreamParse,
-		scrapeAlignInterval:  sc.ScrapeAlignInterval.Duration(),
-		scrapeOffset:         sc.ScrapeOffset.Duration(),
-		seriesLimit:          seriesLimit,
-		noStaleMarkers:       noStaleTracking,
+		scrapeInterval:        scrapeInterval,
+		scrapeIntervalString:  scrapeInterval.String(),
+		scrapeTimeout:         scrapeTimeout,
+		scrapeTimeoutString:   scrapeTimeout.String(),
+		maxScrapeSize:         mss,
+		jobName:               jobName,
+		metricsPath:           metricsPath,
+		scheme:                scheme,
+		params:                params,
+		proxyURL:              sc.ProxyURL,
+		proxyAuthConfig:       proxyAC,
+		authConfig:            ac,
+		honorLabels:           honorLabels,
+		honorTimestamps:       honorTimestamps,
+		denyRedirects:         denyRedirects,
+		externalLabels:        externalLabels,
+		relabelConfigs:        relabelConfigs,
+		metricRelabelConfigs:  metricRelabelConfigs,
+		sampleLimit:           sc.SampleLimit,
+		labelLimit:            sc.LabelLimit,
+		labelNameLengthLimit:  sc.LabelNameLengthLimit,
+		labelValueLengthLimit: sc.LabelValueLengthLimit,
+		scrapeProtocols:       sc.ScrapeProtocols,
+		disableCompression:    disableCompression,
+		disableKeepAlive:      sc.DisableKeepAlive,
+		streamParse:           sc.StreamParse,
+		scrapeAlignInterval:   sc.ScrapeAlignInterval.Duration(),
+		scrapeOffset:          sc.ScrapeOffset.Duration(),
+		seriesLimit:           seriesLimit,
+		noStaleMarkers:        noStaleTracking,
+		prober:                pr,
 	}
 	return swc, nil
 }
 
 type scrapeWorkConfig struct {
-	scrapeInterval       time.Duration
-	scrapeIntervalString string
-	scrapeTimeout        time.Duration
-	scrapeTimeoutString  string
-	maxScrapeSize        int64
-	jobName              string
-	metricsPath          string
-	scheme               string
-	params               map[string][]string
-	proxyURL             *proxy.URL
-	proxyAuthConfig      *promauth.Config
-	authConfig           *promauth.Config
-	honorLabels          bool
-	honorTimestamps      bool
-	denyRedirects        bool
-	externalLabels       *promutils.Labels
-	relabelConfigs       *promrelabel.ParsedConfigs
-	metricRelabelConfigs *promrelabel.ParsedConfigs
-	sampleLimit          int
-	disableCompression   bool
-	disableKeepAlive     bool
-	streamParse          bool
-	scrapeAlignInterval  time.Duration
-	scrapeOffset         time.Duration
-	seriesLimit          int
-	noStaleMarkers       bool
+	scrapeInterval        time.Duration
+	scrapeIntervalString  string
+	scrapeTimeout         time.Duration
+	scrapeTimeoutString   string
+	maxScrapeSize         int64
+	jobName               string
+	metricsPath           string
+	scheme                string
+	params                map[string][]string
+	proxyURL              *proxy.URL
+	proxyAuthConfig       *promauth.Config
+	authConfig            *promauth.Config
+	honorLabels           bool
+	honorTimestamps       bool
+	denyRedirects         bool
+	externalLabels        *promutils.Labels
+	relabelConfigs        *promrelabel.ParsedConfigs
+	metricRelabelConfigs  *promrelabel.ParsedConfigs
+	sampleLimit           int
+	labelLimit            int
+	labelNameLengthLimit  int
+	labelValueLengthLimit int
+	scrapeProtocols       []string
+	disableCompression    bool
+	disableKeepAlive      bool
+	streamParse           bool
+	scrapeAlignInterval   time.Duration
+	scrapeOffset          time.Duration
+	seriesLimit           int
+	noStaleMarkers        bool
+	prober                *prober.Prober
 }
 
 func appendScrapeWorkForTargetLabels(dst []*ScrapeWork, swc *scrapeWorkConfig, targetLabels []*promutils.Labels, discoveryType string) []*ScrapeWork {
@@ -1242,30 +1383,35 @@ func (swc *scrapeWorkConfig) getScrapeWork(target string, extraLabels, metaLabel
 
 	originalLabels = sortOriginalLabelsIfNeeded(originalLabels)
 	sw := &ScrapeWork{
-		ScrapeURL:            scrapeURL,
-		ScrapeInterval:       scrapeInterval,
-		ScrapeTimeout:        scrapeTimeout,
-		MaxScrapeSize:        swc.maxScrapeSize,
-		HonorLabels:          swc.honorLabels,
-		HonorTimestamps:      swc.honorTimestamps,
-		DenyRedirects:        swc.denyRedirects,
-		OriginalLabels:       originalLabels,
-		Labels:               labelsCopy,
-		ExternalLabels:       swc.externalLabels,
-		ProxyURL:             swc.proxyURL,
-		ProxyAuthConfig:      swc.proxyAuthConfig,
-		AuthConfig:           swc.authConfig,
-		RelabelConfigs:       swc.relabelConfigs,
-		MetricRelabelConfigs: swc.metricRelabelConfigs,
-		SampleLimit:          sampleLimit,
-		DisableCompression:   swc.disableCompression,
-		DisableKeepAlive:     swc.disableKeepAlive,
-		StreamParse:          streamParse,
-		ScrapeAlignInterval:  swc.scrapeAlignInterval,
-		ScrapeOffset:         swc.scrapeOffset,
-		SeriesLimit:          seriesLimit,
-		NoStaleMarkers:       swc.noStaleMarkers,
-		AuthToken:            at,
+		ScrapeURL:             scrapeURL,
+		ScrapeInterval:        scrapeInterval,
+		ScrapeTimeout:         scrapeTimeout,
+		MaxScrapeSize:         swc.maxScrapeSize,
+		HonorLabels:           swc.honorLabels,
+		HonorTimestamps:       swc.honorTimestamps,
+		DenyRedirects:         swc.denyRedirects,
+		OriginalLabels:        originalLabels,
+		Labels:                labelsCopy,
+		ExternalLabels:        swc.externalLabels,
+		ProxyURL:              swc.proxyURL,
+		ProxyAuthConfig:       swc.proxyAuthConfig,
+		AuthConfig:            swc.authConfig,
+		RelabelConfigs:        swc.relabelConfigs,
+		MetricRelabelConfigs:  swc.metricRelabelConfigs,
+		SampleLimit:           sampleLimit,
+		LabelLimit:            swc.labelLimit,
+		LabelNameLengthLimit:  swc.labelNameLengthLimit,
+		LabelValueLengthLimit: swc.labelValueLengthLimit,
+		ScrapeProtocols:       swc.scrapeProtocols,
+		DisableCompression:    swc.disableCompression,
+		DisableKeepAlive:      swc.disableKeepAlive,
+		StreamParse:           streamParse,
+		ScrapeAlignInterval:   swc.scrapeAlignInterval,
+		ScrapeOffset:          swc.scrapeOffset,
+		SeriesLimit:           seriesLimit,
+		NoStaleMarkers:        swc.noStaleMarkers,
+		AuthToken:             at,
+		Prober:                swc.prober,
 
 		jobNameOriginal: swc.jobName,
 	}
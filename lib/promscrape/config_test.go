@@ -309,6 +309,94 @@ scrape_configs:
 	checkEqualScrapeWorks(t, sws, swsExpected)
 }
 
+func TestProbeConfigs(t *testing.T) {
+	data := `
+scrape_configs:
+  - job_name: foo
+    static_configs:
+      - targets: ["foo:1234"]
+probe_configs:
+  - job_name: tcp-probe
+    probe:
+      module: tcp
+    static_configs:
+      - targets: ["example.com:8080"]
+  - job_name: http-probe
+    static_configs:
+      - targets: ["https://example.com/health"]
+  - job_name: invalid-module
+    probe:
+      module: icmp
+    static_configs:
+      - targets: ["example.com"]
+`
+	var cfg Config
+	if err := cfg.parseData([]byte(data), "sss"); err != nil {
+		t.Fatalf("cannot parse data: %s", err)
+	}
+	if len(cfg.ProbeConfigs) != 0 {
+		t.Fatalf("probe_configs must be merged into scrape_configs; got %d probe_configs", len(cfg.ProbeConfigs))
+	}
+
+	// The marshaled config must contain probes under `probe_configs`, so it could be loaded again.
+	var cfgReloaded Config
+	if err := cfgReloaded.parseData(cfg.marshal(), "sss"); err != nil {
+		t.Fatalf("cannot parse marshaled config: %s", err)
+	}
+	if !reflect.DeepEqual(cfgReloaded.getJobNames(), cfg.getJobNames()) {
+		t.Fatalf("unexpected job names in the reloaded config; got %q; want %q", cfgReloaded.getJobNames(), cfg.getJobNames())
+	}
+	for i, sc := range cfgReloaded.ScrapeConfigs {
+		if (sc.Probe != nil) != (cfg.ScrapeConfigs[i].Probe != nil) {
+			t.Fatalf("unexpected probe for job %q in the reloaded config: %v", sc.JobName, sc.Probe)
+		}
+	}
+	sws := cfg.getStaticScrapeWork()
+	for i, sw := range sws {
+		isProbe := sw.jobNameOriginal != "foo"
+		if isProbe != (sw.Prober != nil) {
+			t.Fatalf("unexpected Prober for job %q at position %d: %v", sw.jobNameOriginal, i, sw.Prober)
+		}
+		sw.Prober = nil
+	}
+	swsExpected := []*ScrapeWork{
+		{
+			ScrapeURL:      "http://foo:1234/metrics",
+			ScrapeInterval: defaultScrapeInterval,
+			ScrapeTimeout:  defaultScrapeTimeout,
+			MaxScrapeSize:  maxScrapeSize.N,
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"instance": "foo:1234",
+				"job":      "foo",
+			}),
+			jobNameOriginal: "foo",
+		},
+		{
+			ScrapeURL:      "http://example.com:8080/",
+			ScrapeInterval: defaultScrapeInterval,
+			ScrapeTimeout:  defaultScrapeTimeout,
+			MaxScrapeSize:  maxScrapeSize.N,
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"instance": "example.com:8080",
+				"job":      "tcp-probe",
+			}),
+			jobNameOriginal: "tcp-probe",
+		},
+		{
+			ScrapeURL:      "https://example.com/health",
+			ScrapeInterval: defaultScrapeInterval,
+			ScrapeTimeout:  defaultScrapeTimeout,
+			MaxScrapeSize:  maxScrapeSize.N,
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"instance": "example.com:443",
+				"job":      "http-probe",
+			}),
+			jobNameOriginal: "http-probe",
+		},
+	}
+	checkEqualScrapeWorks(t, sws, swsExpected)
+}
+
 func TestGetFileSDScrapeWork(t *testing.T) {
 	data := `
 scrape_configs:
@@ -428,6 +516,28 @@ scrape_configs:
   static_configs:
     targets: ["bar"]
 `)
+
+	// Duplicate job_name in scrape_configs and probe_configs
+	f(`
+scrape_configs:
+- job_name: foo
+  static_configs:
+  - targets: ["foo"]
+probe_configs:
+- job_name: foo
+  static_configs:
+  - targets: ["bar"]
+`)
+
+	// probe section in scrape_configs
+	f(`
+scrape_configs:
+- job_name: foo
+  probe:
+    module: tcp
+  static_configs:
+  - targets: ["foo"]
+`)
 }
 
 // String returns human-readable representation for sw.
@@ -651,6 +761,24 @@ scrape_configs:
   - targets: ["foo"]
 `, []*ScrapeWork{})
 
+	// Scrape config with unsupported scrape_protocols must be skipped
+	f(`
+scrape_configs:
+- job_name: x
+  scrape_protocols: ["foobar"]
+  static_configs:
+  - targets: ["foo"]
+`, []*ScrapeWork{})
+
+	// Scrape config with duplicate scrape_protocols must be skipped
+	f(`
+scrape_configs:
+- job_name: x
+  scrape_protocols: ["PrometheusProto", "PrometheusProto"]
+  static_configs:
+  - targets: ["foo"]
+`, []*ScrapeWork{})
+
 	// Scrape config with missing job_name must be skipped
 	f(`
 scrape_configs:
@@ -1127,6 +1255,9 @@ scrape_configs:
 scrape_configs:
   - job_name: 'snmp'
     sample_limit: 100
+    label_limit: 30
+    label_name_length_limit: 200
+    label_value_length_limit: 4096
     disable_keepalive: true
     disable_compression: true
     headers:
@@ -1164,14 +1295,17 @@ scrape_configs:
 				"instance": "192.168.1.2",
 				"job":      "snmp",
 			}),
-			SampleLimit:         5678,
-			DisableKeepAlive:    true,
-			DisableCompression:  true,
-			StreamParse:         true,
-			ScrapeAlignInterval: time.Second,
-			ScrapeOffset:        500 * time.Millisecond,
-			SeriesLimit:         1234,
-			jobNameOriginal:     "snmp",
+			SampleLimit:           5678,
+			LabelLimit:            30,
+			LabelNameLengthLimit:  200,
+			LabelValueLengthLimit: 4096,
+			DisableKeepAlive:      true,
+			DisableCompression:    true,
+			StreamParse:           true,
+			ScrapeAlignInterval:   time.Second,
+			ScrapeOffset:          500 * time.Millisecond,
+			SeriesLimit:           1234,
+			jobNameOriginal:       "snmp",
 		},
 	})
 	f(`
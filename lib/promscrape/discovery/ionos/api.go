@@ -0,0 +1,54 @@
+package ionos
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+type apiConfig struct {
+	c            *discoveryutils.Client
+	datacenterID string
+	port         int
+}
+
+func getAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	v, err := configMap.Get(sdc, func() (any, error) { return newAPIConfig(sdc, baseDir) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*apiConfig), nil
+}
+
+func newAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	if sdc.DatacenterID == "" {
+		return nil, fmt.Errorf("missing `datacenter_id` option")
+	}
+	port := 80
+	if sdc.Port != nil {
+		port = *sdc.Port
+	}
+
+	// See https://api.ionos.com/docs/cloud/v6/
+	apiServer := "https://api.ionos.com"
+
+	ac, err := sdc.HTTPClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse auth config: %w", err)
+	}
+	proxyAC, err := sdc.ProxyClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse proxy auth config: %w", err)
+	}
+	c, err := discoveryutils.NewClient(apiServer, ac, sdc.ProxyURL, proxyAC, &sdc.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client for %q: %w", apiServer, err)
+	}
+	cfg := &apiConfig{
+		c:            c,
+		datacenterID: url.PathEscape(sdc.DatacenterID),
+		port:         port,
+	}
+	return cfg, nil
+}
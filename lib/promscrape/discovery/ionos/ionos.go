@@ -0,0 +1,54 @@
+package ionos
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
+)
+
+// SDCheckInterval defines interval for IONOS Cloud targets refresh.
+var SDCheckInterval = flag.Duration("promscrape.ionosSDCheckInterval", time.Minute, "Interval for checking for changes in IONOS Cloud. "+
+	"This works only if ionos_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://docs.victoriametrics.com/sd_configs/#ionos_sd_configs for details")
+
+// SDConfig represents service discovery config for IONOS Cloud.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#ionos_sd_config
+type SDConfig struct {
+	DatacenterID string `yaml:"datacenter_id"`
+	Port         *int   `yaml:"port,omitempty"`
+
+	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
+	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
+	// refresh_interval is obtained from `-promscrape.ionosSDCheckInterval` command-line option.
+}
+
+var configMap = discoveryutils.NewConfigMap()
+
+// GetLabels returns IONOS Cloud labels according to sdc.
+func (sdc *SDConfig) GetLabels(baseDir string) ([]*promutils.Labels, error) {
+	cfg, err := getAPIConfig(sdc, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	servers, err := getServers(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return getServersLabels(servers, cfg.port), nil
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	v := configMap.Delete(sdc)
+	if v != nil {
+		cfg := v.(*apiConfig)
+		cfg.c.Stop()
+	}
+}
@@ -0,0 +1,101 @@
+package ionos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestGetServersLabels(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RequestURI() != "/cloudapi/v6/datacenters/8feda53f-15f0-447f-badf-ebe32dad2fc0/servers?depth=3" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{
+  "id": "8feda53f-15f0-447f-badf-ebe32dad2fc0/servers",
+  "type": "collection",
+  "items": [
+    {
+      "id": "d6bf44ee-f7e8-4e19-8716-96fdd18cc697",
+      "type": "server",
+      "metadata": {"state": "AVAILABLE"},
+      "properties": {
+        "name": "prometheus-2",
+        "cores": 2,
+        "ram": 4096,
+        "availabilityZone": "AUTO",
+        "vmState": "RUNNING",
+        "bootCdrom": null,
+        "bootVolume": {"id": "0e4d57f9-cd78-4e01-8b0b-4b54b6e4b2c2", "type": "volume"},
+        "cpuFamily": "INTEL_SKYLAKE",
+        "type": "ENTERPRISE"
+      },
+      "entities": {
+        "nics": {
+          "items": [
+            {"id": "nic-1", "properties": {"name": "metrics", "ips": ["85.215.243.177"]}},
+            {"id": "nic-2", "properties": {"name": "internal-net", "ips": ["10.7.0.3", "10.7.0.4"]}}
+          ]
+        },
+        "volumes": {
+          "items": [
+            {"id": "0e4d57f9-cd78-4e01-8b0b-4b54b6e4b2c2", "properties": {"image": "e7a1bf97-6c2e-11ed-8e5b-66fd5d8e5a5b"}}
+          ]
+        }
+      }
+    },
+    {
+      "id": "b501942c-4e08-43e6-8ec1-00e59c64e0e4",
+      "type": "server",
+      "metadata": {"state": "BUSY"},
+      "properties": {
+        "name": "without-nics",
+        "vmState": "SHUTOFF"
+      },
+      "entities": {"nics": {"items": []}}
+    }
+  ]
+}`))
+	}))
+	defer s.Close()
+
+	c, err := discoveryutils.NewClient(s.URL, nil, nil, nil, &promauth.HTTPClientConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error when creating http client: %s", err)
+	}
+	defer c.Stop()
+	cfg := &apiConfig{
+		c:            c,
+		datacenterID: "8feda53f-15f0-447f-badf-ebe32dad2fc0",
+		port:         9100,
+	}
+	servers, err := getServers(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error in getServers(): %s", err)
+	}
+	labelss := getServersLabels(servers, cfg.port)
+	expectedLabels := []*promutils.Labels{
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                             "85.215.243.177:9100",
+			"__meta_ionos_server_availability_zone":   "AUTO",
+			"__meta_ionos_server_boot_image_id":       "e7a1bf97-6c2e-11ed-8e5b-66fd5d8e5a5b",
+			"__meta_ionos_server_boot_volume_id":      "0e4d57f9-cd78-4e01-8b0b-4b54b6e4b2c2",
+			"__meta_ionos_server_cpu_family":          "INTEL_SKYLAKE",
+			"__meta_ionos_server_id":                  "d6bf44ee-f7e8-4e19-8716-96fdd18cc697",
+			"__meta_ionos_server_ip":                  ",85.215.243.177,10.7.0.3,10.7.0.4,",
+			"__meta_ionos_server_lifecycle":           "AVAILABLE",
+			"__meta_ionos_server_name":                "prometheus-2",
+			"__meta_ionos_server_nic_ip_metrics":      ",85.215.243.177,",
+			"__meta_ionos_server_nic_ip_internal_net": ",10.7.0.3,10.7.0.4,",
+			"__meta_ionos_server_servers_id":          "8feda53f-15f0-447f-badf-ebe32dad2fc0/servers",
+			"__meta_ionos_server_state":               "RUNNING",
+			"__meta_ionos_server_type":                "ENTERPRISE",
+		}),
+	}
+	discoveryutils.TestEqualLabelss(t, labelss, expectedLabels)
+}
@@ -0,0 +1,155 @@
+package ionos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// Servers represents IONOS Cloud servers collection.
+//
+// See https://api.ionos.com/docs/cloud/v6/#tag/Servers/operation/datacentersServersGet
+type Servers struct {
+	ID    string   `json:"id"`
+	Items []Server `json:"items"`
+}
+
+// Server represents IONOS Cloud server.
+type Server struct {
+	ID         string           `json:"id"`
+	Metadata   ServerMetadata   `json:"metadata"`
+	Properties ServerProperties `json:"properties"`
+	Entities   ServerEntities   `json:"entities"`
+}
+
+// ServerMetadata represents IONOS Cloud server metadata.
+type ServerMetadata struct {
+	State string `json:"state"`
+}
+
+// ServerProperties represents IONOS Cloud server properties.
+type ServerProperties struct {
+	Name             string       `json:"name"`
+	Type             string       `json:"type"`
+	AvailabilityZone string       `json:"availabilityZone"`
+	VMState          string       `json:"vmState"`
+	CPUFamily        string       `json:"cpuFamily"`
+	BootCDROM        *ResourceRef `json:"bootCdrom"`
+	BootVolume       *ResourceRef `json:"bootVolume"`
+}
+
+// ResourceRef represents a reference to IONOS Cloud resource.
+type ResourceRef struct {
+	ID string `json:"id"`
+}
+
+// ServerEntities represents IONOS Cloud server entities.
+type ServerEntities struct {
+	NICs    NICs    `json:"nics"`
+	Volumes Volumes `json:"volumes"`
+}
+
+// NICs represents IONOS Cloud NICs collection.
+type NICs struct {
+	Items []NIC `json:"items"`
+}
+
+// NIC represents IONOS Cloud NIC.
+type NIC struct {
+	Properties NICProperties `json:"properties"`
+}
+
+// NICProperties represents IONOS Cloud NIC properties.
+type NICProperties struct {
+	Name string   `json:"name"`
+	IPs  []string `json:"ips"`
+}
+
+// Volumes represents IONOS Cloud volumes collection.
+type Volumes struct {
+	Items []Volume `json:"items"`
+}
+
+// Volume represents IONOS Cloud volume.
+type Volume struct {
+	ID         string           `json:"id"`
+	Properties VolumeProperties `json:"properties"`
+}
+
+// VolumeProperties represents IONOS Cloud volume properties.
+type VolumeProperties struct {
+	Image string `json:"image"`
+}
+
+func getServers(cfg *apiConfig) (*Servers, error) {
+	// depth=3 is needed for obtaining NIC ips and volume images in a single request.
+	path := "/cloudapi/v6/datacenters/" + cfg.datacenterID + "/servers?depth=3"
+	data, err := cfg.c.GetAPIResponse(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get IONOS Cloud response from %q: %w", path, err)
+	}
+	var servers Servers
+	if err := json.Unmarshal(data, &servers); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal IONOS Cloud servers obtained from %q: %w; response=%q", path, err, data)
+	}
+	return &servers, nil
+}
+
+func getServersLabels(servers *Servers, port int) []*promutils.Labels {
+	ms := make([]*promutils.Labels, 0, len(servers.Items))
+	for i := range servers.Items {
+		ms = servers.Items[i].appendTargetLabels(ms, servers.ID, port)
+	}
+	return ms
+}
+
+func (s *Server) appendTargetLabels(ms []*promutils.Labels, serversID string, port int) []*promutils.Labels {
+	var ips []string
+	for _, nic := range s.Entities.NICs.Items {
+		ips = append(ips, nic.Properties.IPs...)
+	}
+	if len(ips) == 0 {
+		// Cannot scrape server without IP address
+		return ms
+	}
+
+	p := &s.Properties
+	m := promutils.NewLabels(16)
+	m.Add("__address__", discoveryutils.JoinHostPort(ips[0], port))
+	m.Add("__meta_ionos_server_availability_zone", p.AvailabilityZone)
+	m.Add("__meta_ionos_server_cpu_family", p.CPUFamily)
+	m.Add("__meta_ionos_server_id", s.ID)
+	m.Add("__meta_ionos_server_lifecycle", s.Metadata.State)
+	m.Add("__meta_ionos_server_name", p.Name)
+	m.Add("__meta_ionos_server_servers_id", serversID)
+	m.Add("__meta_ionos_server_state", p.VMState)
+	m.Add("__meta_ionos_server_type", p.Type)
+	if p.BootCDROM != nil {
+		m.Add("__meta_ionos_server_boot_cdrom_id", p.BootCDROM.ID)
+	}
+	if p.BootVolume != nil {
+		m.Add("__meta_ionos_server_boot_volume_id", p.BootVolume.ID)
+		for _, v := range s.Entities.Volumes.Items {
+			if v.ID == p.BootVolume.ID && v.Properties.Image != "" {
+				m.Add("__meta_ionos_server_boot_image_id", v.Properties.Image)
+				break
+			}
+		}
+	}
+
+	// We surround the separated lists with the separator as well. This way regular expressions
+	// in relabeling rules don't have to consider ip positions.
+	m.Add("__meta_ionos_server_ip", ","+strings.Join(ips, ",")+",")
+	for _, nic := range s.Entities.NICs.Items {
+		if len(nic.Properties.IPs) == 0 {
+			continue
+		}
+		name := discoveryutils.SanitizeLabelName("__meta_ionos_server_nic_ip_" + nic.Properties.Name)
+		m.Add(name, ","+strings.Join(nic.Properties.IPs, ",")+",")
+	}
+	ms = append(ms, m)
+	return ms
+}
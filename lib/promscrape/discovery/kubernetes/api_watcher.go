@@ -465,6 +465,10 @@ func (gw *groupWatcher) startWatchersForRole(role string, aw *apiWatcher) {
 	if gw.attachNodeMetadata && (role == "pod" || role == "endpoints" || role == "endpointslice") {
 		gw.startWatchersForRole("node", nil)
 	}
+	if role == "httproute" {
+		// httproute watchers query gateway objects for obtaining listeners and addresses.
+		gw.startWatchersForRole("gateway", nil)
+	}
 
 	paths := getAPIPathsWithNamespaces(role, gw.namespaces, gw.selectors)
 	for _, path := range paths {
@@ -483,8 +487,8 @@ func (gw *groupWatcher) startWatchersForRole(role string, aw *apiWatcher) {
 		if needStart {
 			uw.reloadObjects()
 			go uw.watchForUpdates()
-			if role == "endpoints" || role == "endpointslice" || (gw.attachNodeMetadata && role == "pod") {
-				// Refresh targets in background, since they depend on other object types such as pod, service or node.
+			if role == "endpoints" || role == "endpointslice" || role == "httproute" || (gw.attachNodeMetadata && role == "pod") {
+				// Refresh targets in background, since they depend on other object types such as pod, service, node or gateway.
 				// This should guarantee that the ScrapeWork objects for these objects are properly updated
 				// as soon as the objects they depend on are updated.
 				// This should fix https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1240 .
@@ -948,7 +952,7 @@ func (uw *urlWatcher) removeObjectLocked(key string) {
 func (uw *urlWatcher) maybeUpdateDependedScrapeWorksLocked() {
 	role := uw.role
 	attachNodeMetadata := uw.gw.attachNodeMetadata
-	if !(role == "pod" || role == "service" || (attachNodeMetadata && role == "node")) {
+	if !(role == "pod" || role == "service" || role == "gateway" || (attachNodeMetadata && role == "node")) {
 		// Nothing to update
 		return
 	}
@@ -963,6 +967,11 @@ func (uw *urlWatcher) maybeUpdateDependedScrapeWorksLocked() {
 			uwx.needRecreateScrapeWorks = true
 			continue
 		}
+		if role == "gateway" && uwx.role == "httproute" {
+			// httproute objects depend on gateway objects
+			uwx.needRecreateScrapeWorks = true
+			continue
+		}
 		if attachNodeMetadata && role == "node" && (uwx.role == "pod" || uwx.role == "endpoints" || uwx.role == "endpointslice") {
 			// pod, endpoints and enpointslices objects depend on node objects if attachNodeMetadata is set
 			uwx.needRecreateScrapeWorks = true
@@ -1032,6 +1041,9 @@ func getAPIPath(objectType, namespace, query string) string {
 	if objectType == "endpointslices" {
 		return "/apis/discovery.k8s.io/v1/" + suffix
 	}
+	if objectType == "gateways" || objectType == "httproutes" {
+		return "/apis/gateway.networking.k8s.io/v1/" + suffix
+	}
 	return "/api/v1/" + suffix
 }
 
@@ -1072,6 +1084,10 @@ func getObjectTypeByRole(role string) string {
 		return "endpointslices"
 	case "ingress":
 		return "ingresses"
+	case "gateway":
+		return "gateways"
+	case "httproute":
+		return "httproutes"
 	default:
 		logger.Panicf("BUG: unknonw role=%q", role)
 		return ""
@@ -1092,6 +1108,10 @@ func getObjectParsersForRole(role string) (parseObjectFunc, parseObjectListFunc)
 		return parseEndpointSlice, parseEndpointSliceList
 	case "ingress":
 		return parseIngress, parseIngressList
+	case "gateway":
+		return parseGateway, parseGatewayList
+	case "httproute":
+		return parseHTTPRoute, parseHTTPRouteList
 	default:
 		logger.Panicf("BUG: unsupported role=%q", role)
 		return nil, nil
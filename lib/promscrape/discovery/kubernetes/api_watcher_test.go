@@ -166,6 +166,24 @@ func TestGetAPIPathsWithNamespaces(t *testing.T) {
 		"/apis/networking.k8s.io/v1/namespaces/x/ingresses?labelSelector=cde%2Cbaaa&fieldSelector=abc",
 		"/apis/networking.k8s.io/v1/namespaces/y/ingresses?labelSelector=cde%2Cbaaa&fieldSelector=abc",
 	})
+
+	// role=gateway
+	f("gateway", nil, nil, []string{"/apis/gateway.networking.k8s.io/v1/gateways"})
+	f("gateway", []string{"x"}, []Selector{
+		{
+			Role:  "gateway",
+			Label: "foo=bar",
+		},
+	}, []string{
+		"/apis/gateway.networking.k8s.io/v1/namespaces/x/gateways?labelSelector=foo%3Dbar",
+	})
+
+	// role=httproute
+	f("httproute", nil, nil, []string{"/apis/gateway.networking.k8s.io/v1/httproutes"})
+	f("httproute", []string{"x", "y"}, nil, []string{
+		"/apis/gateway.networking.k8s.io/v1/namespaces/x/httproutes",
+		"/apis/gateway.networking.k8s.io/v1/namespaces/y/httproutes",
+	})
 }
 
 func TestParseBookmark(t *testing.T) {
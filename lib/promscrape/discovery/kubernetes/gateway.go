@@ -0,0 +1,137 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func (g *Gateway) key() string {
+	return g.Metadata.key()
+}
+
+func parseGatewayList(r io.Reader) (map[string]object, ListMeta, error) {
+	var gl GatewayList
+	d := json.NewDecoder(r)
+	if err := d.Decode(&gl); err != nil {
+		return nil, gl.Metadata, fmt.Errorf("cannot unmarshal GatewayList: %w", err)
+	}
+	objectsByKey := make(map[string]object)
+	for _, g := range gl.Items {
+		objectsByKey[g.key()] = g
+	}
+	return objectsByKey, gl.Metadata, nil
+}
+
+func parseGateway(data []byte) (object, error) {
+	var g Gateway
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GatewayList represents Gateway API gateway list in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#gateway
+type GatewayList struct {
+	Metadata ListMeta
+	Items    []*Gateway
+}
+
+// Gateway represents Gateway API gateway in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#gateway
+type Gateway struct {
+	Metadata ObjectMeta
+	Spec     GatewaySpec
+	Status   GatewayStatus
+}
+
+// GatewaySpec represents Gateway API gateway spec in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#gatewayspec
+type GatewaySpec struct {
+	GatewayClassName string
+	Listeners        []GatewayListener
+}
+
+// GatewayListener represents Gateway API listener in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#listener
+type GatewayListener struct {
+	Name     string
+	Hostname string
+	Port     int
+	Protocol string
+}
+
+// GatewayStatus represents Gateway API gateway status in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#gatewaystatus
+type GatewayStatus struct {
+	Addresses []GatewayStatusAddress
+}
+
+// GatewayStatusAddress represents Gateway API gateway status address in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#gatewaystatusaddress
+type GatewayStatusAddress struct {
+	Type  string
+	Value string
+}
+
+// getTargetLabels returns labels for every listener of g.
+func (g *Gateway) getTargetLabels(_ *groupWatcher) []*promutils.Labels {
+	address := g.getAddress()
+	var ms []*promutils.Labels
+	for _, l := range g.Spec.Listeners {
+		host := l.Hostname
+		if host == "" || strings.HasPrefix(host, "*.") {
+			// Empty or wildcard hostname cannot be scraped, so fall back to gateway address
+			host = address
+		}
+		if host == "" {
+			// Cannot scrape listener without hostname and gateway address
+			continue
+		}
+		m := promutils.GetLabels()
+		m.Add("__address__", discoveryutils.JoinHostPort(host, l.Port))
+		m.Add("__meta_kubernetes_namespace", g.Metadata.Namespace)
+		m.Add("__meta_kubernetes_gateway_name", g.Metadata.Name)
+		m.Add("__meta_kubernetes_gateway_class_name", g.Spec.GatewayClassName)
+		m.Add("__meta_kubernetes_gateway_address", address)
+		m.Add("__meta_kubernetes_gateway_scheme", getSchemeForListenerProtocol(l.Protocol))
+		m.Add("__meta_kubernetes_gateway_listener_name", l.Name)
+		m.Add("__meta_kubernetes_gateway_listener_protocol", l.Protocol)
+		m.Add("__meta_kubernetes_gateway_listener_port", strconv.Itoa(l.Port))
+		m.Add("__meta_kubernetes_gateway_listener_hostname", l.Hostname)
+		g.Metadata.registerLabelsAndAnnotations("__meta_kubernetes_gateway", m)
+		ms = append(ms, m)
+	}
+	return ms
+}
+
+// getAddress returns the first address assigned to g or an empty string if g has no addresses yet.
+func (g *Gateway) getAddress() string {
+	for _, a := range g.Status.Addresses {
+		if a.Value != "" {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func getSchemeForListenerProtocol(protocol string) string {
+	switch protocol {
+	case "HTTPS", "TLS":
+		return "https"
+	default:
+		return "http"
+	}
+}
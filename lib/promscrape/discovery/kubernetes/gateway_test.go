@@ -0,0 +1,151 @@
+package kubernetes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestParseGatewayListFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		r := bytes.NewBufferString(s)
+		objectsByKey, _, err := parseGatewayList(r)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if len(objectsByKey) != 0 {
+			t.Fatalf("unexpected non-empty GatewayList: %v", objectsByKey)
+		}
+	}
+	f(``)
+	f(`[1,23]`)
+	f(`{"items":[{"metadata":1}]}`)
+	f(`{"items":[{"spec":{"listeners":[{"port":"foo"}]}}]}`)
+}
+
+func TestParseGatewayListSuccess(t *testing.T) {
+	data := `
+{
+  "kind": "GatewayList",
+  "apiVersion": "gateway.networking.k8s.io/v1",
+  "metadata": {
+    "resourceVersion": "12345"
+  },
+  "items": [
+    {
+      "metadata": {
+        "name": "prod-web",
+        "namespace": "infra",
+        "labels": {
+          "team": "platform"
+        }
+      },
+      "spec": {
+        "gatewayClassName": "example",
+        "listeners": [
+          {
+            "name": "http",
+            "protocol": "HTTP",
+            "port": 80
+          },
+          {
+            "name": "https",
+            "protocol": "HTTPS",
+            "port": 443,
+            "hostname": "foo.example.com"
+          },
+          {
+            "name": "wildcard",
+            "protocol": "HTTPS",
+            "port": 8443,
+            "hostname": "*.example.com"
+          }
+        ]
+      },
+      "status": {
+        "addresses": [
+          {
+            "type": "IPAddress",
+            "value": "10.1.2.3"
+          }
+        ]
+      }
+    },
+    {
+      "metadata": {
+        "name": "pending",
+        "namespace": "infra"
+      },
+      "spec": {
+        "gatewayClassName": "example",
+        "listeners": [
+          {
+            "name": "http",
+            "protocol": "HTTP",
+            "port": 80
+          }
+        ]
+      }
+    }
+  ]
+}`
+	r := bytes.NewBufferString(data)
+	objectsByKey, meta, err := parseGatewayList(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedResourceVersion := "12345"
+	if meta.ResourceVersion != expectedResourceVersion {
+		t.Fatalf("unexpected resource version; got %s; want %s", meta.ResourceVersion, expectedResourceVersion)
+	}
+	sortedLabelss := getSortedLabelss(objectsByKey)
+	expectedLabelss := []*promutils.Labels{
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                                 "10.1.2.3:80",
+			"__meta_kubernetes_gateway_address":           "10.1.2.3",
+			"__meta_kubernetes_gateway_class_name":        "example",
+			"__meta_kubernetes_gateway_label_team":        "platform",
+			"__meta_kubernetes_gateway_labelpresent_team": "true",
+			"__meta_kubernetes_gateway_listener_hostname": "",
+			"__meta_kubernetes_gateway_listener_name":     "http",
+			"__meta_kubernetes_gateway_listener_port":     "80",
+			"__meta_kubernetes_gateway_listener_protocol": "HTTP",
+			"__meta_kubernetes_gateway_name":              "prod-web",
+			"__meta_kubernetes_gateway_scheme":            "http",
+			"__meta_kubernetes_namespace":                 "infra",
+		}),
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                                 "foo.example.com:443",
+			"__meta_kubernetes_gateway_address":           "10.1.2.3",
+			"__meta_kubernetes_gateway_class_name":        "example",
+			"__meta_kubernetes_gateway_label_team":        "platform",
+			"__meta_kubernetes_gateway_labelpresent_team": "true",
+			"__meta_kubernetes_gateway_listener_hostname": "foo.example.com",
+			"__meta_kubernetes_gateway_listener_name":     "https",
+			"__meta_kubernetes_gateway_listener_port":     "443",
+			"__meta_kubernetes_gateway_listener_protocol": "HTTPS",
+			"__meta_kubernetes_gateway_name":              "prod-web",
+			"__meta_kubernetes_gateway_scheme":            "https",
+			"__meta_kubernetes_namespace":                 "infra",
+		}),
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                                 "10.1.2.3:8443",
+			"__meta_kubernetes_gateway_address":           "10.1.2.3",
+			"__meta_kubernetes_gateway_class_name":        "example",
+			"__meta_kubernetes_gateway_label_team":        "platform",
+			"__meta_kubernetes_gateway_labelpresent_team": "true",
+			"__meta_kubernetes_gateway_listener_hostname": "*.example.com",
+			"__meta_kubernetes_gateway_listener_name":     "wildcard",
+			"__meta_kubernetes_gateway_listener_port":     "8443",
+			"__meta_kubernetes_gateway_listener_protocol": "HTTPS",
+			"__meta_kubernetes_gateway_name":              "prod-web",
+			"__meta_kubernetes_gateway_scheme":            "https",
+			"__meta_kubernetes_namespace":                 "infra",
+		}),
+	}
+	if !areEqualLabelss(sortedLabelss, expectedLabelss) {
+		t.Fatalf("unexpected labels:\ngot\n%v\nwant\n%v", sortedLabelss, expectedLabelss)
+	}
+}
@@ -0,0 +1,233 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func (hr *HTTPRoute) key() string {
+	return hr.Metadata.key()
+}
+
+func parseHTTPRouteList(r io.Reader) (map[string]object, ListMeta, error) {
+	var hrl HTTPRouteList
+	d := json.NewDecoder(r)
+	if err := d.Decode(&hrl); err != nil {
+		return nil, hrl.Metadata, fmt.Errorf("cannot unmarshal HTTPRouteList: %w", err)
+	}
+	objectsByKey := make(map[string]object)
+	for _, hr := range hrl.Items {
+		objectsByKey[hr.key()] = hr
+	}
+	return objectsByKey, hrl.Metadata, nil
+}
+
+func parseHTTPRoute(data []byte) (object, error) {
+	var hr HTTPRoute
+	if err := json.Unmarshal(data, &hr); err != nil {
+		return nil, err
+	}
+	return &hr, nil
+}
+
+// HTTPRouteList represents Gateway API HTTPRoute list in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#httproute
+type HTTPRouteList struct {
+	Metadata ListMeta
+	Items    []*HTTPRoute
+}
+
+// HTTPRoute represents Gateway API HTTPRoute in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#httproute
+type HTTPRoute struct {
+	Metadata ObjectMeta
+	Spec     HTTPRouteSpec
+}
+
+// HTTPRouteSpec represents Gateway API HTTPRoute spec in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#httproutespec
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference
+	Hostnames  []string
+	Rules      []HTTPRouteRule
+}
+
+// ParentReference represents Gateway API parent reference in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#parentreference
+type ParentReference struct {
+	Group       *string
+	Kind        *string
+	Namespace   string
+	Name        string
+	SectionName string
+	Port        int
+}
+
+// HTTPRouteRule represents Gateway API HTTPRoute rule in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#httprouterule
+type HTTPRouteRule struct {
+	Matches []HTTPRouteMatch
+}
+
+// HTTPRouteMatch represents Gateway API HTTPRoute match in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#httproutematch
+type HTTPRouteMatch struct {
+	Path *HTTPPathMatch
+}
+
+// HTTPPathMatch represents Gateway API HTTP path match in k8s.
+//
+// See https://gateway-api.sigs.k8s.io/reference/spec/#httppathmatch
+type HTTPPathMatch struct {
+	Type  string
+	Value string
+}
+
+// getTargetLabels returns labels for hr.
+//
+// A target is generated per every (parent gateway, hostname, path) tuple.
+func (hr *HTTPRoute) getTargetLabels(gw *groupWatcher) []*promutils.Labels {
+	paths := hr.getPaths()
+	var ms []*promutils.Labels
+	for _, ref := range hr.Spec.ParentRefs {
+		if !ref.isGateway() {
+			continue
+		}
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = hr.Metadata.Namespace
+		}
+		o := gw.getObjectByRoleLocked("gateway", namespace, ref.Name)
+		if o == nil {
+			// The gateway isn't discovered yet. The route will be re-processed when the gateway appears.
+			continue
+		}
+		g := o.(*Gateway)
+		listeners := ref.getMatchingListeners(g)
+		if len(listeners) == 0 {
+			continue
+		}
+		for _, host := range hr.getHostnames(g, listeners) {
+			l := getListenerForHost(listeners, host)
+			scheme := getSchemeForListenerProtocol(l.Protocol)
+			address := host
+			if !isDefaultPortForScheme(scheme, l.Port) {
+				address = discoveryutils.JoinHostPort(host, l.Port)
+			}
+			for _, p := range paths {
+				m := promutils.GetLabels()
+				m.Add("__address__", address)
+				m.Add("__meta_kubernetes_namespace", hr.Metadata.Namespace)
+				m.Add("__meta_kubernetes_httproute_name", hr.Metadata.Name)
+				m.Add("__meta_kubernetes_httproute_scheme", scheme)
+				m.Add("__meta_kubernetes_httproute_hostname", host)
+				m.Add("__meta_kubernetes_httproute_path", p.Value)
+				m.Add("__meta_kubernetes_httproute_path_type", p.Type)
+				m.Add("__meta_kubernetes_httproute_gateway_name", g.Metadata.Name)
+				m.Add("__meta_kubernetes_httproute_gateway_namespace", g.Metadata.Namespace)
+				m.Add("__meta_kubernetes_httproute_listener_name", l.Name)
+				hr.Metadata.registerLabelsAndAnnotations("__meta_kubernetes_httproute", m)
+				ms = append(ms, m)
+			}
+		}
+	}
+	return ms
+}
+
+func (ref *ParentReference) isGateway() bool {
+	if ref.Group != nil && *ref.Group != "gateway.networking.k8s.io" {
+		return false
+	}
+	if ref.Kind != nil && *ref.Kind != "Gateway" {
+		return false
+	}
+	return true
+}
+
+// getMatchingListeners returns listeners of g, which may be attached to by ref.
+func (ref *ParentReference) getMatchingListeners(g *Gateway) []*GatewayListener {
+	var result []*GatewayListener
+	for i := range g.Spec.Listeners {
+		l := &g.Spec.Listeners[i]
+		if l.Protocol != "HTTP" && l.Protocol != "HTTPS" {
+			continue
+		}
+		if ref.SectionName != "" && ref.SectionName != l.Name {
+			continue
+		}
+		if ref.Port != 0 && ref.Port != l.Port {
+			continue
+		}
+		result = append(result, l)
+	}
+	return result
+}
+
+// getHostnames returns hostnames to scrape for hr attached to the given listeners of g.
+//
+// Route hostnames take precedence over listener hostnames, while the gateway address is used as the last resort.
+func (hr *HTTPRoute) getHostnames(g *Gateway, listeners []*GatewayListener) []string {
+	if len(hr.Spec.Hostnames) > 0 {
+		return hr.Spec.Hostnames
+	}
+	var hosts []string
+	for _, l := range listeners {
+		if l.Hostname != "" && !strings.HasPrefix(l.Hostname, "*.") {
+			hosts = append(hosts, l.Hostname)
+		}
+	}
+	if len(hosts) > 0 {
+		return hosts
+	}
+	if address := g.getAddress(); address != "" {
+		return []string{address}
+	}
+	return nil
+}
+
+func getListenerForHost(listeners []*GatewayListener, host string) *GatewayListener {
+	for _, l := range listeners {
+		if l.Hostname == "" || matchesHostPattern(l.Hostname, host) {
+			return l
+		}
+	}
+	return listeners[0]
+}
+
+func isDefaultPortForScheme(scheme string, port int) bool {
+	return (scheme == "http" && port == 80) || (scheme == "https" && port == 443)
+}
+
+func (hr *HTTPRoute) getPaths() []HTTPPathMatch {
+	var result []HTTPPathMatch
+	for _, r := range hr.Spec.Rules {
+		for _, m := range r.Matches {
+			if m.Path == nil || m.Path.Value == "" {
+				continue
+			}
+			p := *m.Path
+			if p.Type == "" {
+				p.Type = "PathPrefix"
+			}
+			result = append(result, p)
+		}
+	}
+	if len(result) == 0 {
+		return []HTTPPathMatch{{
+			Type:  "PathPrefix",
+			Value: "/",
+		}}
+	}
+	return result
+}
@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestParseHTTPRouteListFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		r := bytes.NewBufferString(s)
+		objectsByKey, _, err := parseHTTPRouteList(r)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if len(objectsByKey) != 0 {
+			t.Fatalf("unexpected non-empty HTTPRouteList: %v", objectsByKey)
+		}
+	}
+	f(``)
+	f(`[1,23]`)
+	f(`{"items":[{"metadata":1}]}`)
+	f(`{"items":[{"spec":{"hostnames":"foo"}}]}`)
+}
+
+func TestHTTPRouteGetTargetLabels(t *testing.T) {
+	f := func(data string, expectedLabelss []*promutils.Labels) {
+		t.Helper()
+
+		r := bytes.NewBufferString(data)
+		objectsByKey, _, err := parseHTTPRouteList(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var gw groupWatcher
+		gw.m = map[string]*urlWatcher{
+			"gateway": {
+				role: "gateway",
+				objectsByKey: map[string]object{
+					"infra/prod-web": &Gateway{
+						Metadata: ObjectMeta{
+							Name:      "prod-web",
+							Namespace: "infra",
+						},
+						Spec: GatewaySpec{
+							GatewayClassName: "example",
+							Listeners: []GatewayListener{
+								{
+									Name:     "http",
+									Protocol: "HTTP",
+									Port:     8080,
+								},
+								{
+									Name:     "https",
+									Protocol: "HTTPS",
+									Port:     443,
+									Hostname: "*.example.com",
+								},
+							},
+						},
+						Status: GatewayStatus{
+							Addresses: []GatewayStatusAddress{
+								{
+									Type:  "IPAddress",
+									Value: "10.1.2.3",
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		var sortedLabelss []*promutils.Labels
+		for _, o := range objectsByKey {
+			for _, labels := range o.getTargetLabels(&gw) {
+				labels.Sort()
+				sortedLabelss = append(sortedLabelss, labels)
+			}
+		}
+		if !areEqualLabelss(sortedLabelss, expectedLabelss) {
+			t.Fatalf("unexpected labels:\ngot\n%v\nwant\n%v", sortedLabelss, expectedLabelss)
+		}
+	}
+
+	// missing gateway
+	f(`{"items":[{"metadata":{"name":"r","namespace":"default"},"spec":{"parentRefs":[{"name":"missing"}]}}]}`, nil)
+
+	// non-gateway parent
+	f(`{"items":[{"metadata":{"name":"r","namespace":"infra"},"spec":{"parentRefs":[{"kind":"Service","name":"prod-web"}]}}]}`, nil)
+
+	// hostnames and paths from the route, sectionName selects https listener
+	f(`
+{
+  "items": [
+    {
+      "metadata": {
+        "name": "store",
+        "namespace": "default",
+        "annotations": {
+          "owner": "shop"
+        }
+      },
+      "spec": {
+        "parentRefs": [
+          {
+            "name": "prod-web",
+            "namespace": "infra",
+            "sectionName": "https"
+          }
+        ],
+        "hostnames": ["store.example.com"],
+        "rules": [
+          {
+            "matches": [
+              {"path": {"type": "Exact", "value": "/metrics"}},
+              {"headers": [{"name": "foo", "value": "bar"}]}
+            ]
+          }
+        ]
+      }
+    }
+  ]
+}`, []*promutils.Labels{
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__": "store.example.com",
+			"__meta_kubernetes_httproute_annotation_owner":        "shop",
+			"__meta_kubernetes_httproute_annotationpresent_owner": "true",
+			"__meta_kubernetes_httproute_gateway_name":            "prod-web",
+			"__meta_kubernetes_httproute_gateway_namespace":       "infra",
+			"__meta_kubernetes_httproute_hostname":                "store.example.com",
+			"__meta_kubernetes_httproute_listener_name":           "https",
+			"__meta_kubernetes_httproute_name":                    "store",
+			"__meta_kubernetes_httproute_path":                    "/metrics",
+			"__meta_kubernetes_httproute_path_type":               "Exact",
+			"__meta_kubernetes_httproute_scheme":                  "https",
+			"__meta_kubernetes_namespace":                         "default",
+		}),
+	})
+
+	// no hostnames - fall back to gateway address for http listener with non-default port
+	f(`
+{
+  "items": [
+    {
+      "metadata": {
+        "name": "api",
+        "namespace": "infra"
+      },
+      "spec": {
+        "parentRefs": [
+          {
+            "name": "prod-web",
+            "port": 8080
+          }
+        ]
+      }
+    }
+  ]
+}`, []*promutils.Labels{
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__": "10.1.2.3:8080",
+			"__meta_kubernetes_httproute_gateway_name":      "prod-web",
+			"__meta_kubernetes_httproute_gateway_namespace": "infra",
+			"__meta_kubernetes_httproute_hostname":          "10.1.2.3",
+			"__meta_kubernetes_httproute_listener_name":     "http",
+			"__meta_kubernetes_httproute_name":              "api",
+			"__meta_kubernetes_httproute_path":              "/",
+			"__meta_kubernetes_httproute_path_type":         "PathPrefix",
+			"__meta_kubernetes_httproute_scheme":            "http",
+			"__meta_kubernetes_namespace":                   "infra",
+		}),
+	})
+}
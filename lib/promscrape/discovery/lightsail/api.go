@@ -0,0 +1,63 @@
+package lightsail
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/awsapi"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+type apiConfig struct {
+	awsConfig *awsapi.Config
+	apiURL    string
+	port      int
+}
+
+var configMap = discoveryutils.NewConfigMap()
+
+func getAPIConfig(sdc *SDConfig) (*apiConfig, error) {
+	v, err := configMap.Get(sdc, func() (any, error) { return newAPIConfig(sdc) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*apiConfig), nil
+}
+
+func newAPIConfig(sdc *SDConfig) (*apiConfig, error) {
+	port := 80
+	if sdc.Port != nil {
+		port = *sdc.Port
+	}
+	stsEndpoint := sdc.STSEndpoint
+	if stsEndpoint == "" {
+		stsEndpoint = sdc.Endpoint
+	}
+	awsCfg, err := awsapi.NewConfig("", stsEndpoint, sdc.Region, sdc.RoleARN, sdc.AccessKey, sdc.SecretKey.String(), "lightsail")
+	if err != nil {
+		return nil, err
+	}
+	cfg := &apiConfig{
+		awsConfig: awsCfg,
+		apiURL:    buildAPIURL(sdc.Endpoint, awsCfg.GetRegion()),
+		port:      port,
+	}
+	return cfg, nil
+}
+
+// buildAPIURL returns Lightsail API url for the given endpoint and region.
+//
+// See https://docs.aws.amazon.com/general/latest/gr/lightsail.html
+func buildAPIURL(endpoint, region string) string {
+	if endpoint == "" {
+		return fmt.Sprintf("https://lightsail.%s.amazonaws.com/", region)
+	}
+	// endpoint may contain only hostname. Convert it to proper url then.
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+	if !strings.HasSuffix(endpoint, "/") {
+		endpoint += "/"
+	}
+	return endpoint
+}
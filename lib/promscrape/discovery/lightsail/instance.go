@@ -0,0 +1,134 @@
+package lightsail
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// InstancesResponse represents response to GetInstances Lightsail API call.
+//
+// See https://docs.aws.amazon.com/lightsail/2016-11-28/api-reference/API_GetInstances.html
+type InstancesResponse struct {
+	Instances     []Instance `json:"instances"`
+	NextPageToken string     `json:"nextPageToken"`
+}
+
+// Instance represents Lightsail instance.
+//
+// See https://docs.aws.amazon.com/lightsail/2016-11-28/api-reference/API_Instance.html
+type Instance struct {
+	Name             string        `json:"name"`
+	BlueprintID      string        `json:"blueprintId"`
+	BundleID         string        `json:"bundleId"`
+	SupportCode      string        `json:"supportCode"`
+	PrivateIPAddress string        `json:"privateIpAddress"`
+	PublicIPAddress  string        `json:"publicIpAddress"`
+	IPv6Addresses    []string      `json:"ipv6Addresses"`
+	Location         Location      `json:"location"`
+	State            InstanceState `json:"state"`
+	Tags             []Tag         `json:"tags"`
+}
+
+// Location represents ResourceLocation from https://docs.aws.amazon.com/lightsail/2016-11-28/api-reference/API_ResourceLocation.html
+type Location struct {
+	AvailabilityZone string `json:"availabilityZone"`
+	RegionName       string `json:"regionName"`
+}
+
+// InstanceState represents InstanceState from https://docs.aws.amazon.com/lightsail/2016-11-28/api-reference/API_InstanceState.html
+type InstanceState struct {
+	Name string `json:"name"`
+}
+
+// Tag represents Tag from https://docs.aws.amazon.com/lightsail/2016-11-28/api-reference/API_Tag.html
+type Tag struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func getInstances(cfg *apiConfig) ([]Instance, error) {
+	// See https://docs.aws.amazon.com/lightsail/2016-11-28/api-reference/API_GetInstances.html
+	var instances []Instance
+	pageToken := ""
+	for {
+		body := []byte("{}")
+		if pageToken != "" {
+			req := map[string]string{
+				"pageToken": pageToken,
+			}
+			data, err := json.Marshal(req)
+			if err != nil {
+				logger.Panicf("BUG: cannot marshal GetInstances request: %s", err)
+			}
+			body = data
+		}
+		data, err := cfg.awsConfig.GetJSONAPIResponse(cfg.apiURL, "Lightsail_20161128.GetInstances", body)
+		if err != nil {
+			return nil, fmt.Errorf("cannot obtain instances: %w", err)
+		}
+		ir, err := parseInstancesResponse(data)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, ir.Instances...)
+		if len(ir.NextPageToken) == 0 {
+			return instances, nil
+		}
+		pageToken = ir.NextPageToken
+	}
+}
+
+func parseInstancesResponse(data []byte) (*InstancesResponse, error) {
+	var v InstancesResponse
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal InstancesResponse from %q: %w", data, err)
+	}
+	return &v, nil
+}
+
+func getInstancesLabels(instances []Instance, region string, port int) []*promutils.Labels {
+	ms := make([]*promutils.Labels, 0, len(instances))
+	for i := range instances {
+		ms = instances[i].appendTargetLabels(ms, region, port)
+	}
+	return ms
+}
+
+func (inst *Instance) appendTargetLabels(ms []*promutils.Labels, region string, port int) []*promutils.Labels {
+	if len(inst.PrivateIPAddress) == 0 {
+		// Cannot scrape instance without private IP address
+		return ms
+	}
+	addr := discoveryutils.JoinHostPort(inst.PrivateIPAddress, port)
+	m := promutils.NewLabels(12)
+	m.Add("__address__", addr)
+	m.Add("__meta_lightsail_availability_zone", inst.Location.AvailabilityZone)
+	m.Add("__meta_lightsail_blueprint_id", inst.BlueprintID)
+	m.Add("__meta_lightsail_bundle_id", inst.BundleID)
+	m.Add("__meta_lightsail_instance_name", inst.Name)
+	m.Add("__meta_lightsail_instance_state", inst.State.Name)
+	m.Add("__meta_lightsail_instance_support_code", inst.SupportCode)
+	m.Add("__meta_lightsail_private_ip", inst.PrivateIPAddress)
+	m.Add("__meta_lightsail_region", region)
+	if len(inst.PublicIPAddress) > 0 {
+		m.Add("__meta_lightsail_public_ip", inst.PublicIPAddress)
+	}
+	if len(inst.IPv6Addresses) > 0 {
+		// We surround the separated list with the separator as well. This way regular expressions
+		// in relabeling rules don't have to consider ipv6 address positions.
+		m.Add("__meta_lightsail_ipv6_addresses", ","+strings.Join(inst.IPv6Addresses, ",")+",")
+	}
+	for _, t := range inst.Tags {
+		if len(t.Key) == 0 || len(t.Value) == 0 {
+			continue
+		}
+		m.Add(discoveryutils.SanitizeLabelName("__meta_lightsail_tag_"+t.Key), t.Value)
+	}
+	ms = append(ms, m)
+	return ms
+}
@@ -0,0 +1,122 @@
+package lightsail
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/awsapi"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestGetInstancesLabels(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "unexpected method", http.StatusBadRequest)
+			return
+		}
+		if target := r.Header.Get("X-Amz-Target"); target != "Lightsail_20161128.GetInstances" {
+			http.Error(w, "unexpected target", http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "{}" {
+			w.Write([]byte(`{
+  "instances": [
+    {
+      "name": "foo",
+      "blueprintId": "ubuntu_22_04",
+      "bundleId": "nano_3_0",
+      "supportCode": "123/i-abc",
+      "privateIpAddress": "172.26.1.2",
+      "publicIpAddress": "3.4.5.6",
+      "ipv6Addresses": ["2a05:d018::1", "2a05:d018::2"],
+      "location": {"availabilityZone": "eu-west-1a", "regionName": "eu-west-1"},
+      "state": {"code": 16, "name": "running"},
+      "tags": [{"key": "env", "value": "prod"}, {"key": "empty"}]
+    }
+  ],
+  "nextPageToken": "page2"
+}`))
+			return
+		}
+		w.Write([]byte(`{
+  "instances": [
+    {
+      "name": "bar",
+      "blueprintId": "debian_12",
+      "bundleId": "micro_3_0",
+      "supportCode": "123/i-def",
+      "privateIpAddress": "172.26.1.3",
+      "location": {"availabilityZone": "eu-west-1b", "regionName": "eu-west-1"},
+      "state": {"code": 80, "name": "stopped"}
+    },
+    {
+      "name": "without-private-ip",
+      "state": {"code": 0, "name": "pending"}
+    }
+  ]
+}`))
+	}))
+	defer s.Close()
+
+	awsCfg, err := awsapi.NewConfig("", "", "eu-west-1", "", "access-key", "secret-key", "lightsail")
+	if err != nil {
+		t.Fatalf("cannot create AWS config: %s", err)
+	}
+	cfg := &apiConfig{
+		awsConfig: awsCfg,
+		apiURL:    buildAPIURL(s.URL, awsCfg.GetRegion()),
+		port:      9100,
+	}
+	instances, err := getInstances(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	labelss := getInstancesLabels(instances, awsCfg.GetRegion(), cfg.port)
+	expectedLabels := []*promutils.Labels{
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                            "172.26.1.2:9100",
+			"__meta_lightsail_availability_zone":     "eu-west-1a",
+			"__meta_lightsail_blueprint_id":          "ubuntu_22_04",
+			"__meta_lightsail_bundle_id":             "nano_3_0",
+			"__meta_lightsail_instance_name":         "foo",
+			"__meta_lightsail_instance_state":        "running",
+			"__meta_lightsail_instance_support_code": "123/i-abc",
+			"__meta_lightsail_ipv6_addresses":        ",2a05:d018::1,2a05:d018::2,",
+			"__meta_lightsail_private_ip":            "172.26.1.2",
+			"__meta_lightsail_public_ip":             "3.4.5.6",
+			"__meta_lightsail_region":                "eu-west-1",
+			"__meta_lightsail_tag_env":               "prod",
+		}),
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                            "172.26.1.3:9100",
+			"__meta_lightsail_availability_zone":     "eu-west-1b",
+			"__meta_lightsail_blueprint_id":          "debian_12",
+			"__meta_lightsail_bundle_id":             "micro_3_0",
+			"__meta_lightsail_instance_name":         "bar",
+			"__meta_lightsail_instance_state":        "stopped",
+			"__meta_lightsail_instance_support_code": "123/i-def",
+			"__meta_lightsail_private_ip":            "172.26.1.3",
+			"__meta_lightsail_region":                "eu-west-1",
+		}),
+	}
+	discoveryutils.TestEqualLabelss(t, labelss, expectedLabels)
+}
+
+func TestBuildAPIURL(t *testing.T) {
+	f := func(endpoint, region, resultExpected string) {
+		t.Helper()
+
+		result := buildAPIURL(endpoint, region)
+		if result != resultExpected {
+			t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+		}
+	}
+
+	f("", "us-east-1", "https://lightsail.us-east-1.amazonaws.com/")
+	f("lightsail.example.com", "us-east-1", "https://lightsail.example.com/")
+	f("http://127.0.0.1:8080", "us-east-1", "http://127.0.0.1:8080/")
+}
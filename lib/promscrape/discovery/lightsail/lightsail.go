@@ -0,0 +1,47 @@
+package lightsail
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// SDCheckInterval defines interval for targets refresh.
+var SDCheckInterval = flag.Duration("promscrape.lightsailSDCheckInterval", time.Minute, "Interval for checking for changes in Lightsail. "+
+	"This works only if lightsail_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://docs.victoriametrics.com/sd_configs/#lightsail_sd_configs for details")
+
+// SDConfig represents service discovery config for Amazon Lightsail.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#lightsail_sd_config
+type SDConfig struct {
+	Region      string           `yaml:"region,omitempty"`
+	Endpoint    string           `yaml:"endpoint,omitempty"`
+	STSEndpoint string           `yaml:"sts_endpoint,omitempty"`
+	AccessKey   string           `yaml:"access_key,omitempty"`
+	SecretKey   *promauth.Secret `yaml:"secret_key,omitempty"`
+	RoleARN     string           `yaml:"role_arn,omitempty"`
+	// refresh_interval is obtained from `-promscrape.lightsailSDCheckInterval` command-line option.
+	Port *int `yaml:"port,omitempty"`
+}
+
+// GetLabels returns Lightsail labels according to sdc.
+func (sdc *SDConfig) GetLabels(_ string) ([]*promutils.Labels, error) {
+	cfg, err := getAPIConfig(sdc)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	instances, err := getInstances(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error when fetching instances data from Lightsail: %w", err)
+	}
+	return getInstancesLabels(instances, cfg.awsConfig.GetRegion(), cfg.port), nil
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	configMap.Delete(sdc)
+}
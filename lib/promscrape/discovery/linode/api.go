@@ -0,0 +1,207 @@
+package linode
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+type apiConfig struct {
+	c            *discoveryutils.Client
+	port         int
+	tagSeparator string
+
+	// filter contains optional value for X-Filter header.
+	// See https://techdocs.akamai.com/linode-api/reference/filtering-and-sorting
+	filter string
+}
+
+func getAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	v, err := configMap.Get(sdc, func() (any, error) { return newAPIConfig(sdc, baseDir) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*apiConfig), nil
+}
+
+func newAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	port := 80
+	if sdc.Port != nil {
+		port = *sdc.Port
+	}
+	tagSeparator := ","
+	if sdc.TagSeparator != "" {
+		tagSeparator = sdc.TagSeparator
+	}
+
+	// See https://techdocs.akamai.com/linode-api/reference/api
+	apiServer := "https://api.linode.com"
+
+	ac, err := sdc.HTTPClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse auth config: %w", err)
+	}
+	proxyAC, err := sdc.ProxyClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse proxy auth config: %w", err)
+	}
+	c, err := discoveryutils.NewClient(apiServer, ac, sdc.ProxyURL, proxyAC, &sdc.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client for %q: %w", apiServer, err)
+	}
+	cfg := &apiConfig{
+		c:            c,
+		port:         port,
+		tagSeparator: tagSeparator,
+	}
+	if sdc.Region != "" {
+		filter, err := json.Marshal(map[string]string{
+			"region": sdc.Region,
+		})
+		if err != nil {
+			logger.Panicf("BUG: cannot marshal Linode region filter: %s", err)
+		}
+		cfg.filter = string(filter)
+	}
+	return cfg, nil
+}
+
+// pagedResponse represents a single page of Linode API list response.
+//
+// See https://techdocs.akamai.com/linode-api/reference/pagination
+type pagedResponse struct {
+	Data  json.RawMessage `json:"data"`
+	Page  int             `json:"page"`
+	Pages int             `json:"pages"`
+}
+
+// getPagedData calls f for every page of data obtained from Linode API list endpoint at the given path.
+func getPagedData(cfg *apiConfig, path, filter string, f func(data []byte) error) error {
+	page := 1
+	for {
+		pagePath := fmt.Sprintf("%s?page=%d&page_size=500", path, page)
+		data, err := cfg.c.GetAPIResponseWithReqParams(pagePath, func(req *http.Request) {
+			if filter != "" {
+				req.Header.Set("X-Filter", filter)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("cannot get Linode response from %q: %w", pagePath, err)
+		}
+		var resp pagedResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return fmt.Errorf("cannot unmarshal Linode response from %q: %w; response=%q", pagePath, err, data)
+		}
+		if err := f(resp.Data); err != nil {
+			return fmt.Errorf("cannot parse Linode response from %q: %w", pagePath, err)
+		}
+		if resp.Page >= resp.Pages {
+			return nil
+		}
+		page++
+	}
+}
+
+// Instance represents Linode instance.
+//
+// See https://techdocs.akamai.com/linode-api/reference/get-linode-instances
+type Instance struct {
+	ID         int      `json:"id"`
+	Label      string   `json:"label"`
+	Image      string   `json:"image"`
+	Region     string   `json:"region"`
+	Type       string   `json:"type"`
+	Status     string   `json:"status"`
+	Group      string   `json:"group"`
+	Hypervisor string   `json:"hypervisor"`
+	IPv4       []string `json:"ipv4"`
+	IPv6       string   `json:"ipv6"`
+	Tags       []string `json:"tags"`
+	Backups    Backups  `json:"backups"`
+	Specs      Specs    `json:"specs"`
+}
+
+// Backups represents backups info for Linode instance.
+type Backups struct {
+	Enabled bool `json:"enabled"`
+}
+
+// Specs represents Linode instance specs.
+//
+// Disk, Memory and Transfer are measured in MiB.
+type Specs struct {
+	Disk     int64 `json:"disk"`
+	Memory   int64 `json:"memory"`
+	VCPUs    int   `json:"vcpus"`
+	GPUs     int   `json:"gpus"`
+	Transfer int64 `json:"transfer"`
+}
+
+// IPAddress represents Linode IP address.
+//
+// See https://techdocs.akamai.com/linode-api/reference/get-ips
+type IPAddress struct {
+	Address string `json:"address"`
+	Public  bool   `json:"public"`
+	RDNS    string `json:"rdns"`
+}
+
+// IPv6Range represents Linode IPv6 range.
+//
+// See https://techdocs.akamai.com/linode-api/reference/get-ipv6-ranges
+type IPv6Range struct {
+	Range       string `json:"range"`
+	Prefix      int    `json:"prefix"`
+	RouteTarget string `json:"route_target"`
+}
+
+func getInstances(cfg *apiConfig) ([]Instance, error) {
+	var instances []Instance
+	err := getPagedData(cfg, "/v4/linode/instances", cfg.filter, func(data []byte) error {
+		var a []Instance
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		instances = append(instances, a...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return instances, nil
+}
+
+func getIPAddresses(cfg *apiConfig) ([]IPAddress, error) {
+	var ips []IPAddress
+	err := getPagedData(cfg, "/v4/networking/ips", cfg.filter, func(data []byte) error {
+		var a []IPAddress
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		ips = append(ips, a...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ips, nil
+}
+
+func getIPv6Ranges(cfg *apiConfig) ([]IPv6Range, error) {
+	var ranges []IPv6Range
+	err := getPagedData(cfg, "/v4/networking/ipv6/ranges", "", func(data []byte) error {
+		var a []IPv6Range
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		ranges = append(ranges, a...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
@@ -0,0 +1,112 @@
+package linode
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func getInstancesLabels(instances []Instance, ips []IPAddress, ipv6Ranges []IPv6Range, port int, tagSeparator string) []*promutils.Labels {
+	ipsByAddr := make(map[string]*IPAddress, len(ips))
+	for i := range ips {
+		ipsByAddr[ips[i].Address] = &ips[i]
+	}
+	ms := make([]*promutils.Labels, 0, len(instances))
+	for i := range instances {
+		ms = instances[i].appendTargetLabels(ms, ipsByAddr, ipv6Ranges, port, tagSeparator)
+	}
+	return ms
+}
+
+func (inst *Instance) appendTargetLabels(ms []*promutils.Labels, ipsByAddr map[string]*IPAddress, ipv6Ranges []IPv6Range, port int, tagSeparator string) []*promutils.Labels {
+	if len(inst.IPv4) == 0 {
+		// Cannot scrape instance without IPv4 address
+		return ms
+	}
+
+	var publicIPv4, publicIPv4RDNS, privateIPv4, privateIPv4RDNS string
+	var extraIPs []string
+	for _, addr := range inst.IPv4 {
+		ip := ipsByAddr[addr]
+		if ip == nil {
+			continue
+		}
+		switch {
+		case ip.Public && publicIPv4 == "":
+			publicIPv4 = ip.Address
+			publicIPv4RDNS = getRDNS(ip)
+		case !ip.Public && privateIPv4 == "":
+			privateIPv4 = ip.Address
+			privateIPv4RDNS = getRDNS(ip)
+		default:
+			extraIPs = append(extraIPs, ip.Address)
+		}
+	}
+
+	var publicIPv6, publicIPv6RDNS string
+	var ranges []string
+	if inst.IPv6 != "" {
+		slaac, _, _ := strings.Cut(inst.IPv6, "/")
+		if ip := ipsByAddr[slaac]; ip != nil {
+			publicIPv6 = ip.Address
+			publicIPv6RDNS = getRDNS(ip)
+		}
+		for _, r := range ipv6Ranges {
+			if r.RouteTarget == slaac {
+				ranges = append(ranges, fmt.Sprintf("%s/%d", r.Range, r.Prefix))
+			}
+		}
+	}
+
+	backups := "disabled"
+	if inst.Backups.Enabled {
+		backups = "enabled"
+	}
+
+	m := promutils.NewLabels(24)
+	m.Add("__address__", discoveryutils.JoinHostPort(publicIPv4, port))
+	m.Add("__meta_linode_instance_id", strconv.Itoa(inst.ID))
+	m.Add("__meta_linode_instance_label", inst.Label)
+	m.Add("__meta_linode_image", inst.Image)
+	m.Add("__meta_linode_private_ipv4", privateIPv4)
+	m.Add("__meta_linode_public_ipv4", publicIPv4)
+	m.Add("__meta_linode_public_ipv6", publicIPv6)
+	m.Add("__meta_linode_private_ipv4_rdns", privateIPv4RDNS)
+	m.Add("__meta_linode_public_ipv4_rdns", publicIPv4RDNS)
+	m.Add("__meta_linode_public_ipv6_rdns", publicIPv6RDNS)
+	m.Add("__meta_linode_region", inst.Region)
+	m.Add("__meta_linode_type", inst.Type)
+	m.Add("__meta_linode_status", inst.Status)
+	m.Add("__meta_linode_group", inst.Group)
+	m.Add("__meta_linode_gpus", strconv.Itoa(inst.Specs.GPUs))
+	m.Add("__meta_linode_hypervisor", inst.Hypervisor)
+	m.Add("__meta_linode_backups", backups)
+	m.Add("__meta_linode_specs_disk_bytes", strconv.FormatInt(inst.Specs.Disk<<20, 10))
+	m.Add("__meta_linode_specs_memory_bytes", strconv.FormatInt(inst.Specs.Memory<<20, 10))
+	m.Add("__meta_linode_specs_vcpus", strconv.Itoa(inst.Specs.VCPUs))
+	m.Add("__meta_linode_specs_transfer_bytes", strconv.FormatInt(inst.Specs.Transfer<<20, 10))
+
+	// We surround the separated lists with the separator as well. This way regular expressions
+	// in relabeling rules don't have to consider list item positions.
+	if len(extraIPs) > 0 {
+		m.Add("__meta_linode_extra_ips", tagSeparator+strings.Join(extraIPs, tagSeparator)+tagSeparator)
+	}
+	if len(ranges) > 0 {
+		m.Add("__meta_linode_ipv6_ranges", tagSeparator+strings.Join(ranges, tagSeparator)+tagSeparator)
+	}
+	if len(inst.Tags) > 0 {
+		m.Add("__meta_linode_tags", tagSeparator+strings.Join(inst.Tags, tagSeparator)+tagSeparator)
+	}
+	ms = append(ms, m)
+	return ms
+}
+
+func getRDNS(ip *IPAddress) string {
+	if ip.RDNS == "null" {
+		return ""
+	}
+	return ip.RDNS
+}
@@ -0,0 +1,63 @@
+package linode
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
+)
+
+// SDCheckInterval defines interval for Linode targets refresh.
+var SDCheckInterval = flag.Duration("promscrape.linodeSDCheckInterval", time.Minute, "Interval for checking for changes in Linode. "+
+	"This works only if linode_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://docs.victoriametrics.com/sd_configs/#linode_sd_configs for details")
+
+// SDConfig represents service discovery config for Linode.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#linode_sd_config
+type SDConfig struct {
+	Region       string `yaml:"region,omitempty"`
+	Port         *int   `yaml:"port,omitempty"`
+	TagSeparator string `yaml:"tag_separator,omitempty"`
+
+	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
+	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
+	// refresh_interval is obtained from `-promscrape.linodeSDCheckInterval` command-line option.
+}
+
+var configMap = discoveryutils.NewConfigMap()
+
+// GetLabels returns Linode labels according to sdc.
+func (sdc *SDConfig) GetLabels(baseDir string) ([]*promutils.Labels, error) {
+	cfg, err := getAPIConfig(sdc, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	instances, err := getInstances(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := getIPAddresses(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ipv6Ranges, err := getIPv6Ranges(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return getInstancesLabels(instances, ips, ipv6Ranges, cfg.port, cfg.tagSeparator), nil
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	v := configMap.Delete(sdc)
+	if v != nil {
+		cfg := v.(*apiConfig)
+		cfg.c.Stop()
+	}
+}
@@ -0,0 +1,157 @@
+package linode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func newMockLinodeServer(t *testing.T) *httptest.Server {
+	responses := map[string]string{
+		"/v4/linode/instances?page=1&page_size=500": `{"page": 1, "pages": 2, "results": 2, "data": [{
+  "id": 26838044,
+  "label": "prometheus-linode-sd-exporter-1",
+  "group": "",
+  "status": "running",
+  "type": "g6-standard-2",
+  "ipv4": ["45.33.82.151", "96.126.108.16", "192.168.170.51", "192.168.201.25"],
+  "ipv6": "2600:3c03::f03c:92ff:fe1a:1382/128",
+  "image": "linode/arch",
+  "region": "us-east",
+  "specs": {"disk": 81920, "memory": 4096, "vcpus": 2, "gpus": 0, "transfer": 4000},
+  "hypervisor": "kvm",
+  "backups": {"enabled": false},
+  "tags": ["monitoring"]
+}]}`,
+		"/v4/linode/instances?page=2&page_size=500": `{"page": 2, "pages": 2, "results": 2, "data": [{
+  "id": 26837938,
+  "label": "prometheus-linode-sd-exporter-2",
+  "group": "",
+  "status": "running",
+  "type": "g6-nanode-1",
+  "ipv4": ["139.162.196.43"],
+  "ipv6": "2a01:7e01::f03c:92ff:fe1a:9976/128",
+  "image": "linode/ubuntu20.04",
+  "region": "eu-central",
+  "specs": {"disk": 25600, "memory": 1024, "vcpus": 1, "gpus": 0, "transfer": 1000},
+  "hypervisor": "kvm",
+  "backups": {"enabled": true},
+  "tags": []
+}, {
+  "id": 1,
+  "label": "without-ipv4",
+  "ipv4": []
+}]}`,
+		"/v4/networking/ips?page=1&page_size=500": `{"page": 1, "pages": 1, "results": 7, "data": [
+  {"address": "45.33.82.151", "public": true, "rdns": "li1028-151.members.linode.com", "linode_id": 26838044, "type": "ipv4"},
+  {"address": "96.126.108.16", "public": true, "rdns": "li365-16.members.linode.com", "linode_id": 26838044, "type": "ipv4"},
+  {"address": "192.168.170.51", "public": false, "rdns": null, "linode_id": 26838044, "type": "ipv4"},
+  {"address": "192.168.201.25", "public": false, "rdns": null, "linode_id": 26838044, "type": "ipv4"},
+  {"address": "2600:3c03::f03c:92ff:fe1a:1382", "public": true, "rdns": null, "linode_id": 26838044, "type": "ipv6"},
+  {"address": "139.162.196.43", "public": true, "rdns": "li1359-43.members.linode.com", "linode_id": 26837938, "type": "ipv4"},
+  {"address": "2a01:7e01::f03c:92ff:fe1a:9976", "public": true, "rdns": null, "linode_id": 26837938, "type": "ipv6"}
+]}`,
+		"/v4/networking/ipv6/ranges?page=1&page_size=500": `{"page": 1, "pages": 1, "results": 1, "data": [
+  {"range": "2600:3c03:e000:123::", "prefix": 64, "region": "us-east", "route_target": "2600:3c03::f03c:92ff:fe1a:1382"}
+]}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if filter := r.Header.Get("X-Filter"); filter != "" && filter != `{"region":"us-east"}` {
+			t.Errorf("unexpected X-Filter header: %q", filter)
+		}
+		resp, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+}
+
+func TestGetInstancesLabels(t *testing.T) {
+	s := newMockLinodeServer(t)
+	defer s.Close()
+
+	c, err := discoveryutils.NewClient(s.URL, nil, nil, nil, &promauth.HTTPClientConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error when creating http client: %s", err)
+	}
+	defer c.Stop()
+	cfg := &apiConfig{
+		c:            c,
+		port:         9100,
+		tagSeparator: ",",
+		filter:       `{"region":"us-east"}`,
+	}
+
+	instances, err := getInstances(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error in getInstances(): %s", err)
+	}
+	ips, err := getIPAddresses(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error in getIPAddresses(): %s", err)
+	}
+	ipv6Ranges, err := getIPv6Ranges(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error in getIPv6Ranges(): %s", err)
+	}
+	labelss := getInstancesLabels(instances, ips, ipv6Ranges, cfg.port, cfg.tagSeparator)
+
+	expectedLabels := []*promutils.Labels{
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                        "45.33.82.151:9100",
+			"__meta_linode_instance_id":          "26838044",
+			"__meta_linode_instance_label":       "prometheus-linode-sd-exporter-1",
+			"__meta_linode_image":                "linode/arch",
+			"__meta_linode_private_ipv4":         "192.168.170.51",
+			"__meta_linode_public_ipv4":          "45.33.82.151",
+			"__meta_linode_public_ipv6":          "2600:3c03::f03c:92ff:fe1a:1382",
+			"__meta_linode_private_ipv4_rdns":    "",
+			"__meta_linode_public_ipv4_rdns":     "li1028-151.members.linode.com",
+			"__meta_linode_public_ipv6_rdns":     "",
+			"__meta_linode_region":               "us-east",
+			"__meta_linode_type":                 "g6-standard-2",
+			"__meta_linode_status":               "running",
+			"__meta_linode_group":                "",
+			"__meta_linode_gpus":                 "0",
+			"__meta_linode_hypervisor":           "kvm",
+			"__meta_linode_backups":              "disabled",
+			"__meta_linode_specs_disk_bytes":     "85899345920",
+			"__meta_linode_specs_memory_bytes":   "4294967296",
+			"__meta_linode_specs_vcpus":          "2",
+			"__meta_linode_specs_transfer_bytes": "4194304000",
+			"__meta_linode_extra_ips":            ",96.126.108.16,192.168.201.25,",
+			"__meta_linode_ipv6_ranges":          ",2600:3c03:e000:123::/64,",
+			"__meta_linode_tags":                 ",monitoring,",
+		}),
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                        "139.162.196.43:9100",
+			"__meta_linode_instance_id":          "26837938",
+			"__meta_linode_instance_label":       "prometheus-linode-sd-exporter-2",
+			"__meta_linode_image":                "linode/ubuntu20.04",
+			"__meta_linode_private_ipv4":         "",
+			"__meta_linode_public_ipv4":          "139.162.196.43",
+			"__meta_linode_public_ipv6":          "2a01:7e01::f03c:92ff:fe1a:9976",
+			"__meta_linode_private_ipv4_rdns":    "",
+			"__meta_linode_public_ipv4_rdns":     "li1359-43.members.linode.com",
+			"__meta_linode_public_ipv6_rdns":     "",
+			"__meta_linode_region":               "eu-central",
+			"__meta_linode_type":                 "g6-nanode-1",
+			"__meta_linode_status":               "running",
+			"__meta_linode_group":                "",
+			"__meta_linode_gpus":                 "0",
+			"__meta_linode_hypervisor":           "kvm",
+			"__meta_linode_backups":              "enabled",
+			"__meta_linode_specs_disk_bytes":     "26843545600",
+			"__meta_linode_specs_memory_bytes":   "1073741824",
+			"__meta_linode_specs_vcpus":          "1",
+			"__meta_linode_specs_transfer_bytes": "1048576000",
+		}),
+	}
+	discoveryutils.TestEqualLabelss(t, labelss, expectedLabels)
+}
@@ -0,0 +1,118 @@
+package puppetdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+type apiConfig struct {
+	client            *discoveryutils.Client
+	path              string
+	query             string
+	includeParameters bool
+	port              int
+}
+
+func getAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	v, err := configMap.Get(sdc, func() (any, error) { return newAPIConfig(sdc, baseDir) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*apiConfig), nil
+}
+
+func newAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	if sdc.URL == "" {
+		return nil, fmt.Errorf("missing `url` option")
+	}
+	if sdc.Query == "" {
+		return nil, fmt.Errorf("missing `query` option")
+	}
+	u, err := url.Parse(sdc.URL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `url` %q: %w", sdc.URL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme in `url` %q; supported schemes: http, https", sdc.URL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host in `url` %q", sdc.URL)
+	}
+	port := 80
+	if sdc.Port != nil {
+		port = *sdc.Port
+	}
+
+	ac, err := sdc.HTTPClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse auth config: %w", err)
+	}
+	proxyAC, err := sdc.ProxyClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse proxy auth config: %w", err)
+	}
+	apiServer := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+	client, err := discoveryutils.NewClient(apiServer, ac, sdc.ProxyURL, proxyAC, &sdc.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create HTTP client for %q: %w", apiServer, err)
+	}
+	cfg := &apiConfig{
+		client:            client,
+		path:              path.Join("/", u.Path, "/pdb/query/v4"),
+		query:             sdc.Query,
+		includeParameters: sdc.IncludeParameters,
+		port:              port,
+	}
+	return cfg, nil
+}
+
+// resource represents PuppetDB resource.
+//
+// See https://www.puppet.com/docs/puppetdb/8/api/query/v4/resources.html
+type resource struct {
+	Certname    string         `json:"certname"`
+	Resource    string         `json:"resource"`
+	Type        string         `json:"type"`
+	Title       string         `json:"title"`
+	Exported    bool           `json:"exported"`
+	Tags        []string       `json:"tags"`
+	File        string         `json:"file"`
+	Environment string         `json:"environment"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+func getResources(cfg *apiConfig) ([]resource, error) {
+	// See https://www.puppet.com/docs/puppetdb/8/api/query/v4/overview.html
+	requestBody, err := json.Marshal(map[string]string{
+		"query": cfg.query,
+	})
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal PuppetDB query: %s", err)
+	}
+	data, err := cfg.client.GetAPIResponseWithReqParams(cfg.path, func(req *http.Request) {
+		req.Method = http.MethodPost
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot query PuppetDB: %w", err)
+	}
+	return parseResources(data)
+}
+
+func parseResources(data []byte) ([]resource, error) {
+	var resources []resource
+	if err := json.Unmarshal(data, &resources); err != nil {
+		return nil, fmt.Errorf("cannot parse PuppetDB response %q: %w", data, err)
+	}
+	return resources, nil
+}
@@ -0,0 +1,56 @@
+package puppetdb
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
+)
+
+// SDCheckInterval defines interval for PuppetDB targets refresh.
+var SDCheckInterval = flag.Duration("promscrape.puppetdbSDCheckInterval", 60*time.Second, "Interval for checking for changes in PuppetDB API. "+
+	"This works only if puppetdb_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://docs.victoriametrics.com/sd_configs/#puppetdb_sd_configs for details")
+
+// SDConfig represents service discovery config for PuppetDB.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#puppetdb_sd_config
+type SDConfig struct {
+	URL               string `yaml:"url"`
+	Query             string `yaml:"query"`
+	IncludeParameters bool   `yaml:"include_parameters,omitempty"`
+	Port              *int   `yaml:"port,omitempty"`
+
+	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
+	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
+	// refresh_interval is obtained from `-promscrape.puppetdbSDCheckInterval` command-line option.
+}
+
+var configMap = discoveryutils.NewConfigMap()
+
+// GetLabels returns PuppetDB labels according to sdc.
+func (sdc *SDConfig) GetLabels(baseDir string) ([]*promutils.Labels, error) {
+	cfg, err := getAPIConfig(sdc, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	resources, err := getResources(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return getResourcesLabels(resources, cfg), nil
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	v := configMap.Delete(sdc)
+	if v != nil {
+		cfg := v.(*apiConfig)
+		cfg.client.Stop()
+	}
+}
@@ -0,0 +1,127 @@
+package puppetdb
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func newMockPuppetDBServer(t *testing.T, query string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/puppetdb/pdb/query/v4" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		var req map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("cannot parse request body: %s", err)
+		}
+		if req["query"] != query {
+			t.Errorf("unexpected query; got %q; want %q", req["query"], query)
+		}
+		w.Write([]byte(`[
+  {
+    "certname": "edinburgh.example.com",
+    "environment": "prod",
+    "exported": false,
+    "file": "/etc/puppetlabs/code/environments/prod/modules/upstream/apache/manifests/init.pp",
+    "parameters": {
+      "access_log": true,
+      "access_log_file": "ssl_access_log",
+      "port": 443,
+      "docroot": "/var/www/html",
+      "empty": "",
+      "labels": {"alias": "edinburgh", "weight": 1.5},
+      "options": ["Indexes", "FollowSymLinks"],
+      "null": null
+    },
+    "resource": "49af83866dc5a1518968b68e58a25319107afe11",
+    "tags": ["roles::hypervisor", "apache", "class"],
+    "title": "Apache",
+    "type": "Class"
+  }
+]`))
+	}))
+}
+
+func TestGetLabels(t *testing.T) {
+	const query = `resources { type = "Class" and title = "Apache" }`
+	s := newMockPuppetDBServer(t, query)
+	defer s.Close()
+
+	f := func(includeParameters bool, expectedLabels []*promutils.Labels) {
+		t.Helper()
+
+		port := 9100
+		sdc := &SDConfig{
+			URL:               s.URL + "/puppetdb",
+			Query:             query,
+			IncludeParameters: includeParameters,
+			Port:              &port,
+		}
+		defer sdc.MustStop()
+
+		labelss, err := sdc.GetLabels("")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		discoveryutils.TestEqualLabelss(t, labelss, expectedLabels)
+	}
+
+	commonLabels := map[string]string{
+		"__address__":                 "edinburgh.example.com:9100",
+		"__meta_puppetdb_query":       query,
+		"__meta_puppetdb_certname":    "edinburgh.example.com",
+		"__meta_puppetdb_environment": "prod",
+		"__meta_puppetdb_exported":    "false",
+		"__meta_puppetdb_file":        "/etc/puppetlabs/code/environments/prod/modules/upstream/apache/manifests/init.pp",
+		"__meta_puppetdb_resource":    "49af83866dc5a1518968b68e58a25319107afe11",
+		"__meta_puppetdb_tags":        ",roles::hypervisor,apache,class,",
+		"__meta_puppetdb_title":       "Apache",
+		"__meta_puppetdb_type":        "Class",
+	}
+	f(false, []*promutils.Labels{
+		promutils.NewLabelsFromMap(commonLabels),
+	})
+
+	m := promutils.NewLabelsFromMap(commonLabels)
+	m.Add("__meta_puppetdb_parameter_access_log", "true")
+	m.Add("__meta_puppetdb_parameter_access_log_file", "ssl_access_log")
+	m.Add("__meta_puppetdb_parameter_docroot", "/var/www/html")
+	m.Add("__meta_puppetdb_parameter_labels_alias", "edinburgh")
+	m.Add("__meta_puppetdb_parameter_labels_weight", "1.5")
+	m.Add("__meta_puppetdb_parameter_options", "Indexes,FollowSymLinks")
+	m.Add("__meta_puppetdb_parameter_port", "443")
+	m.Sort()
+	f(true, []*promutils.Labels{m})
+}
+
+func TestNewAPIConfigFailure(t *testing.T) {
+	f := func(sdc *SDConfig) {
+		t.Helper()
+
+		if _, err := newAPIConfig(sdc, ""); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// missing url
+	f(&SDConfig{
+		Query: "resources {}",
+	})
+
+	// missing query
+	f(&SDConfig{
+		URL: "http://puppetdb:8080",
+	})
+
+	// unsupported scheme
+	f(&SDConfig{
+		URL:   "ftp://puppetdb:8080",
+		Query: "resources {}",
+	})
+}
@@ -0,0 +1,86 @@
+package puppetdb
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func getResourcesLabels(resources []resource, cfg *apiConfig) []*promutils.Labels {
+	ms := make([]*promutils.Labels, 0, len(resources))
+	for i := range resources {
+		ms = append(ms, resources[i].getLabels(cfg))
+	}
+	return ms
+}
+
+func (r *resource) getLabels(cfg *apiConfig) *promutils.Labels {
+	m := promutils.NewLabels(10)
+	m.Add("__address__", discoveryutils.JoinHostPort(r.Certname, cfg.port))
+	m.Add("__meta_puppetdb_query", cfg.query)
+	m.Add("__meta_puppetdb_certname", r.Certname)
+	m.Add("__meta_puppetdb_resource", r.Resource)
+	m.Add("__meta_puppetdb_type", r.Type)
+	m.Add("__meta_puppetdb_title", r.Title)
+	m.Add("__meta_puppetdb_exported", strconv.FormatBool(r.Exported))
+	m.Add("__meta_puppetdb_file", r.File)
+	m.Add("__meta_puppetdb_environment", r.Environment)
+	if len(r.Tags) > 0 {
+		// We surround the separated list with the separator as well. This way regular expressions
+		// in relabeling rules don't have to consider tag positions.
+		m.Add("__meta_puppetdb_tags", ","+strings.Join(r.Tags, ",")+",")
+	}
+	if cfg.includeParameters {
+		addParametersLabels(m, "__meta_puppetdb_parameter_", r.Parameters)
+	}
+	return m
+}
+
+// addParametersLabels adds labels for the given resource parameters to m.
+//
+// Nested parameters are flattened into `<prefix><name>_<subname>` labels.
+// Parameters with unsupported types are skipped.
+func addParametersLabels(m *promutils.Labels, prefix string, parameters map[string]any) {
+	keys := make([]string, 0, len(parameters))
+	for k := range parameters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := discoveryutils.SanitizeLabelName(prefix + k)
+		switch v := parameters[k].(type) {
+		case map[string]any:
+			addParametersLabels(m, name+"_", v)
+		case []any:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				if s, ok := formatParameterValue(item); ok {
+					values = append(values, s)
+				}
+			}
+			if len(values) > 0 {
+				m.Add(name, strings.Join(values, ","))
+			}
+		default:
+			if s, ok := formatParameterValue(v); ok && s != "" {
+				m.Add(name, s)
+			}
+		}
+	}
+}
+
+func formatParameterValue(v any) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case bool:
+		return strconv.FormatBool(t), true
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,172 @@
+package scaleway
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs/fscore"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+)
+
+type apiConfig struct {
+	c    *discoveryutils.Client
+	role string
+	zone string
+	port int
+
+	// listQueryParams contains filters for the list servers API call.
+	listQueryParams url.Values
+
+	secretKey     string
+	secretKeyFile string
+}
+
+func getAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	v, err := configMap.Get(sdc, func() (any, error) { return newAPIConfig(sdc, baseDir) })
+	if err != nil {
+		return nil, err
+	}
+	return v.(*apiConfig), nil
+}
+
+func newAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
+	switch sdc.Role {
+	case "instance", "baremetal":
+	case "":
+		return nil, fmt.Errorf("missing `role` option; supported values: instance, baremetal")
+	default:
+		return nil, fmt.Errorf("unexpected `role`: %q; supported values: instance, baremetal", sdc.Role)
+	}
+	if sdc.ProjectID == "" {
+		return nil, fmt.Errorf("missing `project_id` option")
+	}
+	if sdc.AccessKey == "" {
+		return nil, fmt.Errorf("missing `access_key` option")
+	}
+	if sdc.SecretKey == nil && sdc.SecretKeyFile == "" {
+		return nil, fmt.Errorf("missing `secret_key` or `secret_key_file` option")
+	}
+	if sdc.SecretKey != nil && sdc.SecretKeyFile != "" {
+		return nil, fmt.Errorf("at most one of `secret_key` and `secret_key_file` options must be set")
+	}
+	zone := sdc.Zone
+	if zone == "" {
+		zone = "fr-par-1"
+	}
+	port := 80
+	if sdc.Port != nil {
+		port = *sdc.Port
+	}
+
+	// See https://www.scaleway.com/en/developers/api/
+	apiServer := sdc.APIURL
+	if apiServer == "" {
+		apiServer = "https://api.scaleway.com"
+	}
+	apiServer = strings.TrimSuffix(apiServer, "/")
+
+	ac, err := sdc.HTTPClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse auth config: %w", err)
+	}
+	proxyAC, err := sdc.ProxyClientConfig.NewConfig(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse proxy auth config: %w", err)
+	}
+	c, err := discoveryutils.NewClient(apiServer, ac, sdc.ProxyURL, proxyAC, &sdc.HTTPClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client for %q: %w", apiServer, err)
+	}
+
+	qp := url.Values{}
+	if sdc.Role == "instance" {
+		qp.Set("project", sdc.ProjectID)
+	} else {
+		qp.Set("project_id", sdc.ProjectID)
+	}
+	if sdc.NameFilter != "" {
+		qp.Set("name", sdc.NameFilter)
+	}
+	if len(sdc.TagsFilter) > 0 {
+		qp.Set("tags", strings.Join(sdc.TagsFilter, ","))
+	}
+
+	cfg := &apiConfig{
+		c:    c,
+		role: sdc.Role,
+		zone: zone,
+		port: port,
+
+		listQueryParams: qp,
+	}
+	if sdc.SecretKeyFile != "" {
+		cfg.secretKeyFile = fscore.GetFilepath(baseDir, sdc.SecretKeyFile)
+	} else {
+		cfg.secretKey = sdc.SecretKey.String()
+	}
+	return cfg, nil
+}
+
+// getAPIResponse returns response for the given Scaleway API path.
+//
+// See https://www.scaleway.com/en/developers/api/#authentication
+func (cfg *apiConfig) getAPIResponse(path string) ([]byte, error) {
+	secretKey := cfg.secretKey
+	if cfg.secretKeyFile != "" {
+		s, err := fscore.ReadPasswordFromFileOrHTTP(cfg.secretKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read `secret_key_file`: %w", err)
+		}
+		secretKey = s
+	}
+	return cfg.c.GetAPIResponseWithReqParams(path, func(req *http.Request) {
+		req.Header.Set("X-Auth-Token", secretKey)
+	})
+}
+
+// getPagedServers calls f for every page of servers obtained from Scaleway list servers API at the given path.
+//
+// f must return the number of servers at the page.
+func getPagedServers(cfg *apiConfig, path string, f func(data []byte) (int, error)) error {
+	const perPage = 100
+	qp := url.Values{}
+	for k, v := range cfg.listQueryParams {
+		qp[k] = v
+	}
+	qp.Set("per_page", strconv.Itoa(perPage))
+	for page := 1; ; page++ {
+		qp.Set("page", strconv.Itoa(page))
+		pagePath := path + "?" + qp.Encode()
+		data, err := cfg.getAPIResponse(pagePath)
+		if err != nil {
+			return fmt.Errorf("cannot get Scaleway response from %q: %w", pagePath, err)
+		}
+		n, err := f(data)
+		if err != nil {
+			return fmt.Errorf("cannot parse Scaleway response from %q: %w; response=%q", pagePath, err, data)
+		}
+		if n < perPage {
+			return nil
+		}
+	}
+}
+
+// getRegion returns region for the given zone.
+//
+// See https://www.scaleway.com/en/developers/api/#regions-and-zones
+func getRegion(zone string) string {
+	n := strings.LastIndexByte(zone, '-')
+	if n < 0 {
+		return zone
+	}
+	return zone[:n]
+}
+
+func joinTags(tags []string) string {
+	// We surround the separated list with the separator as well. This way regular expressions
+	// in relabeling rules don't have to consider tag positions.
+	return "," + strings.Join(tags, ",") + ","
+}
@@ -0,0 +1,147 @@
+package scaleway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// BaremetalServer represents Scaleway baremetal server.
+//
+// See https://www.scaleway.com/en/developers/api/elastic-metal/#path-elastic-metal-servers-list-elastic-metal-servers-for-an-organization
+type BaremetalServer struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	ProjectID string            `json:"project_id"`
+	Status    string            `json:"status"`
+	OfferName string            `json:"offer_name"`
+	Zone      string            `json:"zone"`
+	Tags      []string          `json:"tags"`
+	IPs       []BaremetalIP     `json:"ips"`
+	Install   *BaremetalInstall `json:"install"`
+}
+
+// BaremetalIP represents IP address for Scaleway baremetal server.
+type BaremetalIP struct {
+	Address string `json:"address"`
+	Version string `json:"version"`
+}
+
+// BaremetalInstall represents install info for Scaleway baremetal server.
+type BaremetalInstall struct {
+	OSID string `json:"os_id"`
+}
+
+// BaremetalOS represents Scaleway baremetal OS.
+//
+// See https://www.scaleway.com/en/developers/api/elastic-metal/#path-operating-systems-get-an-os-with-an-id
+type BaremetalOS struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+func getBaremetalServers(cfg *apiConfig) ([]BaremetalServer, error) {
+	var servers []BaremetalServer
+	path := "/baremetal/v1/zones/" + cfg.zone + "/servers"
+	err := getPagedServers(cfg, path, func(data []byte) (int, error) {
+		var resp struct {
+			Servers []BaremetalServer `json:"servers"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return 0, err
+		}
+		servers = append(servers, resp.Servers...)
+		return len(resp.Servers), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+func getBaremetalOS(cfg *apiConfig, osID string) (*BaremetalOS, error) {
+	path := "/baremetal/v1/zones/" + cfg.zone + "/os/" + url.PathEscape(osID)
+	data, err := cfg.getAPIResponse(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get Scaleway response from %q: %w", path, err)
+	}
+	var osInfo BaremetalOS
+	if err := json.Unmarshal(data, &osInfo); err != nil {
+		return nil, fmt.Errorf("cannot parse Scaleway response from %q: %w; response=%q", path, err, data)
+	}
+	return &osInfo, nil
+}
+
+func getBaremetalServersLabels(cfg *apiConfig, servers []BaremetalServer) ([]*promutils.Labels, error) {
+	osByID := make(map[string]*BaremetalOS)
+	ms := make([]*promutils.Labels, 0, len(servers))
+	for i := range servers {
+		s := &servers[i]
+		var osInfo *BaremetalOS
+		if s.Install != nil && s.Install.OSID != "" {
+			osInfo = osByID[s.Install.OSID]
+			if osInfo == nil {
+				v, err := getBaremetalOS(cfg, s.Install.OSID)
+				if err != nil {
+					return nil, err
+				}
+				osInfo = v
+				osByID[s.Install.OSID] = osInfo
+			}
+		}
+		ms = s.appendTargetLabels(ms, osInfo, cfg.port)
+	}
+	return ms, nil
+}
+
+func (s *BaremetalServer) appendTargetLabels(ms []*promutils.Labels, osInfo *BaremetalOS, port int) []*promutils.Labels {
+	var ipv4, ipv6 string
+	for _, ip := range s.IPs {
+		switch ip.Version {
+		case "IPv4":
+			if ipv4 == "" {
+				ipv4 = ip.Address
+			}
+		case "IPv6":
+			if ipv6 == "" {
+				ipv6 = ip.Address
+			}
+		}
+	}
+	// Prefer IPv4 address over IPv6 address for scraping.
+	addr := ipv4
+	if addr == "" {
+		addr = ipv6
+	}
+	if addr == "" {
+		// Cannot scrape server without IP address
+		return ms
+	}
+
+	m := promutils.NewLabels(12)
+	m.Add("__address__", discoveryutils.JoinHostPort(addr, port))
+	m.Add("__meta_scaleway_baremetal_id", s.ID)
+	m.Add("__meta_scaleway_baremetal_name", s.Name)
+	m.Add("__meta_scaleway_baremetal_project_id", s.ProjectID)
+	m.Add("__meta_scaleway_baremetal_status", s.Status)
+	m.Add("__meta_scaleway_baremetal_type", s.OfferName)
+	m.Add("__meta_scaleway_baremetal_zone", s.Zone)
+	if ipv4 != "" {
+		m.Add("__meta_scaleway_baremetal_public_ipv4", ipv4)
+	}
+	if ipv6 != "" {
+		m.Add("__meta_scaleway_baremetal_public_ipv6", ipv6)
+	}
+	if osInfo != nil {
+		m.Add("__meta_scaleway_baremetal_os_name", osInfo.Name)
+		m.Add("__meta_scaleway_baremetal_os_version", osInfo.Version)
+	}
+	if len(s.Tags) > 0 {
+		m.Add("__meta_scaleway_baremetal_tags", joinTags(s.Tags))
+	}
+	ms = append(ms, m)
+	return ms
+}
@@ -0,0 +1,139 @@
+package scaleway
+
+import (
+	"encoding/json"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// InstanceServer represents Scaleway instance server.
+//
+// See https://www.scaleway.com/en/developers/api/instance/#path-instances-list-all-instances
+type InstanceServer struct {
+	ID             string                 `json:"id"`
+	Name           string                 `json:"name"`
+	Hostname       string                 `json:"hostname"`
+	Organization   string                 `json:"organization"`
+	Project        string                 `json:"project"`
+	CommercialType string                 `json:"commercial_type"`
+	Status         string                 `json:"state"`
+	BootType       string                 `json:"boot_type"`
+	Zone           string                 `json:"zone"`
+	Tags           []string               `json:"tags"`
+	Image          *InstanceImage         `json:"image"`
+	Location       *InstanceLocation      `json:"location"`
+	SecurityGroup  *InstanceSecurityGroup `json:"security_group"`
+	PrivateIP      *string                `json:"private_ip"`
+	PublicIP       *InstanceIP            `json:"public_ip"`
+	IPv6           *InstanceIP            `json:"ipv6"`
+}
+
+// InstanceImage represents image for Scaleway instance server.
+type InstanceImage struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Arch string `json:"arch"`
+}
+
+// InstanceLocation represents location for Scaleway instance server.
+type InstanceLocation struct {
+	ClusterID    string `json:"cluster_id"`
+	HypervisorID string `json:"hypervisor_id"`
+	NodeID       string `json:"node_id"`
+}
+
+// InstanceSecurityGroup represents security group for Scaleway instance server.
+type InstanceSecurityGroup struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// InstanceIP represents IP address for Scaleway instance server.
+type InstanceIP struct {
+	Address string `json:"address"`
+}
+
+func getInstanceServers(cfg *apiConfig) ([]InstanceServer, error) {
+	var servers []InstanceServer
+	path := "/instance/v1/zones/" + cfg.zone + "/servers"
+	err := getPagedServers(cfg, path, func(data []byte) (int, error) {
+		var resp struct {
+			Servers []InstanceServer `json:"servers"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return 0, err
+		}
+		servers = append(servers, resp.Servers...)
+		return len(resp.Servers), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+func getInstanceServersLabels(servers []InstanceServer, port int) []*promutils.Labels {
+	ms := make([]*promutils.Labels, 0, len(servers))
+	for i := range servers {
+		ms = servers[i].appendTargetLabels(ms, port)
+	}
+	return ms
+}
+
+func (s *InstanceServer) appendTargetLabels(ms []*promutils.Labels, port int) []*promutils.Labels {
+	// Prefer private IPv4 address over public IPv4 address over public IPv6 address for scraping.
+	var addr string
+	switch {
+	case s.PrivateIP != nil && *s.PrivateIP != "":
+		addr = *s.PrivateIP
+	case s.PublicIP != nil && s.PublicIP.Address != "":
+		addr = s.PublicIP.Address
+	case s.IPv6 != nil && s.IPv6.Address != "":
+		addr = s.IPv6.Address
+	default:
+		// Cannot scrape server without IP address
+		return ms
+	}
+
+	m := promutils.NewLabels(24)
+	m.Add("__address__", discoveryutils.JoinHostPort(addr, port))
+	m.Add("__meta_scaleway_instance_boot_type", s.BootType)
+	m.Add("__meta_scaleway_instance_hostname", s.Hostname)
+	m.Add("__meta_scaleway_instance_id", s.ID)
+	m.Add("__meta_scaleway_instance_name", s.Name)
+	m.Add("__meta_scaleway_instance_organization_id", s.Organization)
+	m.Add("__meta_scaleway_instance_project_id", s.Project)
+	m.Add("__meta_scaleway_instance_status", s.Status)
+	m.Add("__meta_scaleway_instance_type", s.CommercialType)
+	m.Add("__meta_scaleway_instance_zone", s.Zone)
+	m.Add("__meta_scaleway_instance_region", getRegion(s.Zone))
+	if s.Image != nil {
+		m.Add("__meta_scaleway_instance_image_arch", s.Image.Arch)
+		m.Add("__meta_scaleway_instance_image_id", s.Image.ID)
+		m.Add("__meta_scaleway_instance_image_name", s.Image.Name)
+	}
+	if s.Location != nil {
+		m.Add("__meta_scaleway_instance_location_cluster_id", s.Location.ClusterID)
+		m.Add("__meta_scaleway_instance_location_hypervisor_id", s.Location.HypervisorID)
+		m.Add("__meta_scaleway_instance_location_node_id", s.Location.NodeID)
+	}
+	if s.SecurityGroup != nil {
+		m.Add("__meta_scaleway_instance_security_group_id", s.SecurityGroup.ID)
+		m.Add("__meta_scaleway_instance_security_group_name", s.SecurityGroup.Name)
+	}
+	if s.PrivateIP != nil && *s.PrivateIP != "" {
+		m.Add("__meta_scaleway_instance_private_ipv4", *s.PrivateIP)
+	}
+	if s.PublicIP != nil && s.PublicIP.Address != "" {
+		m.Add("__meta_scaleway_instance_public_ipv4", s.PublicIP.Address)
+	}
+	if s.IPv6 != nil && s.IPv6.Address != "" {
+		m.Add("__meta_scaleway_instance_public_ipv6", s.IPv6.Address)
+	}
+	if len(s.Tags) > 0 {
+		m.Add("__meta_scaleway_instance_tags", joinTags(s.Tags))
+	}
+	ms = append(ms, m)
+	return ms
+}
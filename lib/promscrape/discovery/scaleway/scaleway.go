@@ -0,0 +1,73 @@
+package scaleway
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
+)
+
+// SDCheckInterval defines interval for Scaleway targets refresh.
+var SDCheckInterval = flag.Duration("promscrape.scalewaySDCheckInterval", time.Minute, "Interval for checking for changes in Scaleway. "+
+	"This works only if scaleway_sd_configs is configured in '-promscrape.config' file. "+
+	"See https://docs.victoriametrics.com/sd_configs/#scaleway_sd_configs for details")
+
+// SDConfig represents service discovery config for Scaleway.
+//
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#scaleway_sd_config
+type SDConfig struct {
+	Role          string           `yaml:"role"`
+	APIURL        string           `yaml:"api_url,omitempty"`
+	ProjectID     string           `yaml:"project_id"`
+	Zone          string           `yaml:"zone,omitempty"`
+	AccessKey     string           `yaml:"access_key"`
+	SecretKey     *promauth.Secret `yaml:"secret_key,omitempty"`
+	SecretKeyFile string           `yaml:"secret_key_file,omitempty"`
+	NameFilter    string           `yaml:"name_filter,omitempty"`
+	TagsFilter    []string         `yaml:"tags_filter,omitempty"`
+	Port          *int             `yaml:"port,omitempty"`
+
+	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
+	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
+	// refresh_interval is obtained from `-promscrape.scalewaySDCheckInterval` command-line option.
+}
+
+var configMap = discoveryutils.NewConfigMap()
+
+// GetLabels returns Scaleway labels according to sdc.
+func (sdc *SDConfig) GetLabels(baseDir string) ([]*promutils.Labels, error) {
+	cfg, err := getAPIConfig(sdc, baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get API config: %w", err)
+	}
+	switch cfg.role {
+	case "instance":
+		servers, err := getInstanceServers(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return getInstanceServersLabels(servers, cfg.port), nil
+	case "baremetal":
+		servers, err := getBaremetalServers(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return getBaremetalServersLabels(cfg, servers)
+	default:
+		return nil, fmt.Errorf("BUG: unexpected `role`: %q", cfg.role)
+	}
+}
+
+// MustStop stops further usage for sdc.
+func (sdc *SDConfig) MustStop() {
+	v := configMap.Delete(sdc)
+	if v != nil {
+		cfg := v.(*apiConfig)
+		cfg.c.Stop()
+	}
+}
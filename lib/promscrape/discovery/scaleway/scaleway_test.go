@@ -0,0 +1,223 @@
+package scaleway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discoveryutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func newMockScalewayServer(t *testing.T) *httptest.Server {
+	responses := map[string]string{
+		"/instance/v1/zones/fr-par-1/servers?page=1&per_page=100&project=11111111-1111-1111-1111-111111111111&tags=prod": `{"servers": [
+  {
+    "id": "93c18a61-b681-49d0-a1cc-62b43883ae89",
+    "name": "scw-nervous-shirley",
+    "hostname": "scw-nervous-shirley",
+    "organization": "20b3d507-96ac-454c-a795-bc731b46b12f",
+    "project": "11111111-1111-1111-1111-111111111111",
+    "commercial_type": "DEV1-S",
+    "state": "running",
+    "boot_type": "local",
+    "zone": "fr-par-1",
+    "tags": ["prod", "monitoring"],
+    "image": {"id": "45a86b35-eca6-4055-9b34-ca69845da146", "name": "Ubuntu 20.04 Focal Fossa", "arch": "x86_64"},
+    "location": {"cluster_id": "40", "hypervisor_id": "1601", "node_id": "29"},
+    "security_group": {"id": "984414da-9fc2-49c0-a925-fed6266fe092", "name": "Default security group"},
+    "private_ip": "10.70.60.57",
+    "public_ip": {"address": "51.158.183.115"},
+    "ipv6": {"address": "2001:bc8:630:1e1c::1"}
+  },
+  {
+    "id": "5b6198b4-c677-41b5-9c05-04557264ae1f",
+    "name": "scw-quizzical-feistel",
+    "hostname": "scw-quizzical-feistel",
+    "organization": "20b3d507-96ac-454c-a795-bc731b46b12f",
+    "project": "11111111-1111-1111-1111-111111111111",
+    "commercial_type": "DEV1-S",
+    "state": "running",
+    "boot_type": "local",
+    "zone": "fr-par-1",
+    "tags": [],
+    "image": null,
+    "location": null,
+    "security_group": null,
+    "private_ip": null,
+    "public_ip": {"address": "212.47.248.223"},
+    "ipv6": null
+  },
+  {
+    "id": "without-ip",
+    "zone": "fr-par-1",
+    "private_ip": null,
+    "public_ip": null,
+    "ipv6": null
+  }
+]}`,
+		"/baremetal/v1/zones/fr-par-1/servers?page=1&per_page=100&project_id=11111111-1111-1111-1111-111111111111&tags=prod": `{"total_count": 1, "servers": [
+  {
+    "id": "5a13a2ae-1a5e-4d94-9b2b-d6f8f0d2e9f2",
+    "name": "em-poincare",
+    "project_id": "11111111-1111-1111-1111-111111111111",
+    "status": "ready",
+    "offer_name": "EM-A210R-HDD",
+    "zone": "fr-par-1",
+    "tags": ["prod"],
+    "ips": [
+      {"address": "2001:bc8:1640:1568:dc00:ff:fe21:91b", "version": "IPv6"},
+      {"address": "51.159.73.201", "version": "IPv4"}
+    ],
+    "install": {"os_id": "03b7f4ba-a6a1-4305-984e-b54fafbf1681"}
+  }
+]}`,
+		"/baremetal/v1/zones/fr-par-1/os/03b7f4ba-a6a1-4305-984e-b54fafbf1681": `{"id": "03b7f4ba-a6a1-4305-984e-b54fafbf1681", "name": "Ubuntu", "version": "20.04 LTS (Focal Fossa)"}`,
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get("X-Auth-Token"); token != "secret" {
+			t.Errorf("unexpected X-Auth-Token header: %q", token)
+		}
+		resp, ok := responses[r.URL.RequestURI()]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+}
+
+func TestGetLabels(t *testing.T) {
+	s := newMockScalewayServer(t)
+	defer s.Close()
+
+	f := func(role string, expectedLabels []*promutils.Labels) {
+		t.Helper()
+
+		port := 9100
+		sdc := &SDConfig{
+			Role:       role,
+			APIURL:     s.URL,
+			ProjectID:  "11111111-1111-1111-1111-111111111111",
+			AccessKey:  "SCW1W2WQ4ZH1ZG4QF1QF",
+			SecretKey:  promauth.NewSecret("secret"),
+			TagsFilter: []string{"prod"},
+			Port:       &port,
+		}
+		defer sdc.MustStop()
+
+		labelss, err := sdc.GetLabels("")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		discoveryutils.TestEqualLabelss(t, labelss, expectedLabels)
+	}
+
+	f("instance", []*promutils.Labels{
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                                     "10.70.60.57:9100",
+			"__meta_scaleway_instance_boot_type":              "local",
+			"__meta_scaleway_instance_hostname":               "scw-nervous-shirley",
+			"__meta_scaleway_instance_id":                     "93c18a61-b681-49d0-a1cc-62b43883ae89",
+			"__meta_scaleway_instance_image_arch":             "x86_64",
+			"__meta_scaleway_instance_image_id":               "45a86b35-eca6-4055-9b34-ca69845da146",
+			"__meta_scaleway_instance_image_name":             "Ubuntu 20.04 Focal Fossa",
+			"__meta_scaleway_instance_location_cluster_id":    "40",
+			"__meta_scaleway_instance_location_hypervisor_id": "1601",
+			"__meta_scaleway_instance_location_node_id":       "29",
+			"__meta_scaleway_instance_name":                   "scw-nervous-shirley",
+			"__meta_scaleway_instance_organization_id":        "20b3d507-96ac-454c-a795-bc731b46b12f",
+			"__meta_scaleway_instance_private_ipv4":           "10.70.60.57",
+			"__meta_scaleway_instance_project_id":             "11111111-1111-1111-1111-111111111111",
+			"__meta_scaleway_instance_public_ipv4":            "51.158.183.115",
+			"__meta_scaleway_instance_public_ipv6":            "2001:bc8:630:1e1c::1",
+			"__meta_scaleway_instance_region":                 "fr-par",
+			"__meta_scaleway_instance_security_group_id":      "984414da-9fc2-49c0-a925-fed6266fe092",
+			"__meta_scaleway_instance_security_group_name":    "Default security group",
+			"__meta_scaleway_instance_status":                 "running",
+			"__meta_scaleway_instance_tags":                   ",prod,monitoring,",
+			"__meta_scaleway_instance_type":                   "DEV1-S",
+			"__meta_scaleway_instance_zone":                   "fr-par-1",
+		}),
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                              "212.47.248.223:9100",
+			"__meta_scaleway_instance_boot_type":       "local",
+			"__meta_scaleway_instance_hostname":        "scw-quizzical-feistel",
+			"__meta_scaleway_instance_id":              "5b6198b4-c677-41b5-9c05-04557264ae1f",
+			"__meta_scaleway_instance_name":            "scw-quizzical-feistel",
+			"__meta_scaleway_instance_organization_id": "20b3d507-96ac-454c-a795-bc731b46b12f",
+			"__meta_scaleway_instance_project_id":      "11111111-1111-1111-1111-111111111111",
+			"__meta_scaleway_instance_public_ipv4":     "212.47.248.223",
+			"__meta_scaleway_instance_region":          "fr-par",
+			"__meta_scaleway_instance_status":          "running",
+			"__meta_scaleway_instance_type":            "DEV1-S",
+			"__meta_scaleway_instance_zone":            "fr-par-1",
+		}),
+	})
+
+	f("baremetal", []*promutils.Labels{
+		promutils.NewLabelsFromMap(map[string]string{
+			"__address__":                           "51.159.73.201:9100",
+			"__meta_scaleway_baremetal_id":          "5a13a2ae-1a5e-4d94-9b2b-d6f8f0d2e9f2",
+			"__meta_scaleway_baremetal_name":        "em-poincare",
+			"__meta_scaleway_baremetal_os_name":     "Ubuntu",
+			"__meta_scaleway_baremetal_os_version":  "20.04 LTS (Focal Fossa)",
+			"__meta_scaleway_baremetal_project_id":  "11111111-1111-1111-1111-111111111111",
+			"__meta_scaleway_baremetal_public_ipv4": "51.159.73.201",
+			"__meta_scaleway_baremetal_public_ipv6": "2001:bc8:1640:1568:dc00:ff:fe21:91b",
+			"__meta_scaleway_baremetal_status":      "ready",
+			"__meta_scaleway_baremetal_tags":        ",prod,",
+			"__meta_scaleway_baremetal_type":        "EM-A210R-HDD",
+			"__meta_scaleway_baremetal_zone":        "fr-par-1",
+		}),
+	})
+}
+
+func TestNewAPIConfigFailure(t *testing.T) {
+	f := func(sdc *SDConfig) {
+		t.Helper()
+
+		if _, err := newAPIConfig(sdc, ""); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// missing role
+	f(&SDConfig{
+		ProjectID: "foo",
+		AccessKey: "bar",
+		SecretKey: promauth.NewSecret("baz"),
+	})
+
+	// unsupported role
+	f(&SDConfig{
+		Role:      "foobar",
+		ProjectID: "foo",
+		AccessKey: "bar",
+		SecretKey: promauth.NewSecret("baz"),
+	})
+
+	// missing project_id
+	f(&SDConfig{
+		Role:      "instance",
+		AccessKey: "bar",
+		SecretKey: promauth.NewSecret("baz"),
+	})
+
+	// missing secret_key
+	f(&SDConfig{
+		Role:      "instance",
+		ProjectID: "foo",
+		AccessKey: "bar",
+	})
+
+	// both secret_key and secret_key_file
+	f(&SDConfig{
+		Role:          "instance",
+		ProjectID:     "foo",
+		AccessKey:     "bar",
+		SecretKey:     promauth.NewSecret("baz"),
+		SecretKeyFile: "/path/to/secret",
+	})
+}
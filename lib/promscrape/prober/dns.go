@@ -0,0 +1,127 @@
+package prober
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// DNSConfig represents `dns` section of the `probe` config.
+type DNSConfig struct {
+	// QueryName is the name to resolve.
+	QueryName string `yaml:"query_name"`
+
+	// QueryType is the type of DNS query. Supported values: A, AAAA, CNAME, MX, NS, TXT and SRV. Default is A.
+	QueryType string `yaml:"query_type,omitempty"`
+
+	// TransportProtocol is the protocol to use for DNS queries. Supported values: udp and tcp. Default is udp.
+	TransportProtocol string `yaml:"transport_protocol,omitempty"`
+
+	// FailIfNoAnswer marks the probe as failed if the response contains no answer records.
+	FailIfNoAnswer bool `yaml:"fail_if_no_answer,omitempty"`
+}
+
+type dnsProber struct {
+	queryName         string
+	queryType         string
+	transportProtocol string
+	failIfNoAnswer    bool
+}
+
+func newDNSProber(cfg *DNSConfig) (*dnsProber, error) {
+	if cfg == nil || cfg.QueryName == "" {
+		return nil, fmt.Errorf("missing `query_name`")
+	}
+	queryType := strings.ToUpper(cfg.QueryType)
+	switch queryType {
+	case "":
+		queryType = "A"
+	case "A", "AAAA", "CNAME", "MX", "NS", "TXT", "SRV":
+	default:
+		return nil, fmt.Errorf("unsupported `query_type`: %q; supported values: A, AAAA, CNAME, MX, NS, TXT, SRV", cfg.QueryType)
+	}
+	transportProtocol := strings.ToLower(cfg.TransportProtocol)
+	switch transportProtocol {
+	case "":
+		transportProtocol = "udp"
+	case "udp", "tcp":
+	default:
+		return nil, fmt.Errorf("unsupported `transport_protocol`: %q; supported values: udp, tcp", cfg.TransportProtocol)
+	}
+	queryName := cfg.QueryName
+	if !strings.HasSuffix(queryName, ".") {
+		// Make the name fully qualified in order to avoid applying search domains from the local resolv.conf.
+		queryName += "."
+	}
+	dp := &dnsProber{
+		queryName:         queryName,
+		queryType:         queryType,
+		transportProtocol: transportProtocol,
+		failIfNoAnswer:    cfg.FailIfNoAnswer,
+	}
+	return dp, nil
+}
+
+// probe sends DNS query to the DNS server at targetURL host:port. The port defaults to 53.
+func (dp *dnsProber) probe(ctx context.Context, targetURL string, w *metricsWriter) bool {
+	server, err := getHostPort(targetURL, "53")
+	if err != nil {
+		return false
+	}
+	r := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, dp.transportProtocol, server)
+		},
+	}
+	answers, err := dp.lookup(ctx, r)
+	var de *net.DNSError
+	if errors.As(err, &de) && de.IsNotFound {
+		// The server responded, but the name doesn't exist.
+		w.add("probe_dns_answer_rrs", 0)
+		return !dp.failIfNoAnswer
+	}
+	if err != nil {
+		return false
+	}
+	w.add("probe_dns_answer_rrs", float64(answers))
+	if dp.failIfNoAnswer && answers == 0 {
+		return false
+	}
+	return true
+}
+
+func (dp *dnsProber) lookup(ctx context.Context, r *net.Resolver) (int, error) {
+	name := dp.queryName
+	switch dp.queryType {
+	case "A":
+		ips, err := r.LookupIP(ctx, "ip4", name)
+		return len(ips), err
+	case "AAAA":
+		ips, err := r.LookupIP(ctx, "ip6", name)
+		return len(ips), err
+	case "CNAME":
+		cname, err := r.LookupCNAME(ctx, name)
+		if err != nil || cname == name {
+			return 0, err
+		}
+		return 1, nil
+	case "MX":
+		mxs, err := r.LookupMX(ctx, name)
+		return len(mxs), err
+	case "NS":
+		nss, err := r.LookupNS(ctx, name)
+		return len(nss), err
+	case "TXT":
+		txts, err := r.LookupTXT(ctx, name)
+		return len(txts), err
+	case "SRV":
+		_, srvs, err := r.LookupSRV(ctx, "", "", name)
+		return len(srvs), err
+	default:
+		return 0, fmt.Errorf("BUG: unexpected query type %q", dp.queryType)
+	}
+}
@@ -0,0 +1,203 @@
+package prober
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// HTTPConfig represents `http` section of the `probe` config.
+type HTTPConfig struct {
+	// Method is the http method to use. Default is GET.
+	Method string `yaml:"method,omitempty"`
+
+	// Headers contains additional http headers to send with the request.
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// Body is optional request body.
+	Body string `yaml:"body,omitempty"`
+
+	// ValidStatusCodes contains the list of status codes, which are considered successful. Default is 2xx.
+	ValidStatusCodes []int `yaml:"valid_status_codes,omitempty"`
+
+	FailIfBodyMatchesRegexp    []string `yaml:"fail_if_body_matches_regexp,omitempty"`
+	FailIfBodyNotMatchesRegexp []string `yaml:"fail_if_body_not_matches_regexp,omitempty"`
+	FailIfSSL                  bool     `yaml:"fail_if_ssl,omitempty"`
+	FailIfNotSSL               bool     `yaml:"fail_if_not_ssl,omitempty"`
+}
+
+// maxResponseBodySize is the maximum response body size, which is read for regexp matching.
+const maxResponseBodySize = 1 << 20
+
+type httpProber struct {
+	method                      string
+	headers                     map[string]string
+	body                        string
+	validStatusCodes            []int
+	failIfBodyMatchesRegexps    []*regexp.Regexp
+	failIfBodyNotMatchesRegexps []*regexp.Regexp
+	failIfSSL                   bool
+	failIfNotSSL                bool
+}
+
+func newHTTPProber(cfg *HTTPConfig) (*httpProber, error) {
+	if cfg == nil {
+		cfg = &HTTPConfig{}
+	}
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	failIfBodyMatchesRegexps, err := compileRegexps(cfg.FailIfBodyMatchesRegexp)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `fail_if_body_matches_regexp`: %w", err)
+	}
+	failIfBodyNotMatchesRegexps, err := compileRegexps(cfg.FailIfBodyNotMatchesRegexp)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `fail_if_body_not_matches_regexp`: %w", err)
+	}
+	hp := &httpProber{
+		method:                      method,
+		headers:                     cfg.Headers,
+		body:                        cfg.Body,
+		validStatusCodes:            cfg.ValidStatusCodes,
+		failIfBodyMatchesRegexps:    failIfBodyMatchesRegexps,
+		failIfBodyNotMatchesRegexps: failIfBodyNotMatchesRegexps,
+		failIfSSL:                   cfg.FailIfSSL,
+		failIfNotSSL:                cfg.FailIfNotSSL,
+	}
+	return hp, nil
+}
+
+func compileRegexps(exprs []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile regexp %q: %w", expr, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func (hp *httpProber) probe(ctx context.Context, hc *http.Client, targetURL string, w *metricsWriter) bool {
+	var body io.Reader
+	if hp.body != "" {
+		body = strings.NewReader(hp.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, hp.method, targetURL, body)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("User-Agent", "vm_promscrape")
+	for k, v := range hp.headers {
+		if strings.EqualFold(k, "Host") {
+			req.Host = v
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+
+	// Count redirects while respecting the redirect policy of hc.
+	redirects := 0
+	hcCopy := *hc
+	hcCopy.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		redirects++
+		if hc.CheckRedirect != nil {
+			return hc.CheckRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return nil
+	}
+	resp, err := hcCopy.Do(req)
+	w.add("probe_http_redirects", float64(redirects))
+	if err != nil {
+		return false
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var respBody []byte
+	var contentLength int64
+	if len(hp.failIfBodyMatchesRegexps) > 0 || len(hp.failIfBodyNotMatchesRegexps) > 0 {
+		respBody, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseBodySize))
+		contentLength = int64(len(respBody))
+	} else {
+		contentLength, err = io.Copy(io.Discard, resp.Body)
+	}
+	if err != nil {
+		return false
+	}
+
+	w.add("probe_http_status_code", float64(resp.StatusCode))
+	w.add("probe_http_content_length", float64(contentLength))
+	w.add("probe_http_version", float64(resp.ProtoMajor)+float64(resp.ProtoMinor)/10)
+	isSSL := resp.TLS != nil
+	w.addBool("probe_http_ssl", isSSL)
+	if isSSL {
+		if expiry, ok := getEarliestCertExpiry(resp.TLS); ok {
+			w.add("probe_ssl_earliest_cert_expiry", expiry)
+		}
+	}
+
+	ok := hp.isValidStatusCode(resp.StatusCode)
+	if hp.failIfSSL && isSSL || hp.failIfNotSSL && !isSSL {
+		ok = false
+	}
+	if len(hp.failIfBodyMatchesRegexps) > 0 || len(hp.failIfBodyNotMatchesRegexps) > 0 {
+		failedDueToRegex := !hp.matchBody(respBody)
+		w.addBool("probe_failed_due_to_regex", failedDueToRegex)
+		if failedDueToRegex {
+			ok = false
+		}
+	}
+	return ok
+}
+
+func (hp *httpProber) isValidStatusCode(statusCode int) bool {
+	if len(hp.validStatusCodes) == 0 {
+		return statusCode >= 200 && statusCode < 300
+	}
+	for _, code := range hp.validStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+func (hp *httpProber) matchBody(body []byte) bool {
+	for _, re := range hp.failIfBodyMatchesRegexps {
+		if re.Match(body) {
+			return false
+		}
+	}
+	for _, re := range hp.failIfBodyNotMatchesRegexps {
+		if !re.Match(body) {
+			return false
+		}
+	}
+	return true
+}
+
+// getEarliestCertExpiry returns the earliest expiration unix timestamp in seconds for the peer certificates from cs.
+func getEarliestCertExpiry(cs *tls.ConnectionState) (float64, bool) {
+	if len(cs.PeerCertificates) == 0 {
+		return 0, false
+	}
+	earliest := cs.PeerCertificates[0].NotAfter
+	for _, cert := range cs.PeerCertificates[1:] {
+		if cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	return float64(earliest.Unix()), true
+}
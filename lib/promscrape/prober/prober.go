@@ -0,0 +1,137 @@
+package prober
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"gopkg.in/yaml.v2"
+)
+
+// Config represents `probe` section of `probe_configs` in `-promscrape.config`.
+//
+// See https://docs.victoriametrics.com/vmagent/#probing-targets
+type Config struct {
+	// Module is the probe type. Supported values: http, tcp and dns. Default is http.
+	Module string `yaml:"module,omitempty"`
+
+	HTTP *HTTPConfig `yaml:"http,omitempty"`
+	DNS  *DNSConfig  `yaml:"dns,omitempty"`
+}
+
+// Prober performs probes according to the parsed Config.
+type Prober struct {
+	module string
+	http   *httpProber
+	dns    *dnsProber
+
+	// s is the string representation of the Config the Prober was created from.
+	s string
+}
+
+// NewProber returns Prober for the given cfg.
+func NewProber(cfg *Config) (*Prober, error) {
+	module := strings.ToLower(cfg.Module)
+	if module == "" {
+		module = "http"
+	}
+	p := &Prober{
+		module: module,
+	}
+	switch module {
+	case "http":
+		hp, err := newHTTPProber(cfg.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `http` section: %w", err)
+		}
+		p.http = hp
+	case "tcp":
+	case "dns":
+		dp, err := newDNSProber(cfg.DNS)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `dns` section: %w", err)
+		}
+		p.dns = dp
+	default:
+		return nil, fmt.Errorf("unsupported `module`: %q; supported values: http, tcp, dns", cfg.Module)
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal probe config: %w", err)
+	}
+	p.s = string(data)
+	return p, nil
+}
+
+// String returns human-readable representation for p.
+func (p *Prober) String() string {
+	if p == nil {
+		return ""
+	}
+	return p.s
+}
+
+// Probe probes the given targetURL and appends the collected metrics in Prometheus text exposition format to dst.
+//
+// hc is used for http probes. It must contain auth, TLS and proxy settings for the target.
+// Probe failures are reported via probe_success metric, so the returned data must be processed as a regular scrape response.
+func (p *Prober) Probe(ctx context.Context, hc *http.Client, targetURL string, timeout time.Duration, dst []byte) []byte {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	w := &metricsWriter{
+		dst: dst,
+	}
+	startTime := time.Now()
+	var ok bool
+	switch p.module {
+	case "http":
+		ok = p.http.probe(ctx, hc, targetURL, w)
+	case "tcp":
+		ok = probeTCP(ctx, targetURL, w)
+	case "dns":
+		ok = p.dns.probe(ctx, targetURL, w)
+	default:
+		logger.Panicf("BUG: unexpected module=%q", p.module)
+	}
+	w.add("probe_duration_seconds", time.Since(startTime).Seconds())
+	w.addBool("probe_success", ok)
+	return w.dst
+}
+
+// getHostPort returns host:port from targetURL.
+//
+// defaultPort is used if targetURL has no port. The error is returned if targetURL has no port and defaultPort is empty.
+func getHostPort(targetURL, defaultPort string) (string, error) {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return "", fmt.Errorf("cannot parse target url %q: %w", targetURL, err)
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	if defaultPort == "" {
+		return "", fmt.Errorf("missing port in target %q", u.Host)
+	}
+	return u.Host + ":" + defaultPort, nil
+}
+
+type metricsWriter struct {
+	dst []byte
+}
+
+func (w *metricsWriter) add(name string, value float64) {
+	w.dst = fmt.Appendf(w.dst, "%s %g\n", name, value)
+}
+
+func (w *metricsWriter) addBool(name string, v bool) {
+	value := 0.0
+	if v {
+		value = 1
+	}
+	w.add(name, value)
+}
@@ -0,0 +1,384 @@
+package prober
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewProberFailure(t *testing.T) {
+	f := func(cfg *Config) {
+		t.Helper()
+		if _, err := NewProber(cfg); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// unsupported module
+	f(&Config{
+		Module: "icmp",
+	})
+
+	// invalid regexp
+	f(&Config{
+		HTTP: &HTTPConfig{
+			FailIfBodyMatchesRegexp: []string{"foo("},
+		},
+	})
+
+	// missing dns section
+	f(&Config{
+		Module: "dns",
+	})
+
+	// unsupported dns query type
+	f(&Config{
+		Module: "dns",
+		DNS: &DNSConfig{
+			QueryName: "example.com",
+			QueryType: "AXFR",
+		},
+	})
+
+	// unsupported dns transport protocol
+	f(&Config{
+		Module: "dns",
+		DNS: &DNSConfig{
+			QueryName:         "example.com",
+			TransportProtocol: "quic",
+		},
+	})
+}
+
+func TestProberString(t *testing.T) {
+	newProber := func() *Prober {
+		p, err := NewProber(&Config{
+			HTTP: &HTTPConfig{
+				FailIfBodyNotMatchesRegexp: []string{"ok"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return p
+	}
+	// String() is used as a part of ScrapeWork key, so it must be stable across config reloads.
+	s1 := newProber().String()
+	s2 := newProber().String()
+	if s1 != s2 {
+		t.Fatalf("unexpected String() mismatch for the same config;\ngot\n%s\nwant\n%s", s1, s2)
+	}
+	if s := (*Prober)(nil).String(); s != "" {
+		t.Fatalf("unexpected String() for nil prober: %q", s)
+	}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			if r.Header.Get("X-Foo") != "bar" {
+				http.Error(w, "missing X-Foo header", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte("status: healthy"))
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	f := func(cfg *HTTPConfig, path string, resultExpected map[string]float64) {
+		t.Helper()
+		p, err := NewProber(&Config{
+			HTTP: cfg,
+		})
+		if err != nil {
+			t.Fatalf("cannot create prober: %s", err)
+		}
+		data := p.Probe(context.Background(), s.Client(), s.URL+path, time.Second, nil)
+		checkProbeResult(t, data, resultExpected)
+	}
+
+	headers := map[string]string{
+		"X-Foo": "bar",
+	}
+
+	// successful probe
+	f(&HTTPConfig{
+		Headers: headers,
+	}, "/ok", map[string]float64{
+		"probe_success":             1,
+		"probe_http_status_code":    200,
+		"probe_http_content_length": 15,
+		"probe_http_redirects":      0,
+		"probe_http_ssl":            0,
+		"probe_http_version":        1.1,
+	})
+
+	// successful probe after redirect
+	f(&HTTPConfig{
+		Headers: headers,
+	}, "/redirect", map[string]float64{
+		"probe_success":          1,
+		"probe_http_status_code": 200,
+		"probe_http_redirects":   1,
+	})
+
+	// unexpected status code
+	f(nil, "/missing", map[string]float64{
+		"probe_success":          0,
+		"probe_http_status_code": 404,
+	})
+
+	// custom valid status codes
+	f(&HTTPConfig{
+		ValidStatusCodes: []int{404},
+	}, "/missing", map[string]float64{
+		"probe_success":          1,
+		"probe_http_status_code": 404,
+	})
+
+	// body matches regexp
+	f(&HTTPConfig{
+		Headers:                    headers,
+		FailIfBodyNotMatchesRegexp: []string{"healthy"},
+	}, "/ok", map[string]float64{
+		"probe_success":             1,
+		"probe_failed_due_to_regex": 0,
+	})
+
+	// body doesn't match regexp
+	f(&HTTPConfig{
+		Headers:                 headers,
+		FailIfBodyMatchesRegexp: []string{"health"},
+	}, "/ok", map[string]float64{
+		"probe_success":             0,
+		"probe_failed_due_to_regex": 1,
+	})
+
+	// fail if not ssl
+	f(&HTTPConfig{
+		Headers:      headers,
+		FailIfNotSSL: true,
+	}, "/ok", map[string]float64{
+		"probe_success":  0,
+		"probe_http_ssl": 0,
+	})
+}
+
+func TestProbeHTTPS(t *testing.T) {
+	s := httptest.NewTLSServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	defer s.Close()
+
+	p, err := NewProber(&Config{})
+	if err != nil {
+		t.Fatalf("cannot create prober: %s", err)
+	}
+	data := p.Probe(context.Background(), s.Client(), s.URL, time.Second, nil)
+	checkProbeResult(t, data, map[string]float64{
+		"probe_success":                  1,
+		"probe_http_ssl":                 1,
+		"probe_ssl_earliest_cert_expiry": float64(s.Certificate().NotAfter.Unix()),
+	})
+}
+
+func TestProbeTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start listener: %s", err)
+	}
+	addr := ln.Addr().String()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = c.Close()
+		}
+	}()
+
+	p, err := NewProber(&Config{
+		Module: "tcp",
+	})
+	if err != nil {
+		t.Fatalf("cannot create prober: %s", err)
+	}
+	f := func(targetURL string, resultExpected map[string]float64) {
+		t.Helper()
+		data := p.Probe(context.Background(), nil, targetURL, time.Second, nil)
+		checkProbeResult(t, data, resultExpected)
+	}
+
+	// successful probe
+	f("http://"+addr+"/", map[string]float64{
+		"probe_success":     1,
+		"probe_ip_protocol": 4,
+	})
+
+	// missing port
+	f("http://127.0.0.1/", map[string]float64{
+		"probe_success": 0,
+	})
+
+	// closed listener
+	_ = ln.Close()
+	f("http://"+addr+"/", map[string]float64{
+		"probe_success": 0,
+	})
+}
+
+func TestProbeDNS(t *testing.T) {
+	addr := newMockDNSServer(t)
+
+	f := func(cfg *DNSConfig, resultExpected map[string]float64) {
+		t.Helper()
+		p, err := NewProber(&Config{
+			Module: "dns",
+			DNS:    cfg,
+		})
+		if err != nil {
+			t.Fatalf("cannot create prober: %s", err)
+		}
+		data := p.Probe(context.Background(), nil, "http://"+addr+"/", time.Second, nil)
+		checkProbeResult(t, data, resultExpected)
+	}
+
+	// existing name
+	f(&DNSConfig{
+		QueryName: "example.com",
+	}, map[string]float64{
+		"probe_success":        1,
+		"probe_dns_answer_rrs": 1,
+	})
+
+	// missing name
+	f(&DNSConfig{
+		QueryName: "missing.example.com",
+	}, map[string]float64{
+		"probe_success":        1,
+		"probe_dns_answer_rrs": 0,
+	})
+
+	// missing name with fail_if_no_answer
+	f(&DNSConfig{
+		QueryName:      "missing.example.com",
+		FailIfNoAnswer: true,
+	}, map[string]float64{
+		"probe_success":        0,
+		"probe_dns_answer_rrs": 0,
+	})
+}
+
+// newMockDNSServer starts UDP DNS server, which responds with 127.0.0.1 A record to example.com queries
+// and with NXDOMAIN to all the other queries.
+//
+// It returns the address of the started server.
+func newMockDNSServer(t *testing.T) string {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start udp listener: %s", err)
+	}
+	t.Cleanup(func() {
+		_ = pc.Close()
+	})
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := getMockDNSResponse(buf[:n]); resp != nil {
+				_, _ = pc.WriteTo(resp, addr)
+			}
+		}
+	}()
+	return pc.LocalAddr().String()
+}
+
+func getMockDNSResponse(req []byte) []byte {
+	if len(req) < 12 {
+		return nil
+	}
+	// Skip the question name
+	i := 12
+	var labels []string
+	for i < len(req) && req[i] != 0 {
+		n := int(req[i])
+		if i+1+n > len(req) {
+			return nil
+		}
+		labels = append(labels, string(req[i+1:i+1+n]))
+		i += 1 + n
+	}
+	// Skip zero byte, qtype and qclass
+	i += 5
+	if i > len(req) {
+		return nil
+	}
+	question := req[12:i]
+	qtype := binary.BigEndian.Uint16(req[i-4:])
+
+	resp := append([]byte{}, req[:2]...)
+	name := strings.Join(labels, ".")
+	if name != "example.com" {
+		// NXDOMAIN
+		resp = append(resp, 0x81, 0x83, 0, 1, 0, 0, 0, 0, 0, 0)
+		return append(resp, question...)
+	}
+	if qtype != 1 {
+		// Empty NOERROR response for non-A queries
+		resp = append(resp, 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0)
+		return append(resp, question...)
+	}
+	resp = append(resp, 0x81, 0x80, 0, 1, 0, 1, 0, 0, 0, 0)
+	resp = append(resp, question...)
+	// A record: name pointer, type A, class IN, TTL, rdlength and IP
+	resp = append(resp, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4, 127, 0, 0, 1)
+	return resp
+}
+
+func checkProbeResult(t *testing.T, data []byte, resultExpected map[string]float64) {
+	t.Helper()
+
+	result := parseProbeResult(t, data)
+	if _, ok := result["probe_duration_seconds"]; !ok {
+		t.Fatalf("missing probe_duration_seconds metric in the result:\n%s", data)
+	}
+	for name, vExpected := range resultExpected {
+		v, ok := result[name]
+		if !ok {
+			t.Fatalf("missing %s metric in the result:\n%s", name, data)
+		}
+		if v != vExpected {
+			t.Fatalf("unexpected value for %s; got %v; want %v; result:\n%s", name, v, vExpected, data)
+		}
+	}
+}
+
+func parseProbeResult(t *testing.T, data []byte) map[string]float64 {
+	t.Helper()
+
+	result := make(map[string]float64)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var name string
+		var v float64
+		if _, err := fmt.Sscanf(line, "%s %g", &name, &v); err != nil {
+			t.Fatalf("cannot parse line %q: %s", line, err)
+		}
+		result[name] = v
+	}
+	return result
+}
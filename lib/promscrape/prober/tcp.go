@@ -0,0 +1,28 @@
+package prober
+
+import (
+	"context"
+	"net"
+)
+
+// probeTCP verifies whether a TCP connection can be established to the host:port from targetURL.
+func probeTCP(ctx context.Context, targetURL string, w *metricsWriter) bool {
+	addr, err := getHostPort(targetURL, "")
+	if err != nil {
+		return false
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return false
+	}
+	if ta, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		ipProtocol := 6
+		if ta.IP.To4() != nil {
+			ipProtocol = 4
+		}
+		w.add("probe_ip_protocol", float64(ipProtocol))
+	}
+	_ = conn.Close()
+	return true
+}
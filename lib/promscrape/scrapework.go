@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"math"
 	"math/bits"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -22,6 +24,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/prober"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus/stream"
@@ -121,6 +124,20 @@ type ScrapeWork struct {
 	// The maximum number of metrics to scrape after relabeling.
 	SampleLimit int
 
+	// The maximum number of labels per scraped series after relabeling.
+	LabelLimit int
+
+	// The maximum length of label name per scraped series after relabeling.
+	LabelNameLengthLimit int
+
+	// The maximum length of label value per scraped series after relabeling.
+	LabelValueLengthLimit int
+
+	// Optional list of protocols to negotiate with the scrape target in the order of preference.
+	//
+	// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#scrape_config
+	ScrapeProtocols []string
+
 	// Whether to disable response compression when querying ScrapeURL.
 	DisableCompression bool
 
@@ -146,6 +163,11 @@ type ScrapeWork struct {
 	// The Tenant Info
 	AuthToken *auth.Token
 
+	// Optional prober for targets from `probe_configs`.
+	//
+	// If set, then the target is probed instead of scraping.
+	Prober *prober.Prober
+
 	// The original 'job_name'
 	jobNameOriginal string
 }
@@ -156,6 +178,19 @@ func (sw *ScrapeWork) canSwitchToStreamParseMode() bool {
 	return sw.SampleLimit <= 0 && sw.SeriesLimit <= 0
 }
 
+// isOpenMetricsRequested returns true if OpenMetrics text exposition format is explicitly requested via `scrape_protocols`.
+//
+// Responses in OpenMetrics format are strictly validated only in this case, since many targets return
+// `application/openmetrics-text` Content-Type for responses, which do not conform to OpenMetrics specification.
+func (sw *ScrapeWork) isOpenMetricsRequested() bool {
+	for _, protocol := range sw.ScrapeProtocols {
+		if strings.HasPrefix(protocol, "OpenMetricsText") {
+			return true
+		}
+	}
+	return false
+}
+
 // key returns unique identifier for the given sw.
 //
 // It can be used for comparing for equality for two ScrapeWork objects.
@@ -166,13 +201,13 @@ func (sw *ScrapeWork) key() string {
 	key := fmt.Sprintf("JobNameOriginal=%s, ScrapeURL=%s, ScrapeInterval=%s, ScrapeTimeout=%s, HonorLabels=%v, "+
 		"HonorTimestamps=%v, DenyRedirects=%v, Labels=%s, ExternalLabels=%s, MaxScrapeSize=%d, "+
 		"ProxyURL=%s, ProxyAuthConfig=%s, AuthConfig=%s, MetricRelabelConfigs=%q, "+
-		"SampleLimit=%d, DisableCompression=%v, DisableKeepAlive=%v, StreamParse=%v, "+
-		"ScrapeAlignInterval=%s, ScrapeOffset=%s, SeriesLimit=%d, NoStaleMarkers=%v",
+		"SampleLimit=%d, LabelLimit=%d, LabelNameLengthLimit=%d, LabelValueLengthLimit=%d, ScrapeProtocols=%q, DisableCompression=%v, DisableKeepAlive=%v, StreamParse=%v, "+
+		"ScrapeAlignInterval=%s, ScrapeOffset=%s, SeriesLimit=%d, NoStaleMarkers=%v, Prober=%q",
 		sw.jobNameOriginal, sw.ScrapeURL, sw.ScrapeInterval, sw.ScrapeTimeout, sw.HonorLabels,
 		sw.HonorTimestamps, sw.DenyRedirects, sw.Labels.String(), sw.ExternalLabels.String(), sw.MaxScrapeSize,
 		sw.ProxyURL.String(), sw.ProxyAuthConfig.String(), sw.AuthConfig.String(), sw.MetricRelabelConfigs.String(),
-		sw.SampleLimit, sw.DisableCompression, sw.DisableKeepAlive, sw.StreamParse,
-		sw.ScrapeAlignInterval, sw.ScrapeOffset, sw.SeriesLimit, sw.NoStaleMarkers)
+		sw.SampleLimit, sw.LabelLimit, sw.LabelNameLengthLimit, sw.LabelValueLengthLimit, sw.ScrapeProtocols, sw.DisableCompression, sw.DisableKeepAlive, sw.StreamParse,
+		sw.ScrapeAlignInterval, sw.ScrapeOffset, sw.SeriesLimit, sw.NoStaleMarkers, sw.Prober.String())
 	return key
 }
 
@@ -186,7 +221,9 @@ type scrapeWork struct {
 	Config *ScrapeWork
 
 	// ReadData is called for reading the scrape response data into dst.
-	ReadData func(dst *bytesutil.ByteBuffer) error
+	//
+	// It returns the Content-Type of the response.
+	ReadData func(dst *bytesutil.ByteBuffer) (string, error)
 
 	// PushData is called for pushing collected data.
 	PushData func(at *auth.Token, wr *prompbmarshal.WriteRequest)
@@ -200,6 +237,9 @@ type scrapeWork struct {
 	// This flag is set to true if series_limit is exceeded.
 	seriesLimitExceeded bool
 
+	// labelLimitsExceeded is the number of scrapes, which were failed because of label limits.
+	labelLimitsExceeded int
+
 	// labelsHashBuf is used for calculating the hash on series labels
 	labelsHashBuf []byte
 
@@ -224,6 +264,19 @@ type scrapeWork struct {
 	// equals to or exceeds -promscrape.minResponseSizeForStreamParse
 	lastScrapeCompressed []byte
 
+	// lastScrapeIsOpenMetrics is set to true if lastScrape contains the response in OpenMetrics format.
+	// It is used for skipping `_created` series when sending stale markers, since they aren't stored for OpenMetrics responses.
+	lastScrapeIsOpenMetrics bool
+
+	// prevScrapeTimestamp contains the timestamp in milliseconds for the previous scrape.
+	// It is used for detecting counters created since the previous scrape in OpenMetrics responses.
+	prevScrapeTimestamp int64
+
+	// streamParseOverrideLogged is set to true after logging that the configured stream parsing mode
+	// cannot be used for the response format returned by the target.
+	// It is used for logging the warning only once per target.
+	streamParseOverrideLogged bool
+
 	// nextErrorLogTime is the timestamp in millisecond when the next scrape error should be logged.
 	nextErrorLogTime int64
 
@@ -392,6 +445,7 @@ var (
 	scrapeResponseSize          = metrics.NewHistogram("vm_promscrape_scrape_response_size_bytes")
 	scrapedSamples              = metrics.NewHistogram("vm_promscrape_scraped_samples")
 	scrapesSkippedBySampleLimit = metrics.NewCounter("vm_promscrape_scrapes_skipped_by_sample_limit_total")
+	scrapesSkippedByLabelLimit  = metrics.NewCounter("vm_promscrape_scrapes_skipped_by_label_limit_total")
 	scrapesFailed               = metrics.NewCounter("vm_promscrape_scrapes_failed_total")
 	pushDataDuration            = metrics.NewHistogram("vm_promscrape_push_data_duration_seconds")
 )
@@ -409,9 +463,18 @@ func (sw *scrapeWork) needStreamParseMode(responseSize int) bool {
 // getTargetResponse() fetches response from sw target in the same way as when scraping the target.
 func (sw *scrapeWork) getTargetResponse() ([]byte, error) {
 	var bb bytesutil.ByteBuffer
-	if err := sw.ReadData(&bb); err != nil {
+	contentType, err := sw.ReadData(&bb)
+	if err != nil {
 		return nil, err
 	}
+	if parser.IsProtobufContentType(contentType) {
+		// Convert protobuf response to human-readable text exposition format.
+		var rows parser.Rows
+		if err := rows.UnmarshalProtobuf(bb.B); err != nil {
+			return nil, fmt.Errorf("cannot parse protobuf response: %w", err)
+		}
+		return parser.AppendRowsAsText(nil, rows.Rows), nil
+	}
 	return bb.B, nil
 }
 
@@ -423,7 +486,11 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 	// is occupied during parsing of the read response body below.
 	// This also allows measuring the real scrape duration, which doesn't include
 	// the time needed for processing of the read response.
-	err := sw.ReadData(body)
+	contentType, err := sw.ReadData(body)
+	// Strictly parse OpenMetrics responses only if OpenMetrics format is explicitly requested via `scrape_protocols`.
+	// Otherwise such responses are parsed leniently in the same way as Prometheus text exposition format.
+	isOpenMetrics := parser.IsOpenMetricsContentType(contentType) && sw.Config.isOpenMetricsRequested()
+	isProtobuf := parser.IsProtobufContentType(contentType)
 
 	// Measure scrape duration.
 	endTimestamp := time.Now().UnixNano() / 1e6
@@ -437,7 +504,15 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 	// without sacrificing the performance.
 	processScrapedDataConcurrencyLimitCh <- struct{}{}
 
-	if err == nil && sw.needStreamParseMode(len(body.B)) {
+	needStreamParse := err == nil && sw.needStreamParseMode(len(body.B))
+	if needStreamParse && (isOpenMetrics || isProtobuf) {
+		// OpenMetrics responses are always processed at once, since strict validation of OpenMetrics
+		// requires the whole response, while the response body is already read into memory.
+		// Protobuf responses are always processed at once, since they are parsed directly into rows.
+		sw.logStreamParseOverride(contentType, isProtobuf)
+		needStreamParse = false
+	}
+	if needStreamParse {
 		// Process response body from scrape target in streaming manner.
 		// This case is optimized for targets exposing more than ten thousand of metrics per target,
 		// such as kube-state-metrics.
@@ -446,7 +521,7 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 		// Process response body from scrape target at once.
 		// This case should work more optimally than stream parse for common case when scrape target exposes
 		// up to a few thousand metrics.
-		err = sw.processDataOneShot(scrapeTimestamp, realTimestamp, body.B, scrapeDurationSeconds, err)
+		err = sw.processDataOneShot(scrapeTimestamp, realTimestamp, body.B, isOpenMetrics, isProtobuf, scrapeDurationSeconds, err)
 	}
 
 	<-processScrapedDataConcurrencyLimitCh
@@ -458,16 +533,65 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 
 var processScrapedDataConcurrencyLimitCh = make(chan struct{}, cgroup.AvailableCPUs())
 
-func (sw *scrapeWork) processDataOneShot(scrapeTimestamp, realTimestamp int64, body []byte, scrapeDurationSeconds float64, err error) error {
+// logStreamParseOverride registers the scrape, which is processed at once instead of the configured stream parsing mode
+// because of the response format returned by the target.
+//
+// The warning is logged only once per target in order to avoid log flooding.
+func (sw *scrapeWork) logStreamParseOverride(contentType string, isProtobuf bool) {
+	if isProtobuf {
+		streamParseOverriddenProtobuf.Inc()
+	} else {
+		streamParseOverriddenOpenMetrics.Inc()
+	}
+	if sw.streamParseOverrideLogged {
+		return
+	}
+	sw.streamParseOverrideLogged = true
+	logger.Warnf("target %q (%s) returned response with Content-Type %q, which doesn't support stream parsing mode; "+
+		"processing the response at once despite the configured stream parsing mode; this may result in higher memory usage; "+
+		"see https://docs.victoriametrics.com/vmagent/#stream-parsing-mode",
+		sw.Config.ScrapeURL, sw.Config.Labels.String(), contentType)
+}
+
+var (
+	streamParseOverriddenOpenMetrics = metrics.NewCounter(`vm_promscrape_stream_parse_overridden_total{format="openmetrics"}`)
+	streamParseOverriddenProtobuf    = metrics.NewCounter(`vm_promscrape_stream_parse_overridden_total{format="protobuf"}`)
+)
+
+func (sw *scrapeWork) processDataOneShot(scrapeTimestamp, realTimestamp int64, body []byte, isOpenMetrics, isProtobuf bool, scrapeDurationSeconds float64, err error) error {
 	up := 1
+	statusCode := getStatusCode(err)
 	wc := writeRequestCtxPool.Get(sw.prevLabelsLen)
 	lastScrape := sw.loadLastScrape()
 	bodyString := bytesutil.ToUnsafeString(body)
+	if err == nil && isOpenMetrics {
+		if errLocal := wc.rows.UnmarshalOpenMetrics(bodyString); errLocal != nil {
+			err = fmt.Errorf("cannot parse OpenMetrics response from %q: %w", sw.Config.ScrapeURL, errLocal)
+		}
+	}
+	currScrape := body
+	var seriesBuf *bytesutil.ByteBuffer
+	if isProtobuf {
+		currScrape = nil
+		bodyString = ""
+		if err == nil {
+			if errLocal := wc.rows.UnmarshalProtobuf(body); errLocal != nil {
+				err = fmt.Errorf("cannot parse protobuf response from %q: %w", sw.Config.ScrapeURL, errLocal)
+			} else if !sw.Config.NoStaleMarkers || sw.Config.SeriesLimit > 0 {
+				// Track the scraped series in text exposition format, so they could be compared
+				// to the series from the previous scrape in the same way as for text responses.
+				seriesBuf = bbPool.Get()
+				seriesBuf.B = parser.AppendRowsAsText(seriesBuf.B[:0], wc.rows.Rows)
+				currScrape = seriesBuf.B
+				bodyString = bytesutil.ToUnsafeString(currScrape)
+			}
+		}
+	}
 	areIdenticalSeries := sw.areIdenticalSeries(lastScrape, bodyString)
 	if err != nil {
 		up = 0
 		scrapesFailed.Inc()
-	} else {
+	} else if !isOpenMetrics && !isProtobuf {
 		wc.rows.UnmarshalWithErrLogger(bodyString, sw.logError)
 	}
 	srcRows := wc.rows.Rows
@@ -484,6 +608,13 @@ func (sw *scrapeWork) processDataOneShot(scrapeTimestamp, realTimestamp int64, b
 		err = fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
 			"either reduce the sample count for the target or increase sample_limit", sw.Config.ScrapeURL, sw.Config.SampleLimit)
 	}
+	if up == 1 {
+		if errLocal := sw.checkLabelLimits(wc); errLocal != nil {
+			wc.resetNoRows()
+			up = 0
+			err = errLocal
+		}
+	}
 	if up == 0 {
 		bodyString = ""
 	}
@@ -499,6 +630,9 @@ func (sw *scrapeWork) processDataOneShot(scrapeTimestamp, realTimestamp int64, b
 		samplesDropped = sw.applySeriesLimit(wc)
 	}
 	responseSize := len(bodyString)
+	if isProtobuf && up == 1 {
+		responseSize = len(body)
+	}
 	am := &autoMetrics{
 		up:                        up,
 		scrapeDurationSeconds:     scrapeDurationSeconds,
@@ -519,10 +653,15 @@ func (sw *scrapeWork) processDataOneShot(scrapeTimestamp, realTimestamp int64, b
 		// Send stale markers for disappeared metrics with the real scrape timestamp
 		// in order to guarantee that query doesn't return data after this time for the disappeared metrics.
 		sw.sendStaleSeries(lastScrape, bodyString, realTimestamp, false)
-		sw.storeLastScrape(body)
+		sw.storeLastScrape(currScrape)
+	}
+	if seriesBuf != nil {
+		bbPool.Put(seriesBuf)
 	}
+	sw.lastScrapeIsOpenMetrics = isOpenMetrics
+	sw.prevScrapeTimestamp = scrapeTimestamp
 	sw.finalizeLastScrape()
-	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(scrapeDurationSeconds*1000), responseSize, samplesScraped, err)
+	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(scrapeDurationSeconds*1000), responseSize, samplesScraped, statusCode, err, body)
 	return err
 }
 
@@ -553,6 +692,10 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 			return fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
 				"either reduce the sample count for the target or increase sample_limit", sw.Config.ScrapeURL, sw.Config.SampleLimit)
 		}
+		if err := sw.checkLabelLimits(wc); err != nil {
+			wc.resetNoRows()
+			return err
+		}
 		if sw.seriesLimitExceeded || !areIdenticalSeries {
 			samplesDropped += sw.applySeriesLimit(wc)
 		}
@@ -602,13 +745,80 @@ func (sw *scrapeWork) processDataInStreamMode(scrapeTimestamp, realTimestamp int
 		sw.sendStaleSeries(lastScrape, bodyString, realTimestamp, false)
 		sw.storeLastScrape(body.B)
 	}
+	sw.lastScrapeIsOpenMetrics = false
+	sw.prevScrapeTimestamp = scrapeTimestamp
 	sw.finalizeLastScrape()
-	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(scrapeDurationSeconds*1000), responseSize, samplesScraped, err)
+	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(scrapeDurationSeconds*1000), responseSize, samplesScraped, http.StatusOK, err, body.B)
 	// Do not track active series in streaming mode, since this may need too big amounts of memory
 	// when the target exports too big number of metrics.
 	return err
 }
 
+// checkLabelLimits verifies whether the series in wc exceed label_limit, label_name_length_limit or label_value_length_limit.
+//
+// The scrape must be treated as failed if non-nil error is returned.
+// Note that in stream parsing mode the series parsed before the failed check are already pushed to remote storage,
+// in the same way as for sample_limit.
+// See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#scrape_config
+func (sw *scrapeWork) checkLabelLimits(wc *writeRequestCtx) error {
+	cfg := sw.Config
+	if cfg.LabelLimit <= 0 && cfg.LabelNameLengthLimit <= 0 && cfg.LabelValueLengthLimit <= 0 {
+		return nil
+	}
+	err := getLabelLimitsError(wc.writeRequest.Timeseries, cfg.LabelLimit, cfg.LabelNameLengthLimit, cfg.LabelValueLengthLimit)
+	if err == nil {
+		return nil
+	}
+	scrapesSkippedByLabelLimit.Inc()
+	sw.labelLimitsExceeded++
+	return fmt.Errorf("the response from %q exceeds label limits: %w", cfg.ScrapeURL, err)
+}
+
+func getLabelLimitsError(tss []prompbmarshal.TimeSeries, labelLimit, labelNameLengthLimit, labelValueLengthLimit int) error {
+	for i := range tss {
+		labels := tss[i].Labels
+		if labelLimit > 0 && len(labels) > labelLimit {
+			return fmt.Errorf("series %s has %d labels, which exceeds label_limit=%d; "+
+				"either reduce the number of labels for the target or increase label_limit", labelsString(labels), len(labels), labelLimit)
+		}
+		for _, label := range labels {
+			if labelNameLengthLimit > 0 && len(label.Name) > labelNameLengthLimit {
+				return fmt.Errorf("series %s has label name %q with length %d, which exceeds label_name_length_limit=%d; "+
+					"either reduce label name length for the target or increase label_name_length_limit",
+					labelsString(labels), label.Name, len(label.Name), labelNameLengthLimit)
+			}
+			if labelValueLengthLimit > 0 && len(label.Value) > labelValueLengthLimit {
+				return fmt.Errorf("series %s has value for label %q with length %d, which exceeds label_value_length_limit=%d; "+
+					"either reduce label value length for the target or increase label_value_length_limit",
+					labelsString(labels), label.Name, len(label.Value), labelValueLengthLimit)
+			}
+		}
+	}
+	return nil
+}
+
+// labelsString returns string representation of labels, which is suitable for error messages.
+//
+// Too long label values are truncated in order to keep error messages readable.
+func labelsString(labels []prompbmarshal.Label) string {
+	var b []byte
+	b = append(b, '{')
+	for i, label := range labels {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		value := label.Value
+		if len(value) > 64 {
+			value = value[:64] + "..."
+		}
+		b = append(b, label.Name...)
+		b = append(b, '=')
+		b = strconv.AppendQuote(b, value)
+	}
+	b = append(b, '}')
+	return string(b)
+}
+
 func (sw *scrapeWork) pushData(at *auth.Token, wr *prompbmarshal.WriteRequest) {
 	startTime := time.Now()
 	sw.PushData(at, wr)
@@ -675,6 +885,7 @@ type writeRequestCtx struct {
 	writeRequest prompbmarshal.WriteRequest
 	labels       []prompbmarshal.Label
 	samples      []prompbmarshal.Sample
+	exemplars    []prompbmarshal.Exemplar
 }
 
 func (wc *writeRequestCtx) reset() {
@@ -689,6 +900,9 @@ func (wc *writeRequestCtx) resetNoRows() {
 	wc.labels = wc.labels[:0]
 
 	wc.samples = wc.samples[:0]
+
+	clear(wc.exemplars)
+	wc.exemplars = wc.exemplars[:0]
 }
 
 var writeRequestCtxPool leveledWriteRequestCtxPool
@@ -761,7 +975,12 @@ func (sw *scrapeWork) sendStaleSeries(lastScrape, currScrape string, timestamp i
 			mu.Lock()
 			defer mu.Unlock()
 			for i := range rows {
-				sw.addRowToTimeseries(wc, &rows[i], timestamp, true)
+				r := &rows[i]
+				if sw.lastScrapeIsOpenMetrics && strings.HasSuffix(r.Metric, "_created") {
+					// `_created` series aren't stored for OpenMetrics responses, so there is no need in sending stale markers for them.
+					continue
+				}
+				sw.addRowToTimeseries(wc, r, timestamp, true)
 			}
 			// Apply series limit to stale markers in order to prevent sending stale markers for newly created series.
 			// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/3660
@@ -831,6 +1050,10 @@ func isAutoMetric(s string) bool {
 	}
 	switch s {
 	case "scrape_duration_seconds",
+		"scrape_label_limit",
+		"scrape_label_limits_exceeded_total",
+		"scrape_label_name_length_limit",
+		"scrape_label_value_length_limit",
 		"scrape_response_size_bytes",
 		"scrape_samples_limit",
 		"scrape_samples_post_metric_relabeling",
@@ -849,6 +1072,20 @@ func isAutoMetric(s string) bool {
 func (sw *scrapeWork) addAutoMetrics(am *autoMetrics, wc *writeRequestCtx, timestamp int64) {
 	sw.addAutoTimeseries(wc, "scrape_duration_seconds", am.scrapeDurationSeconds, timestamp)
 	sw.addAutoTimeseries(wc, "scrape_response_size_bytes", float64(am.scrapeResponseSize), timestamp)
+	if cfg := sw.Config; cfg.LabelLimit > 0 || cfg.LabelNameLengthLimit > 0 || cfg.LabelValueLengthLimit > 0 {
+		// Expose label limits and the number of scrapes failed because of these limits
+		// if label_limit, label_name_length_limit or label_value_length_limit config is set for the target.
+		if cfg.LabelLimit > 0 {
+			sw.addAutoTimeseries(wc, "scrape_label_limit", float64(cfg.LabelLimit), timestamp)
+		}
+		if cfg.LabelNameLengthLimit > 0 {
+			sw.addAutoTimeseries(wc, "scrape_label_name_length_limit", float64(cfg.LabelNameLengthLimit), timestamp)
+		}
+		if cfg.LabelValueLengthLimit > 0 {
+			sw.addAutoTimeseries(wc, "scrape_label_value_length_limit", float64(cfg.LabelValueLengthLimit), timestamp)
+		}
+		sw.addAutoTimeseries(wc, "scrape_label_limits_exceeded_total", float64(sw.labelLimitsExceeded), timestamp)
+	}
 	if sampleLimit := sw.Config.SampleLimit; sampleLimit > 0 {
 		// Expose scrape_samples_limit metric if sample_limit config is set for the target.
 		// See https://github.com/VictoriaMetrics/operator/issues/497
@@ -917,15 +1154,54 @@ func (sw *scrapeWork) addRowToTimeseries(wc *writeRequestCtx, r *parser.Row, tim
 	if !sw.Config.HonorTimestamps || sampleTimestamp == 0 {
 		sampleTimestamp = timestamp
 	}
+	samplesLen := len(wc.samples)
+	if sw.needCreatedTimestampSample(r.CreatedTimestamp, sampleTimestamp) {
+		// The counter has been created or reset since the previous scrape.
+		// Add zero sample at the creation time, so increase() and rate() account for the first value of the counter.
+		// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#counter-1
+		wc.samples = append(wc.samples, prompbmarshal.Sample{
+			Timestamp: r.CreatedTimestamp,
+		})
+	}
 	wc.samples = append(wc.samples, prompbmarshal.Sample{
 		Value:     r.Value,
 		Timestamp: sampleTimestamp,
 	})
-	wr := &wc.writeRequest
-	wr.Timeseries = append(wr.Timeseries, prompbmarshal.TimeSeries{
+	ts := prompbmarshal.TimeSeries{
 		Labels:  wc.labels[labelsLen:],
-		Samples: wc.samples[len(wc.samples)-1:],
-	})
+		Samples: wc.samples[samplesLen:],
+	}
+	if e := r.Exemplar; e != nil {
+		exemplarLabelsLen := len(wc.labels)
+		for _, tag := range e.Tags {
+			wc.labels = append(wc.labels, prompbmarshal.Label{
+				Name:  tag.Key,
+				Value: tag.Value,
+			})
+		}
+		wc.exemplars = append(wc.exemplars, prompbmarshal.Exemplar{
+			Labels:    wc.labels[exemplarLabelsLen:],
+			Value:     e.Value,
+			Timestamp: e.Timestamp,
+		})
+		ts.Exemplars = wc.exemplars[len(wc.exemplars)-1:]
+	}
+	wr := &wc.writeRequest
+	wr.Timeseries = append(wr.Timeseries, ts)
+}
+
+// needCreatedTimestampSample returns true if zero sample must be added at createdTimestamp before the sample at sampleTimestamp.
+func (sw *scrapeWork) needCreatedTimestampSample(createdTimestamp, sampleTimestamp int64) bool {
+	if createdTimestamp <= 0 || createdTimestamp >= sampleTimestamp {
+		return false
+	}
+	minTimestamp := sw.prevScrapeTimestamp
+	if minTimestamp <= 0 {
+		// The first scrape - add zero sample only for counters created during the last scrape interval,
+		// since the older counters could be already scraped before the restart.
+		minTimestamp = sampleTimestamp - sw.Config.ScrapeInterval.Milliseconds()
+	}
+	return createdTimestamp > minTimestamp
 }
 
 var bbPool bytesutil.ByteBufferPool
@@ -2,10 +2,13 @@ package promscrape
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/VictoriaMetrics/easyproto"
+
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
@@ -33,6 +36,10 @@ func TestIsAutoMetric(t *testing.T) {
 	f("scrape_series_limit_samples_dropped", true)
 	f("scrape_series_limit", true)
 	f("scrape_series_current", true)
+	f("scrape_label_limit", true)
+	f("scrape_label_name_length_limit", true)
+	f("scrape_label_value_length_limit", true)
+	f("scrape_label_limits_exceeded_total", true)
 
 	f("foobar", false)
 	f("exported_up", false)
@@ -91,9 +98,9 @@ func TestScrapeWorkScrapeInternalFailure(t *testing.T) {
 	}
 
 	readDataCalls := 0
-	sw.ReadData = func(_ *bytesutil.ByteBuffer) error {
+	sw.ReadData = func(_ *bytesutil.ByteBuffer) (string, error) {
 		readDataCalls++
-		return fmt.Errorf("error when reading data")
+		return "", fmt.Errorf("error when reading data")
 	}
 
 	pushDataCalls := 0
@@ -122,6 +129,86 @@ func TestScrapeWorkScrapeInternalFailure(t *testing.T) {
 	}
 }
 
+func TestScrapeWorkScrapeInternalProtobuf(t *testing.T) {
+	var mp easyproto.MarshalerPool
+	m := mp.Get()
+	mm := m.MessageMarshaler()
+	mm.AppendString(1, "foo_total")
+	mm.AppendInt32(3, 0)
+	metric := mm.AppendMessage(4)
+	label := metric.AppendMessage(1)
+	label.AppendString(1, "job")
+	label.AppendString(2, "bar")
+	metric.AppendMessage(3).AppendDouble(1, 42)
+	data := m.MarshalWithLen(nil)
+
+	// Native histogram with schema=0 and a single positive bucket (1 .. 2]
+	m.Reset()
+	mm = m.MessageMarshaler()
+	mm.AppendString(1, "latency_seconds")
+	mm.AppendInt32(3, 4)
+	histogram := mm.AppendMessage(4).AppendMessage(7)
+	histogram.AppendUint64(1, 3)
+	histogram.AppendDouble(2, 4.5)
+	histogram.AppendSint32(5, 0)
+	span := histogram.AppendMessage(12)
+	span.AppendSint32(1, 1)
+	span.AppendUint32(2, 1)
+	histogram.AppendSint64s(13, []int64{3})
+	data = m.MarshalWithLen(data)
+	mp.Put(m)
+
+	dataExpected := fmt.Sprintf(`
+		foo_total{job="bar"} 42 123
+		latency_seconds_bucket{vmrange="1...2"} 3 123
+		latency_seconds_sum 4.5 123
+		latency_seconds_count 3 123
+		up 1 123
+		scrape_samples_scraped 4 123
+		scrape_response_size_bytes %d 123
+		scrape_duration_seconds 0 123
+		scrape_samples_post_metric_relabeling 4 123
+		scrape_series_added 4 123
+		scrape_timeout_seconds 42 123
+	`, len(data))
+	timeseriesExpected := parseData(dataExpected)
+
+	var sw scrapeWork
+	sw.Config = &ScrapeWork{
+		ScrapeTimeout: time.Second * 42,
+	}
+	sw.ReadData = func(dst *bytesutil.ByteBuffer) (string, error) {
+		dst.B = append(dst.B, data...)
+		return parser.ProtobufContentType, nil
+	}
+	var pushDataErr error
+	sw.PushData = func(_ *auth.Token, wr *prompbmarshal.WriteRequest) {
+		if err := expectEqualTimeseries(wr.Timeseries, timeseriesExpected); err != nil {
+			pushDataErr = fmt.Errorf("unexpected data pushed: %w\ngot\n%v\nwant\n%v", err, wr.Timeseries, timeseriesExpected)
+		}
+	}
+
+	timestamp := int64(123000)
+	tsmGlobal.Register(&sw)
+	defer tsmGlobal.Unregister(&sw)
+	if err := sw.scrapeInternal(timestamp, timestamp); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if pushDataErr != nil {
+		t.Fatalf("unexpected error: %s", pushDataErr)
+	}
+
+	// The series from the protobuf response must be tracked for sending stale markers.
+	lastScrapeExpected := `foo_total{job="bar"} 42
+latency_seconds_bucket{vmrange="1...2"} 3
+latency_seconds_sum 4.5
+latency_seconds_count 3
+`
+	if lastScrape := sw.loadLastScrape(); lastScrape != lastScrapeExpected {
+		t.Fatalf("unexpected last scrape;\ngot\n%s\nwant\n%s", lastScrape, lastScrapeExpected)
+	}
+}
+
 func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 	f := func(data string, cfg *ScrapeWork, dataExpected string) {
 		t.Helper()
@@ -132,10 +219,10 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 		sw.Config = cfg
 
 		readDataCalls := 0
-		sw.ReadData = func(dst *bytesutil.ByteBuffer) error {
+		sw.ReadData = func(dst *bytesutil.ByteBuffer) (string, error) {
 			readDataCalls++
 			dst.B = append(dst.B, data...)
-			return nil
+			return "", nil
 		}
 
 		pushDataCalls := 0
@@ -158,7 +245,7 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 		timestamp := int64(123000)
 		tsmGlobal.Register(&sw)
 		if err := sw.scrapeInternal(timestamp, timestamp); err != nil {
-			if !strings.Contains(err.Error(), "sample_limit") {
+			if !strings.Contains(err.Error(), "sample_limit") && !strings.Contains(err.Error(), "label limits") {
 				t.Fatalf("unexpected error: %s", err)
 			}
 		}
@@ -468,6 +555,83 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 		scrape_series_limit_samples_dropped 0 123
 		scrape_timeout_seconds 42 123
 	`)
+	// Scrape success with the given label limits.
+	f(`
+		foo{bar="baz"} 34.44
+		bar{a="b",c="d"} -3e4
+	`, &ScrapeWork{
+		ScrapeTimeout:         time.Second * 42,
+		LabelLimit:            3,
+		LabelNameLengthLimit:  8,
+		LabelValueLengthLimit: 3,
+	}, `
+		foo{bar="baz"} 34.44 123
+		bar{a="b",c="d"} -3e4 123
+		up 1 123
+		scrape_label_limit 3 123
+		scrape_label_name_length_limit 8 123
+		scrape_label_value_length_limit 3 123
+		scrape_label_limits_exceeded_total 0 123
+		scrape_response_size_bytes 49 123
+		scrape_samples_scraped 2 123
+		scrape_duration_seconds 0 123
+		scrape_samples_post_metric_relabeling 2 123
+		scrape_series_added 2 123
+		scrape_timeout_seconds 42 123
+	`)
+	// Scrape failure because of the exceeded LabelLimit
+	f(`
+		foo{bar="baz"} 34.44
+		bar{a="b",c="d"} -3e4
+	`, &ScrapeWork{
+		ScrapeTimeout: time.Second * 42,
+		LabelLimit:    2,
+	}, `
+		up 0 123
+		scrape_label_limit 2 123
+		scrape_label_limits_exceeded_total 1 123
+		scrape_response_size_bytes 0 123
+		scrape_samples_scraped 2 123
+		scrape_duration_seconds 0 123
+		scrape_samples_post_metric_relabeling 2 123
+		scrape_series_added 0 123
+		scrape_timeout_seconds 42 123
+	`)
+	// Scrape failure because of the exceeded LabelNameLengthLimit
+	f(`
+		foo{bar="baz"} 34.44
+		bar{a="b",long_label_name="d"} -3e4
+	`, &ScrapeWork{
+		ScrapeTimeout:        time.Second * 42,
+		LabelNameLengthLimit: 8,
+	}, `
+		up 0 123
+		scrape_label_name_length_limit 8 123
+		scrape_label_limits_exceeded_total 1 123
+		scrape_response_size_bytes 0 123
+		scrape_samples_scraped 2 123
+		scrape_duration_seconds 0 123
+		scrape_samples_post_metric_relabeling 2 123
+		scrape_series_added 0 123
+		scrape_timeout_seconds 42 123
+	`)
+	// Scrape failure because of the exceeded LabelValueLengthLimit
+	f(`
+		foo{bar="too long value"} 34.44
+	`, &ScrapeWork{
+		ScrapeTimeout:         time.Second * 42,
+		LabelValueLengthLimit: 10,
+	}, `
+		up 0 123
+		scrape_label_value_length_limit 10 123
+		scrape_label_limits_exceeded_total 1 123
+		scrape_response_size_bytes 0 123
+		scrape_samples_scraped 1 123
+		scrape_duration_seconds 0 123
+		scrape_samples_post_metric_relabeling 1 123
+		scrape_series_added 0 123
+		scrape_timeout_seconds 42 123
+	`)
 	// Exceed SeriesLimit.
 	f(`
 		foo{bar="baz"} 34.44
@@ -490,6 +654,76 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 	`)
 }
 
+func TestScrapeWorkScrapeInternalOpenMetrics(t *testing.T) {
+	f := func(scrapeProtocols []string, data string, resultExpected []string) {
+		t.Helper()
+
+		var sw scrapeWork
+		sw.Config = &ScrapeWork{
+			ScrapeInterval:  time.Minute,
+			ScrapeTimeout:   time.Second * 42,
+			ScrapeProtocols: scrapeProtocols,
+		}
+		sw.ReadData = func(dst *bytesutil.ByteBuffer) (string, error) {
+			dst.B = append(dst.B, data...)
+			return "application/openmetrics-text; version=1.0.0; charset=utf-8", nil
+		}
+		var result []string
+		sw.PushData = func(_ *auth.Token, wr *prompbmarshal.WriteRequest) {
+			for i := range wr.Timeseries {
+				ts := &wr.Timeseries[i]
+				metricName := promrelabel.GetLabelByName(ts.Labels, "__name__").Value
+				if isAutoMetric(metricName) && metricName != "up" {
+					continue
+				}
+				line := fmt.Sprintf("%s samples=%v", promrelabel.LabelsToString(ts.Labels), ts.Samples)
+				if len(ts.Exemplars) > 0 {
+					line += fmt.Sprintf(" exemplars=%v", ts.Exemplars)
+				}
+				result = append(result, line)
+			}
+		}
+
+		timestamp := int64(1700000000000)
+		tsmGlobal.Register(&sw)
+		_ = sw.scrapeInternal(timestamp, timestamp)
+		tsmGlobal.Unregister(&sw)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", strings.Join(result, "\n"), strings.Join(resultExpected, "\n"))
+		}
+	}
+
+	openMetricsProtocols := []string{"OpenMetricsText1.0.0", "PrometheusText0.0.4"}
+
+	// Invalid OpenMetrics response
+	f(openMetricsProtocols, "foo 1\n", []string{
+		`up samples=[{0 1700000000000}]`,
+	})
+
+	// Invalid OpenMetrics response is parsed leniently if OpenMetrics isn't requested via scrape_protocols
+	f(nil, "foo 1\n", []string{
+		`foo samples=[{1 1700000000000}]`,
+		`up samples=[{1 1700000000000}]`,
+	})
+	f([]string{"PrometheusText0.0.4"}, "foo 1\n", []string{
+		`foo samples=[{1 1700000000000}]`,
+		`up samples=[{1 1700000000000}]`,
+	})
+
+	// Counters with exemplars and created timestamps
+	f(openMetricsProtocols, `# TYPE foo counter
+foo_total{a="b"} 10 # {trace_id="abc"} 1.5 1699999999
+foo_created{a="b"} 1699999990
+foo_total{a="c"} 20
+foo_created{a="c"} 1600000000
+# EOF
+`, []string{
+		`foo_total{a="b"} samples=[{0 1699999990000} {10 1700000000000}] exemplars=[{[{trace_id abc}] 1.5 1699999999000}]`,
+		`foo_total{a="c"} samples=[{20 1700000000000}]`,
+		`up samples=[{1 1700000000000}]`,
+	})
+}
+
 func TestAddRowToTimeseriesNoRelabeling(t *testing.T) {
 	f := func(row string, cfg *ScrapeWork, dataExpected string) {
 		t.Helper()
@@ -1,6 +1,7 @@
 package promscrape
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -16,6 +17,8 @@ import (
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/cespare/xxhash/v2"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httputils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
@@ -26,13 +29,31 @@ var maxDroppedTargets = flag.Int("promscrape.maxDroppedTargets", 10000, "The max
 	"Increase this value if your setup drops more scrape targets during relabeling and you need investigating labels for all the dropped targets. "+
 	"Note that the increased number of tracked dropped targets may result in increased memory usage")
 
+var (
+	targetHistorySize = flag.Int("promscrape.targetHistorySize", 10, "The number of recent scrapes to keep per each scrape target. "+
+		"The scrape history is available at /targets page and at /api/v1/targets?history=1 . Set it to 0 for disabling scrape history")
+	maxFailedResponseSize = flagutil.NewBytes("promscrape.maxFailedResponseSize", 64*1024, "The maximum size of the response to keep per each scrape target for the last failed scrape. "+
+		"The response can be downloaded via /target_response?id=<id>&failed=1 . Bigger responses are truncated. Set it to 0 for disabling storing responses for failed scrapes")
+)
+
 var tsmGlobal = newTargetStatusMap()
 
 // WriteTargetResponse serves requests to /target_response?id=<id>
 //
 // It fetches response for the given target id and returns it.
+// If failed=1 query arg is set, then the response from the last failed scrape of the given target id is returned.
 func WriteTargetResponse(w http.ResponseWriter, r *http.Request) error {
 	targetID := r.FormValue("id")
+	if httputils.GetBool(r, "failed") {
+		data, ok := tsmGlobal.getFailedResponseByTargetID(targetID)
+		if !ok {
+			return fmt.Errorf("cannot find the response for the last failed scrape for id=%s", targetID)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="target_response_%s_failed.txt"`, targetID))
+		_, err := w.Write(data)
+		return err
+	}
 	sw := tsmGlobal.getScrapeWorkByTargetID(targetID)
 	if sw == nil {
 		return fmt.Errorf("cannot find target for id=%s", targetID)
@@ -68,19 +89,22 @@ func WriteServiceDiscovery(w http.ResponseWriter, r *http.Request) {
 }
 
 // WriteAPIV1Targets writes /api/v1/targets to w according to https://prometheus.io/docs/prometheus/latest/querying/api/#targets
-func WriteAPIV1Targets(w io.Writer, state string) {
+//
+// If scrapePool isn't empty, then only targets for the given scrapePool are returned.
+// If withHistory is set, then the recent scrape history is returned per each active target.
+func WriteAPIV1Targets(w io.Writer, state, scrapePool string, withHistory bool) {
 	if state == "" {
 		state = "any"
 	}
 	fmt.Fprintf(w, `{"status":"success","data":{"activeTargets":`)
 	if state == "active" || state == "any" {
-		tsmGlobal.WriteActiveTargetsJSON(w)
+		tsmGlobal.WriteActiveTargetsJSON(w, scrapePool, withHistory)
 	} else {
 		fmt.Fprintf(w, `[]`)
 	}
 	fmt.Fprintf(w, `,"droppedTargets":`)
 	if state == "dropped" || state == "any" {
-		droppedTargetsMap.WriteDroppedTargetsJSON(w)
+		droppedTargetsMap.WriteDroppedTargetsJSON(w, scrapePool)
 	} else {
 		fmt.Fprintf(w, `[]`)
 	}
@@ -179,7 +203,11 @@ func (tsm *targetStatusMap) Unregister(sw *scrapeWork) {
 	tsm.mu.Unlock()
 }
 
-func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDuration int64, scrapeResponseSize, samplesScraped int, err error) {
+// Update updates the status for sw after the scrape.
+//
+// statusCode must contain http status code for the scrape response or 0 if the response wasn't received.
+// body must contain the scrape response. It is stored at most -promscrape.maxFailedResponseSize bytes for failed scrapes.
+func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDuration int64, scrapeResponseSize, samplesScraped, statusCode int, err error, body []byte) {
 	jobName := sw.Config.jobNameOriginal
 
 	tsm.mu.Lock()
@@ -204,6 +232,22 @@ func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDu
 		ts.scrapesFailed++
 	}
 	ts.err = err
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	ts.history.add(scrapeHistoryEntry{
+		up:                 up,
+		scrapeTime:         scrapeTime,
+		scrapeDuration:     scrapeDuration,
+		scrapeResponseSize: scrapeResponseSize,
+		samplesScraped:     samplesScraped,
+		statusCode:         statusCode,
+		errMsg:             errMsg,
+	}, *targetHistorySize)
+	if !up {
+		ts.storeFailedResponse(scrapeTime, body, err)
+	}
 	tsm.mu.Unlock()
 }
 
@@ -219,6 +263,17 @@ func (tsm *targetStatusMap) getScrapeWorkByTargetID(targetID string) *scrapeWork
 	return nil
 }
 
+func (tsm *targetStatusMap) getFailedResponseByTargetID(targetID string) ([]byte, bool) {
+	tsm.mu.Lock()
+	defer tsm.mu.Unlock()
+	for sw, ts := range tsm.m {
+		if getLabelsID(sw.Config.OriginalLabels) == targetID {
+			return ts.failedResponse, ts.failedResponseTime > 0
+		}
+	}
+	return nil, false
+}
+
 func getLabelsID(labels *promutils.Labels) string {
 	return fmt.Sprintf("%016x", uintptr(unsafe.Pointer(labels)))
 }
@@ -241,7 +296,12 @@ func (tsm *targetStatusMap) getActiveTargetStatuses() []targetStatus {
 	tsm.mu.Lock()
 	tss := make([]targetStatus, 0, len(tsm.m))
 	for _, ts := range tsm.m {
-		tss = append(tss, *ts)
+		tsCopy := *ts
+		// The history is modified in-place by Update() call, so it must be copied.
+		tsCopy.history = scrapeHistory{
+			entries: ts.history.getEntries(),
+		}
+		tss = append(tss, tsCopy)
 	}
 	tsm.mu.Unlock()
 	// Sort discovered targets by __address__ label, so they stay in consistent order across calls
@@ -254,8 +314,17 @@ func (tsm *targetStatusMap) getActiveTargetStatuses() []targetStatus {
 }
 
 // WriteActiveTargetsJSON writes `activeTargets` contents to w according to https://prometheus.io/docs/prometheus/latest/querying/api/#targets
-func (tsm *targetStatusMap) WriteActiveTargetsJSON(w io.Writer) {
+func (tsm *targetStatusMap) WriteActiveTargetsJSON(w io.Writer, scrapePool string, withHistory bool) {
 	tss := tsm.getActiveTargetStatuses()
+	if scrapePool != "" {
+		tssFiltered := tss[:0]
+		for _, ts := range tss {
+			if ts.sw.Config.Job() == scrapePool {
+				tssFiltered = append(tssFiltered, ts)
+			}
+		}
+		tss = tssFiltered
+	}
 	fmt.Fprintf(w, `[`)
 	for i, ts := range tss {
 		fmt.Fprintf(w, `{"discoveredLabels":`)
@@ -276,7 +345,12 @@ func (tsm *targetStatusMap) WriteActiveTargetsJSON(w io.Writer) {
 		if !ts.up {
 			state = "down"
 		}
-		fmt.Fprintf(w, `,"health":%s}`, stringsutil.JSONString(state))
+		fmt.Fprintf(w, `,"health":%s`, stringsutil.JSONString(state))
+		if withHistory {
+			fmt.Fprintf(w, `,"scrapeHistory":`)
+			ts.history.writeJSON(w)
+		}
+		fmt.Fprintf(w, `}`)
 		if i+1 < len(tss) {
 			fmt.Fprintf(w, `,`)
 		}
@@ -306,6 +380,103 @@ type targetStatus struct {
 	scrapesTotal       int
 	scrapesFailed      int
 	err                error
+
+	// history contains recent scrapes for the target
+	history scrapeHistory
+
+	// failedResponse contains the response for the last failed scrape at failedResponseTime
+	failedResponse     []byte
+	failedResponseTime int64
+}
+
+// storeFailedResponse stores the response for the failed scrape at scrapeTime.
+//
+// The response is extracted from err if the target returned unexpected http status code.
+func (ts *targetStatus) storeFailedResponse(scrapeTime int64, body []byte, err error) {
+	maxSize := maxFailedResponseSize.IntN()
+	if maxSize <= 0 {
+		return
+	}
+	var se *statusCodeError
+	if errors.As(err, &se) {
+		body = se.body
+	}
+	if len(body) > maxSize {
+		body = body[:maxSize]
+	}
+	// Allocate a new slice instead of re-using ts.failedResponse, since it may be referred by copies of ts.
+	ts.failedResponse = append([]byte{}, body...)
+	ts.failedResponseTime = scrapeTime
+}
+
+func (ts *targetStatus) getHistory() []scrapeHistoryEntry {
+	return ts.history.getEntries()
+}
+
+// scrapeHistory is a ring buffer with recent scrapes for a single target.
+type scrapeHistory struct {
+	entries []scrapeHistoryEntry
+
+	// next is the index at entries for the next entry when entries are full
+	next int
+}
+
+type scrapeHistoryEntry struct {
+	up                 bool
+	scrapeTime         int64
+	scrapeDuration     int64
+	scrapeResponseSize int
+	samplesScraped     int
+	statusCode         int
+	errMsg             string
+}
+
+func (sh *scrapeHistory) add(e scrapeHistoryEntry, maxEntries int) {
+	if maxEntries <= 0 {
+		return
+	}
+	if len(sh.entries) < maxEntries {
+		sh.entries = append(sh.entries, e)
+		return
+	}
+	sh.entries[sh.next] = e
+	sh.next = (sh.next + 1) % len(sh.entries)
+}
+
+// getEntries returns a copy of sh entries ordered from the oldest to the newest.
+func (sh *scrapeHistory) getEntries() []scrapeHistoryEntry {
+	if len(sh.entries) == 0 {
+		return nil
+	}
+	entries := make([]scrapeHistoryEntry, 0, len(sh.entries))
+	entries = append(entries, sh.entries[sh.next:]...)
+	return append(entries, sh.entries[:sh.next]...)
+}
+
+func (sh *scrapeHistory) writeJSON(w io.Writer) {
+	entries := sh.getEntries()
+	fmt.Fprintf(w, `[`)
+	for i, e := range entries {
+		health := "up"
+		if !e.up {
+			health = "down"
+		}
+		fmt.Fprintf(w, `{"timestamp":"%s"`, time.UnixMilli(e.scrapeTime).Format(time.RFC3339Nano))
+		fmt.Fprintf(w, `,"health":%s`, stringsutil.JSONString(health))
+		fmt.Fprintf(w, `,"duration":%g`, (time.Millisecond * time.Duration(e.scrapeDuration)).Seconds())
+		fmt.Fprintf(w, `,"responseSize":%d`, e.scrapeResponseSize)
+		fmt.Fprintf(w, `,"samplesScraped":%d`, e.samplesScraped)
+		fmt.Fprintf(w, `,"statusCode":%d`, e.statusCode)
+		fmt.Fprintf(w, `,"error":%s}`, stringsutil.JSONString(e.errMsg))
+		if i+1 < len(entries) {
+			fmt.Fprintf(w, `,`)
+		}
+	}
+	fmt.Fprintf(w, `]`)
+}
+
+func (e *scrapeHistoryEntry) getScrapeTime() string {
+	return time.UnixMilli(e.scrapeTime).Format(time.RFC3339)
 }
 
 func (ts *targetStatus) getDurationFromLastScrape() string {
@@ -421,8 +592,19 @@ var xxhashPool = &sync.Pool{
 }
 
 // WriteDroppedTargetsJSON writes `droppedTargets` contents to w according to https://prometheus.io/docs/prometheus/latest/querying/api/#targets
-func (dt *droppedTargets) WriteDroppedTargetsJSON(w io.Writer) {
+//
+// If scrapePool isn't empty, then only targets with job=scrapePool label are returned.
+func (dt *droppedTargets) WriteDroppedTargetsJSON(w io.Writer, scrapePool string) {
 	dts := dt.getTargetsList()
+	if scrapePool != "" {
+		dtsFiltered := dts[:0]
+		for _, dt := range dts {
+			if dt.originalLabels.Get("job") == scrapePool {
+				dtsFiltered = append(dtsFiltered, dt)
+			}
+		}
+		dts = dtsFiltered
+	}
 	fmt.Fprintf(w, `[`)
 	for i, dt := range dts {
 		fmt.Fprintf(w, `{"discoveredLabels":`)
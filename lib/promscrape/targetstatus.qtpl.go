@@ -587,7 +587,7 @@ func streamscrapeJobTargets(qw422016 *qt422016.Writer, num int, jts *jobTargetsS
 //line lib/promscrape/targetstatus.qtpl:216
 	qw422016.N().S(`<th scope="col" title="total scrapes">Scrapes</th><th scope="col" title="total scrape errors">Errors</th><th scope="col" title="the time of the last scrape">Last Scrape</th><th scope="col" title="the duration of the last scrape">Duration</th><th scope="col" title="the size of the last scrape">Last Scrape Size</th><th scope="col" title="the number of metrics scraped during the last scrape">Samples</th><th scope="col" title="error from the last scrape (if any)">Last error</th></tr></thead><tbody>`)
 //line lib/promscrape/targetstatus.qtpl:227
-	for _, ts := range jts.targetsStatus {
+	for i, ts := range jts.targetsStatus {
 //line lib/promscrape/targetstatus.qtpl:229
 		endpoint := ts.sw.Config.ScrapeURL
 		originalLabels := ts.sw.Config.OriginalLabels
@@ -626,418 +626,570 @@ func streamscrapeJobTargets(qw422016 *qt422016.Writer, num int, jts *jobTargetsS
 //line lib/promscrape/targetstatus.qtpl:240
 			qw422016.N().S(`" target="_blank"title="click to fetch target response on behalf of the scraper">response</a>)`)
 //line lib/promscrape/targetstatus.qtpl:242
+			if ts.failedResponseTime > 0 {
+//line lib/promscrape/targetstatus.qtpl:243
+				qw422016.N().S(` `)
+//line lib/promscrape/targetstatus.qtpl:243
+				qw422016.N().S(`(<a href="target_response?id=`)
+//line lib/promscrape/targetstatus.qtpl:244
+				qw422016.E().S(targetID)
+//line lib/promscrape/targetstatus.qtpl:244
+				qw422016.N().S(`&failed=1"title="click to download the response from the last failed scrape">last failed response</a>)`)
+//line lib/promscrape/targetstatus.qtpl:246
+			}
+//line lib/promscrape/targetstatus.qtpl:247
 		}
-//line lib/promscrape/targetstatus.qtpl:242
+//line lib/promscrape/targetstatus.qtpl:248
+		if len(ts.history.entries) > 0 {
+//line lib/promscrape/targetstatus.qtpl:249
+			qw422016.N().S(` `)
+//line lib/promscrape/targetstatus.qtpl:249
+			qw422016.N().S(`(<a href="#" title="click to show recent scrapes"onclick="const e = document.getElementById('scrape-history-`)
+//line lib/promscrape/targetstatus.qtpl:251
+			qw422016.N().D(num)
+//line lib/promscrape/targetstatus.qtpl:251
+			qw422016.N().S(`-`)
+//line lib/promscrape/targetstatus.qtpl:251
+			qw422016.N().D(i)
+//line lib/promscrape/targetstatus.qtpl:251
+			qw422016.N().S(`'); e.style.display = e.style.display === 'none' ? 'table-row' : 'none'; return false">history</a>)`)
+//line lib/promscrape/targetstatus.qtpl:252
+		}
+//line lib/promscrape/targetstatus.qtpl:252
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:245
+//line lib/promscrape/targetstatus.qtpl:255
 		if ts.up {
-//line lib/promscrape/targetstatus.qtpl:245
+//line lib/promscrape/targetstatus.qtpl:255
 			qw422016.N().S(`<span class="badge bg-success">UP</span>`)
-//line lib/promscrape/targetstatus.qtpl:247
+//line lib/promscrape/targetstatus.qtpl:257
 		} else {
-//line lib/promscrape/targetstatus.qtpl:247
+//line lib/promscrape/targetstatus.qtpl:257
 			qw422016.N().S(`<span class="badge bg-danger">DOWN</span>`)
-//line lib/promscrape/targetstatus.qtpl:249
+//line lib/promscrape/targetstatus.qtpl:259
 		}
-//line lib/promscrape/targetstatus.qtpl:249
+//line lib/promscrape/targetstatus.qtpl:259
 		qw422016.N().S(`</td><td class="labels"><div`)
-//line lib/promscrape/targetstatus.qtpl:253
+//line lib/promscrape/targetstatus.qtpl:263
 		if hasOriginalLabels {
-//line lib/promscrape/targetstatus.qtpl:254
+//line lib/promscrape/targetstatus.qtpl:264
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:254
+//line lib/promscrape/targetstatus.qtpl:264
 			qw422016.N().S(`title="click to show original labels"onclick="document.getElementById('original-labels-`)
-//line lib/promscrape/targetstatus.qtpl:255
+//line lib/promscrape/targetstatus.qtpl:265
 			qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:255
+//line lib/promscrape/targetstatus.qtpl:265
 			qw422016.N().S(`').style.display='block'"`)
-//line lib/promscrape/targetstatus.qtpl:256
+//line lib/promscrape/targetstatus.qtpl:266
 		}
-//line lib/promscrape/targetstatus.qtpl:256
+//line lib/promscrape/targetstatus.qtpl:266
 		qw422016.N().S(`>`)
-//line lib/promscrape/targetstatus.qtpl:258
+//line lib/promscrape/targetstatus.qtpl:268
 		streamformatLabels(qw422016, ts.sw.Config.Labels)
-//line lib/promscrape/targetstatus.qtpl:258
+//line lib/promscrape/targetstatus.qtpl:268
 		qw422016.N().S(`</div>`)
-//line lib/promscrape/targetstatus.qtpl:260
+//line lib/promscrape/targetstatus.qtpl:270
 		if hasOriginalLabels {
-//line lib/promscrape/targetstatus.qtpl:260
+//line lib/promscrape/targetstatus.qtpl:270
 			qw422016.N().S(`<div style="display:none" id="original-labels-`)
-//line lib/promscrape/targetstatus.qtpl:261
+//line lib/promscrape/targetstatus.qtpl:271
 			qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:261
+//line lib/promscrape/targetstatus.qtpl:271
 			qw422016.N().S(`">`)
-//line lib/promscrape/targetstatus.qtpl:262
+//line lib/promscrape/targetstatus.qtpl:272
 			streamformatLabels(qw422016, originalLabels)
-//line lib/promscrape/targetstatus.qtpl:262
+//line lib/promscrape/targetstatus.qtpl:272
 			qw422016.N().S(`</div>`)
-//line lib/promscrape/targetstatus.qtpl:264
+//line lib/promscrape/targetstatus.qtpl:274
 		}
-//line lib/promscrape/targetstatus.qtpl:264
+//line lib/promscrape/targetstatus.qtpl:274
 		qw422016.N().S(`</td>`)
-//line lib/promscrape/targetstatus.qtpl:266
+//line lib/promscrape/targetstatus.qtpl:276
 		if hasOriginalLabels {
-//line lib/promscrape/targetstatus.qtpl:266
+//line lib/promscrape/targetstatus.qtpl:276
 			qw422016.N().S(`<td><a href="target-relabel-debug?id=`)
-//line lib/promscrape/targetstatus.qtpl:268
+//line lib/promscrape/targetstatus.qtpl:278
 			qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:268
+//line lib/promscrape/targetstatus.qtpl:278
 			qw422016.N().S(`" target="_blank">target</a>`)
-//line lib/promscrape/targetstatus.qtpl:268
+//line lib/promscrape/targetstatus.qtpl:278
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:268
+//line lib/promscrape/targetstatus.qtpl:278
 			qw422016.N().S(`<a href="metric-relabel-debug?id=`)
-//line lib/promscrape/targetstatus.qtpl:269
+//line lib/promscrape/targetstatus.qtpl:279
 			qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:269
+//line lib/promscrape/targetstatus.qtpl:279
 			qw422016.N().S(`" target="_blank">metrics</a></td>`)
-//line lib/promscrape/targetstatus.qtpl:271
+//line lib/promscrape/targetstatus.qtpl:281
 		}
-//line lib/promscrape/targetstatus.qtpl:271
+//line lib/promscrape/targetstatus.qtpl:281
 		qw422016.N().S(`<td>`)
-//line lib/promscrape/targetstatus.qtpl:272
+//line lib/promscrape/targetstatus.qtpl:282
 		qw422016.N().D(ts.scrapesTotal)
-//line lib/promscrape/targetstatus.qtpl:272
+//line lib/promscrape/targetstatus.qtpl:282
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:273
+//line lib/promscrape/targetstatus.qtpl:283
 		qw422016.N().D(ts.scrapesFailed)
-//line lib/promscrape/targetstatus.qtpl:273
+//line lib/promscrape/targetstatus.qtpl:283
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:274
+//line lib/promscrape/targetstatus.qtpl:284
 		qw422016.E().S(ts.getDurationFromLastScrape())
-//line lib/promscrape/targetstatus.qtpl:274
+//line lib/promscrape/targetstatus.qtpl:284
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:275
+//line lib/promscrape/targetstatus.qtpl:285
 		qw422016.N().D(int(ts.scrapeDuration))
-//line lib/promscrape/targetstatus.qtpl:275
+//line lib/promscrape/targetstatus.qtpl:285
 		qw422016.N().S(`ms</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:276
+//line lib/promscrape/targetstatus.qtpl:286
 		qw422016.E().S(ts.getSizeFromLastScrape())
-//line lib/promscrape/targetstatus.qtpl:276
+//line lib/promscrape/targetstatus.qtpl:286
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:277
+//line lib/promscrape/targetstatus.qtpl:287
 		qw422016.N().D(ts.samplesScraped)
-//line lib/promscrape/targetstatus.qtpl:277
+//line lib/promscrape/targetstatus.qtpl:287
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:278
+//line lib/promscrape/targetstatus.qtpl:288
 		if ts.err != nil {
-//line lib/promscrape/targetstatus.qtpl:278
+//line lib/promscrape/targetstatus.qtpl:288
 			qw422016.E().S(ts.err.Error())
-//line lib/promscrape/targetstatus.qtpl:278
+//line lib/promscrape/targetstatus.qtpl:288
 		}
-//line lib/promscrape/targetstatus.qtpl:278
+//line lib/promscrape/targetstatus.qtpl:288
 		qw422016.N().S(`</td></tr>`)
-//line lib/promscrape/targetstatus.qtpl:280
+//line lib/promscrape/targetstatus.qtpl:290
+		if len(ts.history.entries) > 0 {
+//line lib/promscrape/targetstatus.qtpl:290
+			qw422016.N().S(`<tr id="scrape-history-`)
+//line lib/promscrape/targetstatus.qtpl:291
+			qw422016.N().D(num)
+//line lib/promscrape/targetstatus.qtpl:291
+			qw422016.N().S(`-`)
+//line lib/promscrape/targetstatus.qtpl:291
+			qw422016.N().D(i)
+//line lib/promscrape/targetstatus.qtpl:291
+			qw422016.N().S(`" style="display:none"><td colspan="`)
+//line lib/promscrape/targetstatus.qtpl:292
+			if hasOriginalLabels {
+//line lib/promscrape/targetstatus.qtpl:292
+				qw422016.N().S(`11`)
+//line lib/promscrape/targetstatus.qtpl:292
+			} else {
+//line lib/promscrape/targetstatus.qtpl:292
+				qw422016.N().S(`10`)
+//line lib/promscrape/targetstatus.qtpl:292
+			}
+//line lib/promscrape/targetstatus.qtpl:292
+			qw422016.N().S(`">`)
+//line lib/promscrape/targetstatus.qtpl:293
+			streamscrapeHistoryTable(qw422016, ts.getHistory())
+//line lib/promscrape/targetstatus.qtpl:293
+			qw422016.N().S(`</td></tr>`)
+//line lib/promscrape/targetstatus.qtpl:296
+		}
+//line lib/promscrape/targetstatus.qtpl:297
 	}
-//line lib/promscrape/targetstatus.qtpl:280
+//line lib/promscrape/targetstatus.qtpl:297
 	qw422016.N().S(`</tbody></table></div></div></div>`)
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 }
 
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 func writescrapeJobTargets(qq422016 qtio422016.Writer, num int, jts *jobTargetsStatuses, hasOriginalLabels bool) {
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 	streamscrapeJobTargets(qw422016, num, jts, hasOriginalLabels)
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 }
 
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 func scrapeJobTargets(num int, jts *jobTargetsStatuses, hasOriginalLabels bool) string {
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 	writescrapeJobTargets(qb422016, num, jts, hasOriginalLabels)
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:286
+//line lib/promscrape/targetstatus.qtpl:303
 }
 
-//line lib/promscrape/targetstatus.qtpl:288
+//line lib/promscrape/targetstatus.qtpl:305
+func streamscrapeHistoryTable(qw422016 *qt422016.Writer, entries []scrapeHistoryEntry) {
+//line lib/promscrape/targetstatus.qtpl:305
+	qw422016.N().S(`<table class="table table-sm table-bordered mb-0"><thead><tr><th scope="col" title="the time of the scrape">Time</th><th scope="col">State</th><th scope="col" title="http status code of the scrape response">Status Code</th><th scope="col" title="the duration of the scrape">Duration</th><th scope="col" title="the size of the scrape response">Size</th><th scope="col" title="the number of metrics scraped">Samples</th><th scope="col" title="scrape error (if any)">Error</th></tr></thead><tbody>`)
+//line lib/promscrape/targetstatus.qtpl:319
+	for i := len(entries) - 1; i >= 0; i-- {
+//line lib/promscrape/targetstatus.qtpl:320
+		e := &entries[i]
+
+//line lib/promscrape/targetstatus.qtpl:320
+		qw422016.N().S(`<tr`)
+//line lib/promscrape/targetstatus.qtpl:321
+		if !e.up {
+//line lib/promscrape/targetstatus.qtpl:321
+			qw422016.N().S(` `)
+//line lib/promscrape/targetstatus.qtpl:321
+			qw422016.N().S(`class="alert alert-danger" role="alert"`)
+//line lib/promscrape/targetstatus.qtpl:321
+		}
+//line lib/promscrape/targetstatus.qtpl:321
+		qw422016.N().S(`><td>`)
+//line lib/promscrape/targetstatus.qtpl:322
+		qw422016.E().S(e.getScrapeTime())
+//line lib/promscrape/targetstatus.qtpl:322
+		qw422016.N().S(`</td><td>`)
+//line lib/promscrape/targetstatus.qtpl:323
+		if e.up {
+//line lib/promscrape/targetstatus.qtpl:323
+			qw422016.N().S(`UP`)
+//line lib/promscrape/targetstatus.qtpl:323
+		} else {
+//line lib/promscrape/targetstatus.qtpl:323
+			qw422016.N().S(`DOWN`)
+//line lib/promscrape/targetstatus.qtpl:323
+		}
+//line lib/promscrape/targetstatus.qtpl:323
+		qw422016.N().S(`</td><td>`)
+//line lib/promscrape/targetstatus.qtpl:324
+		if e.statusCode > 0 {
+//line lib/promscrape/targetstatus.qtpl:324
+			qw422016.N().D(e.statusCode)
+//line lib/promscrape/targetstatus.qtpl:324
+		}
+//line lib/promscrape/targetstatus.qtpl:324
+		qw422016.N().S(`</td><td>`)
+//line lib/promscrape/targetstatus.qtpl:325
+		qw422016.N().D(int(e.scrapeDuration))
+//line lib/promscrape/targetstatus.qtpl:325
+		qw422016.N().S(`ms</td><td>`)
+//line lib/promscrape/targetstatus.qtpl:326
+		qw422016.N().D(e.scrapeResponseSize)
+//line lib/promscrape/targetstatus.qtpl:326
+		qw422016.N().S(`</td><td>`)
+//line lib/promscrape/targetstatus.qtpl:327
+		qw422016.N().D(e.samplesScraped)
+//line lib/promscrape/targetstatus.qtpl:327
+		qw422016.N().S(`</td><td>`)
+//line lib/promscrape/targetstatus.qtpl:328
+		qw422016.E().S(e.errMsg)
+//line lib/promscrape/targetstatus.qtpl:328
+		qw422016.N().S(`</td></tr>`)
+//line lib/promscrape/targetstatus.qtpl:330
+	}
+//line lib/promscrape/targetstatus.qtpl:330
+	qw422016.N().S(`</tbody></table>`)
+//line lib/promscrape/targetstatus.qtpl:333
+}
+
+//line lib/promscrape/targetstatus.qtpl:333
+func writescrapeHistoryTable(qq422016 qtio422016.Writer, entries []scrapeHistoryEntry) {
+//line lib/promscrape/targetstatus.qtpl:333
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line lib/promscrape/targetstatus.qtpl:333
+	streamscrapeHistoryTable(qw422016, entries)
+//line lib/promscrape/targetstatus.qtpl:333
+	qt422016.ReleaseWriter(qw422016)
+//line lib/promscrape/targetstatus.qtpl:333
+}
+
+//line lib/promscrape/targetstatus.qtpl:333
+func scrapeHistoryTable(entries []scrapeHistoryEntry) string {
+//line lib/promscrape/targetstatus.qtpl:333
+	qb422016 := qt422016.AcquireByteBuffer()
+//line lib/promscrape/targetstatus.qtpl:333
+	writescrapeHistoryTable(qb422016, entries)
+//line lib/promscrape/targetstatus.qtpl:333
+	qs422016 := string(qb422016.B)
+//line lib/promscrape/targetstatus.qtpl:333
+	qt422016.ReleaseByteBuffer(qb422016)
+//line lib/promscrape/targetstatus.qtpl:333
+	return qs422016
+//line lib/promscrape/targetstatus.qtpl:333
+}
+
+//line lib/promscrape/targetstatus.qtpl:335
 func streamdiscoveredTargets(qw422016 *qt422016.Writer, tsr *targetsStatusResult) {
-//line lib/promscrape/targetstatus.qtpl:289
+//line lib/promscrape/targetstatus.qtpl:336
 	if !tsr.hasOriginalLabels {
-//line lib/promscrape/targetstatus.qtpl:289
+//line lib/promscrape/targetstatus.qtpl:336
 		qw422016.N().S(`<div class="alert alert-warning" role="alert">Discovered targets are unavailable when <b>-promscrape.dropOriginalLabels</b> command-line flag is set</div>`)
-//line lib/promscrape/targetstatus.qtpl:293
+//line lib/promscrape/targetstatus.qtpl:340
 		return
-//line lib/promscrape/targetstatus.qtpl:294
+//line lib/promscrape/targetstatus.qtpl:341
 	}
-//line lib/promscrape/targetstatus.qtpl:296
+//line lib/promscrape/targetstatus.qtpl:343
 	if n := droppedTargetsMap.getTotalTargets(); n > *maxDroppedTargets {
-//line lib/promscrape/targetstatus.qtpl:296
+//line lib/promscrape/targetstatus.qtpl:343
 		qw422016.N().S(`<div class="alert alert-warning" role="alert">Dropped targets' list below is incomplete, because the number of dropped targets exceeds <b>-promscrape.maxDroppedTargets=`)
-//line lib/promscrape/targetstatus.qtpl:298
+//line lib/promscrape/targetstatus.qtpl:345
 		qw422016.N().D(*maxDroppedTargets)
-//line lib/promscrape/targetstatus.qtpl:298
+//line lib/promscrape/targetstatus.qtpl:345
 		qw422016.N().S(`</b>.<br/>If you want to see the full list of dropped targets, then increase <b>-promscrape.maxDroppedTargets</b> command-line flag value to at least`)
-//line lib/promscrape/targetstatus.qtpl:299
+//line lib/promscrape/targetstatus.qtpl:346
 		qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:299
+//line lib/promscrape/targetstatus.qtpl:346
 		qw422016.N().S(`<b>`)
-//line lib/promscrape/targetstatus.qtpl:299
+//line lib/promscrape/targetstatus.qtpl:346
 		qw422016.N().D(n)
-//line lib/promscrape/targetstatus.qtpl:299
+//line lib/promscrape/targetstatus.qtpl:346
 		qw422016.N().S(`</b>.<br/>Note that this may increase memory usage.</div>`)
-//line lib/promscrape/targetstatus.qtpl:302
+//line lib/promscrape/targetstatus.qtpl:349
 	}
-//line lib/promscrape/targetstatus.qtpl:304
+//line lib/promscrape/targetstatus.qtpl:351
 	tljs := tsr.getTargetLabelsByJob()
 
-//line lib/promscrape/targetstatus.qtpl:304
+//line lib/promscrape/targetstatus.qtpl:351
 	qw422016.N().S(`<div class="row mt-4"><div class="col-12">`)
-//line lib/promscrape/targetstatus.qtpl:307
+//line lib/promscrape/targetstatus.qtpl:354
 	for i, tlj := range tljs {
-//line lib/promscrape/targetstatus.qtpl:308
+//line lib/promscrape/targetstatus.qtpl:355
 		streamdiscoveredJobTargets(qw422016, i, tlj)
-//line lib/promscrape/targetstatus.qtpl:309
+//line lib/promscrape/targetstatus.qtpl:356
 	}
-//line lib/promscrape/targetstatus.qtpl:309
+//line lib/promscrape/targetstatus.qtpl:356
 	qw422016.N().S(`</div></div>`)
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 }
 
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 func writediscoveredTargets(qq422016 qtio422016.Writer, tsr *targetsStatusResult) {
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 	streamdiscoveredTargets(qw422016, tsr)
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 }
 
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 func discoveredTargets(tsr *targetsStatusResult) string {
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 	writediscoveredTargets(qb422016, tsr)
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:312
+//line lib/promscrape/targetstatus.qtpl:359
 }
 
-//line lib/promscrape/targetstatus.qtpl:314
+//line lib/promscrape/targetstatus.qtpl:361
 func streamdiscoveredJobTargets(qw422016 *qt422016.Writer, num int, tlj *targetLabelsByJob) {
-//line lib/promscrape/targetstatus.qtpl:314
+//line lib/promscrape/targetstatus.qtpl:361
 	qw422016.N().S(`<h4><span class="me-2">`)
-//line lib/promscrape/targetstatus.qtpl:316
+//line lib/promscrape/targetstatus.qtpl:363
 	qw422016.E().S(tlj.jobName)
-//line lib/promscrape/targetstatus.qtpl:316
+//line lib/promscrape/targetstatus.qtpl:363
 	qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:316
+//line lib/promscrape/targetstatus.qtpl:363
 	qw422016.N().S(`(`)
-//line lib/promscrape/targetstatus.qtpl:316
+//line lib/promscrape/targetstatus.qtpl:363
 	qw422016.N().D(tlj.activeTargets)
-//line lib/promscrape/targetstatus.qtpl:316
+//line lib/promscrape/targetstatus.qtpl:363
 	qw422016.N().S(`/`)
-//line lib/promscrape/targetstatus.qtpl:316
+//line lib/promscrape/targetstatus.qtpl:363
 	qw422016.N().D(tlj.activeTargets + tlj.droppedTargets)
-//line lib/promscrape/targetstatus.qtpl:316
+//line lib/promscrape/targetstatus.qtpl:363
 	qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:316
+//line lib/promscrape/targetstatus.qtpl:363
 	qw422016.N().S(`active)</span>`)
-//line lib/promscrape/targetstatus.qtpl:317
+//line lib/promscrape/targetstatus.qtpl:364
 	streamshowHideScrapeJobButtons(qw422016, num)
-//line lib/promscrape/targetstatus.qtpl:317
+//line lib/promscrape/targetstatus.qtpl:364
 	qw422016.N().S(`</h4><div id="scrape-job-`)
-//line lib/promscrape/targetstatus.qtpl:319
+//line lib/promscrape/targetstatus.qtpl:366
 	qw422016.N().D(num)
-//line lib/promscrape/targetstatus.qtpl:319
+//line lib/promscrape/targetstatus.qtpl:366
 	qw422016.N().S(`" class="scrape-job table-responsive"><table class="table table-striped table-hover table-bordered table-sm"><thead><tr><th scope="col" style="width: 5%">Status</th><th scope="col" style="width: 60%">Discovered Labels</th><th scope="col" style="width: 30%">Target Labels</th><th scope="col" stile="width: 5%">Debug relabeling</a></tr></thead><tbody>`)
-//line lib/promscrape/targetstatus.qtpl:330
+//line lib/promscrape/targetstatus.qtpl:377
 	for _, t := range tlj.targets {
-//line lib/promscrape/targetstatus.qtpl:330
+//line lib/promscrape/targetstatus.qtpl:377
 		qw422016.N().S(`<tr`)
-//line lib/promscrape/targetstatus.qtpl:332
+//line lib/promscrape/targetstatus.qtpl:379
 		if !t.up {
-//line lib/promscrape/targetstatus.qtpl:333
+//line lib/promscrape/targetstatus.qtpl:380
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:333
+//line lib/promscrape/targetstatus.qtpl:380
 			qw422016.N().S(`role="alert"`)
-//line lib/promscrape/targetstatus.qtpl:333
+//line lib/promscrape/targetstatus.qtpl:380
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:334
+//line lib/promscrape/targetstatus.qtpl:381
 			if t.labels.Len() > 0 {
-//line lib/promscrape/targetstatus.qtpl:334
+//line lib/promscrape/targetstatus.qtpl:381
 				qw422016.N().S(`class="alert alert-danger"`)
-//line lib/promscrape/targetstatus.qtpl:336
+//line lib/promscrape/targetstatus.qtpl:383
 			} else {
-//line lib/promscrape/targetstatus.qtpl:336
+//line lib/promscrape/targetstatus.qtpl:383
 				qw422016.N().S(`class="alert alert-warning"`)
-//line lib/promscrape/targetstatus.qtpl:338
+//line lib/promscrape/targetstatus.qtpl:385
 			}
-//line lib/promscrape/targetstatus.qtpl:339
+//line lib/promscrape/targetstatus.qtpl:386
 		}
-//line lib/promscrape/targetstatus.qtpl:339
+//line lib/promscrape/targetstatus.qtpl:386
 		qw422016.N().S(`><td>`)
-//line lib/promscrape/targetstatus.qtpl:342
+//line lib/promscrape/targetstatus.qtpl:389
 		if t.up {
-//line lib/promscrape/targetstatus.qtpl:342
+//line lib/promscrape/targetstatus.qtpl:389
 			qw422016.N().S(`<span class="badge bg-success">UP</span>`)
-//line lib/promscrape/targetstatus.qtpl:344
+//line lib/promscrape/targetstatus.qtpl:391
 		} else if t.labels.Len() > 0 {
-//line lib/promscrape/targetstatus.qtpl:344
+//line lib/promscrape/targetstatus.qtpl:391
 			qw422016.N().S(`<span class="badge bg-danger">DOWN</span>`)
-//line lib/promscrape/targetstatus.qtpl:346
+//line lib/promscrape/targetstatus.qtpl:393
 		} else {
-//line lib/promscrape/targetstatus.qtpl:346
+//line lib/promscrape/targetstatus.qtpl:393
 			qw422016.N().S(`<span class="badge bg-warning">DROPPED (`)
-//line lib/promscrape/targetstatus.qtpl:347
+//line lib/promscrape/targetstatus.qtpl:394
 			qw422016.E().S(string(t.dropReason))
-//line lib/promscrape/targetstatus.qtpl:347
+//line lib/promscrape/targetstatus.qtpl:394
 			qw422016.N().S(`)</span>`)
-//line lib/promscrape/targetstatus.qtpl:348
+//line lib/promscrape/targetstatus.qtpl:395
 			if len(t.clusterMemberNums) > 0 {
-//line lib/promscrape/targetstatus.qtpl:348
+//line lib/promscrape/targetstatus.qtpl:395
 				qw422016.N().S(`<br/><span title="The target exists at vmagent instances with the given -promscrape.cluster.memberNum values">exists at`)
-//line lib/promscrape/targetstatus.qtpl:351
+//line lib/promscrape/targetstatus.qtpl:398
 				qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:352
+//line lib/promscrape/targetstatus.qtpl:399
 				for i, memberNum := range t.clusterMemberNums {
-//line lib/promscrape/targetstatus.qtpl:353
+//line lib/promscrape/targetstatus.qtpl:400
 					if *clusterMemberURLTemplate == "" {
-//line lib/promscrape/targetstatus.qtpl:353
+//line lib/promscrape/targetstatus.qtpl:400
 						qw422016.N().S(`shard-`)
-//line lib/promscrape/targetstatus.qtpl:354
+//line lib/promscrape/targetstatus.qtpl:401
 						qw422016.N().D(memberNum)
-//line lib/promscrape/targetstatus.qtpl:355
+//line lib/promscrape/targetstatus.qtpl:402
 					} else {
-//line lib/promscrape/targetstatus.qtpl:355
+//line lib/promscrape/targetstatus.qtpl:402
 						qw422016.N().S(`<a href="`)
-//line lib/promscrape/targetstatus.qtpl:356
+//line lib/promscrape/targetstatus.qtpl:403
 						qw422016.E().S(strings.ReplaceAll(*clusterMemberURLTemplate, "%d", strconv.Itoa(memberNum)))
-//line lib/promscrape/targetstatus.qtpl:356
+//line lib/promscrape/targetstatus.qtpl:403
 						qw422016.N().S(`" target="_blank">shard-`)
-//line lib/promscrape/targetstatus.qtpl:356
+//line lib/promscrape/targetstatus.qtpl:403
 						qw422016.N().D(memberNum)
-//line lib/promscrape/targetstatus.qtpl:356
+//line lib/promscrape/targetstatus.qtpl:403
 						qw422016.N().S(`</a>`)
-//line lib/promscrape/targetstatus.qtpl:357
+//line lib/promscrape/targetstatus.qtpl:404
 					}
-//line lib/promscrape/targetstatus.qtpl:358
+//line lib/promscrape/targetstatus.qtpl:405
 					if i+1 < len(t.clusterMemberNums) {
-//line lib/promscrape/targetstatus.qtpl:358
+//line lib/promscrape/targetstatus.qtpl:405
 						qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:358
+//line lib/promscrape/targetstatus.qtpl:405
 						qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:358
+//line lib/promscrape/targetstatus.qtpl:405
 					}
-//line lib/promscrape/targetstatus.qtpl:359
+//line lib/promscrape/targetstatus.qtpl:406
 				}
-//line lib/promscrape/targetstatus.qtpl:360
+//line lib/promscrape/targetstatus.qtpl:407
 			}
-//line lib/promscrape/targetstatus.qtpl:361
+//line lib/promscrape/targetstatus.qtpl:408
 		}
-//line lib/promscrape/targetstatus.qtpl:361
+//line lib/promscrape/targetstatus.qtpl:408
 		qw422016.N().S(`</td><td class="labels">`)
-//line lib/promscrape/targetstatus.qtpl:364
+//line lib/promscrape/targetstatus.qtpl:411
 		streamformatLabels(qw422016, t.originalLabels)
-//line lib/promscrape/targetstatus.qtpl:364
+//line lib/promscrape/targetstatus.qtpl:411
 		qw422016.N().S(`</td><td class="labels">`)
-//line lib/promscrape/targetstatus.qtpl:367
+//line lib/promscrape/targetstatus.qtpl:414
 		streamformatLabels(qw422016, t.labels)
-//line lib/promscrape/targetstatus.qtpl:367
+//line lib/promscrape/targetstatus.qtpl:414
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:370
+//line lib/promscrape/targetstatus.qtpl:417
 		targetID := getLabelsID(t.originalLabels)
 
-//line lib/promscrape/targetstatus.qtpl:370
+//line lib/promscrape/targetstatus.qtpl:417
 		qw422016.N().S(`<a href="target-relabel-debug?id=`)
-//line lib/promscrape/targetstatus.qtpl:371
+//line lib/promscrape/targetstatus.qtpl:418
 		qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:371
+//line lib/promscrape/targetstatus.qtpl:418
 		qw422016.N().S(`" target="_blank">debug</a></td></tr>`)
-//line lib/promscrape/targetstatus.qtpl:374
+//line lib/promscrape/targetstatus.qtpl:421
 	}
-//line lib/promscrape/targetstatus.qtpl:374
+//line lib/promscrape/targetstatus.qtpl:421
 	qw422016.N().S(`</tbody></table></div>`)
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 }
 
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 func writediscoveredJobTargets(qq422016 qtio422016.Writer, num int, tlj *targetLabelsByJob) {
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 	streamdiscoveredJobTargets(qw422016, num, tlj)
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 }
 
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 func discoveredJobTargets(num int, tlj *targetLabelsByJob) string {
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 	writediscoveredJobTargets(qb422016, num, tlj)
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:378
+//line lib/promscrape/targetstatus.qtpl:425
 }
 
-//line lib/promscrape/targetstatus.qtpl:380
+//line lib/promscrape/targetstatus.qtpl:427
 func streamshowHideScrapeJobButtons(qw422016 *qt422016.Writer, num int) {
-//line lib/promscrape/targetstatus.qtpl:380
+//line lib/promscrape/targetstatus.qtpl:427
 	qw422016.N().S(`<button type="button" class="btn btn-primary btn-sm me-1"onclick="document.getElementById('scrape-job-`)
-//line lib/promscrape/targetstatus.qtpl:382
+//line lib/promscrape/targetstatus.qtpl:429
 	qw422016.N().D(num)
-//line lib/promscrape/targetstatus.qtpl:382
+//line lib/promscrape/targetstatus.qtpl:429
 	qw422016.N().S(`').style.display='none'">collapse</button><button type="button" class="btn btn-secondary btn-sm me-1"onclick="document.getElementById('scrape-job-`)
-//line lib/promscrape/targetstatus.qtpl:386
+//line lib/promscrape/targetstatus.qtpl:433
 	qw422016.N().D(num)
-//line lib/promscrape/targetstatus.qtpl:386
+//line lib/promscrape/targetstatus.qtpl:433
 	qw422016.N().S(`').style.display='block'">expand</button>`)
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 }
 
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 func writeshowHideScrapeJobButtons(qq422016 qtio422016.Writer, num int) {
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 	streamshowHideScrapeJobButtons(qw422016, num)
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 }
 
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 func showHideScrapeJobButtons(num int) string {
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 	writeshowHideScrapeJobButtons(qb422016, num)
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:389
+//line lib/promscrape/targetstatus.qtpl:436
 }
 
-//line lib/promscrape/targetstatus.qtpl:391
+//line lib/promscrape/targetstatus.qtpl:438
 func streamqueryArgs(qw422016 *qt422016.Writer, filter *requestFilter, override map[string]string) {
-//line lib/promscrape/targetstatus.qtpl:393
+//line lib/promscrape/targetstatus.qtpl:440
 	showOnlyUnhealthy := "false"
 	if filter.showOnlyUnhealthy {
 		showOnlyUnhealthy = "true"
@@ -1055,89 +1207,89 @@ func streamqueryArgs(qw422016 *qt422016.Writer, filter *requestFilter, override
 		qa[k] = []string{v}
 	}
 
-//line lib/promscrape/targetstatus.qtpl:410
+//line lib/promscrape/targetstatus.qtpl:457
 	qw422016.E().S(qa.Encode())
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 }
 
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 func writequeryArgs(qq422016 qtio422016.Writer, filter *requestFilter, override map[string]string) {
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 	streamqueryArgs(qw422016, filter, override)
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 }
 
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 func queryArgs(filter *requestFilter, override map[string]string) string {
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 	writequeryArgs(qb422016, filter, override)
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:411
+//line lib/promscrape/targetstatus.qtpl:458
 }
 
-//line lib/promscrape/targetstatus.qtpl:413
+//line lib/promscrape/targetstatus.qtpl:460
 func streamformatLabels(qw422016 *qt422016.Writer, labels *promutils.Labels) {
-//line lib/promscrape/targetstatus.qtpl:414
+//line lib/promscrape/targetstatus.qtpl:461
 	labelsList := labels.GetLabels()
 
-//line lib/promscrape/targetstatus.qtpl:414
+//line lib/promscrape/targetstatus.qtpl:461
 	qw422016.N().S(`{`)
-//line lib/promscrape/targetstatus.qtpl:416
+//line lib/promscrape/targetstatus.qtpl:463
 	for i, label := range labelsList {
-//line lib/promscrape/targetstatus.qtpl:417
+//line lib/promscrape/targetstatus.qtpl:464
 		qw422016.E().S(label.Name)
-//line lib/promscrape/targetstatus.qtpl:417
+//line lib/promscrape/targetstatus.qtpl:464
 		qw422016.N().S(`=`)
-//line lib/promscrape/targetstatus.qtpl:417
+//line lib/promscrape/targetstatus.qtpl:464
 		qw422016.E().Q(label.Value)
-//line lib/promscrape/targetstatus.qtpl:418
+//line lib/promscrape/targetstatus.qtpl:465
 		if i+1 < len(labelsList) {
-//line lib/promscrape/targetstatus.qtpl:418
+//line lib/promscrape/targetstatus.qtpl:465
 			qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:418
+//line lib/promscrape/targetstatus.qtpl:465
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:418
+//line lib/promscrape/targetstatus.qtpl:465
 		}
-//line lib/promscrape/targetstatus.qtpl:419
+//line lib/promscrape/targetstatus.qtpl:466
 	}
-//line lib/promscrape/targetstatus.qtpl:419
+//line lib/promscrape/targetstatus.qtpl:466
 	qw422016.N().S(`}`)
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 }
 
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 func writeformatLabels(qq422016 qtio422016.Writer, labels *promutils.Labels) {
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 	streamformatLabels(qw422016, labels)
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 }
 
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 func formatLabels(labels *promutils.Labels) string {
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 	writeformatLabels(qb422016, labels)
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:421
+//line lib/promscrape/targetstatus.qtpl:468
 }
@@ -0,0 +1,133 @@
+package promscrape
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestScrapeHistory(t *testing.T) {
+	f := func(scrapeTimes []int64, maxEntries int, resultExpected []int64) {
+		t.Helper()
+
+		var sh scrapeHistory
+		for _, scrapeTime := range scrapeTimes {
+			sh.add(scrapeHistoryEntry{
+				scrapeTime: scrapeTime,
+			}, maxEntries)
+		}
+		var result []int64
+		for _, e := range sh.getEntries() {
+			result = append(result, e.scrapeTime)
+		}
+		if fmt.Sprint(result) != fmt.Sprint(resultExpected) {
+			t.Fatalf("unexpected entries; got %v; want %v", result, resultExpected)
+		}
+	}
+
+	// empty history
+	f(nil, 3, nil)
+
+	// disabled history
+	f([]int64{1, 2, 3}, 0, nil)
+
+	// history isn't full
+	f([]int64{1, 2}, 3, []int64{1, 2})
+
+	// history is full
+	f([]int64{1, 2, 3}, 3, []int64{1, 2, 3})
+
+	// history is overwritten
+	f([]int64{1, 2, 3, 4, 5}, 3, []int64{3, 4, 5})
+	f([]int64{1, 2, 3, 4, 5, 6, 7}, 3, []int64{5, 6, 7})
+}
+
+func TestGetStatusCode(t *testing.T) {
+	f := func(err error, statusCodeExpected int) {
+		t.Helper()
+
+		statusCode := getStatusCode(err)
+		if statusCode != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d", statusCode, statusCodeExpected)
+		}
+	}
+
+	f(nil, 200)
+	f(fmt.Errorf("cannot perform request"), 0)
+	f(&statusCodeError{
+		statusCode: 503,
+	}, 503)
+	f(fmt.Errorf("wrapped: %w", &statusCodeError{
+		statusCode: 404,
+	}), 404)
+}
+
+func TestTargetStatusMapUpdate(t *testing.T) {
+	tsm := newTargetStatusMap()
+	sw := &scrapeWork{
+		Config: &ScrapeWork{
+			ScrapeURL: "http://foo/metrics",
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"job": "foo",
+			}),
+			OriginalLabels: promutils.NewLabelsFromMap(map[string]string{
+				"__address__": "foo",
+				"job":         "foo",
+			}),
+			jobNameOriginal: "foo",
+		},
+	}
+	tsm.Register(sw)
+	tsm.Update(sw, true, 1000, 10, 100, 5, 200, nil, []byte("foo 1"))
+	err := &statusCodeError{
+		scrapeURL:  "http://foo/metrics",
+		statusCode: 503,
+		body:       []byte("unavailable"),
+	}
+	tsm.Update(sw, false, 2000, 20, 0, 0, 503, err, nil)
+
+	targetID := getLabelsID(sw.Config.OriginalLabels)
+	data, ok := tsm.getFailedResponseByTargetID(targetID)
+	if !ok {
+		t.Fatalf("missing failed response")
+	}
+	if string(data) != "unavailable" {
+		t.Fatalf("unexpected failed response; got %q; want %q", data, "unavailable")
+	}
+
+	f := func(scrapePool string, withHistory bool, resultExpected string) {
+		t.Helper()
+
+		var bb bytes.Buffer
+		tsm.WriteActiveTargetsJSON(&bb, scrapePool, withHistory)
+		result := bb.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// Scrape times are formatted in local timezone
+	t1 := time.UnixMilli(1000).Format(time.RFC3339Nano)
+	t2 := time.UnixMilli(2000).Format(time.RFC3339Nano)
+
+	// missing scrapePool
+	f("bar", true, `[]`)
+
+	// without history
+	f("foo", false, `[{"discoveredLabels":{"__address__":"foo","job":"foo"},"labels":{"job":"foo"},"scrapePool":"foo","scrapeUrl":"http://foo/metrics",`+
+		`"lastError":"unexpected status code returned when scraping \"http://foo/metrics\": 503; expecting 200; response body: \"unavailable\"",`+
+		`"lastScrape":"`+t2+`","lastScrapeDuration":0.02,"lastSamplesScraped":0,"health":"down"}]`)
+
+	// with history
+	f("", true, `[{"discoveredLabels":{"__address__":"foo","job":"foo"},"labels":{"job":"foo"},"scrapePool":"foo","scrapeUrl":"http://foo/metrics",`+
+		`"lastError":"unexpected status code returned when scraping \"http://foo/metrics\": 503; expecting 200; response body: \"unavailable\"",`+
+		`"lastScrape":"`+t2+`","lastScrapeDuration":0.02,"lastSamplesScraped":0,"health":"down","scrapeHistory":[`+
+		`{"timestamp":"`+t1+`","health":"up","duration":0.01,"responseSize":100,"samplesScraped":5,"statusCode":200,"error":""},`+
+		`{"timestamp":"`+t2+`","health":"down","duration":0.02,"responseSize":0,"samplesScraped":0,"statusCode":503,`+
+		`"error":"unexpected status code returned when scraping \"http://foo/metrics\": 503; expecting 200; response body: \"unavailable\""}]}]`)
+
+	tsm.Unregister(sw)
+}
@@ -14,7 +14,7 @@ import (
 // It also extracts Pushgateways-compatible extra labels from req.URL.Path
 // according to https://github.com/prometheus/pushgateway#url .
 func GetExtraLabels(req *http.Request) ([]prompbmarshal.Label, error) {
-	labels, err := getPushgatewayLabels(req.URL.Path)
+	labels, err := GetPushgatewayLabels(req.URL.Path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse pushgateway-style labels from %q: %w", req.URL.Path, err)
 	}
@@ -32,7 +32,11 @@ func GetExtraLabels(req *http.Request) ([]prompbmarshal.Label, error) {
 	return labels, nil
 }
 
-func getPushgatewayLabels(path string) ([]prompbmarshal.Label, error) {
+// GetPushgatewayLabels returns Pushgateway-compatible grouping labels from the given path.
+//
+// Labels are extracted from the path suffix starting with `/metrics/job/` according to https://github.com/prometheus/pushgateway#url .
+// Labels with empty values are skipped. Nil labels are returned if the path doesn't contain `/metrics/job/` part.
+func GetPushgatewayLabels(path string) ([]prompbmarshal.Label, error) {
 	n := strings.Index(path, "/metrics/job")
 	if n < 0 {
 		return nil, nil
@@ -0,0 +1,386 @@
+package nagios
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+// Rows contains parsed Nagios performance data rows.
+type Rows struct {
+	Rows []Row
+
+	tagsPool []Tag
+}
+
+// Reset resets rs.
+func (rs *Rows) Reset() {
+	// Reset items, so they can be GC'ed
+
+	for i := range rs.Rows {
+		rs.Rows[i].reset()
+	}
+	rs.Rows = rs.Rows[:0]
+
+	for i := range rs.tagsPool {
+		rs.tagsPool[i].reset()
+	}
+	rs.tagsPool = rs.tagsPool[:0]
+}
+
+// Unmarshal unmarshals Nagios performance data lines from s.
+//
+// Every line may contain either raw performance data such as `'label'=value[UOM];[warn];[crit];[min];[max]`
+// or tab-separated KEY::VALUE fields written by Nagios perfdata file templates such as
+// `DATATYPE::SERVICEPERFDATA	TIMET::1700000000	HOSTNAME::host	SERVICEDESC::svc	SERVICEPERFDATA::'label'=1s;2;3`.
+//
+// See https://nagios-plugins.org/doc/guidelines.html#AEN200
+//
+// s shouldn't be modified when rs is in use.
+func (rs *Rows) Unmarshal(s string) {
+	rs.Rows, rs.tagsPool = unmarshalRows(rs.Rows[:0], s, rs.tagsPool[:0])
+}
+
+// Row is a single time series sample obtained from Nagios performance data.
+type Row struct {
+	Metric    string
+	Tags      []Tag
+	Value     float64
+	Timestamp int64
+}
+
+func (r *Row) reset() {
+	r.Metric = ""
+	r.Tags = nil
+	r.Value = 0
+	r.Timestamp = 0
+}
+
+// Tag is a label for the Row.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+func (t *Tag) reset() {
+	t.Key = ""
+	t.Value = ""
+}
+
+func unmarshalRows(dst []Row, s string, tagsPool []Tag) ([]Row, []Tag) {
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '\n')
+		if n < 0 {
+			// The last line.
+			return unmarshalLine(dst, s, tagsPool)
+		}
+		dst, tagsPool = unmarshalLine(dst, s[:n], tagsPool)
+		s = s[n+1:]
+	}
+	return dst, tagsPool
+}
+
+func unmarshalLine(dst []Row, s string, tagsPool []Tag) ([]Row, []Tag) {
+	if len(s) > 0 && s[len(s)-1] == '\r' {
+		s = s[:len(s)-1]
+	}
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		// Skip empty line
+		return dst, tagsPool
+	}
+
+	var lc lineContext
+	lc.perfdata = s
+	if strings.Contains(s, "::") {
+		if err := lc.unmarshalFields(s); err != nil {
+			logger.Errorf("cannot unmarshal Nagios performance data line %q: %s", s, err)
+			invalidLines.Inc()
+			return dst, tagsPool
+		}
+	}
+
+	dstLen := len(dst)
+	tagsPoolLen := len(tagsPool)
+	dst, tagsPool, err := lc.appendRows(dst, tagsPool)
+	if err != nil {
+		dst = dst[:dstLen]
+		tagsPool = tagsPool[:tagsPoolLen]
+		logger.Errorf("cannot unmarshal Nagios performance data line %q: %s", s, err)
+		invalidLines.Inc()
+	}
+	return dst, tagsPool
+}
+
+var invalidLines = metrics.NewCounter(`vm_rows_invalid_total{type="nagios"}`)
+
+type lineContext struct {
+	host      string
+	service   string
+	perfdata  string
+	timestamp int64
+}
+
+// unmarshalFields unmarshals tab-separated KEY::VALUE fields from s.
+func (lc *lineContext) unmarshalFields(s string) error {
+	lc.perfdata = ""
+	for len(s) > 0 {
+		field := s
+		n := strings.IndexByte(s, '\t')
+		if n >= 0 {
+			field = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		n = strings.Index(field, "::")
+		if n < 0 {
+			return fmt.Errorf("missing '::' in the field %q", field)
+		}
+		key := field[:n]
+		value := field[n+2:]
+		switch key {
+		case "TIMET":
+			ts, err := fastfloat.Parse(value)
+			if err != nil {
+				return fmt.Errorf("cannot parse TIMET=%q: %w", value, err)
+			}
+			lc.timestamp = int64(ts * 1e3)
+		case "HOSTNAME":
+			lc.host = value
+		case "SERVICEDESC":
+			lc.service = value
+		case "SERVICEPERFDATA", "HOSTPERFDATA":
+			lc.perfdata = value
+		}
+	}
+	return nil
+}
+
+func (lc *lineContext) appendRows(dst []Row, tagsPool []Tag) ([]Row, []Tag, error) {
+	s := lc.perfdata
+	for {
+		s = strings.TrimLeft(s, " ")
+		if len(s) == 0 {
+			return dst, tagsPool, nil
+		}
+		label, tail, err := unmarshalLabel(s)
+		if err != nil {
+			return dst, tagsPool, err
+		}
+		item := tail
+		n := strings.IndexByte(tail, ' ')
+		if n >= 0 {
+			item = tail[:n]
+			s = tail[n+1:]
+		} else {
+			s = ""
+		}
+		dst, tagsPool, err = lc.appendItemRows(dst, tagsPool, label, item)
+		if err != nil {
+			return dst, tagsPool, fmt.Errorf("cannot parse performance data for label %q: %w", label, err)
+		}
+	}
+}
+
+// unmarshalLabel unmarshals the label from the beginning of s until '='.
+//
+// The label may be enclosed into single quotes. Single quote inside quoted label must be escaped with another single quote.
+func unmarshalLabel(s string) (string, string, error) {
+	if s[0] != '\'' {
+		n := strings.IndexByte(s, '=')
+		if n < 0 {
+			return "", s, fmt.Errorf("missing '=' after label in %q", s)
+		}
+		label := s[:n]
+		if strings.IndexByte(label, ' ') >= 0 {
+			return "", s, fmt.Errorf("label %q must be enclosed into single quotes, since it contains whitespace", label)
+		}
+		if len(label) == 0 {
+			return "", s, fmt.Errorf("label cannot be empty")
+		}
+		return label, s[n+1:], nil
+	}
+	var b []byte
+	tail := s[1:]
+	for {
+		n := strings.IndexByte(tail, '\'')
+		if n < 0 {
+			return "", s, fmt.Errorf("missing closing quote for label in %q", s)
+		}
+		b = append(b, tail[:n]...)
+		tail = tail[n+1:]
+		if strings.HasPrefix(tail, "'") {
+			// Escaped quote
+			b = append(b, '\'')
+			tail = tail[1:]
+			continue
+		}
+		if !strings.HasPrefix(tail, "=") {
+			return "", s, fmt.Errorf("missing '=' after label in %q", s)
+		}
+		if len(b) == 0 {
+			return "", s, fmt.Errorf("label cannot be empty")
+		}
+		return string(b), tail[1:], nil
+	}
+}
+
+// appendItemRows appends rows for a single performance data item `value[UOM];[warn];[crit];[min];[max]` with the given label.
+func (lc *lineContext) appendItemRows(dst []Row, tagsPool []Tag, label, item string) ([]Row, []Tag, error) {
+	fields := strings.Split(item, ";")
+	valueStr := fields[0]
+	n := 0
+	for n < len(valueStr) && strings.IndexByte("0123456789.-+", valueStr[n]) >= 0 {
+		n++
+	}
+	uom := valueStr[n:]
+	valueStr = valueStr[:n]
+	unit, multiplier := normalizeUnit(uom)
+	if uom == "U" && valueStr == "" {
+		// The value cannot be determined by the plugin.
+		unit = ""
+		multiplier = 1
+	}
+
+	tagsStart := len(tagsPool)
+	if lc.host != "" {
+		tagsPool = append(tagsPool, Tag{
+			Key:   "host",
+			Value: lc.host,
+		})
+	}
+	if lc.service != "" {
+		tagsPool = append(tagsPool, Tag{
+			Key:   "service",
+			Value: lc.service,
+		})
+	}
+	tagsPool = append(tagsPool, Tag{
+		Key:   "label",
+		Value: label,
+	})
+	if unit != "" {
+		tagsPool = append(tagsPool, Tag{
+			Key:   "unit",
+			Value: unit,
+		})
+	}
+	tags := tagsPool[tagsStart:]
+	tags = tags[:len(tags):len(tags)]
+
+	addRow := func(metric string, v float64) {
+		if cap(dst) > len(dst) {
+			dst = dst[:len(dst)+1]
+		} else {
+			dst = append(dst, Row{})
+		}
+		r := &dst[len(dst)-1]
+		r.Metric = metric
+		r.Tags = tags
+		r.Value = v * multiplier
+		r.Timestamp = lc.timestamp
+	}
+
+	if valueStr != "" {
+		v, err := fastfloat.Parse(valueStr)
+		if err != nil {
+			return dst, tagsPool, fmt.Errorf("cannot parse value %q: %w", valueStr, err)
+		}
+		addRow("nagios_perfdata", v)
+	} else if uom != "U" {
+		return dst, tagsPool, fmt.Errorf("missing value")
+	}
+
+	for i, metric := range []string{"nagios_perfdata_warning", "nagios_perfdata_critical"} {
+		if i+1 >= len(fields) {
+			break
+		}
+		start, end, err := parseRange(fields[i+1])
+		if err != nil {
+			return dst, tagsPool, fmt.Errorf("cannot parse threshold %q: %w", fields[i+1], err)
+		}
+		if start != "" {
+			v, err := fastfloat.Parse(start)
+			if err != nil {
+				return dst, tagsPool, fmt.Errorf("cannot parse threshold start %q: %w", start, err)
+			}
+			addRow(metric+"_low", v)
+		}
+		if end != "" {
+			v, err := fastfloat.Parse(end)
+			if err != nil {
+				return dst, tagsPool, fmt.Errorf("cannot parse threshold end %q: %w", end, err)
+			}
+			addRow(metric, v)
+		}
+	}
+	for i, metric := range []string{"nagios_perfdata_min", "nagios_perfdata_max"} {
+		if i+3 >= len(fields) || fields[i+3] == "" {
+			continue
+		}
+		v, err := fastfloat.Parse(fields[i+3])
+		if err != nil {
+			return dst, tagsPool, fmt.Errorf("cannot parse %s=%q: %w", metric, fields[i+3], err)
+		}
+		addRow(metric, v)
+	}
+	return dst, tagsPool, nil
+}
+
+// parseRange parses Nagios threshold range `[@][start:]end` and returns start and end.
+//
+// The start is empty if it is missing, zero or negative infinity (~). The end is empty if it is missing (positive infinity).
+//
+// See https://nagios-plugins.org/doc/guidelines.html#THRESHOLDFORMAT
+func parseRange(s string) (string, string, error) {
+	s = strings.TrimPrefix(s, "@")
+	n := strings.IndexByte(s, ':')
+	if n < 0 {
+		return "", s, nil
+	}
+	start := s[:n]
+	end := s[n+1:]
+	if start == "~" || start == "0" {
+		start = ""
+	}
+	if strings.IndexByte(end, ':') >= 0 {
+		return "", "", fmt.Errorf("unexpected ':' in the range end")
+	}
+	return start, end, nil
+}
+
+// normalizeUnit returns base unit name and the multiplier for converting values with the given Nagios unit of measurement to the base unit.
+func normalizeUnit(uom string) (string, float64) {
+	switch strings.ToLower(uom) {
+	case "":
+		return "", 1
+	case "s":
+		return "seconds", 1
+	case "ms":
+		return "seconds", 1e-3
+	case "us":
+		return "seconds", 1e-6
+	case "ns":
+		return "seconds", 1e-9
+	case "%":
+		return "percent", 1
+	case "b":
+		return "bytes", 1
+	case "kb", "kib":
+		return "bytes", 1 << 10
+	case "mb", "mib":
+		return "bytes", 1 << 20
+	case "gb", "gib":
+		return "bytes", 1 << 30
+	case "tb", "tib":
+		return "bytes", 1 << 40
+	case "c":
+		return "counter", 1
+	default:
+		return uom, 1
+	}
+}
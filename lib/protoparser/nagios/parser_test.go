@@ -0,0 +1,329 @@
+package nagios
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowsUnmarshalFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows: %+v", len(rows.Rows), rows.Rows)
+		}
+
+		// Try again
+		rows.Unmarshal(s)
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows: %+v", len(rows.Rows), rows.Rows)
+		}
+	}
+
+	// Missing value
+	f("foo")
+	f("foo=")
+	f("foo=;1;2")
+
+	// Invalid value
+	f("foo=bar")
+	f("foo=1..2s")
+
+	// Empty label
+	f("=1")
+	f("''=1")
+
+	// Missing closing quote
+	f("'foo bar=1")
+
+	// Whitespace in unquoted label
+	f("foo bar=1")
+
+	// Invalid thresholds
+	f("foo=1;bar")
+	f("foo=1;1:2:3")
+	f("foo=1;;;x")
+
+	// Invalid fields
+	f("TIMET::foo\tSERVICEPERFDATA::foo=1")
+	f("HOSTNAME::foo\tbar\tSERVICEPERFDATA::foo=1")
+}
+
+func TestRowsUnmarshalSuccess(t *testing.T) {
+	f := func(s string, rowsExpected []Row) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+
+		// Try unmarshaling again
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows at the second unmarshal;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+
+		rows.Reset()
+		if len(rows.Rows) != 0 {
+			t.Fatalf("non-empty rows after reset: %+v", rows.Rows)
+		}
+	}
+
+	// Empty line
+	f("", nil)
+	f("\r\n", nil)
+
+	// Value without unit
+	labelTags := []Tag{{
+		Key:   "label",
+		Value: "users",
+	}}
+	f("users=5", []Row{{
+		Metric: "nagios_perfdata",
+		Tags:   labelTags,
+		Value:  5,
+	}})
+
+	// Value with thresholds, min and max
+	f("users=5;10;20;0;100", []Row{
+		{
+			Metric: "nagios_perfdata",
+			Tags:   labelTags,
+			Value:  5,
+		},
+		{
+			Metric: "nagios_perfdata_warning",
+			Tags:   labelTags,
+			Value:  10,
+		},
+		{
+			Metric: "nagios_perfdata_critical",
+			Tags:   labelTags,
+			Value:  20,
+		},
+		{
+			Metric: "nagios_perfdata_min",
+			Tags:   labelTags,
+			Value:  0,
+		},
+		{
+			Metric: "nagios_perfdata_max",
+			Tags:   labelTags,
+			Value:  100,
+		},
+	})
+
+	// Threshold ranges
+	f("users=5;@2:10;~:20", []Row{
+		{
+			Metric: "nagios_perfdata",
+			Tags:   labelTags,
+			Value:  5,
+		},
+		{
+			Metric: "nagios_perfdata_warning_low",
+			Tags:   labelTags,
+			Value:  2,
+		},
+		{
+			Metric: "nagios_perfdata_warning",
+			Tags:   labelTags,
+			Value:  10,
+		},
+		{
+			Metric: "nagios_perfdata_critical",
+			Tags:   labelTags,
+			Value:  20,
+		},
+	})
+	f("users=5;10:", []Row{
+		{
+			Metric: "nagios_perfdata",
+			Tags:   labelTags,
+			Value:  5,
+		},
+		{
+			Metric: "nagios_perfdata_warning_low",
+			Tags:   labelTags,
+			Value:  10,
+		},
+	})
+
+	// Unknown value
+	f("users=U;10", []Row{{
+		Metric: "nagios_perfdata_warning",
+		Tags:   labelTags,
+		Value:  10,
+	}})
+
+	// Units are normalized to base units
+	f("time=250ms;500;1000 'disk usage'=2KB;;;0;4 load=12%", []Row{
+		{
+			Metric: "nagios_perfdata",
+			Tags: []Tag{
+				{
+					Key:   "label",
+					Value: "time",
+				},
+				{
+					Key:   "unit",
+					Value: "seconds",
+				},
+			},
+			Value: 0.25,
+		},
+		{
+			Metric: "nagios_perfdata_warning",
+			Tags: []Tag{
+				{
+					Key:   "label",
+					Value: "time",
+				},
+				{
+					Key:   "unit",
+					Value: "seconds",
+				},
+			},
+			Value: 0.5,
+		},
+		{
+			Metric: "nagios_perfdata_critical",
+			Tags: []Tag{
+				{
+					Key:   "label",
+					Value: "time",
+				},
+				{
+					Key:   "unit",
+					Value: "seconds",
+				},
+			},
+			Value: 1,
+		},
+		{
+			Metric: "nagios_perfdata",
+			Tags: []Tag{
+				{
+					Key:   "label",
+					Value: "disk usage",
+				},
+				{
+					Key:   "unit",
+					Value: "bytes",
+				},
+			},
+			Value: 2048,
+		},
+		{
+			Metric: "nagios_perfdata_min",
+			Tags: []Tag{
+				{
+					Key:   "label",
+					Value: "disk usage",
+				},
+				{
+					Key:   "unit",
+					Value: "bytes",
+				},
+			},
+			Value: 0,
+		},
+		{
+			Metric: "nagios_perfdata_max",
+			Tags: []Tag{
+				{
+					Key:   "label",
+					Value: "disk usage",
+				},
+				{
+					Key:   "unit",
+					Value: "bytes",
+				},
+			},
+			Value: 4096,
+		},
+		{
+			Metric: "nagios_perfdata",
+			Tags: []Tag{
+				{
+					Key:   "label",
+					Value: "load",
+				},
+				{
+					Key:   "unit",
+					Value: "percent",
+				},
+			},
+			Value: 12,
+		},
+	})
+
+	// Unknown unit is left as is
+	f("'it''s'=3rpm", []Row{{
+		Metric: "nagios_perfdata",
+		Tags: []Tag{
+			{
+				Key:   "label",
+				Value: "it's",
+			},
+			{
+				Key:   "unit",
+				Value: "rpm",
+			},
+		},
+		Value: 3,
+	}})
+
+	// Perfdata file line
+	f("DATATYPE::SERVICEPERFDATA\tTIMET::1700000000\tHOSTNAME::web1\tSERVICEDESC::HTTP\tSERVICEPERFDATA::time=0.5s\tSERVICESTATE::OK", []Row{{
+		Metric: "nagios_perfdata",
+		Tags: []Tag{
+			{
+				Key:   "host",
+				Value: "web1",
+			},
+			{
+				Key:   "service",
+				Value: "HTTP",
+			},
+			{
+				Key:   "label",
+				Value: "time",
+			},
+			{
+				Key:   "unit",
+				Value: "seconds",
+			},
+		},
+		Value:     0.5,
+		Timestamp: 1700000000000,
+	}})
+	f("DATATYPE::HOSTPERFDATA\tTIMET::1700000000\tHOSTNAME::web1\tHOSTPERFDATA::pl=0%", []Row{{
+		Metric: "nagios_perfdata",
+		Tags: []Tag{
+			{
+				Key:   "host",
+				Value: "web1",
+			},
+			{
+				Key:   "label",
+				Value: "pl",
+			},
+			{
+				Key:   "unit",
+				Value: "percent",
+			},
+		},
+		Value:     0,
+		Timestamp: 1700000000000,
+	}})
+
+	// Invalid lines are skipped
+	f("foo\nusers=5\nbar=baz", []Row{{
+		Metric: "nagios_perfdata",
+		Tags:   labelTags,
+		Value:  5,
+	}})
+}
@@ -0,0 +1,562 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+// OpenMetricsContentType is the Content-Type for OpenMetrics text exposition format.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md
+const OpenMetricsContentType = "application/openmetrics-text"
+
+// IsOpenMetricsContentType returns true if contentType is the Content-Type for OpenMetrics text exposition format.
+func IsOpenMetricsContentType(contentType string) bool {
+	mediaType := contentType
+	if n := strings.IndexByte(mediaType, ';'); n >= 0 {
+		mediaType = mediaType[:n]
+	}
+	return strings.EqualFold(strings.TrimSpace(mediaType), OpenMetricsContentType)
+}
+
+// Exemplar is an OpenMetrics exemplar attached to Row.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#exemplars
+type Exemplar struct {
+	// Tags contains exemplar labels such as trace_id.
+	Tags []Tag
+
+	// Value is the exemplar value.
+	Value float64
+
+	// Timestamp is an optional exemplar timestamp in milliseconds.
+	Timestamp int64
+}
+
+// UnmarshalOpenMetrics strictly unmarshals OpenMetrics text exposition rows from s.
+//
+// Unlike Unmarshal, it returns an error if s doesn't conform to OpenMetrics 1.0 specification, e.g. if metric families
+// are interleaved, samples do not match the declared family type or `# EOF` is missing.
+// Exemplars are stored in Row.Exemplar. `_created` samples for counters, histograms and summaries
+// are removed from the result and are stored in Row.CreatedTimestamp of the corresponding samples instead.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md
+//
+// s shouldn't be modified while rs is in use.
+func (rs *Rows) UnmarshalOpenMetrics(s string) error {
+	rs.Reset()
+	noEscapes := strings.IndexByte(s, '\\') < 0
+	var p omParser
+	rows, tagsPool, err := p.unmarshalRows(rs.Rows[:0], s, rs.tagsPool[:0], noEscapes)
+	rs.Rows, rs.tagsPool = rows, tagsPool
+	if err != nil {
+		rs.Reset()
+		invalidLines.Inc()
+		return err
+	}
+	rowsReadScrape.Add(len(rs.Rows))
+	return nil
+}
+
+// omFamily contains the state for the currently parsed OpenMetrics metric family.
+type omFamily struct {
+	name string
+	typ  string
+	unit string
+
+	hasType    bool
+	hasHelp    bool
+	hasUnit    bool
+	hasSamples bool
+}
+
+type omParser struct {
+	// seenFamilies contains names of the already parsed metric families.
+	seenFamilies map[string]struct{}
+
+	// family is the currently parsed metric family.
+	family omFamily
+
+	// createdTimestamps contains `_created` values in milliseconds keyed by the family name and labels.
+	createdTimestamps map[string]int64
+
+	// families contains family names per each parsed row.
+	families []string
+
+	keyBuf []byte
+}
+
+func (p *omParser) unmarshalRows(dst []Row, s string, tagsPool []Tag, noEscapes bool) ([]Row, []Tag, error) {
+	dstLen := len(dst)
+	eofSeen := false
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '\n')
+		line := s
+		if n < 0 {
+			s = ""
+		} else {
+			line = s[:n]
+			s = s[n+1:]
+		}
+		if eofSeen {
+			return dst, tagsPool, fmt.Errorf("unexpected data after `# EOF`: %q", line)
+		}
+		if line == "# EOF" {
+			eofSeen = true
+			continue
+		}
+		if n < 0 {
+			return dst, tagsPool, fmt.Errorf("missing newline at the end of line %q", line)
+		}
+		var err error
+		dst, tagsPool, err = p.unmarshalLine(dst, line, tagsPool, noEscapes)
+		if err != nil {
+			return dst, tagsPool, fmt.Errorf("cannot unmarshal OpenMetrics line %q: %w", line, err)
+		}
+	}
+	if !eofSeen {
+		return dst, tagsPool, fmt.Errorf("missing `# EOF` at the end of OpenMetrics response")
+	}
+	dst = p.applyCreatedTimestamps(dst, dstLen)
+	return dst, tagsPool, nil
+}
+
+func (p *omParser) unmarshalLine(dst []Row, line string, tagsPool []Tag, noEscapes bool) ([]Row, []Tag, error) {
+	if len(line) == 0 {
+		return dst, tagsPool, fmt.Errorf("empty lines aren't allowed")
+	}
+	if line[0] == '#' {
+		return dst, tagsPool, p.unmarshalMetadata(line)
+	}
+
+	if cap(dst) > len(dst) {
+		dst = dst[:len(dst)+1]
+	} else {
+		dst = append(dst, Row{})
+	}
+	r := &dst[len(dst)-1]
+	var err error
+	tagsPool, err = p.unmarshalSample(r, line, tagsPool, noEscapes)
+	if err != nil {
+		return dst[:len(dst)-1], tagsPool, err
+	}
+	return dst, tagsPool, nil
+}
+
+func (p *omParser) unmarshalMetadata(line string) error {
+	if !strings.HasPrefix(line, "# ") {
+		return fmt.Errorf("comments aren't allowed")
+	}
+	line = line[len("# "):]
+	n := strings.IndexByte(line, ' ')
+	if n < 0 {
+		return fmt.Errorf("missing metric family name")
+	}
+	kind := line[:n]
+	line = line[n+1:]
+	name := line
+	value := ""
+	if n := strings.IndexByte(line, ' '); n >= 0 {
+		name = line[:n]
+		value = line[n+1:]
+	}
+	if err := validateMetricName(name); err != nil {
+		return err
+	}
+	if name != p.family.name {
+		if err := p.startFamily(name); err != nil {
+			return err
+		}
+	}
+	f := &p.family
+	if f.hasSamples {
+		return fmt.Errorf("metadata for metric family %q must be placed before its samples", name)
+	}
+	switch kind {
+	case "TYPE":
+		if f.hasType {
+			return fmt.Errorf("duplicate TYPE for metric family %q", name)
+		}
+		switch value {
+		case "counter", "gauge", "histogram", "gaugehistogram", "summary", "info", "stateset", "unknown":
+		default:
+			return fmt.Errorf("unsupported TYPE %q for metric family %q", value, name)
+		}
+		if f.hasUnit && f.unit != "" && (value == "info" || value == "stateset") {
+			return fmt.Errorf("UNIT cannot be set for metric family %q of type %q", name, value)
+		}
+		f.typ = value
+		f.hasType = true
+	case "HELP":
+		if f.hasHelp {
+			return fmt.Errorf("duplicate HELP for metric family %q", name)
+		}
+		f.hasHelp = true
+	case "UNIT":
+		if f.hasUnit {
+			return fmt.Errorf("duplicate UNIT for metric family %q", name)
+		}
+		if value != "" {
+			if !strings.HasSuffix(name, "_"+value) {
+				return fmt.Errorf("metric family name %q must have the unit %q as a suffix", name, value)
+			}
+			if f.typ == "info" || f.typ == "stateset" {
+				return fmt.Errorf("UNIT cannot be set for metric family %q of type %q", name, f.typ)
+			}
+		}
+		f.unit = value
+		f.hasUnit = true
+	default:
+		return fmt.Errorf("unsupported metadata %q; supported values: TYPE, HELP, UNIT", kind)
+	}
+	return nil
+}
+
+func (p *omParser) startFamily(name string) error {
+	if p.seenFamilies == nil {
+		p.seenFamilies = make(map[string]struct{})
+	}
+	if _, ok := p.seenFamilies[name]; ok {
+		return fmt.Errorf("metric family %q is interleaved with other metric families", name)
+	}
+	p.seenFamilies[name] = struct{}{}
+	p.family = omFamily{
+		name: name,
+		typ:  "unknown",
+	}
+	return nil
+}
+
+// getFamilySuffix returns the suffix for the given metric in the current metric family.
+//
+// It returns false if the metric doesn't belong to the current family.
+func (p *omParser) getFamilySuffix(metric string) (string, bool) {
+	f := &p.family
+	if f.name == "" || !strings.HasPrefix(metric, f.name) {
+		return "", false
+	}
+	suffix := metric[len(f.name):]
+	switch f.typ {
+	case "counter":
+		return suffix, suffix == "_total" || suffix == "_created"
+	case "histogram":
+		return suffix, suffix == "_bucket" || suffix == "_count" || suffix == "_sum" || suffix == "_created"
+	case "gaugehistogram":
+		return suffix, suffix == "_bucket" || suffix == "_gcount" || suffix == "_gsum"
+	case "summary":
+		return suffix, suffix == "" || suffix == "_count" || suffix == "_sum" || suffix == "_created"
+	case "info":
+		return suffix, suffix == "_info"
+	default:
+		// gauge, stateset and unknown
+		return suffix, suffix == ""
+	}
+}
+
+func (p *omParser) unmarshalSample(r *Row, line string, tagsPool []Tag, noEscapes bool) ([]Tag, error) {
+	r.reset()
+
+	// Parse metric name and labels
+	s := line
+	tagsStart := len(tagsPool)
+	n := strings.IndexAny(s, "{ ")
+	if n < 0 {
+		return tagsPool, fmt.Errorf("missing value")
+	}
+	r.Metric = s[:n]
+	if err := validateMetricName(r.Metric); err != nil {
+		return tagsPool, err
+	}
+	s = s[n:]
+	if s[0] == '{' {
+		var err error
+		s, tagsPool, err = unmarshalTags(tagsPool, s[1:], noEscapes)
+		if err != nil {
+			return tagsPool, fmt.Errorf("cannot unmarshal labels: %w", err)
+		}
+		tags := tagsPool[tagsStart:]
+		r.Tags = tags[:len(tags):len(tags)]
+		if err := validateLabels(r.Tags); err != nil {
+			return tagsPool, err
+		}
+	}
+	if len(s) == 0 || s[0] != ' ' {
+		return tagsPool, fmt.Errorf("missing whitespace after metric name and labels")
+	}
+	s = s[1:]
+
+	// Parse exemplar
+	exemplarStr := ""
+	if n := strings.Index(s, " # "); n >= 0 {
+		exemplarStr = s[n+len(" # "):]
+		s = s[:n]
+	}
+
+	// Parse value and timestamp
+	valueStr := s
+	timestampStr := ""
+	if n := strings.IndexByte(s, ' '); n >= 0 {
+		valueStr = s[:n]
+		timestampStr = s[n+1:]
+	}
+	v, err := parseOpenMetricsNumber(valueStr)
+	if err != nil {
+		return tagsPool, fmt.Errorf("cannot parse value: %w", err)
+	}
+	r.Value = v
+	if timestampStr != "" {
+		ts, err := parseOpenMetricsTimestamp(timestampStr)
+		if err != nil {
+			return tagsPool, err
+		}
+		r.Timestamp = ts
+	}
+
+	// Verify the sample against its metric family
+	suffix, ok := p.getFamilySuffix(r.Metric)
+	if !ok {
+		if r.Metric == p.family.name {
+			return tagsPool, fmt.Errorf("metric %q must have a suffix for metric family of type %q", r.Metric, p.family.typ)
+		}
+		// The sample starts new metric family without metadata.
+		if err := p.startFamily(r.Metric); err != nil {
+			return tagsPool, err
+		}
+		suffix = ""
+	}
+	p.family.hasSamples = true
+	if err := p.validateSample(r, suffix); err != nil {
+		return tagsPool, err
+	}
+
+	if exemplarStr != "" {
+		if !(p.family.typ == "counter" && suffix == "_total") && !((p.family.typ == "histogram" || p.family.typ == "gaugehistogram") && suffix == "_bucket") {
+			return tagsPool, fmt.Errorf("exemplars are allowed only for counter `_total` and histogram `_bucket` samples")
+		}
+		tagsPool, err = unmarshalExemplar(r, exemplarStr, tagsPool, noEscapes)
+		if err != nil {
+			return tagsPool, fmt.Errorf("cannot unmarshal exemplar: %w", err)
+		}
+	}
+
+	if suffix == "_created" {
+		p.addCreatedTimestamp(r)
+	}
+	p.families = append(p.families, p.family.name)
+	return tagsPool, nil
+}
+
+func (p *omParser) validateSample(r *Row, suffix string) error {
+	f := &p.family
+	switch f.typ {
+	case "counter":
+		if suffix == "_total" && (math.IsNaN(r.Value) || r.Value < 0) {
+			return fmt.Errorf("counter value must be non-negative; got %v", r.Value)
+		}
+	case "histogram", "gaugehistogram":
+		if suffix == "_bucket" && getTagValue(r.Tags, "le") == "" {
+			return fmt.Errorf("missing `le` label for histogram bucket")
+		}
+		if suffix == "_bucket" || suffix == "_count" || suffix == "_gcount" {
+			if math.IsNaN(r.Value) || r.Value < 0 {
+				return fmt.Errorf("histogram bucket and count values must be non-negative; got %v", r.Value)
+			}
+		}
+	case "summary":
+		if suffix == "" && getTagValue(r.Tags, "quantile") == "" {
+			return fmt.Errorf("missing `quantile` label for summary")
+		}
+		if suffix == "_count" && (math.IsNaN(r.Value) || r.Value < 0) {
+			return fmt.Errorf("summary count value must be non-negative; got %v", r.Value)
+		}
+	case "info":
+		if r.Value != 1 {
+			return fmt.Errorf("info value must be 1; got %v", r.Value)
+		}
+	case "stateset":
+		if getTagValue(r.Tags, f.name) == "" {
+			return fmt.Errorf("missing %q label for stateset", f.name)
+		}
+		if r.Value != 0 && r.Value != 1 {
+			return fmt.Errorf("stateset value must be 0 or 1; got %v", r.Value)
+		}
+	}
+	return nil
+}
+
+func (p *omParser) addCreatedTimestamp(r *Row) {
+	if p.createdTimestamps == nil {
+		p.createdTimestamps = make(map[string]int64)
+	}
+	p.keyBuf = appendCreatedTimestampKey(p.keyBuf[:0], p.family.name, r.Tags)
+	p.createdTimestamps[string(p.keyBuf)] = int64(r.Value * 1e3)
+}
+
+// applyCreatedTimestamps removes `_created` rows from dst[dstLen:] and sets CreatedTimestamp for the corresponding rows.
+func (p *omParser) applyCreatedTimestamps(dst []Row, dstLen int) []Row {
+	if len(p.createdTimestamps) == 0 {
+		return dst
+	}
+	rows := dst[dstLen:]
+	dstRows := rows[:0]
+	for i := range rows {
+		r := &rows[i]
+		familyName := p.families[i]
+		if r.Metric == familyName+"_created" {
+			continue
+		}
+		if r.Metric != familyName {
+			// Skip summary quantiles, since they aren't cumulative.
+			p.keyBuf = appendCreatedTimestampKey(p.keyBuf[:0], familyName, r.Tags)
+			if ts, ok := p.createdTimestamps[string(p.keyBuf)]; ok {
+				r.CreatedTimestamp = ts
+			}
+		}
+		dstRows = append(dstRows, *r)
+	}
+	for i := len(dstRows); i < len(rows); i++ {
+		rows[i].reset()
+	}
+	return dst[:dstLen+len(dstRows)]
+}
+
+// appendCreatedTimestampKey appends the key for the given family and tags to dst.
+//
+// `le` and `quantile` labels are ignored, since `_created` sample is shared among all the histogram buckets and summary quantiles.
+func appendCreatedTimestampKey(dst []byte, familyName string, tags []Tag) []byte {
+	dst = append(dst, familyName...)
+	for _, tag := range tags {
+		if tag.Key == "le" || tag.Key == "quantile" {
+			continue
+		}
+		dst = append(dst, ',')
+		dst = append(dst, tag.Key...)
+		dst = append(dst, '=')
+		dst = appendEscapedValue(dst, tag.Value)
+	}
+	return dst
+}
+
+func unmarshalExemplar(r *Row, s string, tagsPool []Tag, noEscapes bool) ([]Tag, error) {
+	if len(s) == 0 || s[0] != '{' {
+		return tagsPool, fmt.Errorf("missing exemplar labels")
+	}
+	tagsStart := len(tagsPool)
+	s, tagsPool, err := unmarshalTags(tagsPool, s[1:], noEscapes)
+	if err != nil {
+		return tagsPool, fmt.Errorf("cannot unmarshal labels: %w", err)
+	}
+	tags := tagsPool[tagsStart:]
+	e := &Exemplar{
+		Tags: tags[:len(tags):len(tags)],
+	}
+	if err := validateLabels(e.Tags); err != nil {
+		return tagsPool, err
+	}
+	// The combined length of the label names and values of an Exemplar's LabelSet MUST NOT exceed 128 UTF-8 characters.
+	// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#exemplars
+	labelsLen := 0
+	for _, tag := range e.Tags {
+		labelsLen += len([]rune(tag.Key)) + len([]rune(tag.Value))
+	}
+	if labelsLen > 128 {
+		return tagsPool, fmt.Errorf("the combined length of exemplar label names and values cannot exceed 128 chars; got %d chars", labelsLen)
+	}
+	if len(s) == 0 || s[0] != ' ' {
+		return tagsPool, fmt.Errorf("missing exemplar value")
+	}
+	s = s[1:]
+	valueStr := s
+	timestampStr := ""
+	if n := strings.IndexByte(s, ' '); n >= 0 {
+		valueStr = s[:n]
+		timestampStr = s[n+1:]
+	}
+	v, err := parseOpenMetricsNumber(valueStr)
+	if err != nil {
+		return tagsPool, fmt.Errorf("cannot parse value: %w", err)
+	}
+	e.Value = v
+	if timestampStr != "" {
+		ts, err := parseOpenMetricsTimestamp(timestampStr)
+		if err != nil {
+			return tagsPool, err
+		}
+		e.Timestamp = ts
+	}
+	r.Exemplar = e
+	return tagsPool, nil
+}
+
+func parseOpenMetricsNumber(s string) (float64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("value cannot be empty")
+	}
+	return fastfloat.Parse(s)
+}
+
+// parseOpenMetricsTimestamp parses OpenMetrics timestamp in seconds and returns it in milliseconds.
+func parseOpenMetricsTimestamp(s string) (int64, error) {
+	ts, err := parseOpenMetricsNumber(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse timestamp: %w", err)
+	}
+	if math.IsNaN(ts) || math.IsInf(ts, 0) {
+		return 0, fmt.Errorf("timestamp must be finite; got %q", s)
+	}
+	return int64(math.Round(ts * 1e3)), nil
+}
+
+func validateMetricName(name string) error {
+	if name == "" {
+		return fmt.Errorf("metric name cannot be empty")
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return fmt.Errorf("invalid metric name %q", name)
+	}
+	return nil
+}
+
+func validateLabels(tags []Tag) error {
+	for i, tag := range tags {
+		if !isValidLabelName(tag.Key) {
+			return fmt.Errorf("invalid label name %q", tag.Key)
+		}
+		for _, prevTag := range tags[:i] {
+			if prevTag.Key == tag.Key {
+				return fmt.Errorf("duplicate label %q", tag.Key)
+			}
+		}
+	}
+	return nil
+}
+
+func isValidLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+func getTagValue(tags []Tag, key string) string {
+	for _, tag := range tags {
+		if tag.Key == key {
+			return tag.Value
+		}
+	}
+	return ""
+}
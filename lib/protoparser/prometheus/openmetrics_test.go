@@ -0,0 +1,271 @@
+package prometheus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsOpenMetricsContentType(t *testing.T) {
+	f := func(contentType string, resultExpected bool) {
+		t.Helper()
+
+		result := IsOpenMetricsContentType(contentType)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", contentType, result, resultExpected)
+		}
+	}
+
+	f("", false)
+	f("text/plain; version=0.0.4", false)
+	f(ProtobufContentType, false)
+	f("application/openmetrics-text", true)
+	f("application/openmetrics-text; version=1.0.0; charset=utf-8", true)
+	f("Application/OpenMetrics-Text;version=0.0.1", true)
+}
+
+func TestRowsUnmarshalOpenMetricsFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+
+		var rows Rows
+		if err := rows.UnmarshalOpenMetrics(s); err == nil {
+			t.Fatalf("expecting non-nil error when parsing %q", s)
+		}
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows", len(rows.Rows))
+		}
+	}
+
+	// Missing # EOF
+	f("")
+	f("foo 1\n")
+
+	// Data after # EOF
+	f("# EOF\nfoo 1\n")
+
+	// Missing newline
+	f("foo 1# EOF\n")
+
+	// Empty line
+	f("foo 1\n\n# EOF\n")
+
+	// Comments aren't allowed
+	f("#foo\n# EOF\n")
+	f("# foo bar\n# EOF\n")
+
+	// Invalid metadata
+	f("# TYPE foo bar\n# EOF\n")
+	f("# TYPE foo counter\n# TYPE foo counter\n# EOF\n")
+	f("# HELP foo x\n# HELP foo y\n# EOF\n")
+	f("# UNIT foo_seconds seconds\n# UNIT foo_seconds seconds\n# EOF\n")
+	f("# UNIT foo seconds\n# EOF\n")
+	f("# TYPE foo_seconds info\n# UNIT foo_seconds seconds\n# EOF\n")
+	f("# TYPE foo gauge\nfoo 1\n# HELP foo bar\n# EOF\n")
+
+	// Interleaved metric families
+	f("foo 1\nbar 2\nfoo 3\n# EOF\n")
+	f("# TYPE foo counter\nfoo_total 1\n# TYPE bar gauge\nbar 2\n# TYPE foo counter\n# EOF\n")
+
+	// Invalid metric name and labels
+	f("1foo 1\n# EOF\n")
+	f("foo{1a=\"b\"} 1\n# EOF\n")
+	f("foo{a=\"b\",a=\"c\"} 1\n# EOF\n")
+	f("foo{a=\"b\" 1\n# EOF\n")
+
+	// Invalid value or timestamp
+	f("foo\n# EOF\n")
+	f("foo \n# EOF\n")
+	f("foo bar\n# EOF\n")
+	f("foo 1 bar\n# EOF\n")
+	f("foo 1 NaN\n# EOF\n")
+
+	// Samples do not match the metric family type
+	f("# TYPE foo counter\nfoo 1\n# EOF\n")
+	f("# TYPE foo counter\nfoo_total -1\n# EOF\n")
+	f("# TYPE foo histogram\nfoo_bucket 1\n# EOF\n")
+	f("# TYPE foo histogram\nfoo_bucket{le=\"+Inf\"} -1\n# EOF\n")
+	f("# TYPE foo summary\nfoo 1\n# EOF\n")
+	f("# TYPE foo info\nfoo_info 2\n# EOF\n")
+	f("# TYPE foo stateset\nfoo{bar=\"a\"} 1\n# EOF\n")
+	f("# TYPE foo stateset\nfoo{foo=\"a\"} 2\n# EOF\n")
+
+	// Invalid exemplars
+	f("# TYPE foo gauge\nfoo 1 # {trace_id=\"a\"} 1\n# EOF\n")
+	f("# TYPE foo counter\nfoo_total 1 # trace_id 1\n# EOF\n")
+	f("# TYPE foo counter\nfoo_total 1 # {trace_id=\"a\"}\n# EOF\n")
+	f("# TYPE foo counter\nfoo_total 1 # {trace_id=\"a\"} bar\n# EOF\n")
+	f("# TYPE foo counter\nfoo_total 1 # {trace_id=\"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\"} 1\n# EOF\n")
+}
+
+func TestRowsUnmarshalOpenMetricsSuccess(t *testing.T) {
+	f := func(s string, rowsExpected []Row) {
+		t.Helper()
+
+		var rows Rows
+		if err := rows.UnmarshalOpenMetrics(s); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%+v\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+
+		// Try unmarshaling again
+		if err := rows.UnmarshalOpenMetrics(s); err != nil {
+			t.Fatalf("unexpected error at the second unmarshal: %s", err)
+		}
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows at the second unmarshal;\ngot\n%+v\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+	}
+
+	// Empty response
+	f("# EOF\n", nil)
+	f("# EOF", nil)
+
+	// Metric families without samples
+	f("# TYPE foo counter\n# HELP foo bar baz\n# EOF\n", nil)
+
+	// Samples without metadata
+	f("foo 1\nbar{a=\"b\"} 2 1700000000.5\n# EOF\n", []Row{
+		{
+			Metric: "foo",
+			Value:  1,
+		},
+		{
+			Metric:    "bar",
+			Tags:      []Tag{{Key: "a", Value: "b"}},
+			Value:     2,
+			Timestamp: 1700000000500,
+		},
+	})
+
+	// Counter with _created and exemplar
+	f(`# TYPE http_requests counter
+# HELP http_requests The total number of requests
+http_requests_total{path="/foo"} 10 # {trace_id="abc"} 1.5 1700000000
+http_requests_created{path="/foo"} 1600000000.123
+http_requests_total{path="/bar"} 3
+# EOF
+`, []Row{
+		{
+			Metric: "http_requests_total",
+			Tags:   []Tag{{Key: "path", Value: "/foo"}},
+			Value:  10,
+			Exemplar: &Exemplar{
+				Tags:      []Tag{{Key: "trace_id", Value: "abc"}},
+				Value:     1.5,
+				Timestamp: 1700000000000,
+			},
+			CreatedTimestamp: 1600000000123,
+		},
+		{
+			Metric: "http_requests_total",
+			Tags:   []Tag{{Key: "path", Value: "/bar"}},
+			Value:  3,
+		},
+	})
+
+	// Histogram with _created and unit
+	f(`# TYPE request_duration_seconds histogram
+# UNIT request_duration_seconds seconds
+request_duration_seconds_bucket{le="0.5"} 2 # {} 0.3
+request_duration_seconds_bucket{le="+Inf"} 3
+request_duration_seconds_count 3
+request_duration_seconds_sum 1.2
+request_duration_seconds_created 1600000000
+# EOF
+`, []Row{
+		{
+			Metric:           "request_duration_seconds_bucket",
+			Tags:             []Tag{{Key: "le", Value: "0.5"}},
+			Value:            2,
+			Exemplar:         &Exemplar{Tags: []Tag{}, Value: 0.3},
+			CreatedTimestamp: 1600000000000,
+		},
+		{
+			Metric:           "request_duration_seconds_bucket",
+			Tags:             []Tag{{Key: "le", Value: "+Inf"}},
+			Value:            3,
+			CreatedTimestamp: 1600000000000,
+		},
+		{
+			Metric:           "request_duration_seconds_count",
+			Value:            3,
+			CreatedTimestamp: 1600000000000,
+		},
+		{
+			Metric:           "request_duration_seconds_sum",
+			Value:            1.2,
+			CreatedTimestamp: 1600000000000,
+		},
+	})
+
+	// Gauge histogram, summary, info and stateset
+	f(`# TYPE queue_size gaugehistogram
+queue_size_bucket{le="+Inf"} 5
+queue_size_gcount 5
+queue_size_gsum 12
+# TYPE rpc_duration summary
+rpc_duration{quantile="0.5"} 0.1
+rpc_duration_count 4
+rpc_duration_sum 0.8
+rpc_duration_created 1600000000
+# TYPE build info
+build_info{version="1.2.3"} 1
+# TYPE state stateset
+state{state="a"} 1
+state{state="b"} 0
+# TYPE temperature_celsius gauge
+# UNIT temperature_celsius celsius
+temperature_celsius 21.5
+# EOF
+`, []Row{
+		{
+			Metric: "queue_size_bucket",
+			Tags:   []Tag{{Key: "le", Value: "+Inf"}},
+			Value:  5,
+		},
+		{
+			Metric: "queue_size_gcount",
+			Value:  5,
+		},
+		{
+			Metric: "queue_size_gsum",
+			Value:  12,
+		},
+		{
+			Metric: "rpc_duration",
+			Tags:   []Tag{{Key: "quantile", Value: "0.5"}},
+			Value:  0.1,
+		},
+		{
+			Metric:           "rpc_duration_count",
+			Value:            4,
+			CreatedTimestamp: 1600000000000,
+		},
+		{
+			Metric:           "rpc_duration_sum",
+			Value:            0.8,
+			CreatedTimestamp: 1600000000000,
+		},
+		{
+			Metric: "build_info",
+			Tags:   []Tag{{Key: "version", Value: "1.2.3"}},
+			Value:  1,
+		},
+		{
+			Metric: "state",
+			Tags:   []Tag{{Key: "state", Value: "a"}},
+			Value:  1,
+		},
+		{
+			Metric: "state",
+			Tags:   []Tag{{Key: "state", Value: "b"}},
+			Value:  0,
+		},
+		{
+			Metric: "temperature_celsius",
+			Value:  21.5,
+		},
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -63,6 +64,16 @@ type Row struct {
 	Tags      []Tag
 	Value     float64
 	Timestamp int64
+
+	// Exemplar is an optional exemplar for the row.
+	//
+	// It is set only by Rows.UnmarshalOpenMetrics.
+	Exemplar *Exemplar
+
+	// CreatedTimestamp is an optional creation timestamp in milliseconds for counters, histograms and summaries.
+	//
+	// It is set only by Rows.UnmarshalOpenMetrics from `_created` samples.
+	CreatedTimestamp int64
 }
 
 func (r *Row) reset() {
@@ -70,6 +81,8 @@ func (r *Row) reset() {
 	r.Tags = nil
 	r.Value = 0
 	r.Timestamp = 0
+	r.Exemplar = nil
+	r.CreatedTimestamp = 0
 }
 
 func skipTrailingComment(s string) string {
@@ -522,6 +535,22 @@ func marshalMetricNameWithTags(dst []byte, r *Row) []byte {
 	return dst
 }
 
+// AppendRowsAsText appends rows in Prometheus text exposition format to dst and returns the result.
+func AppendRowsAsText(dst []byte, rows []Row) []byte {
+	for i := range rows {
+		r := &rows[i]
+		dst = marshalMetricNameWithTags(dst, r)
+		dst = append(dst, ' ')
+		dst = appendFloat(dst, r.Value)
+		if r.Timestamp != 0 {
+			dst = append(dst, ' ')
+			dst = strconv.AppendInt(dst, r.Timestamp, 10)
+		}
+		dst = append(dst, '\n')
+	}
+	return dst
+}
+
 // AreIdenticalSeriesFast returns true if s1 and s2 contains identical Prometheus series with possible different values.
 //
 // This function is optimized for speed.
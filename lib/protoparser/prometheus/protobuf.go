@@ -0,0 +1,658 @@
+package prometheus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/easyproto"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+)
+
+// ProtobufContentType is the content type for Prometheus protobuf exposition format.
+//
+// See https://github.com/prometheus/docs/blob/main/content/docs/instrumenting/exposition_formats.md#protobuf-format
+const ProtobufContentType = "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited"
+
+// IsProtobufContentType returns true if contentType corresponds to Prometheus protobuf exposition format.
+func IsProtobufContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/vnd.google.protobuf") && strings.Contains(contentType, "proto=io.prometheus.client.MetricFamily")
+}
+
+// UnmarshalProtobuf unmarshals delimited MetricFamily messages in Prometheus protobuf exposition format from src.
+//
+// Native histograms are converted to VictoriaMetrics histogram buckets with `vmrange` labels,
+// which preserve the original bucket boundaries. Such buckets are supported by histogram_quantile() and other MetricsQL functions.
+// See https://valyala.medium.com/improving-histogram-usability-for-prometheus-and-grafana-bc7e5df0e350
+//
+// src shouldn't be modified while rs is in use.
+//
+// See https://github.com/prometheus/client_model/blob/master/io/prometheus/client/metrics.proto
+func (rs *Rows) UnmarshalProtobuf(src []byte) error {
+	rs.Reset()
+	var mf metricFamily
+	for len(src) > 0 {
+		n, offset := binary.Uvarint(src)
+		if offset <= 0 {
+			return fmt.Errorf("cannot read MetricFamily message length")
+		}
+		src = src[offset:]
+		if uint64(len(src)) < n {
+			return fmt.Errorf("too short data for MetricFamily message; got %d bytes; want %d bytes", len(src), n)
+		}
+		if err := mf.unmarshalProtobuf(src[:n]); err != nil {
+			return fmt.Errorf("cannot unmarshal MetricFamily: %w", err)
+		}
+		src = src[n:]
+		rs.Rows, rs.tagsPool = mf.appendRows(rs.Rows, rs.tagsPool)
+	}
+	return nil
+}
+
+// Metric types from MetricType enum.
+const (
+	metricTypeCounter        = 0
+	metricTypeGauge          = 1
+	metricTypeSummary        = 2
+	metricTypeUntyped        = 3
+	metricTypeHistogram      = 4
+	metricTypeGaugeHistogram = 5
+)
+
+// metricFamily represents MetricFamily message.
+//
+//	message MetricFamily {
+//	  string name = 1;
+//	  string help = 2;
+//	  MetricType type = 3;
+//	  repeated Metric metric = 4;
+//	  string unit = 5;
+//	}
+type metricFamily struct {
+	name    string
+	typ     int32
+	metrics []protoMetric
+
+	// buf is used for constructing metric names and label values, which are missing in the original message.
+	buf []byte
+}
+
+func (mf *metricFamily) unmarshalProtobuf(src []byte) (err error) {
+	mf.name = ""
+	mf.typ = metricTypeUntyped
+	mf.metrics = mf.metrics[:0]
+
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in MetricFamily message: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			name, ok := fc.String()
+			if !ok {
+				return fmt.Errorf("cannot read name")
+			}
+			mf.name = name
+		case 3:
+			typ, ok := fc.Int32()
+			if !ok {
+				return fmt.Errorf("cannot read type")
+			}
+			mf.typ = typ
+		case 4:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read metric data")
+			}
+			mf.metrics = append(mf.metrics, protoMetric{})
+			m := &mf.metrics[len(mf.metrics)-1]
+			if err := m.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal metric: %w", err)
+			}
+		}
+	}
+	if mf.name == "" {
+		return fmt.Errorf("missing name")
+	}
+	return nil
+}
+
+// protoMetric represents Metric message.
+//
+//	message Metric {
+//	  repeated LabelPair label = 1;
+//	  Gauge gauge = 2;
+//	  Counter counter = 3;
+//	  Summary summary = 4;
+//	  Untyped untyped = 5;
+//	  Histogram histogram = 7;
+//	  int64 timestamp_ms = 6;
+//	}
+//
+// Gauge, Counter and Untyped messages contain `double value = 1` field.
+type protoMetric struct {
+	labels      []Tag
+	value       float64
+	summary     *protoSummary
+	histogram   *protoHistogram
+	timestampMs int64
+}
+
+func (m *protoMetric) unmarshalProtobuf(src []byte) (err error) {
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in Metric message: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read label data")
+			}
+			label, err := unmarshalLabelPair(data)
+			if err != nil {
+				return fmt.Errorf("cannot unmarshal label: %w", err)
+			}
+			m.labels = append(m.labels, label)
+		case 2, 3, 5:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read value data")
+			}
+			v, err := unmarshalValue(data)
+			if err != nil {
+				return fmt.Errorf("cannot unmarshal value: %w", err)
+			}
+			m.value = v
+		case 4:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read summary data")
+			}
+			m.summary = &protoSummary{}
+			if err := m.summary.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal summary: %w", err)
+			}
+		case 6:
+			ts, ok := fc.Int64()
+			if !ok {
+				return fmt.Errorf("cannot read timestamp_ms")
+			}
+			m.timestampMs = ts
+		case 7:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read histogram data")
+			}
+			m.histogram = &protoHistogram{}
+			if err := m.histogram.unmarshalProtobuf(data); err != nil {
+				return fmt.Errorf("cannot unmarshal histogram: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// unmarshalLabelPair unmarshals LabelPair message.
+//
+//	message LabelPair {
+//	  string name = 1;
+//	  string value = 2;
+//	}
+func unmarshalLabelPair(src []byte) (label Tag, err error) {
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return label, fmt.Errorf("cannot read next field in LabelPair message: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			name, ok := fc.String()
+			if !ok {
+				return label, fmt.Errorf("cannot read name")
+			}
+			label.Key = name
+		case 2:
+			value, ok := fc.String()
+			if !ok {
+				return label, fmt.Errorf("cannot read value")
+			}
+			label.Value = value
+		}
+	}
+	return label, nil
+}
+
+// unmarshalValue unmarshals `double value = 1` field from Gauge, Counter or Untyped message.
+func unmarshalValue(src []byte) (v float64, err error) {
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return 0, fmt.Errorf("cannot read next field: %w", err)
+		}
+		if fc.FieldNum == 1 {
+			value, ok := fc.Double()
+			if !ok {
+				return 0, fmt.Errorf("cannot read value")
+			}
+			v = value
+		}
+	}
+	return v, nil
+}
+
+// protoSummary represents Summary message.
+//
+//	message Summary {
+//	  uint64 sample_count = 1;
+//	  double sample_sum = 2;
+//	  repeated Quantile quantile = 3;
+//	}
+//
+//	message Quantile {
+//	  double quantile = 1;
+//	  double value = 2;
+//	}
+type protoSummary struct {
+	count     uint64
+	sum       float64
+	quantiles [][2]float64
+}
+
+func (s *protoSummary) unmarshalProtobuf(src []byte) (err error) {
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in Summary message: %w", err)
+		}
+		switch fc.FieldNum {
+		case 1:
+			count, ok := fc.Uint64()
+			if !ok {
+				return fmt.Errorf("cannot read sample_count")
+			}
+			s.count = count
+		case 2:
+			sum, ok := fc.Double()
+			if !ok {
+				return fmt.Errorf("cannot read sample_sum")
+			}
+			s.sum = sum
+		case 3:
+			data, ok := fc.MessageData()
+			if !ok {
+				return fmt.Errorf("cannot read quantile data")
+			}
+			var q [2]float64
+			var fcq easyproto.FieldContext
+			for len(data) > 0 {
+				data, err = fcq.NextField(data)
+				if err != nil {
+					return fmt.Errorf("cannot read next field in Quantile message: %w", err)
+				}
+				if fcq.FieldNum == 1 || fcq.FieldNum == 2 {
+					v, ok := fcq.Double()
+					if !ok {
+						return fmt.Errorf("cannot read quantile field #%d", fcq.FieldNum)
+					}
+					q[fcq.FieldNum-1] = v
+				}
+			}
+			s.quantiles = append(s.quantiles, q)
+		}
+	}
+	return nil
+}
+
+// protoHistogram represents Histogram message.
+//
+//	message Histogram {
+//	  uint64 sample_count = 1;
+//	  double sample_count_float = 4;
+//	  double sample_sum = 2;
+//	  repeated Bucket bucket = 3;
+//	  sint32 schema = 5;
+//	  double zero_threshold = 6;
+//	  uint64 zero_count = 7;
+//	  double zero_count_float = 8;
+//	  repeated BucketSpan negative_span = 9;
+//	  repeated sint64 negative_delta = 10;
+//	  repeated double negative_count = 11;
+//	  repeated BucketSpan positive_span = 12;
+//	  repeated sint64 positive_delta = 13;
+//	  repeated double positive_count = 14;
+//	}
+//
+//	message Bucket {
+//	  uint64 cumulative_count = 1;
+//	  double cumulative_count_float = 4;
+//	  double upper_bound = 2;
+//	}
+type protoHistogram struct {
+	count float64
+	sum   float64
+
+	// buckets contains classic buckets as (upper_bound, cumulative_count) pairs.
+	buckets [][2]float64
+
+	schema        int32
+	zeroThreshold float64
+	zeroCount     float64
+
+	negativeSpans  []bucketSpan
+	negativeDeltas []int64
+	negativeCounts []float64
+
+	positiveSpans  []bucketSpan
+	positiveDeltas []int64
+	positiveCounts []float64
+}
+
+// bucketSpan represents BucketSpan message.
+//
+//	message BucketSpan {
+//	  sint32 offset = 1;
+//	  uint32 length = 2;
+//	}
+type bucketSpan struct {
+	offset int32
+	length uint32
+}
+
+func (h *protoHistogram) unmarshalProtobuf(src []byte) (err error) {
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return fmt.Errorf("cannot read next field in Histogram message: %w", err)
+		}
+		ok := true
+		switch fc.FieldNum {
+		case 1:
+			var count uint64
+			count, ok = fc.Uint64()
+			h.count = float64(count)
+		case 2:
+			h.sum, ok = fc.Double()
+		case 3:
+			var data []byte
+			data, ok = fc.MessageData()
+			if ok {
+				b, err := unmarshalBucket(data)
+				if err != nil {
+					return fmt.Errorf("cannot unmarshal bucket: %w", err)
+				}
+				h.buckets = append(h.buckets, b)
+			}
+		case 4:
+			h.count, ok = fc.Double()
+		case 5:
+			h.schema, ok = fc.Sint32()
+		case 6:
+			h.zeroThreshold, ok = fc.Double()
+		case 7:
+			var zeroCount uint64
+			zeroCount, ok = fc.Uint64()
+			h.zeroCount = float64(zeroCount)
+		case 8:
+			h.zeroCount, ok = fc.Double()
+		case 9, 12:
+			var data []byte
+			data, ok = fc.MessageData()
+			if ok {
+				span, err := unmarshalBucketSpan(data)
+				if err != nil {
+					return fmt.Errorf("cannot unmarshal bucket span: %w", err)
+				}
+				if fc.FieldNum == 9 {
+					h.negativeSpans = append(h.negativeSpans, span)
+				} else {
+					h.positiveSpans = append(h.positiveSpans, span)
+				}
+			}
+		case 10:
+			h.negativeDeltas, ok = fc.UnpackSint64s(h.negativeDeltas)
+		case 11:
+			h.negativeCounts, ok = fc.UnpackDoubles(h.negativeCounts)
+		case 13:
+			h.positiveDeltas, ok = fc.UnpackSint64s(h.positiveDeltas)
+		case 14:
+			h.positiveCounts, ok = fc.UnpackDoubles(h.positiveCounts)
+		}
+		if !ok {
+			return fmt.Errorf("cannot read field #%d", fc.FieldNum)
+		}
+	}
+	return nil
+}
+
+func unmarshalBucket(src []byte) (b [2]float64, err error) {
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return b, fmt.Errorf("cannot read next field in Bucket message: %w", err)
+		}
+		ok := true
+		switch fc.FieldNum {
+		case 1:
+			var count uint64
+			count, ok = fc.Uint64()
+			b[1] = float64(count)
+		case 2:
+			b[0], ok = fc.Double()
+		case 4:
+			b[1], ok = fc.Double()
+		}
+		if !ok {
+			return b, fmt.Errorf("cannot read field #%d", fc.FieldNum)
+		}
+	}
+	return b, nil
+}
+
+func unmarshalBucketSpan(src []byte) (span bucketSpan, err error) {
+	var fc easyproto.FieldContext
+	for len(src) > 0 {
+		src, err = fc.NextField(src)
+		if err != nil {
+			return span, fmt.Errorf("cannot read next field in BucketSpan message: %w", err)
+		}
+		ok := true
+		switch fc.FieldNum {
+		case 1:
+			span.offset, ok = fc.Sint32()
+		case 2:
+			span.length, ok = fc.Uint32()
+		}
+		if !ok {
+			return span, fmt.Errorf("cannot read field #%d", fc.FieldNum)
+		}
+	}
+	return span, nil
+}
+
+func (h *protoHistogram) isNative() bool {
+	return len(h.buckets) == 0 && (len(h.positiveSpans) > 0 || len(h.negativeSpans) > 0 || h.zeroCount > 0 || h.schema != 0 || h.zeroThreshold > 0)
+}
+
+type nativeBucket struct {
+	index int32
+	count float64
+}
+
+func getNativeBucketCounts(spans []bucketSpan, deltas []int64, counts []float64) []nativeBucket {
+	var nbs []nativeBucket
+	idx := int32(0)
+	countIdx := 0
+	absCount := int64(0)
+	for i, span := range spans {
+		idx += span.offset
+		if i > 0 {
+			// The offset for the first span is the index of the first bucket,
+			// while the offset for the subsequent spans is the gap after the previous span.
+			idx++
+		}
+		for j := uint32(0); j < span.length; j++ {
+			if j > 0 {
+				idx++
+			}
+			var count float64
+			if len(counts) > 0 {
+				if countIdx >= len(counts) {
+					return nbs
+				}
+				count = counts[countIdx]
+			} else {
+				if countIdx >= len(deltas) {
+					return nbs
+				}
+				absCount += deltas[countIdx]
+				count = float64(absCount)
+			}
+			countIdx++
+			nbs = append(nbs, nativeBucket{
+				index: idx,
+				count: count,
+			})
+		}
+	}
+	return nbs
+}
+
+func (mf *metricFamily) appendRows(dst []Row, tagsPool []Tag) ([]Row, []Tag) {
+	var bucketName, sumName, countName string
+	if mf.typ == metricTypeSummary || mf.typ == metricTypeHistogram || mf.typ == metricTypeGaugeHistogram {
+		bucketName = mf.internSuffixedName("_bucket")
+		sumName = mf.internSuffixedName("_sum")
+		countName = mf.internSuffixedName("_count")
+	}
+	for i := range mf.metrics {
+		m := &mf.metrics[i]
+		switch {
+		case m.summary != nil && mf.typ == metricTypeSummary:
+			s := m.summary
+			for _, q := range s.quantiles {
+				quantile := mf.internFloat(q[0])
+				dst, tagsPool = appendRow(dst, tagsPool, mf.name, m.labels, "quantile", quantile, q[1], m.timestampMs)
+			}
+			dst, tagsPool = appendRow(dst, tagsPool, sumName, m.labels, "", "", s.sum, m.timestampMs)
+			dst, tagsPool = appendRow(dst, tagsPool, countName, m.labels, "", "", float64(s.count), m.timestampMs)
+		case m.histogram != nil && (mf.typ == metricTypeHistogram || mf.typ == metricTypeGaugeHistogram):
+			h := m.histogram
+			if h.isNative() {
+				dst, tagsPool = mf.appendNativeBucketRows(dst, tagsPool, bucketName, m)
+			} else {
+				hasInf := false
+				for _, b := range h.buckets {
+					if math.IsInf(b[0], 1) {
+						hasInf = true
+					}
+					le := mf.internFloat(b[0])
+					dst, tagsPool = appendRow(dst, tagsPool, bucketName, m.labels, "le", le, b[1], m.timestampMs)
+				}
+				if !hasInf {
+					dst, tagsPool = appendRow(dst, tagsPool, bucketName, m.labels, "le", "+Inf", h.count, m.timestampMs)
+				}
+			}
+			dst, tagsPool = appendRow(dst, tagsPool, sumName, m.labels, "", "", h.sum, m.timestampMs)
+			dst, tagsPool = appendRow(dst, tagsPool, countName, m.labels, "", "", h.count, m.timestampMs)
+		default:
+			dst, tagsPool = appendRow(dst, tagsPool, mf.name, m.labels, "", "", m.value, m.timestampMs)
+		}
+	}
+	return dst, tagsPool
+}
+
+// appendNativeBucketRows appends rows for native histogram buckets from m to dst.
+//
+// Every native histogram bucket is converted to a bucket with `vmrange="lower...upper"` label and non-cumulative count,
+// so the original bucket boundaries for positive, negative and zero buckets are preserved.
+// Empty buckets are skipped, since they do not affect histogram_quantile() results.
+func (mf *metricFamily) appendNativeBucketRows(dst []Row, tagsPool []Tag, bucketName string, m *protoMetric) ([]Row, []Tag) {
+	h := m.histogram
+	base := math.Pow(2, math.Pow(2, -float64(h.schema)))
+
+	// Negative buckets cover [-base^index ... -base^(index-1)) ranges.
+	for _, nb := range getNativeBucketCounts(h.negativeSpans, h.negativeDeltas, h.negativeCounts) {
+		if nb.count == 0 {
+			continue
+		}
+		vmrange := mf.internRange(-math.Pow(base, float64(nb.index)), -math.Pow(base, float64(nb.index-1)))
+		dst, tagsPool = appendRow(dst, tagsPool, bucketName, m.labels, "vmrange", vmrange, nb.count, m.timestampMs)
+	}
+
+	if h.zeroCount > 0 {
+		vmrange := mf.internRange(-h.zeroThreshold, h.zeroThreshold)
+		dst, tagsPool = appendRow(dst, tagsPool, bucketName, m.labels, "vmrange", vmrange, h.zeroCount, m.timestampMs)
+	}
+
+	// Positive buckets cover (base^(index-1) ... base^index] ranges.
+	for _, nb := range getNativeBucketCounts(h.positiveSpans, h.positiveDeltas, h.positiveCounts) {
+		if nb.count == 0 {
+			continue
+		}
+		vmrange := mf.internRange(math.Pow(base, float64(nb.index-1)), math.Pow(base, float64(nb.index)))
+		dst, tagsPool = appendRow(dst, tagsPool, bucketName, m.labels, "vmrange", vmrange, nb.count, m.timestampMs)
+	}
+	return dst, tagsPool
+}
+
+func (mf *metricFamily) internSuffixedName(suffix string) string {
+	mf.buf = append(mf.buf[:0], mf.name...)
+	mf.buf = append(mf.buf, suffix...)
+	return bytesutil.InternBytes(mf.buf)
+}
+
+func (mf *metricFamily) internFloat(v float64) string {
+	mf.buf = appendFloat(mf.buf[:0], v)
+	return bytesutil.InternBytes(mf.buf)
+}
+
+func (mf *metricFamily) internRange(start, end float64) string {
+	mf.buf = appendFloat(mf.buf[:0], start)
+	mf.buf = append(mf.buf, "..."...)
+	mf.buf = appendFloat(mf.buf, end)
+	return bytesutil.InternBytes(mf.buf)
+}
+
+func appendRow(dst []Row, tagsPool []Tag, metric string, labels []Tag, extraName, extraValue string, value float64, timestampMs int64) ([]Row, []Tag) {
+	tagsStart := len(tagsPool)
+	tagsPool = append(tagsPool, labels...)
+	if extraName != "" {
+		tagsPool = append(tagsPool, Tag{
+			Key:   extraName,
+			Value: extraValue,
+		})
+	}
+	dst = append(dst, Row{
+		Metric:    metric,
+		Tags:      tagsPool[tagsStart:],
+		Value:     value,
+		Timestamp: timestampMs,
+	})
+	return dst, tagsPool
+}
+
+func appendFloat(dst []byte, v float64) []byte {
+	switch {
+	case math.IsInf(v, 1):
+		return append(dst, "+Inf"...)
+	case math.IsInf(v, -1):
+		return append(dst, "-Inf"...)
+	case math.IsNaN(v):
+		return append(dst, "NaN"...)
+	default:
+		return strconv.AppendFloat(dst, v, 'g', -1, 64)
+	}
+}
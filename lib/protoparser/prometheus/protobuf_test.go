@@ -0,0 +1,206 @@
+package prometheus
+
+import (
+	"math"
+	"testing"
+
+	"github.com/VictoriaMetrics/easyproto"
+)
+
+func TestIsProtobufContentType(t *testing.T) {
+	f := func(contentType string, resultExpected bool) {
+		t.Helper()
+
+		result := IsProtobufContentType(contentType)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %v; want %v", contentType, result, resultExpected)
+		}
+	}
+
+	f("", false)
+	f("text/plain; version=0.0.4", false)
+	f("application/openmetrics-text; version=1.0.0; charset=utf-8", false)
+	f("application/vnd.google.protobuf", false)
+	f(ProtobufContentType, true)
+	f("application/vnd.google.protobuf; proto=io.prometheus.client.MetricFamily; encoding=delimited", true)
+}
+
+func TestRowsUnmarshalProtobufFailure(t *testing.T) {
+	f := func(data []byte) {
+		t.Helper()
+
+		var rows Rows
+		if err := rows.UnmarshalProtobuf(data); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// Too short message
+	f([]byte{10, 1, 2})
+
+	// Invalid message length
+	f([]byte{0xff})
+
+	// Missing metric family name
+	var mp easyproto.MarshalerPool
+	m := mp.Get()
+	mm := m.MessageMarshaler()
+	mm.AppendInt32(3, metricTypeGauge)
+	f(m.MarshalWithLen(nil))
+	mp.Put(m)
+}
+
+func TestRowsUnmarshalProtobufSuccess(t *testing.T) {
+	f := func(data []byte, resultExpected string) {
+		t.Helper()
+
+		var rows Rows
+		if err := rows.UnmarshalProtobuf(data); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := AppendRowsAsText(nil, rows.Rows)
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+
+		// Verify that the parsed rows match the rows parsed from text exposition format.
+		var textRows Rows
+		textRows.UnmarshalWithErrLogger(string(result), func(s string) {
+			t.Fatalf("cannot parse the result: %s", s)
+		})
+		if len(textRows.Rows) != len(rows.Rows) {
+			t.Fatalf("unexpected number of rows; got %d; want %d", len(rows.Rows), len(textRows.Rows))
+		}
+	}
+
+	var mp easyproto.MarshalerPool
+
+	// Empty data
+	f(nil, "")
+
+	// Counter and gauge
+	m := mp.Get()
+	mm := m.MessageMarshaler()
+	mm.AppendString(1, "http_requests_total")
+	mm.AppendString(2, "The total number of requests")
+	mm.AppendInt32(3, metricTypeCounter)
+	metric := mm.AppendMessage(4)
+	appendLabelPair(metric, "path", "/foo")
+	appendLabelPair(metric, "code", `2"0\0`)
+	metric.AppendMessage(3).AppendDouble(1, 123)
+	metric = mm.AppendMessage(4)
+	metric.AppendMessage(3).AppendDouble(1, 4.5)
+	metric.AppendInt64(6, 1700000000000)
+	data := m.MarshalWithLen(nil)
+	m.Reset()
+	mm = m.MessageMarshaler()
+	mm.AppendString(1, "temperature")
+	mm.AppendInt32(3, metricTypeGauge)
+	mm.AppendMessage(4).AppendMessage(2).AppendDouble(1, -1.5)
+	data = m.MarshalWithLen(data)
+	mp.Put(m)
+	f(data, `http_requests_total{path="/foo",code="2\"0\\0"} 123
+http_requests_total 4.5 1700000000000
+temperature -1.5
+`)
+
+	// Summary
+	m = mp.Get()
+	mm = m.MessageMarshaler()
+	mm.AppendString(1, "rpc_duration_seconds")
+	mm.AppendInt32(3, metricTypeSummary)
+	summary := mm.AppendMessage(4).AppendMessage(4)
+	summary.AppendUint64(1, 10)
+	summary.AppendDouble(2, 1.5)
+	q := summary.AppendMessage(3)
+	q.AppendDouble(1, 0.5)
+	q.AppendDouble(2, 0.1)
+	q = summary.AppendMessage(3)
+	q.AppendDouble(1, 0.99)
+	q.AppendDouble(2, 0.3)
+	data = m.MarshalWithLen(nil)
+	mp.Put(m)
+	f(data, `rpc_duration_seconds{quantile="0.5"} 0.1
+rpc_duration_seconds{quantile="0.99"} 0.3
+rpc_duration_seconds_sum 1.5
+rpc_duration_seconds_count 10
+`)
+
+	// Classic histogram
+	m = mp.Get()
+	mm = m.MessageMarshaler()
+	mm.AppendString(1, "request_duration_seconds")
+	mm.AppendInt32(3, metricTypeHistogram)
+	metric = mm.AppendMessage(4)
+	appendLabelPair(metric, "job", "foo")
+	histogram := metric.AppendMessage(7)
+	histogram.AppendUint64(1, 5)
+	histogram.AppendDouble(2, 3.25)
+	b := histogram.AppendMessage(3)
+	b.AppendUint64(1, 2)
+	b.AppendDouble(2, 0.5)
+	b = histogram.AppendMessage(3)
+	b.AppendUint64(1, 4)
+	b.AppendDouble(2, 1)
+	data = m.MarshalWithLen(nil)
+	mp.Put(m)
+	f(data, `request_duration_seconds_bucket{job="foo",le="0.5"} 2
+request_duration_seconds_bucket{job="foo",le="1"} 4
+request_duration_seconds_bucket{job="foo",le="+Inf"} 5
+request_duration_seconds_sum{job="foo"} 3.25
+request_duration_seconds_count{job="foo"} 5
+`)
+
+	// Native histogram with schema=0, e.g. bucket boundaries are powers of 2
+	m = mp.Get()
+	mm = m.MessageMarshaler()
+	mm.AppendString(1, "latency_seconds")
+	mm.AppendInt32(3, metricTypeHistogram)
+	histogram = mm.AppendMessage(4).AppendMessage(7)
+	histogram.AppendUint64(1, 10)
+	histogram.AppendDouble(2, 12)
+	histogram.AppendSint32(5, 0)
+	histogram.AppendDouble(6, 0.001)
+	histogram.AppendUint64(7, 1)
+	// Negative bucket (-2 .. -1]
+	span := histogram.AppendMessage(9)
+	span.AppendSint32(1, 1)
+	span.AppendUint32(2, 1)
+	histogram.AppendSint64s(10, []int64{1})
+	// Positive buckets (0.5 .. 1], (1 .. 2] and (4 .. 8]
+	span = histogram.AppendMessage(12)
+	span.AppendSint32(1, 0)
+	span.AppendUint32(2, 2)
+	span = histogram.AppendMessage(12)
+	span.AppendSint32(1, 1)
+	span.AppendUint32(2, 1)
+	histogram.AppendSint64s(13, []int64{2, 1, -1})
+	data = m.MarshalWithLen(nil)
+	mp.Put(m)
+	f(data, `latency_seconds_bucket{vmrange="-2...-1"} 1
+latency_seconds_bucket{vmrange="-0.001...0.001"} 1
+latency_seconds_bucket{vmrange="0.5...1"} 2
+latency_seconds_bucket{vmrange="1...2"} 3
+latency_seconds_bucket{vmrange="4...8"} 2
+latency_seconds_sum 12
+latency_seconds_count 10
+`)
+
+	// Special values
+	m = mp.Get()
+	mm = m.MessageMarshaler()
+	mm.AppendString(1, "foo")
+	mm.AppendMessage(4).AppendMessage(5).AppendDouble(1, math.Inf(1))
+	mm.AppendMessage(4).AppendMessage(5).AppendDouble(1, math.NaN())
+	data = m.MarshalWithLen(nil)
+	mp.Put(m)
+	f(data, `foo +Inf
+foo NaN
+`)
+}
+
+func appendLabelPair(mm *easyproto.MessageMarshaler, name, value string) {
+	label := mm.AppendMessage(1)
+	label.AppendString(1, name)
+	label.AppendString(2, value)
+}
@@ -0,0 +1,299 @@
+package statsd
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/valyala/fastrand"
+)
+
+// summaryQuantiles contains quantiles, which are calculated for timers, histograms and distributions.
+var summaryQuantiles = []float64{0.5, 0.9, 0.99}
+
+// maxSamplesPerInterval is the maximum number of samples to keep per each timer, histogram and distribution
+// during flush interval for calculating quantiles.
+//
+// Reservoir sampling is used when more samples are received during flush interval.
+const maxSamplesPerInterval = 10000
+
+// PushFunc is called by Aggregator with aggregated series on every flush.
+//
+// tss mustn't be held after returning from the function.
+type PushFunc func(tss []prompbmarshal.TimeSeries)
+
+// Aggregator aggregates StatsD rows and pushes the aggregated series to PushFunc every flush interval.
+//
+// The following series are generated for every StatsD metric:
+//
+//   - counter: the total sum of counter values adjusted by sample rate. The counter is increased across flushes.
+//   - gauge: the last gauge value. The value is changed by delta if the gauge value starts with explicit sign.
+//   - timer, histogram and distribution: summary with 0.5, 0.9 and 0.99 quantiles over the values received during flush interval
+//     plus <metric>_sum and <metric>_count series. Timer values are converted from milliseconds to seconds.
+//   - set: the number of unique values received during flush interval.
+//
+// Series without updates during seriesTTL are dropped.
+type Aggregator struct {
+	flushInterval time.Duration
+	seriesTTL     time.Duration
+	pushFunc      PushFunc
+
+	mu sync.Mutex
+	m  map[string]*aggrState
+
+	wg     sync.WaitGroup
+	stopCh chan struct{}
+}
+
+type aggrState struct {
+	typ    MetricType
+	labels []prompbmarshal.Label
+
+	// lastUpdate is the unix timestamp in seconds for the last update of the state
+	lastUpdate uint64
+
+	// updated is set if the state has been updated during the current flush interval
+	updated bool
+
+	// value is the current value for counters and gauges
+	value float64
+
+	// sum, count and samples are used by timers, histograms and distributions
+	sum          float64
+	count        float64
+	samples      []float64
+	samplesTotal int
+
+	// set contains unique values for sets received during the current flush interval
+	set map[string]struct{}
+}
+
+// NewAggregator returns new Aggregator, which pushes the aggregated series to pushFunc every flushInterval.
+//
+// MustStop must be called on the returned Aggregator when it is no longer needed.
+func NewAggregator(flushInterval, seriesTTL time.Duration, pushFunc PushFunc) *Aggregator {
+	a := &Aggregator{
+		flushInterval: flushInterval,
+		seriesTTL:     seriesTTL,
+		pushFunc:      pushFunc,
+		m:             make(map[string]*aggrState),
+		stopCh:        make(chan struct{}),
+	}
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.runFlusher()
+	}()
+	return a
+}
+
+// MustStop stops a and flushes the aggregated series.
+func (a *Aggregator) MustStop() {
+	close(a.stopCh)
+	a.wg.Wait()
+	a.flush(time.Now().UnixMilli())
+}
+
+func (a *Aggregator) runFlusher() {
+	t := time.NewTicker(a.flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-a.stopCh:
+			return
+		case <-t.C:
+			a.flush(time.Now().UnixMilli())
+		}
+	}
+}
+
+// Push adds rows to a.
+func (a *Aggregator) Push(rows []Row) {
+	bb := keyBufPool.Get()
+	currentTime := fasttime.UnixTimestamp()
+
+	a.mu.Lock()
+	for i := range rows {
+		r := &rows[i]
+		sortTags(r.Tags)
+		bb.B = marshalKey(bb.B[:0], r)
+		s := a.m[string(bb.B)]
+		if s == nil {
+			s = newAggrState(r)
+			a.m[string(bb.B)] = s
+		}
+		s.lastUpdate = currentTime
+		s.update(r)
+	}
+	a.mu.Unlock()
+
+	keyBufPool.Put(bb)
+}
+
+var keyBufPool bytesutil.ByteBufferPool
+
+func sortTags(tags []Tag) {
+	if sort.SliceIsSorted(tags, func(i, j int) bool { return tags[i].Key < tags[j].Key }) {
+		return
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Key < tags[j].Key
+	})
+}
+
+func marshalKey(dst []byte, r *Row) []byte {
+	dst = append(dst, r.Type...)
+	dst = append(dst, 0)
+	dst = append(dst, r.Metric...)
+	for _, tag := range r.Tags {
+		dst = append(dst, 0)
+		dst = append(dst, tag.Key...)
+		dst = append(dst, 0)
+		dst = append(dst, tag.Value...)
+	}
+	return dst
+}
+
+func newAggrState(r *Row) *aggrState {
+	labels := make([]prompbmarshal.Label, 0, len(r.Tags)+1)
+	labels = append(labels, prompbmarshal.Label{
+		Name:  "__name__",
+		Value: bytesutil.InternString(r.Metric),
+	})
+	for _, tag := range r.Tags {
+		labels = append(labels, prompbmarshal.Label{
+			Name:  bytesutil.InternString(tag.Key),
+			Value: bytesutil.InternString(tag.Value),
+		})
+	}
+	return &aggrState{
+		typ:    r.Type,
+		labels: labels,
+	}
+}
+
+func (s *aggrState) update(r *Row) {
+	s.updated = true
+	switch r.Type {
+	case MetricTypeCounter:
+		s.value += r.Value / r.SampleRate
+	case MetricTypeGauge:
+		if r.IsDelta {
+			s.value += r.Value
+		} else {
+			s.value = r.Value
+		}
+	case MetricTypeTimer, MetricTypeHistogram, MetricTypeDistribution:
+		v := r.Value
+		if r.Type == MetricTypeTimer {
+			// Convert milliseconds to seconds
+			v /= 1e3
+		}
+		count := 1 / r.SampleRate
+		s.sum += v * count
+		s.count += count
+		s.samplesTotal++
+		if len(s.samples) < maxSamplesPerInterval {
+			s.samples = append(s.samples, v)
+		} else if n := fastrand.Uint32n(uint32(s.samplesTotal)); n < maxSamplesPerInterval {
+			s.samples[n] = v
+		}
+	case MetricTypeSet:
+		if s.set == nil {
+			s.set = make(map[string]struct{})
+		}
+		if _, ok := s.set[r.SetValue]; !ok {
+			s.set[bytesutil.InternString(r.SetValue)] = struct{}{}
+		}
+	}
+}
+
+func (a *Aggregator) flush(timestamp int64) {
+	var ctx flushCtx
+	deadline := fasttime.UnixTimestamp() - uint64(a.seriesTTL.Seconds())
+
+	a.mu.Lock()
+	for k, s := range a.m {
+		if a.seriesTTL > 0 && s.lastUpdate < deadline {
+			delete(a.m, k)
+			continue
+		}
+		s.appendSeries(&ctx, timestamp)
+	}
+	a.mu.Unlock()
+
+	if len(ctx.tss) > 0 {
+		a.pushFunc(ctx.tss)
+	}
+}
+
+type flushCtx struct {
+	tss     []prompbmarshal.TimeSeries
+	labels  []prompbmarshal.Label
+	samples []prompbmarshal.Sample
+}
+
+func (ctx *flushCtx) appendSeries(labels []prompbmarshal.Label, suffix, extraName, extraValue string, timestamp int64, value float64) {
+	labelsLen := len(ctx.labels)
+	for _, label := range labels {
+		if suffix != "" && label.Name == "__name__" {
+			label.Value += suffix
+		}
+		ctx.labels = append(ctx.labels, label)
+	}
+	if extraName != "" {
+		ctx.labels = append(ctx.labels, prompbmarshal.Label{
+			Name:  extraName,
+			Value: extraValue,
+		})
+	}
+	ctx.samples = append(ctx.samples, prompbmarshal.Sample{
+		Timestamp: timestamp,
+		Value:     value,
+	})
+	ctx.tss = append(ctx.tss, prompbmarshal.TimeSeries{
+		Labels:  ctx.labels[labelsLen:],
+		Samples: ctx.samples[len(ctx.samples)-1:],
+	})
+}
+
+// appendSeries appends series for s to ctx and resets s state for the next flush interval.
+//
+// The caller must hold the lock on the Aggregator.
+func (s *aggrState) appendSeries(ctx *flushCtx, timestamp int64) {
+	switch s.typ {
+	case MetricTypeCounter, MetricTypeGauge:
+		ctx.appendSeries(s.labels, "", "", "", timestamp, s.value)
+	case MetricTypeTimer, MetricTypeHistogram, MetricTypeDistribution:
+		if len(s.samples) > 0 {
+			sort.Float64s(s.samples)
+			for _, q := range summaryQuantiles {
+				ctx.appendSeries(s.labels, "", "quantile", strconv.FormatFloat(q, 'g', -1, 64), timestamp, getQuantile(s.samples, q))
+			}
+		}
+		ctx.appendSeries(s.labels, "_sum", "", "", timestamp, s.sum)
+		ctx.appendSeries(s.labels, "_count", "", "", timestamp, s.count)
+		s.samples = s.samples[:0]
+		s.samplesTotal = 0
+	case MetricTypeSet:
+		if s.updated {
+			ctx.appendSeries(s.labels, "", "", "", timestamp, float64(len(s.set)))
+		}
+		s.set = nil
+	}
+	s.updated = false
+}
+
+// getQuantile returns quantile q for the sorted samples.
+func getQuantile(samples []float64, q float64) float64 {
+	n := int(math.Ceil(q*float64(len(samples)))) - 1
+	if n < 0 {
+		n = 0
+	}
+	return samples[n]
+}
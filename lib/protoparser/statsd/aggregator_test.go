@@ -0,0 +1,129 @@
+package statsd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestAggregator(t *testing.T) {
+	f := func(inputs []string, resultExpected string) {
+		t.Helper()
+
+		var flushes []string
+		stopped := false
+		pushFunc := func(tss []prompbmarshal.TimeSeries) {
+			if stopped {
+				return
+			}
+			var lines []string
+			for _, ts := range tss {
+				lines = append(lines, timeSeriesToString(ts))
+			}
+			sort.Strings(lines)
+			flushes = append(flushes, strings.Join(lines, "\n"))
+		}
+		a := NewAggregator(time.Hour, 0, pushFunc)
+		for _, input := range inputs {
+			var rows Rows
+			rows.Unmarshal(input)
+			a.Push(rows.Rows)
+			a.flush(1000)
+		}
+		stopped = true
+		a.MustStop()
+
+		result := strings.Join(flushes, "\n\n")
+		if result != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+
+	// Counters are increased across flushes
+	f([]string{"foo:1|c\nfoo:2|c|@0.5", "foo:3|c"}, `foo 5 1000
+
+foo 8 1000`)
+
+	// Gauges with deltas
+	f([]string{"foo:10|g|#x:y\nfoo:+2|g|#x:y", "foo:-3|g|#x:y"}, `foo{x="y"} 12 1000
+
+foo{x="y"} 9 1000`)
+
+	// Tags order doesn't matter
+	f([]string{"foo:1|c|#a:b,c:d\nfoo:1|c|#c:d,a:b"}, `foo{a="b",c="d"} 2 1000`)
+
+	// Metric types are tracked separately
+	f([]string{"foo:1|c\nfoo:3|g"}, `foo 1 1000
+foo 3 1000`)
+
+	// Timers are converted to seconds. Quantiles are returned only for flush intervals with samples
+	f([]string{"foo:100|ms\nfoo:200|ms\nfoo:300|ms|@0.5", "bar:1|c"}, `foo_count 4 1000
+foo_sum 0.9 1000
+foo{quantile="0.5"} 0.2 1000
+foo{quantile="0.9"} 0.3 1000
+foo{quantile="0.99"} 0.3 1000
+
+bar 1 1000
+foo_count 4 1000
+foo_sum 0.9 1000`)
+
+	// Sets are reset after every flush
+	f([]string{"foo:a|s\nfoo:b|s\nfoo:a|s", "foo:c|s", "bar:1|g"}, `foo 2 1000
+
+foo 1 1000
+
+bar 1 1000`)
+}
+
+func TestAggregatorSeriesTTL(t *testing.T) {
+	var tssLen int
+	a := NewAggregator(time.Hour, time.Second, func(tss []prompbmarshal.TimeSeries) {
+		tssLen = len(tss)
+	})
+	defer a.MustStop()
+
+	var rows Rows
+	rows.Unmarshal("foo:1|c\nbar:2|g")
+	a.Push(rows.Rows)
+	a.flush(1000)
+	if tssLen != 2 {
+		t.Fatalf("unexpected number of flushed series; got %d; want 2", tssLen)
+	}
+
+	// Make the series stale
+	a.mu.Lock()
+	for _, s := range a.m {
+		s.lastUpdate -= 10
+	}
+	a.mu.Unlock()
+	tssLen = 0
+	a.flush(2000)
+	if tssLen != 0 {
+		t.Fatalf("unexpected number of flushed series; got %d; want 0", tssLen)
+	}
+	if n := len(a.m); n != 0 {
+		t.Fatalf("unexpected number of tracked series; got %d; want 0", n)
+	}
+}
+
+func timeSeriesToString(ts prompbmarshal.TimeSeries) string {
+	metricName := ""
+	var labels []string
+	for _, label := range ts.Labels {
+		if label.Name == "__name__" {
+			metricName = label.Value
+			continue
+		}
+		labels = append(labels, fmt.Sprintf("%s=%q", label.Name, label.Value))
+	}
+	labelsStr := ""
+	if len(labels) > 0 {
+		labelsStr = "{" + strings.Join(labels, ",") + "}"
+	}
+	sample := ts.Samples[0]
+	return fmt.Sprintf("%s%s %g %d", metricName, labelsStr, sample.Value, sample.Timestamp)
+}
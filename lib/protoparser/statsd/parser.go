@@ -0,0 +1,290 @@
+package statsd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+// MetricType is StatsD metric type.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+type MetricType string
+
+// Supported StatsD metric types.
+const (
+	MetricTypeCounter      = MetricType("c")
+	MetricTypeGauge        = MetricType("g")
+	MetricTypeTimer        = MetricType("ms")
+	MetricTypeHistogram    = MetricType("h")
+	MetricTypeDistribution = MetricType("d")
+	MetricTypeSet          = MetricType("s")
+)
+
+// Rows contains parsed StatsD rows.
+type Rows struct {
+	Rows []Row
+
+	tagsPool []Tag
+}
+
+// Reset resets rs.
+func (rs *Rows) Reset() {
+	// Reset items, so they can be GC'ed
+
+	for i := range rs.Rows {
+		rs.Rows[i].reset()
+	}
+	rs.Rows = rs.Rows[:0]
+
+	for i := range rs.tagsPool {
+		rs.tagsPool[i].reset()
+	}
+	rs.tagsPool = rs.tagsPool[:0]
+}
+
+// Unmarshal unmarshals StatsD and DogStatsD lines from s.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+// and https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/
+//
+// s shouldn't be modified when rs is in use.
+func (rs *Rows) Unmarshal(s string) {
+	rs.Rows, rs.tagsPool = unmarshalRows(rs.Rows[:0], s, rs.tagsPool[:0])
+}
+
+// Row is a single StatsD row.
+type Row struct {
+	Metric string
+	Tags   []Tag
+	Type   MetricType
+
+	// Value contains the parsed value for all the metric types except of MetricTypeSet.
+	Value float64
+
+	// SetValue contains the original value for MetricTypeSet.
+	SetValue string
+
+	// IsDelta is set to true if the gauge value starts with explicit sign, e.g. it must be added to the current gauge value.
+	IsDelta bool
+
+	// SampleRate is the sample rate for the row. It is set to 1 if the row has no sample rate.
+	SampleRate float64
+}
+
+func (r *Row) reset() {
+	r.Metric = ""
+	r.Tags = nil
+	r.Type = ""
+	r.Value = 0
+	r.SetValue = ""
+	r.IsDelta = false
+	r.SampleRate = 0
+}
+
+func (r *Row) unmarshalMetadata(s string, tagsPool []Tag) ([]Tag, error) {
+	for len(s) > 0 {
+		field := s
+		n := strings.IndexByte(s, '|')
+		if n >= 0 {
+			field = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		if len(field) == 0 {
+			continue
+		}
+		switch field[0] {
+		case '@':
+			sampleRate, err := fastfloat.Parse(field[1:])
+			if err != nil {
+				return tagsPool, fmt.Errorf("cannot parse sample rate from %q: %w", field, err)
+			}
+			if sampleRate <= 0 || sampleRate > 1 {
+				return tagsPool, fmt.Errorf("sample rate must be in the range (0..1]; got %q", field)
+			}
+			r.SampleRate = sampleRate
+		case '#':
+			tagsStart := len(tagsPool)
+			tagsPool = unmarshalTags(tagsPool, field[1:])
+			tags := tagsPool[tagsStart:]
+			r.Tags = tags[:len(tags):len(tags)]
+		default:
+			// Ignore unsupported DogStatsD fields such as timestamp (T) and container id (c:)
+		}
+	}
+	return tagsPool, nil
+}
+
+func unmarshalRows(dst []Row, s string, tagsPool []Tag) ([]Row, []Tag) {
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '\n')
+		if n < 0 {
+			// The last line.
+			return unmarshalRow(dst, s, tagsPool)
+		}
+		dst, tagsPool = unmarshalRow(dst, s[:n], tagsPool)
+		s = s[n+1:]
+	}
+	return dst, tagsPool
+}
+
+func unmarshalRow(dst []Row, s string, tagsPool []Tag) ([]Row, []Tag) {
+	if len(s) > 0 && s[len(s)-1] == '\r' {
+		s = s[:len(s)-1]
+	}
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		// Skip empty line
+		return dst, tagsPool
+	}
+	if strings.HasPrefix(s, "_e{") || strings.HasPrefix(s, "_sc|") {
+		// Skip DogStatsD events and service checks, since they cannot be converted to time series.
+		return dst, tagsPool
+	}
+	dstLen := len(dst)
+	tagsPoolLen := len(tagsPool)
+	dst, tagsPool, err := unmarshalLine(dst, s, tagsPool)
+	if err != nil {
+		dst = dst[:dstLen]
+		tagsPool = tagsPool[:tagsPoolLen]
+		logger.Errorf("cannot unmarshal StatsD line %q: %s", s, err)
+		invalidLines.Inc()
+	}
+	return dst, tagsPool
+}
+
+var invalidLines = metrics.NewCounter(`vm_rows_invalid_total{type="statsd"}`)
+
+// unmarshalLine appends rows parsed from the given StatsD line s to dst.
+//
+// Multiple rows are appended if the line contains multiple values separated by ':'.
+// See https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/#dogstatsd-protocol-v11
+func unmarshalLine(dst []Row, s string, tagsPool []Tag) ([]Row, []Tag, error) {
+	n := strings.IndexByte(s, ':')
+	if n < 0 {
+		return dst, tagsPool, fmt.Errorf("cannot find ':' between metric name and value")
+	}
+	metric := s[:n]
+	if len(metric) == 0 {
+		return dst, tagsPool, fmt.Errorf("metric name cannot be empty")
+	}
+	s = s[n+1:]
+	n = strings.IndexByte(s, '|')
+	if n < 0 {
+		return dst, tagsPool, fmt.Errorf("cannot find '|' between value and metric type")
+	}
+	valuesStr := s[:n]
+	s = s[n+1:]
+	typeStr := s
+	metadata := ""
+	if n := strings.IndexByte(s, '|'); n >= 0 {
+		typeStr = s[:n]
+		metadata = s[n+1:]
+	}
+	typ := MetricType(typeStr)
+	switch typ {
+	case MetricTypeCounter, MetricTypeGauge, MetricTypeTimer, MetricTypeHistogram, MetricTypeDistribution, MetricTypeSet:
+	default:
+		return dst, tagsPool, fmt.Errorf("unsupported metric type %q; supported types: c, g, ms, h, d, s", typeStr)
+	}
+	var rTmp Row
+	rTmp.SampleRate = 1
+	tagsPool, err := rTmp.unmarshalMetadata(metadata, tagsPool)
+	if err != nil {
+		return dst, tagsPool, err
+	}
+	for {
+		valueStr := valuesStr
+		n := strings.IndexByte(valuesStr, ':')
+		if n >= 0 {
+			valueStr = valuesStr[:n]
+			valuesStr = valuesStr[n+1:]
+		}
+		if len(valueStr) == 0 {
+			return dst, tagsPool, fmt.Errorf("value cannot be empty")
+		}
+		if cap(dst) > len(dst) {
+			dst = dst[:len(dst)+1]
+		} else {
+			dst = append(dst, Row{})
+		}
+		r := &dst[len(dst)-1]
+		r.Metric = metric
+		r.Tags = rTmp.Tags
+		r.Type = typ
+		r.SampleRate = rTmp.SampleRate
+		r.Value = 0
+		r.SetValue = ""
+		r.IsDelta = false
+		if typ == MetricTypeSet {
+			r.SetValue = valueStr
+		} else {
+			r.IsDelta = typ == MetricTypeGauge && (valueStr[0] == '+' || valueStr[0] == '-')
+			v, err := fastfloat.Parse(strings.TrimPrefix(valueStr, "+"))
+			if err != nil {
+				return dst, tagsPool, fmt.Errorf("cannot unmarshal value from %q: %w", valueStr, err)
+			}
+			r.Value = v
+		}
+		if n < 0 {
+			return dst, tagsPool, nil
+		}
+	}
+}
+
+func unmarshalTags(dst []Tag, s string) []Tag {
+	for len(s) > 0 {
+		tagStr := s
+		n := strings.IndexByte(s, ',')
+		if n >= 0 {
+			tagStr = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		if len(tagStr) == 0 {
+			// Skip empty tag
+			continue
+		}
+		if cap(dst) > len(dst) {
+			dst = dst[:len(dst)+1]
+		} else {
+			dst = append(dst, Tag{})
+		}
+		tag := &dst[len(dst)-1]
+		tag.unmarshal(tagStr)
+		if len(tag.Key) == 0 {
+			// Skip tag without key
+			dst = dst[:len(dst)-1]
+		}
+	}
+	return dst
+}
+
+// Tag is a DogStatsD tag.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+func (t *Tag) reset() {
+	t.Key = ""
+	t.Value = ""
+}
+
+func (t *Tag) unmarshal(s string) {
+	t.reset()
+	n := strings.IndexByte(s, ':')
+	if n < 0 {
+		// Tag without value
+		t.Key = s
+		return
+	}
+	t.Key = s[:n]
+	t.Value = s[n+1:]
+}
@@ -0,0 +1,216 @@
+package statsd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowsUnmarshalFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows", len(rows.Rows))
+		}
+
+		// Try again
+		rows.Unmarshal(s)
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows", len(rows.Rows))
+		}
+	}
+
+	// Missing value
+	f("foo")
+	f("foo:|c")
+
+	// Missing metric name
+	f(":1|c")
+
+	// Missing type
+	f("foo:1")
+
+	// Unsupported type
+	f("foo:1|x")
+
+	// Invalid value
+	f("foo:bar|c")
+	f("foo:1:bar|c")
+
+	// Invalid sample rate
+	f("foo:1|c|@bar")
+	f("foo:1|c|@0")
+	f("foo:1|c|@2")
+
+	// DogStatsD events and service checks
+	f("_e{5,4}:title|text")
+	f("_sc|name|0")
+}
+
+func TestRowsUnmarshalSuccess(t *testing.T) {
+	f := func(s string, rowsExpected *Rows) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected.Rows) {
+			t.Fatalf("unexpected rows;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected.Rows)
+		}
+
+		// Try unmarshaling again
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected.Rows) {
+			t.Fatalf("unexpected rows on the second unmarshal;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected.Rows)
+		}
+
+		rows.Reset()
+		if len(rows.Rows) != 0 {
+			t.Fatalf("non-empty rows after reset: %+v", rows.Rows)
+		}
+	}
+
+	// Empty line
+	f("", &Rows{})
+	f("\r", &Rows{})
+	f("\n\n", &Rows{})
+
+	// Counter
+	f("foo.bar:1|c", &Rows{
+		Rows: []Row{{
+			Metric:     "foo.bar",
+			Type:       MetricTypeCounter,
+			Value:      1,
+			SampleRate: 1,
+		}},
+	})
+
+	// Counter with sample rate
+	f("foo:2|c|@0.5", &Rows{
+		Rows: []Row{{
+			Metric:     "foo",
+			Type:       MetricTypeCounter,
+			Value:      2,
+			SampleRate: 0.5,
+		}},
+	})
+
+	// Gauge
+	f("foo:-1.5|g\nbar:2|g", &Rows{
+		Rows: []Row{
+			{
+				Metric:     "foo",
+				Type:       MetricTypeGauge,
+				Value:      -1.5,
+				IsDelta:    true,
+				SampleRate: 1,
+			},
+			{
+				Metric:     "bar",
+				Type:       MetricTypeGauge,
+				Value:      2,
+				SampleRate: 1,
+			},
+		},
+	})
+
+	// Timer, histogram and distribution
+	f("foo:320|ms\nbar:1.5|h\nbaz:3|d", &Rows{
+		Rows: []Row{
+			{
+				Metric:     "foo",
+				Type:       MetricTypeTimer,
+				Value:      320,
+				SampleRate: 1,
+			},
+			{
+				Metric:     "bar",
+				Type:       MetricTypeHistogram,
+				Value:      1.5,
+				SampleRate: 1,
+			},
+			{
+				Metric:     "baz",
+				Type:       MetricTypeDistribution,
+				Value:      3,
+				SampleRate: 1,
+			},
+		},
+	})
+
+	// Set
+	f("users:john|s", &Rows{
+		Rows: []Row{{
+			Metric:     "users",
+			Type:       MetricTypeSet,
+			SetValue:   "john",
+			SampleRate: 1,
+		}},
+	})
+
+	// DogStatsD tags and unsupported fields
+	f("foo:1|c|@0.1|#env:prod,host:a:b,canary|T1656581400|c:abc\r\n", &Rows{
+		Rows: []Row{{
+			Metric: "foo",
+			Tags: []Tag{
+				{
+					Key:   "env",
+					Value: "prod",
+				},
+				{
+					Key:   "host",
+					Value: "a:b",
+				},
+				{
+					Key: "canary",
+				},
+			},
+			Type:       MetricTypeCounter,
+			Value:      1,
+			SampleRate: 0.1,
+		}},
+	})
+
+	// Multiple values
+	f("foo:1:2|h|#x:y", &Rows{
+		Rows: []Row{
+			{
+				Metric: "foo",
+				Tags: []Tag{{
+					Key:   "x",
+					Value: "y",
+				}},
+				Type:       MetricTypeHistogram,
+				Value:      1,
+				SampleRate: 1,
+			},
+			{
+				Metric: "foo",
+				Tags: []Tag{{
+					Key:   "x",
+					Value: "y",
+				}},
+				Type:       MetricTypeHistogram,
+				Value:      2,
+				SampleRate: 1,
+			},
+		},
+	})
+
+	// Invalid line is skipped
+	f("foo:1|c\nbar\nbaz:2|g", &Rows{
+		Rows: []Row{
+			{
+				Metric:     "foo",
+				Type:       MetricTypeCounter,
+				Value:      1,
+				SampleRate: 1,
+			},
+			{
+				Metric:     "baz",
+				Type:       MetricTypeGauge,
+				Value:      2,
+				SampleRate: 1,
+			},
+		},
+	})
+}
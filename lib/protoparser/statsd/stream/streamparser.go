@@ -0,0 +1,208 @@
+package stream
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	flushInterval = flag.Duration("statsd.flushInterval", 10*time.Second, "The interval for flushing aggregated StatsD metrics received via -statsdListenAddr. "+
+		"See https://docs.victoriametrics.com/#how-to-send-data-from-statsd-compatible-clients")
+	seriesTTL = flag.Duration("statsd.seriesTTL", 5*time.Minute, "StatsD metrics without updates during this duration are no longer flushed. "+
+		"Counters for such metrics start from zero on the next update. Set it to 0 for keeping all the StatsD metrics forever. "+
+		"See https://docs.victoriametrics.com/#how-to-send-data-from-statsd-compatible-clients")
+)
+
+var aggr *statsd.Aggregator
+
+// Init starts aggregation of StatsD metrics passed to Parse.
+//
+// The aggregated series are passed to pushFunc every -statsd.flushInterval.
+//
+// MustStop must be called when the aggregation is no longer needed.
+func Init(pushFunc statsd.PushFunc) {
+	if aggr != nil {
+		logger.Panicf("BUG: Init() has been already called")
+	}
+	if *flushInterval <= 0 {
+		logger.Fatalf("-statsd.flushInterval must be positive; got %s", *flushInterval)
+	}
+	aggr = statsd.NewAggregator(*flushInterval, *seriesTTL, pushFunc)
+}
+
+// MustStop stops aggregation of StatsD metrics and flushes the aggregated series.
+func MustStop() {
+	aggr.MustStop()
+	aggr = nil
+}
+
+// Parse parses StatsD lines from r and passes them to the aggregator started via Init.
+func Parse(r io.Reader) error {
+	return ParseWithCallback(r, func(rows []statsd.Row) error {
+		aggr.Push(rows)
+		return nil
+	})
+}
+
+// ParseWithCallback parses StatsD lines from r and calls callback for the parsed rows.
+//
+// The callback can be called concurrently multiple times for streamed data from r.
+//
+// callback shouldn't hold rows after returning.
+func ParseWithCallback(r io.Reader, callback func(rows []statsd.Row) error) error {
+	wcr := writeconcurrencylimiter.GetReader(r)
+	defer writeconcurrencylimiter.PutReader(wcr)
+	r = wcr
+
+	ctx := getStreamContext(r)
+	defer putStreamContext(ctx)
+
+	for ctx.Read() {
+		uw := getUnmarshalWork()
+		uw.ctx = ctx
+		uw.callback = callback
+		uw.reqBuf, ctx.reqBuf = ctx.reqBuf, uw.reqBuf
+		ctx.wg.Add(1)
+		common.ScheduleUnmarshalWork(uw)
+		wcr.DecConcurrency()
+	}
+	ctx.wg.Wait()
+	if err := ctx.Error(); err != nil {
+		return err
+	}
+	return ctx.callbackErr
+}
+
+func (ctx *streamContext) Read() bool {
+	readCalls.Inc()
+	if ctx.err != nil || ctx.hasCallbackError() {
+		return false
+	}
+	ctx.reqBuf, ctx.tailBuf, ctx.err = common.ReadLinesBlock(ctx.br, ctx.reqBuf, ctx.tailBuf)
+	if ctx.err != nil {
+		if ctx.err != io.EOF {
+			readErrors.Inc()
+			ctx.err = fmt.Errorf("cannot read statsd data: %w", ctx.err)
+		}
+		return false
+	}
+	return true
+}
+
+type streamContext struct {
+	br      *bufio.Reader
+	reqBuf  []byte
+	tailBuf []byte
+	err     error
+
+	wg              sync.WaitGroup
+	callbackErrLock sync.Mutex
+	callbackErr     error
+}
+
+func (ctx *streamContext) Error() error {
+	if ctx.err == io.EOF {
+		return nil
+	}
+	return ctx.err
+}
+
+func (ctx *streamContext) hasCallbackError() bool {
+	ctx.callbackErrLock.Lock()
+	ok := ctx.callbackErr != nil
+	ctx.callbackErrLock.Unlock()
+	return ok
+}
+
+func (ctx *streamContext) reset() {
+	ctx.br.Reset(nil)
+	ctx.reqBuf = ctx.reqBuf[:0]
+	ctx.tailBuf = ctx.tailBuf[:0]
+	ctx.err = nil
+	ctx.callbackErr = nil
+}
+
+var (
+	readCalls  = metrics.NewCounter(`vm_protoparser_read_calls_total{type="statsd"}`)
+	readErrors = metrics.NewCounter(`vm_protoparser_read_errors_total{type="statsd"}`)
+	rowsRead   = metrics.NewCounter(`vm_protoparser_rows_read_total{type="statsd"}`)
+)
+
+func getStreamContext(r io.Reader) *streamContext {
+	if v := streamContextPool.Get(); v != nil {
+		ctx := v.(*streamContext)
+		ctx.br.Reset(r)
+		return ctx
+	}
+	return &streamContext{
+		br: bufio.NewReaderSize(r, 64*1024),
+	}
+}
+
+func putStreamContext(ctx *streamContext) {
+	ctx.reset()
+	streamContextPool.Put(ctx)
+}
+
+var streamContextPool sync.Pool
+
+type unmarshalWork struct {
+	rows     statsd.Rows
+	ctx      *streamContext
+	callback func(rows []statsd.Row) error
+	reqBuf   []byte
+}
+
+func (uw *unmarshalWork) reset() {
+	uw.rows.Reset()
+	uw.ctx = nil
+	uw.callback = nil
+	uw.reqBuf = uw.reqBuf[:0]
+}
+
+func (uw *unmarshalWork) runCallback(rows []statsd.Row) {
+	ctx := uw.ctx
+	if err := uw.callback(rows); err != nil {
+		ctx.callbackErrLock.Lock()
+		if ctx.callbackErr == nil {
+			ctx.callbackErr = fmt.Errorf("error when processing imported data: %w", err)
+		}
+		ctx.callbackErrLock.Unlock()
+	}
+	ctx.wg.Done()
+}
+
+// Unmarshal implements common.UnmarshalWork
+func (uw *unmarshalWork) Unmarshal() {
+	uw.rows.Unmarshal(bytesutil.ToUnsafeString(uw.reqBuf))
+	rows := uw.rows.Rows
+	rowsRead.Add(len(rows))
+	uw.runCallback(rows)
+	putUnmarshalWork(uw)
+}
+
+func getUnmarshalWork() *unmarshalWork {
+	v := unmarshalWorkPool.Get()
+	if v == nil {
+		return &unmarshalWork{}
+	}
+	return v.(*unmarshalWork)
+}
+
+func putUnmarshalWork(uw *unmarshalWork) {
+	uw.reset()
+	unmarshalWorkPool.Put(uw)
+}
+
+var unmarshalWorkPool sync.Pool
@@ -0,0 +1,67 @@
+package stream
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd"
+)
+
+func TestStreamContextRead(t *testing.T) {
+	f := func(s string, rowsExpected *statsd.Rows) {
+		t.Helper()
+		ctx := getStreamContext(strings.NewReader(s))
+		if !ctx.Read() {
+			t.Fatalf("expecting successful read")
+		}
+		uw := getUnmarshalWork()
+		callbackCalls := 0
+		uw.ctx = ctx
+		uw.callback = func(rows []statsd.Row) error {
+			callbackCalls++
+			if !reflect.DeepEqual(rows, rowsExpected.Rows) {
+				t.Fatalf("unexpected rows;\ngot\n%+v;\nwant\n%+v", rows, rowsExpected.Rows)
+			}
+			return nil
+		}
+		uw.reqBuf = append(uw.reqBuf[:0], ctx.reqBuf...)
+		ctx.wg.Add(1)
+		uw.Unmarshal()
+		if callbackCalls != 1 {
+			t.Fatalf("unexpected number of callback calls; got %d; want 1", callbackCalls)
+		}
+	}
+
+	// Single line
+	f("foo:1|c", &statsd.Rows{
+		Rows: []statsd.Row{{
+			Metric:     "foo",
+			Type:       statsd.MetricTypeCounter,
+			Value:      1,
+			SampleRate: 1,
+		}},
+	})
+
+	// Multiple lines with tags
+	f("foo:1|c|#x:y\nbar:2.5|g\n", &statsd.Rows{
+		Rows: []statsd.Row{
+			{
+				Metric: "foo",
+				Tags: []statsd.Tag{{
+					Key:   "x",
+					Value: "y",
+				}},
+				Type:       statsd.MetricTypeCounter,
+				Value:      1,
+				SampleRate: 1,
+			},
+			{
+				Metric:     "bar",
+				Type:       statsd.MetricTypeGauge,
+				Value:      2.5,
+				SampleRate: 1,
+			},
+		},
+	})
+}
@@ -0,0 +1,282 @@
+package zabbix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fastjson"
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+// Rows contains rows parsed from Zabbix sender request.
+//
+// See https://www.zabbix.com/documentation/current/en/manual/appendix/protocols/zabbix_sender
+type Rows struct {
+	Rows []Row
+
+	// Failed is the number of items, which couldn't be converted to rows, e.g. items with non-numeric values.
+	Failed int
+
+	tagsPool []Tag
+}
+
+// Reset resets rs, so it can be re-used.
+func (rs *Rows) Reset() {
+	for i := range rs.Rows {
+		rs.Rows[i].reset()
+	}
+	rs.Rows = rs.Rows[:0]
+
+	for i := range rs.tagsPool {
+		rs.tagsPool[i].reset()
+	}
+	rs.tagsPool = rs.tagsPool[:0]
+
+	rs.Failed = 0
+}
+
+// Row is a single Zabbix item.
+type Row struct {
+	Metric    string
+	Tags      []Tag
+	Value     float64
+	Timestamp int64
+}
+
+func (r *Row) reset() {
+	r.Metric = ""
+	r.Tags = nil
+	r.Value = 0
+	r.Timestamp = 0
+}
+
+// Tag is a label for the Row.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+func (t *Tag) reset() {
+	t.Key = ""
+	t.Value = ""
+}
+
+var jsonParserPool fastjson.ParserPool
+
+// Unmarshal parses Zabbix sender request from b to rs.
+//
+// The request may start with ZBXD header. Items with non-numeric values are skipped and are counted in rs.Failed.
+//
+// b can be re-used after returning from rs.
+func (rs *Rows) Unmarshal(b []byte) error {
+	rs.Reset()
+
+	b, err := trimHeader(b)
+	if err != nil {
+		return err
+	}
+
+	p := jsonParserPool.Get()
+	defer jsonParserPool.Put(p)
+
+	v, err := p.ParseBytes(b)
+	if err != nil {
+		return err
+	}
+	if request := v.GetStringBytes("request"); string(request) != "sender data" {
+		return fmt.Errorf("unsupported request=%q; want %q", request, "sender data")
+	}
+	defaultTimestamp := getTimestamp(v)
+	data := v.Get("data")
+	if data == nil {
+		return fmt.Errorf("missing data array")
+	}
+	items, err := data.Array()
+	if err != nil {
+		return fmt.Errorf("cannot find data array: %w", err)
+	}
+	for _, item := range items {
+		if err := rs.unmarshalItem(item, defaultTimestamp); err != nil {
+			rs.Failed++
+		}
+	}
+	return nil
+}
+
+func (rs *Rows) unmarshalItem(item *fastjson.Value, defaultTimestamp int64) error {
+	if item.Type() != fastjson.TypeObject {
+		return fmt.Errorf("unexpected item type %s; want object", item.Type())
+	}
+	key := string(item.GetStringBytes("key"))
+	metric, params, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	value, err := getValue(item.Get("value"))
+	if err != nil {
+		return err
+	}
+	timestamp := getTimestamp(item)
+	if timestamp == 0 {
+		timestamp = defaultTimestamp
+	}
+
+	tagsStart := len(rs.tagsPool)
+	if host := item.GetStringBytes("host"); len(host) > 0 {
+		rs.tagsPool = append(rs.tagsPool, Tag{
+			Key:   "host",
+			Value: string(host),
+		})
+	}
+	for i, param := range params {
+		if param == "" {
+			continue
+		}
+		rs.tagsPool = append(rs.tagsPool, Tag{
+			Key:   "param" + strconv.Itoa(i+1),
+			Value: param,
+		})
+	}
+	var tags []Tag
+	if len(rs.tagsPool) > tagsStart {
+		tags = rs.tagsPool[tagsStart:]
+		tags = tags[:len(tags):len(tags)]
+	}
+
+	rs.Rows = append(rs.Rows, Row{
+		Metric:    metric,
+		Tags:      tags,
+		Value:     value,
+		Timestamp: timestamp,
+	})
+	return nil
+}
+
+// getTimestamp returns timestamp in milliseconds from clock and ns fields of v.
+//
+// Zero is returned if v has no clock field.
+func getTimestamp(v *fastjson.Value) int64 {
+	clock := v.GetInt64("clock")
+	if clock <= 0 {
+		return 0
+	}
+	return clock*1e3 + v.GetInt64("ns")/1e6
+}
+
+func getValue(v *fastjson.Value) (float64, error) {
+	if v == nil {
+		return 0, fmt.Errorf("missing value")
+	}
+	switch v.Type() {
+	case fastjson.TypeNumber:
+		return v.Float64()
+	case fastjson.TypeString:
+		s := strings.TrimSpace(string(v.GetStringBytes()))
+		return fastfloat.Parse(s)
+	default:
+		return 0, fmt.Errorf("unsupported value type %s", v.Type())
+	}
+}
+
+// parseKey parses Zabbix item key in the form `key[param1,param2,...]`.
+//
+// See https://www.zabbix.com/documentation/current/en/manual/config/items/item/key
+func parseKey(s string) (string, []string, error) {
+	n := strings.IndexByte(s, '[')
+	if n < 0 {
+		if s == "" {
+			return "", nil, fmt.Errorf("key cannot be empty")
+		}
+		return s, nil, nil
+	}
+	metric := s[:n]
+	if metric == "" {
+		return "", nil, fmt.Errorf("key cannot be empty")
+	}
+	if !strings.HasSuffix(s, "]") {
+		return "", nil, fmt.Errorf("missing ']' at the end of key %q", s)
+	}
+	tail := s[n+1 : len(s)-1]
+	var params []string
+	for {
+		tail = strings.TrimLeft(tail, " ")
+		var param string
+		switch {
+		case strings.HasPrefix(tail, `"`):
+			var b []byte
+			i := 1
+			for i < len(tail) && tail[i] != '"' {
+				if tail[i] == '\\' && i+1 < len(tail) && tail[i+1] == '"' {
+					i++
+				}
+				b = append(b, tail[i])
+				i++
+			}
+			if i >= len(tail) {
+				return "", nil, fmt.Errorf("missing closing quote in key %q", s)
+			}
+			param = string(b)
+			tail = strings.TrimLeft(tail[i+1:], " ")
+		case strings.HasPrefix(tail, "["):
+			i := strings.IndexByte(tail, ']')
+			if i < 0 {
+				return "", nil, fmt.Errorf("missing ']' for array parameter in key %q", s)
+			}
+			param = tail[1:i]
+			tail = strings.TrimLeft(tail[i+1:], " ")
+		default:
+			i := strings.IndexByte(tail, ',')
+			if i < 0 {
+				i = len(tail)
+			}
+			param = strings.TrimRight(tail[:i], " ")
+			tail = tail[i:]
+		}
+		params = append(params, param)
+		if len(tail) == 0 {
+			return metric, params, nil
+		}
+		if tail[0] != ',' {
+			return "", nil, fmt.Errorf("unexpected char %q after parameter %q in key %q", tail[0], param, s)
+		}
+		tail = tail[1:]
+	}
+}
+
+// trimHeader removes optional ZBXD header from b.
+//
+// See https://www.zabbix.com/documentation/current/en/manual/appendix/protocols/header_datalen
+func trimHeader(b []byte) ([]byte, error) {
+	if len(b) < 4 || string(b[:4]) != "ZBXD" {
+		return b, nil
+	}
+	if len(b) < 5 {
+		return nil, fmt.Errorf("missing flags in ZBXD header")
+	}
+	flags := b[4]
+	if flags&0x02 != 0 {
+		return nil, fmt.Errorf("compressed Zabbix packets aren't supported")
+	}
+	b = b[5:]
+	var dataLen uint64
+	if flags&0x04 != 0 {
+		// Large packet
+		if len(b) < 16 {
+			return nil, fmt.Errorf("too short ZBXD header for large packet")
+		}
+		dataLen = binary.LittleEndian.Uint64(b)
+		b = b[16:]
+	} else {
+		if len(b) < 8 {
+			return nil, fmt.Errorf("too short ZBXD header")
+		}
+		dataLen = uint64(binary.LittleEndian.Uint32(b))
+		b = b[8:]
+	}
+	if dataLen != uint64(len(b)) {
+		return nil, fmt.Errorf("unexpected data length in ZBXD header; got %d; want %d", dataLen, len(b))
+	}
+	return b, nil
+}
@@ -0,0 +1,138 @@
+package zabbix
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestRowsUnmarshalFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+
+		var rows Rows
+		if err := rows.Unmarshal([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	f("")
+	f("foo")
+	f("[]")
+	f(`{"request":"active checks"}`)
+	f(`{"request":"sender data"}`)
+	f(`{"request":"sender data","data":{}}`)
+
+	// Invalid ZBXD header
+	f("ZBXD")
+	f("ZBXD\x01\x02")
+	f("ZBXD\x03\x00\x00\x00\x00\x00\x00\x00\x00{}")
+	f("ZBXD\x01\x10\x00\x00\x00\x00\x00\x00\x00{}")
+}
+
+func TestRowsUnmarshalSuccess(t *testing.T) {
+	f := func(data string, rowsExpected []Row, failedExpected int) {
+		t.Helper()
+
+		var rows Rows
+		if err := rows.Unmarshal([]byte(data)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%+v\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+		if rows.Failed != failedExpected {
+			t.Fatalf("unexpected number of failed items; got %d; want %d", rows.Failed, failedExpected)
+		}
+
+		// Try unmarshaling again
+		if err := rows.Unmarshal([]byte(data)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows at the second unmarshal;\ngot\n%+v\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+		if rows.Failed != failedExpected {
+			t.Fatalf("unexpected number of failed items at the second unmarshal; got %d; want %d", rows.Failed, failedExpected)
+		}
+	}
+
+	// Empty data
+	f(`{"request":"sender data","data":[]}`, nil, 0)
+
+	// Single item
+	data := `{"request":"sender data","data":[{"host":"web1","key":"system.cpu.load","value":"1.5","clock":1700000000,"ns":500000000}]}`
+	rowsExpected := []Row{{
+		Metric: "system.cpu.load",
+		Tags: []Tag{{
+			Key:   "host",
+			Value: "web1",
+		}},
+		Value:     1.5,
+		Timestamp: 1700000000500,
+	}}
+	f(data, rowsExpected, 0)
+
+	// The same item with ZBXD header
+	header := []byte("ZBXD\x01\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint32(header[5:], uint32(len(data)))
+	f(string(header)+data, rowsExpected, 0)
+
+	// Large packet header
+	header = []byte("ZBXD\x05\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+	binary.LittleEndian.PutUint64(header[5:], uint64(len(data)))
+	f(string(header)+data, rowsExpected, 0)
+
+	// Key parameters, numeric values and request-level clock
+	f(`{"request":"sender data","clock":1700000000,"data":[
+		{"host":"web1","key":"vfs.fs.size[/, pfree]","value":42},
+		{"host":"web1","key":"net.if.in[\"eth0, main\",,[a,b]]","value":"10"},
+		{"host":"web1","key":"agent.version","value":"6.0.1"},
+		{"host":"web1","key":"bad[","value":"1"}
+	]}`, []Row{
+		{
+			Metric: "vfs.fs.size",
+			Tags: []Tag{
+				{
+					Key:   "host",
+					Value: "web1",
+				},
+				{
+					Key:   "param1",
+					Value: "/",
+				},
+				{
+					Key:   "param2",
+					Value: "pfree",
+				},
+			},
+			Value:     42,
+			Timestamp: 1700000000000,
+		},
+		{
+			Metric: "net.if.in",
+			Tags: []Tag{
+				{
+					Key:   "host",
+					Value: "web1",
+				},
+				{
+					Key:   "param1",
+					Value: "eth0, main",
+				},
+				{
+					Key:   "param3",
+					Value: "a,b",
+				},
+			},
+			Value:     10,
+			Timestamp: 1700000000000,
+		},
+	}, 2)
+
+	// Missing clock
+	f(`{"request":"sender data","data":[{"key":"foo","value":"1"}]}`, []Row{{
+		Metric: "foo",
+		Value:  1,
+	}}, 0)
+}
@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/zabbix"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+)
+
+var (
+	maxInsertRequestSize = flagutil.NewBytes("zabbix.maxInsertRequestSize", 64*1024*1024, "The maximum size in bytes of a single NewRelic request "+
+		"to /newrelic/infra/v2/metrics/events/bulk")
+)
+
+// Parse parses Zabbix sender request from r and calls callback for the parsed rows.
+//
+// It returns the number of items, which couldn't be parsed.
+//
+// callback shouldn't hold rows after returning.
+func Parse(r io.Reader, isGzip bool, callback func(rows []zabbix.Row) error) (int, error) {
+	wcr := writeconcurrencylimiter.GetReader(r)
+	defer writeconcurrencylimiter.PutReader(wcr)
+	r = wcr
+
+	if isGzip {
+		zr, err := common.GetGzipReader(r)
+		if err != nil {
+			return 0, fmt.Errorf("cannot read gzipped Zabbix sender data: %w", err)
+		}
+		defer common.PutGzipReader(zr)
+		r = zr
+	}
+
+	ctx := getPushCtx(r)
+	defer putPushCtx(ctx)
+	if err := ctx.Read(); err != nil {
+		return 0, fmt.Errorf("cannot read Zabbix sender request: %w", err)
+	}
+
+	rows := getRows()
+	defer putRows(rows)
+
+	if err := rows.Unmarshal(ctx.reqBuf.B); err != nil {
+		unmarshalErrors.Inc()
+		return 0, fmt.Errorf("cannot unmarshal Zabbix sender request: %w", err)
+	}
+	rowsRead.Add(len(rows.Rows))
+	invalidRows.Add(rows.Failed)
+
+	// Fill in missing timestamps
+	currentTimestamp := int64(fasttime.UnixTimestamp())
+	for i := range rows.Rows {
+		r := &rows.Rows[i]
+		if r.Timestamp == 0 {
+			r.Timestamp = currentTimestamp * 1e3
+		}
+	}
+
+	if err := callback(rows.Rows); err != nil {
+		return 0, fmt.Errorf("error when processing imported data: %w", err)
+	}
+	return rows.Failed, nil
+}
+
+// WriteSuccessResponse writes Zabbix sender response to w for the given number of processed and failed items.
+//
+// See https://www.zabbix.com/documentation/current/en/manual/appendix/protocols/zabbix_sender
+func WriteSuccessResponse(w http.ResponseWriter, processed, failed int, duration time.Duration) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"response":"success","info":"processed: %d; failed: %d; total: %d; seconds spent: %.6f"}`, processed, failed, processed+failed, duration.Seconds())
+}
+
+func getRows() *zabbix.Rows {
+	v := rowsPool.Get()
+	if v == nil {
+		return &zabbix.Rows{}
+	}
+	return v.(*zabbix.Rows)
+}
+
+func putRows(rows *zabbix.Rows) {
+	rows.Reset()
+	rowsPool.Put(rows)
+}
+
+var rowsPool sync.Pool
+
+var (
+	readCalls       = metrics.NewCounter(`vm_protoparser_read_calls_total{type="zabbix"}`)
+	readErrors      = metrics.NewCounter(`vm_protoparser_read_errors_total{type="zabbix"}`)
+	unmarshalErrors = metrics.NewCounter(`vm_protoparser_unmarshal_errors_total{type="zabbix"}`)
+	rowsRead        = metrics.NewCounter(`vm_protoparser_rows_read_total{type="zabbix"}`)
+	invalidRows     = metrics.NewCounter(`vm_rows_invalid_total{type="zabbix"}`)
+)
+
+type pushCtx struct {
+	br     *bufio.Reader
+	reqBuf bytesutil.ByteBuffer
+}
+
+func (ctx *pushCtx) Read() error {
+	readCalls.Inc()
+	lr := io.LimitReader(ctx.br, maxInsertRequestSize.N+1)
+	startTime := fasttime.UnixTimestamp()
+	reqLen, err := ctx.reqBuf.ReadFrom(lr)
+	if err != nil {
+		readErrors.Inc()
+		return fmt.Errorf("cannot read request in %d seconds: %w", fasttime.UnixTimestamp()-startTime, err)
+	}
+	if reqLen > maxInsertRequestSize.N {
+		readErrors.Inc()
+		return fmt.Errorf("too big request; mustn't exceed -zabbix.maxInsertRequestSize=%d bytes", maxInsertRequestSize.N)
+	}
+	return nil
+}
+
+func (ctx *pushCtx) reset() {
+	ctx.br.Reset(nil)
+	ctx.reqBuf.Reset()
+}
+
+func getPushCtx(r io.Reader) *pushCtx {
+	if v := pushCtxPool.Get(); v != nil {
+		ctx := v.(*pushCtx)
+		ctx.br.Reset(r)
+		return ctx
+	}
+	return &pushCtx{
+		br: bufio.NewReaderSize(r, 64*1024),
+	}
+}
+
+func putPushCtx(ctx *pushCtx) {
+	ctx.reset()
+	pushCtxPool.Put(ctx)
+}
+
+var pushCtxPool sync.Pool
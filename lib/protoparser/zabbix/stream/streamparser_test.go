@@ -0,0 +1,70 @@
+package stream
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/zabbix"
+)
+
+func TestParseFailure(t *testing.T) {
+	f := func(req string) {
+		t.Helper()
+
+		callback := func(_ []zabbix.Row) error {
+			panic(fmt.Errorf("unexpected call into callback"))
+		}
+		r := bytes.NewReader([]byte(req))
+		if _, err := Parse(r, false, callback); err == nil {
+			t.Fatalf("expecting non-empty error")
+		}
+	}
+	f("")
+	f("foo")
+	f("{}")
+	f(`{"request":"sender data","data":1}`)
+}
+
+func TestParseSuccess(t *testing.T) {
+	req := `{"request":"sender data","data":[{"host":"web1","key":"foo","value":"2","clock":1700000000},{"host":"web1","key":"bar","value":"abc"}]}`
+	rowsExpected := []zabbix.Row{{
+		Metric: "foo",
+		Tags: []zabbix.Tag{{
+			Key:   "host",
+			Value: "web1",
+		}},
+		Value:     2,
+		Timestamp: 1700000000000,
+	}}
+	callback := func(rows []zabbix.Row) error {
+		if !reflect.DeepEqual(rows, rowsExpected) {
+			return fmt.Errorf("unexpected rows\ngot\n%v\nwant\n%v", rows, rowsExpected)
+		}
+		return nil
+	}
+	r := bytes.NewReader([]byte(req))
+	failed, err := Parse(r, false, callback)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if failed != 1 {
+		t.Fatalf("unexpected number of failed items; got %d; want 1", failed)
+	}
+}
+
+func TestWriteSuccessResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	WriteSuccessResponse(w, 3, 1, 1500*time.Microsecond)
+	result := w.Body.String()
+	resultExpected := `{"response":"success","info":"processed: 3; failed: 1; total: 4; seconds spent: 0.001500"}`
+	if result != resultExpected {
+		t.Fatalf("unexpected response\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("unexpected Content-Type; got %q; want %q", contentType, "application/json")
+	}
+}
@@ -0,0 +1,88 @@
+package rwclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestClientPush(t *testing.T) {
+	f := func(disablePathAppend bool, statusCodes []int, requestsExpected int, sentRowsExpected, droppedRowsExpected uint64) {
+		t.Helper()
+
+		pathExpected := "/api/v1/write"
+		if disablePathAppend {
+			pathExpected = "/"
+		}
+		var requests atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := int(requests.Add(1))
+			if r.URL.Path != pathExpected {
+				t.Errorf("unexpected path; got %q; want %q", r.URL.Path, pathExpected)
+			}
+			if r.Header.Get("Content-Encoding") != "snappy" {
+				t.Errorf("unexpected Content-Encoding header: %q", r.Header.Get("Content-Encoding"))
+			}
+			w.WriteHeader(statusCodes[n-1])
+		}))
+		defer srv.Close()
+
+		prefix := "rwclient_test_" + t.Name()
+		c, err := NewClient(context.Background(), Config{
+			Addr:              srv.URL,
+			Concurrency:       1,
+			FlushInterval:     time.Hour,
+			RetryMinInterval:  time.Millisecond,
+			RetryMaxTime:      time.Second,
+			DisablePathAppend: disablePathAppend,
+			MetricsPrefix:     prefix,
+		})
+		if err != nil {
+			t.Fatalf("cannot create client: %s", err)
+		}
+		sentRows := c.sentRows.Get()
+		droppedRows := c.droppedRows.Get()
+
+		ts := prompbmarshal.TimeSeries{
+			Labels: []prompbmarshal.Label{{
+				Name:  "__name__",
+				Value: "foo",
+			}},
+			Samples: []prompbmarshal.Sample{{
+				Value:     1,
+				Timestamp: 1234,
+			}},
+		}
+		if err := c.Push(ts); err != nil {
+			t.Fatalf("cannot push time series: %s", err)
+		}
+		if err := c.Close(); err != nil {
+			t.Fatalf("cannot close client: %s", err)
+		}
+
+		if n := int(requests.Load()); n != requestsExpected {
+			t.Fatalf("unexpected number of requests; got %d; want %d", n, requestsExpected)
+		}
+		if n := c.sentRows.Get() - sentRows; n != sentRowsExpected {
+			t.Fatalf("unexpected number of sent rows; got %d; want %d", n, sentRowsExpected)
+		}
+		if n := c.droppedRows.Get() - droppedRows; n != droppedRowsExpected {
+			t.Fatalf("unexpected number of dropped rows; got %d; want %d", n, droppedRowsExpected)
+		}
+	}
+
+	// successful request
+	f(false, []int{204}, 1, 1, 0)
+	f(true, []int{200}, 1, 1, 0)
+
+	// retry on temporary errors
+	f(false, []int{503, 429, 200}, 3, 1, 0)
+
+	// no retries for rejected requests
+	f(false, []int{400}, 1, 0, 1)
+}
@@ -16,6 +16,9 @@ type blockStreamMerger struct {
 	// Blocks with smaller timestamps are removed because of retention.
 	retentionDeadline int64
 
+	// src holds downsampling rules and retention filters for the currently merged time series.
+	src seriesRulesCache
+
 	// Whether the call to NextBlock must be no-op.
 	nextBlockNoop bool
 
@@ -32,6 +35,7 @@ func (bsm *blockStreamMerger) reset() {
 	bsm.bsrHeap = bsm.bsrHeap[:0]
 
 	bsm.retentionDeadline = 0
+	bsm.src.reset()
 	bsm.nextBlockNoop = false
 	bsm.err = nil
 }
@@ -61,7 +65,11 @@ func (bsm *blockStreamMerger) Init(bsrs []*blockStreamReader, retentionDeadline
 	bsm.nextBlockNoop = true
 }
 
-func (bsm *blockStreamMerger) getRetentionDeadline(_ *blockHeader) int64 {
+func (bsm *blockStreamMerger) getRetentionDeadline(bh *blockHeader) int64 {
+	retentionDeadline := bsm.src.getRetentionDeadline(bh.TSID.MetricID)
+	if retentionDeadline > bsm.retentionDeadline {
+		return retentionDeadline
+	}
 	return bsm.retentionDeadline
 }
 
@@ -0,0 +1,491 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// downsamplingRule contains downsampling periods for time series matching the filter.
+type downsamplingRule struct {
+	// filter is the original series filter. It is empty for rules applied to all the time series.
+	filter string
+
+	// ie is nil if filter is empty.
+	ie *promrelabel.IfExpression
+
+	// periods are sorted by offset in ascending order.
+	periods []downsamplingPeriod
+}
+
+// downsamplingPeriod instructs leaving the last sample per each interval for samples older than offset.
+type downsamplingPeriod struct {
+	offset   int64
+	interval int64
+}
+
+// retentionFilter contains retention for time series matching the filter.
+type retentionFilter struct {
+	filter         string
+	ie             *promrelabel.IfExpression
+	retentionMsecs int64
+}
+
+var (
+	globalDownsamplingRules []*downsamplingRule
+	globalRetentionFilters  []*retentionFilter
+
+	// hasSeriesFilters is set to true if downsampling or retention rules depend on series labels.
+	hasSeriesFilters bool
+)
+
+// SetDownsamplingPeriods sets downsampling periods from a, which may contain `[filter:]offset:interval` items.
+//
+// Rules with filter are applied to time series matching the filter. The first matching filter wins.
+// Rules without filter are applied to time series, which do not match any filter.
+//
+// This function must be called after SetDedupInterval and before initializing the storage.
+func SetDownsamplingPeriods(a []string) error {
+	rules, err := parseDownsamplingPeriods(a, GetDedupInterval())
+	if err != nil {
+		return err
+	}
+	globalDownsamplingRules = rules
+	updateHasSeriesFilters()
+	return nil
+}
+
+// SetRetentionFilters sets retention filters from a, which may contain `filter:duration` items.
+//
+// The duration for every filter cannot exceed maxRetention.
+// The smallest retention is applied to time series matching multiple filters.
+//
+// This function must be called before initializing the storage.
+func SetRetentionFilters(a []string, maxRetention time.Duration) error {
+	rfs, err := parseRetentionFilters(a, maxRetention.Milliseconds())
+	if err != nil {
+		return err
+	}
+	globalRetentionFilters = rfs
+	updateHasSeriesFilters()
+	return nil
+}
+
+func updateHasSeriesFilters() {
+	hasSeriesFilters = len(globalRetentionFilters) > 0
+	for _, r := range globalDownsamplingRules {
+		if r.ie != nil {
+			hasSeriesFilters = true
+		}
+	}
+}
+
+// IsDownsamplingEnabled returns true if downsampling or retention filters are configured.
+func IsDownsamplingEnabled() bool {
+	return len(globalDownsamplingRules) > 0 || len(globalRetentionFilters) > 0
+}
+
+func parseDownsamplingPeriods(a []string, dedupInterval int64) ([]*downsamplingRule, error) {
+	var rules []*downsamplingRule
+	var defaultRule *downsamplingRule
+	m := make(map[string]*downsamplingRule)
+	for _, s := range a {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		filter, tail := splitSeriesFilter(s)
+		n := strings.IndexByte(tail, ':')
+		if n < 0 {
+			return nil, fmt.Errorf("missing ':' in %q; want `[filter:]offset:interval`", s)
+		}
+		offset, err := promutils.ParseDuration(tail[:n])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse offset in %q: %w", s, err)
+		}
+		interval, err := promutils.ParseDuration(tail[n+1:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse interval in %q: %w", s, err)
+		}
+		if offset < 0 || interval < 0 {
+			return nil, fmt.Errorf("offset and interval cannot be negative in %q", s)
+		}
+		if interval == 0 && offset > 0 {
+			return nil, fmt.Errorf("interval cannot be zero for non-zero offset in %q", s)
+		}
+		p := downsamplingPeriod{
+			offset:   offset.Milliseconds(),
+			interval: interval.Milliseconds(),
+		}
+		if dedupInterval > 0 && p.interval > 0 && p.interval%dedupInterval != 0 {
+			return nil, fmt.Errorf("interval in %q must be multiple of -dedup.minScrapeInterval=%dms", s, dedupInterval)
+		}
+
+		r := m[filter]
+		if r == nil {
+			r = &downsamplingRule{
+				filter: filter,
+			}
+			if filter != "" {
+				ie, err := newIfExpression(filter)
+				if err != nil {
+					return nil, fmt.Errorf("cannot parse filter in %q: %w", s, err)
+				}
+				r.ie = ie
+				rules = append(rules, r)
+			} else {
+				defaultRule = r
+			}
+			m[filter] = r
+		}
+		for _, pPrev := range r.periods {
+			if pPrev.offset == p.offset {
+				return nil, fmt.Errorf("duplicate offset in %q", s)
+			}
+		}
+		r.periods = append(r.periods, p)
+	}
+	if defaultRule != nil {
+		// Rules without filter are applied to time series, which do not match any filter.
+		rules = append(rules, defaultRule)
+	}
+	for _, r := range rules {
+		sort.Slice(r.periods, func(i, j int) bool {
+			return r.periods[i].offset < r.periods[j].offset
+		})
+		for i := 1; i < len(r.periods); i++ {
+			prev := r.periods[i-1].interval
+			curr := r.periods[i].interval
+			if prev > 0 && (curr < prev || curr%prev != 0) {
+				return nil, fmt.Errorf("downsampling intervals for filter %q must be multiples of each other and must increase with offset; got %dms after %dms",
+					r.filter, curr, prev)
+			}
+		}
+	}
+	return rules, nil
+}
+
+func parseRetentionFilters(a []string, maxRetentionMsecs int64) ([]*retentionFilter, error) {
+	var rfs []*retentionFilter
+	for _, s := range a {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		filter, tail := splitSeriesFilter(s)
+		if filter == "" {
+			return nil, fmt.Errorf("missing series filter in %q; want `filter:duration`", s)
+		}
+		ie, err := newIfExpression(filter)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse filter in %q: %w", s, err)
+		}
+		d, err := promutils.ParseDuration(tail)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse duration in %q: %w", s, err)
+		}
+		retentionMsecs := d.Milliseconds()
+		if retentionMsecs <= 0 {
+			return nil, fmt.Errorf("duration must be positive in %q", s)
+		}
+		if maxRetentionMsecs > 0 && retentionMsecs > maxRetentionMsecs {
+			return nil, fmt.Errorf("duration in %q cannot exceed -retentionPeriod=%s", s, time.Duration(maxRetentionMsecs)*time.Millisecond)
+		}
+		rfs = append(rfs, &retentionFilter{
+			filter:         filter,
+			ie:             ie,
+			retentionMsecs: retentionMsecs,
+		})
+	}
+	return rfs, nil
+}
+
+// splitSeriesFilter splits s into optional series filter and the remaining tail.
+//
+// The series filter must end with '}', e.g. `{foo="bar"}` or `foo{bar="baz"}`.
+func splitSeriesFilter(s string) (string, string) {
+	n := strings.LastIndexByte(s, '}')
+	if n < 0 {
+		return "", s
+	}
+	filter := s[:n+1]
+	tail := strings.TrimPrefix(s[n+1:], ":")
+	return filter, tail
+}
+
+func newIfExpression(filter string) (*promrelabel.IfExpression, error) {
+	var ie promrelabel.IfExpression
+	if err := ie.Parse(filter); err != nil {
+		return nil, err
+	}
+	return &ie, nil
+}
+
+// DownsampleSamples applies retention filters and downsampling rules for the time series with the given mn to samples.
+//
+// currentTimestamp is used as a base for offsets in downsampling rules and for retention filters.
+// Samples must be sorted by timestamps. The returned samples may refer to the original samples.
+func DownsampleSamples(mn *MetricName, timestamps []int64, values []float64, currentTimestamp int64) ([]int64, []float64) {
+	if !IsDownsamplingEnabled() || len(timestamps) == 0 {
+		return timestamps, values
+	}
+	periods, retentionMsecs := getSeriesRules(mn)
+	if retentionMsecs > 0 {
+		deadline := currentTimestamp - retentionMsecs
+		n := sort.Search(len(timestamps), func(i int) bool {
+			return timestamps[i] >= deadline
+		})
+		timestamps = timestamps[n:]
+		values = values[n:]
+	}
+	return downsampleSamples(timestamps, values, periods, currentTimestamp, DeduplicateSamples)
+}
+
+// downsampleSamples applies the given periods to samples, which must be sorted by timestamps.
+//
+// dedup is used for leaving the last sample per each downsampling interval.
+func downsampleSamples[T int64 | float64](timestamps []int64, values []T, periods []downsamplingPeriod, currentTimestamp int64,
+	dedup func(timestamps []int64, values []T, interval int64) ([]int64, []T)) ([]int64, []T) {
+	if len(periods) == 0 || len(timestamps) == 0 {
+		return timestamps, values
+	}
+	if timestamps[0] > getDownsamplingBoundary(periods[0], currentTimestamp) {
+		// Fast path - there is no need in downsampling.
+		return timestamps, values
+	}
+
+	dstTimestamps := timestamps[:0]
+	dstValues := values[:0]
+	i := 0
+	for j := len(periods) - 1; j >= 0; j-- {
+		// Process periods from the oldest to the newest, since samples are sorted by timestamps.
+		boundary := getDownsamplingBoundary(periods[j], currentTimestamp)
+		n := i + sort.Search(len(timestamps)-i, func(k int) bool {
+			return timestamps[i+k] > boundary
+		})
+		tss, vss := dedup(timestamps[i:n], values[i:n], periods[j].interval)
+		dstTimestamps = append(dstTimestamps, tss...)
+		dstValues = append(dstValues, vss...)
+		i = n
+	}
+	dstTimestamps = append(dstTimestamps, timestamps[i:]...)
+	dstValues = append(dstValues, values[i:]...)
+	return dstTimestamps, dstValues
+}
+
+// getDownsamplingBoundary returns the timestamp aligned to p.interval. Samples with timestamps up to the returned timestamp must be downsampled with p.
+//
+// The alignment guarantees that downsampling intervals aren't split by the boundary,
+// since DeduplicateSamples leaves the last sample per each (N*interval .. (N+1)*interval] time range.
+func getDownsamplingBoundary(p downsamplingPeriod, currentTimestamp int64) int64 {
+	boundary := currentTimestamp - p.offset
+	if p.interval > 0 {
+		boundary -= boundary % p.interval
+	}
+	return boundary
+}
+
+// getSeriesRules returns downsampling periods and retention in milliseconds for the time series with the given mn.
+//
+// Zero retention means that -retentionPeriod must be used.
+func getSeriesRules(mn *MetricName) ([]downsamplingPeriod, int64) {
+	if !hasSeriesFilters {
+		// Fast path - there is no need in converting mn to labels.
+		if len(globalDownsamplingRules) > 0 {
+			return globalDownsamplingRules[0].periods, 0
+		}
+		return nil, 0
+	}
+
+	lbs := labelsPool.Get().(*promutils.Labels)
+	lbs.Labels = appendMetricNameLabels(lbs.Labels[:0], mn)
+	periods, retentionMsecs := getSeriesRulesForLabels(lbs.Labels)
+	clear(lbs.Labels)
+	labelsPool.Put(lbs)
+	return periods, retentionMsecs
+}
+
+var labelsPool = &sync.Pool{
+	New: func() any {
+		return &promutils.Labels{}
+	},
+}
+
+func getSeriesRulesForLabels(labels []prompbmarshal.Label) ([]downsamplingPeriod, int64) {
+	var periods []downsamplingPeriod
+	for _, r := range globalDownsamplingRules {
+		if r.ie == nil || r.ie.Match(labels) {
+			periods = r.periods
+			break
+		}
+	}
+	retentionMsecs := int64(0)
+	for _, rf := range globalRetentionFilters {
+		if rf.ie.Match(labels) && (retentionMsecs == 0 || rf.retentionMsecs < retentionMsecs) {
+			retentionMsecs = rf.retentionMsecs
+		}
+	}
+	return periods, retentionMsecs
+}
+
+func appendMetricNameLabels(dst []prompbmarshal.Label, mn *MetricName) []prompbmarshal.Label {
+	dst = append(dst, prompbmarshal.Label{
+		Name:  "__name__",
+		Value: bytesutil.ToUnsafeString(mn.MetricGroup),
+	})
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		dst = append(dst, prompbmarshal.Label{
+			Name:  bytesutil.ToUnsafeString(tag.Key),
+			Value: bytesutil.ToUnsafeString(tag.Value),
+		})
+	}
+	return dst
+}
+
+// seriesRulesCache caches downsampling rules for the last seen metricID during background merge.
+//
+// Blocks for the same metricID are merged sequentially, so this eliminates repeated lookups for metric names.
+type seriesRulesCache struct {
+	s                *Storage
+	currentTimestamp int64
+
+	metricID       uint64
+	periods        []downsamplingPeriod
+	retentionMsecs int64
+
+	metricName []byte
+	mn         MetricName
+	labels     []prompbmarshal.Label
+}
+
+func (src *seriesRulesCache) reset() {
+	src.s = nil
+	src.currentTimestamp = 0
+	src.metricID = 0
+	src.periods = nil
+	src.retentionMsecs = 0
+	src.metricName = src.metricName[:0]
+	src.mn.Reset()
+	clear(src.labels)
+	src.labels = src.labels[:0]
+}
+
+func (src *seriesRulesCache) init(s *Storage, currentTimestamp int64) {
+	src.reset()
+	src.s = s
+	src.currentTimestamp = currentTimestamp
+}
+
+func (src *seriesRulesCache) update(metricID uint64) {
+	if !hasSeriesFilters {
+		src.periods, src.retentionMsecs = getSeriesRules(nil)
+		return
+	}
+	if metricID == src.metricID && src.metricID != 0 {
+		return
+	}
+	src.metricID = metricID
+	src.periods = nil
+	src.retentionMsecs = 0
+	if src.s == nil {
+		return
+	}
+	var ok bool
+	src.metricName, ok = src.s.idb().searchMetricNameWithCache(src.metricName[:0], metricID)
+	if !ok {
+		// Do not apply series-specific rules to time series with missing metric names.
+		return
+	}
+	if err := src.mn.Unmarshal(src.metricName); err != nil {
+		return
+	}
+	src.labels = appendMetricNameLabels(src.labels[:0], &src.mn)
+	src.periods, src.retentionMsecs = getSeriesRulesForLabels(src.labels)
+}
+
+// getRetentionDeadline returns the minimum timestamp for samples of the time series with the given metricID
+// according to retention filters.
+func (src *seriesRulesCache) getRetentionDeadline(metricID uint64) int64 {
+	if len(globalRetentionFilters) == 0 {
+		return 0
+	}
+	src.update(metricID)
+	if src.retentionMsecs <= 0 {
+		return 0
+	}
+	return src.currentTimestamp - src.retentionMsecs
+}
+
+// applyToBlock applies retention filters and downsampling rules to b.
+//
+// It returns false if all the samples in b are outside the retention configured via retention filters.
+func (src *seriesRulesCache) applyToBlock(b *Block, rowsDeleted *atomic.Uint64) bool {
+	if !IsDownsamplingEnabled() {
+		return true
+	}
+	if retentionDeadline := src.getRetentionDeadline(b.bh.TSID.MetricID); b.bh.MinTimestamp < retentionDeadline {
+		if err := b.UnmarshalData(); err != nil {
+			logger.Panicf("FATAL: cannot unmarshal block: %s", err)
+		}
+		skipSamplesOutsideRetention(b, retentionDeadline, rowsDeleted)
+		if b.nextIdx >= len(b.timestamps) {
+			return false
+		}
+	}
+
+	src.update(b.bh.TSID.MetricID)
+	periods := src.periods
+	if len(periods) == 0 || b.bh.MinTimestamp > getDownsamplingBoundary(periods[0], src.currentTimestamp) {
+		// Fast path - there is no need in downsampling.
+		return true
+	}
+	if err := b.UnmarshalData(); err != nil {
+		logger.Panicf("FATAL: cannot unmarshal block: %s", err)
+	}
+	srcTimestamps := b.timestamps[b.nextIdx:]
+	if len(srcTimestamps) < 2 {
+		return true
+	}
+	srcValues := b.values[b.nextIdx:]
+	timestamps, values := downsampleSamples(srcTimestamps, srcValues, periods, src.currentTimestamp, deduplicateSamplesDuringMerge)
+	dedupsDuringMerge.Add(uint64(len(srcTimestamps) - len(timestamps)))
+	b.timestamps = b.timestamps[:b.nextIdx+len(timestamps)]
+	b.values = b.values[:b.nextIdx+len(values)]
+	return true
+}
+
+// getDownsamplingRulesHash returns hash for downsampling rules and retention filters,
+// which must be applied to all the samples on the given tr at currentTimestamp.
+//
+// Zero is returned if there are no such rules.
+func getDownsamplingRulesHash(tr TimeRange, currentTimestamp int64) uint64 {
+	var b []byte
+	for _, r := range globalDownsamplingRules {
+		for _, p := range r.periods {
+			if p.interval > 0 && tr.MaxTimestamp <= getDownsamplingBoundary(p, currentTimestamp) {
+				b = fmt.Appendf(b, "downsampling:%s:%d:%d\n", r.filter, p.offset, p.interval)
+			}
+		}
+	}
+	for _, rf := range globalRetentionFilters {
+		if tr.MaxTimestamp < currentTimestamp-rf.retentionMsecs {
+			b = fmt.Appendf(b, "retention:%s:%d\n", rf.filter, rf.retentionMsecs)
+		}
+	}
+	if len(b) == 0 {
+		return 0
+	}
+	return xxhash.Sum64(b)
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDownsamplingPeriodsFailure(t *testing.T) {
+	f := func(a []string, dedupInterval int64) {
+		t.Helper()
+		if _, err := parseDownsamplingPeriods(a, dedupInterval); err == nil {
+			t.Fatalf("expecting non-nil error for %q", a)
+		}
+	}
+
+	// missing interval
+	f([]string{"30d"}, 0)
+
+	// invalid durations
+	f([]string{"foo:5m"}, 0)
+	f([]string{"30d:bar"}, 0)
+	f([]string{"-30d:5m"}, 0)
+
+	// zero interval for non-zero offset
+	f([]string{"30d:0s"}, 0)
+
+	// invalid filter
+	f([]string{"{foo=~\"(\"}:30d:5m"}, 0)
+
+	// duplicate offset
+	f([]string{"30d:5m", "30d:1h"}, 0)
+
+	// intervals aren't multiples of each other
+	f([]string{"30d:5m", "180d:7m"}, 0)
+	f([]string{"30d:1h", "180d:5m"}, 0)
+
+	// interval isn't multiple of dedup interval
+	f([]string{"30d:5m"}, 7*1000)
+}
+
+func TestParseDownsamplingPeriodsSuccess(t *testing.T) {
+	f := func(a []string, resultExpected []string) {
+		t.Helper()
+		rules, err := parseDownsamplingPeriods(a, 30*1000)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var result []string
+		for _, r := range rules {
+			for _, p := range r.periods {
+				result = append(result, r.filter+":"+time.Duration(p.offset*1e6).String()+":"+time.Duration(p.interval*1e6).String())
+			}
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected rules;\ngot\n%q\nwant\n%q", result, resultExpected)
+		}
+	}
+
+	f(nil, nil)
+	f([]string{"180d:1h", "30d:5m"}, []string{":720h0m0s:5m0s", ":4320h0m0s:1h0m0s"})
+
+	// rules without filter are applied after rules with filters
+	f([]string{"30d:5m", `{env="prod"}:1d:30s`, `{__name__=~"node_.*",instance="host:9100"}:1d:5m`, `{env="prod"}:0s:0s`}, []string{
+		`{env="prod"}:0s:0s`,
+		`{env="prod"}:24h0m0s:30s`,
+		`{__name__=~"node_.*",instance="host:9100"}:24h0m0s:5m0s`,
+		`:720h0m0s:5m0s`,
+	})
+}
+
+func TestParseRetentionFilters(t *testing.T) {
+	f := func(a []string, maxRetention time.Duration, resultExpected []int64) {
+		t.Helper()
+		rfs, err := parseRetentionFilters(a, maxRetention.Milliseconds())
+		if resultExpected == nil {
+			if err == nil {
+				t.Fatalf("expecting non-nil error for %q", a)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var result []int64
+		for _, rf := range rfs {
+			result = append(result, rf.retentionMsecs)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected retentions; got %d; want %d", result, resultExpected)
+		}
+	}
+
+	// missing filter
+	f([]string{"3d"}, 0, nil)
+
+	// invalid retention
+	f([]string{`{team="juniors"}:foo`}, 0, nil)
+	f([]string{`{team="juniors"}:0s`}, 0, nil)
+
+	// retention exceeding -retentionPeriod
+	f([]string{`{team="juniors"}:30d`}, 24*time.Hour*7, nil)
+
+	f([]string{`{team="juniors"}:3d`, `{env=~"dev|staging"}:1h`}, 24*time.Hour*365, []int64{3 * 24 * 3600 * 1000, 3600 * 1000})
+}
+
+func TestDownsampleSamples(t *testing.T) {
+	defer func() {
+		globalDownsamplingRules = nil
+		globalRetentionFilters = nil
+		updateHasSeriesFilters()
+	}()
+
+	f := func(downsamplingPeriods, retentionFilters []string, mn *MetricName, timestamps []int64, currentTimestamp int64, timestampsExpected []int64) {
+		t.Helper()
+		if err := SetDownsamplingPeriods(downsamplingPeriods); err != nil {
+			t.Fatalf("cannot set downsampling periods: %s", err)
+		}
+		if err := SetRetentionFilters(retentionFilters, 0); err != nil {
+			t.Fatalf("cannot set retention filters: %s", err)
+		}
+		values := make([]float64, len(timestamps))
+		for i := range values {
+			values[i] = float64(i)
+		}
+		result, _ := DownsampleSamples(mn, append([]int64{}, timestamps...), values, currentTimestamp)
+		if len(result) == 0 {
+			result = nil
+		}
+		if !reflect.DeepEqual(result, timestampsExpected) {
+			t.Fatalf("unexpected timestamps;\ngot\n%d\nwant\n%d", result, timestampsExpected)
+		}
+	}
+
+	mnFoo := &MetricName{
+		MetricGroup: []byte("foo"),
+	}
+	mnBar := &MetricName{
+		MetricGroup: []byte("bar"),
+		Tags: []Tag{{
+			Key:   []byte("env"),
+			Value: []byte("dev"),
+		}},
+	}
+	timestamps := []int64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100, 110, 120}
+
+	// downsampling is disabled
+	f(nil, nil, mnFoo, timestamps, 1000, timestamps)
+
+	// all the samples are newer than the offset
+	f([]string{"1s:20ms"}, nil, mnFoo, timestamps, 1000, timestamps)
+
+	// all the samples are older than the offset
+	f([]string{"100ms:40ms"}, nil, mnFoo, timestamps, 1000, []int64{0, 40, 80, 120})
+
+	// multi-level downsampling
+	f([]string{"40ms:20ms", "90ms:40ms"}, nil, mnFoo, timestamps, 200, []int64{0, 40, 80, 100, 120})
+
+	// downsampling with series filters
+	f([]string{`{env="dev"}:100ms:40ms`}, nil, mnFoo, timestamps, 1000, timestamps)
+	f([]string{`{env="dev"}:100ms:40ms`}, nil, mnBar, timestamps, 1000, []int64{0, 40, 80, 120})
+	f([]string{`{env="dev"}:0s:0s`, "100ms:40ms"}, nil, mnBar, timestamps, 1000, timestamps)
+	f([]string{`{env="dev"}:0s:0s`, "100ms:40ms"}, nil, mnFoo, timestamps, 1000, []int64{0, 40, 80, 120})
+
+	// retention filters
+	f(nil, []string{`{env="dev"}:50ms`}, mnFoo, timestamps, 150, timestamps)
+	f(nil, []string{`{env="dev"}:50ms`}, mnBar, timestamps, 150, []int64{100, 110, 120})
+	f(nil, []string{`{env="dev"}:50ms`}, mnBar, timestamps, 1000, nil)
+	f(nil, []string{`{env="dev"}:80ms`, `{__name__="bar"}:50ms`}, mnBar, timestamps, 150, []int64{100, 110, 120})
+
+	// retention filters and downsampling
+	f([]string{"50ms:40ms"}, []string{`{env="dev"}:100ms`}, mnBar, timestamps, 150, []int64{80, 90, 100, 110, 120})
+}
+
+func TestGetDownsamplingRulesHash(t *testing.T) {
+	defer func() {
+		globalDownsamplingRules = nil
+		globalRetentionFilters = nil
+		updateHasSeriesFilters()
+	}()
+
+	if err := SetDownsamplingPeriods([]string{"1d:1m", `{env="dev"}:0s:0s`}); err != nil {
+		t.Fatalf("cannot set downsampling periods: %s", err)
+	}
+	if err := SetRetentionFilters([]string{`{env="dev"}:3d`}, 0); err != nil {
+		t.Fatalf("cannot set retention filters: %s", err)
+	}
+	tr := TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: 3600 * 1000,
+	}
+	day := int64(24 * 3600 * 1000)
+
+	// No rules must be applied to the whole time range
+	if h := getDownsamplingRulesHash(tr, day); h != 0 {
+		t.Fatalf("unexpected non-zero hash: %d", h)
+	}
+
+	// Downsampling must be applied to the whole time range
+	h1 := getDownsamplingRulesHash(tr, 2*day)
+	if h1 == 0 {
+		t.Fatalf("expecting non-zero hash")
+	}
+	if h := getDownsamplingRulesHash(tr, 3*day); h != h1 {
+		t.Fatalf("unexpected hash change; got %d; want %d", h, h1)
+	}
+
+	// Downsampling and retention filter must be applied to the whole time range
+	h2 := getDownsamplingRulesHash(tr, 5*day)
+	if h2 == 0 || h2 == h1 {
+		t.Fatalf("unexpected hash; got %d; previous hash %d", h2, h1)
+	}
+}
@@ -1316,6 +1316,13 @@ func (is *indexSearch) getTSDBStatus(qt *querytracer.Tracer, tfss []*TagFilters,
 		qt.Printf("no matching series for filter=%s", tfss)
 		return &TSDBStatus{}, nil
 	}
+	return is.getTSDBStatusForMetricIDs(filter, date, focusLabel, topN)
+}
+
+// getTSDBStatusForMetricIDs returns topN entries for tsdb status for the given date and focusLabel.
+//
+// Only series with metricIDs from the filter are taken into account. All the series are taken into account if filter is nil.
+func (is *indexSearch) getTSDBStatusForMetricIDs(filter *uint64set.Set, date uint64, focusLabel string, topN int) (*TSDBStatus, error) {
 	ts := &is.ts
 	kb := &is.kb
 	mp := &is.mp
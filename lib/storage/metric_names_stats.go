@@ -0,0 +1,293 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+// SetTrackMetricNamesStats enables tracking of metric names usage by queries.
+//
+// This function must be called before MustOpenStorage.
+func SetTrackMetricNamesStats(ok bool) {
+	trackMetricNamesStats = ok
+}
+
+var trackMetricNamesStats = false
+
+const metricNamesStatsFilename = "metric_names_stats.json"
+
+// MetricNamesStatsRecord contains usage stats for a single metric name.
+type MetricNamesStatsRecord struct {
+	// MetricName is the metric name.
+	MetricName string `json:"metricName"`
+
+	// QueryRequestsCount is the number of queries, which selected series with the given MetricName.
+	QueryRequestsCount uint64 `json:"queryRequestsCount"`
+
+	// LastRequestTimestamp is unix timestamp in seconds for the last query, which selected series with the given MetricName.
+	//
+	// It is set to 0 if series with the given MetricName weren't queried yet.
+	LastRequestTimestamp uint64 `json:"lastRequestTimestamp"`
+}
+
+// MetricNamesStatsResponse is the response for GetMetricNamesStats.
+type MetricNamesStatsResponse struct {
+	// StatsCollectedSince is unix timestamp in seconds since the stats are collected.
+	StatsCollectedSince uint64
+
+	// TotalRecords is the number of metric names matching the query before applying the limit.
+	TotalRecords int
+
+	// Records contains stats for metric names sorted by QueryRequestsCount in ascending order.
+	Records []MetricNamesStatsRecord
+}
+
+// metricNamesStatsTracker tracks how frequently every metric name is selected by queries.
+type metricNamesStatsTracker struct {
+	path string
+
+	mu                  sync.Mutex
+	statsCollectedSince uint64
+	m                   map[string]*metricNameStats
+
+	// changed is set to true when the stats are changed since the last save.
+	changed bool
+}
+
+type metricNameStats struct {
+	requestsCount        uint64
+	lastRequestTimestamp uint64
+}
+
+// metricNamesStatsState is the on-disk representation of metricNamesStatsTracker.
+type metricNamesStatsState struct {
+	StatsCollectedSince uint64                   `json:"statsCollectedSince"`
+	Records             []MetricNamesStatsRecord `json:"records"`
+}
+
+func mustLoadMetricNamesStatsTracker(path string) *metricNamesStatsTracker {
+	mt := &metricNamesStatsTracker{
+		path:                path,
+		statsCollectedSince: fasttime.UnixTimestamp(),
+		m:                   make(map[string]*metricNameStats),
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Errorf("cannot read metric names stats from %q, so starting from scratch; error: %s", path, err)
+		}
+		return mt
+	}
+	var state metricNamesStatsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger.Errorf("cannot parse metric names stats from %q, so starting from scratch; error: %s", path, err)
+		return mt
+	}
+	mt.statsCollectedSince = state.StatsCollectedSince
+	for _, r := range state.Records {
+		mt.m[r.MetricName] = &metricNameStats{
+			requestsCount:        r.QueryRequestsCount,
+			lastRequestTimestamp: r.LastRequestTimestamp,
+		}
+	}
+	logger.Infof("loaded stats for %d metric names from %q", len(mt.m), path)
+	return mt
+}
+
+// mustSaveIfChanged saves mt to disk if it has been changed since the last save.
+func (mt *metricNamesStatsTracker) mustSaveIfChanged() {
+	mt.mu.Lock()
+	changed := mt.changed
+	mt.mu.Unlock()
+	if changed {
+		mt.mustSave()
+	}
+}
+
+func (mt *metricNamesStatsTracker) mustSave() {
+	mt.mu.Lock()
+	state := metricNamesStatsState{
+		StatsCollectedSince: mt.statsCollectedSince,
+		Records:             mt.getRecordsLocked(),
+	}
+	mt.changed = false
+	mt.mu.Unlock()
+
+	data, err := json.Marshal(&state)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal metric names stats: %s", err)
+	}
+	fs.MustWriteAtomic(mt.path, data, true)
+}
+
+func (mt *metricNamesStatsTracker) getRecordsLocked() []MetricNamesStatsRecord {
+	records := make([]MetricNamesStatsRecord, 0, len(mt.m))
+	for metricName, ms := range mt.m {
+		records = append(records, MetricNamesStatsRecord{
+			MetricName:           metricName,
+			QueryRequestsCount:   ms.requestsCount,
+			LastRequestTimestamp: ms.lastRequestTimestamp,
+		})
+	}
+	return records
+}
+
+// registerQuery registers a query, which selected series with the given metricGroups.
+//
+// metricGroups mustn't contain duplicates.
+func (mt *metricNamesStatsTracker) registerQuery(metricGroups []string) {
+	if len(metricGroups) == 0 {
+		return
+	}
+	currentTimestamp := fasttime.UnixTimestamp()
+
+	mt.mu.Lock()
+	for _, metricGroup := range metricGroups {
+		ms := mt.m[metricGroup]
+		if ms == nil {
+			ms = &metricNameStats{}
+			mt.m[metricGroup] = ms
+		}
+		ms.requestsCount++
+		ms.lastRequestTimestamp = currentTimestamp
+	}
+	mt.changed = true
+	mt.mu.Unlock()
+}
+
+func (mt *metricNamesStatsTracker) reset() {
+	mt.mu.Lock()
+	mt.m = make(map[string]*metricNameStats)
+	mt.statsCollectedSince = fasttime.UnixTimestamp()
+	mt.changed = true
+	mt.mu.Unlock()
+}
+
+// getStats returns stats for the given metricNames and for all the tracked metric names.
+//
+// Only records with QueryRequestsCount <= maxRequestsCount and with metric names containing matchPattern are returned.
+// Up to limit records with the smallest QueryRequestsCount are returned.
+func (mt *metricNamesStatsTracker) getStats(metricNames []string, limit int, maxRequestsCount uint64, matchPattern string) MetricNamesStatsResponse {
+	mt.mu.Lock()
+	statsCollectedSince := mt.statsCollectedSince
+	records := mt.getRecordsLocked()
+	for _, metricName := range metricNames {
+		if _, ok := mt.m[metricName]; !ok {
+			// The metric name wasn't queried yet.
+			records = append(records, MetricNamesStatsRecord{
+				MetricName: metricName,
+			})
+		}
+	}
+	mt.mu.Unlock()
+
+	dst := records[:0]
+	for _, r := range records {
+		if r.QueryRequestsCount > maxRequestsCount {
+			continue
+		}
+		if matchPattern != "" && !strings.Contains(r.MetricName, matchPattern) {
+			continue
+		}
+		dst = append(dst, r)
+	}
+	records = dst
+	sort.Slice(records, func(i, j int) bool {
+		a, b := &records[i], &records[j]
+		if a.QueryRequestsCount != b.QueryRequestsCount {
+			return a.QueryRequestsCount < b.QueryRequestsCount
+		}
+		return a.MetricName < b.MetricName
+	})
+	totalRecords := len(records)
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return MetricNamesStatsResponse{
+		StatsCollectedSince: statsCollectedSince,
+		TotalRecords:        totalRecords,
+		Records:             records,
+	}
+}
+
+// RegisterMetricNamesQuery registers a query, which selected series with the given marshaled metricNames.
+//
+// It is no-op if tracking of metric names stats is disabled via SetTrackMetricNamesStats.
+func (s *Storage) RegisterMetricNamesQuery(metricNames []string) {
+	mt := s.metricNamesStats
+	if mt == nil || len(metricNames) == 0 {
+		return
+	}
+
+	seen := make(map[string]struct{})
+	var metricGroups []string
+	var buf []byte
+	prevMetricGroupPrefix := ""
+	for _, metricName := range metricNames {
+		if prevMetricGroupPrefix != "" && strings.HasPrefix(metricName, prevMetricGroupPrefix) {
+			// Fast path - the series has the same MetricGroup as the previous series.
+			// This is the common case, since series with the same MetricGroup are usually located next to each other.
+			continue
+		}
+		tail, b, err := unmarshalTagValue(buf[:0], bytesutil.ToUnsafeBytes(metricName))
+		if err != nil {
+			logger.Panicf("BUG: cannot unmarshal metric group from metricName=%q: %s", metricName, err)
+		}
+		buf = b
+		prevMetricGroupPrefix = metricName[:len(metricName)-len(tail)]
+		if _, ok := seen[string(buf)]; ok {
+			continue
+		}
+		metricGroup := string(buf)
+		seen[metricGroup] = struct{}{}
+		metricGroups = append(metricGroups, metricGroup)
+	}
+	mt.registerQuery(metricGroups)
+}
+
+// GetMetricNamesStats returns usage stats for metric names registered via RegisterMetricNamesQuery.
+//
+// Metric names, which exist in the storage, but weren't queried yet, are returned with zero QueryRequestsCount.
+// See metricNamesStatsTracker.getStats for the description of limit, maxRequestsCount and matchPattern args.
+func (s *Storage) GetMetricNamesStats(qt *querytracer.Tracer, limit int, maxRequestsCount uint64, matchPattern string, deadline uint64) (MetricNamesStatsResponse, error) {
+	mt := s.metricNamesStats
+	if mt == nil {
+		return MetricNamesStatsResponse{}, fmt.Errorf("tracking of metric names stats is disabled; pass -storage.trackMetricNamesStats command-line flag for enabling it")
+	}
+	tr := TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: timestampFromTime(time.Now()),
+	}
+	metricNames, err := s.SearchLabelValuesWithFiltersOnTimeRange(qt, "__name__", nil, tr, maxMetricNamesForStats, maxMetricNamesForStats, deadline)
+	if err != nil {
+		return MetricNamesStatsResponse{}, fmt.Errorf("cannot obtain metric names: %w", err)
+	}
+	resp := mt.getStats(metricNames, limit, maxRequestsCount, matchPattern)
+	qt.Printf("collected stats for %d metric names out of %d metric names", len(resp.Records), resp.TotalRecords)
+	return resp, nil
+}
+
+// ResetMetricNamesStats resets metric names usage stats.
+func (s *Storage) ResetMetricNamesStats() {
+	if mt := s.metricNamesStats; mt != nil {
+		mt.reset()
+	}
+}
+
+const maxMetricNamesForStats = 1e9
+
+func metricNamesStatsPath(path string) string {
+	return filepath.Join(path, metadataDirname, metricNamesStatsFilename)
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestMetricNamesStatsTrackerGetStats(t *testing.T) {
+	mt := &metricNamesStatsTracker{
+		m: make(map[string]*metricNameStats),
+	}
+	mt.registerQuery([]string{"foo", "bar"})
+	mt.registerQuery([]string{"foo"})
+	mt.registerQuery([]string{"foo", "foobar"})
+
+	f := func(metricNames []string, limit int, le uint64, matchPattern string, totalRecordsExpected int, resultExpected []string) {
+		t.Helper()
+		resp := mt.getStats(metricNames, limit, le, matchPattern)
+		if resp.TotalRecords != totalRecordsExpected {
+			t.Fatalf("unexpected TotalRecords; got %d; want %d", resp.TotalRecords, totalRecordsExpected)
+		}
+		var result []string
+		for _, r := range resp.Records {
+			result = append(result, r.MetricName)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected records;\ngot\n%q\nwant\n%q", result, resultExpected)
+		}
+	}
+
+	// metric names are sorted by the number of queries
+	f(nil, 0, 1e9, "", 3, []string{"bar", "foobar", "foo"})
+
+	// never queried metric names are returned first
+	f([]string{"baz", "foo", "qwe"}, 0, 1e9, "", 5, []string{"baz", "qwe", "bar", "foobar", "foo"})
+
+	// limit
+	f([]string{"baz", "foo", "qwe"}, 2, 1e9, "", 5, []string{"baz", "qwe"})
+
+	// le
+	f([]string{"baz"}, 0, 0, "", 1, []string{"baz"})
+	f([]string{"baz"}, 0, 1, "", 3, []string{"baz", "bar", "foobar"})
+
+	// match_pattern
+	f([]string{"baz"}, 0, 1e9, "foo", 2, []string{"foobar", "foo"})
+}
+
+func TestStorageMetricNamesStats(t *testing.T) {
+	path := "TestStorageMetricNamesStats"
+	SetTrackMetricNamesStats(true)
+	defer SetTrackMetricNamesStats(false)
+
+	s := MustOpenStorage(path, 0, 0, 0)
+
+	var mrs []MetricRow
+	var metricNames []string
+	now := time.Now().UnixMilli()
+	for _, name := range []string{"foo", "bar", "baz"} {
+		for i := 0; i < 3; i++ {
+			mn := MetricName{
+				MetricGroup: []byte(name),
+				Tags: []Tag{{
+					Key:   []byte("instance"),
+					Value: []byte{byte('a' + i)},
+				}},
+			}
+			mrs = append(mrs, MetricRow{
+				MetricNameRaw: mn.marshalRaw(nil),
+				Timestamp:     now,
+				Value:         1,
+			})
+			if name != "baz" {
+				metricNames = append(metricNames, string(mn.Marshal(nil)))
+			}
+		}
+	}
+	s.AddRows(mrs, defaultPrecisionBits)
+	s.DebugFlush()
+
+	s.RegisterMetricNamesQuery(metricNames)
+	s.RegisterMetricNamesQuery(metricNames[:3])
+
+	checkStats := func(s *Storage) {
+		t.Helper()
+		resp, err := s.GetMetricNamesStats(nil, 0, 1e9, "", noDeadline)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var result []string
+		var counts []uint64
+		for _, r := range resp.Records {
+			result = append(result, r.MetricName)
+			counts = append(counts, r.QueryRequestsCount)
+		}
+		if !reflect.DeepEqual(result, []string{"baz", "bar", "foo"}) {
+			t.Fatalf("unexpected metric names: %q", result)
+		}
+		if !reflect.DeepEqual(counts, []uint64{0, 1, 2}) {
+			t.Fatalf("unexpected query counts: %d", counts)
+		}
+	}
+	checkStats(s)
+
+	// The stats must be persisted periodically, so they aren't lost on unclean shutdown
+	s.metricNamesStats.mustSaveIfChanged()
+	mt := mustLoadMetricNamesStatsTracker(metricNamesStatsPath(path))
+	if len(mt.m) != 2 || mt.m["foo"].requestsCount != 2 || mt.m["bar"].requestsCount != 1 {
+		t.Fatalf("unexpected stats loaded from disk: %d records", len(mt.m))
+	}
+
+	// The stats must be persisted across restarts
+	s.MustClose()
+	s = MustOpenStorage(path, 0, 0, 0)
+	checkStats(s)
+
+	s.ResetMetricNamesStats()
+	resp, err := s.GetMetricNamesStats(nil, 0, 0, "", noDeadline)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.TotalRecords != 3 {
+		t.Fatalf("unexpected number of never queried metric names after the reset; got %d; want 3", resp.TotalRecords)
+	}
+
+	// Every metric name must be registered only once per query, even if series with the same metric name aren't adjacent
+	s.RegisterMetricNamesQuery([]string{metricNames[0], metricNames[3], metricNames[1], metricNames[4], metricNames[2]})
+	resp, err = s.GetMetricNamesStats(nil, 0, 1e9, "", noDeadline)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, r := range resp.Records {
+		countExpected := uint64(1)
+		if r.MetricName == "baz" {
+			countExpected = 0
+		}
+		if r.QueryRequestsCount != countExpected {
+			t.Fatalf("unexpected query count for %q; got %d; want %d", r.MetricName, r.QueryRequestsCount, countExpected)
+		}
+	}
+	s.MustClose()
+	fs.MustRemoveAll(path)
+}
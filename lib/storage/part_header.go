@@ -31,6 +31,11 @@ type partHeader struct {
 
 	// MinDedupInterval is minimal dedup interval in milliseconds across all the blocks in the part.
 	MinDedupInterval int64
+
+	// DownsamplingRulesHash is the hash of downsampling rules and retention filters applied to all the samples in the part.
+	//
+	// See getDownsamplingRulesHash for details.
+	DownsamplingRulesHash uint64 `json:",omitempty"`
 }
 
 // String returns string representation of ph.
@@ -45,6 +50,7 @@ func (ph *partHeader) Reset() {
 	ph.MinTimestamp = (1 << 63) - 1
 	ph.MaxTimestamp = -1 << 63
 	ph.MinDedupInterval = 0
+	ph.DownsamplingRulesHash = 0
 }
 
 func (ph *partHeader) readMinDedupInterval(partPath string) error {
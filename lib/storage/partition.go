@@ -1345,6 +1345,25 @@ func (pt *partition) isFinalDedupNeeded() bool {
 	return dedupInterval > minDedupInterval
 }
 
+// isDownsamplingNeeded returns true if downsampling rules or retention filters, which must be applied to all the samples
+// in the partition at currentTimestamp, weren't applied to some parts yet.
+func (pt *partition) isDownsamplingNeeded(currentTimestamp int64) bool {
+	h := getDownsamplingRulesHash(pt.tr, currentTimestamp)
+	if h == 0 {
+		return false
+	}
+
+	pws := pt.GetParts(nil, false)
+	defer pt.PutParts(pws)
+
+	for _, pw := range pws {
+		if pw.p.ph.DownsamplingRulesHash != h {
+			return true
+		}
+	}
+	return false
+}
+
 func getMinDedupInterval(pws []*partWrapper) int64 {
 	if len(pws) == 0 {
 		return 0
@@ -1386,7 +1405,7 @@ func (pt *partition) mergeParts(pws []*partWrapper, stopCh <-chan struct{}, isFi
 	mergeIdx := pt.nextMergeIdx()
 	dstPartPath := pt.getDstPartPath(dstPartType, mergeIdx)
 
-	if !isDedupEnabled() && isFinal && len(pws) == 1 && pws[0].mp != nil {
+	if !isDedupEnabled() && !IsDownsamplingEnabled() && isFinal && len(pws) == 1 && pws[0].mp != nil {
 		// Fast path: flush a single in-memory part to disk.
 		mp := pws[0].mp
 		mp.MustStoreToDisk(dstPartPath)
@@ -1560,7 +1579,10 @@ func (pt *partition) mergePartsInternal(dstPartPath string, bsw *blockStreamWrit
 	default:
 		logger.Panicf("BUG: unknown partType=%d", dstPartType)
 	}
-	retentionDeadline := timestampFromTime(time.Now()) - pt.s.retentionMsecs
+	currentTimestamp := timestampFromTime(time.Now())
+	retentionDeadline := currentTimestamp - pt.s.retentionMsecs
+	// Obtain the hash before the merge, since all the rules for the hash are guaranteed to be applied during the merge.
+	downsamplingRulesHash := getDownsamplingRulesHash(pt.tr, currentTimestamp)
 	activeMerges.Add(1)
 	err := mergeBlockStreams(&ph, bsw, bsrs, stopCh, pt.s, retentionDeadline, rowsMerged, rowsDeleted)
 	activeMerges.Add(-1)
@@ -1570,6 +1592,7 @@ func (pt *partition) mergePartsInternal(dstPartPath string, bsw *blockStreamWrit
 	}
 	if dstPartPath != "" {
 		ph.MinDedupInterval = GetDedupInterval()
+		ph.DownsamplingRulesHash = downsamplingRulesHash
 		ph.MustWriteMetadata(dstPartPath)
 	}
 	return &ph, nil
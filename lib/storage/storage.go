@@ -134,6 +134,7 @@ type Storage struct {
 	nextDayMetricIDsUpdaterWG  sync.WaitGroup
 	retentionWatcherWG         sync.WaitGroup
 	freeDiskSpaceWatcherWG     sync.WaitGroup
+	metricNamesStatsSaverWG    sync.WaitGroup
 
 	// The snapshotLock prevents from concurrent creation of snapshots,
 	// since this may result in snapshots without recently added data,
@@ -144,6 +145,11 @@ type Storage struct {
 	// The minimum timestamp when composite index search can be used.
 	minTimestampForCompositeIndex int64
 
+	// metricNamesStats tracks metric names usage by queries.
+	//
+	// It is nil if tracking is disabled via SetTrackMetricNamesStats.
+	metricNamesStats *metricNamesStatsTracker
+
 	// An inmemory set of deleted metricIDs.
 	//
 	// It is safe to keep the set in memory even for big number of deleted
@@ -239,6 +245,9 @@ func MustOpenStorage(path string, retention time.Duration, maxHourlySeries, maxD
 	isEmptyDB := !fs.IsPathExist(filepath.Join(path, indexdbDirname))
 	fs.MustMkdirIfNotExist(metadataDir)
 	s.minTimestampForCompositeIndex = mustGetMinTimestampForCompositeIndex(metadataDir, isEmptyDB)
+	if trackMetricNamesStats {
+		s.metricNamesStats = mustLoadMetricNamesStatsTracker(metricNamesStatsPath(path))
+	}
 
 	// Load indexdb
 	idbPath := filepath.Join(path, indexdbDirname)
@@ -288,6 +297,7 @@ func MustOpenStorage(path string, retention time.Duration, maxHourlySeries, maxD
 	s.startCurrHourMetricIDsUpdater()
 	s.startNextDayMetricIDsUpdater()
 	s.startRetentionWatcher()
+	s.startMetricNamesStatsSaver()
 
 	return s
 }
@@ -757,6 +767,35 @@ func (s *Storage) startNextDayMetricIDsUpdater() {
 	}()
 }
 
+func (s *Storage) startMetricNamesStatsSaver() {
+	if s.metricNamesStats == nil {
+		return
+	}
+	s.metricNamesStatsSaverWG.Add(1)
+	go func() {
+		s.metricNamesStatsSaver()
+		s.metricNamesStatsSaverWG.Done()
+	}()
+}
+
+// metricNamesStatsSaveInterval is the interval for periodic saving of metric names stats to disk,
+// so they aren't lost on unclean shutdown.
+const metricNamesStatsSaveInterval = time.Minute
+
+func (s *Storage) metricNamesStatsSaver() {
+	d := timeutil.AddJitterToDuration(metricNamesStatsSaveInterval)
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			s.metricNamesStats.mustSaveIfChanged()
+		}
+	}
+}
+
 func (s *Storage) currHourMetricIDsUpdater() {
 	d := timeutil.AddJitterToDuration(time.Second * 10)
 	ticker := time.NewTicker(d)
@@ -871,6 +910,7 @@ func (s *Storage) MustClose() {
 	s.retentionWatcherWG.Wait()
 	s.currHourMetricIDsUpdaterWG.Wait()
 	s.nextDayMetricIDsUpdaterWG.Wait()
+	s.metricNamesStatsSaverWG.Wait()
 
 	s.tb.MustClose()
 	s.idb().MustClose()
@@ -891,6 +931,10 @@ func (s *Storage) MustClose() {
 	nextDayMetricIDs := s.nextDayMetricIDs.Load()
 	s.mustSaveNextDayMetricIDs(nextDayMetricIDs)
 
+	if mt := s.metricNamesStats; mt != nil {
+		mt.mustSave()
+	}
+
 	// Release lock file.
 	fs.MustClose(s.flockF)
 	s.flockF = nil
@@ -1131,6 +1175,21 @@ func nextRetentionDeadlineSeconds(atSecs, retentionSecs, offsetSecs int64) int64
 	return deadline
 }
 
+// GetMatchingSeriesCount returns the number of series matching the given tfss on the given tr.
+//
+// The number is obtained from the index without reading samples.
+func (s *Storage) GetMatchingSeriesCount(qt *querytracer.Tracer, tfss []*TagFilters, tr TimeRange, maxMetrics int, deadline uint64) (int, error) {
+	qt = qt.NewChild("count matching series: filters=%s, timeRange=%s", tfss, &tr)
+	defer qt.Done()
+
+	metricIDs, err := s.idb().searchMetricIDs(qt, tfss, tr, maxMetrics, deadline)
+	if err != nil {
+		return 0, err
+	}
+	qt.Printf("found %d matching series", len(metricIDs))
+	return len(metricIDs), nil
+}
+
 // SearchMetricNames returns marshaled metric names matching the given tfss on the given tr.
 //
 // The marshaled metric names must be unmarshaled via MetricName.UnmarshalString().
@@ -435,8 +435,8 @@ func (tb *table) startFinalDedupWatcher() {
 }
 
 func (tb *table) finalDedupWatcher() {
-	if !isDedupEnabled() {
-		// Deduplication is disabled.
+	if !isDedupEnabled() && !IsDownsamplingEnabled() {
+		// Deduplication and downsampling are disabled.
 		return
 	}
 	f := func() {
@@ -450,8 +450,8 @@ func (tb *table) finalDedupWatcher() {
 				// Do not run final dedup for the current month.
 				continue
 			}
-			if !ptw.pt.isFinalDedupNeeded() {
-				// There is no need to run final dedup for the given partition.
+			if !ptw.pt.isFinalDedupNeeded() && !ptw.pt.isDownsamplingNeeded(timestamp) {
+				// There is no need to run final dedup or downsampling for the given partition.
 				continue
 			}
 			// mark partition with final deduplication marker
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/uint64set"
+)
+
+// TSDBStatusDiff contains the difference between TSDB stats for two dates.
+type TSDBStatusDiff struct {
+	// NewSeries contains stats for series registered at the date, which are missing at the compareDate.
+	NewSeries *TSDBStatus
+
+	// DisappearedSeries contains stats for series registered at the compareDate, which are missing at the date.
+	DisappearedSeries *TSDBStatus
+
+	// Churn contains series churn stats.
+	Churn SeriesChurn
+}
+
+// SeriesChurn contains series churn stats.
+type SeriesChurn struct {
+	// NetCreatedSeriesPerHour is the number of series registered at the date, which are missing at the compareDate,
+	// divided by the number of hours between the compareDate and the date.
+	//
+	// This isn't the real churn rate for non-adjacent dates, since series created and disappeared between these dates aren't counted.
+	NetCreatedSeriesPerHour float64
+
+	// NetDisappearedSeriesPerHour is the number of series registered at the compareDate, which are missing at the date,
+	// divided by the number of hours between the compareDate and the date.
+	//
+	// This isn't the real churn rate for non-adjacent dates, since series created and disappeared between these dates aren't counted.
+	NetDisappearedSeriesPerHour float64
+
+	// CurrentHourSeries is the number of series with samples ingested during the current hour.
+	CurrentHourSeries uint64
+
+	// PrevHourSeries is the number of series with samples ingested during the previous hour.
+	PrevHourSeries uint64
+
+	// CurrentHourCreatedSeries is the number of series ingested during the current hour, which weren't ingested during the previous hour.
+	CurrentHourCreatedSeries uint64
+
+	// CurrentHourDisappearedSeries is the number of series ingested during the previous hour, which weren't ingested during the current hour yet.
+	CurrentHourDisappearedSeries uint64
+}
+
+// GetTSDBStatusDiff returns the difference between TSDB stats at the date and at the compareDate for series matching the given tfss.
+//
+// Both date and compareDate must be non-zero, since the difference is calculated from the per-day index.
+func (s *Storage) GetTSDBStatusDiff(qt *querytracer.Tracer, tfss []*TagFilters, date, compareDate uint64, focusLabel string, topN, maxMetrics int, deadline uint64) (*TSDBStatusDiff, error) {
+	if date == 0 || compareDate == 0 {
+		return nil, fmt.Errorf("cannot compare TSDB stats on the whole time range; date and compareDate must be set to particular days")
+	}
+	if date == compareDate {
+		return nil, fmt.Errorf("date and compareDate must differ; got %s for both of them", dateToString(date))
+	}
+	idb := s.idb()
+	metricIDs, err := idb.getMetricIDsForDate(qt, tfss, date, maxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+	compareMetricIDs, err := idb.getMetricIDsForDate(qt, tfss, compareDate, maxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	newMetricIDs := metricIDs.Clone()
+	newMetricIDs.Subtract(compareMetricIDs)
+	disappearedMetricIDs := compareMetricIDs
+	disappearedMetricIDs.Subtract(metricIDs)
+	qt.Printf("found %d new series and %d disappeared series at %s comparing to %s",
+		newMetricIDs.Len(), disappearedMetricIDs.Len(), dateToString(date), dateToString(compareDate))
+
+	newSeries, err := idb.getTSDBStatusForMetricIDs(qt, newMetricIDs, date, focusLabel, topN, deadline)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain TSDB stats for new series: %w", err)
+	}
+	disappearedSeries, err := idb.getTSDBStatusForMetricIDs(qt, disappearedMetricIDs, compareDate, focusLabel, topN, deadline)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain TSDB stats for disappeared series: %w", err)
+	}
+
+	hours := float64(24 * absDiff(date, compareDate))
+	churn := s.getCurrentHourSeriesChurn()
+	churn.NetCreatedSeriesPerHour = float64(newSeries.TotalSeries) / hours
+	churn.NetDisappearedSeriesPerHour = float64(disappearedSeries.TotalSeries) / hours
+	diff := &TSDBStatusDiff{
+		NewSeries:         newSeries,
+		DisappearedSeries: disappearedSeries,
+		Churn:             churn,
+	}
+	return diff, nil
+}
+
+// getCurrentHourSeriesChurn returns series churn stats for the current hour.
+//
+// The stats are approximate, since the current hour may be not finished yet.
+func (s *Storage) getCurrentHourSeriesChurn() SeriesChurn {
+	hmCurr := s.currHourMetricIDs.Load()
+	hmPrev := s.prevHourMetricIDs.Load()
+	created := hmCurr.m.Clone()
+	if hmPrev.hour+1 == hmCurr.hour {
+		created.Subtract(hmPrev.m)
+	}
+	disappeared := hmPrev.m.Clone()
+	disappeared.Subtract(hmCurr.m)
+	return SeriesChurn{
+		CurrentHourSeries:            uint64(hmCurr.m.Len()),
+		PrevHourSeries:               uint64(hmPrev.m.Len()),
+		CurrentHourCreatedSeries:     uint64(created.Len()),
+		CurrentHourDisappearedSeries: uint64(disappeared.Len()),
+	}
+}
+
+// getMetricIDsForDate returns metricIDs for series matching tfss at the given date.
+//
+// All the series for the given date are returned if tfss is empty.
+func (db *indexDB) getMetricIDsForDate(qt *querytracer.Tracer, tfss []*TagFilters, date uint64, maxMetrics int, deadline uint64) (*uint64set.Set, error) {
+	qt = qt.NewChild("search for metricIDs at %s: filters=%s", dateToString(date), tfss)
+	defer qt.Done()
+
+	is := db.getIndexSearch(deadline)
+	metricIDs, err := is.getMetricIDsForDateWithFilters(qt, tfss, date, maxMetrics)
+	db.putIndexSearch(is)
+	if err != nil {
+		return nil, err
+	}
+	db.doExtDB(func(extDB *indexDB) {
+		is := extDB.getIndexSearch(deadline)
+		var extMetricIDs *uint64set.Set
+		extMetricIDs, err = is.getMetricIDsForDateWithFilters(qt, tfss, date, maxMetrics)
+		extDB.putIndexSearch(is)
+		if err == nil {
+			metricIDs.UnionMayOwn(extMetricIDs)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when searching for metricIDs in the previous indexdb: %w", err)
+	}
+	if metricIDs.Len() >= maxMetrics {
+		return nil, fmt.Errorf("the number of series at %s exceeds %d; narrow down the search with more specific filters", dateToString(date), maxMetrics)
+	}
+	qt.Printf("found %d metricIDs", metricIDs.Len())
+	return metricIDs, nil
+}
+
+func (is *indexSearch) getMetricIDsForDateWithFilters(qt *querytracer.Tracer, tfss []*TagFilters, date uint64, maxMetrics int) (*uint64set.Set, error) {
+	if len(tfss) == 0 {
+		return is.getMetricIDsForDate(date, maxMetrics)
+	}
+	metricIDs, err := is.searchMetricIDsWithFiltersOnDate(qt, tfss, date, maxMetrics)
+	if err != nil {
+		return nil, err
+	}
+	if metricIDs == nil {
+		metricIDs = &uint64set.Set{}
+	}
+	return metricIDs, nil
+}
+
+// getTSDBStatusForMetricIDs returns topN entries for tsdb status for series with the given metricIDs at the given date.
+func (db *indexDB) getTSDBStatusForMetricIDs(qt *querytracer.Tracer, metricIDs *uint64set.Set, date uint64, focusLabel string, topN int, deadline uint64) (*TSDBStatus, error) {
+	if metricIDs.Len() == 0 {
+		return &TSDBStatus{}, nil
+	}
+	qtChild := qt.NewChild("collect tsdb stats for %d series at %s in the current indexdb", metricIDs.Len(), dateToString(date))
+	is := db.getIndexSearch(deadline)
+	status, err := is.getTSDBStatusForMetricIDs(metricIDs, date, focusLabel, topN)
+	qtChild.Done()
+	db.putIndexSearch(is)
+	if err != nil {
+		return nil, err
+	}
+	if status.hasEntries() {
+		return status, nil
+	}
+	db.doExtDB(func(extDB *indexDB) {
+		qtChild := qt.NewChild("collect tsdb stats for %d series at %s in the previous indexdb", metricIDs.Len(), dateToString(date))
+		is := extDB.getIndexSearch(deadline)
+		status, err = is.getTSDBStatusForMetricIDs(metricIDs, date, focusLabel, topN)
+		qtChild.Done()
+		extDB.putIndexSearch(is)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when obtaining TSDB status from extDB: %w", err)
+	}
+	return status, nil
+}
+
+func absDiff(a, b uint64) uint64 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestStorageGetTSDBStatusDiff(t *testing.T) {
+	path := "TestStorageGetTSDBStatusDiff"
+	s := MustOpenStorage(path, 0, 0, 0)
+
+	compareDate := uint64(time.Now().UnixMilli()/msecPerDay) - 2
+	date := compareDate + 1
+	var mrs []MetricRow
+	addSeries := func(date uint64, metricGroup, instance string) {
+		mn := MetricName{
+			MetricGroup: []byte(metricGroup),
+			Tags: []Tag{{
+				Key:   []byte("instance"),
+				Value: []byte(instance),
+			}},
+		}
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     int64(date)*msecPerDay + 3600*1000,
+			Value:         1,
+		})
+	}
+	addSeries(compareDate, "foo", "a")
+	addSeries(compareDate, "bar", "a")
+	addSeries(compareDate, "bar", "b")
+	addSeries(date, "foo", "a")
+	addSeries(date, "foo", "b")
+	addSeries(date, "foo", "c")
+	addSeries(date, "baz", "a")
+	s.AddRows(mrs, defaultPrecisionBits)
+	s.DebugFlush()
+
+	diff, err := s.GetTSDBStatusDiff(nil, nil, date, compareDate, "instance", 10, 1e6, noDeadline)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := diff.NewSeries.TotalSeries; n != 3 {
+		t.Fatalf("unexpected number of new series; got %d; want 3", n)
+	}
+	newSeriesExpected := []TopHeapEntry{
+		{Name: "foo", Count: 2},
+		{Name: "baz", Count: 1},
+	}
+	if !reflect.DeepEqual(diff.NewSeries.SeriesCountByMetricName, newSeriesExpected) {
+		t.Fatalf("unexpected new series by metric name;\ngot\n%v\nwant\n%v", diff.NewSeries.SeriesCountByMetricName, newSeriesExpected)
+	}
+	newFocusLabelValuesExpected := []TopHeapEntry{
+		{Name: "a", Count: 1},
+		{Name: "b", Count: 1},
+		{Name: "c", Count: 1},
+	}
+	if !reflect.DeepEqual(diff.NewSeries.SeriesCountByFocusLabelValue, newFocusLabelValuesExpected) {
+		t.Fatalf("unexpected new series by focus label value;\ngot\n%v\nwant\n%v", diff.NewSeries.SeriesCountByFocusLabelValue, newFocusLabelValuesExpected)
+	}
+
+	if n := diff.DisappearedSeries.TotalSeries; n != 2 {
+		t.Fatalf("unexpected number of disappeared series; got %d; want 2", n)
+	}
+	disappearedSeriesExpected := []TopHeapEntry{
+		{Name: "bar", Count: 2},
+	}
+	if !reflect.DeepEqual(diff.DisappearedSeries.SeriesCountByMetricName, disappearedSeriesExpected) {
+		t.Fatalf("unexpected disappeared series by metric name;\ngot\n%v\nwant\n%v", diff.DisappearedSeries.SeriesCountByMetricName, disappearedSeriesExpected)
+	}
+
+	if v := diff.Churn.NetCreatedSeriesPerHour; v != 3.0/24 {
+		t.Fatalf("unexpected net created series per hour; got %v; want %v", v, 3.0/24)
+	}
+	if v := diff.Churn.NetDisappearedSeriesPerHour; v != 2.0/24 {
+		t.Fatalf("unexpected net disappeared series per hour; got %v; want %v", v, 2.0/24)
+	}
+
+	// Series with filters
+	tfs := NewTagFilters()
+	if err := tfs.Add(nil, []byte("foo"), false, false); err != nil {
+		t.Fatalf("cannot add filter: %s", err)
+	}
+	diff, err = s.GetTSDBStatusDiff(nil, []*TagFilters{tfs}, date, compareDate, "", 10, 1e6, noDeadline)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := diff.NewSeries.TotalSeries; n != 2 {
+		t.Fatalf("unexpected number of new series; got %d; want 2", n)
+	}
+	if n := diff.DisappearedSeries.TotalSeries; n != 0 {
+		t.Fatalf("unexpected number of disappeared series; got %d; want 0", n)
+	}
+
+	// Invalid dates
+	if _, err := s.GetTSDBStatusDiff(nil, nil, date, 0, "", 10, 1e6, noDeadline); err == nil {
+		t.Fatalf("expecting non-nil error for zero compareDate")
+	}
+	if _, err := s.GetTSDBStatusDiff(nil, nil, date, date, "", 10, 1e6, noDeadline); err == nil {
+		t.Fatalf("expecting non-nil error for equal dates")
+	}
+
+	s.MustClose()
+	fs.MustRemoveAll(path)
+}
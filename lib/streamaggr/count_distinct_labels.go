@@ -0,0 +1,114 @@
+package streamaggr
+
+import (
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// countDistinctLabelsAggrState calculates output=count_distinct_labels(label1, ..., labelN),
+// e.g. the estimated number of unique values for the given labels across input series.
+type countDistinctLabelsAggrState struct {
+	m sync.Map
+
+	labelNames []string
+}
+
+type countDistinctLabelsStateValue struct {
+	mu      sync.Mutex
+	hlls    []hyperLogLog
+	deleted bool
+}
+
+func newCountDistinctLabelsAggrState(labelNames []string) *countDistinctLabelsAggrState {
+	return &countDistinctLabelsAggrState{
+		labelNames: labelNames,
+	}
+}
+
+func (as *countDistinctLabelsAggrState) pushSamples(samples []pushSample) {
+	labelNames := as.labelNames
+	hashes := make([]uint64, len(labelNames))
+	found := make([]bool, len(labelNames))
+	auxLabels := promutils.GetLabels()
+	defer promutils.PutLabels(auxLabels)
+	for i := range samples {
+		s := &samples[i]
+		inputKey, outputKey := getInputOutputKey(s.key)
+
+		// Count unique hashes over the label values instead of unique label values.
+		// This reduces memory usage at the cost of possible hash collisions for distinct label values.
+		auxLabels.Labels = decompressLabels(auxLabels.Labels[:0], inputKey)
+		auxLabels.Labels = decompressLabels(auxLabels.Labels, outputKey)
+		for j, labelName := range labelNames {
+			hashes[j], found[j] = getLabelValueHash(auxLabels.Labels, labelName)
+		}
+
+	again:
+		v, ok := as.m.Load(outputKey)
+		if !ok {
+			// The entry is missing in the map. Try creating it.
+			v = &countDistinctLabelsStateValue{
+				hlls: make([]hyperLogLog, len(labelNames)),
+			}
+			outputKey = bytesutil.InternString(outputKey)
+			vNew, loaded := as.m.LoadOrStore(outputKey, v)
+			if loaded {
+				// Use the entry created by a concurrent goroutine.
+				v = vNew
+			}
+		}
+		sv := v.(*countDistinctLabelsStateValue)
+		sv.mu.Lock()
+		deleted := sv.deleted
+		if !deleted {
+			for j, h := range hashes {
+				if found[j] {
+					sv.hlls[j].add(h)
+				}
+			}
+		}
+		sv.mu.Unlock()
+		if deleted {
+			// The entry has been deleted by the concurrent call to flushState
+			// Try obtaining and updating the entry again.
+			goto again
+		}
+	}
+}
+
+func getLabelValueHash(labels []prompbmarshal.Label, name string) (uint64, bool) {
+	for _, label := range labels {
+		if label.Name == name {
+			return xxhash.Sum64(bytesutil.ToUnsafeBytes(label.Value)), true
+		}
+	}
+	return 0, false
+}
+
+func (as *countDistinctLabelsAggrState) flushState(ctx *flushCtx) {
+	labelNames := as.labelNames
+	m := &as.m
+	m.Range(func(k, v any) bool {
+		// Atomically delete the entry from the map, so new entry is created for the next flush.
+		m.Delete(k)
+
+		sv := v.(*countDistinctLabelsStateValue)
+		sv.mu.Lock()
+		hlls := sv.hlls
+		// Mark the entry as deleted, so it won't be updated anymore by concurrent pushSample() calls.
+		sv.deleted = true
+		sv.mu.Unlock()
+
+		key := k.(string)
+		for i := range hlls {
+			n := hlls[i].count()
+			ctx.appendSeriesWithExtraLabel(key, "count_distinct_labels", float64(n), "label", labelNames[i])
+		}
+		return true
+	})
+}
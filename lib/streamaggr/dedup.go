@@ -1,6 +1,7 @@
 package streamaggr
 
 import (
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"unsafe"
@@ -8,6 +9,7 @@ import (
 	"github.com/cespare/xxhash/v2"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/slicesutil"
 )
 
@@ -223,3 +225,60 @@ func (das *dedupAggrShard) flush(ctx *dedupFlushCtx, f func(samples []pushSample
 	f(dstSamples)
 	ctx.samples = dstSamples
 }
+
+func (da *dedupAggr) marshalState(dst []byte) []byte {
+	var entries []byte
+	entriesCount := 0
+	for i := range da.shards {
+		das := &da.shards[i]
+		das.mu.Lock()
+		for key, s := range das.m {
+			inputKey, outputKey := getInputOutputKey(key)
+			entries = marshalLabelsKey(entries, inputKey)
+			entries = marshalLabelsKey(entries, outputKey)
+			entries = marshalFloat64(entries, s.value)
+			entries = encoding.MarshalInt64(entries, s.timestamp)
+			entriesCount++
+		}
+		das.mu.Unlock()
+	}
+	dst = encoding.MarshalVarUint64(dst, uint64(entriesCount))
+	return append(dst, entries...)
+}
+
+func (da *dedupAggr) unmarshalState(src []byte) error {
+	src, entriesCount, err := unmarshalVarUint64(src)
+	if err != nil {
+		return fmt.Errorf("cannot read the number of entries: %w", err)
+	}
+	var inputKey, key []byte
+	samples := make([]pushSample, 0, entriesCount)
+	for i := uint64(0); i < entriesCount; i++ {
+		inputKey, src, err = unmarshalLabelsKey(inputKey[:0], src)
+		if err != nil {
+			return fmt.Errorf("cannot read input key: %w", err)
+		}
+		key = encoding.MarshalVarUint64(key[:0], uint64(len(inputKey)))
+		key = append(key, inputKey...)
+		key, src, err = unmarshalLabelsKey(key, src)
+		if err != nil {
+			return fmt.Errorf("cannot read output key: %w", err)
+		}
+		var s pushSample
+		s.key = string(key)
+		src, s.value, err = unmarshalFloat64(src)
+		if err != nil {
+			return fmt.Errorf("cannot read sample value: %w", err)
+		}
+		src, s.timestamp, err = unmarshalInt64(src)
+		if err != nil {
+			return fmt.Errorf("cannot read sample timestamp: %w", err)
+		}
+		samples = append(samples, s)
+	}
+	if len(src) > 0 {
+		return fmt.Errorf("unexpected non-empty tail left; len(tail)=%d", len(src))
+	}
+	da.pushSamples(samples)
+	return nil
+}
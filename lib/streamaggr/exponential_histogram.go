@@ -0,0 +1,475 @@
+package streamaggr
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+const (
+	// exponentialHistogramMinScale and exponentialHistogramMaxScale are the limits for the scale
+	// of exponential histogram according to Prometheus native histograms.
+	exponentialHistogramMinScale = -4
+	exponentialHistogramMaxScale = 8
+
+	// exponentialHistogramDefaultScale is the default scale for exponential_histogram_bucket output.
+	//
+	// It results in 8 buckets per every power of 2, e.g. every bucket is ~9% wider than the previous one.
+	exponentialHistogramDefaultScale = 3
+
+	// exponentialHistogramMaxSpreadBuckets is the maximum number of exponential buckets
+	// a single classic histogram bucket can be spread to.
+	exponentialHistogramMaxSpreadBuckets = 256
+)
+
+// exponentialHistogramAggrState calculates output=exponential_histogram_bucket,
+// e.g. exponential histogram over input samples or over classic histogram buckets with `le` label.
+type exponentialHistogramAggrState struct {
+	m sync.Map
+
+	// keys contains exponentialHistogramSeriesInfo per each input key.
+	keys sync.Map
+
+	scale int
+
+	stalenessSecs uint64
+
+	// The first sample per each new classic histogram bucket is ignored until this unix timestamp deadline in seconds.
+	// See totalAggrState.ignoreFirstSampleDeadline for details.
+	ignoreFirstSampleDeadline uint64
+
+	// vmranges contains cached vmrange label values per each bucket index.
+	vmranges sync.Map
+}
+
+// exponentialHistogramSeriesInfo contains information about input series obtained from its key.
+type exponentialHistogramSeriesInfo struct {
+	// outputKey is the output key without `le` label.
+	outputKey string
+
+	// sourceKey identifies the classic histogram the series belongs to.
+	//
+	// It is empty for series without `le` label.
+	sourceKey string
+
+	// le is the upper bound of the classic histogram bucket.
+	le float64
+
+	deleteDeadline atomic.Uint64
+}
+
+type exponentialHistogramStateValue struct {
+	mu sync.Mutex
+
+	// buckets contains cumulative counters per each exponential bucket index.
+	buckets map[int]float64
+
+	// zeroCount contains cumulative counter for zero values.
+	zeroCount float64
+
+	// sources contains the state for classic histograms, which are converted to exponential histogram.
+	sources map[string]*exponentialHistogramSource
+
+	deleteDeadline uint64
+	deleted        bool
+}
+
+// exponentialHistogramSource contains the state of a single classic histogram.
+type exponentialHistogramSource struct {
+	// buckets contains the state per each `le` value.
+	buckets map[float64]*exponentialHistogramSourceBucket
+
+	deleteDeadline uint64
+}
+
+type exponentialHistogramSourceBucket struct {
+	lastValue      float64
+	lastTimestamp  int64
+	increase       float64
+	deleteDeadline uint64
+}
+
+func newExponentialHistogramAggrState(scale int, stalenessInterval time.Duration) *exponentialHistogramAggrState {
+	stalenessSecs := roundDurationToSecs(stalenessInterval)
+	ignoreFirstSampleDeadline := fasttime.UnixTimestamp() + stalenessSecs
+
+	return &exponentialHistogramAggrState{
+		scale:                     scale,
+		stalenessSecs:             stalenessSecs,
+		ignoreFirstSampleDeadline: ignoreFirstSampleDeadline,
+	}
+}
+
+// parseExponentialHistogramScale parses scale from `exponential_histogram_bucket(scale)` output.
+func parseExponentialHistogramScale(output string) (int, error) {
+	if output == "exponential_histogram_bucket" {
+		return exponentialHistogramDefaultScale, nil
+	}
+	if !strings.HasSuffix(output, ")") {
+		return 0, fmt.Errorf("missing closing brace for `exponential_histogram_bucket()` output")
+	}
+	argStr := strings.TrimSpace(output[len("exponential_histogram_bucket(") : len(output)-1])
+	scale, err := strconv.Atoi(argStr)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse scale=%q for exponential_histogram_bucket(%s): %w", argStr, argStr, err)
+	}
+	if scale < exponentialHistogramMinScale || scale > exponentialHistogramMaxScale {
+		return 0, fmt.Errorf("scale inside exponential_histogram_bucket(%s) must be in the range [%d..%d]; got %d",
+			argStr, exponentialHistogramMinScale, exponentialHistogramMaxScale, scale)
+	}
+	return scale, nil
+}
+
+func (as *exponentialHistogramAggrState) pushSamples(samples []pushSample) {
+	currentTime := fasttime.UnixTimestamp()
+	deleteDeadline := currentTime + as.stalenessSecs
+	keepFirstSample := currentTime > as.ignoreFirstSampleDeadline
+	for i := range samples {
+		s := &samples[i]
+		si := as.getSeriesInfo(s.key, deleteDeadline)
+		outputKey := si.outputKey
+
+	again:
+		v, ok := as.m.Load(outputKey)
+		if !ok {
+			// The entry is missing in the map. Try creating it.
+			v = &exponentialHistogramStateValue{
+				buckets: make(map[int]float64),
+			}
+			vNew, loaded := as.m.LoadOrStore(outputKey, v)
+			if loaded {
+				// Use the entry created by a concurrent goroutine.
+				v = vNew
+			}
+		}
+		sv := v.(*exponentialHistogramStateValue)
+		sv.mu.Lock()
+		deleted := sv.deleted
+		if !deleted {
+			if si.sourceKey == "" {
+				sv.update(s.value, as.scale)
+			} else {
+				sv.updateSource(si, s, keepFirstSample, deleteDeadline)
+			}
+			sv.deleteDeadline = deleteDeadline
+		}
+		sv.mu.Unlock()
+		if deleted {
+			// The entry has been deleted by the concurrent call to flushState
+			// Try obtaining and updating the entry again.
+			goto again
+		}
+	}
+}
+
+// getSeriesInfo returns exponentialHistogramSeriesInfo for the given key.
+func (as *exponentialHistogramAggrState) getSeriesInfo(key string, deleteDeadline uint64) *exponentialHistogramSeriesInfo {
+	v, ok := as.keys.Load(key)
+	if !ok {
+		si := newExponentialHistogramSeriesInfo(key)
+		key = bytesutil.InternString(key)
+		v, _ = as.keys.LoadOrStore(key, si)
+	}
+	si := v.(*exponentialHistogramSeriesInfo)
+	if si.deleteDeadline.Load() != deleteDeadline {
+		si.deleteDeadline.Store(deleteDeadline)
+	}
+	return si
+}
+
+func newExponentialHistogramSeriesInfo(key string) *exponentialHistogramSeriesInfo {
+	inputKey, outputKey := getInputOutputKey(key)
+	inputLabels := decompressLabels(nil, inputKey)
+	outputLabels := decompressLabels(nil, outputKey)
+
+	inputLabels, leStr, ok := removeLeLabel(inputLabels)
+	if !ok {
+		outputLabels, leStr, ok = removeLeLabel(outputLabels)
+	}
+	if !ok {
+		return &exponentialHistogramSeriesInfo{
+			outputKey: bytesutil.InternString(outputKey),
+		}
+	}
+	le, err := strconv.ParseFloat(leStr, 64)
+	if err != nil || math.IsNaN(le) {
+		// Treat series with invalid `le` label as regular series.
+		return &exponentialHistogramSeriesInfo{
+			outputKey: bytesutil.InternString(outputKey),
+		}
+	}
+
+	bb := bbPool.Get()
+	bb.B = lc.Compress(bb.B[:0], outputLabels)
+	outputKeyWithoutLe := bytesutil.InternBytes(bb.B)
+	bb.B = compressLabels(bb.B[:0], inputLabels, outputLabels)
+	sourceKey := bytesutil.InternBytes(bb.B)
+	bbPool.Put(bb)
+
+	return &exponentialHistogramSeriesInfo{
+		outputKey: outputKeyWithoutLe,
+		sourceKey: sourceKey,
+		le:        le,
+	}
+}
+
+// removeLeLabel removes `le` label from labels and returns its value.
+func removeLeLabel(labels []prompbmarshal.Label) ([]prompbmarshal.Label, string, bool) {
+	for i, label := range labels {
+		if label.Name == "le" {
+			labels = append(labels[:i], labels[i+1:]...)
+			return labels, label.Value, true
+		}
+	}
+	return labels, "", false
+}
+
+// update registers raw sample value v in sv.
+func (sv *exponentialHistogramStateValue) update(v float64, scale int) {
+	if v < 0 {
+		// Skip negative values in the same way as histogram_bucket does.
+		return
+	}
+	if v == 0 {
+		sv.zeroCount++
+		return
+	}
+	idx := getExponentialBucketIdx(v, scale)
+	sv.buckets[idx]++
+}
+
+// updateSource registers the sample s for classic histogram bucket identified by si.
+func (sv *exponentialHistogramStateValue) updateSource(si *exponentialHistogramSeriesInfo, s *pushSample, keepFirstSample bool, deleteDeadline uint64) {
+	if sv.sources == nil {
+		sv.sources = make(map[string]*exponentialHistogramSource)
+	}
+	src := sv.sources[si.sourceKey]
+	if src == nil {
+		src = &exponentialHistogramSource{
+			buckets: make(map[float64]*exponentialHistogramSourceBucket),
+		}
+		sv.sources[si.sourceKey] = src
+	}
+	src.deleteDeadline = deleteDeadline
+
+	b := src.buckets[si.le]
+	if b == nil {
+		b = &exponentialHistogramSourceBucket{}
+		src.buckets[si.le] = b
+		if keepFirstSample {
+			b.increase = s.value
+		}
+	} else {
+		if s.timestamp < b.lastTimestamp {
+			// Skip out of order sample
+			return
+		}
+		if s.value >= b.lastValue {
+			b.increase += s.value - b.lastValue
+		} else {
+			// counter reset
+			b.increase += s.value
+		}
+	}
+	b.lastValue = s.value
+	b.lastTimestamp = s.timestamp
+	b.deleteDeadline = deleteDeadline
+}
+
+// mergeSources converts the collected increases for classic histogram buckets into exponential buckets.
+func (sv *exponentialHistogramStateValue) mergeSources(scale int) {
+	var les []float64
+	for _, src := range sv.sources {
+		les = les[:0]
+		for le := range src.buckets {
+			les = append(les, le)
+		}
+		sort.Float64s(les)
+
+		// Classic histogram buckets are cumulative, so the number of hits for every bucket
+		// is the difference between its increase and the increase of the previous bucket.
+		prevLe := 0.0
+		prevIncrease := 0.0
+		for _, le := range les {
+			b := src.buckets[le]
+			count := b.increase - prevIncrease
+			if count > 0 {
+				sv.addRange(prevLe, le, count, scale)
+			}
+			if b.increase > prevIncrease {
+				prevIncrease = b.increase
+			}
+			b.increase = 0
+			if !math.IsInf(le, 1) {
+				prevLe = le
+			}
+		}
+	}
+}
+
+// addRange spreads count hits evenly among exponential buckets on the (lower ... upper] range.
+func (sv *exponentialHistogramStateValue) addRange(lower, upper, count float64, scale int) {
+	if upper <= 0 {
+		sv.zeroCount += count
+		return
+	}
+	if math.IsInf(upper, 1) {
+		// The +Inf bucket - put all the hits into the overflow bucket, which is located above the lower bound of the range.
+		// The hits cannot be put into the bucket containing the lower bound, since they are bigger than the lower bound,
+		// while the lower bound may match the upper bound of this bucket.
+		if lower <= 0 {
+			sv.zeroCount += count
+			return
+		}
+		idx := getExponentialBucketIdx(lower, scale)
+		if idx < math.MaxInt32 {
+			idx++
+		}
+		sv.buckets[idx] += count
+		return
+	}
+	if lower <= 0 || lower >= upper {
+		// The lower bound for the first classic bucket is unknown.
+		// Assume its hits are spread over the upper half of the bucket.
+		lower = upper / 2
+	}
+
+	width := upper - lower
+	remaining := count
+	idx := getExponentialBucketIdx(upper, scale)
+	for i := 0; i < exponentialHistogramMaxSpreadBuckets; i++ {
+		bucketLower, bucketUpper := getExponentialBucketBounds(idx, scale)
+		end := math.Min(bucketUpper, upper)
+		start := math.Max(bucketLower, lower)
+		if start >= end {
+			break
+		}
+		n := count * (end - start) / width
+		if bucketLower <= lower || n >= remaining {
+			n = remaining
+		}
+		sv.buckets[idx] += n
+		remaining -= n
+		if remaining <= 0 {
+			return
+		}
+		idx--
+	}
+	// Put the remaining hits into the lowest visited bucket.
+	sv.buckets[idx+1] += remaining
+}
+
+func (as *exponentialHistogramAggrState) removeOldEntries(currentTime uint64) {
+	m := &as.m
+	m.Range(func(k, v any) bool {
+		sv := v.(*exponentialHistogramStateValue)
+
+		sv.mu.Lock()
+		if currentTime > sv.deleteDeadline {
+			// Mark the current entry as deleted
+			sv.deleted = true
+			sv.mu.Unlock()
+			m.Delete(k)
+			return true
+		}
+
+		// Delete outdated classic histograms and their buckets
+		for sourceKey, src := range sv.sources {
+			if currentTime > src.deleteDeadline {
+				delete(sv.sources, sourceKey)
+				continue
+			}
+			for le, b := range src.buckets {
+				if currentTime > b.deleteDeadline {
+					delete(src.buckets, le)
+				}
+			}
+		}
+		sv.mu.Unlock()
+		return true
+	})
+
+	keys := &as.keys
+	keys.Range(func(k, v any) bool {
+		si := v.(*exponentialHistogramSeriesInfo)
+		if currentTime > si.deleteDeadline.Load() {
+			keys.Delete(k)
+		}
+		return true
+	})
+}
+
+func (as *exponentialHistogramAggrState) flushState(ctx *flushCtx) {
+	currentTime := fasttime.UnixTimestamp()
+
+	as.removeOldEntries(currentTime)
+
+	m := &as.m
+	var idxs []int
+	m.Range(func(k, v any) bool {
+		sv := v.(*exponentialHistogramStateValue)
+		sv.mu.Lock()
+		if !sv.deleted {
+			key := k.(string)
+			sv.mergeSources(as.scale)
+			if sv.zeroCount > 0 {
+				ctx.appendSeriesWithExtraLabel(key, "exponential_histogram_bucket", sv.zeroCount, "vmrange", exponentialHistogramZeroRange)
+			}
+			idxs = idxs[:0]
+			for idx := range sv.buckets {
+				idxs = append(idxs, idx)
+			}
+			sort.Ints(idxs)
+			for _, idx := range idxs {
+				vmrange := as.getVMRange(idx)
+				ctx.appendSeriesWithExtraLabel(key, "exponential_histogram_bucket", sv.buckets[idx], "vmrange", vmrange)
+			}
+		}
+		sv.mu.Unlock()
+		return true
+	})
+}
+
+func (as *exponentialHistogramAggrState) getVMRange(idx int) string {
+	v, ok := as.vmranges.Load(idx)
+	if ok {
+		return v.(string)
+	}
+	lower, upper := getExponentialBucketBounds(idx, as.scale)
+	vmrange := fmt.Sprintf("%.3e...%.3e", lower, upper)
+	as.vmranges.Store(idx, vmrange)
+	return vmrange
+}
+
+var exponentialHistogramZeroRange = fmt.Sprintf("%.3e...%.3e", 0.0, 0.0)
+
+// getExponentialBucketIdx returns the index of exponential bucket for positive v at the given scale.
+//
+// The bucket with the index idx covers (base^idx ... base^(idx+1)] range, where base=2^(2^-scale).
+func getExponentialBucketIdx(v float64, scale int) int {
+	idx := math.Ceil(math.Log2(v)*math.Ldexp(1, scale)) - 1
+	if idx < math.MinInt32 {
+		return math.MinInt32
+	}
+	if idx > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(idx)
+}
+
+// getExponentialBucketBounds returns lower and upper bounds for the exponential bucket with the given idx at the given scale.
+func getExponentialBucketBounds(idx, scale int) (float64, float64) {
+	ratio := math.Ldexp(1, -scale)
+	lower := math.Exp2(float64(idx) * ratio)
+	upper := math.Exp2(float64(idx+1) * ratio)
+	return lower, upper
+}
@@ -0,0 +1,80 @@
+package streamaggr
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	// hllPrecision is the number of bits used for selecting HyperLogLog register.
+	//
+	// It results in 4096 registers with ~1.6% standard error for the estimated cardinality.
+	hllPrecision = 12
+
+	hllRegistersCount = 1 << hllPrecision
+
+	// hllSparseMaxItems is the maximum number of unique hashes to keep in the exact sparse representation
+	// before switching to HyperLogLog registers.
+	hllSparseMaxItems = 256
+)
+
+// hyperLogLog estimates the number of unique 64-bit hashes.
+//
+// It keeps exact set of hashes for small cardinalities in order to save memory and to return exact results.
+type hyperLogLog struct {
+	sparse    map[uint64]struct{}
+	registers []uint8
+}
+
+// add registers the hash h in hll.
+func (hll *hyperLogLog) add(h uint64) {
+	if hll.registers == nil {
+		if hll.sparse == nil {
+			hll.sparse = make(map[uint64]struct{})
+		}
+		hll.sparse[h] = struct{}{}
+		if len(hll.sparse) <= hllSparseMaxItems {
+			return
+		}
+		// Switch to HyperLogLog registers.
+		hll.registers = make([]uint8, hllRegistersCount)
+		for h := range hll.sparse {
+			hll.addToRegisters(h)
+		}
+		hll.sparse = nil
+		return
+	}
+	hll.addToRegisters(h)
+}
+
+func (hll *hyperLogLog) addToRegisters(h uint64) {
+	idx := h >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(h<<hllPrecision|1<<(hllPrecision-1))) + 1
+	if rank > hll.registers[idx] {
+		hll.registers[idx] = rank
+	}
+}
+
+// count returns the estimated number of unique hashes registered in hll.
+func (hll *hyperLogLog) count() uint64 {
+	if hll.registers == nil {
+		return uint64(len(hll.sparse))
+	}
+
+	sum := 0.0
+	zeros := 0
+	for _, r := range hll.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	m := float64(hllRegistersCount)
+	alpha := 0.7213 / (1 + 1.079/m)
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Use linear counting for small cardinalities.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
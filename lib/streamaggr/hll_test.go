@@ -0,0 +1,43 @@
+package streamaggr
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	f := func(n int, maxRelativeError float64) {
+		t.Helper()
+
+		var hll hyperLogLog
+		var buf []byte
+		for i := 0; i < n; i++ {
+			buf = encoding.MarshalUint64(buf[:0], uint64(i))
+			hll.add(xxhash.Sum64(buf))
+			// Duplicate values mustn't change the result.
+			hll.add(xxhash.Sum64(buf))
+		}
+		count := hll.count()
+		relativeError := math.Abs(float64(count)-float64(n)) / math.Max(float64(n), 1)
+		if relativeError > maxRelativeError {
+			t.Fatalf("too big relative error for n=%d; got %.4f; want no more than %.4f; estimated count: %d", n, relativeError, maxRelativeError, count)
+		}
+	}
+
+	// Exact results for small cardinalities
+	f(0, 0)
+	f(1, 0)
+	f(100, 0)
+	f(hllSparseMaxItems, 0)
+
+	// Estimated results for big cardinalities
+	f(hllSparseMaxItems+1, 0.05)
+	f(1_000, 0.05)
+	f(10_000, 0.05)
+	f(100_000, 0.05)
+	f(1_000_000, 0.05)
+}
@@ -1,10 +1,12 @@
 package streamaggr
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 )
 
@@ -181,3 +183,95 @@ func (as *rateAggrState) removeOldEntries(currentTime uint64) {
 		return true
 	})
 }
+
+func (as *rateAggrState) marshalState(dst []byte) []byte {
+	var entries []byte
+	entriesCount := 0
+	as.m.Range(func(k, v any) bool {
+		sv := v.(*rateStateValue)
+
+		sv.mu.Lock()
+		if !sv.deleted {
+			entries = marshalLabelsKey(entries, k.(string))
+			entries = encoding.MarshalUint64(entries, sv.deleteDeadline)
+			entries = encoding.MarshalVarUint64(entries, uint64(len(sv.lastValues)))
+			for inputKey, lv := range sv.lastValues {
+				entries = marshalLabelsKey(entries, inputKey)
+				entries = marshalFloat64(entries, lv.value)
+				entries = encoding.MarshalInt64(entries, lv.timestamp)
+				entries = encoding.MarshalUint64(entries, lv.deleteDeadline)
+				entries = marshalFloat64(entries, lv.increase)
+				entries = encoding.MarshalInt64(entries, lv.prevTimestamp)
+			}
+			entriesCount++
+		}
+		sv.mu.Unlock()
+		return true
+	})
+	dst = encoding.MarshalVarUint64(dst, uint64(entriesCount))
+	return append(dst, entries...)
+}
+
+func (as *rateAggrState) unmarshalState(src []byte, isCurrentInterval bool) error {
+	src, entriesCount, err := unmarshalVarUint64(src)
+	if err != nil {
+		return fmt.Errorf("cannot read the number of entries: %w", err)
+	}
+	for i := uint64(0); i < entriesCount; i++ {
+		var outputKey string
+		src, outputKey, err = unmarshalInternedLabelsKey(src)
+		if err != nil {
+			return fmt.Errorf("cannot read output key: %w", err)
+		}
+		sv := &rateStateValue{}
+		src, sv.deleteDeadline, err = unmarshalUint64(src)
+		if err != nil {
+			return fmt.Errorf("cannot read deleteDeadline: %w", err)
+		}
+		var lastValuesCount uint64
+		src, lastValuesCount, err = unmarshalVarUint64(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the number of last values: %w", err)
+		}
+		sv.lastValues = make(map[string]rateLastValueState, lastValuesCount)
+		for j := uint64(0); j < lastValuesCount; j++ {
+			var inputKey string
+			src, inputKey, err = unmarshalInternedLabelsKey(src)
+			if err != nil {
+				return fmt.Errorf("cannot read input key: %w", err)
+			}
+			var lv rateLastValueState
+			src, lv.value, err = unmarshalFloat64(src)
+			if err != nil {
+				return fmt.Errorf("cannot read last value: %w", err)
+			}
+			src, lv.timestamp, err = unmarshalInt64(src)
+			if err != nil {
+				return fmt.Errorf("cannot read last timestamp: %w", err)
+			}
+			src, lv.deleteDeadline, err = unmarshalUint64(src)
+			if err != nil {
+				return fmt.Errorf("cannot read deleteDeadline for last value: %w", err)
+			}
+			src, lv.increase, err = unmarshalFloat64(src)
+			if err != nil {
+				return fmt.Errorf("cannot read increase: %w", err)
+			}
+			src, lv.prevTimestamp, err = unmarshalInt64(src)
+			if err != nil {
+				return fmt.Errorf("cannot read previous timestamp: %w", err)
+			}
+			if !isCurrentInterval {
+				// The increase belongs to the previous aggregation interval.
+				lv.increase = 0
+				lv.prevTimestamp = lv.timestamp
+			}
+			sv.lastValues[inputKey] = lv
+		}
+		as.m.Store(outputKey, sv)
+	}
+	if len(src) > 0 {
+		return fmt.Errorf("unexpected non-empty tail left; len(tail)=%d", len(src))
+	}
+	return nil
+}
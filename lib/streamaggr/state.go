@@ -0,0 +1,350 @@
+package streamaggr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+// stateFormatVersion is the version of the file format for persisted aggregation state.
+//
+// It must be incremented on every incompatible change of the format.
+const stateFormatVersion = 1
+
+// aggrStatePersister must be implemented by aggrState, which supports persisting its state across restarts.
+type aggrStatePersister interface {
+	// marshalState appends the marshaled state to dst and returns the result.
+	marshalState(dst []byte) []byte
+
+	// unmarshalState restores the state from src.
+	//
+	// isCurrentInterval is set to true if the state has been saved during the current aggregation interval.
+	unmarshalState(src []byte, isCurrentInterval bool) error
+}
+
+// stateSnapshot contains aggregation state loaded from a file.
+type stateSnapshot struct {
+	// timestamp is the unix timestamp in seconds when the snapshot has been created.
+	timestamp uint64
+
+	// aggregators contains the state of aggregators by their config hash.
+	aggregators map[uint64][]*aggregatorSnapshot
+}
+
+type aggregatorSnapshot struct {
+	configHash   uint64
+	dedupState   []byte
+	outputStates map[string][]byte
+}
+
+// getAggregatorSnapshot returns and removes from ss the snapshot for the aggregator with the given configHash.
+//
+// It returns nil if there is no snapshot for the given configHash.
+func (ss *stateSnapshot) getAggregatorSnapshot(configHash uint64) *aggregatorSnapshot {
+	if ss == nil {
+		return nil
+	}
+	snapshots := ss.aggregators[configHash]
+	if len(snapshots) == 0 {
+		return nil
+	}
+	ags := snapshots[0]
+	ss.aggregators[configHash] = snapshots[1:]
+	return ags
+}
+
+// isCurrentInterval returns true if ss has been created during the current interval.
+func (ss *stateSnapshot) isCurrentInterval(interval time.Duration) bool {
+	intervalSecs := roundDurationToSecs(interval)
+	if intervalSecs == 0 {
+		return false
+	}
+	currentTime := uint64(time.Now().Unix())
+	return ss.timestamp/intervalSecs == currentTime/intervalSecs
+}
+
+// getConfigHash returns hash for the given cfg and opts.
+//
+// The hash is used for verifying whether the persisted state can be restored for the aggregator.
+func getConfigHash(cfg *Config, opts *Options) uint64 {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal the provided config: %s", err)
+	}
+	data = fmt.Appendf(data, "%s|%v|%v|%v", opts.DedupInterval, opts.DropInputLabels, opts.KeepMetricNames, opts.IgnoreOldSamples)
+	return xxhash.Sum64(data)
+}
+
+// mustLoadStateSnapshot loads aggregation state from the file at path.
+//
+// The file is removed after loading, so the state is restored only once.
+// nil is returned if the file doesn't exist or cannot be loaded.
+func mustLoadStateSnapshot(path string) *stateSnapshot {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Errorf("cannot read stream aggregation state from %q: %s; starting with empty state", path, err)
+		}
+		return nil
+	}
+	fs.MustRemoveAll(path)
+
+	ss, err := unmarshalStateSnapshot(data)
+	if err != nil {
+		logger.Errorf("cannot load stream aggregation state from %q: %s; starting with empty state", path, err)
+		return nil
+	}
+	return ss
+}
+
+func unmarshalStateSnapshot(data []byte) (*stateSnapshot, error) {
+	src, err := encoding.DecompressZSTD(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress state: %w", err)
+	}
+
+	src, version, err := unmarshalUint64(src)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read format version: %w", err)
+	}
+	if version != stateFormatVersion {
+		return nil, fmt.Errorf("unsupported format version %d; want %d", version, stateFormatVersion)
+	}
+	src, timestamp, err := unmarshalUint64(src)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snapshot timestamp: %w", err)
+	}
+	src, aggregatorsCount, err := unmarshalVarUint64(src)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the number of aggregators: %w", err)
+	}
+
+	ss := &stateSnapshot{
+		timestamp:   timestamp,
+		aggregators: make(map[uint64][]*aggregatorSnapshot),
+	}
+	for i := uint64(0); i < aggregatorsCount; i++ {
+		var ags aggregatorSnapshot
+		src, ags.configHash, err = unmarshalUint64(src)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read config hash for aggregator #%d: %w", i, err)
+		}
+		src, ags.dedupState, err = unmarshalBytes(src)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read dedup state for aggregator #%d: %w", i, err)
+		}
+		var outputsCount uint64
+		src, outputsCount, err = unmarshalVarUint64(src)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read the number of outputs for aggregator #%d: %w", i, err)
+		}
+		ags.outputStates = make(map[string][]byte, outputsCount)
+		for j := uint64(0); j < outputsCount; j++ {
+			var output, state []byte
+			src, output, err = unmarshalBytes(src)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read output name #%d for aggregator #%d: %w", j, i, err)
+			}
+			src, state, err = unmarshalBytes(src)
+			if err != nil {
+				return nil, fmt.Errorf("cannot read state for output %q at aggregator #%d: %w", output, i, err)
+			}
+			ags.outputStates[string(output)] = state
+		}
+		ss.aggregators[ags.configHash] = append(ss.aggregators[ags.configHash], &ags)
+	}
+	if len(src) > 0 {
+		return nil, fmt.Errorf("unexpected non-empty tail left after reading the state; len(tail)=%d", len(src))
+	}
+	return ss, nil
+}
+
+// mustSaveState saves the state of a to the file at a.stateFilePath.
+func (a *Aggregators) mustSaveState() {
+	path := a.stateFilePath
+	if path == "" {
+		return
+	}
+
+	startTime := time.Now()
+	var dst []byte
+	dst = encoding.MarshalUint64(dst, stateFormatVersion)
+	dst = encoding.MarshalUint64(dst, uint64(startTime.Unix()))
+	dst = encoding.MarshalVarUint64(dst, uint64(len(a.as)))
+	for _, aggr := range a.as {
+		dst = aggr.marshalState(dst)
+	}
+	data := encoding.CompressZSTDLevel(nil, dst, 1)
+
+	fs.MustMkdirIfNotExist(filepath.Dir(path))
+	fs.MustWriteAtomic(path, data, true)
+	logger.Infof("saved stream aggregation state to %q in %.3f seconds; size: %d bytes", path, time.Since(startTime).Seconds(), len(data))
+}
+
+// marshalState appends the state of a to dst and returns the result.
+func (a *aggregator) marshalState(dst []byte) []byte {
+	dst = encoding.MarshalUint64(dst, a.configHash)
+
+	var dedupState []byte
+	if a.da != nil {
+		dedupState = a.da.marshalState(nil)
+	}
+	dst = encoding.MarshalBytes(dst, dedupState)
+
+	var outputStates []byte
+	outputsCount := 0
+	for i := range a.aggrOutputs {
+		ao := &a.aggrOutputs[i]
+		sp, ok := ao.as.(aggrStatePersister)
+		if !ok {
+			continue
+		}
+		outputStates = encoding.MarshalBytes(outputStates, bytesutil.ToUnsafeBytes(ao.output))
+		state := sp.marshalState(nil)
+		outputStates = encoding.MarshalBytes(outputStates, state)
+		outputsCount++
+	}
+	dst = encoding.MarshalVarUint64(dst, uint64(outputsCount))
+	dst = append(dst, outputStates...)
+	return dst
+}
+
+// restoreState restores the state for a from ss.
+//
+// It returns true if the restored state belongs to the current aggregation interval.
+func (a *aggregator) restoreState(ss *stateSnapshot) bool {
+	ags := ss.getAggregatorSnapshot(a.configHash)
+	if ags == nil {
+		return false
+	}
+
+	if a.da != nil && len(ags.dedupState) > 0 && ss.isCurrentInterval(a.dedupInterval) {
+		if err := a.da.unmarshalState(ags.dedupState); err != nil {
+			logger.Errorf("cannot restore deduplication state for stream aggregation: %s; starting with empty state", err)
+		}
+	}
+
+	isCurrentInterval := ss.isCurrentInterval(a.interval)
+	for i := range a.aggrOutputs {
+		ao := &a.aggrOutputs[i]
+		sp, ok := ao.as.(aggrStatePersister)
+		if !ok {
+			continue
+		}
+		state, ok := ags.outputStates[ao.output]
+		if !ok {
+			continue
+		}
+		if err := sp.unmarshalState(state, isCurrentInterval); err != nil {
+			logger.Errorf("cannot restore state for stream aggregation output %q: %s; starting with empty state", ao.output, err)
+		}
+	}
+	return isCurrentInterval
+}
+
+// marshalLabelsKey appends labels for the given compressed key to dst and returns the result.
+func marshalLabelsKey(dst []byte, key string) []byte {
+	auxLabels := promutils.GetLabels()
+	auxLabels.Labels = decompressLabels(auxLabels.Labels[:0], key)
+	dst = encoding.MarshalVarUint64(dst, uint64(len(auxLabels.Labels)))
+	for _, label := range auxLabels.Labels {
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(label.Name))
+		dst = encoding.MarshalBytes(dst, bytesutil.ToUnsafeBytes(label.Value))
+	}
+	promutils.PutLabels(auxLabels)
+	return dst
+}
+
+// unmarshalLabelsKey reads labels from src and appends the compressed key for them to dst.
+func unmarshalLabelsKey(dst, src []byte) ([]byte, []byte, error) {
+	src, labelsCount, err := unmarshalVarUint64(src)
+	if err != nil {
+		return dst, src, fmt.Errorf("cannot read the number of labels: %w", err)
+	}
+	auxLabels := promutils.GetLabels()
+	defer promutils.PutLabels(auxLabels)
+	for i := uint64(0); i < labelsCount; i++ {
+		var name, value []byte
+		src, name, err = unmarshalBytes(src)
+		if err != nil {
+			return dst, src, fmt.Errorf("cannot read label name: %w", err)
+		}
+		src, value, err = unmarshalBytes(src)
+		if err != nil {
+			return dst, src, fmt.Errorf("cannot read value for label %q: %w", name, err)
+		}
+		auxLabels.Labels = append(auxLabels.Labels, prompbmarshal.Label{
+			Name:  bytesutil.InternBytes(name),
+			Value: bytesutil.InternBytes(value),
+		})
+	}
+	dst = lc.Compress(dst, auxLabels.Labels)
+	return dst, src, nil
+}
+
+// unmarshalInternedLabelsKey reads labels from src and returns interned compressed key for them.
+func unmarshalInternedLabelsKey(src []byte) ([]byte, string, error) {
+	bb := bbPool.Get()
+	defer bbPool.Put(bb)
+
+	var err error
+	bb.B, src, err = unmarshalLabelsKey(bb.B[:0], src)
+	if err != nil {
+		return src, "", err
+	}
+	return src, bytesutil.InternBytes(bb.B), nil
+}
+
+func marshalFloat64(dst []byte, v float64) []byte {
+	return encoding.MarshalUint64(dst, math.Float64bits(v))
+}
+
+func unmarshalFloat64(src []byte) ([]byte, float64, error) {
+	src, n, err := unmarshalUint64(src)
+	return src, math.Float64frombits(n), err
+}
+
+func unmarshalUint64(src []byte) ([]byte, uint64, error) {
+	if len(src) < 8 {
+		return src, 0, fmt.Errorf("cannot read uint64 from %d bytes; need at least 8 bytes", len(src))
+	}
+	return src[8:], encoding.UnmarshalUint64(src), nil
+}
+
+func unmarshalInt64(src []byte) ([]byte, int64, error) {
+	if len(src) < 8 {
+		return src, 0, fmt.Errorf("cannot read int64 from %d bytes; need at least 8 bytes", len(src))
+	}
+	return src[8:], encoding.UnmarshalInt64(src), nil
+}
+
+func unmarshalVarUint64(src []byte) ([]byte, uint64, error) {
+	n, nSize := encoding.UnmarshalVarUint64(src)
+	if nSize <= 0 {
+		return src, 0, fmt.Errorf("cannot read varuint64")
+	}
+	return src[nSize:], n, nil
+}
+
+func unmarshalBytes(src []byte) ([]byte, []byte, error) {
+	b, nSize := encoding.UnmarshalBytes(src)
+	if nSize <= 0 {
+		return src, nil, fmt.Errorf("cannot read bytes")
+	}
+	return src[nSize:], b, nil
+}
@@ -0,0 +1,140 @@
+package streamaggr
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestAggregatorsStatePersistence(t *testing.T) {
+	stateFilePath := filepath.Join(t.TempDir(), "state.bin")
+	offsetMsecs := time.Now().UnixMilli()
+
+	f := func(config, inputMetrics, outputMetricsExpected string) {
+		t.Helper()
+
+		var tssOutput []prompbmarshal.TimeSeries
+		var tssOutputLock sync.Mutex
+		pushFunc := func(tss []prompbmarshal.TimeSeries) {
+			tssOutputLock.Lock()
+			tssOutput = appendClonedTimeseries(tssOutput, tss)
+			tssOutputLock.Unlock()
+		}
+		opts := &Options{
+			FlushOnShutdown:        true,
+			NoAlignFlushToInterval: true,
+			StateFilePath:          stateFilePath,
+		}
+		a, err := LoadFromData([]byte(config), pushFunc, opts, "some_alias")
+		if err != nil {
+			t.Fatalf("cannot initialize aggregators: %s", err)
+		}
+		if fs.IsPathExist(stateFilePath) {
+			t.Fatalf("state file %q must be removed after loading the state", stateFilePath)
+		}
+
+		tssInput := prompbmarshal.MustParsePromMetrics(inputMetrics, offsetMsecs)
+		_ = a.Push(tssInput, nil)
+		a.MustStopAndSaveState()
+
+		if !fs.IsPathExist(stateFilePath) {
+			t.Fatalf("missing state file %q after stopping the aggregators", stateFilePath)
+		}
+
+		outputMetrics := timeSeriessToString(tssOutput)
+		if outputMetrics != outputMetricsExpected {
+			t.Fatalf("unexpected output metrics;\ngot\n%s\nwant\n%s", outputMetrics, outputMetricsExpected)
+		}
+	}
+
+	config := `
+- interval: 1m
+  outputs: [total, rate_sum]
+`
+
+	// The first sample is ignored, since the state is empty
+	f(config, `
+foo{bar="baz"} 1 10
+foo{bar="baz"} 3 20
+`, `foo:1m_rate_sum{bar="baz"} 0.2
+foo:1m_total{bar="baz"} 2
+`)
+
+	// The state must be restored, so the total continues from the previous value
+	f(config, `
+foo{bar="baz"} 5 30
+foo{bar="baz"} 6 40
+`, `foo:1m_rate_sum{bar="baz"} 0.15
+foo:1m_total{bar="baz"} 5
+`)
+
+	// The state mustn't be restored for the changed config
+	f(`
+- interval: 2m
+  outputs: [total, rate_sum]
+`, `
+foo{bar="baz"} 7 50
+foo{bar="baz"} 8 60
+`, `foo:2m_rate_sum{bar="baz"} 0.1
+foo:2m_total{bar="baz"} 1
+`)
+}
+
+func TestAggregatorsMustStopDoesNotSaveState(t *testing.T) {
+	stateFilePath := filepath.Join(t.TempDir(), "state.bin")
+	pushFunc := func(_ []prompbmarshal.TimeSeries) {}
+	opts := &Options{
+		StateFilePath: stateFilePath,
+	}
+	config := `
+- interval: 1m
+  outputs: [total]
+`
+	a, err := LoadFromData([]byte(config), pushFunc, opts, "some_alias")
+	if err != nil {
+		t.Fatalf("cannot initialize aggregators: %s", err)
+	}
+	tssInput := prompbmarshal.MustParsePromMetrics(`foo 1`, time.Now().UnixMilli())
+	_ = a.Push(tssInput, nil)
+
+	// MustStop is used for stopping temporary aggregators on config reload, so it mustn't touch the state file.
+	a.MustStop()
+	if fs.IsPathExist(stateFilePath) {
+		t.Fatalf("unexpected state file %q after MustStop", stateFilePath)
+	}
+}
+
+func TestDedupAggrStatePersistence(t *testing.T) {
+	da := newDedupAggr()
+	key := string(compressLabels(nil, nil, []prompbmarshal.Label{{Name: "__name__", Value: "foo"}}))
+	da.pushSamples([]pushSample{
+		{key: key, value: 1, timestamp: 10},
+		{key: key, value: 2, timestamp: 20},
+	})
+	data := da.marshalState(nil)
+
+	daRestored := newDedupAggr()
+	if err := daRestored.unmarshalState(data); err != nil {
+		t.Fatalf("cannot unmarshal dedup state: %s", err)
+	}
+	var samples []pushSample
+	daRestored.flush(func(ss []pushSample) {
+		for _, s := range ss {
+			samples = append(samples, pushSample{
+				key:       string(s.key),
+				value:     s.value,
+				timestamp: s.timestamp,
+			})
+		}
+	})
+	if len(samples) != 1 {
+		t.Fatalf("unexpected number of samples; got %d; want 1", len(samples))
+	}
+	if samples[0].key != key || samples[0].value != 2 || samples[0].timestamp != 20 {
+		t.Fatalf("unexpected sample; got %+v; want key=%q, value=2, timestamp=20", samples[0], key)
+	}
+}
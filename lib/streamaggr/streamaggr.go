@@ -28,8 +28,11 @@ import (
 
 var supportedOutputs = []string{
 	"avg",
+	"bottomk(N)",
+	"count_distinct_labels(label1, ..., labelN)",
 	"count_samples",
 	"count_series",
+	"exponential_histogram_bucket(scale)",
 	"histogram_bucket",
 	"increase",
 	"increase_prometheus",
@@ -42,6 +45,7 @@ var supportedOutputs = []string{
 	"stddev",
 	"stdvar",
 	"sum_samples",
+	"topk(N)",
 	"total",
 	"total_prometheus",
 	"unique_samples",
@@ -140,6 +144,14 @@ type Options struct {
 	//
 	// By default, aggregates samples are dropped, while the remaining samples are written to the corresponding -remoteWrite.url.
 	KeepInput bool
+
+	// StateFilePath is an optional path to file for persisting aggregation state across restarts.
+	//
+	// The state is saved to the file when the Aggregators are stopped via MustStopAndSaveState and it is restored on the next start
+	// for aggregations with unchanged configs. The file is removed after the state is restored.
+	//
+	// By default, the aggregation state isn't persisted.
+	StateFilePath string
 }
 
 // Config is a configuration for a single stream aggregation.
@@ -157,6 +169,12 @@ type Config struct {
 	// Interval is the interval between aggregations.
 	Interval string `yaml:"interval"`
 
+	// Window is an optional sliding window for the outputs.
+	//
+	// If set, then outputs are calculated over the last Window and are emitted every Interval.
+	// Window must be a multiple of Interval. Only outputs from windowOutputs support Window.
+	Window string `yaml:"window,omitempty"`
+
 	// NoAlighFlushToInterval disables aligning of flushes to multiples of Interval.
 	// By default flushes are aligned to Interval.
 	//
@@ -179,8 +197,11 @@ type Config struct {
 	// The following names are allowed:
 	//
 	// - avg - the average value across all the samples
+	// - bottomk(N) - the sum of samples for N groups with the smallest sums; the rest of groups is collapsed into `__other__="true"` series
+	// - count_distinct_labels(label1, ..., labelN) - estimates the number of unique values for the given labels
 	// - count_samples - counts the input samples
 	// - count_series - counts the number of unique input series
+	// - exponential_histogram_bucket(scale) - creates exponential histogram for input samples or for input classic histogram buckets
 	// - histogram_bucket - creates VictoriaMetrics histogram for input samples
 	// - increase - calculates the increase over input series
 	// - increase_prometheus - calculates the increase over input series, ignoring the first sample in new time series
@@ -193,6 +214,7 @@ type Config struct {
 	// - stddev - standard deviation across all the samples
 	// - stdvar - standard variance across all the samples
 	// - sum_samples - sums the input sample values
+	// - topk(N) - the sum of samples for N groups with the biggest sums; the rest of groups is collapsed into `__other__="true"` series
 	// - total - aggregates input counters
 	// - total_prometheus - aggregates input counters, ignoring the first sample in new time series
 	// - unique_samples - counts the number of unique sample values
@@ -255,6 +277,9 @@ type Aggregators struct {
 	// filePath is the path to config file used for creating the Aggregators.
 	filePath string
 
+	// stateFilePath is the path to file for persisting aggregation state.
+	stateFilePath string
+
 	// ms contains metrics associated with the Aggregators.
 	ms *metrics.Set
 }
@@ -277,10 +302,15 @@ func loadFromData(data []byte, filePath string, pushFunc PushFunc, opts *Options
 		return nil, fmt.Errorf("cannot parse stream aggregation config: %w", err)
 	}
 
+	if opts == nil {
+		opts = &Options{}
+	}
+	ss := mustLoadStateSnapshot(opts.StateFilePath)
+
 	ms := metrics.NewSet()
 	as := make([]*aggregator, len(cfgs))
 	for i, cfg := range cfgs {
-		a, err := newAggregator(cfg, filePath, pushFunc, ms, opts, alias, i+1)
+		a, err := newAggregator(cfg, filePath, pushFunc, ms, opts, alias, i+1, ss)
 		if err != nil {
 			// Stop already initialized aggregators before returning the error.
 			for _, a := range as[:i] {
@@ -297,10 +327,11 @@ func loadFromData(data []byte, filePath string, pushFunc PushFunc, opts *Options
 
 	metrics.RegisterSet(ms)
 	return &Aggregators{
-		as:         as,
-		configData: configData,
-		filePath:   filePath,
-		ms:         ms,
+		as:            as,
+		configData:    configData,
+		filePath:      filePath,
+		stateFilePath: opts.StateFilePath,
+		ms:            ms,
 	}, nil
 }
 
@@ -316,7 +347,21 @@ func (a *Aggregators) IsEnabled() bool {
 }
 
 // MustStop stops a.
+//
+// The aggregation state isn't persisted by MustStop, since it is also called for stopping temporary Aggregators
+// during config reloads. Use MustStopAndSaveState for stopping a at process shutdown.
 func (a *Aggregators) MustStop() {
+	a.mustStop(false)
+}
+
+// MustStopAndSaveState stops a and saves its state to Options.StateFilePath passed to LoadFromFile or LoadFromData.
+//
+// It must be called only at process shutdown, so the saved state could be restored on the next start.
+func (a *Aggregators) MustStopAndSaveState() {
+	a.mustStop(true)
+}
+
+func (a *Aggregators) mustStop(saveState bool) {
 	if a == nil {
 		return
 	}
@@ -327,6 +372,9 @@ func (a *Aggregators) MustStop() {
 	for _, aggr := range a.as {
 		aggr.MustStop()
 	}
+	if saveState {
+		a.mustSaveState()
+	}
 	a.as = nil
 }
 
@@ -390,6 +438,11 @@ type aggregator struct {
 	// aggrOutputs contains aggregate states for the given outputs
 	aggrOutputs []aggrOutput
 
+	// configHash is the hash of the config used for creating the aggregator.
+	//
+	// It is used for verifying whether the persisted aggregation state can be restored.
+	configHash uint64
+
 	// minTimestamp is used for ignoring old samples when ignoreOldSamples is set
 	minTimestamp atomic.Int64
 
@@ -417,6 +470,9 @@ type aggregator struct {
 type aggrOutput struct {
 	as aggrState
 
+	// output is the output name from the config.
+	output string
+
 	outputSamples *metrics.Counter
 }
 
@@ -437,8 +493,10 @@ type PushFunc func(tss []prompbmarshal.TimeSeries)
 //
 // opts can contain additional options. If opts is nil, then default options are used.
 //
+// ss may contain the persisted aggregation state, which must be restored for the aggregator.
+//
 // The returned aggregator must be stopped when no longer needed by calling MustStop().
-func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set, opts *Options, alias string, aggrID int) (*aggregator, error) {
+func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set, opts *Options, alias string, aggrID int, ss *stateSnapshot) (*aggregator, error) {
 	// check cfg.Interval
 	if cfg.Interval == "" {
 		return nil, fmt.Errorf("missing `interval` option")
@@ -455,6 +513,22 @@ func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set,
 		opts = &Options{}
 	}
 
+	// check cfg.Window
+	windowSize := 1
+	if cfg.Window != "" {
+		window, err := time.ParseDuration(cfg.Window)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `window: %q`: %w", cfg.Window, err)
+		}
+		if window < interval {
+			return nil, fmt.Errorf("window=%s cannot be smaller than interval=%s", cfg.Window, cfg.Interval)
+		}
+		if window%interval != 0 {
+			return nil, fmt.Errorf("window=%s must be a multiple of interval=%s", cfg.Window, cfg.Interval)
+		}
+		windowSize = int(window / interval)
+	}
+
 	// check cfg.DedupInterval
 	dedupInterval := opts.DedupInterval
 	if cfg.DedupInterval != "" {
@@ -473,6 +547,10 @@ func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set,
 
 	// check cfg.StalenessInterval
 	stalenessInterval := interval * 2
+	if windowSize > 1 {
+		// Keep the state for the series during the whole window.
+		stalenessInterval = interval * time.Duration(windowSize+1)
+	}
 	if cfg.StalenessInterval != "" {
 		stalenessInterval, err = time.ParseDuration(cfg.StalenessInterval)
 		if err != nil {
@@ -524,7 +602,8 @@ func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set,
 			return nil, fmt.Errorf("`outputs` list must contain only a single entry if `keep_metric_names` is set; got %q; "+
 				"see https://docs.victoriametrics.com/stream-aggregation/#output-metric-names", cfg.Outputs)
 		}
-		if cfg.Outputs[0] == "histogram_bucket" || strings.HasPrefix(cfg.Outputs[0], "quantiles(") && strings.Contains(cfg.Outputs[0], ",") {
+		if cfg.Outputs[0] == "histogram_bucket" || strings.HasPrefix(cfg.Outputs[0], "exponential_histogram_bucket") ||
+			(strings.HasPrefix(cfg.Outputs[0], "quantiles(") || strings.HasPrefix(cfg.Outputs[0], "count_distinct_labels(")) && strings.Contains(cfg.Outputs[0], ",") {
 			return nil, fmt.Errorf("`keep_metric_names` cannot be applied to `outputs: %q`, since they can generate multiple time series; "+
 				"see https://docs.victoriametrics.com/stream-aggregation/#output-metric-names", cfg.Outputs)
 		}
@@ -557,12 +636,13 @@ func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set,
 	aggrOutputs := make([]aggrOutput, len(cfg.Outputs))
 	outputsSeen := make(map[string]struct{}, len(cfg.Outputs))
 	for i, output := range cfg.Outputs {
-		as, err := newAggrState(output, outputsSeen, stalenessInterval)
+		as, err := newAggrState(output, outputsSeen, stalenessInterval, windowSize)
 		if err != nil {
 			return nil, err
 		}
 		aggrOutputs[i] = aggrOutput{
-			as: as,
+			as:     as,
+			output: output,
 
 			outputSamples: ms.NewCounter(fmt.Sprintf(`vm_streamaggr_output_samples_total{output=%q,%s}`, output, metricLabels)),
 		}
@@ -570,6 +650,9 @@ func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set,
 
 	// initialize suffix to add to metric names after aggregation
 	suffix := ":" + cfg.Interval
+	if windowSize > 1 {
+		suffix = ":" + cfg.Window
+	}
 	if labels := removeUnderscoreName(by); len(labels) > 0 {
 		suffix += fmt.Sprintf("_by_%s", strings.Join(labels, "_"))
 	}
@@ -597,6 +680,7 @@ func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set,
 		dedupInterval: dedupInterval,
 
 		aggrOutputs: aggrOutputs,
+		configHash:  getConfigHash(cfg, opts),
 
 		suffix: suffix,
 
@@ -636,22 +720,33 @@ func newAggregator(cfg *Config, path string, pushFunc PushFunc, ms *metrics.Set,
 		skipIncompleteFlush = !*v
 	}
 
+	// The first incomplete interval mustn't be skipped if its state has been restored from the previous run.
+	skipFirstFlush := alignFlushToInterval && skipIncompleteFlush
+	if ss != nil && a.restoreState(ss) {
+		skipFirstFlush = false
+	}
+
 	a.wg.Add(1)
 	go func() {
-		a.runFlusher(pushFunc, alignFlushToInterval, skipIncompleteFlush, ignoreFirstIntervals)
+		a.runFlusher(pushFunc, alignFlushToInterval, skipIncompleteFlush, skipFirstFlush, ignoreFirstIntervals)
 		a.wg.Done()
 	}()
 
 	return a, nil
 }
 
-func newAggrState(output string, outputsSeen map[string]struct{}, stalenessInterval time.Duration) (aggrState, error) {
+func newAggrState(output string, outputsSeen map[string]struct{}, stalenessInterval time.Duration, windowSize int) (aggrState, error) {
 	// check for duplicated output
 	if _, ok := outputsSeen[output]; ok {
 		return nil, fmt.Errorf("`outputs` list contains duplicate aggregation function: %s", output)
 	}
 	outputsSeen[output] = struct{}{}
 
+	if windowSize > 1 && !isWindowOutput(output) {
+		return nil, fmt.Errorf("output=%q doesn't support `window` option; supported outputs: %s; "+
+			"see https://docs.victoriametrics.com/stream-aggregation/#sliding-windows", output, windowOutputs)
+	}
+
 	if strings.HasPrefix(output, "quantiles(") {
 		if !strings.HasSuffix(output, ")") {
 			return nil, fmt.Errorf("missing closing brace for `quantiles()` output")
@@ -677,15 +772,72 @@ func newAggrState(output string, outputsSeen map[string]struct{}, stalenessInter
 			return nil, fmt.Errorf("`outputs` list contains duplicated `quantiles()` function, please combine multiple phi* like `quantiles(0.5, 0.9)`")
 		}
 		outputsSeen["quantiles"] = struct{}{}
+		if windowSize > 1 {
+			return newWindowQuantilesAggrState(windowSize, phis), nil
+		}
 		return newQuantilesAggrState(phis), nil
 	}
 
+	if strings.HasPrefix(output, "topk(") || strings.HasPrefix(output, "bottomk(") {
+		funcName := output[:strings.IndexByte(output, '(')]
+		args, err := parseOutputArgs(output, funcName)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) != 1 {
+			return nil, fmt.Errorf("`%s()` must contain a single arg; got %d args", funcName, len(args))
+		}
+		k, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse N=%q for %s(%s): %w", args[0], funcName, args[0], err)
+		}
+		if k <= 0 {
+			return nil, fmt.Errorf("N inside %s(%s) must be positive", funcName, args[0])
+		}
+		if _, ok := outputsSeen[funcName]; ok {
+			return nil, fmt.Errorf("`outputs` list contains duplicated `%s()` function", funcName)
+		}
+		outputsSeen[funcName] = struct{}{}
+		return newTopkAggrState(k, funcName == "bottomk"), nil
+	}
+
+	if strings.HasPrefix(output, "count_distinct_labels(") {
+		args, err := parseOutputArgs(output, "count_distinct_labels")
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := outputsSeen["count_distinct_labels"]; ok {
+			return nil, fmt.Errorf("`outputs` list contains duplicated `count_distinct_labels()` function, " +
+				"please combine multiple labels like `count_distinct_labels(user, ip)`")
+		}
+		outputsSeen["count_distinct_labels"] = struct{}{}
+		return newCountDistinctLabelsAggrState(args), nil
+	}
+
+	if strings.HasPrefix(output, "exponential_histogram_bucket(") || output == "exponential_histogram_bucket" {
+		scale, err := parseExponentialHistogramScale(output)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := outputsSeen["exponential_histogram_bucket()"]; ok {
+			return nil, fmt.Errorf("`outputs` list contains duplicated `exponential_histogram_bucket()` function")
+		}
+		outputsSeen["exponential_histogram_bucket()"] = struct{}{}
+		return newExponentialHistogramAggrState(scale, stalenessInterval), nil
+	}
+
 	switch output {
 	case "avg":
 		return newAvgAggrState(), nil
 	case "count_samples":
+		if windowSize > 1 {
+			return newWindowSamplesAggrState(windowSize, true), nil
+		}
 		return newCountSamplesAggrState(), nil
 	case "count_series":
+		if windowSize > 1 {
+			return newWindowCountSeriesAggrState(windowSize), nil
+		}
 		return newCountSeriesAggrState(), nil
 	case "histogram_bucket":
 		return newHistogramBucketAggrState(stalenessInterval), nil
@@ -700,14 +852,23 @@ func newAggrState(output string, outputsSeen map[string]struct{}, stalenessInter
 	case "min":
 		return newMinAggrState(), nil
 	case "rate_avg":
+		if windowSize > 1 {
+			return newWindowRateAggrState(windowSize, stalenessInterval, true), nil
+		}
 		return newRateAggrState(stalenessInterval, true), nil
 	case "rate_sum":
+		if windowSize > 1 {
+			return newWindowRateAggrState(windowSize, stalenessInterval, false), nil
+		}
 		return newRateAggrState(stalenessInterval, false), nil
 	case "stddev":
 		return newStddevAggrState(), nil
 	case "stdvar":
 		return newStdvarAggrState(), nil
 	case "sum_samples":
+		if windowSize > 1 {
+			return newWindowSamplesAggrState(windowSize, false), nil
+		}
 		return newSumSamplesAggrState(), nil
 	case "total":
 		return newTotalAggrState(stalenessInterval, false, true), nil
@@ -720,7 +881,27 @@ func newAggrState(output string, outputsSeen map[string]struct{}, stalenessInter
 	}
 }
 
-func (a *aggregator) runFlusher(pushFunc PushFunc, alignFlushToInterval, skipIncompleteFlush bool, ignoreFirstIntervals int) {
+// parseOutputArgs parses comma-separated args for the output in the form `funcName(arg1, ..., argN)`.
+func parseOutputArgs(output, funcName string) ([]string, error) {
+	if !strings.HasSuffix(output, ")") {
+		return nil, fmt.Errorf("missing closing brace for `%s()` output", funcName)
+	}
+	argsStr := output[len(funcName)+1 : len(output)-1]
+	if len(strings.TrimSpace(argsStr)) == 0 {
+		return nil, fmt.Errorf("`%s()` must contain at least one arg", funcName)
+	}
+	args := strings.Split(argsStr, ",")
+	for i, arg := range args {
+		arg = strings.TrimSpace(arg)
+		if arg == "" {
+			return nil, fmt.Errorf("`%s(%s)` cannot contain empty args", funcName, argsStr)
+		}
+		args[i] = arg
+	}
+	return args, nil
+}
+
+func (a *aggregator) runFlusher(pushFunc PushFunc, alignFlushToInterval, skipIncompleteFlush, skipFirstFlush bool, ignoreFirstIntervals int) {
 	alignedSleep := func(d time.Duration) {
 		if !alignFlushToInterval {
 			return
@@ -753,7 +934,7 @@ func (a *aggregator) runFlusher(pushFunc PushFunc, alignFlushToInterval, skipInc
 		t := time.NewTicker(a.interval)
 		defer t.Stop()
 
-		if alignFlushToInterval && skipIncompleteFlush {
+		if skipFirstFlush {
 			a.flush(nil, 0)
 			ignoreFirstIntervals--
 		}
@@ -786,7 +967,7 @@ func (a *aggregator) runFlusher(pushFunc PushFunc, alignFlushToInterval, skipInc
 			ct := time.Now()
 			if ct.After(flushDeadline) {
 				// It is time to flush the aggregated state
-				if alignFlushToInterval && skipIncompleteFlush && !isSkippedFirstFlush {
+				if skipFirstFlush && !isSkippedFirstFlush {
 					a.flush(nil, 0)
 					ignoreFirstIntervals--
 					isSkippedFirstFlush = true
@@ -188,11 +188,98 @@ func TestAggregatorsFailure(t *testing.T) {
 - interval: 1m
   outputs: [total, total]
 `)
+	// Invalid exponential_histogram_bucket()
+	f(`
+- interval: 1m
+  outputs: ["exponential_histogram_bucket("]
+`)
+	f(`
+- interval: 1m
+  outputs: ["exponential_histogram_bucket(foo)"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["exponential_histogram_bucket(9)"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["exponential_histogram_bucket", "exponential_histogram_bucket(2)"]
+`)
+	// keep_metric_names cannot be used with exponential_histogram_bucket
+	f(`
+- interval: 1m
+  keep_metric_names: true
+  outputs: ["exponential_histogram_bucket"]
+`)
+	// Invalid topk() and bottomk()
+	f(`
+- interval: 1m
+  outputs: ["topk("]
+`)
+	f(`
+- interval: 1m
+  outputs: ["topk()"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["topk(foo)"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["bottomk(0)"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["bottomk(1, 2)"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["topk(1)", "topk(2)"]
+`)
+
+	// Invalid count_distinct_labels()
+	f(`
+- interval: 1m
+  outputs: ["count_distinct_labels()"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["count_distinct_labels(foo,)"]
+`)
+	f(`
+- interval: 1m
+  outputs: ["count_distinct_labels(foo)", "count_distinct_labels(bar)"]
+`)
+
 	// "quantiles(0.5)", "quantiles(0.9)" should be set as "quantiles(0.5, 0.9)"
 	f(`
 - interval: 1m
   outputs: ["quantiles(0.5)", "quantiles(0.9)"]
 `)
+
+	// Invalid window
+	f(`
+- interval: 1m
+  window: foo
+  outputs: [sum_samples]
+`)
+	f(`
+- interval: 1m
+  window: 30s
+  outputs: [sum_samples]
+`)
+	f(`
+- interval: 1m
+  window: 90s
+  outputs: [sum_samples]
+`)
+
+	// Output doesn't support window
+	f(`
+- interval: 1m
+  window: 5m
+  outputs: [total]
+`)
 }
 
 func TestAggregatorsEqual(t *testing.T) {
@@ -816,6 +903,120 @@ cpu_usage:1m_without_cpu_histogram_bucket{vmrange="2.448e+01...2.783e+01"} 1
 cpu_usage:1m_without_cpu_histogram_bucket{vmrange="8.799e+01...1.000e+02"} 1
 `, "1111111")
 
+	// topk output
+	f(`
+- interval: 1m
+  by: [path]
+  outputs: ["topk(2)"]
+`, `
+requests{path="/a",user="foo"} 1
+requests{path="/b",user="foo"} 5
+requests{path="/c",user="foo"} 3
+requests{path="/d",user="bar"} 2
+requests{path="/a",user="bar"} 1
+errors{path="/a"} 1
+`, `errors:1m_by_path_topk{path="/a"} 1
+requests:1m_by_path_topk{__other__="true"} 4
+requests:1m_by_path_topk{path="/b"} 5
+requests:1m_by_path_topk{path="/c"} 3
+`, "111111")
+
+	// topk output with a real group, which has `other` label value
+	f(`
+- interval: 1m
+  by: [path]
+  outputs: ["topk(1)"]
+`, `
+requests{path="other"} 5
+requests{path="/a"} 1
+requests{path="/b"} 2
+`, `requests:1m_by_path_topk{__other__="true"} 3
+requests:1m_by_path_topk{path="other"} 5
+`, "111")
+
+	// bottomk output
+	f(`
+- interval: 1m
+  by: [path]
+  outputs: ["bottomk(1)"]
+`, `
+requests{path="/a",user="foo"} 1
+requests{path="/b",user="foo"} 5
+requests{path="/c",user="foo"} 3
+requests{path="/a",user="bar"} 1
+`, `requests:1m_by_path_bottomk{__other__="true"} 8
+requests:1m_by_path_bottomk{path="/a"} 2
+`, "1111")
+
+	// count_distinct_labels output
+	f(`
+- interval: 1m
+  by: [path]
+  outputs: ["count_distinct_labels(user, ip)"]
+`, `
+requests{path="/a",user="foo",ip="1.1.1.1"} 1
+requests{path="/a",user="bar",ip="1.1.1.1"} 1
+requests{path="/a",user="foo",ip="1.1.1.2"} 1
+requests{path="/b",user="baz"} 1
+`, `requests:1m_by_path_count_distinct_labels{label="ip",path="/a"} 2
+requests:1m_by_path_count_distinct_labels{label="ip",path="/b"} 0
+requests:1m_by_path_count_distinct_labels{label="user",path="/a"} 2
+requests:1m_by_path_count_distinct_labels{label="user",path="/b"} 1
+`, "1111")
+
+	// exponential_histogram_bucket output
+	f(`
+- interval: 1m
+  outputs: ["exponential_histogram_bucket(0)"]
+`, `
+cpu_usage{cpu="1"} 12.5
+cpu_usage{cpu="1"} 13.3
+cpu_usage{cpu="1"} 13
+cpu_usage{cpu="1"} 16
+cpu_usage{cpu="1"} 0
+cpu_usage{cpu="1"} 25
+cpu_usage{cpu="2"} 90
+cpu_usage{cpu="2"} -1
+`, `cpu_usage:1m_exponential_histogram_bucket{cpu="1",vmrange="0.000e+00...0.000e+00"} 1
+cpu_usage:1m_exponential_histogram_bucket{cpu="1",vmrange="1.600e+01...3.200e+01"} 1
+cpu_usage:1m_exponential_histogram_bucket{cpu="1",vmrange="8.000e+00...1.600e+01"} 4
+cpu_usage:1m_exponential_histogram_bucket{cpu="2",vmrange="6.400e+01...1.280e+02"} 1
+`, "11111111")
+
+	// exponential_histogram_bucket output over classic histograms with distinct buckets
+	f(`
+- interval: 1m
+  without: [pod]
+  outputs: ["exponential_histogram_bucket(-1)"]
+`, `
+req_duration_bucket{le="1",pod="a"} 0 10
+req_duration_bucket{le="2",pod="a"} 0 10
+req_duration_bucket{le="+Inf",pod="a"} 0 10
+req_duration_bucket{le="4",pod="b"} 0 10
+req_duration_bucket{le="+Inf",pod="b"} 0 10
+req_duration_bucket{le="1",pod="a"} 2 20
+req_duration_bucket{le="2",pod="a"} 6 20
+req_duration_bucket{le="+Inf",pod="a"} 7 20
+req_duration_bucket{le="4",pod="b"} 3 20
+req_duration_bucket{le="+Inf",pod="b"} 3 20
+`, `req_duration_bucket:1m_without_pod_exponential_histogram_bucket{vmrange="1.000e+00...4.000e+00"} 7
+req_duration_bucket:1m_without_pod_exponential_histogram_bucket{vmrange="2.500e-01...1.000e+00"} 2
+req_duration_bucket:1m_without_pod_exponential_histogram_bucket{vmrange="4.000e+00...1.600e+01"} 1
+`, "1111111111")
+
+	// exponential_histogram_bucket output over classic histogram with +Inf hits on the exponential bucket boundary
+	f(`
+- interval: 1m
+  outputs: ["exponential_histogram_bucket(0)"]
+`, `
+req_duration_bucket{le="4"} 0 10
+req_duration_bucket{le="+Inf"} 0 10
+req_duration_bucket{le="4"} 2 20
+req_duration_bucket{le="+Inf"} 5 20
+`, `req_duration_bucket:1m_exponential_histogram_bucket{vmrange="2.000e+00...4.000e+00"} 2
+req_duration_bucket:1m_exponential_histogram_bucket{vmrange="4.000e+00...8.000e+00"} 3
+`, "1111")
+
 	// quantiles output
 	f(`
 - interval: 1m
@@ -883,6 +1084,25 @@ foo-1m-without-abc-count-series{new_label="must_keep_metric_name"} 1
 foo-1m-without-abc-sum-samples{new_label="must_keep_metric_name"} 12.5
 `, "1111")
 
+	// sliding window
+	f(`
+- interval: 1m
+  window: 5m
+  outputs: [count_samples, sum_samples, count_series, "quantiles(0.5)"]
+`, `
+foo{abc="123"} 4
+foo{abc="123"} 8.5 10
+foo{abc="456"} 8
+`, `foo:5m_count_samples{abc="123"} 2
+foo:5m_count_samples{abc="456"} 1
+foo:5m_count_series{abc="123"} 1
+foo:5m_count_series{abc="456"} 1
+foo:5m_quantiles{abc="123",quantile="0.5"} 8.5
+foo:5m_quantiles{abc="456",quantile="0.5"} 8
+foo:5m_sum_samples{abc="123"} 12.5
+foo:5m_sum_samples{abc="456"} 8
+`, "111")
+
 	// test rate_sum and rate_avg
 	f(`
 - interval: 1m
@@ -0,0 +1,167 @@
+package streamaggr
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+// topkOtherLabelName is the name of the marker label for the series with the collapsed groups, which didn't get into topk or bottomk.
+//
+// The label name starts with `__`, since such label names are reserved, so the collapsed series cannot clash with real groups.
+const topkOtherLabelName = "__other__"
+
+// topkAggrState calculates output=topk(N) and bottomk(N), e.g. the sum over input samples for N groups
+// with the biggest or the smallest sums per each metric name. The remaining groups are collapsed
+// into a single series with `__other__="true"` label.
+type topkAggrState struct {
+	m sync.Map
+
+	// k is the number of groups to leave per each metric name.
+	k int
+
+	// isBottom is set to true if bottomk(N) must be calculated instead of topk(N).
+	isBottom bool
+}
+
+type topkStateValue struct {
+	mu      sync.Mutex
+	sum     float64
+	deleted bool
+}
+
+type topkEntry struct {
+	key    string
+	labels []prompbmarshal.Label
+	sum    float64
+}
+
+func newTopkAggrState(k int, isBottom bool) *topkAggrState {
+	return &topkAggrState{
+		k:        k,
+		isBottom: isBottom,
+	}
+}
+
+func (as *topkAggrState) pushSamples(samples []pushSample) {
+	for i := range samples {
+		s := &samples[i]
+		outputKey := getOutputKey(s.key)
+
+	again:
+		v, ok := as.m.Load(outputKey)
+		if !ok {
+			// The entry is missing in the map. Try creating it.
+			v = &topkStateValue{
+				sum: s.value,
+			}
+			outputKey = bytesutil.InternString(outputKey)
+			vNew, loaded := as.m.LoadOrStore(outputKey, v)
+			if !loaded {
+				// The new entry has been successfully created.
+				continue
+			}
+			// Use the entry created by a concurrent goroutine.
+			v = vNew
+		}
+		sv := v.(*topkStateValue)
+		sv.mu.Lock()
+		deleted := sv.deleted
+		if !deleted {
+			sv.sum += s.value
+		}
+		sv.mu.Unlock()
+		if deleted {
+			// The entry has been deleted by the concurrent call to flushState
+			// Try obtaining and updating the entry again.
+			goto again
+		}
+	}
+}
+
+func (as *topkAggrState) flushState(ctx *flushCtx) {
+	// Group entries by metric name, since topk and bottomk are calculated individually per each metric name.
+	groups := make(map[string][]topkEntry)
+	m := &as.m
+	m.Range(func(k, v any) bool {
+		// Atomically delete the entry from the map, so new entry is created for the next flush.
+		m.Delete(k)
+
+		sv := v.(*topkStateValue)
+		sv.mu.Lock()
+		sum := sv.sum
+		// Mark the entry as deleted, so it won't be updated anymore by concurrent pushSample() calls.
+		sv.deleted = true
+		sv.mu.Unlock()
+
+		key := k.(string)
+		labels := decompressLabels(nil, key)
+		metricName := getMetricName(labels)
+		groups[metricName] = append(groups[metricName], topkEntry{
+			key:    key,
+			labels: labels,
+			sum:    sum,
+		})
+		return true
+	})
+
+	suffix := as.getSuffix()
+	var otherLabels []prompbmarshal.Label
+	var otherKey []byte
+	for metricName, entries := range groups {
+		if as.isBottom {
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].sum < entries[j].sum
+			})
+		} else {
+			sort.Slice(entries, func(i, j int) bool {
+				return entries[i].sum > entries[j].sum
+			})
+		}
+
+		n := min(as.k, len(entries))
+		for _, e := range entries[:n] {
+			ctx.appendSeries(e.key, suffix, e.sum)
+		}
+		if n == len(entries) {
+			continue
+		}
+
+		// Collapse the remaining groups into a single series with the metric name and `__other__="true"` label.
+		otherLabels = otherLabels[:0]
+		if metricName != "" {
+			otherLabels = append(otherLabels, prompbmarshal.Label{
+				Name:  "__name__",
+				Value: metricName,
+			})
+		}
+		otherLabels = append(otherLabels, prompbmarshal.Label{
+			Name:  topkOtherLabelName,
+			Value: "true",
+		})
+		otherSum := 0.0
+		for _, e := range entries[n:] {
+			otherSum += e.sum
+		}
+		otherKey = lc.Compress(otherKey[:0], otherLabels)
+		ctx.appendSeries(bytesutil.ToUnsafeString(otherKey), suffix, otherSum)
+	}
+}
+
+func (as *topkAggrState) getSuffix() string {
+	if as.isBottom {
+		return "bottomk"
+	}
+	return "topk"
+}
+
+func getMetricName(labels []prompbmarshal.Label) string {
+	for _, label := range labels {
+		if label.Name == "__name__" {
+			return label.Value
+		}
+	}
+	return ""
+}
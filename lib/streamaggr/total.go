@@ -1,11 +1,13 @@
 package streamaggr
 
 import (
+	"fmt"
 	"math"
 	"sync"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 )
 
@@ -184,3 +186,89 @@ func (as *totalAggrState) removeOldEntries(currentTime uint64) {
 		return true
 	})
 }
+
+func (as *totalAggrState) marshalState(dst []byte) []byte {
+	var entries []byte
+	entriesCount := 0
+	as.m.Range(func(k, v any) bool {
+		sv := v.(*totalStateValue)
+
+		sv.mu.Lock()
+		if !sv.deleted {
+			entries = marshalLabelsKey(entries, k.(string))
+			entries = marshalFloat64(entries, sv.total)
+			entries = encoding.MarshalUint64(entries, sv.deleteDeadline)
+			entries = encoding.MarshalVarUint64(entries, uint64(len(sv.lastValues)))
+			for inputKey, lv := range sv.lastValues {
+				entries = marshalLabelsKey(entries, inputKey)
+				entries = marshalFloat64(entries, lv.value)
+				entries = encoding.MarshalInt64(entries, lv.timestamp)
+				entries = encoding.MarshalUint64(entries, lv.deleteDeadline)
+			}
+			entriesCount++
+		}
+		sv.mu.Unlock()
+		return true
+	})
+	dst = encoding.MarshalVarUint64(dst, uint64(entriesCount))
+	return append(dst, entries...)
+}
+
+func (as *totalAggrState) unmarshalState(src []byte, isCurrentInterval bool) error {
+	src, entriesCount, err := unmarshalVarUint64(src)
+	if err != nil {
+		return fmt.Errorf("cannot read the number of entries: %w", err)
+	}
+	for i := uint64(0); i < entriesCount; i++ {
+		var outputKey string
+		src, outputKey, err = unmarshalInternedLabelsKey(src)
+		if err != nil {
+			return fmt.Errorf("cannot read output key: %w", err)
+		}
+		sv := &totalStateValue{}
+		src, sv.total, err = unmarshalFloat64(src)
+		if err != nil {
+			return fmt.Errorf("cannot read total: %w", err)
+		}
+		if as.resetTotalOnFlush && !isCurrentInterval {
+			// The total belongs to the previous aggregation interval.
+			sv.total = 0
+		}
+		src, sv.deleteDeadline, err = unmarshalUint64(src)
+		if err != nil {
+			return fmt.Errorf("cannot read deleteDeadline: %w", err)
+		}
+		var lastValuesCount uint64
+		src, lastValuesCount, err = unmarshalVarUint64(src)
+		if err != nil {
+			return fmt.Errorf("cannot read the number of last values: %w", err)
+		}
+		sv.lastValues = make(map[string]totalLastValueState, lastValuesCount)
+		for j := uint64(0); j < lastValuesCount; j++ {
+			var inputKey string
+			src, inputKey, err = unmarshalInternedLabelsKey(src)
+			if err != nil {
+				return fmt.Errorf("cannot read input key: %w", err)
+			}
+			var lv totalLastValueState
+			src, lv.value, err = unmarshalFloat64(src)
+			if err != nil {
+				return fmt.Errorf("cannot read last value: %w", err)
+			}
+			src, lv.timestamp, err = unmarshalInt64(src)
+			if err != nil {
+				return fmt.Errorf("cannot read last timestamp: %w", err)
+			}
+			src, lv.deleteDeadline, err = unmarshalUint64(src)
+			if err != nil {
+				return fmt.Errorf("cannot read deleteDeadline for last value: %w", err)
+			}
+			sv.lastValues[inputKey] = lv
+		}
+		as.m.Store(outputKey, sv)
+	}
+	if len(src) > 0 {
+		return fmt.Errorf("unexpected non-empty tail left; len(tail)=%d", len(src))
+	}
+	return nil
+}
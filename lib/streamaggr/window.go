@@ -0,0 +1,669 @@
+package streamaggr
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+)
+
+// windowOutputs contains outputs, which support `window` option.
+var windowOutputs = []string{
+	"count_samples",
+	"count_series",
+	"quantiles(phi1, ..., phiN)",
+	"rate_avg",
+	"rate_sum",
+	"sum_samples",
+}
+
+func isWindowOutput(output string) bool {
+	if strings.HasPrefix(output, "quantiles(") {
+		return true
+	}
+	return slices.Contains(windowOutputs, output)
+}
+
+// windowSlots tracks aggregation intervals for ring buffer slots with sub-interval state.
+//
+// The ring buffer contains windowSize slots. Every slot contains the state for a single aggregation interval.
+// Slots are lazily reset when they are re-used for newer aggregation intervals.
+type windowSlots struct {
+	// intervals contains aggregation interval index per each slot.
+	intervals []int64
+}
+
+func newWindowSlots(windowSize int) windowSlots {
+	intervals := make([]int64, windowSize)
+	for i := range intervals {
+		intervals[i] = -1
+	}
+	return windowSlots{
+		intervals: intervals,
+	}
+}
+
+// getSlot returns the slot index for the aggregation interval with the given index.
+//
+// The returned isNew is set to true if the slot must be reset before use.
+func (ws *windowSlots) getSlot(interval int64) (int, bool) {
+	idx := int(interval % int64(len(ws.intervals)))
+	if ws.intervals[idx] == interval {
+		return idx, false
+	}
+	ws.intervals[idx] = interval
+	return idx, true
+}
+
+// isActive returns true if the slot with the given idx belongs to the window ending at currentInterval.
+func (ws *windowSlots) isActive(idx int, currentInterval int64) bool {
+	interval := ws.intervals[idx]
+	return interval >= 0 && interval > currentInterval-int64(len(ws.intervals)) && interval <= currentInterval
+}
+
+// hasActive returns true if ws contains at least a single active slot for the window ending at currentInterval.
+func (ws *windowSlots) hasActive(currentInterval int64) bool {
+	for i := range ws.intervals {
+		if ws.isActive(i, currentInterval) {
+			return true
+		}
+	}
+	return false
+}
+
+// windowSamplesAggrState calculates output=sum_samples and count_samples over sliding window.
+type windowSamplesAggrState struct {
+	m sync.Map
+
+	windowSize int
+
+	// currentInterval is the index of the current aggregation interval.
+	currentInterval atomic.Int64
+
+	// isCount is set to true if count_samples must be calculated instead of sum_samples.
+	isCount bool
+}
+
+type windowSamplesStateValue struct {
+	mu      sync.Mutex
+	slots   windowSlots
+	sums    []float64
+	deleted bool
+}
+
+func newWindowSamplesAggrState(windowSize int, isCount bool) *windowSamplesAggrState {
+	return &windowSamplesAggrState{
+		windowSize: windowSize,
+		isCount:    isCount,
+	}
+}
+
+func (as *windowSamplesAggrState) pushSamples(samples []pushSample) {
+	currentInterval := as.currentInterval.Load()
+	for i := range samples {
+		s := &samples[i]
+		outputKey := getOutputKey(s.key)
+
+	again:
+		v, ok := as.m.Load(outputKey)
+		if !ok {
+			// The entry is missing in the map. Try creating it.
+			v = &windowSamplesStateValue{
+				slots: newWindowSlots(as.windowSize),
+				sums:  make([]float64, as.windowSize),
+			}
+			outputKey = bytesutil.InternString(outputKey)
+			vNew, loaded := as.m.LoadOrStore(outputKey, v)
+			if loaded {
+				// Use the entry created by a concurrent goroutine.
+				v = vNew
+			}
+		}
+		sv := v.(*windowSamplesStateValue)
+		sv.mu.Lock()
+		deleted := sv.deleted
+		if !deleted {
+			idx, isNew := sv.slots.getSlot(currentInterval)
+			if isNew {
+				sv.sums[idx] = 0
+			}
+			if as.isCount {
+				sv.sums[idx]++
+			} else {
+				sv.sums[idx] += s.value
+			}
+		}
+		sv.mu.Unlock()
+		if deleted {
+			// The entry has been deleted by the concurrent call to flushState
+			// Try obtaining and updating the entry again.
+			goto again
+		}
+	}
+}
+
+func (as *windowSamplesAggrState) flushState(ctx *flushCtx) {
+	currentInterval := as.currentInterval.Load()
+	suffix := as.getSuffix()
+	m := &as.m
+	m.Range(func(k, v any) bool {
+		sv := v.(*windowSamplesStateValue)
+		sv.mu.Lock()
+		if !sv.slots.hasActive(currentInterval) {
+			// The entry has no samples on the window. Delete it.
+			sv.deleted = true
+			sv.mu.Unlock()
+			m.Delete(k)
+			return true
+		}
+		sum := 0.0
+		for i, v := range sv.sums {
+			if sv.slots.isActive(i, currentInterval) {
+				sum += v
+			}
+		}
+		sv.mu.Unlock()
+
+		key := k.(string)
+		ctx.appendSeries(key, suffix, sum)
+		return true
+	})
+	as.currentInterval.Add(1)
+}
+
+func (as *windowSamplesAggrState) getSuffix() string {
+	if as.isCount {
+		return "count_samples"
+	}
+	return "sum_samples"
+}
+
+// windowCountSeriesAggrState calculates output=count_series over sliding window.
+type windowCountSeriesAggrState struct {
+	m sync.Map
+
+	windowSize int
+
+	// currentInterval is the index of the current aggregation interval.
+	currentInterval atomic.Int64
+}
+
+type windowCountSeriesStateValue struct {
+	mu sync.Mutex
+
+	// m contains the index of the last aggregation interval per each input series hash.
+	m       map[uint64]int64
+	deleted bool
+}
+
+func newWindowCountSeriesAggrState(windowSize int) *windowCountSeriesAggrState {
+	return &windowCountSeriesAggrState{
+		windowSize: windowSize,
+	}
+}
+
+func (as *windowCountSeriesAggrState) pushSamples(samples []pushSample) {
+	currentInterval := as.currentInterval.Load()
+	for i := range samples {
+		s := &samples[i]
+		inputKey, outputKey := getInputOutputKey(s.key)
+
+		// Count unique hashes over the inputKeys instead of unique inputKey values.
+		// This reduces memory usage at the cost of possible hash collisions for distinct inputKey values.
+		h := xxhash.Sum64(bytesutil.ToUnsafeBytes(inputKey))
+
+	again:
+		v, ok := as.m.Load(outputKey)
+		if !ok {
+			// The entry is missing in the map. Try creating it.
+			v = &windowCountSeriesStateValue{
+				m: make(map[uint64]int64),
+			}
+			outputKey = bytesutil.InternString(outputKey)
+			vNew, loaded := as.m.LoadOrStore(outputKey, v)
+			if loaded {
+				// Use the entry created by a concurrent goroutine.
+				v = vNew
+			}
+		}
+		sv := v.(*windowCountSeriesStateValue)
+		sv.mu.Lock()
+		deleted := sv.deleted
+		if !deleted {
+			sv.m[h] = currentInterval
+		}
+		sv.mu.Unlock()
+		if deleted {
+			// The entry has been deleted by the concurrent call to flushState
+			// Try obtaining and updating the entry again.
+			goto again
+		}
+	}
+}
+
+func (as *windowCountSeriesAggrState) flushState(ctx *flushCtx) {
+	currentInterval := as.currentInterval.Load()
+	minInterval := currentInterval - int64(as.windowSize) + 1
+	m := &as.m
+	m.Range(func(k, v any) bool {
+		sv := v.(*windowCountSeriesStateValue)
+		sv.mu.Lock()
+		for h, interval := range sv.m {
+			if interval < minInterval {
+				// The series has no samples on the window.
+				delete(sv.m, h)
+			}
+		}
+		n := len(sv.m)
+		if n == 0 {
+			sv.deleted = true
+			sv.mu.Unlock()
+			m.Delete(k)
+			return true
+		}
+		sv.mu.Unlock()
+
+		key := k.(string)
+		ctx.appendSeries(key, "count_series", float64(n))
+		return true
+	})
+	as.currentInterval.Add(1)
+}
+
+// windowQuantilesMaxSamples is the maximum number of samples to keep per each slot for windowQuantilesAggrState.
+const windowQuantilesMaxSamples = 1000
+
+// windowQuantilesAggrState calculates output=quantiles over sliding window.
+type windowQuantilesAggrState struct {
+	m sync.Map
+
+	windowSize int
+
+	// currentInterval is the index of the current aggregation interval.
+	currentInterval atomic.Int64
+
+	phis []float64
+}
+
+type windowQuantilesStateValue struct {
+	mu      sync.Mutex
+	slots   windowSlots
+	samples []windowQuantilesSamples
+	deleted bool
+}
+
+// windowQuantilesSamples contains a uniform random sample of values registered during a single aggregation interval.
+type windowQuantilesSamples struct {
+	// count is the number of all the values registered in the slot.
+	count  uint64
+	values []float64
+}
+
+func (wqs *windowQuantilesSamples) reset() {
+	wqs.count = 0
+	wqs.values = wqs.values[:0]
+}
+
+func (wqs *windowQuantilesSamples) update(v float64) {
+	wqs.count++
+	if len(wqs.values) < windowQuantilesMaxSamples {
+		wqs.values = append(wqs.values, v)
+		return
+	}
+	// Use reservoir sampling, so the values represent all the registered values.
+	n := rand.Uint64N(wqs.count)
+	if n < uint64(len(wqs.values)) {
+		wqs.values[n] = v
+	}
+}
+
+type weightedValue struct {
+	value  float64
+	weight float64
+}
+
+func newWindowQuantilesAggrState(windowSize int, phis []float64) *windowQuantilesAggrState {
+	return &windowQuantilesAggrState{
+		windowSize: windowSize,
+		phis:       phis,
+	}
+}
+
+func (as *windowQuantilesAggrState) pushSamples(samples []pushSample) {
+	currentInterval := as.currentInterval.Load()
+	for i := range samples {
+		s := &samples[i]
+		outputKey := getOutputKey(s.key)
+
+	again:
+		v, ok := as.m.Load(outputKey)
+		if !ok {
+			// The entry is missing in the map. Try creating it.
+			v = &windowQuantilesStateValue{
+				slots:   newWindowSlots(as.windowSize),
+				samples: make([]windowQuantilesSamples, as.windowSize),
+			}
+			outputKey = bytesutil.InternString(outputKey)
+			vNew, loaded := as.m.LoadOrStore(outputKey, v)
+			if loaded {
+				// Use the entry created by a concurrent goroutine.
+				v = vNew
+			}
+		}
+		sv := v.(*windowQuantilesStateValue)
+		sv.mu.Lock()
+		deleted := sv.deleted
+		if !deleted {
+			idx, isNew := sv.slots.getSlot(currentInterval)
+			if isNew {
+				sv.samples[idx].reset()
+			}
+			sv.samples[idx].update(s.value)
+		}
+		sv.mu.Unlock()
+		if deleted {
+			// The entry has been deleted by the concurrent call to flushState
+			// Try obtaining and updating the entry again.
+			goto again
+		}
+	}
+}
+
+func (as *windowQuantilesAggrState) flushState(ctx *flushCtx) {
+	currentInterval := as.currentInterval.Load()
+	phis := as.phis
+	var wvs []weightedValue
+	var quantiles []float64
+	var b []byte
+	m := &as.m
+	m.Range(func(k, v any) bool {
+		sv := v.(*windowQuantilesStateValue)
+		sv.mu.Lock()
+		if !sv.slots.hasActive(currentInterval) {
+			// The entry has no samples on the window. Delete it.
+			sv.deleted = true
+			sv.mu.Unlock()
+			m.Delete(k)
+			return true
+		}
+		wvs = wvs[:0]
+		for i := range sv.samples {
+			if !sv.slots.isActive(i, currentInterval) {
+				continue
+			}
+			wqs := &sv.samples[i]
+			if len(wqs.values) == 0 {
+				continue
+			}
+			// Every value in the slot represents count/len(values) registered values.
+			weight := float64(wqs.count) / float64(len(wqs.values))
+			for _, v := range wqs.values {
+				wvs = append(wvs, weightedValue{
+					value:  v,
+					weight: weight,
+				})
+			}
+		}
+		sv.mu.Unlock()
+
+		quantiles = getWeightedQuantiles(quantiles[:0], wvs, phis)
+		key := k.(string)
+		for i, quantile := range quantiles {
+			b = strconv.AppendFloat(b[:0], phis[i], 'g', -1, 64)
+			phiStr := bytesutil.InternBytes(b)
+			ctx.appendSeriesWithExtraLabel(key, "quantiles", quantile, "quantile", phiStr)
+		}
+		return true
+	})
+	as.currentInterval.Add(1)
+}
+
+// getWeightedQuantiles appends quantiles for the given phis over wvs to dst and returns the result.
+//
+// The quantiles are calculated in the same way as histogram.Fast does, while taking into account weights for wvs.
+// wvs is sorted in place.
+func getWeightedQuantiles(dst []float64, wvs []weightedValue, phis []float64) []float64 {
+	if len(wvs) == 0 {
+		for range phis {
+			dst = append(dst, math.NaN())
+		}
+		return dst
+	}
+	sort.Slice(wvs, func(i, j int) bool {
+		return wvs[i].value < wvs[j].value
+	})
+	totalWeight := 0.0
+	for _, wv := range wvs {
+		totalWeight += wv.weight
+	}
+	for _, phi := range phis {
+		if phi <= 0 {
+			dst = append(dst, wvs[0].value)
+			continue
+		}
+		if phi >= 1 {
+			dst = append(dst, wvs[len(wvs)-1].value)
+			continue
+		}
+		rank := math.Floor(phi*(totalWeight-1) + 0.5)
+		result := wvs[len(wvs)-1].value
+		cumulativeWeight := 0.0
+		for _, wv := range wvs {
+			cumulativeWeight += wv.weight
+			if cumulativeWeight > rank {
+				result = wv.value
+				break
+			}
+		}
+		dst = append(dst, result)
+	}
+	return dst
+}
+
+// windowRateAggrState calculates output=rate_avg and rate_sum over sliding window.
+type windowRateAggrState struct {
+	m sync.Map
+
+	windowSize int
+
+	// currentInterval is the index of the current aggregation interval.
+	currentInterval atomic.Int64
+
+	// isAvg is set to true if rate_avg() must be calculated instead of rate_sum().
+	isAvg bool
+
+	// Time series state is dropped if no new samples are received during stalenessSecs.
+	stalenessSecs uint64
+}
+
+type windowRateStateValue struct {
+	mu             sync.Mutex
+	lastValues     map[string]*windowRateLastValueState
+	deleteDeadline uint64
+	deleted        bool
+}
+
+type windowRateLastValueState struct {
+	value          float64
+	timestamp      int64
+	deleteDeadline uint64
+
+	slots windowSlots
+
+	// increases contains the increase for the time series per each slot.
+	increases []float64
+
+	// startTimestamps contains the timestamp of the last sample before the given slot.
+	startTimestamps []int64
+}
+
+func newWindowRateAggrState(windowSize int, stalenessInterval time.Duration, isAvg bool) *windowRateAggrState {
+	stalenessSecs := roundDurationToSecs(stalenessInterval)
+	return &windowRateAggrState{
+		windowSize:    windowSize,
+		isAvg:         isAvg,
+		stalenessSecs: stalenessSecs,
+	}
+}
+
+func (as *windowRateAggrState) pushSamples(samples []pushSample) {
+	currentTime := fasttime.UnixTimestamp()
+	deleteDeadline := currentTime + as.stalenessSecs
+	currentInterval := as.currentInterval.Load()
+	for i := range samples {
+		s := &samples[i]
+		inputKey, outputKey := getInputOutputKey(s.key)
+
+	again:
+		v, ok := as.m.Load(outputKey)
+		if !ok {
+			// The entry is missing in the map. Try creating it.
+			v = &windowRateStateValue{
+				lastValues: make(map[string]*windowRateLastValueState),
+			}
+			outputKey = bytesutil.InternString(outputKey)
+			vNew, loaded := as.m.LoadOrStore(outputKey, v)
+			if loaded {
+				// Use the entry created by a concurrent goroutine.
+				v = vNew
+			}
+		}
+		sv := v.(*windowRateStateValue)
+		sv.mu.Lock()
+		deleted := sv.deleted
+		if !deleted {
+			lv, ok := sv.lastValues[inputKey]
+			if !ok {
+				lv = &windowRateLastValueState{
+					timestamp:       s.timestamp,
+					value:           s.value,
+					slots:           newWindowSlots(as.windowSize),
+					increases:       make([]float64, as.windowSize),
+					startTimestamps: make([]int64, as.windowSize),
+				}
+				inputKey = bytesutil.InternString(inputKey)
+				sv.lastValues[inputKey] = lv
+			}
+			if s.timestamp >= lv.timestamp {
+				idx, isNew := lv.slots.getSlot(currentInterval)
+				if isNew {
+					lv.increases[idx] = 0
+					lv.startTimestamps[idx] = lv.timestamp
+				}
+				if s.value >= lv.value {
+					lv.increases[idx] += s.value - lv.value
+				} else {
+					// counter reset
+					lv.increases[idx] += s.value
+				}
+				lv.value = s.value
+				lv.timestamp = s.timestamp
+				lv.deleteDeadline = deleteDeadline
+			}
+			// Out of order samples are skipped.
+			sv.deleteDeadline = deleteDeadline
+		}
+		sv.mu.Unlock()
+		if deleted {
+			// The entry has been deleted by the concurrent call to flushState
+			// Try obtaining and updating the entry again.
+			goto again
+		}
+	}
+}
+
+func (as *windowRateAggrState) flushState(ctx *flushCtx) {
+	currentTime := fasttime.UnixTimestamp()
+	currentInterval := as.currentInterval.Load()
+
+	suffix := as.getSuffix()
+
+	as.removeOldEntries(currentTime)
+
+	m := &as.m
+	m.Range(func(k, v any) bool {
+		sv := v.(*windowRateStateValue)
+
+		sv.mu.Lock()
+		sumRate := 0.0
+		countSeries := 0
+		for _, lv := range sv.lastValues {
+			increase := 0.0
+			startTimestamp := lv.timestamp
+			for i := range lv.increases {
+				if !lv.slots.isActive(i, currentInterval) {
+					continue
+				}
+				increase += lv.increases[i]
+				if lv.startTimestamps[i] < startTimestamp {
+					startTimestamp = lv.startTimestamps[i]
+				}
+			}
+			d := float64(lv.timestamp-startTimestamp) / 1000
+			if d > 0 {
+				sumRate += increase / d
+				countSeries++
+			}
+		}
+		deleted := sv.deleted
+		sv.mu.Unlock()
+
+		if countSeries == 0 || deleted {
+			// Nothing to update
+			return true
+		}
+
+		result := sumRate
+		if as.isAvg {
+			result /= float64(countSeries)
+		}
+
+		key := k.(string)
+		ctx.appendSeries(key, suffix, result)
+		return true
+	})
+	as.currentInterval.Add(1)
+}
+
+func (as *windowRateAggrState) getSuffix() string {
+	if as.isAvg {
+		return "rate_avg"
+	}
+	return "rate_sum"
+}
+
+func (as *windowRateAggrState) removeOldEntries(currentTime uint64) {
+	m := &as.m
+	m.Range(func(k, v any) bool {
+		sv := v.(*windowRateStateValue)
+
+		sv.mu.Lock()
+		if currentTime > sv.deleteDeadline {
+			// Mark the current entry as deleted
+			sv.deleted = true
+			sv.mu.Unlock()
+			m.Delete(k)
+			return true
+		}
+
+		// Delete outdated entries in sv.lastValues
+		lvs := sv.lastValues
+		for k1, lv := range lvs {
+			if currentTime > lv.deleteDeadline {
+				delete(lvs, k1)
+			}
+		}
+		sv.mu.Unlock()
+		return true
+	})
+}
@@ -0,0 +1,147 @@
+package streamaggr
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestWindowAggrStates(t *testing.T) {
+	f := func(as aggrState, samplesPerInterval [][]pushSample, outputsExpected []string) {
+		t.Helper()
+
+		a := &aggregator{
+			suffix: ":3m_",
+		}
+		ctx := getFlushCtx(a, nil, nil, 0)
+		defer putFlushCtx(ctx)
+		for i, samples := range samplesPerInterval {
+			as.pushSamples(samples)
+			as.flushState(ctx)
+			output := timeSeriessToString(ctx.tss)
+			ctx.resetSeries()
+			if output != outputsExpected[i] {
+				t.Fatalf("unexpected output at interval #%d;\ngot\n%s\nwant\n%s", i, output, outputsExpected[i])
+			}
+		}
+	}
+
+	newKey := func(inputValue string) string {
+		inputLabels := []prompbmarshal.Label{{Name: "instance", Value: inputValue}}
+		outputLabels := []prompbmarshal.Label{{Name: "__name__", Value: "foo"}}
+		return string(compressLabels(nil, inputLabels, outputLabels))
+	}
+	keyA := newKey("a")
+	keyB := newKey("b")
+
+	// sum_samples over 3 intervals
+	f(newWindowSamplesAggrState(3, false), [][]pushSample{
+		{{key: keyA, value: 1}, {key: keyB, value: 2}},
+		{{key: keyA, value: 3}},
+		{},
+		{{key: keyA, value: 4}},
+		{},
+		{},
+		{},
+	}, []string{
+		"foo:3m_sum_samples 3\n",
+		"foo:3m_sum_samples 6\n",
+		"foo:3m_sum_samples 6\n",
+		"foo:3m_sum_samples 7\n",
+		"foo:3m_sum_samples 4\n",
+		"foo:3m_sum_samples 4\n",
+		"",
+	})
+
+	// count_samples over 2 intervals
+	f(newWindowSamplesAggrState(2, true), [][]pushSample{
+		{{key: keyA, value: 1}, {key: keyB, value: 2}},
+		{{key: keyA, value: 3}},
+		{},
+	}, []string{
+		"foo:3m_count_samples 2\n",
+		"foo:3m_count_samples 3\n",
+		"foo:3m_count_samples 1\n",
+	})
+
+	// count_series over 2 intervals
+	f(newWindowCountSeriesAggrState(2), [][]pushSample{
+		{{key: keyA, value: 1}, {key: keyB, value: 2}},
+		{{key: keyA, value: 3}},
+		{{key: keyA, value: 3}},
+		{},
+		{},
+	}, []string{
+		"foo:3m_count_series 2\n",
+		"foo:3m_count_series 2\n",
+		"foo:3m_count_series 1\n",
+		"foo:3m_count_series 1\n",
+		"",
+	})
+
+	// quantiles over 2 intervals
+	f(newWindowQuantilesAggrState(2, []float64{0, 0.5, 1}), [][]pushSample{
+		{{key: keyA, value: 1}, {key: keyB, value: 2}},
+		{{key: keyA, value: 3}},
+		{},
+	}, []string{
+		`foo:3m_quantiles{quantile="0"} 1
+foo:3m_quantiles{quantile="0.5"} 2
+foo:3m_quantiles{quantile="1"} 2
+`,
+		`foo:3m_quantiles{quantile="0"} 1
+foo:3m_quantiles{quantile="0.5"} 2
+foo:3m_quantiles{quantile="1"} 3
+`,
+		`foo:3m_quantiles{quantile="0"} 3
+foo:3m_quantiles{quantile="0.5"} 3
+foo:3m_quantiles{quantile="1"} 3
+`,
+	})
+
+	// rate_sum over 2 intervals
+	f(newWindowRateAggrState(2, 10*time.Minute, false), [][]pushSample{
+		{{key: keyA, value: 1, timestamp: 0}, {key: keyA, value: 3, timestamp: 10_000}},
+		{{key: keyA, value: 7, timestamp: 20_000}},
+		{{key: keyA, value: 8, timestamp: 40_000}},
+	}, []string{
+		"foo:3m_rate_sum 0.2\n",
+		"foo:3m_rate_sum 0.3\n",
+		"foo:3m_rate_sum 0.16666666666666666\n",
+	})
+}
+
+func TestGetWeightedQuantiles(t *testing.T) {
+	f := func(wvs []weightedValue, phis, resultExpected []float64) {
+		t.Helper()
+
+		result := getWeightedQuantiles(nil, wvs, phis)
+		if len(result) != len(resultExpected) {
+			t.Fatalf("unexpected number of results; got %d; want %d", len(result), len(resultExpected))
+		}
+		for i := range result {
+			if math.IsNaN(resultExpected[i]) {
+				if !math.IsNaN(result[i]) {
+					t.Fatalf("unexpected result for phi=%v; got %v; want NaN", phis[i], result[i])
+				}
+				continue
+			}
+			if result[i] != resultExpected[i] {
+				t.Fatalf("unexpected result for phi=%v; got %v; want %v", phis[i], result[i], resultExpected[i])
+			}
+		}
+	}
+
+	phis := []float64{0, 0.5, 0.9, 1}
+
+	// empty values
+	f(nil, phis, []float64{math.NaN(), math.NaN(), math.NaN(), math.NaN()})
+
+	// equal weights
+	f([]weightedValue{{25, 1}, {12, 1}, {13.3, 1}, {13, 1}, {14, 1}, {12.5, 1}}, phis, []float64{12, 13.3, 25, 25})
+
+	// the value with the biggest weight dominates
+	f([]weightedValue{{1, 1}, {2, 100}, {3, 1}}, phis, []float64{1, 2, 2, 3})
+}